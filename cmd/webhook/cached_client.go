@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cachedReadClient reads Secrets and ConfigMaps from an informer cache and
+// everything else, including all writes, through the API server. Objects
+// missing from the cache are looked up through the API server too, as a
+// Secret created right before its VM may not have reached the cache yet.
+type cachedReadClient struct {
+	client.Client
+	cache client.Reader
+}
+
+// isCachedType reports whether reads of obj are served from the cache
+func isCachedType(obj client.Object) bool {
+	switch obj.(type) {
+	case *corev1.Secret, *corev1.ConfigMap:
+		return true
+	}
+	return false
+}
+
+// Get reads Secrets and ConfigMaps from the cache
+func (c *cachedReadClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if !isCachedType(obj) {
+		return c.Client.Get(ctx, key, obj, opts...)
+	}
+	err := c.cache.Get(ctx, key, obj, opts...)
+	if apierrors.IsNotFound(err) {
+		return c.Client.Get(ctx, key, obj, opts...)
+	}
+	return err
+}
+
+// List reads Secrets and ConfigMaps from the cache
+func (c *cachedReadClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	switch list.(type) {
+	case *corev1.SecretList, *corev1.ConfigMapList:
+		return c.cache.List(ctx, list, opts...)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
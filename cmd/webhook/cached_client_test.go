@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("cachedReadClient", func() {
+	var (
+		ctx       context.Context
+		apiServer client.Client
+		cached    client.Client
+		c         *cachedReadClient
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		testScheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(testScheme)).To(Succeed())
+		Expect(kubevirtv1.AddToScheme(testScheme)).To(Succeed())
+		apiServer = fake.NewClientBuilder().WithScheme(testScheme).Build()
+		cached = fake.NewClientBuilder().WithScheme(testScheme).Build()
+		c = &cachedReadClient{Client: apiServer, cache: cached}
+	})
+
+	It("should read Secrets and ConfigMaps from the cache", func() {
+		Expect(cached.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "userdata", Namespace: "default"},
+			Data:       map[string][]byte{"userdata": []byte("cached")},
+		})).To(Succeed())
+		Expect(apiServer.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "userdata", Namespace: "default"},
+			Data:       map[string][]byte{"userdata": []byte("live")},
+		})).To(Succeed())
+		Expect(cached.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "sysprep", Namespace: "default"},
+		})).To(Succeed())
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "userdata"}, secret)).To(Succeed())
+		Expect(secret.Data).To(HaveKeyWithValue("userdata", []byte("cached")))
+
+		configMaps := &corev1.ConfigMapList{}
+		Expect(c.List(ctx, configMaps)).To(Succeed())
+		Expect(configMaps.Items).To(HaveLen(1))
+	})
+
+	It("should fall back to the API server for objects missing from the cache", func() {
+		Expect(apiServer.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bootstrap", Namespace: "default"},
+		})).To(Succeed())
+
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "bootstrap"}, &corev1.Secret{})).To(Succeed())
+	})
+
+	It("should read other types and write everything through the API server", func() {
+		Expect(c.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "hook", Namespace: "default"},
+		})).To(Succeed())
+		Expect(apiServer.Get(ctx, client.ObjectKey{Namespace: "default", Name: "hook"}, &corev1.ConfigMap{})).To(Succeed())
+
+		Expect(cached.Create(ctx, &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{Name: "vm", Namespace: "default"},
+		})).To(Succeed())
+		err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "vm"}, &kubevirtv1.VirtualMachine{})
+		Expect(err).To(HaveOccurred())
+	})
+})
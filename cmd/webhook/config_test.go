@@ -0,0 +1,30 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("loadConfig", func() {
+	BeforeEach(func() {
+		previous := version
+		version = "v1.2.3"
+		DeferCleanup(func() { version = previous })
+	})
+
+	It("should record the build version in mutations", func() {
+		GinkgoT().Setenv("WEBHOOK_VERSION", "")
+
+		cfg, err := loadConfig(&options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.WebhookVersion).To(Equal("v1.2.3"))
+	})
+
+	It("should let WEBHOOK_VERSION override the build version", func() {
+		GinkgoT().Setenv("WEBHOOK_VERSION", "v1.2.3-hotfix")
+
+		cfg, err := loadConfig(&options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.WebhookVersion).To(Equal("v1.2.3-hotfix"))
+	})
+})
@@ -8,22 +8,38 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
 
+	uberzap "go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/apis/v1alpha1"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/certs"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/controller"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/plugins"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/webhook"
 )
@@ -37,29 +53,52 @@ var (
 	date    = "unknown"
 )
 
+// featureStatePollInterval is how often the feature state file is re-read
+const featureStatePollInterval = 10 * time.Second
+
+// certRenewInterval is how often a bootstrapped certificate is checked for
+// renewal
+const certRenewInterval = time.Hour
+
 func init() {
 	_ = corev1.AddToScheme(scheme)
 	_ = kubevirtv1.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = authorizationv1.AddToScheme(scheme)
+	_ = admissionregistrationv1.AddToScheme(scheme)
+}
+
+// options holds the command-line settings that override the configuration
+type options struct {
+	configFile    string
+	port          int
+	healthPort    int
+	certDir       string
+	errorHandling string
+	logLevel      string
+	configSource  string
+	mode          string
+	enablePprof   bool
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var showVersion bool
-	var port int
-	var certDir string
-	var errorHandling string
-	var logLevel string
-	var configSource string
+	var opts options
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election for controller manager.")
 	flag.BoolVar(&showVersion, "version", false, "Show version information and exit.")
-	flag.IntVar(&port, "port", 0, "The port the webhook server binds to (overrides PORT env var).")
-	flag.StringVar(&certDir, "cert-dir", "", "The directory containing TLS certificates (overrides CERT_DIR env var).")
-	flag.StringVar(&errorHandling, "error-handling", "", "Error handling mode: 'reject' or 'allow' (overrides ERROR_HANDLING_MODE env var).")
-	flag.StringVar(&logLevel, "log-level", "", "Log level: 'debug', 'info', 'warn', 'error' (overrides LOG_LEVEL env var).")
-	flag.StringVar(&configSource, "config-source", "", "Configuration source: 'annotations' or 'labels' (overrides CONFIG_SOURCE env var).")
+	flag.IntVar(&opts.port, "port", 0, "The port the webhook server binds to (overrides PORT env var).")
+	flag.IntVar(&opts.healthPort, "health-port", 0, "The plain HTTP port serving health, readiness, metrics and debug endpoints (overrides HEALTH_PORT env var).")
+	flag.StringVar(&opts.certDir, "cert-dir", "", "The directory containing TLS certificates (overrides CERT_DIR env var).")
+	flag.StringVar(&opts.errorHandling, "error-handling", "", "Error handling mode: 'reject', 'allow-and-log', 'strip-label' or 'continue' (overrides ERROR_HANDLING_MODE env var).")
+	flag.StringVar(&opts.logLevel, "log-level", "", "Log level: 'debug', 'info', 'warn', 'error' (overrides LOG_LEVEL env var).")
+	flag.StringVar(&opts.configSource, "config-source", "", "Configuration source: 'annotations', 'labels' or 'both' (overrides CONFIG_SOURCE env var).")
+	flag.StringVar(&opts.mode, "mode", "", "Webhook mode: 'enforce' or 'shadow', which only logs and counts patches and rejections (overrides WEBHOOK_MODE env var).")
+	flag.BoolVar(&opts.enablePprof, "enable-pprof", false, "Serve pprof profiles under /debug/pprof on the webhook port (overrides PPROF_ENABLED env var).")
+	flag.StringVar(&opts.configFile, "config", "", "Path to a YAML or JSON config file; environment variables override its settings.")
 	flag.Parse()
 
 	// Show version and exit if requested
@@ -68,45 +107,15 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Load configuration first to get defaults
-	cfg := config.LoadConfig()
-
-	// Override config with command-line flags if provided
-	if port != 0 {
-		cfg.Port = port
-	}
-	if certDir != "" {
-		cfg.CertDir = certDir
-	}
-	if errorHandling != "" {
-		cfg.ErrorHandlingMode = errorHandling
-	}
-	if logLevel != "" {
-		cfg.LogLevel = logLevel
-	}
-	if configSource != "" {
-		if !utils.IsValidConfigSource(configSource) {
-			fmt.Fprintf(os.Stderr, "Invalid config-source value: %s (must be 'annotations' or 'labels')\n", configSource)
-			os.Exit(1)
-		}
-		cfg.ConfigSource = utils.ParseConfigSource(configSource)
+	cfg, err := loadConfig(&opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Set up logger with configured log level
-	zapOpts := []zap.Opts{}
-	switch strings.ToLower(cfg.LogLevel) {
-	case "debug":
-		zapOpts = append(zapOpts, zap.UseDevMode(true), zap.Level(zapcore.DebugLevel))
-	case "info":
-		zapOpts = append(zapOpts, zap.UseDevMode(false), zap.Level(zapcore.InfoLevel))
-	case "warn", "warning":
-		zapOpts = append(zapOpts, zap.UseDevMode(false), zap.Level(zapcore.WarnLevel))
-	case "error":
-		zapOpts = append(zapOpts, zap.UseDevMode(false), zap.Level(zapcore.ErrorLevel))
-	default:
-		zapOpts = append(zapOpts, zap.UseDevMode(false), zap.Level(zapcore.InfoLevel))
-	}
-	log.SetLogger(zap.New(zapOpts...))
+	// Set up logger with configured log level; the level follows config reloads
+	logLevel := uberzap.NewAtomicLevelAt(zapLevel(cfg.LogLevel))
+	log.SetLogger(zap.New(zap.UseDevMode(strings.EqualFold(cfg.LogLevel, "debug")), zap.Level(logLevel)))
 	logger := log.Log.WithName("vm-feature-manager")
 	ctx := log.IntoContext(context.Background(), logger)
 
@@ -117,9 +126,18 @@ func main() {
 
 	logger.Info("Configuration loaded",
 		"port", cfg.Port,
+		"healthPort", cfg.HealthPort,
 		"logLevel", cfg.LogLevel,
 		"errorHandlingMode", cfg.ErrorHandlingMode,
-		"configSource", cfg.ConfigSource)
+		"configSource", cfg.ConfigSource,
+		"mode", cfg.Mode,
+		"pprof", cfg.Pprof)
+	if cfg.Features.PCIPassthrough.ResourceMapFile != "" {
+		logger.Info("PCI resource map loaded", "entries", len(cfg.Features.PCIPassthrough.ResourceMap))
+	}
+	if cfg.FeatureRulesFile != "" {
+		logger.Info("Feature rules loaded", "rules", len(cfg.FeatureRules))
+	}
 
 	// Create Kubernetes client
 	restConfig, err := ctrlconfig.GetConfig()
@@ -134,18 +152,117 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Typed client for events and API server readiness
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		logger.Error(err, "Failed to create Kubernetes clientset")
+		os.Exit(1)
+	}
+
+	// Issue the serving certificate when neither cert-manager nor a mounted one provides it
+	if cfg.CertBootstrap.Enabled {
+		if err := certs.Bootstrap(ctx, k8sClient, &cfg.CertBootstrap, cfg.CertDir); err != nil {
+			logger.Error(err, "Failed to bootstrap serving certificate")
+			os.Exit(1)
+		}
+		logger.Info("Serving certificate bootstrapped",
+			"secret", cfg.CertBootstrap.SecretName,
+			"webhookConfiguration", cfg.CertBootstrap.WebhookConfiguration)
+
+		// Keep it renewed; the server reloads the key pair when it changes
+		go certs.Renew(ctx, k8sClient, &cfg.CertBootstrap, cfg.CertDir, certRenewInterval)
+	}
+
+	// Read Secrets and ConfigMaps from an informer cache, started below
+	var readCache cache.Cache
+	if cfg.CachedReads {
+		readCache, err = cache.New(restConfig, cache.Options{
+			Scheme:                      scheme,
+			ReaderFailOnMissingInformer: true,
+			DefaultTransform:            cache.TransformStripManagedFields(),
+		})
+		if err != nil {
+			logger.Error(err, "Failed to create Secret and ConfigMap cache")
+			os.Exit(1)
+		}
+		k8sClient = &cachedReadClient{Client: k8sClient, cache: readCache}
+	}
+
 	// Initialize features
-	featureList := []features.Feature{
-		features.NewNestedVirtualization(&cfg.Features.NestedVirtualization, cfg.ConfigSource),
-		features.NewPciPassthrough(cfg.ConfigSource),
-		features.NewVBiosInjection(cfg.ConfigSource),
-		features.NewGpuDevicePlugin(cfg.ConfigSource),
+	registry, err := features.NewRegistry(features.Builtin(cfg))
+	if err != nil {
+		logger.Error(err, "Invalid built-in features")
+		os.Exit(1)
 	}
 
-	logger.Info("Features initialized", "count", len(featureList))
+	// Add features served by external gRPC plugins
+	pluginTimeout := time.Duration(cfg.Plugins.TimeoutSeconds) * time.Second
+	for _, name := range slices.Sorted(maps.Keys(cfg.Plugins.GRPC)) {
+		plugin, err := plugins.NewGRPCFeature(name, cfg.Plugins.GRPC[name], pluginTimeout)
+		if err != nil {
+			logger.Error(err, "Failed to create feature plugin", "plugin", name)
+			os.Exit(1)
+		}
+		defer plugin.Close()
+		if err := plugin.CheckName(context.Background()); err != nil {
+			logger.Error(err, "Feature plugin is not ready", "plugin", name)
+		}
+		if err := registry.Register(plugin); err != nil {
+			logger.Error(err, "Failed to register feature plugin", "plugin", name)
+			os.Exit(1)
+		}
+	}
+
+	// Add features implemented by WASM modules
+	if cfg.Plugins.WASMDir != "" {
+		wasmRuntime, err := plugins.NewWASMRuntime(context.Background(), cfg.Plugins.WASMMemoryLimitMB, pluginTimeout)
+		if err != nil {
+			logger.Error(err, "Failed to create WASM runtime")
+			os.Exit(1)
+		}
+		defer wasmRuntime.Close(context.Background())
+		modules, err := wasmRuntime.LoadDir(context.Background(), cfg.Plugins.WASMDir)
+		if err != nil {
+			logger.Error(err, "Failed to load WASM feature modules")
+			os.Exit(1)
+		}
+		for _, module := range modules {
+			if err := registry.Register(module); err != nil {
+				logger.Error(err, "Failed to register WASM feature module", "plugin", module.Name())
+				os.Exit(1)
+			}
+		}
+		logger.Info("WASM feature modules loaded", "count", len(modules))
+	}
+
+	// Add exec hook features
+	if cfg.Plugins.ExecDir != "" {
+		hooks, err := plugins.LoadExecDir(cfg.Plugins.ExecDir, cfg.ConfigSource, pluginTimeout, cfg.Plugins.ExecMaxOutputKB*1024)
+		if err != nil {
+			logger.Error(err, "Failed to load exec hooks")
+			os.Exit(1)
+		}
+		for _, hook := range hooks {
+			if err := registry.Register(hook); err != nil {
+				logger.Error(err, "Failed to register exec hook", "plugin", hook.Name())
+				os.Exit(1)
+			}
+		}
+		logger.Info("Exec hooks loaded", "count", len(hooks))
+	}
+
+	logger.Info("Features initialized", "count", len(registry.States()))
 
 	// Create mutator
-	mutator := webhook.NewMutator(k8sClient, cfg, featureList)
+	mutator := webhook.NewMutatorWithRegistry(k8sClient, cfg, registry)
+
+	// Record what the webhook did as Events on the admitted objects
+	if cfg.Events {
+		broadcaster := record.NewBroadcaster()
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+		defer broadcaster.Shutdown()
+		mutator.SetEventRecorder(broadcaster.NewRecorder(scheme, corev1.EventSource{Component: "vm-feature-manager"}))
+	}
 
 	// Create handler
 	handler := webhook.NewHandler(mutator)
@@ -153,10 +270,113 @@ func main() {
 	// Create server
 	server := webhook.NewServer(cfg, handler)
 
+	// Only report ready while the API server, which features read from, answers
+	server.AddReadinessCheck("apiserver", func(ctx context.Context) error {
+		return clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+	})
+
 	// Set up signal handling
 	sigCtx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	// Re-read the configuration on SIGHUP
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	go watchReload(sigCtx, hangup, &opts, mutator, logLevel)
+
+	// Follow runtime feature states
+	if cfg.FeatureStateFile != "" {
+		go registry.WatchStateFile(sigCtx, cfg.FeatureStateFile, featureStatePollInterval)
+	}
+
+	if readCache != nil {
+		for _, obj := range []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}} {
+			if _, err := readCache.GetInformer(sigCtx, obj); err != nil {
+				logger.Error(err, "Failed to watch Secrets and ConfigMaps")
+				os.Exit(1)
+			}
+		}
+		go func() {
+			if err := readCache.Start(sigCtx); err != nil {
+				logger.Error(err, "Secret and ConfigMap cache stopped")
+				cancel()
+			}
+		}()
+		if !readCache.WaitForCacheSync(sigCtx) {
+			logger.Error(nil, "Failed to sync Secret and ConfigMap cache")
+			os.Exit(1)
+		}
+		logger.Info("Serving Secret and ConfigMap reads from cache")
+	}
+
+	// Count namespace GPU usage from an informer cache of VMs
+	if len(cfg.Features.GPUDevicePlugin.NamespaceQuota) > 0 {
+		vmCache, err := cache.New(restConfig, cache.Options{Scheme: scheme, ReaderFailOnMissingInformer: true})
+		if err != nil {
+			logger.Error(err, "Failed to create VM cache")
+			os.Exit(1)
+		}
+		if _, err := vmCache.GetInformer(sigCtx, &kubevirtv1.VirtualMachine{}); err != nil {
+			logger.Error(err, "Failed to watch VirtualMachines")
+			os.Exit(1)
+		}
+		go func() {
+			if err := vmCache.Start(sigCtx); err != nil {
+				logger.Error(err, "VM cache stopped")
+				cancel()
+			}
+		}()
+		if !vmCache.WaitForCacheSync(sigCtx) {
+			logger.Error(nil, "Failed to sync VM cache")
+			os.Exit(1)
+		}
+		mutator.SetVMLister(vmCache)
+		logger.Info("GPU quotas enabled", "namespaces", len(cfg.Features.GPUDevicePlugin.NamespaceQuota))
+	}
+
+	// Start the optional controllers, under leader election when enabled
+	if cfg.Features.PCIPassthrough.AutoRegister || cfg.Reconcile.Enabled {
+		mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+			Scheme:           scheme,
+			Metrics:          metricsserver.Options{BindAddress: metricsAddr},
+			LeaderElection:   enableLeaderElection,
+			LeaderElectionID: "vm-feature-manager-controllers",
+		})
+		if err != nil {
+			logger.Error(err, "Failed to create controller manager")
+			os.Exit(1)
+		}
+
+		if cfg.Features.PCIPassthrough.AutoRegister {
+			reconciler := controller.NewPCIRegistrationReconciler(mgr.GetClient(), &cfg.Features.PCIPassthrough, cfg.ConfigSource, cfg.KeyPrefix)
+			if err := reconciler.SetupWithManager(mgr); err != nil {
+				logger.Error(err, "Failed to set up PCI registration controller")
+				os.Exit(1)
+			}
+			logger.Info("PCI registration controller enabled",
+				"allowlist", len(cfg.Features.PCIPassthrough.AutoRegisterAllowlist))
+		}
+
+		// Existing VMs go through the same mutator as admission requests
+		if cfg.Reconcile.Enabled {
+			reconciler := controller.NewFeatureReconciler(mgr.GetClient(), mutator, &cfg.Reconcile)
+			if err := reconciler.SetupWithManager(mgr); err != nil {
+				logger.Error(err, "Failed to set up feature reconciler")
+				os.Exit(1)
+			}
+			logger.Info("Feature reconciler enabled", "qps", cfg.Reconcile.QPS, "burst", cfg.Reconcile.Burst)
+		}
+
+		go func() {
+			logger.Info("Starting controllers", "leaderElection", enableLeaderElection)
+			if err := mgr.Start(sigCtx); err != nil {
+				logger.Error(err, "Controllers stopped")
+				cancel()
+			}
+		}()
+	}
+
 	// Start server
 	logger.Info("Starting webhook server", "port", cfg.Port)
 	if err := server.Start(sigCtx); err != nil {
@@ -166,3 +386,124 @@ func main() {
 
 	logger.Info("Webhook server stopped gracefully")
 }
+
+// loadConfig loads the configuration from the config file or the
+// environment, applies the command-line overrides and reads the files the
+// configuration points to
+func loadConfig(opts *options) (*config.Config, error) {
+	var cfg *config.Config
+	var err error
+	if opts.configFile != "" {
+		cfg, err = config.LoadConfigFile(opts.configFile)
+	} else {
+		cfg, err = config.LoadConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Override config with command-line flags if provided
+	if opts.port != 0 {
+		cfg.Port = opts.port
+	}
+	if opts.healthPort != 0 {
+		cfg.HealthPort = opts.healthPort
+	}
+	if opts.certDir != "" {
+		cfg.CertDir = opts.certDir
+	}
+	if opts.errorHandling != "" {
+		cfg.ErrorHandlingMode = opts.errorHandling
+	}
+	if opts.logLevel != "" {
+		cfg.LogLevel = opts.logLevel
+	}
+	if opts.configSource != "" {
+		if !utils.IsValidConfigSource(opts.configSource) {
+			return nil, fmt.Errorf("invalid config-source value: %s (must be 'annotations', 'labels' or 'both')", opts.configSource)
+		}
+		cfg.ConfigSource = utils.WithPrecedence(utils.ParseConfigSource(opts.configSource), cfg.ConfigSourcePrecedence)
+	}
+	if opts.mode != "" {
+		if opts.mode != utils.WebhookModeEnforce && opts.mode != utils.WebhookModeShadow {
+			return nil, fmt.Errorf("invalid mode value: %s (must be 'enforce' or 'shadow')", opts.mode)
+		}
+		cfg.Mode = opts.mode
+	}
+	if opts.enablePprof {
+		cfg.Pprof = true
+	}
+	// Mutations record the release unless WEBHOOK_VERSION names another version
+	if cfg.WebhookVersion == "" {
+		cfg.WebhookVersion = version
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if cfg.Features.PCIPassthrough.ResourceMapFile != "" {
+		resourceMap, err := config.LoadPCIResourceMap(cfg.Features.PCIPassthrough.ResourceMapFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Features.PCIPassthrough.ResourceMap = resourceMap
+	}
+
+	if cfg.FeatureRulesFile != "" {
+		rules, err := config.LoadFeatureRules(cfg.FeatureRulesFile)
+		if err == nil {
+			err = webhook.ValidateFeatureRules(rules)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load feature rules: %w", err)
+		}
+		cfg.FeatureRules = rules
+	}
+
+	return cfg, nil
+}
+
+// watchReload reloads the configuration into the mutator on every signal
+// from hangup until ctx is done. The log level, error handling and built-in feature
+// settings take effect; server, plugin and controller settings need a restart.
+// A configuration that fails to load is logged and the current one kept.
+func watchReload(ctx context.Context, hangup <-chan os.Signal, opts *options, mutator *webhook.Mutator, logLevel uberzap.AtomicLevel) {
+	logger := log.FromContext(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+
+		cfg, err := loadConfig(opts)
+		if err == nil {
+			err = mutator.Reload(cfg, features.Builtin(cfg))
+		}
+		if err != nil {
+			logger.Error(err, "Failed to reload configuration, keeping the current one")
+			continue
+		}
+		logLevel.SetLevel(zapLevel(cfg.LogLevel))
+		logger.Info("Configuration reloaded",
+			"logLevel", cfg.LogLevel,
+			"errorHandlingMode", cfg.ErrorHandlingMode,
+			"configSource", cfg.ConfigSource,
+			"mode", cfg.Mode)
+	}
+}
+
+// zapLevel maps a configured log level to a zap level, defaulting to info
+func zapLevel(level string) zapcore.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return zapcore.DebugLevel
+	case "warn", "warning":
+		return zapcore.WarnLevel
+	case "error":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
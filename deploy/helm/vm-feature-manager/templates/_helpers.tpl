@@ -86,3 +86,69 @@ Issuer name
 {{- define "vm-feature-manager.issuerName" -}}
 {{- include "vm-feature-manager.fullname" . }}-issuer
 {{- end }}
+
+{{/*
+PCI auto-register allowlist as comma-separated resourceName=VENDOR:DEVICE pairs
+*/}}
+{{- define "vm-feature-manager.pciAllowlist" -}}
+{{- $pairs := list }}
+{{- range $name, $selector := .Values.features.pciPassthrough.autoRegister.allowlist }}
+{{- $pairs = append $pairs (printf "%s=%s" $name $selector) }}
+{{- end }}
+{{- join "," $pairs }}
+{{- end }}
+
+{{/*
+Per-namespace PCI devices rendered as namespace=dev1;dev2 pairs for PCI_NAMESPACE_DEVICES
+*/}}
+{{- define "vm-feature-manager.pciNamespaceDevices" -}}
+{{- $pairs := list }}
+{{- range $namespace, $devices := .Values.features.pciPassthrough.namespaceDevices }}
+{{- $pairs = append $pairs (printf "%s=%s" $namespace (join ";" $devices)) }}
+{{- end }}
+{{- join "," $pairs }}
+{{- end }}
+
+{{/*
+Per-namespace GPU quotas rendered as namespace=count pairs for GPU_NAMESPACE_QUOTA
+*/}}
+{{- define "vm-feature-manager.gpuNamespaceQuota" -}}
+{{- $pairs := list }}
+{{- range $namespace, $count := .Values.features.gpuDevicePlugin.namespaceQuota }}
+{{- $pairs = append $pairs (printf "%s=%v" $namespace $count) }}
+{{- end }}
+{{- join "," $pairs }}
+{{- end }}
+
+{{/*
+Deprecated key aliases rendered as old=new pairs for KEY_ALIASES
+*/}}
+{{- define "vm-feature-manager.keyAliases" -}}
+{{- $pairs := list }}
+{{- range $alias, $canonical := .Values.keyAliases.aliases }}
+{{- $pairs = append $pairs (printf "%s=%s" $alias $canonical) }}
+{{- end }}
+{{- join "," $pairs }}
+{{- end }}
+
+{{/*
+gRPC feature plugins rendered as name=endpoint pairs for FEATURE_PLUGINS_GRPC
+*/}}
+{{- define "vm-feature-manager.grpcPlugins" -}}
+{{- $pairs := list }}
+{{- range $name, $endpoint := .Values.plugins.grpc }}
+{{- $pairs = append $pairs (printf "%s=%s" $name $endpoint) }}
+{{- end }}
+{{- join "," $pairs }}
+{{- end }}
+
+{{/*
+Feature namespace restrictions rendered as feature=ns1;ns2 pairs for FEATURE_ALLOWED_NAMESPACES
+*/}}
+{{- define "vm-feature-manager.featureNamespaces" -}}
+{{- $pairs := list }}
+{{- range $feature, $namespaces := .Values.featureNamespaces }}
+{{- $pairs = append $pairs (printf "%s=%s" $feature (join ";" $namespaces)) }}
+{{- end }}
+{{- join "," $pairs }}
+{{- end }}
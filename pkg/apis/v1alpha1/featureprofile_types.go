@@ -0,0 +1,36 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FeatureProfileSpec is a named bundle of feature requests
+type FeatureProfileSpec struct {
+	// Features maps feature names (fully qualified or short) to their values
+	Features map[string]string `json:"features,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=vmprofile
+
+// FeatureProfile is an admin-defined preset VMs select with the
+// vm-feature-manager.io/profile annotation
+type FeatureProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec FeatureProfileSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// FeatureProfileList contains a list of FeatureProfile
+type FeatureProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FeatureProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FeatureProfile{}, &FeatureProfileList{})
+}
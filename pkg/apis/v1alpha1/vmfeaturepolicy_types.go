@@ -0,0 +1,95 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VMFeaturePolicySpec declares feature requests for the VMs a policy matches.
+// Feature names may be fully qualified (vm-feature-manager.io/nested-virt) or
+// short (nested-virt).
+type VMFeaturePolicySpec struct {
+	// Selector limits the policy to VMs with matching labels; empty matches all VMs
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Defaults are feature requests used when the VM doesn't set the feature itself
+	// +optional
+	Defaults map[string]string `json:"defaults,omitempty"`
+
+	// Forced are feature requests that override the VM's own value
+	// +optional
+	Forced map[string]string `json:"forced,omitempty"`
+
+	// Forbidden lists features matching VMs may not request
+	// +optional
+	Forbidden []string `json:"forbidden,omitempty"`
+
+	// Rules enable or deny features based on CEL expressions over the VM
+	// +optional
+	Rules []FeatureRule `json:"rules,omitempty"`
+}
+
+// FeatureRule enables or denies features for VMs its CEL expression matches.
+// The expression sees the VM as vm, its namespace as namespaceName, its guest
+// memory in bytes as memory and its vCPU count as cpus, and may convert
+// quantities with quantity('16Gi').
+type FeatureRule struct {
+	// Name identifies the rule in errors and logs
+	Name string `json:"name"`
+
+	// Expression is a CEL expression that must evaluate to a bool
+	Expression string `json:"expression"`
+
+	// Enable are feature requests forced on matching VMs
+	// +optional
+	Enable map[string]string `json:"enable,omitempty"`
+
+	// Deny lists features matching VMs may not request
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=vmfp
+
+// VMFeaturePolicy applies feature defaults to VMs in its namespace
+type VMFeaturePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VMFeaturePolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VMFeaturePolicyList contains a list of VMFeaturePolicy
+type VMFeaturePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VMFeaturePolicy `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=cvmfp
+
+// ClusterVMFeaturePolicy applies feature defaults to VMs in all namespaces
+type ClusterVMFeaturePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VMFeaturePolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterVMFeaturePolicyList contains a list of ClusterVMFeaturePolicy
+type ClusterVMFeaturePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterVMFeaturePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VMFeaturePolicy{}, &VMFeaturePolicyList{})
+	SchemeBuilder.Register(&ClusterVMFeaturePolicy{}, &ClusterVMFeaturePolicyList{})
+}
@@ -0,0 +1,384 @@
+// Package certs issues the webhook's serving certificate without cert-manager.
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+)
+
+// Secret keys holding the CA bundle, the CA key and the serving key pair
+const (
+	keyCACert = "ca.crt"
+	keyCAKey  = "ca.key"
+	keyCert   = corev1.TLSCertKey
+	keyKey    = corev1.TLSPrivateKeyKey
+)
+
+// Bootstrap makes sure the configured Secret holds a CA and a serving
+// certificate for the webhook Service with at least a third of its validity
+// left, reissuing it otherwise. The serving key pair is written to certDir and
+// the CA bundle is patched into every webhook of the configured
+// MutatingWebhookConfiguration. Concurrent replicas settle on the Secret the
+// first one created.
+//
+// A reissue keeps the CA while it has a third of its validity left, so
+// replicas still serving the previous certificate stay trusted. A replaced CA
+// stays in the bundle until it expires for the same reason.
+func Bootstrap(ctx context.Context, c client.Client, cfg *config.CertBootstrapConfig, certDir string) error {
+	if cfg.Namespace == "" || cfg.SecretName == "" || cfg.ServiceName == "" || cfg.WebhookConfiguration == "" {
+		return errors.New("certificate bootstrap needs a namespace, Secret, Service and webhook configuration")
+	}
+	if cfg.ValidityDays < 1 {
+		return fmt.Errorf("certificate validity of %d days is too short", cfg.ValidityDays)
+	}
+
+	data, err := ensureSecret(ctx, c, cfg)
+	if err != nil {
+		return err
+	}
+	if err := writeKeyPair(certDir, data); err != nil {
+		return err
+	}
+	return patchCABundle(ctx, c, cfg.WebhookConfiguration, data[keyCACert])
+}
+
+// Renew runs Bootstrap every interval until ctx is done, so a certificate
+// nearing expiry, or one another replica reissued, reaches certDir without a
+// restart. Failures are logged and retried at the next interval.
+func Renew(ctx context.Context, c client.Client, cfg *config.CertBootstrapConfig, certDir string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := Bootstrap(ctx, c, cfg, certDir); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to renew serving certificate")
+		}
+	}
+}
+
+// ensureSecret returns the contents of the certificate Secret, creating or
+// reissuing it when it has no usable certificate
+func ensureSecret(ctx context.Context, c client.Client, cfg *config.CertBootstrapConfig) (map[string][]byte, error) {
+	logger := log.FromContext(ctx)
+	dnsNames := serviceDNSNames(cfg.ServiceName, cfg.Namespace)
+	validity := time.Duration(cfg.ValidityDays) * 24 * time.Hour
+
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: cfg.Namespace, Name: cfg.SecretName}, secret)
+	switch {
+	case err == nil:
+		if usable(secret.Data, dnsNames, validity/3) == nil {
+			return secret.Data, nil
+		}
+		data, err := reissue(secret.Data, dnsNames, validity)
+		if err != nil {
+			return nil, err
+		}
+		secret.Data = data
+		if err := c.Update(ctx, secret); err != nil {
+			return nil, fmt.Errorf("failed to update certificate Secret %s/%s: %w", cfg.Namespace, cfg.SecretName, err)
+		}
+		logger.Info("Reissued serving certificate", "secret", cfg.SecretName)
+		return data, nil
+	case apierrors.IsNotFound(err):
+		data, err := issue(dnsNames, validity)
+		if err != nil {
+			return nil, err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: cfg.Namespace, Name: cfg.SecretName},
+			Type:       corev1.SecretTypeTLS,
+			Data:       data,
+		}
+		err = c.Create(ctx, secret)
+		if apierrors.IsAlreadyExists(err) {
+			// Another replica won the race, use its certificate
+			if err := c.Get(ctx, client.ObjectKeyFromObject(secret), secret); err != nil {
+				return nil, err
+			}
+			if err := usable(secret.Data, dnsNames, 0); err != nil {
+				return nil, fmt.Errorf("certificate Secret %s/%s: %w", cfg.Namespace, cfg.SecretName, err)
+			}
+			return secret.Data, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create certificate Secret %s/%s: %w", cfg.Namespace, cfg.SecretName, err)
+		}
+		logger.Info("Issued serving certificate", "secret", cfg.SecretName)
+		return data, nil
+	default:
+		return nil, fmt.Errorf("failed to get certificate Secret %s/%s: %w", cfg.Namespace, cfg.SecretName, err)
+	}
+}
+
+// usable checks that data holds a CA and a matching serving key pair for
+// dnsNames that is valid for at least renewBefore
+func usable(data map[string][]byte, dnsNames []string, renewBefore time.Duration) error {
+	if len(data[keyCACert]) == 0 {
+		return errors.New("no CA certificate")
+	}
+	pair, err := tls.X509KeyPair(data[keyCert], data[keyKey])
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return err
+	}
+	if time.Now().Add(renewBefore).After(cert.NotAfter) {
+		return fmt.Errorf("certificate expires at %s", cert.NotAfter.Format(time.RFC3339))
+	}
+	for _, name := range dnsNames {
+		if !slices.Contains(cert.DNSNames, name) {
+			return fmt.Errorf("certificate is not valid for %s", name)
+		}
+	}
+	return nil
+}
+
+// issue generates a self-signed CA and a serving certificate for dnsNames
+// signed by it, both valid for validity
+func issue(dnsNames []string, validity time.Duration) (map[string][]byte, error) {
+	ca, caKey, err := newCA(validity)
+	if err != nil {
+		return nil, err
+	}
+	caKeyDER, err := x509.MarshalECPrivateKey(caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := issueServing(ca, caKey, dnsNames, validity)
+	if err != nil {
+		return nil, err
+	}
+	data[keyCACert] = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	data[keyCAKey] = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: caKeyDER})
+	return data, nil
+}
+
+// reissue replaces the serving certificate in data. The CA is kept when its
+// key is stored and it is valid for at least a third of validity; otherwise
+// a new one is issued and the unexpired certificates of the old bundle are
+// appended to the new one.
+func reissue(data map[string][]byte, dnsNames []string, validity time.Duration) (map[string][]byte, error) {
+	ca, caKey, err := parseCA(data)
+	if err == nil && time.Now().Add(validity/3).Before(ca.NotAfter) {
+		serving, err := issueServing(ca, caKey, dnsNames, validity)
+		if err != nil {
+			return nil, err
+		}
+		serving[keyCACert] = data[keyCACert]
+		serving[keyCAKey] = data[keyCAKey]
+		return serving, nil
+	}
+
+	issued, err := issue(dnsNames, validity)
+	if err != nil {
+		return nil, err
+	}
+	issued[keyCACert] = append(issued[keyCACert], unexpiredCertificates(data[keyCACert])...)
+	return issued, nil
+}
+
+// newCA generates a self-signed CA valid for validity
+func newCA(validity time.Duration) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	now := time.Now()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: "vm-feature-manager-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ca, caKey, nil
+}
+
+// issueServing generates a serving key pair for dnsNames signed by ca, valid
+// for validity but not beyond the CA
+func issueServing(ca *x509.Certificate, caKey *ecdsa.PrivateKey, dnsNames []string, validity time.Duration) (map[string][]byte, error) {
+	now := time.Now()
+	notAfter := now.Add(validity)
+	if notAfter.After(ca.NotAfter) {
+		notAfter = ca.NotAfter
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create serving certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{
+		keyCert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyKey:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// parseCA returns the first certificate of the CA bundle in data and its key
+func parseCA(data map[string][]byte) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certBlock, _ := pem.Decode(data[keyCACert])
+	keyBlock, _ := pem.Decode(data[keyCAKey])
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, errors.New("no CA key pair")
+	}
+	ca, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	caKey, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !caKey.PublicKey.Equal(ca.PublicKey) {
+		return nil, nil, errors.New("CA key does not match the CA certificate")
+	}
+	return ca, caKey, nil
+}
+
+// unexpiredCertificates returns the PEM certificates of bundle that have not
+// expired yet
+func unexpiredCertificates(bundle []byte) []byte {
+	var kept []byte
+	for {
+		var block *pem.Block
+		block, bundle = pem.Decode(bundle)
+		if block == nil {
+			return kept
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || time.Now().After(cert.NotAfter) {
+			continue
+		}
+		kept = append(kept, pem.EncodeToMemory(block)...)
+	}
+}
+
+// serialNumber returns a random 128-bit certificate serial number
+func serialNumber() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
+
+// serviceDNSNames lists the names the apiserver may use to reach the Service
+func serviceDNSNames(service, namespace string) []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", service, namespace),
+		service,
+		fmt.Sprintf("%s.%s", service, namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", service, namespace),
+	}
+}
+
+// writeKeyPair writes the serving key pair where the server loads it from.
+// Unchanged files are left alone, and changed ones are replaced by renaming
+// so the server never reads a partly written file.
+func writeKeyPair(certDir string, data map[string][]byte) error {
+	if err := os.MkdirAll(certDir, 0o755); err != nil {
+		return err
+	}
+	if err := replaceFile(filepath.Join(certDir, keyKey), data[keyKey], 0o600); err != nil {
+		return err
+	}
+	return replaceFile(filepath.Join(certDir, keyCert), data[keyCert], 0o644)
+}
+
+// replaceFile atomically replaces path with content unless it already holds it
+func replaceFile(path string, content []byte, perm os.FileMode) error {
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, content) {
+		return nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// patchCABundle sets caBundle on every webhook of the named configuration
+func patchCABundle(ctx context.Context, c client.Client, name string, caBundle []byte) error {
+	webhookConfig := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, webhookConfig); err != nil {
+		return fmt.Errorf("failed to get MutatingWebhookConfiguration %s: %w", name, err)
+	}
+
+	base := webhookConfig.DeepCopy()
+	changed := false
+	for i := range webhookConfig.Webhooks {
+		if !bytes.Equal(webhookConfig.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			webhookConfig.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := c.Patch(ctx, webhookConfig, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+		return fmt.Errorf("failed to patch caBundle of MutatingWebhookConfiguration %s: %w", name, err)
+	}
+	log.FromContext(ctx).Info("Patched webhook CA bundle", "webhookConfiguration", name)
+	return nil
+}
@@ -0,0 +1,147 @@
+package certs_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/certs"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+)
+
+var _ = Describe("Bootstrap", func() {
+	var (
+		ctx       context.Context
+		cfg       *config.CertBootstrapConfig
+		certDir   string
+		k8sClient client.Client
+	)
+
+	secretKey := client.ObjectKey{Namespace: "vm-feature-manager", Name: "webhook-tls"}
+
+	getSecret := func() *corev1.Secret {
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, secretKey, secret)).To(Succeed())
+		return secret
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		certDir = filepath.Join(GinkgoT().TempDir(), "certs")
+		cfg = &config.CertBootstrapConfig{
+			Enabled:              true,
+			Namespace:            secretKey.Namespace,
+			SecretName:           secretKey.Name,
+			ServiceName:          "vm-feature-manager-webhook",
+			WebhookConfiguration: "vm-feature-manager",
+			ValidityDays:         365,
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		Expect(admissionregistrationv1.AddToScheme(scheme)).To(Succeed())
+		webhookConfig := &admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "vm-feature-manager"},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{
+				{Name: "vm-feature-manager.vm-feature-manager.svc"},
+			},
+		}
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(webhookConfig).Build()
+	})
+
+	It("should issue a certificate, write it and patch the CA bundle", func() {
+		Expect(certs.Bootstrap(ctx, k8sClient, cfg, certDir)).To(Succeed())
+
+		secret := getSecret()
+		Expect(secret.Type).To(Equal(corev1.SecretTypeTLS))
+		Expect(secret.Data).To(HaveKey("ca.crt"))
+
+		_, err := tls.LoadX509KeyPair(filepath.Join(certDir, "tls.crt"), filepath.Join(certDir, "tls.key"))
+		Expect(err).ToNot(HaveOccurred())
+		written, err := os.ReadFile(filepath.Join(certDir, "tls.crt"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(written).To(Equal(secret.Data["tls.crt"]))
+
+		webhookConfig := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "vm-feature-manager"}, webhookConfig)).To(Succeed())
+		Expect(webhookConfig.Webhooks[0].ClientConfig.CABundle).To(Equal(secret.Data["ca.crt"]))
+	})
+
+	It("should issue a certificate the CA verifies for the Service", func() {
+		Expect(certs.Bootstrap(ctx, k8sClient, cfg, certDir)).To(Succeed())
+
+		secret := getSecret()
+		roots := x509.NewCertPool()
+		Expect(roots.AppendCertsFromPEM(secret.Data["ca.crt"])).To(BeTrue())
+		block, _ := pem.Decode(secret.Data["tls.crt"])
+		cert, err := x509.ParseCertificate(block.Bytes)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = cert.Verify(x509.VerifyOptions{
+			DNSName: "vm-feature-manager-webhook.vm-feature-manager.svc",
+			Roots:   roots,
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should keep a usable certificate", func() {
+		Expect(certs.Bootstrap(ctx, k8sClient, cfg, certDir)).To(Succeed())
+		issued := getSecret().Data
+
+		Expect(certs.Bootstrap(ctx, k8sClient, cfg, certDir)).To(Succeed())
+		Expect(getSecret().Data).To(Equal(issued))
+	})
+
+	It("should reissue a certificate for another Service", func() {
+		Expect(certs.Bootstrap(ctx, k8sClient, cfg, certDir)).To(Succeed())
+		issued := getSecret().Data
+
+		cfg.ServiceName = "renamed-webhook"
+		Expect(certs.Bootstrap(ctx, k8sClient, cfg, certDir)).To(Succeed())
+		reissued := getSecret().Data
+		Expect(reissued["tls.crt"]).ToNot(Equal(issued["tls.crt"]))
+		Expect(reissued["ca.crt"]).To(Equal(issued["ca.crt"]))
+		Expect(reissued["ca.key"]).To(Equal(issued["ca.key"]))
+	})
+
+	It("should keep trusting the old CA when the CA is replaced", func() {
+		Expect(certs.Bootstrap(ctx, k8sClient, cfg, certDir)).To(Succeed())
+		secret := getSecret()
+		issued := secret.Data["ca.crt"]
+		// Secrets issued before the CA key was stored can't sign a new certificate
+		delete(secret.Data, "ca.key")
+		Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+
+		cfg.ServiceName = "renamed-webhook"
+		Expect(certs.Bootstrap(ctx, k8sClient, cfg, certDir)).To(Succeed())
+		reissued := getSecret().Data
+		Expect(reissued).To(HaveKey("ca.key"))
+		Expect(reissued["ca.crt"]).To(HaveSuffix(string(issued)))
+		Expect(len(reissued["ca.crt"])).To(BeNumerically(">", len(issued)))
+
+		webhookConfig := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "vm-feature-manager"}, webhookConfig)).To(Succeed())
+		Expect(webhookConfig.Webhooks[0].ClientConfig.CABundle).To(Equal(reissued["ca.crt"]))
+	})
+
+	It("should require the Secret, Service and webhook configuration", func() {
+		cfg.WebhookConfiguration = ""
+		Expect(certs.Bootstrap(ctx, k8sClient, cfg, certDir)).To(MatchError(ContainSubstring("webhook configuration")))
+	})
+
+	It("should fail when the webhook configuration is missing", func() {
+		cfg.WebhookConfiguration = "missing"
+		Expect(certs.Bootstrap(ctx, k8sClient, cfg, certDir)).To(MatchError(ContainSubstring("MutatingWebhookConfiguration missing")))
+	})
+})
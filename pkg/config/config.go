@@ -2,118 +2,737 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
 
+	"sigs.k8s.io/yaml"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/apis/v1alpha1"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
 
 // Config holds the webhook configuration
 type Config struct {
 	// Server configuration
-	Port    int
-	CertDir string
+	Port    int    `json:"port"`
+	CertDir string `json:"certDir"`
+	// HealthPort, when set, serves the probe, metrics and debug endpoints
+	// over plain HTTP, leaving the TLS port to admission requests
+	HealthPort int `json:"healthPort"`
+	// ClientCAFile, when set, makes /mutate require a client certificate
+	// issued by one of its CAs, e.g. the apiserver's
+	ClientCAFile string `json:"clientCAFile"`
+	// CertBootstrap issues the serving certificate at startup instead of
+	// relying on one mounted into CertDir
+	CertBootstrap CertBootstrapConfig `json:"certBootstrap"`
 
 	// Logging
-	LogLevel string
+	LogLevel string `json:"logLevel"`
 
 	// Error handling
-	ErrorHandlingMode string
+	ErrorHandlingMode string `json:"errorHandlingMode"`
+
+	// Configuration source: annotations, labels or both
+	ConfigSource utils.ConfigSource `json:"configSource"`
+	// ConfigSourcePrecedence picks the winner (annotations or labels) for the "both" source
+	ConfigSourcePrecedence string `json:"configSourcePrecedence"`
+
+	// KeyPrefix replaces vm-feature-manager.io/ in all feature keys, so
+	// independent deployments can coexist
+	KeyPrefix string `json:"keyPrefix"`
 
-	// Configuration source: annotations or labels
-	ConfigSource utils.ConfigSource
+	// KeyAliases maps deprecated keys to the canonical key (or feature name)
+	// they stand for; RewriteKeyAliases renames them in the patch
+	KeyAliases        map[string]string `json:"keyAliases"`
+	RewriteKeyAliases bool              `json:"rewriteKeyAliases"`
 
 	// Features configuration
-	Features FeaturesConfig
+	Features FeaturesConfig `json:"features"`
+
+	// Tracking; an empty WebhookVersion records the version of the binary
+	AddTrackingAnnotations bool   `json:"addTrackingAnnotations"`
+	WebhookVersion         string `json:"webhookVersion"`
+
+	// Dry-run: when strict, dry-run requests are allowed without any patch
+	DryRunStrict bool `json:"dryRunStrict"`
+
+	// Mode is "enforce" or "shadow", where patches and rejections are only
+	// logged and counted
+	Mode string `json:"mode"`
+
+	// FeaturePolicies enables VMFeaturePolicy and ClusterVMFeaturePolicy evaluation
+	FeaturePolicies bool `json:"featurePolicies"`
+
+	// NamespaceDefaults treats feature keys on the VM's Namespace as defaults
+	NamespaceDefaults bool `json:"namespaceDefaults"`
+
+	// FeatureNamespaces restricts features to the listed namespaces; VMs in
+	// other namespaces may not request them
+	FeatureNamespaces map[string][]string `json:"featureNamespaces"`
+
+	// UserdataDirectives reads feature directives from cloud-init userdata;
+	// disabling it skips userdata parsing and Secret reads entirely
+	UserdataDirectives bool `json:"userdataDirectives"`
+
+	// StripDirectiveComments removes "# @kubevirt-feature" comment lines from
+	// inline userdata and networkData once their directives are read
+	StripDirectiveComments bool `json:"stripDirectiveComments"`
+
+	// CachedReads serves Secret and ConfigMap reads from an informer cache,
+	// which needs list and watch on both cluster-wide
+	CachedReads bool `json:"cachedReads"`
+
+	// Events records what the webhook did to each object as Kubernetes Events on it
+	Events bool `json:"events"`
+
+	// Pprof serves the net/http/pprof profiles under /debug/pprof on the
+	// webhook port
+	Pprof bool `json:"pprof"`
+
+	// UserdataSecrets limits the Secrets userdata directives are read from
+	UserdataSecrets UserdataSecretsConfig `json:"userdataSecrets"`
+
+	// Timeouts bound admission well below the apiserver's webhook timeout
+	Timeouts TimeoutsConfig `json:"timeouts"`
+
+	// Concurrency bounds how many admission requests are handled at once
+	Concurrency ConcurrencyConfig `json:"concurrency"`
+
+	// Server tunes the HTTPS server
+	Server ServerConfig `json:"server"`
+
+	// Reconcile applies requested features to VMs that exist already
+	Reconcile ReconcileConfig `json:"reconcile"`
+
+	// RBACFeatures may only be requested by users allowed to use
+	// features.vm-feature-manager.io/<feature>, checked with a SubjectAccessReview
+	RBACFeatures []string `json:"rbacFeatures"`
+
+	// FeatureRulesFile points to a YAML list of CEL feature rules applied to
+	// every VM; FeatureRules holds its contents.
+	FeatureRulesFile string                 `json:"featureRulesFile"`
+	FeatureRules     []v1alpha1.FeatureRule `json:"-"`
+
+	// FeatureStateFile points to a YAML map of feature name to enabled,
+	// re-read at runtime to switch features off without a restart
+	FeatureStateFile string `json:"featureStateFile"`
+
+	// Plugins configures features served outside the webhook
+	Plugins PluginsConfig `json:"plugins"`
+}
+
+// UserdataSecretsConfig holds the userdata Secret guard and parsing configuration
+type UserdataSecretsConfig struct {
+	// Guard is "none", "label" (only Secrets labeled
+	// vm-feature-manager.io/userdata=allowed) or "namespace-allowlist"
+	Guard string `json:"guard"`
+	// Namespaces userdata Secrets may be read from in namespace-allowlist mode
+	Namespaces []string `json:"namespaces"`
+	// Keys are tried in order when reading userdata from a Secret
+	Keys []string `json:"keys"`
+	// MaxSize is the largest userdata, in bytes, directives are read from;
+	// 0 keeps the 64KiB default
+	MaxSize int `json:"maxSize"`
+	// CacheSize is how many Secrets are cached; 0 disables the cache
+	CacheSize int `json:"cacheSize"`
+	// CacheTTLSeconds is how long a cached Secret is used before it is fetched again
+	CacheTTLSeconds int `json:"cacheTTLSeconds"`
+}
+
+// TimeoutsConfig holds the admission deadlines
+type TimeoutsConfig struct {
+	// RequestSeconds bounds the handling of a whole request; 0 disables it
+	RequestSeconds int `json:"requestSeconds"`
+	// FeatureSeconds bounds the validation and application of each feature; 0 disables it
+	FeatureSeconds int `json:"featureSeconds"`
+	// OnTimeout is "allow" (admit unmutated with a warning) or "reject" when
+	// RequestSeconds is exceeded
+	OnTimeout string `json:"onTimeout"`
+}
+
+// ConcurrencyConfig holds the admission concurrency limit
+type ConcurrencyConfig struct {
+	// MaxInFlight caps concurrently handled requests; 0 disables the limit
+	MaxInFlight int `json:"maxInFlight"`
+	// WaitMilliseconds is how long a request waits for a free slot
+	WaitMilliseconds int `json:"waitMilliseconds"`
+	// OnSaturated is "allow" (admit unmutated with a warning) or "reject"
+	// when no slot frees up in time
+	OnSaturated string `json:"onSaturated"`
+}
+
+// ServerConfig holds the HTTPS server limits
+type ServerConfig struct {
+	ReadTimeoutSeconds  int `json:"readTimeoutSeconds"`
+	WriteTimeoutSeconds int `json:"writeTimeoutSeconds"`
+	IdleTimeoutSeconds  int `json:"idleTimeoutSeconds"`
+	// MaxHeaderBytes caps request headers; 0 keeps Go's 1MiB default
+	MaxHeaderBytes int `json:"maxHeaderBytes"`
+	// MaxRequestBytes caps AdmissionReview bodies, rejecting larger ones
+	// before they are read; 0 disables the limit
+	MaxRequestBytes int `json:"maxRequestBytes"`
+	// HTTP2 serves HTTP/2 besides HTTP/1.1
+	HTTP2 bool `json:"http2"`
+}
+
+// ReconcileConfig holds the background reconciliation of existing VMs
+type ReconcileConfig struct {
+	// Enabled runs the controller that passes existing VMs through the
+	// admission pipeline and patches them with the result
+	Enabled bool `json:"enabled"`
+	// QPS and Burst bound how fast VMs are reconciled
+	QPS   int `json:"qps"`
+	Burst int `json:"burst"`
+}
+
+// CertBootstrapConfig holds the built-in certificate bootstrap configuration
+type CertBootstrapConfig struct {
+	// Enabled generates a self-signed CA and serving certificate, kept in a
+	// Secret, and patches the CA into the webhook configuration
+	Enabled bool `json:"enabled"`
+	// Namespace of the webhook Service and the certificate Secret
+	Namespace string `json:"namespace"`
+	// SecretName is the Secret the CA and serving certificate are kept in
+	SecretName string `json:"secretName"`
+	// ServiceName is the webhook Service the certificate is issued for
+	ServiceName string `json:"serviceName"`
+	// WebhookConfiguration is the MutatingWebhookConfiguration whose caBundle is patched
+	WebhookConfiguration string `json:"webhookConfiguration"`
+	// ValidityDays is how long generated certificates are valid; they are
+	// reissued at startup once less than a third of it remains
+	ValidityDays int `json:"validityDays"`
+}
 
-	// Tracking
-	AddTrackingAnnotations bool
-	WebhookVersion         string
+// PluginsConfig holds external feature plugin configuration
+type PluginsConfig struct {
+	// GRPC maps feature names to the gRPC endpoints serving them
+	GRPC map[string]string `json:"grpc"`
+	// WASMDir holds *.wasm feature modules, e.g. a mounted ConfigMap or OCI image volume
+	WASMDir string `json:"wasmDir"`
+	// WASMMemoryLimitMB caps the memory of each WASM module
+	WASMMemoryLimitMB int `json:"wasmMemoryLimitMB"`
+	// ExecDir holds executables run as exec hook features
+	ExecDir string `json:"execDir"`
+	// ExecMaxOutputKB caps the patch an exec hook may print
+	ExecMaxOutputKB int `json:"execMaxOutputKB"`
+	// TimeoutSeconds bounds each call to a plugin
+	TimeoutSeconds int `json:"timeoutSeconds"`
 }
 
 // FeaturesConfig holds feature-specific configuration
 type FeaturesConfig struct {
-	NestedVirtualization NestedVirtConfig
-	VBiosInjection       VBiosConfig
-	PCIPassthrough       PCIPassthroughConfig
-	GPUDevicePlugin      GPUDevicePluginConfig
+	NestedVirtualization NestedVirtConfig          `json:"nestedVirtualization"`
+	VBiosInjection       VBiosConfig               `json:"vbiosInjection"`
+	PCIPassthrough       PCIPassthroughConfig      `json:"pciPassthrough"`
+	GPUDevicePlugin      GPUDevicePluginConfig     `json:"gpuDevicePlugin"`
+	VGpu                 VGpuConfig                `json:"vgpu"`
+	HyperV               HyperVConfig              `json:"hyperV"`
+	CPUModel             CPUModelConfig            `json:"cpuModel"`
+	WindowsPreset        WindowsPresetConfig       `json:"windowsPreset"`
+	MetadataPropagation  MetadataPropagationConfig `json:"metadataPropagation"`
 }
 
 // NestedVirtConfig holds nested virtualization configuration
 type NestedVirtConfig struct {
-	Enabled       bool
-	AutoDetectCPU bool
+	Enabled       bool `json:"enabled"`
+	AutoDetectCPU bool `json:"autoDetectCPU"`
+	// DefaultCPUFeature (svm or vmx) is used when detection is off or inconclusive
+	DefaultCPUFeature string `json:"defaultCPUFeature"`
+	// OptionalCPUFeatures adds both vmx and svm with the optional policy unless pinned
+	OptionalCPUFeatures bool `json:"optionalCPUFeatures"`
+	// NodeAffinity requires KubeVirt's cpu-feature node label for the chosen feature
+	NodeAffinity bool `json:"nodeAffinity"`
 }
 
 // VBiosConfig holds vBIOS injection configuration
 type VBiosConfig struct {
-	Enabled                   bool
-	SidecarImage              string
-	SidecarImageOverride      string
-	SidecarVersion            string
-	SourceConfigMapKey        string
-	HookConfigMapNameTemplate string
-	VBiosPath                 string
-	ValidateSidecarTools      bool
-	RequiredTools             []string
+	Enabled                   bool     `json:"enabled"`
+	SidecarImage              string   `json:"sidecarImage"`
+	SidecarImageOverride      string   `json:"sidecarImageOverride"`
+	SidecarVersion            string   `json:"sidecarVersion"`
+	SourceConfigMapKey        string   `json:"sourceConfigMapKey"`
+	HookConfigMapNameTemplate string   `json:"hookConfigMapNameTemplate"`
+	HookMode                  string   `json:"hookMode"`
+	VBiosPath                 string   `json:"vbiosPath"`
+	ValidateSidecarTools      bool     `json:"validateSidecarTools"`
+	RequiredTools             []string `json:"requiredTools"`
+	AllowedSourceNamespaces   []string `json:"allowedSourceNamespaces"`
 }
 
 // PCIPassthroughConfig holds PCI passthrough configuration
 type PCIPassthroughConfig struct {
-	Enabled       bool
-	ErrorHandling string
-	MaxDevices    int
+	Enabled       bool   `json:"enabled"`
+	ErrorHandling string `json:"errorHandling"`
+	MaxDevices    int    `json:"maxDevices"`
+
+	// ResourceMapFile points to a YAML file mapping PCI addresses or aliases
+	// to permittedHostDevices resource names; ResourceMap holds its contents.
+	ResourceMapFile string            `json:"resourceMapFile"`
+	ResourceMap     map[string]string `json:"-"`
+
+	// AutoRegister enables the controller that adds requested devices to the
+	// KubeVirt CR; only resource names in AutoRegisterAllowlist (mapped to
+	// their VENDOR:DEVICE selector) are registered.
+	AutoRegister          bool              `json:"autoRegister"`
+	AutoRegisterAllowlist map[string]string `json:"autoRegisterAllowlist"`
+
+	// NodeSelector labels are required on nodes when devices are attached
+	NodeSelector map[string]string `json:"nodeSelector"`
+
+	// NamespaceDevices lists the PCI addresses, aliases or resource names VMs
+	// in each namespace may request; "*" covers namespaces not listed. VMs in
+	// other namespaces are unrestricted.
+	NamespaceDevices map[string][]string `json:"namespaceDevices"`
 }
 
 // GPUDevicePluginConfig holds GPU device plugin configuration
 type GPUDevicePluginConfig struct {
-	Enabled        bool
-	AllowedPlugins []string
+	Enabled        bool              `json:"enabled"`
+	AllowedPlugins []string          `json:"allowedPlugins"`
+	MaxDevices     int               `json:"maxDevices"`
+	NodeSelector   map[string]string `json:"nodeSelector"`
+
+	// NamespaceQuota caps the GPUs requested by all VMs in a namespace; "*"
+	// covers namespaces not listed. VMs in other namespaces are unlimited.
+	NamespaceQuota map[string]int `json:"namespaceQuota"`
+}
+
+// VGpuConfig holds vGPU (mediated device) configuration
+type VGpuConfig struct {
+	Enabled      bool              `json:"enabled"`
+	NodeSelector map[string]string `json:"nodeSelector"`
+}
+
+// HyperVConfig holds Hyper-V enlightenments configuration
+type HyperVConfig struct {
+	Enabled        bool     `json:"enabled"`
+	Enlightenments []string `json:"enlightenments"`
 }
 
-// LoadConfig loads configuration from environment variables
-func LoadConfig() *Config {
+// CPUModelConfig holds CPU model selection configuration
+type CPUModelConfig struct {
+	Enabled       bool     `json:"enabled"`
+	AllowedModels []string `json:"allowedModels"`
+}
+
+// WindowsPresetConfig holds the Windows OS preset configuration.
+// Each item of the preset can be turned off individually.
+type WindowsPresetConfig struct {
+	Enabled      bool   `json:"enabled"`
+	HyperV       bool   `json:"hyperV"`
+	ClockTimers  bool   `json:"clockTimers"`
+	TPM          bool   `json:"tpm"`
+	SecureBoot   bool   `json:"secureBoot"`
+	NetworkModel string `json:"networkModel"`
+}
+
+// MetadataPropagationConfig holds VM label/annotation propagation configuration
+type MetadataPropagationConfig struct {
+	Enabled            bool     `json:"enabled"`
+	LabelPrefixes      []string `json:"labelPrefixes"`
+	AnnotationPrefixes []string `json:"annotationPrefixes"`
+}
+
+// DefaultConfig returns the configuration used for settings that are neither
+// in the config file nor in the environment
+func DefaultConfig() *Config {
 	return &Config{
-		Port:                   getEnvAsInt("PORT", 8443),
-		CertDir:                getEnv("CERT_DIR", "/etc/webhook/certs"),
-		LogLevel:               getEnv("LOG_LEVEL", "info"),
-		ErrorHandlingMode:      getEnv("ERROR_HANDLING_MODE", utils.ErrorHandlingReject),
-		ConfigSource:           utils.ParseConfigSource(getEnv("CONFIG_SOURCE", string(utils.ConfigSourceAnnotations))),
-		AddTrackingAnnotations: getEnvAsBool("ADD_TRACKING_ANNOTATIONS", true),
-		WebhookVersion:         getEnv("WEBHOOK_VERSION", "v0.1.0"),
+		Port:                   8443,
+		CertDir:                "/etc/webhook/certs",
+		LogLevel:               "info",
+		ErrorHandlingMode:      utils.ErrorHandlingReject,
+		ConfigSource:           utils.ConfigSourceAnnotations,
+		ConfigSourcePrecedence: string(utils.ConfigSourceAnnotations),
+		KeyPrefix:              utils.DefaultKeyPrefix,
+		KeyAliases:             map[string]string{},
+		FeatureNamespaces:      map[string][]string{},
+		RBACFeatures:           []string{},
+		AddTrackingAnnotations: true,
+		UserdataDirectives:     true,
+		Events:                 true,
+		Mode:                   utils.WebhookModeEnforce,
+		CertBootstrap: CertBootstrapConfig{
+			ValidityDays: 365,
+		},
+		Reconcile: ReconcileConfig{
+			QPS:   5,
+			Burst: 10,
+		},
+		UserdataSecrets: UserdataSecretsConfig{
+			Guard:           utils.UserdataSecretGuardNone,
+			Namespaces:      []string{},
+			Keys:            []string{"userdata", "userData", "user-data"},
+			MaxSize:         65536,
+			CacheSize:       256,
+			CacheTTLSeconds: 30,
+		},
+		Timeouts: TimeoutsConfig{
+			RequestSeconds: 5,
+			FeatureSeconds: 2,
+			OnTimeout:      utils.TimeoutPolicyAllow,
+		},
+		Concurrency: ConcurrencyConfig{
+			WaitMilliseconds: 100,
+			OnSaturated:      utils.SaturationPolicyAllow,
+		},
+		Server: ServerConfig{
+			ReadTimeoutSeconds:  10,
+			WriteTimeoutSeconds: 10,
+			IdleTimeoutSeconds:  60,
+			MaxRequestBytes:     4 << 20,
+		},
+		Plugins: PluginsConfig{
+			GRPC:              map[string]string{},
+			WASMMemoryLimitMB: 128,
+			ExecMaxOutputKB:   1024,
+			TimeoutSeconds:    5,
+		},
 		Features: FeaturesConfig{
 			NestedVirtualization: NestedVirtConfig{
-				Enabled:       getEnvAsBool("FEATURE_NESTED_VIRT_ENABLED", true),
-				AutoDetectCPU: getEnvAsBool("FEATURE_NESTED_VIRT_AUTO_DETECT", true),
+				Enabled:           true,
+				AutoDetectCPU:     true,
+				DefaultCPUFeature: utils.CPUFeatureSVM,
 			},
 			VBiosInjection: VBiosConfig{
-				Enabled:                   getEnvAsBool("FEATURE_VBIOS_ENABLED", true),
-				SidecarImage:              getEnv("VBIOS_SIDECAR_IMAGE", ""),
-				SidecarImageOverride:      getEnv("VBIOS_SIDECAR_IMAGE_OVERRIDE", utils.DefaultSidecarImage),
-				SidecarVersion:            getEnv("VBIOS_SIDECAR_VERSION", utils.SidecarHookVersion),
-				SourceConfigMapKey:        getEnv("VBIOS_SOURCE_CM_KEY", utils.VBiosConfigMapKey),
-				HookConfigMapNameTemplate: getEnv("VBIOS_HOOK_CM_TEMPLATE", "{{ .VMName }}-vbios-hook"),
-				VBiosPath:                 getEnv("VBIOS_PATH", "/tmp/vbios.rom"),
-				ValidateSidecarTools:      getEnvAsBool("VBIOS_VALIDATE_TOOLS", true),
-				RequiredTools:             getEnvAsSlice("VBIOS_REQUIRED_TOOLS", []string{"xmlstarlet", "base64"}),
+				Enabled:                   true,
+				SidecarImageOverride:      utils.DefaultSidecarImage,
+				SidecarVersion:            utils.SidecarHookVersionAuto,
+				SourceConfigMapKey:        utils.VBiosConfigMapKey,
+				HookConfigMapNameTemplate: utils.DefaultVBiosHookConfigMapTemplate,
+				HookMode:                  utils.VBiosHookModeImage,
+				VBiosPath:                 "/tmp/vbios.rom",
+				ValidateSidecarTools:      true,
+				RequiredTools:             []string{"xmlstarlet", "base64"},
+				AllowedSourceNamespaces:   []string{},
 			},
 			PCIPassthrough: PCIPassthroughConfig{
-				Enabled:       getEnvAsBool("FEATURE_PCI_PASSTHROUGH_ENABLED", true),
-				ErrorHandling: getEnv("PCI_PASSTHROUGH_ERROR_HANDLING", utils.ErrorHandlingReject),
-				MaxDevices:    getEnvAsInt("PCI_MAX_DEVICES", 8),
+				Enabled:               true,
+				ErrorHandling:         utils.ErrorHandlingReject,
+				MaxDevices:            8,
+				AutoRegisterAllowlist: map[string]string{},
+				NodeSelector:          map[string]string{},
+				NamespaceDevices:      map[string][]string{},
 			},
 			GPUDevicePlugin: GPUDevicePluginConfig{
-				Enabled: getEnvAsBool("FEATURE_GPU_DEVICE_PLUGIN_ENABLED", true),
-				AllowedPlugins: getEnvAsSlice("GPU_ALLOWED_PLUGINS", []string{
+				Enabled: true,
+				AllowedPlugins: []string{
 					"kubevirt.io/integrated-gpu",
 					"nvidia.com/gpu",
-				}),
+				},
+				MaxDevices:     8,
+				NodeSelector:   map[string]string{},
+				NamespaceQuota: map[string]int{},
+			},
+			VGpu: VGpuConfig{
+				Enabled:      true,
+				NodeSelector: map[string]string{},
+			},
+			HyperV: HyperVConfig{
+				Enabled: true,
+				Enlightenments: []string{
+					"relaxed", "vapic", "spinlocks", "vpindex", "runtime",
+					"synic", "stimer", "reset", "frequencies", "reenlightenment", "ipi",
+				},
+			},
+			CPUModel: CPUModelConfig{
+				Enabled: true,
+				AllowedModels: []string{
+					"host-passthrough",
+					"host-model",
+				},
+			},
+			WindowsPreset: WindowsPresetConfig{
+				Enabled:      true,
+				HyperV:       true,
+				ClockTimers:  true,
+				TPM:          true,
+				SecureBoot:   true,
+				NetworkModel: "e1000e",
+			},
+			MetadataPropagation: MetadataPropagationConfig{
+				Enabled:            true,
+				LabelPrefixes:      []string{"app.kubernetes.io/"},
+				AnnotationPrefixes: []string{},
 			},
 		},
 	}
 }
 
+// LoadConfig loads configuration from environment variables. Invalid values
+// are rejected.
+func LoadConfig() (*Config, error) {
+	cfg := applyEnv(DefaultConfig())
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// LoadConfigFile loads configuration from a YAML or JSON file, with
+// environment variables overriding the settings it contains. Unknown keys
+// and invalid values are rejected.
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	cfg := DefaultConfig()
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	cfg = applyEnv(cfg)
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration from %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks the settings that have a fixed set of values
+func (c *Config) Validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("port %d out of range", c.Port)
+	}
+	if c.HealthPort < 0 || c.HealthPort > 65535 || (c.HealthPort != 0 && c.HealthPort == c.Port) {
+		return fmt.Errorf("healthPort %d out of range or equal to port", c.HealthPort)
+	}
+	if s := c.Server; s.ReadTimeoutSeconds < 0 || s.WriteTimeoutSeconds < 0 || s.IdleTimeoutSeconds < 0 || s.MaxHeaderBytes < 0 || s.MaxRequestBytes < 0 {
+		return fmt.Errorf("server timeouts and limits must not be negative")
+	}
+	switch c.ErrorHandlingMode {
+	case utils.ErrorHandlingReject, utils.ErrorHandlingAllowAndLog, utils.ErrorHandlingStripLabel, utils.ErrorHandlingContinue:
+	default:
+		return fmt.Errorf("unknown errorHandlingMode %q", c.ErrorHandlingMode)
+	}
+	if !utils.IsValidConfigSource(string(c.ConfigSource)) {
+		return fmt.Errorf("unknown configSource %q", c.ConfigSource)
+	}
+	switch utils.ConfigSource(c.ConfigSourcePrecedence) {
+	case utils.ConfigSourceAnnotations, utils.ConfigSourceLabels:
+	default:
+		return fmt.Errorf("unknown configSourcePrecedence %q", c.ConfigSourcePrecedence)
+	}
+	switch c.UserdataSecrets.Guard {
+	case utils.UserdataSecretGuardNone, utils.UserdataSecretGuardLabel, utils.UserdataSecretGuardNamespaceAllowlist:
+	default:
+		return fmt.Errorf("unknown userdataSecrets.guard %q", c.UserdataSecrets.Guard)
+	}
+	switch c.Timeouts.OnTimeout {
+	case utils.TimeoutPolicyAllow, utils.TimeoutPolicyReject:
+	default:
+		return fmt.Errorf("unknown timeouts.onTimeout %q", c.Timeouts.OnTimeout)
+	}
+	if c.Reconcile.Enabled && (c.Reconcile.QPS < 1 || c.Reconcile.Burst < 1) {
+		return fmt.Errorf("reconcile.qps and reconcile.burst must be at least 1")
+	}
+	switch c.Mode {
+	case utils.WebhookModeEnforce, utils.WebhookModeShadow:
+	default:
+		return fmt.Errorf("unknown mode %q", c.Mode)
+	}
+	switch c.Concurrency.OnSaturated {
+	case utils.SaturationPolicyAllow, utils.SaturationPolicyReject:
+	default:
+		return fmt.Errorf("unknown concurrency.onSaturated %q", c.Concurrency.OnSaturated)
+	}
+	switch c.Features.VBiosInjection.HookMode {
+	case utils.VBiosHookModeImage, utils.VBiosHookModeConfigMap:
+	default:
+		return fmt.Errorf("unknown features.vbiosInjection.hookMode %q", c.Features.VBiosInjection.HookMode)
+	}
+	return nil
+}
+
+// applyEnv returns base with the settings present in the environment applied
+func applyEnv(base *Config) *Config {
+	return &Config{
+		Port:                   getEnvAsInt("PORT", base.Port),
+		CertDir:                getEnv("CERT_DIR", base.CertDir),
+		HealthPort:             getEnvAsInt("HEALTH_PORT", base.HealthPort),
+		ClientCAFile:           getEnv("CLIENT_CA_FILE", base.ClientCAFile),
+		LogLevel:               getEnv("LOG_LEVEL", base.LogLevel),
+		ErrorHandlingMode:      getEnv("ERROR_HANDLING_MODE", base.ErrorHandlingMode),
+		ConfigSource:           utils.WithPrecedence(utils.ParseConfigSource(getEnv("CONFIG_SOURCE", string(base.ConfigSource))), getEnv("CONFIG_SOURCE_PRECEDENCE", base.ConfigSourcePrecedence)),
+		ConfigSourcePrecedence: getEnv("CONFIG_SOURCE_PRECEDENCE", base.ConfigSourcePrecedence),
+		KeyPrefix:              getEnv("KEY_PREFIX", base.KeyPrefix),
+		KeyAliases:             getEnvAsMap("KEY_ALIASES", base.KeyAliases),
+		RewriteKeyAliases:      getEnvAsBool("REWRITE_KEY_ALIASES", base.RewriteKeyAliases),
+		AddTrackingAnnotations: getEnvAsBool("ADD_TRACKING_ANNOTATIONS", base.AddTrackingAnnotations),
+		WebhookVersion:         getEnv("WEBHOOK_VERSION", base.WebhookVersion),
+		DryRunStrict:           getEnvAsBool("DRY_RUN_STRICT", base.DryRunStrict),
+		Mode:                   getEnv("WEBHOOK_MODE", base.Mode),
+		FeaturePolicies:        getEnvAsBool("FEATURE_POLICIES_ENABLED", base.FeaturePolicies),
+		NamespaceDefaults:      getEnvAsBool("NAMESPACE_DEFAULTS_ENABLED", base.NamespaceDefaults),
+		UserdataDirectives:     getEnvAsBool("FEATURE_USERDATA_DIRECTIVES_ENABLED", base.UserdataDirectives),
+		StripDirectiveComments: getEnvAsBool("USERDATA_STRIP_DIRECTIVES", base.StripDirectiveComments),
+		CachedReads:            getEnvAsBool("CACHED_READS_ENABLED", base.CachedReads),
+		Events:                 getEnvAsBool("EVENTS_ENABLED", base.Events),
+		Pprof:                  getEnvAsBool("PPROF_ENABLED", base.Pprof),
+		FeatureNamespaces:      getEnvAsListMap("FEATURE_ALLOWED_NAMESPACES", base.FeatureNamespaces),
+		RBACFeatures:           getEnvAsSlice("FEATURE_RBAC_REQUIRED", base.RBACFeatures),
+		FeatureRulesFile:       getEnv("FEATURE_RULES_FILE", base.FeatureRulesFile),
+		FeatureStateFile:       getEnv("FEATURE_STATE_FILE", base.FeatureStateFile),
+		CertBootstrap: CertBootstrapConfig{
+			Enabled:              getEnvAsBool("CERT_BOOTSTRAP_ENABLED", base.CertBootstrap.Enabled),
+			Namespace:            getEnv("CERT_BOOTSTRAP_NAMESPACE", base.CertBootstrap.Namespace),
+			SecretName:           getEnv("CERT_BOOTSTRAP_SECRET", base.CertBootstrap.SecretName),
+			ServiceName:          getEnv("CERT_BOOTSTRAP_SERVICE", base.CertBootstrap.ServiceName),
+			WebhookConfiguration: getEnv("CERT_BOOTSTRAP_WEBHOOK_CONFIGURATION", base.CertBootstrap.WebhookConfiguration),
+			ValidityDays:         getEnvAsInt("CERT_BOOTSTRAP_VALIDITY_DAYS", base.CertBootstrap.ValidityDays),
+		},
+		Reconcile: ReconcileConfig{
+			Enabled: getEnvAsBool("RECONCILE_ENABLED", base.Reconcile.Enabled),
+			QPS:     getEnvAsInt("RECONCILE_QPS", base.Reconcile.QPS),
+			Burst:   getEnvAsInt("RECONCILE_BURST", base.Reconcile.Burst),
+		},
+		UserdataSecrets: UserdataSecretsConfig{
+			Guard:           getEnv("USERDATA_SECRET_GUARD", base.UserdataSecrets.Guard),
+			Namespaces:      getEnvAsSlice("USERDATA_SECRET_NAMESPACES", base.UserdataSecrets.Namespaces),
+			Keys:            getEnvAsSlice("USERDATA_SECRET_KEYS", base.UserdataSecrets.Keys),
+			MaxSize:         getEnvAsInt("USERDATA_MAX_SIZE", base.UserdataSecrets.MaxSize),
+			CacheSize:       getEnvAsInt("USERDATA_SECRET_CACHE_SIZE", base.UserdataSecrets.CacheSize),
+			CacheTTLSeconds: getEnvAsInt("USERDATA_SECRET_CACHE_TTL_SECONDS", base.UserdataSecrets.CacheTTLSeconds),
+		},
+		Timeouts: TimeoutsConfig{
+			RequestSeconds: getEnvAsInt("ADMISSION_TIMEOUT_SECONDS", base.Timeouts.RequestSeconds),
+			FeatureSeconds: getEnvAsInt("FEATURE_TIMEOUT_SECONDS", base.Timeouts.FeatureSeconds),
+			OnTimeout:      getEnv("ADMISSION_TIMEOUT_POLICY", base.Timeouts.OnTimeout),
+		},
+		Concurrency: ConcurrencyConfig{
+			MaxInFlight:      getEnvAsInt("MAX_CONCURRENT_ADMISSIONS", base.Concurrency.MaxInFlight),
+			WaitMilliseconds: getEnvAsInt("ADMISSION_QUEUE_WAIT_MS", base.Concurrency.WaitMilliseconds),
+			OnSaturated:      getEnv("ADMISSION_SATURATED_POLICY", base.Concurrency.OnSaturated),
+		},
+		Server: ServerConfig{
+			ReadTimeoutSeconds:  getEnvAsInt("SERVER_READ_TIMEOUT_SECONDS", base.Server.ReadTimeoutSeconds),
+			WriteTimeoutSeconds: getEnvAsInt("SERVER_WRITE_TIMEOUT_SECONDS", base.Server.WriteTimeoutSeconds),
+			IdleTimeoutSeconds:  getEnvAsInt("SERVER_IDLE_TIMEOUT_SECONDS", base.Server.IdleTimeoutSeconds),
+			MaxHeaderBytes:      getEnvAsInt("SERVER_MAX_HEADER_BYTES", base.Server.MaxHeaderBytes),
+			MaxRequestBytes:     getEnvAsInt("SERVER_MAX_REQUEST_BYTES", base.Server.MaxRequestBytes),
+			HTTP2:               getEnvAsBool("SERVER_HTTP2_ENABLED", base.Server.HTTP2),
+		},
+		Plugins: PluginsConfig{
+			GRPC:              getEnvAsMap("FEATURE_PLUGINS_GRPC", base.Plugins.GRPC),
+			WASMDir:           getEnv("FEATURE_PLUGINS_WASM_DIR", base.Plugins.WASMDir),
+			WASMMemoryLimitMB: getEnvAsInt("FEATURE_PLUGIN_WASM_MEMORY_MB", base.Plugins.WASMMemoryLimitMB),
+			ExecDir:           getEnv("FEATURE_PLUGINS_EXEC_DIR", base.Plugins.ExecDir),
+			ExecMaxOutputKB:   getEnvAsInt("FEATURE_PLUGIN_EXEC_MAX_OUTPUT_KB", base.Plugins.ExecMaxOutputKB),
+			TimeoutSeconds:    getEnvAsInt("FEATURE_PLUGIN_TIMEOUT_SECONDS", base.Plugins.TimeoutSeconds),
+		},
+		Features: applyFeaturesEnv(&base.Features),
+	}
+}
+
+// applyFeaturesEnv returns base with the feature settings present in the environment applied
+func applyFeaturesEnv(base *FeaturesConfig) FeaturesConfig {
+	return FeaturesConfig{
+		NestedVirtualization: NestedVirtConfig{
+			Enabled:             getEnvAsBool("FEATURE_NESTED_VIRT_ENABLED", base.NestedVirtualization.Enabled),
+			AutoDetectCPU:       getEnvAsBool("FEATURE_NESTED_VIRT_AUTO_DETECT", base.NestedVirtualization.AutoDetectCPU),
+			DefaultCPUFeature:   getEnv("NESTED_VIRT_DEFAULT_CPU_FEATURE", base.NestedVirtualization.DefaultCPUFeature),
+			OptionalCPUFeatures: getEnvAsBool("NESTED_VIRT_OPTIONAL_CPU_FEATURES", base.NestedVirtualization.OptionalCPUFeatures),
+			NodeAffinity:        getEnvAsBool("NESTED_VIRT_NODE_AFFINITY", base.NestedVirtualization.NodeAffinity),
+		},
+		VBiosInjection: VBiosConfig{
+			Enabled:                   getEnvAsBool("FEATURE_VBIOS_ENABLED", base.VBiosInjection.Enabled),
+			SidecarImage:              getEnv("VBIOS_SIDECAR_IMAGE", base.VBiosInjection.SidecarImage),
+			SidecarImageOverride:      getEnv("VBIOS_SIDECAR_IMAGE_OVERRIDE", base.VBiosInjection.SidecarImageOverride),
+			SidecarVersion:            getEnv("VBIOS_SIDECAR_VERSION", base.VBiosInjection.SidecarVersion),
+			SourceConfigMapKey:        getEnv("VBIOS_SOURCE_CM_KEY", base.VBiosInjection.SourceConfigMapKey),
+			HookConfigMapNameTemplate: getEnv("VBIOS_HOOK_CM_TEMPLATE", base.VBiosInjection.HookConfigMapNameTemplate),
+			HookMode:                  getEnv("VBIOS_HOOK_MODE", base.VBiosInjection.HookMode),
+			VBiosPath:                 getEnv("VBIOS_PATH", base.VBiosInjection.VBiosPath),
+			ValidateSidecarTools:      getEnvAsBool("VBIOS_VALIDATE_TOOLS", base.VBiosInjection.ValidateSidecarTools),
+			RequiredTools:             getEnvAsSlice("VBIOS_REQUIRED_TOOLS", base.VBiosInjection.RequiredTools),
+			AllowedSourceNamespaces:   getEnvAsSlice("VBIOS_ALLOWED_SOURCE_NAMESPACES", base.VBiosInjection.AllowedSourceNamespaces),
+		},
+		PCIPassthrough: PCIPassthroughConfig{
+			Enabled:               getEnvAsBool("FEATURE_PCI_PASSTHROUGH_ENABLED", base.PCIPassthrough.Enabled),
+			ErrorHandling:         getEnv("PCI_PASSTHROUGH_ERROR_HANDLING", base.PCIPassthrough.ErrorHandling),
+			MaxDevices:            getEnvAsInt("PCI_MAX_DEVICES", base.PCIPassthrough.MaxDevices),
+			ResourceMapFile:       getEnv("PCI_RESOURCE_MAP_FILE", base.PCIPassthrough.ResourceMapFile),
+			AutoRegister:          getEnvAsBool("PCI_AUTO_REGISTER", base.PCIPassthrough.AutoRegister),
+			AutoRegisterAllowlist: getEnvAsMap("PCI_AUTO_REGISTER_ALLOWLIST", base.PCIPassthrough.AutoRegisterAllowlist),
+			NodeSelector:          getEnvAsMap("PCI_NODE_SELECTOR", base.PCIPassthrough.NodeSelector),
+			NamespaceDevices:      getEnvAsListMap("PCI_NAMESPACE_DEVICES", base.PCIPassthrough.NamespaceDevices),
+		},
+		GPUDevicePlugin: GPUDevicePluginConfig{
+			Enabled:        getEnvAsBool("FEATURE_GPU_DEVICE_PLUGIN_ENABLED", base.GPUDevicePlugin.Enabled),
+			AllowedPlugins: getEnvAsSlice("GPU_ALLOWED_PLUGINS", base.GPUDevicePlugin.AllowedPlugins),
+			MaxDevices:     getEnvAsInt("GPU_MAX_DEVICES", base.GPUDevicePlugin.MaxDevices),
+			NodeSelector:   getEnvAsMap("GPU_NODE_SELECTOR", base.GPUDevicePlugin.NodeSelector),
+			NamespaceQuota: getEnvAsIntMap("GPU_NAMESPACE_QUOTA", base.GPUDevicePlugin.NamespaceQuota),
+		},
+		VGpu: VGpuConfig{
+			Enabled:      getEnvAsBool("FEATURE_VGPU_ENABLED", base.VGpu.Enabled),
+			NodeSelector: getEnvAsMap("VGPU_NODE_SELECTOR", base.VGpu.NodeSelector),
+		},
+		HyperV: HyperVConfig{
+			Enabled:        getEnvAsBool("FEATURE_HYPERV_ENABLED", base.HyperV.Enabled),
+			Enlightenments: getEnvAsSlice("HYPERV_ENLIGHTENMENTS", base.HyperV.Enlightenments),
+		},
+		CPUModel: CPUModelConfig{
+			Enabled:       getEnvAsBool("FEATURE_CPU_MODEL_ENABLED", base.CPUModel.Enabled),
+			AllowedModels: getEnvAsSlice("CPU_ALLOWED_MODELS", base.CPUModel.AllowedModels),
+		},
+		WindowsPreset: WindowsPresetConfig{
+			Enabled:      getEnvAsBool("FEATURE_WINDOWS_PRESET_ENABLED", base.WindowsPreset.Enabled),
+			HyperV:       getEnvAsBool("WINDOWS_PRESET_HYPERV", base.WindowsPreset.HyperV),
+			ClockTimers:  getEnvAsBool("WINDOWS_PRESET_CLOCK_TIMERS", base.WindowsPreset.ClockTimers),
+			TPM:          getEnvAsBool("WINDOWS_PRESET_TPM", base.WindowsPreset.TPM),
+			SecureBoot:   getEnvAsBool("WINDOWS_PRESET_SECURE_BOOT", base.WindowsPreset.SecureBoot),
+			NetworkModel: getEnv("WINDOWS_PRESET_NETWORK_MODEL", base.WindowsPreset.NetworkModel),
+		},
+		MetadataPropagation: MetadataPropagationConfig{
+			Enabled:            getEnvAsBool("FEATURE_METADATA_PROPAGATION_ENABLED", base.MetadataPropagation.Enabled),
+			LabelPrefixes:      getEnvAsSlice("PROPAGATE_LABEL_PREFIXES", base.MetadataPropagation.LabelPrefixes),
+			AnnotationPrefixes: getEnvAsSlice("PROPAGATE_ANNOTATION_PREFIXES", base.MetadataPropagation.AnnotationPrefixes),
+		},
+	}
+}
+
+// LoadPCIResourceMap reads a YAML mapping of PCI address or alias to resource name.
+// Keys are lower-cased so PCI addresses match regardless of hex case.
+func LoadPCIResourceMap(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PCI resource map %s: %w", path, err)
+	}
+
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse PCI resource map %s: %w", path, err)
+	}
+
+	resourceMap := make(map[string]string, len(raw))
+	for key, resourceName := range raw {
+		key = strings.ToLower(strings.TrimSpace(key))
+		resourceName = strings.TrimSpace(resourceName)
+		if key == "" || resourceName == "" {
+			return nil, fmt.Errorf("invalid entry in PCI resource map %s: empty key or resource name", path)
+		}
+		resourceMap[key] = resourceName
+	}
+
+	return resourceMap, nil
+}
+
+// LoadFeatureRules reads a YAML list of feature rules
+func LoadFeatureRules(path string) ([]v1alpha1.FeatureRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feature rules %s: %w", path, err)
+	}
+
+	var rules []v1alpha1.FeatureRule
+	if err := yaml.UnmarshalStrict(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse feature rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
 // Helper functions for environment variables
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -145,3 +764,52 @@ func getEnvAsSlice(key string, defaultValue []string) []string {
 	}
 	return strings.Split(valueStr, ",")
 }
+
+func getEnvAsMap(key string, defaultValue map[string]string) map[string]string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	result := make(map[string]string)
+	for _, pair := range strings.Split(valueStr, ",") {
+		k, v, found := strings.Cut(pair, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !found || k == "" || v == "" {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// getEnvAsIntMap parses key=n pairs separated by commas, skipping invalid counts
+func getEnvAsIntMap(key string, defaultValue map[string]int) map[string]int {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	result := make(map[string]int)
+	for k, v := range getEnvAsMap(key, nil) {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			result[k] = n
+		}
+	}
+	return result
+}
+
+// getEnvAsListMap parses key=a;b pairs separated by commas
+func getEnvAsListMap(key string, defaultValue map[string][]string) map[string][]string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	result := make(map[string][]string)
+	for k, v := range getEnvAsMap(key, nil) {
+		for _, item := range strings.Split(v, ";") {
+			if item = strings.TrimSpace(item); item != "" {
+				result[k] = append(result[k], item)
+			}
+		}
+	}
+	return result
+}
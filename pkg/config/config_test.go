@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -17,14 +18,28 @@ var _ = Describe("Config", func() {
 		// Save original environment - include ALL environment variables that config uses
 		originalEnv = make(map[string]string)
 		envVars := []string{
-			"PORT", "CERT_DIR", "LOG_LEVEL", "ERROR_HANDLING_MODE", "CONFIG_SOURCE",
-			"ADD_TRACKING_ANNOTATIONS", "WEBHOOK_VERSION",
-			"FEATURE_NESTED_VIRT_ENABLED", "FEATURE_NESTED_VIRT_AUTO_DETECT",
+			"PORT", "HEALTH_PORT", "RECONCILE_ENABLED", "RECONCILE_QPS", "RECONCILE_BURST", "WEBHOOK_MODE", "MAX_CONCURRENT_ADMISSIONS", "ADMISSION_QUEUE_WAIT_MS", "ADMISSION_SATURATED_POLICY", "SERVER_READ_TIMEOUT_SECONDS", "SERVER_WRITE_TIMEOUT_SECONDS", "SERVER_IDLE_TIMEOUT_SECONDS", "SERVER_MAX_HEADER_BYTES", "SERVER_MAX_REQUEST_BYTES", "SERVER_HTTP2_ENABLED", "CLIENT_CA_FILE", "CERT_DIR", "CERT_BOOTSTRAP_ENABLED", "CERT_BOOTSTRAP_NAMESPACE", "CERT_BOOTSTRAP_SECRET", "CERT_BOOTSTRAP_SERVICE", "CERT_BOOTSTRAP_WEBHOOK_CONFIGURATION", "CERT_BOOTSTRAP_VALIDITY_DAYS", "LOG_LEVEL", "ERROR_HANDLING_MODE", "CONFIG_SOURCE", "CONFIG_SOURCE_PRECEDENCE", "KEY_PREFIX",
+			"KEY_ALIASES", "REWRITE_KEY_ALIASES",
+			"ADD_TRACKING_ANNOTATIONS", "WEBHOOK_VERSION", "DRY_RUN_STRICT", "FEATURE_POLICIES_ENABLED",
+			"NAMESPACE_DEFAULTS_ENABLED", "FEATURE_ALLOWED_NAMESPACES", "FEATURE_RBAC_REQUIRED", "FEATURE_USERDATA_DIRECTIVES_ENABLED", "USERDATA_STRIP_DIRECTIVES", "CACHED_READS_ENABLED", "EVENTS_ENABLED", "PPROF_ENABLED", "USERDATA_SECRET_GUARD", "USERDATA_SECRET_NAMESPACES", "USERDATA_SECRET_KEYS", "USERDATA_MAX_SIZE", "USERDATA_SECRET_CACHE_SIZE", "USERDATA_SECRET_CACHE_TTL_SECONDS", "ADMISSION_TIMEOUT_SECONDS", "FEATURE_TIMEOUT_SECONDS", "ADMISSION_TIMEOUT_POLICY", "FEATURE_RULES_FILE", "FEATURE_STATE_FILE",
+			"FEATURE_PLUGINS_GRPC", "FEATURE_PLUGINS_WASM_DIR", "FEATURE_PLUGIN_WASM_MEMORY_MB",
+			"FEATURE_PLUGINS_EXEC_DIR", "FEATURE_PLUGIN_EXEC_MAX_OUTPUT_KB", "FEATURE_PLUGIN_TIMEOUT_SECONDS",
+			"FEATURE_NESTED_VIRT_ENABLED", "FEATURE_NESTED_VIRT_AUTO_DETECT", "NESTED_VIRT_DEFAULT_CPU_FEATURE",
+			"NESTED_VIRT_OPTIONAL_CPU_FEATURES",
 			"FEATURE_VBIOS_ENABLED", "VBIOS_SIDECAR_IMAGE", "VBIOS_SIDECAR_IMAGE_OVERRIDE",
-			"VBIOS_SIDECAR_VERSION", "VBIOS_SOURCE_CM_KEY", "VBIOS_HOOK_CM_TEMPLATE",
+			"VBIOS_SIDECAR_VERSION", "VBIOS_SOURCE_CM_KEY", "VBIOS_HOOK_CM_TEMPLATE", "VBIOS_HOOK_MODE",
 			"VBIOS_PATH", "VBIOS_VALIDATE_TOOLS", "VBIOS_REQUIRED_TOOLS",
+			"VBIOS_ALLOWED_SOURCE_NAMESPACES",
 			"FEATURE_PCI_PASSTHROUGH_ENABLED", "PCI_PASSTHROUGH_ERROR_HANDLING", "PCI_MAX_DEVICES",
+			"PCI_RESOURCE_MAP_FILE", "PCI_AUTO_REGISTER", "PCI_AUTO_REGISTER_ALLOWLIST",
+			"NESTED_VIRT_NODE_AFFINITY", "PCI_NODE_SELECTOR", "PCI_NAMESPACE_DEVICES", "GPU_NODE_SELECTOR", "GPU_NAMESPACE_QUOTA",
+			"FEATURE_VGPU_ENABLED", "VGPU_NODE_SELECTOR",
 			"FEATURE_GPU_DEVICE_PLUGIN_ENABLED", "GPU_ALLOWED_PLUGINS",
+			"FEATURE_HYPERV_ENABLED", "HYPERV_ENLIGHTENMENTS",
+			"FEATURE_CPU_MODEL_ENABLED", "CPU_ALLOWED_MODELS", "GPU_MAX_DEVICES",
+			"FEATURE_WINDOWS_PRESET_ENABLED", "WINDOWS_PRESET_HYPERV", "WINDOWS_PRESET_CLOCK_TIMERS",
+			"WINDOWS_PRESET_TPM", "WINDOWS_PRESET_SECURE_BOOT", "WINDOWS_PRESET_NETWORK_MODEL",
+			"FEATURE_METADATA_PROPAGATION_ENABLED", "PROPAGATE_LABEL_PREFIXES", "PROPAGATE_ANNOTATION_PREFIXES",
 		}
 		for _, key := range envVars {
 			originalEnv[key] = os.Getenv(key)
@@ -44,69 +59,216 @@ var _ = Describe("Config", func() {
 	})
 
 	Describe("LoadConfig", func() {
+		loadConfig := func() *config.Config {
+			cfg, err := config.LoadConfig()
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			return cfg
+		}
+
 		Context("with default values", func() {
 			It("should load default configuration", func() {
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 
 				Expect(cfg.Port).To(Equal(8443))
+				Expect(cfg.HealthPort).To(BeZero())
+				Expect(cfg.ClientCAFile).To(BeEmpty())
+				Expect(cfg.Mode).To(Equal(utils.WebhookModeEnforce))
+				Expect(cfg.Reconcile).To(Equal(config.ReconcileConfig{QPS: 5, Burst: 10}))
+				Expect(cfg.Concurrency).To(Equal(config.ConcurrencyConfig{
+					WaitMilliseconds: 100,
+					OnSaturated:      utils.SaturationPolicyAllow,
+				}))
+				Expect(cfg.Server).To(Equal(config.ServerConfig{
+					ReadTimeoutSeconds:  10,
+					WriteTimeoutSeconds: 10,
+					IdleTimeoutSeconds:  60,
+					MaxRequestBytes:     4 << 20,
+				}))
+				Expect(cfg.CertBootstrap).To(Equal(config.CertBootstrapConfig{ValidityDays: 365}))
 				Expect(cfg.CertDir).To(Equal("/etc/webhook/certs"))
 				Expect(cfg.LogLevel).To(Equal("info"))
 				Expect(cfg.ErrorHandlingMode).To(Equal(utils.ErrorHandlingReject))
 				Expect(cfg.ConfigSource).To(Equal(utils.ConfigSourceAnnotations))
 				Expect(cfg.AddTrackingAnnotations).To(BeTrue())
-				Expect(cfg.WebhookVersion).To(Equal("v0.1.0"))
+				Expect(cfg.WebhookVersion).To(BeEmpty())
+				Expect(cfg.DryRunStrict).To(BeFalse())
+				Expect(cfg.FeaturePolicies).To(BeFalse())
+				Expect(cfg.NamespaceDefaults).To(BeFalse())
+				Expect(cfg.FeatureRulesFile).To(BeEmpty())
+				Expect(cfg.FeatureStateFile).To(BeEmpty())
+				Expect(cfg.FeatureNamespaces).To(BeEmpty())
+				Expect(cfg.RBACFeatures).To(BeEmpty())
+				Expect(cfg.UserdataDirectives).To(BeTrue())
+				Expect(cfg.StripDirectiveComments).To(BeFalse())
+				Expect(cfg.CachedReads).To(BeFalse())
+				Expect(cfg.Events).To(BeTrue())
+				Expect(cfg.Pprof).To(BeFalse())
+				Expect(cfg.UserdataSecrets.Guard).To(Equal(utils.UserdataSecretGuardNone))
+				Expect(cfg.UserdataSecrets.Namespaces).To(BeEmpty())
+				Expect(cfg.UserdataSecrets.Keys).To(Equal([]string{"userdata", "userData", "user-data"}))
+				Expect(cfg.UserdataSecrets.MaxSize).To(Equal(65536))
+				Expect(cfg.UserdataSecrets.CacheSize).To(Equal(256))
+				Expect(cfg.UserdataSecrets.CacheTTLSeconds).To(Equal(30))
+				Expect(cfg.Timeouts.RequestSeconds).To(Equal(5))
+				Expect(cfg.Timeouts.FeatureSeconds).To(Equal(2))
+				Expect(cfg.Timeouts.OnTimeout).To(Equal(utils.TimeoutPolicyAllow))
+				Expect(cfg.Plugins.GRPC).To(BeEmpty())
+				Expect(cfg.Plugins.WASMDir).To(BeEmpty())
+				Expect(cfg.Plugins.WASMMemoryLimitMB).To(Equal(128))
+				Expect(cfg.Plugins.ExecDir).To(BeEmpty())
+				Expect(cfg.Plugins.ExecMaxOutputKB).To(Equal(1024))
+				Expect(cfg.Plugins.TimeoutSeconds).To(Equal(5))
+				Expect(cfg.KeyPrefix).To(Equal(utils.DefaultKeyPrefix))
+				Expect(cfg.KeyAliases).To(BeEmpty())
+				Expect(cfg.RewriteKeyAliases).To(BeFalse())
 			})
 
 			It("should enable all features by default", func() {
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 
 				Expect(cfg.Features.NestedVirtualization.Enabled).To(BeTrue())
 				Expect(cfg.Features.NestedVirtualization.AutoDetectCPU).To(BeTrue())
+				Expect(cfg.Features.NestedVirtualization.DefaultCPUFeature).To(Equal(utils.CPUFeatureSVM))
+				Expect(cfg.Features.NestedVirtualization.OptionalCPUFeatures).To(BeFalse())
 				Expect(cfg.Features.VBiosInjection.Enabled).To(BeTrue())
 				Expect(cfg.Features.PCIPassthrough.Enabled).To(BeTrue())
+				Expect(cfg.Features.PCIPassthrough.ResourceMapFile).To(BeEmpty())
+				Expect(cfg.Features.PCIPassthrough.AutoRegister).To(BeFalse())
+				Expect(cfg.Features.PCIPassthrough.AutoRegisterAllowlist).To(BeEmpty())
 				Expect(cfg.Features.GPUDevicePlugin.Enabled).To(BeTrue())
+				Expect(cfg.Features.GPUDevicePlugin.MaxDevices).To(Equal(8))
+				Expect(cfg.Features.VGpu.Enabled).To(BeTrue())
+				Expect(cfg.Features.HyperV.Enabled).To(BeTrue())
+				Expect(cfg.Features.HyperV.Enlightenments).To(ContainElements("relaxed", "vapic", "spinlocks", "synic", "stimer"))
+				Expect(cfg.Features.CPUModel.Enabled).To(BeTrue())
+				Expect(cfg.Features.CPUModel.AllowedModels).To(ConsistOf("host-passthrough", "host-model"))
+				Expect(cfg.Features.WindowsPreset.Enabled).To(BeTrue())
+				Expect(cfg.Features.WindowsPreset.HyperV).To(BeTrue())
+				Expect(cfg.Features.WindowsPreset.ClockTimers).To(BeTrue())
+				Expect(cfg.Features.WindowsPreset.TPM).To(BeTrue())
+				Expect(cfg.Features.WindowsPreset.SecureBoot).To(BeTrue())
+				Expect(cfg.Features.WindowsPreset.NetworkModel).To(Equal("e1000e"))
+				Expect(cfg.Features.MetadataPropagation.Enabled).To(BeTrue())
+				Expect(cfg.Features.MetadataPropagation.LabelPrefixes).To(ConsistOf("app.kubernetes.io/"))
+				Expect(cfg.Features.MetadataPropagation.AnnotationPrefixes).To(BeEmpty())
+			})
+
+			It("should leave node affinity off by default", func() {
+				cfg := loadConfig()
+				Expect(cfg.Features.NestedVirtualization.NodeAffinity).To(BeFalse())
+				Expect(cfg.Features.PCIPassthrough.NodeSelector).To(BeEmpty())
+				Expect(cfg.Features.PCIPassthrough.NamespaceDevices).To(BeEmpty())
+				Expect(cfg.Features.GPUDevicePlugin.NodeSelector).To(BeEmpty())
+				Expect(cfg.Features.GPUDevicePlugin.NamespaceQuota).To(BeEmpty())
+				Expect(cfg.Features.VGpu.NodeSelector).To(BeEmpty())
 			})
 
 			It("should set vBIOS defaults correctly", func() {
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 
 				Expect(cfg.Features.VBiosInjection.SidecarImageOverride).To(Equal(utils.DefaultSidecarImage))
-				Expect(cfg.Features.VBiosInjection.SidecarVersion).To(Equal(utils.SidecarHookVersion))
+				Expect(cfg.Features.VBiosInjection.SidecarVersion).To(Equal(utils.SidecarHookVersionAuto))
 				Expect(cfg.Features.VBiosInjection.SourceConfigMapKey).To(Equal(utils.VBiosConfigMapKey))
+				Expect(cfg.Features.VBiosInjection.HookMode).To(Equal(utils.VBiosHookModeImage))
 				Expect(cfg.Features.VBiosInjection.VBiosPath).To(Equal("/tmp/vbios.rom"))
 				Expect(cfg.Features.VBiosInjection.ValidateSidecarTools).To(BeTrue())
+				Expect(cfg.Features.VBiosInjection.AllowedSourceNamespaces).To(BeEmpty())
 			})
 		})
 
 		Context("with custom environment variables", func() {
 			It("should override port from environment", func() {
 				Expect(os.Setenv("PORT", "9443")).To(Succeed())
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 				Expect(cfg.Port).To(Equal(9443))
 			})
 
+			It("should override health port from environment", func() {
+				Expect(os.Setenv("HEALTH_PORT", "8081")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.HealthPort).To(Equal(8081))
+			})
+
+			It("should override reconciliation from environment", func() {
+				Expect(os.Setenv("RECONCILE_ENABLED", "true")).To(Succeed())
+				Expect(os.Setenv("RECONCILE_QPS", "20")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Reconcile.Enabled).To(BeTrue())
+				Expect(cfg.Reconcile.QPS).To(Equal(20))
+				Expect(cfg.Reconcile.Burst).To(Equal(10))
+			})
+
+			It("should override mode from environment", func() {
+				Expect(os.Setenv("WEBHOOK_MODE", "shadow")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Mode).To(Equal(utils.WebhookModeShadow))
+			})
+
+			It("should override the concurrency limit from environment", func() {
+				Expect(os.Setenv("MAX_CONCURRENT_ADMISSIONS", "32")).To(Succeed())
+				Expect(os.Setenv("ADMISSION_SATURATED_POLICY", "reject")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Concurrency.MaxInFlight).To(Equal(32))
+				Expect(cfg.Concurrency.WaitMilliseconds).To(Equal(100))
+				Expect(cfg.Concurrency.OnSaturated).To(Equal(utils.SaturationPolicyReject))
+			})
+
+			It("should override server limits from environment", func() {
+				Expect(os.Setenv("SERVER_WRITE_TIMEOUT_SECONDS", "30")).To(Succeed())
+				Expect(os.Setenv("SERVER_MAX_REQUEST_BYTES", "1048576")).To(Succeed())
+				Expect(os.Setenv("SERVER_HTTP2_ENABLED", "true")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Server.WriteTimeoutSeconds).To(Equal(30))
+				Expect(cfg.Server.MaxRequestBytes).To(Equal(1048576))
+				Expect(cfg.Server.HTTP2).To(BeTrue())
+				Expect(cfg.Server.ReadTimeoutSeconds).To(Equal(10))
+			})
+
+			It("should override client CA file from environment", func() {
+				Expect(os.Setenv("CLIENT_CA_FILE", "/etc/webhook/client-ca/ca.crt")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.ClientCAFile).To(Equal("/etc/webhook/client-ca/ca.crt"))
+			})
+
+			It("should configure certificate bootstrap from environment", func() {
+				Expect(os.Setenv("CERT_BOOTSTRAP_ENABLED", "true")).To(Succeed())
+				Expect(os.Setenv("CERT_BOOTSTRAP_NAMESPACE", "vm-feature-manager")).To(Succeed())
+				Expect(os.Setenv("CERT_BOOTSTRAP_SECRET", "webhook-tls")).To(Succeed())
+				Expect(os.Setenv("CERT_BOOTSTRAP_SERVICE", "webhook")).To(Succeed())
+				Expect(os.Setenv("CERT_BOOTSTRAP_WEBHOOK_CONFIGURATION", "vm-feature-manager")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.CertBootstrap).To(Equal(config.CertBootstrapConfig{
+					Enabled:              true,
+					Namespace:            "vm-feature-manager",
+					SecretName:           "webhook-tls",
+					ServiceName:          "webhook",
+					WebhookConfiguration: "vm-feature-manager",
+					ValidityDays:         365,
+				}))
+			})
+
 			It("should override log level from environment", func() {
 				Expect(os.Setenv("LOG_LEVEL", "debug")).To(Succeed())
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 				Expect(cfg.LogLevel).To(Equal("debug"))
 			})
 
 			It("should override error handling mode from environment", func() {
 				Expect(os.Setenv("ERROR_HANDLING_MODE", utils.ErrorHandlingAllowAndLog)).To(Succeed())
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 				Expect(cfg.ErrorHandlingMode).To(Equal(utils.ErrorHandlingAllowAndLog))
 			})
 
 			It("should disable tracking annotations from environment", func() {
 				Expect(os.Setenv("ADD_TRACKING_ANNOTATIONS", "false")).To(Succeed())
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 				Expect(cfg.AddTrackingAnnotations).To(BeFalse())
 			})
 
 			It("should disable features from environment", func() {
 				Expect(os.Setenv("FEATURE_NESTED_VIRT_ENABLED", "false")).To(Succeed())
 				Expect(os.Setenv("FEATURE_VBIOS_ENABLED", "false")).To(Succeed())
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 				Expect(cfg.Features.NestedVirtualization.Enabled).To(BeFalse())
 				Expect(cfg.Features.VBiosInjection.Enabled).To(BeFalse())
 			})
@@ -114,35 +276,476 @@ var _ = Describe("Config", func() {
 			It("should override vBIOS sidecar image from environment", func() {
 				customImage := "myregistry.io/sidecar-shim:custom"
 				Expect(os.Setenv("VBIOS_SIDECAR_IMAGE_OVERRIDE", customImage)).To(Succeed())
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 				Expect(cfg.Features.VBiosInjection.SidecarImageOverride).To(Equal(customImage))
 			})
 
+			It("should read the vBIOS hook mode from environment", func() {
+				Expect(os.Setenv("VBIOS_HOOK_MODE", utils.VBiosHookModeConfigMap)).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.VBiosInjection.HookMode).To(Equal(utils.VBiosHookModeConfigMap))
+			})
+
+			It("should read allowed vBIOS source namespaces from environment", func() {
+				Expect(os.Setenv("VBIOS_ALLOWED_SOURCE_NAMESPACES", "vbios-library,gpu-roms")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.VBiosInjection.AllowedSourceNamespaces).To(Equal([]string{"vbios-library", "gpu-roms"}))
+			})
+
+			It("should read the PCI resource map file from environment", func() {
+				Expect(os.Setenv("PCI_RESOURCE_MAP_FILE", "/etc/vm-feature-manager/pci-resource-map.yaml")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.PCIPassthrough.ResourceMapFile).To(Equal("/etc/vm-feature-manager/pci-resource-map.yaml"))
+			})
+
+			It("should parse the PCI auto-register allowlist from environment", func() {
+				Expect(os.Setenv("PCI_AUTO_REGISTER", "true")).To(Succeed())
+				Expect(os.Setenv("PCI_AUTO_REGISTER_ALLOWLIST", "nvidia.com/GA102=10DE:2204, intel.com/QAT=8086:4940,invalid")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.PCIPassthrough.AutoRegister).To(BeTrue())
+				Expect(cfg.Features.PCIPassthrough.AutoRegisterAllowlist).To(Equal(map[string]string{
+					"nvidia.com/GA102": "10DE:2204",
+					"intel.com/QAT":    "8086:4940",
+				}))
+			})
+
+			It("should override the nested virtualization default CPU feature", func() {
+				Expect(os.Setenv("NESTED_VIRT_DEFAULT_CPU_FEATURE", "vmx")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.NestedVirtualization.DefaultCPUFeature).To(Equal(utils.CPUFeatureVMX))
+			})
+
+			It("should enable optional nested virtualization CPU features from environment", func() {
+				Expect(os.Setenv("NESTED_VIRT_OPTIONAL_CPU_FEATURES", "true")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.NestedVirtualization.OptionalCPUFeatures).To(BeTrue())
+			})
+
+			It("should parse per-feature node selectors from environment", func() {
+				Expect(os.Setenv("NESTED_VIRT_NODE_AFFINITY", "true")).To(Succeed())
+				Expect(os.Setenv("GPU_NODE_SELECTOR", "nvidia.com/gpu.present=true")).To(Succeed())
+				Expect(os.Setenv("VGPU_NODE_SELECTOR", "nvidia.com/vgpu.present=true")).To(Succeed())
+				Expect(os.Setenv("PCI_NODE_SELECTOR", "example.com/passthrough=true,zone=a")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.NestedVirtualization.NodeAffinity).To(BeTrue())
+				Expect(cfg.Features.GPUDevicePlugin.NodeSelector).To(HaveKeyWithValue("nvidia.com/gpu.present", "true"))
+				Expect(cfg.Features.VGpu.NodeSelector).To(HaveKeyWithValue("nvidia.com/vgpu.present", "true"))
+				Expect(cfg.Features.PCIPassthrough.NodeSelector).To(HaveLen(2))
+			})
+
+			It("should parse per-namespace PCI devices from environment", func() {
+				Expect(os.Setenv("PCI_NAMESPACE_DEVICES", "hw-lab=0000:01:00.0;nvidia.com/GA102,*=0000:03:00.0")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.PCIPassthrough.NamespaceDevices).To(Equal(map[string][]string{
+					"hw-lab": {"0000:01:00.0", "nvidia.com/GA102"},
+					"*":      {"0000:03:00.0"},
+				}))
+			})
+
+			It("should parse GPU namespace quotas from environment", func() {
+				Expect(os.Setenv("GPU_NAMESPACE_QUOTA", "ml-team=8,*=2,broken=many")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.GPUDevicePlugin.NamespaceQuota).To(Equal(map[string]int{"ml-team": 8, "*": 2}))
+			})
+
 			It("should parse GPU allowed plugins from environment", func() {
 				Expect(os.Setenv("GPU_ALLOWED_PLUGINS", "plugin1,plugin2,plugin3")).To(Succeed())
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 				Expect(cfg.Features.GPUDevicePlugin.AllowedPlugins).To(ConsistOf("plugin1", "plugin2", "plugin3"))
 			})
 
+			It("should parse GPU max devices from environment", func() {
+				Expect(os.Setenv("GPU_MAX_DEVICES", "2")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.GPUDevicePlugin.MaxDevices).To(Equal(2))
+			})
+
+			It("should parse Hyper-V enlightenments from environment", func() {
+				Expect(os.Setenv("HYPERV_ENLIGHTENMENTS", "relaxed,vapic")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.HyperV.Enlightenments).To(ConsistOf("relaxed", "vapic"))
+			})
+
+			It("should parse allowed CPU models from environment", func() {
+				Expect(os.Setenv("CPU_ALLOWED_MODELS", "host-model,EPYC,Skylake-Server")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.CPUModel.AllowedModels).To(ConsistOf("host-model", "EPYC", "Skylake-Server"))
+			})
+
+			It("should override Windows preset items from environment", func() {
+				Expect(os.Setenv("WINDOWS_PRESET_TPM", "false")).To(Succeed())
+				Expect(os.Setenv("WINDOWS_PRESET_NETWORK_MODEL", "virtio")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.WindowsPreset.TPM).To(BeFalse())
+				Expect(cfg.Features.WindowsPreset.NetworkModel).To(Equal("virtio"))
+			})
+
+			It("should parse metadata propagation prefixes from environment", func() {
+				Expect(os.Setenv("PROPAGATE_LABEL_PREFIXES", "cost-center,team.example.com/")).To(Succeed())
+				Expect(os.Setenv("PROPAGATE_ANNOTATION_PREFIXES", "prometheus.io/")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Features.MetadataPropagation.LabelPrefixes).To(ConsistOf("cost-center", "team.example.com/"))
+				Expect(cfg.Features.MetadataPropagation.AnnotationPrefixes).To(ConsistOf("prometheus.io/"))
+			})
+
+			It("should enable strict dry-run from environment", func() {
+				Expect(os.Setenv("DRY_RUN_STRICT", "true")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.DryRunStrict).To(BeTrue())
+			})
+
+			It("should enable feature policies from environment", func() {
+				Expect(os.Setenv("FEATURE_POLICIES_ENABLED", "true")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.FeaturePolicies).To(BeTrue())
+			})
+
+			It("should enable namespace defaults from environment", func() {
+				Expect(os.Setenv("NAMESPACE_DEFAULTS_ENABLED", "true")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.NamespaceDefaults).To(BeTrue())
+			})
+
+			It("should disable userdata directives from environment", func() {
+				Expect(os.Setenv("FEATURE_USERDATA_DIRECTIVES_ENABLED", "false")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.UserdataDirectives).To(BeFalse())
+			})
+
+			It("should enable stripping userdata directives from environment", func() {
+				Expect(os.Setenv("USERDATA_STRIP_DIRECTIVES", "true")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.StripDirectiveComments).To(BeTrue())
+			})
+
+			It("should enable cached reads from environment", func() {
+				Expect(os.Setenv("CACHED_READS_ENABLED", "true")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.CachedReads).To(BeTrue())
+			})
+
+			It("should disable events from environment", func() {
+				Expect(os.Setenv("EVENTS_ENABLED", "false")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Events).To(BeFalse())
+			})
+
+			It("should enable pprof from environment", func() {
+				Expect(os.Setenv("PPROF_ENABLED", "true")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Pprof).To(BeTrue())
+			})
+
+			It("should override feature namespace restrictions from environment", func() {
+				Expect(os.Setenv("FEATURE_ALLOWED_NAMESPACES", "pci-passthrough=hw-lab;ci, vbios-injection=gpu")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.FeatureNamespaces).To(Equal(map[string][]string{
+					"pci-passthrough": {"hw-lab", "ci"},
+					"vbios-injection": {"gpu"},
+				}))
+			})
+
+			It("should override the userdata secret guard from environment", func() {
+				Expect(os.Setenv("USERDATA_SECRET_GUARD", utils.UserdataSecretGuardNamespaceAllowlist)).To(Succeed())
+				Expect(os.Setenv("USERDATA_SECRET_NAMESPACES", "tenant-a,tenant-b")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.UserdataSecrets.Guard).To(Equal(utils.UserdataSecretGuardNamespaceAllowlist))
+				Expect(cfg.UserdataSecrets.Namespaces).To(Equal([]string{"tenant-a", "tenant-b"}))
+			})
+
+			It("should override userdata secret keys and size limit from environment", func() {
+				Expect(os.Setenv("USERDATA_SECRET_KEYS", "value,userdata")).To(Succeed())
+				Expect(os.Setenv("USERDATA_MAX_SIZE", "262144")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.UserdataSecrets.Keys).To(Equal([]string{"value", "userdata"}))
+				Expect(cfg.UserdataSecrets.MaxSize).To(Equal(262144))
+			})
+
+			It("should override the userdata secret cache from environment", func() {
+				Expect(os.Setenv("USERDATA_SECRET_CACHE_SIZE", "0")).To(Succeed())
+				Expect(os.Setenv("USERDATA_SECRET_CACHE_TTL_SECONDS", "5")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.UserdataSecrets.CacheSize).To(Equal(0))
+				Expect(cfg.UserdataSecrets.CacheTTLSeconds).To(Equal(5))
+			})
+
+			It("should override admission timeouts from environment", func() {
+				Expect(os.Setenv("ADMISSION_TIMEOUT_SECONDS", "8")).To(Succeed())
+				Expect(os.Setenv("FEATURE_TIMEOUT_SECONDS", "0")).To(Succeed())
+				Expect(os.Setenv("ADMISSION_TIMEOUT_POLICY", "reject")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Timeouts.RequestSeconds).To(Equal(8))
+				Expect(cfg.Timeouts.FeatureSeconds).To(Equal(0))
+				Expect(cfg.Timeouts.OnTimeout).To(Equal(utils.TimeoutPolicyReject))
+			})
+
+			It("should override RBAC-gated features from environment", func() {
+				Expect(os.Setenv("FEATURE_RBAC_REQUIRED", "pci-passthrough,gpu-device-plugin")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.RBACFeatures).To(Equal([]string{"pci-passthrough", "gpu-device-plugin"}))
+			})
+
+			It("should override the feature rules file from environment", func() {
+				Expect(os.Setenv("FEATURE_RULES_FILE", "/etc/vm-feature-manager/feature-rules.yaml")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.FeatureRulesFile).To(Equal("/etc/vm-feature-manager/feature-rules.yaml"))
+			})
+
+			It("should override the feature state file from environment", func() {
+				Expect(os.Setenv("FEATURE_STATE_FILE", "/etc/vm-feature-manager/state/feature-states.yaml")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.FeatureStateFile).To(Equal("/etc/vm-feature-manager/state/feature-states.yaml"))
+			})
+
+			It("should override plugin settings from environment", func() {
+				Expect(os.Setenv("FEATURE_PLUGINS_GRPC", "team-label=team-plugin.plugins.svc:9000")).To(Succeed())
+				Expect(os.Setenv("FEATURE_PLUGINS_WASM_DIR", "/etc/vm-feature-manager/wasm")).To(Succeed())
+				Expect(os.Setenv("FEATURE_PLUGIN_WASM_MEMORY_MB", "64")).To(Succeed())
+				Expect(os.Setenv("FEATURE_PLUGINS_EXEC_DIR", "/etc/vm-feature-manager/exec")).To(Succeed())
+				Expect(os.Setenv("FEATURE_PLUGIN_EXEC_MAX_OUTPUT_KB", "256")).To(Succeed())
+				Expect(os.Setenv("FEATURE_PLUGIN_TIMEOUT_SECONDS", "2")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.Plugins.GRPC).To(HaveKeyWithValue("team-label", "team-plugin.plugins.svc:9000"))
+				Expect(cfg.Plugins.WASMDir).To(Equal("/etc/vm-feature-manager/wasm"))
+				Expect(cfg.Plugins.WASMMemoryLimitMB).To(Equal(64))
+				Expect(cfg.Plugins.ExecDir).To(Equal("/etc/vm-feature-manager/exec"))
+				Expect(cfg.Plugins.ExecMaxOutputKB).To(Equal(256))
+				Expect(cfg.Plugins.TimeoutSeconds).To(Equal(2))
+			})
+
+			It("should override the key prefix from environment", func() {
+				Expect(os.Setenv("KEY_PREFIX", "ourcompany.io/vm-")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.KeyPrefix).To(Equal("ourcompany.io/vm-"))
+			})
+
+			It("should load key aliases from environment", func() {
+				Expect(os.Setenv("KEY_ALIASES", "nested-virt.vmfm.io/enabled=nested-virt")).To(Succeed())
+				Expect(os.Setenv("REWRITE_KEY_ALIASES", "true")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.KeyAliases).To(HaveKeyWithValue("nested-virt.vmfm.io/enabled", "nested-virt"))
+				Expect(cfg.RewriteKeyAliases).To(BeTrue())
+			})
+
 			It("should override config source from environment", func() {
 				Expect(os.Setenv("CONFIG_SOURCE", string(utils.ConfigSourceLabels))).To(Succeed())
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 				Expect(cfg.ConfigSource).To(Equal(utils.ConfigSourceLabels))
 			})
+
+			It("should read both labels and annotations with annotations first by default", func() {
+				Expect(os.Setenv("CONFIG_SOURCE", string(utils.ConfigSourceBoth))).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.ConfigSource).To(Equal(utils.ConfigSourceBoth))
+			})
+
+			It("should let labels take precedence when configured", func() {
+				Expect(os.Setenv("CONFIG_SOURCE", string(utils.ConfigSourceBoth))).To(Succeed())
+				Expect(os.Setenv("CONFIG_SOURCE_PRECEDENCE", "labels")).To(Succeed())
+				cfg := loadConfig()
+				Expect(cfg.ConfigSource).To(Equal(utils.ConfigSourceBothLabelsFirst))
+			})
 		})
 
 		Context("with invalid environment values", func() {
 			It("should use default for invalid port", func() {
 				Expect(os.Setenv("PORT", "invalid")).To(Succeed())
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 				Expect(cfg.Port).To(Equal(8443))
 			})
 
 			It("should use default for invalid boolean", func() {
 				Expect(os.Setenv("ADD_TRACKING_ANNOTATIONS", "not-a-bool")).To(Succeed())
-				cfg := config.LoadConfig()
+				cfg := loadConfig()
 				Expect(cfg.AddTrackingAnnotations).To(BeTrue())
 			})
+
+			It("should reject values outside the allowed set", func() {
+				Expect(os.Setenv("ERROR_HANDLING_MODE", "ignore")).To(Succeed())
+				_, err := config.LoadConfig()
+				Expect(err).To(MatchError(ContainSubstring(`unknown errorHandlingMode "ignore"`)))
+			})
+		})
+	})
+
+	Describe("LoadPCIResourceMap", func() {
+		writeMap := func(content string) string {
+			path := filepath.Join(GinkgoT().TempDir(), "pci-resource-map.yaml")
+			Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+			return path
+		}
+
+		It("should load addresses and aliases", func() {
+			path := writeMap(`"0000:01:00.0": nvidia.com/GA102
+"0000:0A:00.0": intel.com/QAT
+fast-nic: mellanox.com/ConnectX6
+`)
+			resourceMap, err := config.LoadPCIResourceMap(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resourceMap).To(HaveKeyWithValue("0000:01:00.0", "nvidia.com/GA102"))
+			Expect(resourceMap).To(HaveKeyWithValue("0000:0a:00.0", "intel.com/QAT"))
+			Expect(resourceMap).To(HaveKeyWithValue("fast-nic", "mellanox.com/ConnectX6"))
+		})
+
+		It("should reject entries without a resource name", func() {
+			path := writeMap(`fast-nic: ""
+`)
+			_, err := config.LoadPCIResourceMap(path)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail for a missing file", func() {
+			_, err := config.LoadPCIResourceMap(filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("LoadFeatureRules", func() {
+		writeRules := func(content string) string {
+			path := filepath.Join(GinkgoT().TempDir(), "feature-rules.yaml")
+			Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+			return path
+		}
+
+		It("should load rules", func() {
+			path := writeRules(`- name: ml-hugepages
+  expression: "memory > quantity('16Gi') && namespaceName.startsWith('ml-')"
+  enable:
+    nested-virt: enabled
+  deny:
+    - pci-passthrough
+`)
+			rules, err := config.LoadFeatureRules(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rules).To(HaveLen(1))
+			Expect(rules[0].Name).To(Equal("ml-hugepages"))
+			Expect(rules[0].Enable).To(HaveKeyWithValue("nested-virt", "enabled"))
+			Expect(rules[0].Deny).To(ConsistOf("pci-passthrough"))
+		})
+
+		It("should reject unknown fields", func() {
+			path := writeRules(`- name: typo
+  expresion: "true"
+`)
+			_, err := config.LoadFeatureRules(path)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail for a missing file", func() {
+			_, err := config.LoadFeatureRules(filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("LoadConfigFile", func() {
+		writeConfig := func(name, content string) string {
+			path := filepath.Join(GinkgoT().TempDir(), name)
+			Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+			return path
+		}
+
+		It("should load nested settings and keep defaults for the rest", func() {
+			path := writeConfig("config.yaml", `port: 9443
+configSource: both
+configSourcePrecedence: labels
+features:
+  pciPassthrough:
+    maxDevices: 4
+    autoRegisterAllowlist:
+      nvidia.com/GA102: "10DE:2204"
+  gpuDevicePlugin:
+    allowedPlugins:
+      - nvidia.com/gpu
+plugins:
+  grpc:
+    team-label: team-label.plugins.svc:9000
+`)
+			cfg, err := config.LoadConfigFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.Port).To(Equal(9443))
+			Expect(cfg.ConfigSource).To(Equal(utils.ConfigSourceBothLabelsFirst))
+			Expect(cfg.Features.PCIPassthrough.MaxDevices).To(Equal(4))
+			Expect(cfg.Features.PCIPassthrough.AutoRegisterAllowlist).To(HaveKeyWithValue("nvidia.com/GA102", "10DE:2204"))
+			Expect(cfg.Features.GPUDevicePlugin.AllowedPlugins).To(Equal([]string{"nvidia.com/gpu"}))
+			Expect(cfg.Features.GPUDevicePlugin.MaxDevices).To(Equal(8))
+			Expect(cfg.Plugins.GRPC).To(HaveKeyWithValue("team-label", "team-label.plugins.svc:9000"))
+			Expect(cfg.CertDir).To(Equal("/etc/webhook/certs"))
+		})
+
+		It("should load JSON", func() {
+			path := writeConfig("config.json", `{"logLevel": "debug", "features": {"hyperV": {"enabled": false}}}`)
+			cfg, err := config.LoadConfigFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.LogLevel).To(Equal("debug"))
+			Expect(cfg.Features.HyperV.Enabled).To(BeFalse())
+		})
+
+		It("should let environment variables override the file", func() {
+			Expect(os.Setenv("PCI_MAX_DEVICES", "2")).To(Succeed())
+			path := writeConfig("config.yaml", `features:
+  pciPassthrough:
+    maxDevices: 4
+    enabled: false
+`)
+			cfg, err := config.LoadConfigFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.Features.PCIPassthrough.MaxDevices).To(Equal(2))
+			Expect(cfg.Features.PCIPassthrough.Enabled).To(BeFalse())
+		})
+
+		It("should validate environment overrides", func() {
+			Expect(os.Setenv("WEBHOOK_MODE", "audit")).To(Succeed())
+			path := writeConfig("config.yaml", "mode: shadow\n")
+			_, err := config.LoadConfigFile(path)
+			Expect(err).To(MatchError(ContainSubstring(`unknown mode "audit"`)))
+		})
+
+		It("should reject unknown keys", func() {
+			path := writeConfig("config.yaml", `features:
+  pciPasthrough:
+    maxDevices: 4
+`)
+			_, err := config.LoadConfigFile(path)
+			Expect(err).To(MatchError(ContainSubstring("pciPasthrough")))
+		})
+
+		It("should reject invalid values", func() {
+			path := writeConfig("config.yaml", "errorHandlingMode: ignore\n")
+			_, err := config.LoadConfigFile(path)
+			Expect(err).To(MatchError(ContainSubstring(`unknown errorHandlingMode "ignore"`)))
+
+			path = writeConfig("config.yaml", "userdataSecrets:\n  guard: everything\n")
+			_, err = config.LoadConfigFile(path)
+			Expect(err).To(MatchError(ContainSubstring(`unknown userdataSecrets.guard "everything"`)))
+
+			path = writeConfig("config.yaml", "timeouts:\n  onTimeout: ignore\n")
+			_, err = config.LoadConfigFile(path)
+			Expect(err).To(MatchError(ContainSubstring(`unknown timeouts.onTimeout "ignore"`)))
+
+			path = writeConfig("config.yaml", "reconcile:\n  enabled: true\n  qps: 0\n")
+			_, err = config.LoadConfigFile(path)
+			Expect(err).To(MatchError(ContainSubstring("reconcile.qps")))
+
+			path = writeConfig("config.yaml", "mode: audit\n")
+			_, err = config.LoadConfigFile(path)
+			Expect(err).To(MatchError(ContainSubstring(`unknown mode "audit"`)))
+
+			path = writeConfig("config.yaml", "concurrency:\n  onSaturated: queue\n")
+			_, err = config.LoadConfigFile(path)
+			Expect(err).To(MatchError(ContainSubstring(`unknown concurrency.onSaturated "queue"`)))
+
+			path = writeConfig("config.yaml", "healthPort: 8443\n")
+			_, err = config.LoadConfigFile(path)
+			Expect(err).To(MatchError(ContainSubstring("healthPort 8443")))
+
+			path = writeConfig("config.yaml", "server:\n  maxRequestBytes: -1\n")
+			_, err = config.LoadConfigFile(path)
+			Expect(err).To(MatchError(ContainSubstring("must not be negative")))
+		})
+
+		It("should fail for a missing file", func() {
+			_, err := config.LoadConfigFile(filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+			Expect(err).To(HaveOccurred())
 		})
 	})
 })
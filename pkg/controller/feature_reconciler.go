@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+)
+
+// Admitter computes the mutation admission would apply to an existing
+// object, as the webhook's Mutator does. Unlike admission itself, it must not
+// shed or time out the request, since nothing would retry it.
+type Admitter interface {
+	Mutate(ctx context.Context, req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error)
+}
+
+// FeatureReconciler brings VMs that exist already, e.g. created before the
+// webhook was installed or while it was down, to the state admission would
+// have given them. Each VM is passed to the admitter as an UPDATE that
+// changes nothing and the returned patch is applied to it.
+type FeatureReconciler struct {
+	client   client.Client
+	admitter Admitter
+	config   *config.ReconcileConfig
+}
+
+// NewFeatureReconciler creates a new FeatureReconciler
+func NewFeatureReconciler(k8sClient client.Client, admitter Admitter, cfg *config.ReconcileConfig) *FeatureReconciler {
+	return &FeatureReconciler{
+		client:   k8sClient,
+		admitter: admitter,
+		config:   cfg,
+	}
+}
+
+// SetupWithManager registers the reconciler for the VMs listed at startup.
+// Later changes go through the API server and so through the webhook.
+func (r *FeatureReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	listed := predicate.Funcs{
+		UpdateFunc:  func(event.UpdateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("feature-reconciler").
+		For(&kubevirtv1.VirtualMachine{}).
+		WithEventFilter(listed).
+		WithOptions(controller.Options{RateLimiter: r.rateLimiter()}).
+		Complete(r)
+}
+
+// rateLimiter bounds reconciles to the configured rate overall and backs off
+// VMs that keep failing
+func (r *FeatureReconciler) rateLimiter() workqueue.TypedRateLimiter[reconcile.Request] {
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](time.Second, 5*time.Minute),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(r.config.QPS), r.config.Burst)},
+	)
+}
+
+// Reconcile admits the VM again and patches it with the result
+func (r *FeatureReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("vm", req.NamespacedName)
+
+	vm := &kubevirtv1.VirtualMachine{}
+	if err := r.client.Get(ctx, req.NamespacedName, vm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get VM %s: %w", req.NamespacedName, err)
+	}
+	if !vm.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	gvk := kubevirtv1.SchemeGroupVersion.WithKind("VirtualMachine")
+	vm.APIVersion, vm.Kind = gvk.ToAPIVersionAndKind()
+	raw, err := json.Marshal(vm)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The old object is the stored VM too, so features it still records as
+	// applied but no longer requests are reverted
+	response, err := r.admitter.Mutate(ctx, &admissionv1.AdmissionRequest{
+		UID:       types.UID(fmt.Sprintf("reconcile-%s-%s", vm.UID, vm.ResourceVersion)),
+		Kind:      metav1.GroupVersionKind(gvk),
+		Resource:  metav1.GroupVersionResource{Group: kubevirtv1.SchemeGroupVersion.Group, Version: kubevirtv1.SchemeGroupVersion.Version, Resource: "virtualmachines"},
+		Name:      vm.Name,
+		Namespace: vm.Namespace,
+		Operation: admissionv1.Update,
+		Object:    runtime.RawExtension{Raw: raw},
+		OldObject: runtime.RawExtension{Raw: raw},
+	})
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to admit VM %s: %w", req.NamespacedName, err)
+	}
+	if !response.Allowed {
+		reason := ""
+		if response.Result != nil {
+			reason = response.Result.Message
+		}
+		logger.Info("Admission rejected the VM, leaving it unchanged", "reason", reason)
+		return ctrl.Result{}, nil
+	}
+	if len(response.Patch) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	patch, err := guardResourceVersion(response.Patch, vm.ResourceVersion)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.client.Patch(ctx, vm, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to patch VM %s: %w", req.NamespacedName, err)
+	}
+
+	logger.Info("Applied requested features to existing VM", "warnings", response.Warnings)
+	return ctrl.Result{}, nil
+}
+
+// guardResourceVersion prepends a test of the resource version to a JSON
+// patch, so it fails instead of applying to a VM changed in the meantime
+func guardResourceVersion(patch []byte, resourceVersion string) ([]byte, error) {
+	var ops []json.RawMessage
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	test, err := json.Marshal(map[string]string{
+		"op":    "test",
+		"path":  "/metadata/resourceVersion",
+		"value": resourceVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(append([]json.RawMessage{test}, ops...))
+}
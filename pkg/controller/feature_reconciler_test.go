@@ -0,0 +1,132 @@
+package controller_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/controller"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/webhook"
+)
+
+// admitterFunc adapts a function to the Admitter interface
+type admitterFunc func(context.Context, *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error)
+
+func (f admitterFunc) Mutate(ctx context.Context, req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	return f(ctx, req)
+}
+
+var _ = Describe("FeatureReconciler", func() {
+	var (
+		ctx      context.Context
+		scheme   *runtime.Scheme
+		vm       *kubevirtv1.VirtualMachine
+		admitter controller.Admitter
+	)
+
+	vmKey := types.NamespacedName{Namespace: "default", Name: "test-vm"}
+
+	reconcile := func(objs ...client.Object) client.Client {
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		r := controller.NewFeatureReconciler(k8sClient, admitter, &config.ReconcileConfig{Enabled: true, QPS: 5, Burst: 10})
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: vmKey})
+		Expect(err).ToNot(HaveOccurred())
+		return k8sClient
+	}
+
+	getVM := func(k8sClient client.Client) *kubevirtv1.VirtualMachine {
+		updated := &kubevirtv1.VirtualMachine{}
+		Expect(k8sClient.Get(ctx, vmKey, updated)).To(Succeed())
+		return updated
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme = runtime.NewScheme()
+		_ = kubevirtv1.AddToScheme(scheme)
+
+		cfg := &config.Config{
+			AddTrackingAnnotations: true,
+			ErrorHandlingMode:      utils.ErrorHandlingReject,
+			ConfigSource:           utils.ConfigSourceAnnotations,
+		}
+		mutator, err := webhook.NewMutator(nil, cfg, []features.Feature{
+			features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true, AutoDetectCPU: true}, utils.ConfigSourceAnnotations),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		admitter = mutator
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        vmKey.Name,
+				Namespace:   vmKey.Namespace,
+				Annotations: map[string]string{utils.AnnotationNestedVirt: "enabled"},
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+			},
+		}
+	})
+
+	It("should apply requested features to an existing VM", func() {
+		updated := getVM(reconcile(vm))
+
+		Expect(updated.Annotations).To(HaveKeyWithValue(utils.AnnotationNestedVirtApplied, "true"))
+		Expect(updated.Annotations).To(HaveKey(utils.AnnotationLastMutation))
+		Expect(updated.Spec.Template.Spec.Domain.CPU).ToNot(BeNil())
+		Expect(updated.Spec.Template.Spec.Domain.CPU.Features).ToNot(BeEmpty())
+	})
+
+	It("should leave a VM without feature requests unchanged", func() {
+		vm.Annotations = nil
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vm).Build()
+		before := getVM(k8sClient)
+
+		r := controller.NewFeatureReconciler(k8sClient, admitter, &config.ReconcileConfig{Enabled: true, QPS: 5, Burst: 10})
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: vmKey})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(getVM(k8sClient).ResourceVersion).To(Equal(before.ResourceVersion))
+	})
+
+	It("should leave a VM unchanged when admission rejects it", func() {
+		admitter = admitterFunc(func(context.Context, *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+			return &admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result:  &metav1.Status{Message: "feature nested-virt failed"},
+			}, nil
+		})
+
+		updated := getVM(reconcile(vm))
+		Expect(updated.Annotations).ToNot(HaveKey(utils.AnnotationNestedVirtApplied))
+	})
+
+	It("should pass the VM as an UPDATE that changes nothing", func() {
+		var seen *admissionv1.AdmissionRequest
+		admitter = admitterFunc(func(_ context.Context, req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+			seen = req
+			return &admissionv1.AdmissionResponse{Allowed: true}, nil
+		})
+
+		reconcile(vm)
+		Expect(seen).ToNot(BeNil())
+		Expect(seen.Operation).To(Equal(admissionv1.Update))
+		Expect(seen.Kind.Kind).To(Equal("VirtualMachine"))
+		Expect(seen.Object.Raw).To(Equal(seen.OldObject.Raw))
+	})
+
+	It("should ignore VMs that no longer exist", func() {
+		reconcile()
+	})
+})
@@ -0,0 +1,141 @@
+// Package controller implements optional controllers that complement the admission webhook.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// PCIRegistrationReconciler adds the PCI devices requested by VMs to the
+// permittedHostDevices of the KubeVirt CR. Only resource names present in the
+// allowlist are registered, using the VENDOR:DEVICE selector configured for them.
+type PCIRegistrationReconciler struct {
+	client       client.Client
+	config       *config.PCIPassthroughConfig
+	configSource utils.ConfigSource
+	keyPrefix    string
+	pci          *features.PciPassthrough
+}
+
+// NewPCIRegistrationReconciler creates a new PCIRegistrationReconciler
+func NewPCIRegistrationReconciler(k8sClient client.Client, cfg *config.PCIPassthroughConfig, configSource utils.ConfigSource, keyPrefix string) *PCIRegistrationReconciler {
+	return &PCIRegistrationReconciler{
+		client:       k8sClient,
+		config:       cfg,
+		configSource: configSource,
+		keyPrefix:    keyPrefix,
+		pci:          features.NewPciPassthrough(cfg, configSource),
+	}
+}
+
+// SetupWithManager registers the reconciler for VMs requesting PCI passthrough
+func (r *PCIRegistrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	requestsPCI := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		key := utils.AnnotationPciPassthrough
+		if r.keyPrefix != "" {
+			key = r.keyPrefix + strings.TrimPrefix(key, utils.DefaultKeyPrefix)
+		}
+		value, exists := utils.GetConfigValue(r.configSource, obj.GetAnnotations(), obj.GetLabels(), key)
+		return exists && value != ""
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("pci-registration").
+		For(&kubevirtv1.VirtualMachine{}).
+		WithEventFilter(requestsPCI).
+		Complete(r)
+}
+
+// Reconcile registers any allowlisted devices requested by the VM that the
+// KubeVirt CR does not permit yet
+func (r *PCIRegistrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("vm", req.NamespacedName)
+
+	vm := &kubevirtv1.VirtualMachine{}
+	if err := r.client.Get(ctx, req.NamespacedName, vm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get VM %s: %w", req.NamespacedName, err)
+	}
+	utils.SwapKeyPrefix(vm.Labels, r.keyPrefix)
+	utils.SwapKeyPrefix(vm.Annotations, r.keyPrefix)
+
+	names, err := r.pci.ResourceNames(vm)
+	if err != nil {
+		// Invalid configuration is reported by the webhook; nothing to register
+		logger.Info("Ignoring invalid PCI passthrough configuration", "reason", err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	wanted := make(map[string]string)
+	for _, name := range names {
+		selector, ok := r.config.AutoRegisterAllowlist[name]
+		if !ok {
+			logger.V(1).Info("PCI resource not in auto-register allowlist, skipping", "resourceName", name)
+			continue
+		}
+		wanted[name] = selector
+	}
+	if len(wanted) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	kubevirts := &kubevirtv1.KubeVirtList{}
+	if err := r.client.List(ctx, kubevirts); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list KubeVirt resources: %w", err)
+	}
+	if len(kubevirts.Items) == 0 {
+		logger.Info("No KubeVirt CR found, cannot register PCI devices")
+		return ctrl.Result{}, nil
+	}
+
+	kv := &kubevirts.Items[0]
+	base := kv.DeepCopy()
+
+	if kv.Spec.Configuration.PermittedHostDevices == nil {
+		kv.Spec.Configuration.PermittedHostDevices = &kubevirtv1.PermittedHostDevices{}
+	}
+	permitted := kv.Spec.Configuration.PermittedHostDevices
+	for _, dev := range permitted.PciHostDevices {
+		delete(wanted, dev.ResourceName)
+	}
+	if len(wanted) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	added := make([]string, 0, len(wanted))
+	for name := range wanted {
+		added = append(added, name)
+	}
+	sort.Strings(added)
+	for _, name := range added {
+		permitted.PciHostDevices = append(permitted.PciHostDevices, kubevirtv1.PciHostDevice{
+			PCIVendorSelector: wanted[name],
+			ResourceName:      name,
+		})
+	}
+
+	// Optimistic locking makes concurrent registrations retry instead of
+	// overwriting each other's additions to the list
+	patch := client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})
+	if err := r.client.Patch(ctx, kv, patch); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to patch KubeVirt %s/%s: %w", kv.Namespace, kv.Name, err)
+	}
+
+	logger.Info("Registered PCI devices in KubeVirt permittedHostDevices", "kubevirt", kv.Name, "resourceNames", added)
+	return ctrl.Result{}, nil
+}
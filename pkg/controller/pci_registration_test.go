@@ -0,0 +1,146 @@
+package controller_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/controller"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("PCIRegistrationReconciler", func() {
+	var (
+		ctx       context.Context
+		pciCfg    *config.PCIPassthroughConfig
+		keyPrefix string
+		scheme    *runtime.Scheme
+		vm        *kubevirtv1.VirtualMachine
+		kv        *kubevirtv1.KubeVirt
+	)
+
+	vmKey := types.NamespacedName{Namespace: "default", Name: "test-vm"}
+	kvKey := types.NamespacedName{Namespace: "kubevirt", Name: "kubevirt"}
+
+	reconcile := func(objs ...client.Object) client.Client {
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		r := controller.NewPCIRegistrationReconciler(k8sClient, pciCfg, utils.ConfigSourceAnnotations, keyPrefix)
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: vmKey})
+		Expect(err).ToNot(HaveOccurred())
+		return k8sClient
+	}
+
+	permittedDevices := func(k8sClient client.Client) []kubevirtv1.PciHostDevice {
+		updated := &kubevirtv1.KubeVirt{}
+		Expect(k8sClient.Get(ctx, kvKey, updated)).To(Succeed())
+		if updated.Spec.Configuration.PermittedHostDevices == nil {
+			return nil
+		}
+		return updated.Spec.Configuration.PermittedHostDevices.PciHostDevices
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pciCfg = &config.PCIPassthroughConfig{
+			Enabled:      true,
+			AutoRegister: true,
+			ResourceMap: map[string]string{
+				"0000:01:00.0": "nvidia.com/GA102",
+				"0000:02:00.0": "intel.com/QAT",
+			},
+			AutoRegisterAllowlist: map[string]string{
+				"nvidia.com/GA102": "10DE:2204",
+			},
+		}
+
+		keyPrefix = utils.DefaultKeyPrefix
+
+		scheme = runtime.NewScheme()
+		_ = kubevirtv1.AddToScheme(scheme)
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      vmKey.Name,
+				Namespace: vmKey.Namespace,
+				Annotations: map[string]string{
+					utils.AnnotationPciPassthrough: `{"devices": ["0000:01:00.0", "0000:02:00.0"]}`,
+				},
+			},
+		}
+		kv = &kubevirtv1.KubeVirt{
+			ObjectMeta: metav1.ObjectMeta{Name: kvKey.Name, Namespace: kvKey.Namespace},
+		}
+	})
+
+	It("should register allowlisted devices", func() {
+		k8sClient := reconcile(vm, kv)
+
+		devices := permittedDevices(k8sClient)
+		Expect(devices).To(HaveLen(1))
+		Expect(devices[0].ResourceName).To(Equal("nvidia.com/GA102"))
+		Expect(devices[0].PCIVendorSelector).To(Equal("10DE:2204"))
+	})
+
+	It("should read requests under a custom key prefix", func() {
+		keyPrefix = "ourcompany.io/vm-"
+		request := vm.Annotations[utils.AnnotationPciPassthrough]
+		vm.Annotations = map[string]string{"ourcompany.io/vm-pci-passthrough": request}
+
+		devices := permittedDevices(reconcile(vm, kv))
+		Expect(devices).To(HaveLen(1))
+		Expect(devices[0].ResourceName).To(Equal("nvidia.com/GA102"))
+	})
+
+	It("should ignore requests of the default prefix under a custom one", func() {
+		keyPrefix = "ourcompany.io/vm-"
+		Expect(permittedDevices(reconcile(vm, kv))).To(BeEmpty())
+	})
+
+	It("should keep existing permitted devices", func() {
+		kv.Spec.Configuration.PermittedHostDevices = &kubevirtv1.PermittedHostDevices{
+			PciHostDevices: []kubevirtv1.PciHostDevice{
+				{PCIVendorSelector: "8086:4940", ResourceName: "intel.com/QAT"},
+			},
+		}
+		k8sClient := reconcile(vm, kv)
+
+		devices := permittedDevices(k8sClient)
+		Expect(devices).To(HaveLen(2))
+		Expect(devices[0].ResourceName).To(Equal("intel.com/QAT"))
+		Expect(devices[1].ResourceName).To(Equal("nvidia.com/GA102"))
+	})
+
+	It("should not duplicate already permitted devices", func() {
+		kv.Spec.Configuration.PermittedHostDevices = &kubevirtv1.PermittedHostDevices{
+			PciHostDevices: []kubevirtv1.PciHostDevice{
+				{PCIVendorSelector: "10DE:2204", ResourceName: "nvidia.com/GA102"},
+			},
+		}
+		k8sClient := reconcile(vm, kv)
+		Expect(permittedDevices(k8sClient)).To(HaveLen(1))
+	})
+
+	It("should not register devices outside the allowlist", func() {
+		pciCfg.AutoRegisterAllowlist = map[string]string{}
+		k8sClient := reconcile(vm, kv)
+		Expect(permittedDevices(k8sClient)).To(BeEmpty())
+	})
+
+	It("should ignore VMs that no longer exist", func() {
+		k8sClient := reconcile(kv)
+		Expect(permittedDevices(k8sClient)).To(BeEmpty())
+	})
+
+	It("should do nothing without a KubeVirt CR", func() {
+		reconcile(vm)
+	})
+})
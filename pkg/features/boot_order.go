@@ -0,0 +1,149 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// BootOrder implements boot order configuration.
+// The annotation maps disk or interface names to boot order integers,
+// e.g. {"default": 1, "rootdisk": 2} to boot from the network first.
+type BootOrder struct {
+	configSource utils.ConfigSource
+}
+
+// NewBootOrder creates a new BootOrder feature
+func NewBootOrder(configSource utils.ConfigSource) *BootOrder {
+	return &BootOrder{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *BootOrder) Name() string {
+	return utils.FeatureBootOrder
+}
+
+// IsEnabled checks if a boot order is requested via annotations or labels
+func (f *BootOrder) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationBootOrder)
+	return exists && value != ""
+}
+
+// Validate ensures the boot order is valid and refers to existing devices
+func (f *BootOrder) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationBootOrder)
+	if !exists {
+		return nil
+	}
+
+	orders, err := parseBootOrder(value)
+	if err != nil {
+		return err
+	}
+
+	if vm.Spec.Template == nil {
+		return fmt.Errorf("VM template is nil")
+	}
+
+	devices := vm.Spec.Template.Spec.Domain.Devices
+	known := make(map[string]bool, len(devices.Disks)+len(devices.Interfaces))
+	for _, disk := range devices.Disks {
+		known[disk.Name] = true
+	}
+	for _, iface := range devices.Interfaces {
+		known[iface.Name] = true
+	}
+
+	for _, name := range sortedBootOrderNames(orders) {
+		if !known[name] {
+			return fmt.Errorf("unknown device %q in %s: no disk or interface with that name", name, utils.AnnotationBootOrder)
+		}
+	}
+
+	return nil
+}
+
+// Apply sets the boot order on the matching disks and interfaces
+func (f *BootOrder) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationBootOrder)
+	orders, _ := parseBootOrder(value)
+
+	logger.Info("Applying boot order", "vm", vm.Name, "bootOrder", orders)
+
+	devices := &vm.Spec.Template.Spec.Domain.Devices
+	for i := range devices.Disks {
+		if order, ok := orders[devices.Disks[i].Name]; ok {
+			bootOrder := order
+			devices.Disks[i].BootOrder = &bootOrder
+		}
+	}
+	for i := range devices.Interfaces {
+		if order, ok := orders[devices.Interfaces[i].Name]; ok {
+			bootOrder := order
+			devices.Interfaces[i].BootOrder = &bootOrder
+		}
+	}
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationBootOrderApplied, value)
+	result.AddMessage(fmt.Sprintf("Set boot order for %d devices", len(orders)))
+
+	return result, nil
+}
+
+// parseBootOrder parses the boot order annotation, ensuring orders are positive and unique
+func parseBootOrder(value string) (map[string]uint, error) {
+	var orders map[string]uint
+	if err := json.Unmarshal([]byte(value), &orders); err != nil {
+		return nil, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationBootOrder, err)
+	}
+
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("no devices specified in %s", utils.AnnotationBootOrder)
+	}
+
+	seen := make(map[uint]string, len(orders))
+	for _, name := range sortedBootOrderNames(orders) {
+		order := orders[name]
+		if order == 0 {
+			return nil, fmt.Errorf("invalid boot order for %q: must be greater than 0", name)
+		}
+		if other, ok := seen[order]; ok {
+			return nil, fmt.Errorf("duplicate boot order %d for %q and %q", order, other, name)
+		}
+		seen[order] = name
+	}
+
+	return orders, nil
+}
+
+// sortedBootOrderNames returns the device names in a stable order for deterministic errors
+func sortedBootOrderNames(orders map[string]uint) []string {
+	names := make([]string, 0, len(orders))
+	for name := range orders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,138 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("BootOrder", func() {
+	var (
+		feature *features.BootOrder
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewBootOrder(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							Devices: kubevirtv1.Devices{
+								Disks: []kubevirtv1.Disk{
+									{Name: "rootdisk"},
+									{Name: "cdrom"},
+								},
+								Interfaces: []kubevirtv1.Interface{
+									{Name: "default"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureBootOrder))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBootOrder: `{"default": 1}`}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept known disks and interfaces", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBootOrder: `{"default": 1, "rootdisk": 2}`}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject unknown device names", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBootOrder: `{"floppy": 1}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unknown device \"floppy\""))
+		})
+
+		It("should reject malformed JSON", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBootOrder: `{"rootdisk": "first"}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid JSON"))
+		})
+
+		It("should reject a zero boot order", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBootOrder: `{"rootdisk": 0}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("greater than 0"))
+		})
+
+		It("should reject duplicate boot orders", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBootOrder: `{"rootdisk": 1, "cdrom": 1}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("duplicate boot order"))
+		})
+
+		It("should reject an empty map", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBootOrder: `{}`}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should set boot order on matching devices", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBootOrder: `{"cdrom": 1, "rootdisk": 2, "default": 3}`}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+
+			devices := vm.Spec.Template.Spec.Domain.Devices
+			Expect(*devices.Disks[0].BootOrder).To(Equal(uint(2)))
+			Expect(*devices.Disks[1].BootOrder).To(Equal(uint(1)))
+			Expect(*devices.Interfaces[0].BootOrder).To(Equal(uint(3)))
+		})
+
+		It("should leave unlisted devices untouched", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBootOrder: `{"default": 1}`}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			devices := vm.Spec.Template.Spec.Domain.Devices
+			Expect(devices.Disks[0].BootOrder).To(BeNil())
+			Expect(*devices.Interfaces[0].BootOrder).To(Equal(uint(1)))
+		})
+
+		It("should return error for unknown devices", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBootOrder: `{"nic1": 1}`}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+		})
+	})
+})
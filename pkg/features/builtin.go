@@ -0,0 +1,42 @@
+package features
+
+import (
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+)
+
+// Builtin returns the features built into the webhook, configured from cfg
+func Builtin(cfg *config.Config) []Feature {
+	return []Feature{
+		NewNestedVirtualization(&cfg.Features.NestedVirtualization, cfg.ConfigSource),
+		NewPciPassthrough(&cfg.Features.PCIPassthrough, cfg.ConfigSource),
+		NewVBiosInjection(&cfg.Features.VBiosInjection, cfg.ConfigSource),
+		NewGpuDevicePlugin(&cfg.Features.GPUDevicePlugin, cfg.ConfigSource),
+		NewTpm(cfg.ConfigSource),
+		NewSev(cfg.ConfigSource),
+		NewDedicatedCPUs(cfg.ConfigSource),
+		NewNuma(cfg.ConfigSource),
+		NewRealtime(cfg.ConfigSource),
+		NewHyperV(&cfg.Features.HyperV, cfg.ConfigSource),
+		NewCPUModel(&cfg.Features.CPUModel, cfg.ConfigSource),
+		NewVGpu(&cfg.Features.VGpu, cfg.ConfigSource),
+		NewUsbPassthrough(cfg.ConfigSource),
+		NewStoragePerformance(cfg.ConfigSource),
+		NewNetMultiQueue(cfg.ConfigSource),
+		NewBootOrder(cfg.ConfigSource),
+		NewSmbios(cfg.ConfigSource),
+		NewTolerations(cfg.ConfigSource),
+		NewCPUTopology(cfg.ConfigSource),
+		NewGuaranteedQoS(cfg.ConfigSource),
+		NewHotplug(cfg.ConfigSource),
+		NewKernelBoot(cfg.ConfigSource),
+		NewCdromIso(cfg.ConfigSource),
+		NewSSHKeys(cfg.ConfigSource),
+		NewSysprep(cfg.ConfigSource),
+		NewGuestAgent(cfg.ConfigSource),
+		NewMacAddresses(cfg.ConfigSource),
+		NewOSPreset(&cfg.Features.WindowsPreset, &cfg.Features.HyperV, cfg.ConfigSource),
+		NewMeshExclude(cfg.ConfigSource),
+		NewPropagateMetadata(&cfg.Features.MetadataPropagation, cfg.ConfigSource),
+		NewHookSidecars(cfg.ConfigSource),
+	}
+}
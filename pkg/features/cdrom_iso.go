@@ -0,0 +1,207 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// cdromIsoDeviceName is the name of the injected CD-ROM disk and volume
+const cdromIsoDeviceName = "cdrom-iso"
+
+// dataVolumeGVK identifies CDI DataVolumes, looked up without importing the CDI API
+var dataVolumeGVK = schema.GroupVersionKind{Group: "cdi.kubevirt.io", Version: "v1beta1", Kind: "DataVolume"}
+
+// CdromIso implements attaching an ISO image as a bootable CD-ROM.
+// The annotation names the source as "datavolume/<name>" or "pvc/<name>";
+// a bare name is treated as a PVC.
+type CdromIso struct {
+	configSource utils.ConfigSource
+}
+
+// NewCdromIso creates a new CdromIso feature
+func NewCdromIso(configSource utils.ConfigSource) *CdromIso {
+	return &CdromIso{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *CdromIso) Name() string {
+	return utils.FeatureCdromIso
+}
+
+// IsEnabled checks if a CD-ROM ISO is requested via annotations or labels
+func (f *CdromIso) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationCdromIso)
+	return exists && value != ""
+}
+
+// Validate ensures the ISO source is well-formed and exists in the VM namespace
+func (f *CdromIso) Validate(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationCdromIso)
+	if !exists {
+		return nil
+	}
+
+	kind, name, err := parseCdromIsoSource(value)
+	if err != nil {
+		return err
+	}
+
+	// Existence can only be checked when a client is available
+	if k8sClient == nil {
+		return nil
+	}
+
+	key := types.NamespacedName{Namespace: vm.Namespace, Name: name}
+	var obj client.Object
+	if kind == utils.CdromIsoSourceDataVolume {
+		dv := &unstructured.Unstructured{}
+		dv.SetGroupVersionKind(dataVolumeGVK)
+		obj = dv
+	} else {
+		obj = &corev1.PersistentVolumeClaim{}
+	}
+
+	if err := k8sClient.Get(ctx, key, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("%s %s/%s not found", kind, vm.Namespace, name)
+		}
+		return fmt.Errorf("failed to get %s %s/%s: %w", kind, vm.Namespace, name, err)
+	}
+
+	return nil
+}
+
+// Apply adds the CD-ROM disk and volume and makes it the first boot device
+func (f *CdromIso) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationCdromIso)
+	kind, name, _ := parseCdromIsoSource(value)
+
+	spec := &vm.Spec.Template.Spec
+	for _, disk := range spec.Domain.Devices.Disks {
+		if disk.Name == cdromIsoDeviceName {
+			logger.Info("CD-ROM ISO disk already present, skipping", "vm", vm.Name)
+			return result, nil
+		}
+	}
+
+	logger.Info("Applying CD-ROM ISO", "vm", vm.Name, "source", kind, "name", name)
+
+	bootFirst(&spec.Domain.Devices)
+
+	bootOrder := uint(1)
+	spec.Domain.Devices.Disks = append(spec.Domain.Devices.Disks, kubevirtv1.Disk{
+		Name:      cdromIsoDeviceName,
+		BootOrder: &bootOrder,
+		DiskDevice: kubevirtv1.DiskDevice{
+			CDRom: &kubevirtv1.CDRomTarget{
+				Bus: kubevirtv1.DiskBusSATA,
+			},
+		},
+	})
+
+	volume := kubevirtv1.Volume{Name: cdromIsoDeviceName}
+	if kind == utils.CdromIsoSourceDataVolume {
+		volume.DataVolume = &kubevirtv1.DataVolumeSource{Name: name}
+	} else {
+		volume.PersistentVolumeClaim = &kubevirtv1.PersistentVolumeClaimVolumeSource{
+			PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: name,
+				ReadOnly:  true,
+			},
+		}
+	}
+	spec.Volumes = append(spec.Volumes, volume)
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationCdromIsoApplied, kind+"/"+name)
+	result.AddMessage(fmt.Sprintf("Attached ISO from %s %s as CD-ROM", kind, name))
+
+	return result, nil
+}
+
+// bootFirst frees boot order 1 for the CD-ROM. Existing boot orders are shifted
+// to keep their relative order; when none are set, the existing disks follow the
+// CD-ROM in declaration order so the installed system remains bootable.
+func bootFirst(devices *kubevirtv1.Devices) {
+	hasOrder := false
+	for _, disk := range devices.Disks {
+		hasOrder = hasOrder || disk.BootOrder != nil
+	}
+	for _, iface := range devices.Interfaces {
+		hasOrder = hasOrder || iface.BootOrder != nil
+	}
+
+	if !hasOrder {
+		for i := range devices.Disks {
+			order := uint(i + 2)
+			devices.Disks[i].BootOrder = &order
+		}
+		return
+	}
+
+	for i := range devices.Disks {
+		if devices.Disks[i].BootOrder != nil {
+			order := *devices.Disks[i].BootOrder + 1
+			devices.Disks[i].BootOrder = &order
+		}
+	}
+	for i := range devices.Interfaces {
+		if devices.Interfaces[i].BootOrder != nil {
+			order := *devices.Interfaces[i].BootOrder + 1
+			devices.Interfaces[i].BootOrder = &order
+		}
+	}
+}
+
+// parseCdromIsoSource splits the annotation value into source kind and name
+func parseCdromIsoSource(value string) (string, string, error) {
+	kind, name := utils.CdromIsoSourcePVC, value
+	if prefix, rest, found := strings.Cut(value, "/"); found {
+		kind, name = strings.ToLower(prefix), rest
+	}
+
+	if kind != utils.CdromIsoSourceDataVolume && kind != utils.CdromIsoSourcePVC {
+		return "", "", fmt.Errorf("invalid source kind %q in %s (must be %s or %s)",
+			kind, utils.AnnotationCdromIso, utils.CdromIsoSourceDataVolume, utils.CdromIsoSourcePVC)
+	}
+
+	if name == "" {
+		return "", "", fmt.Errorf("empty source name in %s", utils.AnnotationCdromIso)
+	}
+
+	if len(name) > 253 || !configMapNameRegex.MatchString(name) {
+		return "", "", fmt.Errorf("invalid %s name %q (must be a valid DNS subdomain)", kind, name)
+	}
+
+	return kind, name, nil
+}
@@ -0,0 +1,216 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("CdromIso", func() {
+	var (
+		feature    *features.CdromIso
+		vm         *kubevirtv1.VirtualMachine
+		ctx        context.Context
+		fakeClient client.Client
+	)
+
+	BeforeEach(func() {
+		feature = features.NewCdromIso(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		dataVolumeGVK := schema.GroupVersionKind{Group: "cdi.kubevirt.io", Version: "v1beta1", Kind: "DataVolume"}
+		scheme := runtime.NewScheme()
+		_ = corev1.AddToScheme(scheme)
+		scheme.AddKnownTypeWithName(dataVolumeGVK, &unstructured.Unstructured{})
+
+		dv := &unstructured.Unstructured{}
+		dv.SetGroupVersionKind(dataVolumeGVK)
+		dv.SetNamespace("default")
+		dv.SetName("installer-dv")
+
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "installer-pvc", Namespace: "default"},
+		}
+
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(pvc, dv).Build()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							Devices: kubevirtv1.Devices{
+								Disks: []kubevirtv1.Disk{
+									{Name: "rootdisk"},
+									{Name: "datadisk"},
+								},
+								Interfaces: []kubevirtv1.Interface{
+									{Name: "default"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	cdromDisk := func() *kubevirtv1.Disk {
+		for i, disk := range vm.Spec.Template.Spec.Domain.Devices.Disks {
+			if disk.Name == "cdrom-iso" {
+				return &vm.Spec.Template.Spec.Domain.Devices.Disks[i]
+			}
+		}
+		return nil
+	}
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureCdromIso))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "installer-pvc"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept an existing PVC", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "pvc/installer-pvc"}
+			Expect(feature.Validate(ctx, vm, fakeClient)).To(Succeed())
+		})
+
+		It("should accept an existing DataVolume", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "datavolume/installer-dv"}
+			Expect(feature.Validate(ctx, vm, fakeClient)).To(Succeed())
+		})
+
+		It("should reject a missing PVC", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "missing"}
+			err := feature.Validate(ctx, vm, fakeClient)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not found"))
+		})
+
+		It("should reject a missing DataVolume", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "datavolume/installer-pvc"}
+			Expect(feature.Validate(ctx, vm, fakeClient)).ToNot(Succeed())
+		})
+
+		It("should reject an unknown source kind", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "configmap/installer"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid source kind"))
+		})
+
+		It("should reject an invalid name", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "pvc/Invalid_Name"}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should skip the existence check without a client", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "missing"}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should attach a PVC as a read-only SATA CD-ROM", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "installer-pvc"}
+			result, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationCdromIsoApplied, "pvc/installer-pvc"))
+
+			disk := cdromDisk()
+			Expect(disk).ToNot(BeNil())
+			Expect(disk.CDRom).ToNot(BeNil())
+			Expect(disk.CDRom.Bus).To(Equal(kubevirtv1.DiskBusSATA))
+			Expect(*disk.BootOrder).To(Equal(uint(1)))
+
+			volumes := vm.Spec.Template.Spec.Volumes
+			Expect(volumes).To(HaveLen(1))
+			Expect(volumes[0].PersistentVolumeClaim).ToNot(BeNil())
+			Expect(volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("installer-pvc"))
+			Expect(volumes[0].PersistentVolumeClaim.ReadOnly).To(BeTrue())
+		})
+
+		It("should attach a DataVolume", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "datavolume/installer-dv"}
+			_, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+
+			volumes := vm.Spec.Template.Spec.Volumes
+			Expect(volumes).To(HaveLen(1))
+			Expect(volumes[0].DataVolume).ToNot(BeNil())
+			Expect(volumes[0].DataVolume.Name).To(Equal("installer-dv"))
+		})
+
+		It("should order existing disks after the CD-ROM when no boot order is set", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "installer-pvc"}
+			_, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+
+			disks := vm.Spec.Template.Spec.Domain.Devices.Disks
+			Expect(*disks[0].BootOrder).To(Equal(uint(2)))
+			Expect(*disks[1].BootOrder).To(Equal(uint(3)))
+		})
+
+		It("should shift existing boot orders behind the CD-ROM", func() {
+			netOrder, diskOrder := uint(1), uint(2)
+			devices := &vm.Spec.Template.Spec.Domain.Devices
+			devices.Interfaces[0].BootOrder = &netOrder
+			devices.Disks[0].BootOrder = &diskOrder
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "installer-pvc"}
+
+			_, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*devices.Interfaces[0].BootOrder).To(Equal(uint(2)))
+			Expect(*devices.Disks[0].BootOrder).To(Equal(uint(3)))
+			Expect(devices.Disks[1].BootOrder).To(BeNil())
+			Expect(*cdromDisk().BootOrder).To(Equal(uint(1)))
+		})
+
+		It("should not add a duplicate CD-ROM", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "installer-pvc"}
+			_, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+
+			result, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Volumes).To(HaveLen(1))
+		})
+
+		It("should fail when the source does not exist", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCdromIso: "missing"}
+			_, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).To(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Volumes).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,83 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// cpuDetectionCacheTTL bounds how long a detected CPU feature is reused before
+// the node inventory is listed again
+const cpuDetectionCacheTTL = 5 * time.Minute
+
+// cpuFeatureNodeLabels maps node labels advertising hardware virtualization,
+// as set by the KubeVirt node labeller and Node Feature Discovery, to the
+// corresponding CPU feature
+var cpuFeatureNodeLabels = map[string]string{
+	utils.NodeLabelCPUFeaturePrefix + utils.CPUFeatureVMX: utils.CPUFeatureVMX,
+	utils.NodeLabelCPUFeaturePrefix + utils.CPUFeatureSVM: utils.CPUFeatureSVM,
+	"feature.node.kubernetes.io/cpu-cpuid.VMX":            utils.CPUFeatureVMX,
+	"feature.node.kubernetes.io/cpu-cpuid.SVM":            utils.CPUFeatureSVM,
+}
+
+// cpuFeatureDetector chooses between VMX and SVM from the labels of the
+// cluster's nodes, caching the answer for cpuDetectionCacheTTL
+type cpuFeatureDetector struct {
+	mu      sync.Mutex
+	feature string
+	expires time.Time
+}
+
+// detect returns the CPU feature advertised by the most nodes, or "" when no
+// node advertises either feature
+func (d *cpuFeatureDetector) detect(ctx context.Context, k8sClient client.Client) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if time.Now().Before(d.expires) {
+		return d.feature, nil
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := k8sClient.List(ctx, nodes); err != nil {
+		return "", fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	feature := majorityCPUFeature(nodes.Items)
+	d.feature = feature
+	d.expires = time.Now().Add(cpuDetectionCacheTTL)
+
+	return feature, nil
+}
+
+// majorityCPUFeature counts nodes per advertised CPU feature. Ties, including
+// clusters without any labelled node, return "".
+func majorityCPUFeature(nodes []corev1.Node) string {
+	counts := make(map[string]int)
+	for _, node := range nodes {
+		advertised := make(map[string]bool)
+		for label, feature := range cpuFeatureNodeLabels {
+			if node.Labels[label] == "true" {
+				advertised[feature] = true
+			}
+		}
+		for feature := range advertised {
+			counts[feature]++
+		}
+	}
+
+	switch {
+	case counts[utils.CPUFeatureVMX] > counts[utils.CPUFeatureSVM]:
+		return utils.CPUFeatureVMX
+	case counts[utils.CPUFeatureSVM] > counts[utils.CPUFeatureVMX]:
+		return utils.CPUFeatureSVM
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,114 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// cpuModelRegex validates CPU model names (e.g., host-passthrough, EPYC, Skylake-Server-IBRS)
+var cpuModelRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// CPUModel implements guest CPU model selection.
+// Requested models are checked against the configured allowlist.
+type CPUModel struct {
+	config       *config.CPUModelConfig
+	configSource utils.ConfigSource
+}
+
+// NewCPUModel creates a new CPUModel feature
+func NewCPUModel(cfg *config.CPUModelConfig, configSource utils.ConfigSource) *CPUModel {
+	return &CPUModel{
+		config:       cfg,
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *CPUModel) Name() string {
+	return utils.FeatureCPUModel
+}
+
+// Config returns the feature settings
+func (f *CPUModel) Config() interface{} {
+	return f.config
+}
+
+// IsEnabled checks if a CPU model is requested via annotations or labels
+func (f *CPUModel) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	if !f.config.Enabled {
+		return false
+	}
+
+	model, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationCPUModel)
+	return exists && model != ""
+}
+
+// Validate ensures the CPU model name is valid and allowed.
+// An empty allowlist allows any valid model name.
+func (f *CPUModel) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	model, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationCPUModel)
+	if !exists {
+		return nil
+	}
+
+	if model == "" {
+		return fmt.Errorf("CPU model cannot be empty")
+	}
+
+	if !cpuModelRegex.MatchString(model) {
+		return fmt.Errorf("invalid CPU model %q", model)
+	}
+
+	if len(f.config.AllowedModels) == 0 {
+		return nil
+	}
+	for _, allowed := range f.config.AllowedModels {
+		if allowed == model {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("CPU model %q is not allowed (allowed: %v)", model, f.config.AllowedModels)
+}
+
+// Apply sets the CPU model on the VM template
+func (f *CPUModel) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	model, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationCPUModel)
+
+	logger.Info("Applying CPU model", "vm", vm.Name, "model", model)
+
+	if vm.Spec.Template.Spec.Domain.CPU == nil {
+		vm.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{}
+	}
+	vm.Spec.Template.Spec.Domain.CPU.Model = model
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationCPUModelApplied, model)
+	result.AddMessage(fmt.Sprintf("Set CPU model to %s", model))
+
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("CPUModel", func() {
+	var (
+		cfg     *config.CPUModelConfig
+		feature *features.CPUModel
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		cfg = &config.CPUModelConfig{
+			Enabled:       true,
+			AllowedModels: []string{"host-passthrough", "host-model", "EPYC"},
+		}
+		feature = features.NewCPUModel(cfg, utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureCPUModel))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when a model is set", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUModel: "host-passthrough"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return false when the feature is disabled in config", func() {
+			cfg.Enabled = false
+			vm.Annotations = map[string]string{utils.AnnotationCPUModel: "host-passthrough"}
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept allowed models", func() {
+			for _, model := range []string{"host-passthrough", "host-model", "EPYC"} {
+				vm.Annotations = map[string]string{utils.AnnotationCPUModel: model}
+				Expect(feature.Validate(ctx, vm, nil)).To(Succeed(), model)
+			}
+		})
+
+		It("should reject models not in the allowlist", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUModel: "Skylake-Server"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not allowed"))
+		})
+
+		It("should allow any valid model with an empty allowlist", func() {
+			cfg.AllowedModels = nil
+			vm.Annotations = map[string]string{utils.AnnotationCPUModel: "Skylake-Server"}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject malformed model names", func() {
+			cfg.AllowedModels = nil
+			vm.Annotations = map[string]string{utils.AnnotationCPUModel: "bad model!"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid CPU model"))
+		})
+
+		It("should reject an empty model", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUModel: ""}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should set the CPU model", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUModel: "host-model"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationCPUModelApplied, "host-model"))
+			Expect(vm.Spec.Template.Spec.Domain.CPU.Model).To(Equal("host-model"))
+		})
+
+		It("should preserve other CPU settings", func() {
+			vm.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{Cores: 2}
+			vm.Annotations = map[string]string{utils.AnnotationCPUModel: "EPYC"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.CPU.Cores).To(Equal(uint32(2)))
+			Expect(vm.Spec.Template.Spec.Domain.CPU.Model).To(Equal("EPYC"))
+		})
+
+		It("should return error for disallowed models", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUModel: "Haswell"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.CPU).To(BeNil())
+		})
+	})
+})
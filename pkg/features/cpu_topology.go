@@ -0,0 +1,169 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// CPUTopologySpec defines the vCPU topology.
+// Unset values default to 1.
+type CPUTopologySpec struct {
+	Sockets uint32 `json:"sockets,omitempty"`
+	Cores   uint32 `json:"cores,omitempty"`
+	Threads uint32 `json:"threads,omitempty"`
+}
+
+// vCPUs returns the number of vCPUs of the topology
+func (s CPUTopologySpec) vCPUs() int64 {
+	return int64(orOne(s.Sockets)) * int64(orOne(s.Cores)) * int64(orOne(s.Threads))
+}
+
+// CPUTopology implements vCPU topology configuration
+type CPUTopology struct {
+	configSource utils.ConfigSource
+}
+
+// NewCPUTopology creates a new CPUTopology feature
+func NewCPUTopology(configSource utils.ConfigSource) *CPUTopology {
+	return &CPUTopology{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *CPUTopology) Name() string {
+	return utils.FeatureCPUTopology
+}
+
+// IsEnabled checks if a vCPU topology is requested via annotations or labels
+func (f *CPUTopology) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationCPUTopology)
+	return exists && value != ""
+}
+
+// Validate ensures the topology is valid and matches the requested vCPUs when set
+func (f *CPUTopology) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationCPUTopology)
+	if !exists {
+		return nil
+	}
+
+	spec, err := parseCPUTopology(value)
+	if err != nil {
+		return err
+	}
+
+	if vm.Spec.Template == nil {
+		return nil
+	}
+
+	resources := vm.Spec.Template.Spec.Domain.Resources
+	for _, list := range []corev1.ResourceList{resources.Requests, resources.Limits} {
+		cpu, ok := list[corev1.ResourceCPU]
+		if !ok {
+			continue
+		}
+		if cpu.MilliValue()%1000 == 0 && cpu.Value() != spec.vCPUs() {
+			return fmt.Errorf("CPU topology %s provides %d vCPUs but %s CPUs are requested",
+				value, spec.vCPUs(), cpu.String())
+		}
+	}
+
+	return nil
+}
+
+// Apply sets sockets, cores and threads on the VM template
+func (f *CPUTopology) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationCPUTopology)
+	spec, _ := parseCPUTopology(value)
+
+	if vm.Spec.Template.Spec.Domain.CPU == nil {
+		vm.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{}
+	}
+	cpu := vm.Spec.Template.Spec.Domain.CPU
+	cpu.Sockets = orOne(spec.Sockets)
+	cpu.Cores = orOne(spec.Cores)
+	cpu.Threads = orOne(spec.Threads)
+
+	topology := fmt.Sprintf("sockets=%d,cores=%d,threads=%d", cpu.Sockets, cpu.Cores, cpu.Threads)
+	logger.Info("Applied vCPU topology", "vm", vm.Name, "topology", topology)
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationCPUTopologyApplied, topology)
+	result.AddMessage(fmt.Sprintf("Set vCPU topology to %s (%d vCPUs)", topology, spec.vCPUs()))
+
+	return result, nil
+}
+
+// parseCPUTopology parses a topology from key=value pairs or JSON
+func parseCPUTopology(value string) (CPUTopologySpec, error) {
+	var spec CPUTopologySpec
+
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "{") {
+		if err := json.Unmarshal([]byte(value), &spec); err != nil {
+			return spec, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationCPUTopology, err)
+		}
+	} else {
+		for _, pair := range strings.Split(value, ",") {
+			key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found {
+				return spec, fmt.Errorf("invalid %s entry %q (expected key=value)", utils.AnnotationCPUTopology, pair)
+			}
+			n, err := strconv.ParseUint(strings.TrimSpace(val), 10, 32)
+			if err != nil || n == 0 {
+				return spec, fmt.Errorf("invalid %s value for %s: %q (expected a positive integer)", utils.AnnotationCPUTopology, key, val)
+			}
+			switch strings.TrimSpace(key) {
+			case "sockets":
+				spec.Sockets = uint32(n)
+			case "cores":
+				spec.Cores = uint32(n)
+			case "threads":
+				spec.Threads = uint32(n)
+			default:
+				return spec, fmt.Errorf("unknown %s key %q (expected sockets, cores or threads)", utils.AnnotationCPUTopology, key)
+			}
+		}
+	}
+
+	if spec.Sockets == 0 && spec.Cores == 0 && spec.Threads == 0 {
+		return spec, fmt.Errorf("no topology specified in %s", utils.AnnotationCPUTopology)
+	}
+
+	return spec, nil
+}
+
+// orOne returns v, or 1 when v is unset
+func orOne(v uint32) uint32 {
+	if v == 0 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,143 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("CPUTopology", func() {
+	var (
+		feature *features.CPUTopology
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewCPUTopology(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureCPUTopology))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: "cores=4"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept key=value topology", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: "sockets=2,cores=4,threads=2"}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should accept JSON topology", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: `{"sockets": 1, "cores": 8}`}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject unknown keys", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: "dies=2"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unknown"))
+		})
+
+		It("should reject non-positive values", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: "cores=0"}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+
+			vm.Annotations[utils.AnnotationCPUTopology] = "cores=four"
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject entries without a value", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: "cores"}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should accept a topology matching requested vCPUs", func() {
+			vm.Spec.Template.Spec.Domain.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("16"),
+			}
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: "sockets=2,cores=4,threads=2"}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject a topology not matching requested vCPUs", func() {
+			vm.Spec.Template.Spec.Domain.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("8"),
+			}
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: "sockets=2,cores=4,threads=2"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("16 vCPUs"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should set sockets, cores and threads", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: "sockets=2,cores=4,threads=2"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationCPUTopologyApplied, "sockets=2,cores=4,threads=2"))
+
+			cpu := vm.Spec.Template.Spec.Domain.CPU
+			Expect(cpu.Sockets).To(Equal(uint32(2)))
+			Expect(cpu.Cores).To(Equal(uint32(4)))
+			Expect(cpu.Threads).To(Equal(uint32(2)))
+		})
+
+		It("should default unset values to 1", func() {
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: `{"cores": 4}`}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			cpu := vm.Spec.Template.Spec.Domain.CPU
+			Expect(cpu.Sockets).To(Equal(uint32(1)))
+			Expect(cpu.Cores).To(Equal(uint32(4)))
+			Expect(cpu.Threads).To(Equal(uint32(1)))
+		})
+
+		It("should preserve other CPU settings", func() {
+			vm.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{Model: "host-passthrough"}
+			vm.Annotations = map[string]string{utils.AnnotationCPUTopology: "cores=2"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.CPU.Model).To(Equal("host-passthrough"))
+		})
+	})
+})
@@ -0,0 +1,107 @@
+package features
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// DedicatedCPUs implements dedicated CPU placement (CPU pinning).
+// It pins the VM's vCPUs to dedicated host CPUs and can optionally
+// isolate the emulator thread on an additional dedicated CPU.
+type DedicatedCPUs struct {
+	configSource utils.ConfigSource
+}
+
+// NewDedicatedCPUs creates a new DedicatedCPUs feature
+func NewDedicatedCPUs(configSource utils.ConfigSource) *DedicatedCPUs {
+	return &DedicatedCPUs{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *DedicatedCPUs) Name() string {
+	return utils.FeatureDedicatedCPUs
+}
+
+// IsEnabled checks if dedicated CPU placement is requested via annotations or labels
+func (f *DedicatedCPUs) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationDedicatedCPUs)
+	return exists && utils.IsTruthyValue(value)
+}
+
+// Validate ensures the configured values are supported and that
+// CPU requests and limits are whole cores
+func (f *DedicatedCPUs) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationDedicatedCPUs)
+	if !exists {
+		return nil
+	}
+
+	if value != "enabled" {
+		return fmt.Errorf("invalid value for %s: %s (expected 'enabled')",
+			utils.AnnotationDedicatedCPUs, value)
+	}
+
+	if vm.Spec.Template == nil {
+		return nil
+	}
+
+	resources := vm.Spec.Template.Spec.Domain.Resources
+	for _, list := range []corev1.ResourceList{resources.Requests, resources.Limits} {
+		if cpu, ok := list[corev1.ResourceCPU]; ok && cpu.MilliValue()%1000 != 0 {
+			return fmt.Errorf("dedicated CPU placement requires whole CPU cores, got %s", cpu.String())
+		}
+	}
+
+	return nil
+}
+
+// Apply enables dedicated CPU placement on the VM template
+func (f *DedicatedCPUs) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	isolateValue, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationIsolateEmulatorThread)
+	isolateEmulatorThread := utils.IsTruthyValue(isolateValue)
+
+	logger.Info("Applying dedicated CPU placement", "vm", vm.Name, "isolateEmulatorThread", isolateEmulatorThread)
+
+	if vm.Spec.Template.Spec.Domain.CPU == nil {
+		vm.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{}
+	}
+	vm.Spec.Template.Spec.Domain.CPU.DedicatedCPUPlacement = true
+	if isolateEmulatorThread {
+		vm.Spec.Template.Spec.Domain.CPU.IsolateEmulatorThread = true
+	}
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationDedicatedCPUsApplied, "true")
+	if isolateEmulatorThread {
+		result.AddMessage("Enabled dedicated CPU placement with isolated emulator thread")
+	} else {
+		result.AddMessage("Enabled dedicated CPU placement")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,161 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("DedicatedCPUs", func() {
+	var (
+		feature *features.DedicatedCPUs
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewDedicatedCPUs(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureDedicatedCPUs))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is enabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationDedicatedCPUs: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		Context("when using labels as config source", func() {
+			BeforeEach(func() {
+				feature = features.NewDedicatedCPUs(utils.ConfigSourceLabels)
+			})
+
+			It("should return true when label is set", func() {
+				vm.Labels = map[string]string{utils.AnnotationDedicatedCPUs: "enabled"}
+				Expect(feature.IsEnabled(vm)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		BeforeEach(func() {
+			vm.Annotations = map[string]string{utils.AnnotationDedicatedCPUs: "enabled"}
+		})
+
+		It("should succeed without CPU resources", func() {
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject invalid values", func() {
+			vm.Annotations[utils.AnnotationDedicatedCPUs] = "always"
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid value"))
+		})
+
+		It("should accept whole-core CPU requests", func() {
+			vm.Spec.Template.Spec.Domain.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("4"),
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject fractional CPU requests", func() {
+			vm.Spec.Template.Spec.Domain.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("1500m"),
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("whole CPU cores"))
+		})
+
+		It("should reject fractional CPU limits", func() {
+			vm.Spec.Template.Spec.Domain.Resources.Limits = corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("500m"),
+			}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should not modify the VM when not enabled", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.CPU).To(BeNil())
+		})
+
+		It("should enable dedicated CPU placement", func() {
+			vm.Annotations = map[string]string{utils.AnnotationDedicatedCPUs: "enabled"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationDedicatedCPUsApplied, "true"))
+
+			cpu := vm.Spec.Template.Spec.Domain.CPU
+			Expect(cpu).ToNot(BeNil())
+			Expect(cpu.DedicatedCPUPlacement).To(BeTrue())
+			Expect(cpu.IsolateEmulatorThread).To(BeFalse())
+		})
+
+		It("should isolate the emulator thread when requested", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationDedicatedCPUs:         "enabled",
+				utils.AnnotationIsolateEmulatorThread: "true",
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.CPU.IsolateEmulatorThread).To(BeTrue())
+		})
+
+		It("should preserve existing CPU settings", func() {
+			vm.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{Cores: 4}
+			vm.Annotations = map[string]string{utils.AnnotationDedicatedCPUs: "enabled"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.CPU.Cores).To(Equal(uint32(4)))
+			Expect(vm.Spec.Template.Spec.Domain.CPU.DedicatedCPUPlacement).To(BeTrue())
+		})
+
+		It("should return error for fractional CPU requests", func() {
+			vm.Annotations = map[string]string{utils.AnnotationDedicatedCPUs: "enabled"}
+			vm.Spec.Template.Spec.Domain.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("250m"),
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,103 @@
+package features
+
+import (
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+// Dependency declares a feature that must be applied before another one
+type Dependency struct {
+	// Feature is the name of the prerequisite feature
+	Feature string
+
+	// Required rejects the dependent feature unless the prerequisite is
+	// requested on the VM too. Otherwise the dependency only orders them.
+	Required bool
+}
+
+// Dependent is implemented by features that build on the mutations of others
+type Dependent interface {
+	// Dependencies returns the features this feature builds on
+	Dependencies() []Dependency
+}
+
+// SortByDependencies orders features so that prerequisites are applied first,
+// keeping the given order wherever dependencies allow. Prerequisites missing
+// from the list are ignored; a dependency cycle is an error.
+func SortByDependencies(list []Feature) ([]Feature, error) {
+	present := make(map[string]bool, len(list))
+	for _, feature := range list {
+		present[feature.Name()] = true
+	}
+
+	sorted := make([]Feature, 0, len(list))
+	placed := make(map[string]bool, len(list))
+	for len(sorted) < len(list) {
+		progressed := false
+		for _, feature := range list {
+			if placed[feature.Name()] || !prerequisitesPlaced(feature, present, placed) {
+				continue
+			}
+			sorted = append(sorted, feature)
+			placed[feature.Name()] = true
+			progressed = true
+			// Restart so earlier features keep precedence
+			break
+		}
+		if !progressed {
+			var pending []string
+			for _, feature := range list {
+				if !placed[feature.Name()] {
+					pending = append(pending, feature.Name())
+				}
+			}
+			return nil, fmt.Errorf("feature dependency cycle between %s", strings.Join(pending, ", "))
+		}
+	}
+
+	return sorted, nil
+}
+
+// prerequisitesPlaced reports whether every prerequisite of feature that is
+// part of the list has already been placed
+func prerequisitesPlaced(feature Feature, present, placed map[string]bool) bool {
+	dependent, ok := feature.(Dependent)
+	if !ok {
+		return true
+	}
+	for _, dep := range dependent.Dependencies() {
+		if present[dep.Feature] && !placed[dep.Feature] {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckPrerequisites returns an error when feature has a required
+// prerequisite that isn't requested on the VM
+func CheckPrerequisites(feature Feature, registry []Feature, vm *kubevirtv1.VirtualMachine) error {
+	dependent, ok := feature.(Dependent)
+	if !ok {
+		return nil
+	}
+
+	for _, dep := range dependent.Dependencies() {
+		if !dep.Required {
+			continue
+		}
+		enabled := false
+		for _, candidate := range registry {
+			if candidate.Name() == dep.Feature {
+				enabled = candidate.IsEnabled(vm)
+				break
+			}
+		}
+		if !enabled {
+			return fmt.Errorf("feature %s requires feature %s to be requested as well", feature.Name(), dep.Feature)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// dependentFeature is a minimal feature declaring dependencies
+type dependentFeature struct {
+	name string
+	deps []features.Dependency
+}
+
+func (f *dependentFeature) Name() string { return f.name }
+
+func (f *dependentFeature) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	_, ok := vm.GetAnnotations()["test/"+f.name]
+	return ok
+}
+
+func (f *dependentFeature) Apply(_ context.Context, _ *kubevirtv1.VirtualMachine, _ client.Client) (*features.MutationResult, error) {
+	return features.NewMutationResult(), nil
+}
+
+func (f *dependentFeature) Validate(_ context.Context, _ *kubevirtv1.VirtualMachine, _ client.Client) error {
+	return nil
+}
+
+func (f *dependentFeature) Dependencies() []features.Dependency { return f.deps }
+
+// featureNames returns the names of the features in order
+func featureNames(list []features.Feature) []string {
+	names := make([]string, 0, len(list))
+	for _, feature := range list {
+		names = append(names, feature.Name())
+	}
+	return names
+}
+
+var _ = Describe("Dependencies", func() {
+	Describe("SortByDependencies", func() {
+		It("should place prerequisites before their dependents", func() {
+			list := []features.Feature{
+				&dependentFeature{name: "a", deps: []features.Dependency{{Feature: "c"}}},
+				&dependentFeature{name: "b"},
+				&dependentFeature{name: "c"},
+			}
+
+			sorted, err := features.SortByDependencies(list)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(featureNames(sorted)).To(Equal([]string{"b", "c", "a"}))
+		})
+
+		It("should keep the original order without dependencies", func() {
+			list := []features.Feature{
+				&dependentFeature{name: "a"},
+				&dependentFeature{name: "b"},
+				&dependentFeature{name: "c"},
+			}
+
+			sorted, err := features.SortByDependencies(list)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(featureNames(sorted)).To(Equal([]string{"a", "b", "c"}))
+		})
+
+		It("should ignore prerequisites that aren't registered", func() {
+			list := []features.Feature{
+				&dependentFeature{name: "a", deps: []features.Dependency{{Feature: "missing"}}},
+			}
+
+			sorted, err := features.SortByDependencies(list)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(featureNames(sorted)).To(Equal([]string{"a"}))
+		})
+
+		It("should reject dependency cycles", func() {
+			list := []features.Feature{
+				&dependentFeature{name: "a", deps: []features.Dependency{{Feature: "b"}}},
+				&dependentFeature{name: "b", deps: []features.Dependency{{Feature: "a"}}},
+			}
+
+			_, err := features.SortByDependencies(list)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cycle"))
+		})
+
+		It("should apply guaranteed QoS before guest NUMA mapping", func() {
+			list := []features.Feature{
+				features.NewNuma(utils.ConfigSourceAnnotations),
+				features.NewHotplug(utils.ConfigSourceAnnotations),
+				features.NewGuaranteedQoS(utils.ConfigSourceAnnotations),
+				features.NewCPUTopology(utils.ConfigSourceAnnotations),
+				features.NewVGpu(&config.VGpuConfig{Enabled: true}, utils.ConfigSourceAnnotations),
+				features.NewVBiosInjection(&config.VBiosConfig{Enabled: true}, utils.ConfigSourceAnnotations),
+			}
+
+			sorted, err := features.SortByDependencies(list)
+			Expect(err).ToNot(HaveOccurred())
+			names := featureNames(sorted)
+			Expect(indexOf(names, utils.FeatureGuaranteedQoS)).To(BeNumerically("<", indexOf(names, utils.FeatureNuma)))
+			Expect(indexOf(names, utils.FeatureCPUTopology)).To(BeNumerically("<", indexOf(names, utils.FeatureHotplug)))
+			Expect(indexOf(names, utils.FeatureVBiosInjection)).To(BeNumerically("<", indexOf(names, utils.FeatureVGpu)))
+		})
+	})
+
+	Describe("CheckPrerequisites", func() {
+		var (
+			prerequisite *dependentFeature
+			dependent    *dependentFeature
+			registry     []features.Feature
+			vm           *kubevirtv1.VirtualMachine
+		)
+
+		BeforeEach(func() {
+			prerequisite = &dependentFeature{name: "efi"}
+			dependent = &dependentFeature{name: "secure-boot", deps: []features.Dependency{{Feature: "efi", Required: true}}}
+			registry = []features.Feature{prerequisite, dependent}
+			vm = &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-vm",
+					Namespace:   "default",
+					Annotations: map[string]string{"test/secure-boot": ""},
+				},
+			}
+		})
+
+		It("should fail when a required prerequisite isn't requested", func() {
+			err := features.CheckPrerequisites(dependent, registry, vm)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("requires feature efi"))
+		})
+
+		It("should pass when the prerequisite is requested", func() {
+			vm.Annotations["test/efi"] = ""
+			Expect(features.CheckPrerequisites(dependent, registry, vm)).To(Succeed())
+		})
+
+		It("should ignore ordering-only dependencies", func() {
+			dependent.deps = []features.Dependency{{Feature: "efi"}}
+			Expect(features.CheckPrerequisites(dependent, registry, vm)).To(Succeed())
+		})
+	})
+})
+
+// indexOf returns the position of name in names, or -1
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
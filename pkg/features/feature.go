@@ -3,11 +3,48 @@ package features
 
 import (
 	"context"
+	"encoding/json"
 
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// dryRunKey is the context key marking a dry-run admission request
+type dryRunKey struct{}
+
+// WithDryRun returns a context that records whether the admission request is a dry run
+func WithDryRun(ctx context.Context, dryRun bool) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, dryRun)
+}
+
+// IsDryRun reports whether the admission request is a dry run.
+// Features must not perform side effects (creating objects, emitting events)
+// when this returns true, but should still mutate the VM so the patch is returned.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// vmListerKey is the context key for the reader features list VMs with
+type vmListerKey struct{}
+
+// WithVMLister returns a context carrying a reader, typically backed by an
+// informer cache, for features that list other VMs
+func WithVMLister(ctx context.Context, reader client.Reader) context.Context {
+	return context.WithValue(ctx, vmListerKey{}, reader)
+}
+
+// vmLister returns the context's VM reader, falling back to k8sClient
+func vmLister(ctx context.Context, k8sClient client.Client) client.Reader {
+	if reader, ok := ctx.Value(vmListerKey{}).(client.Reader); ok && reader != nil {
+		return reader
+	}
+	if k8sClient == nil {
+		return nil
+	}
+	return k8sClient
+}
+
 // Feature represents a VM feature that can be applied via mutation
 type Feature interface {
 	// Name returns the feature name for logging and tracking
@@ -26,6 +63,20 @@ type Feature interface {
 	Validate(ctx context.Context, vm *kubevirtv1.VirtualMachine, client client.Client) error
 }
 
+// Configured is implemented by features with settings of their own
+type Configured interface {
+	// Config returns the settings the feature was created with
+	Config() interface{}
+}
+
+// Reverter is implemented by features that can undo their mutation when the
+// request is removed from a VM on UPDATE
+type Reverter interface {
+	// Revert removes the spec changes made by Apply. previous is the VM as
+	// stored before the update, carrying the original request and tracking annotations.
+	Revert(ctx context.Context, vm, previous *kubevirtv1.VirtualMachine) error
+}
+
 // MutationResult contains information about what was mutated
 type MutationResult struct {
 	// Applied indicates if the feature was successfully applied
@@ -36,6 +87,9 @@ type MutationResult struct {
 
 	// Messages are informational messages about the mutation
 	Messages []string
+
+	// Warnings are non-fatal issues surfaced to the client in the admission response
+	Warnings []string
 }
 
 // NewMutationResult creates a new MutationResult
@@ -44,6 +98,7 @@ func NewMutationResult() *MutationResult {
 		Applied:     false,
 		Annotations: make(map[string]string),
 		Messages:    []string{},
+		Warnings:    []string{},
 	}
 }
 
@@ -56,3 +111,31 @@ func (r *MutationResult) AddAnnotation(key, value string) {
 func (r *MutationResult) AddMessage(msg string) {
 	r.Messages = append(r.Messages, msg)
 }
+
+// AddWarning adds a non-fatal warning for the client
+func (r *MutationResult) AddWarning(msg string) {
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// previouslyApplied reports whether value is recorded in the VM's tracking
+// annotation key, either as the whole value or as an entry of a JSON array.
+// Features use it to stay quiet about devices they attached themselves.
+func previouslyApplied(vm *kubevirtv1.VirtualMachine, key, value string) bool {
+	recorded, ok := vm.GetAnnotations()[key]
+	if !ok {
+		return false
+	}
+	if recorded == value {
+		return true
+	}
+	var entries []string
+	if err := json.Unmarshal([]byte(recorded), &entries); err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
@@ -3,13 +3,17 @@ package features
 import (
 	"context"
 	"fmt"
+	"path"
 	"regexp"
+	"strconv"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
 
@@ -19,15 +23,20 @@ import (
 var devicePluginNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)+/[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
 
 // GpuDevicePlugin implements GPU device plugin resource allocation for VMs.
-// It adds Kubernetes device plugin resources to the VM's resource limits,
-// enabling GPU passthrough via device plugins like nvidia.com/gpu.
+// In resource mode (default) it adds Kubernetes device plugin resources to the
+// VM's resource limits, enabling GPU passthrough via device plugins like
+// nvidia.com/gpu. In device mode it adds the GPU to spec.domain.devices.gpus,
+// which also allows configuring the GPU display.
+// A GPU count can be requested as "domain/resource=count" (e.g., nvidia.com/gpu=2).
 type GpuDevicePlugin struct {
+	config       *config.GPUDevicePluginConfig
 	configSource utils.ConfigSource
 }
 
 // NewGpuDevicePlugin creates a new GpuDevicePlugin instance.
-func NewGpuDevicePlugin(configSource utils.ConfigSource) *GpuDevicePlugin {
+func NewGpuDevicePlugin(cfg *config.GPUDevicePluginConfig, configSource utils.ConfigSource) *GpuDevicePlugin {
 	return &GpuDevicePlugin{
+		config:       cfg,
 		configSource: configSource,
 	}
 }
@@ -37,25 +46,44 @@ func (f *GpuDevicePlugin) Name() string {
 	return utils.FeatureGpuDevicePlugin
 }
 
+// Config returns the feature settings
+func (f *GpuDevicePlugin) Config() interface{} {
+	return f.config
+}
+
 // IsEnabled checks if the GPU device plugin feature is enabled for this VM.
 func (f *GpuDevicePlugin) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	if !f.config.Enabled {
+		return false
+	}
+
 	pluginName, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGpuDevicePlugin)
 	return exists && pluginName != ""
 }
 
-// Validate ensures the device plugin name is valid.
-func (f *GpuDevicePlugin) Validate(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) error {
-	pluginName, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGpuDevicePlugin)
+// Validate ensures the device plugin name and GPU count are valid.
+func (f *GpuDevicePlugin) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGpuDevicePlugin)
 	if !exists {
 		return nil
 	}
 
-	if pluginName == "" {
-		return fmt.Errorf("GPU device plugin name cannot be empty")
+	if _, _, err := f.parseRequest(value); err != nil {
+		return err
 	}
 
-	if !devicePluginNameRegex.MatchString(pluginName) {
-		return fmt.Errorf("invalid device plugin name %q: must be in format 'domain/resource' (e.g., nvidia.com/gpu)", pluginName)
+	mode, err := f.mode(vm)
+	if err != nil {
+		return err
+	}
+
+	if display, ok := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGpuDisplay); ok {
+		if mode != utils.GpuModeDevice {
+			return fmt.Errorf("%s requires %s=%s", utils.AnnotationGpuDisplay, utils.AnnotationGpuMode, utils.GpuModeDevice)
+		}
+		if _, err := parseVGpuDisplay(utils.AnnotationGpuDisplay, display); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -81,18 +109,48 @@ func (f *GpuDevicePlugin) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachi
 		return result, fmt.Errorf("VM template is nil")
 	}
 
-	pluginName, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGpuDevicePlugin)
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGpuDevicePlugin)
+	pluginName, count, _ := f.parseRequest(value)
+	mode, _ := f.mode(vm)
 
-	// Initialize resources if needed
-	if vm.Spec.Template.Spec.Domain.Resources.Limits == nil {
-		vm.Spec.Template.Spec.Domain.Resources.Limits = make(corev1.ResourceList)
+	// The quota lists the namespace's VMs, so it is checked here rather than
+	// in Validate, which runs again from the mutator
+	if err := f.checkQuota(ctx, vm, k8sClient, pluginName, count, mode); err != nil {
+		return result, err
 	}
-
-	// Add GPU resource limit (quantity of 1)
-	// Note: We don't override if the resource already exists
+	limits := vm.Spec.Template.Spec.Domain.Resources.Limits
 	resourceName := corev1.ResourceName(pluginName)
-	if _, exists := vm.Spec.Template.Spec.Domain.Resources.Limits[resourceName]; !exists {
-		vm.Spec.Template.Spec.Domain.Resources.Limits[resourceName] = resource.MustParse("1")
+
+	// Only what this feature adds is recorded, so Revert never removes GPUs
+	// the user configured
+	applied := previouslyApplied(vm, utils.AnnotationGpuDevicePluginApplied, pluginName)
+	if !applied {
+		if mode == utils.GpuModeDevice && f.attachedGPUs(vm, pluginName) >= count {
+			return result, nil
+		}
+		if _, exists := limits[resourceName]; exists && mode != utils.GpuModeDevice {
+			result.AddWarning(fmt.Sprintf("resource limit %s is already set, keeping the existing value", pluginName))
+			return result, nil
+		}
+	}
+
+	if added := requireNodeLabels(vm, f.Name(), f.config.NodeSelector, result); len(added) > 0 {
+		result.AddMessage(fmt.Sprintf("Required node labels: %s", strings.Join(added, ", ")))
+	}
+
+	if mode == utils.GpuModeDevice {
+		// GPUs added earlier follow the requested count
+		if applied {
+			f.removeGPUDevices(vm, pluginName, count)
+		}
+		f.addGPUDevices(vm, pluginName, count)
+	} else if _, exists := limits[resourceName]; applied || !exists {
+		// Set the GPU resource limit to the requested quantity; a limit added
+		// earlier follows a changed count
+		if limits == nil {
+			vm.Spec.Template.Spec.Domain.Resources.Limits = make(corev1.ResourceList)
+		}
+		vm.Spec.Template.Spec.Domain.Resources.Limits[resourceName] = *resource.NewQuantity(int64(count), resource.DecimalSI)
 	}
 
 	result.Applied = true
@@ -100,3 +158,233 @@ func (f *GpuDevicePlugin) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachi
 
 	return result, nil
 }
+
+// Revert removes the GPU resource limit or devices recorded in the previous
+// VM's tracking annotation
+func (f *GpuDevicePlugin) Revert(_ context.Context, vm, previous *kubevirtv1.VirtualMachine) error {
+	pluginName := previous.GetAnnotations()[utils.AnnotationGpuDevicePluginApplied]
+	if vm.Spec.Template == nil || pluginName == "" {
+		return nil
+	}
+	spec := &vm.Spec.Template.Spec
+
+	if mode, _ := f.mode(previous); mode == utils.GpuModeDevice {
+		kept := spec.Domain.Devices.GPUs[:0]
+		for _, gpu := range spec.Domain.Devices.GPUs {
+			if strings.HasPrefix(gpu.Name, "gpu-device-") && gpu.DeviceName == pluginName {
+				continue
+			}
+			kept = append(kept, gpu)
+		}
+		spec.Domain.Devices.GPUs = kept
+		if len(kept) == 0 {
+			spec.Domain.Devices.GPUs = nil
+		}
+	} else {
+		delete(spec.Domain.Resources.Limits, corev1.ResourceName(pluginName))
+		if len(spec.Domain.Resources.Limits) == 0 {
+			spec.Domain.Resources.Limits = nil
+		}
+	}
+
+	releaseNodeLabels(vm, previous, f.Name(), f.config.NodeSelector)
+	return nil
+}
+
+// mode returns the configured GPU assignment mode, defaulting to resource mode
+func (f *GpuDevicePlugin) mode(vm *kubevirtv1.VirtualMachine) (string, error) {
+	mode, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGpuMode)
+	if !exists || mode == "" {
+		return utils.GpuModeResource, nil
+	}
+
+	if mode != utils.GpuModeResource && mode != utils.GpuModeDevice {
+		return "", fmt.Errorf("invalid value for %s: %s (expected '%s' or '%s')",
+			utils.AnnotationGpuMode, mode, utils.GpuModeResource, utils.GpuModeDevice)
+	}
+
+	return mode, nil
+}
+
+// attachedGPUs counts the VM's GPU devices using the plugin
+func (f *GpuDevicePlugin) attachedGPUs(vm *kubevirtv1.VirtualMachine, pluginName string) int {
+	present := 0
+	for _, gpu := range vm.Spec.Template.Spec.Domain.Devices.GPUs {
+		if gpu.DeviceName == pluginName {
+			present++
+		}
+	}
+	return present
+}
+
+// addGPUDevices adds GPUs to the VM's GPU devices until count GPUs use the plugin
+func (f *GpuDevicePlugin) addGPUDevices(vm *kubevirtv1.VirtualMachine, pluginName string, count int) {
+	gpus := vm.Spec.Template.Spec.Domain.Devices.GPUs
+	usedNames := make(map[string]bool, len(gpus))
+	for _, gpu := range gpus {
+		usedNames[gpu.Name] = true
+	}
+	present := f.attachedGPUs(vm, pluginName)
+
+	var displayOptions *kubevirtv1.VGPUDisplayOptions
+	if display, ok := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGpuDisplay); ok {
+		displayOptions, _ = parseVGpuDisplay(utils.AnnotationGpuDisplay, display)
+	}
+
+	for i := 0; present < count; i++ {
+		// Generate a unique device name
+		name := fmt.Sprintf("gpu-device-%d", i)
+		if usedNames[name] {
+			continue
+		}
+
+		gpu := kubevirtv1.GPU{
+			Name:       name,
+			DeviceName: pluginName,
+		}
+		if displayOptions != nil {
+			gpu.VirtualGPUOptions = &kubevirtv1.VGPUOptions{Display: displayOptions.DeepCopy()}
+		}
+
+		gpus = append(gpus, gpu)
+		usedNames[name] = true
+		present++
+	}
+
+	vm.Spec.Template.Spec.Domain.Devices.GPUs = gpus
+}
+
+// removeGPUDevices removes the GPUs added by addGPUDevices beyond count,
+// newest first. GPUs the user configured are kept.
+func (f *GpuDevicePlugin) removeGPUDevices(vm *kubevirtv1.VirtualMachine, pluginName string, count int) {
+	gpus := vm.Spec.Template.Spec.Domain.Devices.GPUs
+	excess := f.attachedGPUs(vm, pluginName) - count
+	for i := len(gpus) - 1; i >= 0 && excess > 0; i-- {
+		if gpus[i].DeviceName == pluginName && strings.HasPrefix(gpus[i].Name, "gpu-device-") {
+			gpus = append(gpus[:i], gpus[i+1:]...)
+			excess--
+		}
+	}
+	if len(gpus) == 0 {
+		gpus = nil
+	}
+	vm.Spec.Template.Spec.Domain.Devices.GPUs = gpus
+}
+
+// parseRequest splits a "domain/resource[=count]" value into the plugin name and GPU count
+func (f *GpuDevicePlugin) parseRequest(value string) (string, int, error) {
+	pluginName, countStr, hasCount := strings.Cut(value, "=")
+
+	if pluginName == "" {
+		return "", 0, fmt.Errorf("GPU device plugin name cannot be empty")
+	}
+
+	if !devicePluginNameRegex.MatchString(pluginName) {
+		return "", 0, fmt.Errorf("invalid device plugin name %q: must be in format 'domain/resource' (e.g., nvidia.com/gpu)", pluginName)
+	}
+
+	count := 1
+	if hasCount {
+		var err error
+		count, err = strconv.Atoi(countStr)
+		if err != nil || count < 1 {
+			return "", 0, fmt.Errorf("invalid GPU count %q for %s: must be a positive integer", countStr, pluginName)
+		}
+	}
+
+	if !f.isAllowed(pluginName) {
+		return "", 0, fmt.Errorf("device plugin %q is not in the allowed plugins list", pluginName)
+	}
+
+	if f.config.MaxDevices > 0 && count > f.config.MaxDevices {
+		return "", 0, fmt.Errorf("requested %d GPUs of %s exceeds the maximum of %d per VM", count, pluginName, f.config.MaxDevices)
+	}
+
+	return pluginName, count, nil
+}
+
+// checkQuota rejects requests that would take the VM's namespace over its GPU
+// quota. Requests that don't add GPUs to the VM are always admitted, so VMs
+// keep working when a quota is lowered.
+func (f *GpuDevicePlugin) checkQuota(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client, pluginName string, count int, mode string) error {
+	limit, ok := f.config.NamespaceQuota[vm.Namespace]
+	if !ok {
+		if limit, ok = f.config.NamespaceQuota["*"]; !ok {
+			return nil
+		}
+	}
+
+	current := f.gpuCount(vm)
+	requested := current
+	if vm.Spec.Template != nil {
+		if mode == utils.GpuModeDevice {
+			present := 0
+			for _, gpu := range vm.Spec.Template.Spec.Domain.Devices.GPUs {
+				if gpu.DeviceName == pluginName {
+					present++
+				}
+			}
+			requested += max(count-present, 0)
+		} else if _, exists := vm.Spec.Template.Spec.Domain.Resources.Limits[corev1.ResourceName(pluginName)]; !exists {
+			requested += count
+		}
+	}
+	if requested <= current {
+		return nil
+	}
+
+	reader := vmLister(ctx, k8sClient)
+	if reader == nil {
+		return fmt.Errorf("cannot enforce the GPU quota of namespace %s without a Kubernetes client", vm.Namespace)
+	}
+	var vms kubevirtv1.VirtualMachineList
+	if err := reader.List(ctx, &vms, client.InNamespace(vm.Namespace)); err != nil {
+		return fmt.Errorf("failed to count GPUs in namespace %s: %w", vm.Namespace, err)
+	}
+	used := 0
+	for i := range vms.Items {
+		if vms.Items[i].Name != vm.Name {
+			used += f.gpuCount(&vms.Items[i])
+		}
+	}
+
+	if used+requested > limit {
+		return fmt.Errorf("GPU quota of namespace %s exceeded: %d requested with %d in use by other VMs, limit %d",
+			vm.Namespace, requested, used, limit)
+	}
+	return nil
+}
+
+// gpuCount returns the GPUs a VM template requests, as GPU devices plus the
+// limits of allowed device plugin resources
+func (f *GpuDevicePlugin) gpuCount(vm *kubevirtv1.VirtualMachine) int {
+	if vm.Spec.Template == nil {
+		return 0
+	}
+	domain := vm.Spec.Template.Spec.Domain
+
+	count := len(domain.Devices.GPUs)
+	for name, quantity := range domain.Resources.Limits {
+		if devicePluginNameRegex.MatchString(string(name)) && f.isAllowed(string(name)) {
+			count += int(quantity.Value())
+		}
+	}
+	return count
+}
+
+// isAllowed checks the plugin against the configured allowlist.
+// Entries may use wildcards (e.g., "nvidia.com/*" or "*.example.com/gpu");
+// an empty allowlist allows any plugin.
+func (f *GpuDevicePlugin) isAllowed(pluginName string) bool {
+	if len(f.config.AllowedPlugins) == 0 {
+		return true
+	}
+
+	for _, pattern := range f.config.AllowedPlugins {
+		if matched, err := path.Match(strings.TrimSpace(pattern), pluginName); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,14 +2,19 @@ package features_test
 
 import (
 	"context"
+	"fmt"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
@@ -17,12 +22,17 @@ import (
 var _ = Describe("GpuDevicePlugin", func() {
 	var (
 		feature *features.GpuDevicePlugin
+		gpuCfg  *config.GPUDevicePluginConfig
 		vm      *kubevirtv1.VirtualMachine
 		ctx     context.Context
 	)
 
 	BeforeEach(func() {
-		feature = features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations)
+		gpuCfg = &config.GPUDevicePluginConfig{
+			Enabled:    true,
+			MaxDevices: 4,
+		}
+		feature = features.NewGpuDevicePlugin(gpuCfg, utils.ConfigSourceAnnotations)
 		ctx = context.Background()
 
 		vm = &kubevirtv1.VirtualMachine{
@@ -73,7 +83,7 @@ var _ = Describe("GpuDevicePlugin", func() {
 
 		Context("when using labels as config source", func() {
 			BeforeEach(func() {
-				feature = features.NewGpuDevicePlugin(utils.ConfigSourceLabels)
+				feature = features.NewGpuDevicePlugin(gpuCfg, utils.ConfigSourceLabels)
 			})
 
 			It("should return true when label is set", func() {
@@ -170,7 +180,7 @@ var _ = Describe("GpuDevicePlugin", func() {
 
 		Context("when using labels as config source", func() {
 			BeforeEach(func() {
-				feature = features.NewGpuDevicePlugin(utils.ConfigSourceLabels)
+				feature = features.NewGpuDevicePlugin(gpuCfg, utils.ConfigSourceLabels)
 			})
 
 			It("should accept valid device plugin name from label", func() {
@@ -191,6 +201,43 @@ var _ = Describe("GpuDevicePlugin", func() {
 		})
 	})
 
+	Describe("Allowed plugins", func() {
+		BeforeEach(func() {
+			gpuCfg.AllowedPlugins = []string{"nvidia.com/gpu", "*.example.com/*"}
+		})
+
+		It("should accept an allowed plugin", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should accept a plugin matching a wildcard entry", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "gpu.example.com/a100=2"}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject a plugin not in the allowlist", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not in the allowed plugins list"))
+		})
+
+		It("should not apply a rejected plugin", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.Resources.Limits).To(BeEmpty())
+		})
+
+		It("should allow any plugin when the allowlist is empty", func() {
+			gpuCfg.AllowedPlugins = nil
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+	})
+
 	Describe("Apply", func() {
 		Context("when VM template is nil", func() {
 			It("should return error", func() {
@@ -217,7 +264,7 @@ var _ = Describe("GpuDevicePlugin", func() {
 				// Check resource limit was added
 				limits := vm.Spec.Template.Spec.Domain.Resources.Limits
 				Expect(limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
-				Expect(limits[corev1.ResourceName("nvidia.com/gpu")]).To(Equal(resource.MustParse("1")))
+				Expect(limits[corev1.ResourceName("nvidia.com/gpu")]).To(BeComparableTo(resource.MustParse("1")))
 			})
 
 			It("should add tracking annotation", func() {
@@ -278,12 +325,13 @@ var _ = Describe("GpuDevicePlugin", func() {
 				vm.Annotations = map[string]string{
 					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
 				}
-				_, err := feature.Apply(ctx, vm, nil)
+				result, err := feature.Apply(ctx, vm, nil)
 				Expect(err).ToNot(HaveOccurred())
 
 				limits := vm.Spec.Template.Spec.Domain.Resources.Limits
 				Expect(limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
-				Expect(limits[corev1.ResourceName("nvidia.com/gpu")]).To(Equal(resource.MustParse("1")))
+				Expect(limits[corev1.ResourceName("nvidia.com/gpu")]).To(BeComparableTo(resource.MustParse("1")))
+				Expect(result.Warnings).To(ConsistOf(ContainSubstring("already set")))
 			})
 		})
 
@@ -301,7 +349,7 @@ var _ = Describe("GpuDevicePlugin", func() {
 
 		Context("when using labels as config source", func() {
 			BeforeEach(func() {
-				feature = features.NewGpuDevicePlugin(utils.ConfigSourceLabels)
+				feature = features.NewGpuDevicePlugin(gpuCfg, utils.ConfigSourceLabels)
 			})
 
 			It("should add GPU resource limit from label", func() {
@@ -314,7 +362,7 @@ var _ = Describe("GpuDevicePlugin", func() {
 
 				limits := vm.Spec.Template.Spec.Domain.Resources.Limits
 				Expect(limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
-				Expect(limits[corev1.ResourceName("nvidia.com/gpu")]).To(Equal(resource.MustParse("1")))
+				Expect(limits[corev1.ResourceName("nvidia.com/gpu")]).To(BeComparableTo(resource.MustParse("1")))
 			})
 
 			It("should not apply when only annotation is set", func() {
@@ -336,5 +384,352 @@ var _ = Describe("GpuDevicePlugin", func() {
 				Expect(result.Annotations[utils.AnnotationGpuDevicePluginApplied]).To(Equal("amd.com/gpu"))
 			})
 		})
+
+		Context("in device mode", func() {
+			BeforeEach(func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+					utils.AnnotationGpuMode:         utils.GpuModeDevice,
+				}
+			})
+
+			It("should add the GPU to the devices instead of the limits", func() {
+				result, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Applied).To(BeTrue())
+
+				gpus := vm.Spec.Template.Spec.Domain.Devices.GPUs
+				Expect(gpus).To(HaveLen(1))
+				Expect(gpus[0].Name).To(Equal("gpu-device-0"))
+				Expect(gpus[0].DeviceName).To(Equal("nvidia.com/gpu"))
+				Expect(vm.Spec.Template.Spec.Domain.Resources.Limits).ToNot(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+			})
+
+			It("should configure the GPU display", func() {
+				vm.Annotations[utils.AnnotationGpuDisplay] = "ramfb"
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				display := vm.Spec.Template.Spec.Domain.Devices.GPUs[0].VirtualGPUOptions.Display
+				Expect(*display.Enabled).To(BeTrue())
+				Expect(*display.RamFB.Enabled).To(BeTrue())
+			})
+
+			It("should not add a duplicate GPU", func() {
+				vm.Spec.Template.Spec.Domain.Devices.GPUs = []kubevirtv1.GPU{
+					{Name: "gpu-device-0", DeviceName: "nvidia.com/gpu"},
+				}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.Spec.Template.Spec.Domain.Devices.GPUs).To(HaveLen(1))
+			})
+		})
+
+		Context("with invalid mode settings", func() {
+			It("should reject an unknown mode", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+					utils.AnnotationGpuMode:         "passthrough",
+				}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(utils.AnnotationGpuMode))
+			})
+
+			It("should reject a display setting in resource mode", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+					utils.AnnotationGpuDisplay:      "enabled",
+				}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("with a GPU count", func() {
+			It("should set the resource quantity", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu=2",
+				}
+				result, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Annotations[utils.AnnotationGpuDevicePluginApplied]).To(Equal("nvidia.com/gpu"))
+
+				limits := vm.Spec.Template.Spec.Domain.Resources.Limits
+				Expect(limits[corev1.ResourceName("nvidia.com/gpu")]).To(BeComparableTo(resource.MustParse("2")))
+			})
+
+			It("should add one GPU device per count in device mode", func() {
+				vm.Spec.Template.Spec.Domain.Devices.GPUs = []kubevirtv1.GPU{
+					{Name: "gpu-device-0", DeviceName: "nvidia.com/gpu"},
+				}
+				vm.Annotations = map[string]string{
+					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu=3",
+					utils.AnnotationGpuMode:         utils.GpuModeDevice,
+				}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				gpus := vm.Spec.Template.Spec.Domain.Devices.GPUs
+				Expect(gpus).To(HaveLen(3))
+				Expect(gpus[1].Name).To(Equal("gpu-device-1"))
+				Expect(gpus[2].Name).To(Equal("gpu-device-2"))
+			})
+
+			Context("when the plugin was applied before", func() {
+				BeforeEach(func() {
+					vm.Annotations = map[string]string{utils.AnnotationGpuDevicePluginApplied: "nvidia.com/gpu"}
+				})
+
+				applyLimit := func(before, requested string) {
+					vm.Spec.Template.Spec.Domain.Resources.Limits = corev1.ResourceList{
+						"nvidia.com/gpu": resource.MustParse(before),
+					}
+					vm.Annotations[utils.AnnotationGpuDevicePlugin] = "nvidia.com/gpu=" + requested
+
+					result, err := feature.Apply(ctx, vm, nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.Applied).To(BeTrue())
+					Expect(result.Warnings).To(BeEmpty())
+				}
+
+				// applyDevices applies the request to a VM with a GPU of the user
+				// and before GPUs added earlier, and returns the GPU names
+				applyDevices := func(before int, requested string) []string {
+					gpus := []kubevirtv1.GPU{{Name: "user-gpu", DeviceName: "nvidia.com/gpu"}}
+					for i := 0; i < before; i++ {
+						gpus = append(gpus, kubevirtv1.GPU{Name: fmt.Sprintf("gpu-device-%d", i), DeviceName: "nvidia.com/gpu"})
+					}
+					vm.Spec.Template.Spec.Domain.Devices.GPUs = gpus
+					vm.Annotations[utils.AnnotationGpuDevicePlugin] = "nvidia.com/gpu=" + requested
+					vm.Annotations[utils.AnnotationGpuMode] = utils.GpuModeDevice
+
+					_, err := feature.Apply(ctx, vm, nil)
+					Expect(err).ToNot(HaveOccurred())
+
+					var names []string
+					for _, gpu := range vm.Spec.Template.Spec.Domain.Devices.GPUs {
+						names = append(names, gpu.Name)
+					}
+					return names
+				}
+
+				It("should raise the limit it added", func() {
+					applyLimit("1", "2")
+					Expect(vm.Spec.Template.Spec.Domain.Resources.Limits[corev1.ResourceName("nvidia.com/gpu")]).To(BeComparableTo(resource.MustParse("2")))
+				})
+
+				It("should lower the limit it added", func() {
+					applyLimit("3", "1")
+					Expect(vm.Spec.Template.Spec.Domain.Resources.Limits[corev1.ResourceName("nvidia.com/gpu")]).To(BeComparableTo(resource.MustParse("1")))
+				})
+
+				It("should add GPUs for a raised count in device mode", func() {
+					Expect(applyDevices(1, "3")).To(Equal([]string{"user-gpu", "gpu-device-0", "gpu-device-1"}))
+				})
+
+				It("should remove the GPUs it added for a lowered count in device mode", func() {
+					Expect(applyDevices(3, "2")).To(Equal([]string{"user-gpu", "gpu-device-0"}))
+				})
+			})
+
+			It("should reject counts above the configured maximum", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu=5",
+				}
+				err := feature.Validate(ctx, vm, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("exceeds the maximum"))
+			})
+
+			It("should reject invalid counts", func() {
+				for _, value := range []string{"nvidia.com/gpu=0", "nvidia.com/gpu=two", "nvidia.com/gpu="} {
+					vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: value}
+					Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed(), value)
+				}
+			})
+		})
+
+		Context("when the feature is disabled in config", func() {
+			It("should not apply", func() {
+				gpuCfg.Enabled = false
+				vm.Annotations = map[string]string{
+					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+				}
+				result, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Applied).To(BeFalse())
+			})
+		})
+
+		Context("with a node selector configured", func() {
+			It("should require the node labels in both modes", func() {
+				gpuCfg.NodeSelector = map[string]string{"nvidia.com/gpu.present": "true"}
+				for _, mode := range []string{utils.GpuModeResource, utils.GpuModeDevice} {
+					vm.Spec.Template.Spec.NodeSelector = nil
+					vm.Annotations = map[string]string{
+						utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+						utils.AnnotationGpuMode:         mode,
+					}
+					_, err := feature.Apply(ctx, vm, nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(vm.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue("nvidia.com/gpu.present", "true"), mode)
+				}
+			})
+		})
+	})
+
+	Describe("NamespaceQuota", func() {
+		var fakeClient client.Client
+
+		// apply runs the feature, which checks the quota
+		apply := func(ctx context.Context, k8sClient client.Client) error {
+			_, err := feature.Apply(ctx, vm, k8sClient)
+			return err
+		}
+
+		gpuVM := func(name, namespace string, limit string, devices int) *kubevirtv1.VirtualMachine {
+			other := &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+				},
+			}
+			if limit != "" {
+				other.Spec.Template.Spec.Domain.Resources.Limits = corev1.ResourceList{
+					"nvidia.com/gpu":      resource.MustParse(limit),
+					corev1.ResourceMemory: resource.MustParse("4Gi"),
+				}
+			}
+			for i := 0; i < devices; i++ {
+				other.Spec.Template.Spec.Domain.Devices.GPUs = append(other.Spec.Template.Spec.Domain.Devices.GPUs,
+					kubevirtv1.GPU{Name: "gpu", DeviceName: "nvidia.com/gpu"})
+			}
+			return other
+		}
+
+		BeforeEach(func() {
+			gpuCfg.NamespaceQuota = map[string]int{"default": 4}
+
+			scheme := runtime.NewScheme()
+			_ = kubevirtv1.AddToScheme(scheme)
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				gpuVM("trainer", "default", "2", 0),
+				gpuVM("desktop", "default", "", 1),
+				gpuVM("elsewhere", "other", "8", 0),
+			).Build()
+		})
+
+		It("should admit requests within the namespace quota", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+			Expect(apply(ctx, fakeClient)).To(Succeed())
+		})
+
+		It("should reject requests over the namespace quota", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu=2"}
+			Expect(apply(ctx, fakeClient)).To(MatchError(
+				"GPU quota of namespace default exceeded: 2 requested with 3 in use by other VMs, limit 4"))
+		})
+
+		It("should not count the VM's own stored GPUs twice", func() {
+			Expect(fakeClient.Create(ctx, gpuVM("test-vm", "default", "1", 0))).To(Succeed())
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+			Expect(apply(ctx, fakeClient)).To(Succeed())
+		})
+
+		It("should admit requests that don't add GPUs", func() {
+			gpuCfg.NamespaceQuota["default"] = 1
+			vm.Spec.Template.Spec.Domain.Resources.Limits = corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")}
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+			Expect(apply(ctx, fakeClient)).To(Succeed())
+		})
+
+		It("should apply the wildcard quota to namespaces not listed", func() {
+			gpuCfg.NamespaceQuota = map[string]int{"*": 8}
+			vm.Namespace = "other"
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+			Expect(apply(ctx, fakeClient)).To(MatchError(ContainSubstring("limit 8")))
+		})
+
+		It("should prefer the VM lister in the context", func() {
+			empty := fake.NewClientBuilder().WithScheme(fakeClient.Scheme()).Build()
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu=2"}
+			Expect(apply(features.WithVMLister(ctx, empty), fakeClient)).To(Succeed())
+		})
+
+		It("should not list VMs to validate", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu=2"}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should fail without a way to list VMs", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+			Expect(apply(ctx, nil)).To(MatchError(ContainSubstring("cannot enforce the GPU quota")))
+		})
+	})
+
+	Describe("Revert", func() {
+		It("should remove the applied GPU resource limit", func() {
+			vm.Spec.Template.Spec.Domain.Resources.Limits = corev1.ResourceList{
+				"amd.com/gpu": resource.MustParse("2"),
+			}
+			vm.Annotations = map[string]string{
+				utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			previous := vm.DeepCopy()
+			for k, v := range result.Annotations {
+				previous.Annotations[k] = v
+			}
+			delete(vm.Annotations, utils.AnnotationGpuDevicePlugin)
+
+			Expect(feature.Revert(ctx, vm, previous)).To(Succeed())
+			limits := vm.Spec.Template.Spec.Domain.Resources.Limits
+			Expect(limits).ToNot(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+			Expect(limits).To(HaveKey(corev1.ResourceName("amd.com/gpu")))
+		})
+
+		It("should keep a resource limit the user set", func() {
+			vm.Spec.Template.Spec.Domain.Resources.Limits = corev1.ResourceList{
+				"nvidia.com/gpu": resource.MustParse("4"),
+			}
+			vm.Annotations = map[string]string{
+				utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Annotations).ToNot(HaveKey(utils.AnnotationGpuDevicePluginApplied))
+
+			previous := vm.DeepCopy()
+			for k, v := range result.Annotations {
+				previous.Annotations[k] = v
+			}
+			delete(vm.Annotations, utils.AnnotationGpuDevicePlugin)
+
+			Expect(feature.Revert(ctx, vm, previous)).To(Succeed())
+			limits := vm.Spec.Template.Spec.Domain.Resources.Limits
+			Expect(limits[corev1.ResourceName("nvidia.com/gpu")]).To(BeComparableTo(resource.MustParse("4")))
+		})
+
+		It("should remove GPUs added in device mode", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+				utils.AnnotationGpuMode:         utils.GpuModeDevice,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.GPUs).ToNot(BeEmpty())
+
+			previous := vm.DeepCopy()
+			for k, v := range result.Annotations {
+				previous.Annotations[k] = v
+			}
+			vm.Annotations = nil
+
+			Expect(feature.Revert(ctx, vm, previous)).To(Succeed())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.GPUs).To(BeEmpty())
+		})
 	})
 })
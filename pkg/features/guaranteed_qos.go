@@ -0,0 +1,137 @@
+package features
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// GuaranteedQoS implements the guaranteed-QoS preset.
+// It makes CPU and memory requests equal to limits, enables dedicated
+// CPU placement and backs guest memory with hugepages.
+type GuaranteedQoS struct {
+	configSource utils.ConfigSource
+}
+
+// NewGuaranteedQoS creates a new GuaranteedQoS feature
+func NewGuaranteedQoS(configSource utils.ConfigSource) *GuaranteedQoS {
+	return &GuaranteedQoS{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *GuaranteedQoS) Name() string {
+	return utils.FeatureGuaranteedQoS
+}
+
+// IsEnabled checks if the guaranteed-QoS preset is requested via annotations or labels
+func (f *GuaranteedQoS) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGuaranteedQoS)
+	return exists && utils.IsTruthyValue(value)
+}
+
+// Validate ensures CPU and memory are specified, CPUs are whole cores
+// and the hugepage size is supported
+func (f *GuaranteedQoS) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGuaranteedQoS)
+	if !exists {
+		return nil
+	}
+
+	if value != "enabled" {
+		return fmt.Errorf("invalid value for %s: %s (expected 'enabled')",
+			utils.AnnotationGuaranteedQoS, value)
+	}
+
+	if size, ok := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationHugepagesSize); ok {
+		if size != "2Mi" && size != "1Gi" {
+			return fmt.Errorf("invalid value for %s: %s (expected '2Mi' or '1Gi')",
+				utils.AnnotationHugepagesSize, size)
+		}
+	}
+
+	if vm.Spec.Template == nil {
+		return fmt.Errorf("VM template is nil")
+	}
+
+	resources := vm.Spec.Template.Spec.Domain.Resources
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		_, hasLimit := resources.Limits[name]
+		_, hasRequest := resources.Requests[name]
+		if !hasLimit && !hasRequest {
+			return fmt.Errorf("guaranteed QoS requires a %s request or limit", name)
+		}
+	}
+
+	for _, list := range []corev1.ResourceList{resources.Requests, resources.Limits} {
+		if cpu, ok := list[corev1.ResourceCPU]; ok && cpu.MilliValue()%1000 != 0 {
+			return fmt.Errorf("guaranteed QoS requires whole CPU cores, got %s", cpu.String())
+		}
+	}
+
+	return nil
+}
+
+// Apply equalizes requests and limits, enables dedicated CPUs and configures hugepages
+func (f *GuaranteedQoS) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	domain := &vm.Spec.Template.Spec.Domain
+
+	// Limits take precedence; requests are used when no limit is set
+	if domain.Resources.Requests == nil {
+		domain.Resources.Requests = make(corev1.ResourceList)
+	}
+	if domain.Resources.Limits == nil {
+		domain.Resources.Limits = make(corev1.ResourceList)
+	}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if limit, ok := domain.Resources.Limits[name]; ok {
+			domain.Resources.Requests[name] = limit.DeepCopy()
+		} else {
+			domain.Resources.Limits[name] = domain.Resources.Requests[name].DeepCopy()
+		}
+	}
+
+	if domain.CPU == nil {
+		domain.CPU = &kubevirtv1.CPU{}
+	}
+	domain.CPU.DedicatedCPUPlacement = true
+
+	// Keep an explicitly configured hugepage size
+	pageSize := utils.DefaultHugepagesSize
+	if size, ok := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationHugepagesSize); ok {
+		pageSize = size
+	}
+	if domain.Memory == nil {
+		domain.Memory = &kubevirtv1.Memory{}
+	}
+	if domain.Memory.Hugepages == nil {
+		domain.Memory.Hugepages = &kubevirtv1.Hugepages{PageSize: pageSize}
+	}
+
+	logger.Info("Applied guaranteed QoS preset", "vm", vm.Name, "hugepages", domain.Memory.Hugepages.PageSize)
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationGuaranteedQoSApplied, "true")
+	result.AddMessage(fmt.Sprintf("Applied guaranteed QoS with dedicated CPUs and %s hugepages", domain.Memory.Hugepages.PageSize))
+
+	return result, nil
+}
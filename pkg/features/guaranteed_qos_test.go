@@ -0,0 +1,164 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("GuaranteedQoS", func() {
+	var (
+		feature *features.GuaranteedQoS
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewGuaranteedQoS(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							Resources: kubevirtv1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("2"),
+									corev1.ResourceMemory: resource.MustParse("4Gi"),
+								},
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("4"),
+									corev1.ResourceMemory: resource.MustParse("8Gi"),
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureGuaranteedQoS))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is enabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGuaranteedQoS: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		BeforeEach(func() {
+			vm.Annotations = map[string]string{utils.AnnotationGuaranteedQoS: "enabled"}
+		})
+
+		It("should succeed with CPU and memory resources", func() {
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject VMs without memory resources", func() {
+			delete(vm.Spec.Template.Spec.Domain.Resources.Requests, corev1.ResourceMemory)
+			delete(vm.Spec.Template.Spec.Domain.Resources.Limits, corev1.ResourceMemory)
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("memory"))
+		})
+
+		It("should reject fractional CPUs", func() {
+			vm.Spec.Template.Spec.Domain.Resources.Requests[corev1.ResourceCPU] = resource.MustParse("1500m")
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("whole CPU cores"))
+		})
+
+		It("should reject unsupported hugepage sizes", func() {
+			vm.Annotations[utils.AnnotationHugepagesSize] = "4Ki"
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(utils.AnnotationHugepagesSize))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should not modify the VM when not enabled", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.CPU).To(BeNil())
+		})
+
+		It("should copy limits to requests", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGuaranteedQoS: "enabled"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationGuaranteedQoSApplied, "true"))
+
+			resources := vm.Spec.Template.Spec.Domain.Resources
+			Expect(resources.Requests.Cpu().Equal(resource.MustParse("4"))).To(BeTrue())
+			Expect(resources.Requests.Memory().Equal(resource.MustParse("8Gi"))).To(BeTrue())
+		})
+
+		It("should copy requests to limits when limits are missing", func() {
+			vm.Spec.Template.Spec.Domain.Resources.Limits = nil
+			vm.Annotations = map[string]string{utils.AnnotationGuaranteedQoS: "enabled"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			resources := vm.Spec.Template.Spec.Domain.Resources
+			Expect(resources.Limits.Cpu().Equal(resource.MustParse("2"))).To(BeTrue())
+			Expect(resources.Limits.Memory().Equal(resource.MustParse("4Gi"))).To(BeTrue())
+		})
+
+		It("should enable dedicated CPUs and default hugepages", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGuaranteedQoS: "enabled"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			domain := vm.Spec.Template.Spec.Domain
+			Expect(domain.CPU.DedicatedCPUPlacement).To(BeTrue())
+			Expect(domain.Memory.Hugepages.PageSize).To(Equal(utils.DefaultHugepagesSize))
+		})
+
+		It("should use the requested hugepage size", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationGuaranteedQoS: "enabled",
+				utils.AnnotationHugepagesSize: "1Gi",
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.Memory.Hugepages.PageSize).To(Equal("1Gi"))
+		})
+
+		It("should keep existing hugepages configuration", func() {
+			vm.Spec.Template.Spec.Domain.Memory = &kubevirtv1.Memory{
+				Hugepages: &kubevirtv1.Hugepages{PageSize: "1Gi"},
+			}
+			vm.Annotations = map[string]string{utils.AnnotationGuaranteedQoS: "enabled"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.Memory.Hugepages.PageSize).To(Equal("1Gi"))
+		})
+	})
+})
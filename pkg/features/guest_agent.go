@@ -0,0 +1,126 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// agentlessImageHints are container disk image name fragments for guest images
+// known to ship without the QEMU guest agent
+var agentlessImageHints = []string{"cirros"}
+
+// guestAgentPackage is the package name looked for in cloud-init userdata
+const guestAgentPackage = "qemu-guest-agent"
+
+// GuestAgent implements QEMU guest agent enforcement.
+// KubeVirt attaches the org.qemu.guest_agent.0 virtio-serial channel to every
+// domain, so no device is added; instead the VM is checked for images that are
+// unlikely to run the agent and a warning is recorded for downstream tooling.
+type GuestAgent struct {
+	configSource utils.ConfigSource
+}
+
+// NewGuestAgent creates a new GuestAgent feature
+func NewGuestAgent(configSource utils.ConfigSource) *GuestAgent {
+	return &GuestAgent{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *GuestAgent) Name() string {
+	return utils.FeatureGuestAgent
+}
+
+// IsEnabled checks if the guest agent is required via annotations or labels
+func (f *GuestAgent) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationGuestAgent)
+	return exists && utils.IsTruthyValue(value)
+}
+
+// Validate performs validation of the guest agent configuration
+func (f *GuestAgent) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	if !f.IsEnabled(vm) {
+		return nil
+	}
+
+	if vm.Spec.Template == nil {
+		return fmt.Errorf("VM template is nil")
+	}
+
+	return nil
+}
+
+// Apply records the guest agent requirement and warns about agentless images
+func (f *GuestAgent) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	logger.Info("Applying guest agent enforcement", "vm", vm.Name)
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationGuestAgentApplied, "true")
+	result.AddMessage("QEMU guest agent channel is attached by KubeVirt")
+
+	if warning := guestAgentWarning(&vm.Spec.Template.Spec); warning != "" {
+		logger.Info("Guest image may lack the QEMU guest agent", "vm", vm.Name, "reason", warning)
+		result.AddAnnotation(utils.AnnotationGuestAgentWarning, warning)
+		result.AddMessage(fmt.Sprintf("Warning: %s", warning))
+	}
+
+	return result, nil
+}
+
+// guestAgentWarning returns a reason the guest is unlikely to run the agent, if any
+func guestAgentWarning(spec *kubevirtv1.VirtualMachineInstanceSpec) string {
+	for _, vol := range spec.Volumes {
+		if vol.ContainerDisk == nil {
+			continue
+		}
+		image := strings.ToLower(vol.ContainerDisk.Image)
+		for _, hint := range agentlessImageHints {
+			if strings.Contains(image, hint) {
+				if cloudInitInstallsAgent(spec) {
+					return ""
+				}
+				return fmt.Sprintf("container disk image %s does not include %s", vol.ContainerDisk.Image, guestAgentPackage)
+			}
+		}
+	}
+
+	return ""
+}
+
+// cloudInitInstallsAgent reports whether inline cloud-init userdata mentions the agent package
+func cloudInitInstallsAgent(spec *kubevirtv1.VirtualMachineInstanceSpec) bool {
+	for _, vol := range spec.Volumes {
+		var userData string
+		switch {
+		case vol.CloudInitNoCloud != nil:
+			userData = vol.CloudInitNoCloud.UserData
+		case vol.CloudInitConfigDrive != nil:
+			userData = vol.CloudInitConfigDrive.UserData
+		}
+		if strings.Contains(userData, guestAgentPackage) {
+			return true
+		}
+	}
+
+	return false
+}
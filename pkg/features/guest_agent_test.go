@@ -0,0 +1,114 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("GuestAgent", func() {
+	var (
+		feature *features.GuestAgent
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewGuestAgent(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+						Volumes: []kubevirtv1.Volume{{
+							Name: "rootdisk",
+							VolumeSource: kubevirtv1.VolumeSource{
+								ContainerDisk: &kubevirtv1.ContainerDiskSource{
+									Image: "quay.io/containerdisks/fedora:40",
+								},
+							},
+						}},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureGuestAgent))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is enabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGuestAgent: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return false when annotation is disabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGuestAgent: "false"}
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+	})
+
+	Describe("Apply", func() {
+		BeforeEach(func() {
+			vm.Annotations = map[string]string{utils.AnnotationGuestAgent: "enabled"}
+		})
+
+		It("should record the requirement without a warning", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationGuestAgentApplied, "true"))
+			Expect(result.Annotations).ToNot(HaveKey(utils.AnnotationGuestAgentWarning))
+		})
+
+		It("should warn for images known to lack the agent", func() {
+			vm.Spec.Template.Spec.Volumes[0].ContainerDisk.Image = "quay.io/kubevirt/cirros-container-disk-demo"
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKey(utils.AnnotationGuestAgentWarning))
+			Expect(result.Messages).To(ContainElement(ContainSubstring("Warning")))
+		})
+
+		It("should not warn when cloud-init installs the agent", func() {
+			vm.Spec.Template.Spec.Volumes[0].ContainerDisk.Image = "quay.io/kubevirt/cirros-container-disk-demo"
+			vm.Spec.Template.Spec.Volumes = append(vm.Spec.Template.Spec.Volumes, kubevirtv1.Volume{
+				Name: "cloudinit",
+				VolumeSource: kubevirtv1.VolumeSource{
+					CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+						UserData: "#cloud-config\npackages:\n  - qemu-guest-agent\n",
+					},
+				},
+			})
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Annotations).ToNot(HaveKey(utils.AnnotationGuestAgentWarning))
+		})
+
+		It("should return an error when the template is nil", func() {
+			vm.Spec.Template = nil
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
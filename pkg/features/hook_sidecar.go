@@ -0,0 +1,273 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// HookSidecar represents a KubeVirt hook sidecar configuration
+type HookSidecar struct {
+	Image           string                `json:"image,omitempty"`
+	ImagePullPolicy string                `json:"imagePullPolicy,omitempty"`
+	Args            []string              `json:"args,omitempty"`
+	ConfigMap       *HookSidecarConfigMap `json:"configMap,omitempty"`
+}
+
+// HookSidecarConfigMap references a hook script that sidecar-shim runs from a ConfigMap
+type HookSidecarConfigMap struct {
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	HookPath string `json:"hookPath"`
+}
+
+// HookSidecars implements generic KubeVirt hook sidecar injection.
+// Sidecars are appended to hooks.kubevirt.io/hookSidecars on the VM template.
+type HookSidecars struct {
+	configSource utils.ConfigSource
+}
+
+// NewHookSidecars creates a new HookSidecars feature
+func NewHookSidecars(configSource utils.ConfigSource) *HookSidecars {
+	return &HookSidecars{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *HookSidecars) Name() string {
+	return utils.FeatureHookSidecar
+}
+
+// IsEnabled checks if hook sidecars are requested via annotations or labels
+func (f *HookSidecars) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationHookSidecar)
+	return exists && value != ""
+}
+
+// Validate checks the sidecar definitions and that referenced script ConfigMaps exist
+func (f *HookSidecars) Validate(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationHookSidecar)
+	if !exists {
+		return nil
+	}
+
+	sidecars, err := parseHookSidecars(value)
+	if err != nil {
+		return err
+	}
+
+	// Script ConfigMaps can only be checked when a client is available
+	if k8sClient == nil {
+		return nil
+	}
+
+	for _, sidecar := range sidecars {
+		if sidecar.ConfigMap == nil {
+			continue
+		}
+		configMap := &corev1.ConfigMap{}
+		key := client.ObjectKey{Namespace: vm.Namespace, Name: sidecar.ConfigMap.Name}
+		if err := k8sClient.Get(ctx, key, configMap); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Errorf("hook ConfigMap %s/%s not found", vm.Namespace, sidecar.ConfigMap.Name)
+			}
+			return fmt.Errorf("failed to get hook ConfigMap %s/%s: %w", vm.Namespace, sidecar.ConfigMap.Name, err)
+		}
+		if _, ok := configMap.Data[sidecar.ConfigMap.Key]; !ok {
+			return fmt.Errorf("hook ConfigMap %s/%s has no %q key", vm.Namespace, sidecar.ConfigMap.Name, sidecar.ConfigMap.Key)
+		}
+	}
+
+	return nil
+}
+
+// Apply appends the requested sidecars to the VM template's hook annotation
+func (f *HookSidecars) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationHookSidecar)
+	sidecars, _ := parseHookSidecars(value)
+
+	added, err := mergeHookSidecars(vm, sidecars...)
+	if err != nil {
+		return result, err
+	}
+	if added == 0 {
+		logger.Info("Hook sidecars already present, skipping", "vm", vm.Name)
+		return result, nil
+	}
+
+	logger.Info("Added hook sidecars", "vm", vm.Name, "count", added)
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationHookSidecarApplied, "true")
+	result.AddMessage(fmt.Sprintf("Added %d hook sidecar(s)", added))
+
+	return result, nil
+}
+
+// parseHookSidecars parses a single sidecar object or an array of sidecars
+func parseHookSidecars(value string) ([]HookSidecar, error) {
+	var sidecars []HookSidecar
+	trimmed := strings.TrimSpace(value)
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.DisallowUnknownFields()
+	if strings.HasPrefix(trimmed, "[") {
+		if err := decoder.Decode(&sidecars); err != nil {
+			return nil, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationHookSidecar, err)
+		}
+	} else {
+		var sidecar HookSidecar
+		if err := decoder.Decode(&sidecar); err != nil {
+			return nil, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationHookSidecar, err)
+		}
+		sidecars = append(sidecars, sidecar)
+	}
+
+	if len(sidecars) == 0 {
+		return nil, fmt.Errorf("no hook sidecars specified in %s", utils.AnnotationHookSidecar)
+	}
+
+	for i, sidecar := range sidecars {
+		if sidecar.Image == "" && sidecar.ConfigMap == nil {
+			return nil, fmt.Errorf("hook sidecar %d needs an image or a configMap", i)
+		}
+		if sidecar.Image != "" && !imageRefRegex.MatchString(sidecar.Image) {
+			return nil, fmt.Errorf("invalid hook sidecar image reference: %s", sidecar.Image)
+		}
+		if cm := sidecar.ConfigMap; cm != nil {
+			if cm.Name == "" || cm.Key == "" || cm.HookPath == "" {
+				return nil, fmt.Errorf("hook sidecar %d configMap needs name, key and hookPath", i)
+			}
+			if len(cm.Name) > 253 || !configMapNameRegex.MatchString(cm.Name) {
+				return nil, fmt.Errorf("invalid hook ConfigMap name format: %s (must be a valid DNS subdomain)", cm.Name)
+			}
+		}
+	}
+
+	return sidecars, nil
+}
+
+// mergeHookSidecars appends sidecars missing from the VM template's hook
+// annotation and returns how many were added. Existing entries are kept
+// verbatim so fields unknown to HookSidecar survive the rewrite.
+func mergeHookSidecars(vm *kubevirtv1.VirtualMachine, sidecars ...HookSidecar) (int, error) {
+	annotations := vm.Spec.Template.ObjectMeta.Annotations
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	var existing []json.RawMessage
+	if raw := annotations[utils.HookAnnotationKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &existing); err != nil {
+			return 0, fmt.Errorf("failed to parse existing %s annotation: %w", utils.HookAnnotationKey, err)
+		}
+	}
+
+	current := make([]HookSidecar, len(existing))
+	for i, raw := range existing {
+		// Entries that don't decode are kept but never match
+		_ = json.Unmarshal(raw, &current[i])
+	}
+
+	added := 0
+	for _, sidecar := range sidecars {
+		present := false
+		for _, c := range current {
+			if reflect.DeepEqual(c, sidecar) {
+				present = true
+				break
+			}
+		}
+		if present {
+			continue
+		}
+
+		raw, err := json.Marshal(sidecar)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal hook sidecar configuration: %w", err)
+		}
+		existing = append(existing, raw)
+		current = append(current, sidecar)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+
+	// Marshal to JSON array (KubeVirt expects an array of sidecars)
+	hookJSON, err := json.Marshal(existing)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal hook sidecar configuration: %w", err)
+	}
+
+	annotations[utils.HookAnnotationKey] = string(hookJSON)
+	vm.Spec.Template.ObjectMeta.Annotations = annotations
+	return added, nil
+}
+
+// removeHookSidecars drops the sidecars matching match from the VM template's
+// hook annotation and returns how many were removed. The annotation is deleted
+// once no sidecars remain.
+func removeHookSidecars(vm *kubevirtv1.VirtualMachine, match func(HookSidecar) bool) (int, error) {
+	annotations := vm.Spec.Template.ObjectMeta.Annotations
+	raw := annotations[utils.HookAnnotationKey]
+	if raw == "" {
+		return 0, nil
+	}
+
+	var existing []json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &existing); err != nil {
+		return 0, fmt.Errorf("failed to parse existing %s annotation: %w", utils.HookAnnotationKey, err)
+	}
+
+	kept := make([]json.RawMessage, 0, len(existing))
+	for _, entry := range existing {
+		var sidecar HookSidecar
+		if err := json.Unmarshal(entry, &sidecar); err == nil && match(sidecar) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	removed := len(existing) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	if len(kept) == 0 {
+		delete(annotations, utils.HookAnnotationKey)
+		return removed, nil
+	}
+
+	hookJSON, err := json.Marshal(kept)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal hook sidecar configuration: %w", err)
+	}
+	annotations[utils.HookAnnotationKey] = string(hookJSON)
+	return removed, nil
+}
@@ -0,0 +1,186 @@
+package features_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("HookSidecars", func() {
+	var (
+		feature    *features.HookSidecars
+		vm         *kubevirtv1.VirtualMachine
+		ctx        context.Context
+		fakeClient client.Client
+	)
+
+	const scriptSidecar = `{"args": ["--version", "v1alpha2"], "configMap": {"name": "my-hook", "key": "hook.sh", "hookPath": "/usr/bin/onDefineDomain"}}`
+
+	hookAnnotation := func() []map[string]interface{} {
+		var sidecars []map[string]interface{}
+		Expect(json.Unmarshal([]byte(vm.Spec.Template.ObjectMeta.Annotations[utils.HookAnnotationKey]), &sidecars)).To(Succeed())
+		return sidecars
+	}
+
+	BeforeEach(func() {
+		feature = features.NewHookSidecars(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		_ = corev1.AddToScheme(scheme)
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-hook", Namespace: "default"},
+				Data:       map[string]string{"hook.sh": "#!/bin/sh\necho \"$4\"\n"},
+			},
+		).Build()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureHookSidecar))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationHookSidecar: scriptSidecar}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept an image sidecar", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationHookSidecar: `{"image": "quay.io/example/hook:v1", "args": ["--version", "v1alpha2"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should accept a script ConfigMap sidecar that exists", func() {
+			vm.Annotations = map[string]string{utils.AnnotationHookSidecar: scriptSidecar}
+			Expect(feature.Validate(ctx, vm, fakeClient)).To(Succeed())
+		})
+
+		It("should reject a missing script ConfigMap", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationHookSidecar: `{"configMap": {"name": "missing", "key": "hook.sh", "hookPath": "/usr/bin/onDefineDomain"}}`,
+			}
+			err := feature.Validate(ctx, vm, fakeClient)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not found"))
+		})
+
+		It("should reject a script ConfigMap without the key", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationHookSidecar: `{"configMap": {"name": "my-hook", "key": "other.sh", "hookPath": "/usr/bin/onDefineDomain"}}`,
+			}
+			err := feature.Validate(ctx, vm, fakeClient)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`has no "other.sh" key`))
+		})
+
+		It("should reject a sidecar without image or configMap", func() {
+			vm.Annotations = map[string]string{utils.AnnotationHookSidecar: `{"args": ["--version", "v1alpha2"]}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("needs an image or a configMap"))
+		})
+
+		It("should reject an incomplete configMap reference", func() {
+			vm.Annotations = map[string]string{utils.AnnotationHookSidecar: `{"configMap": {"name": "my-hook"}}`}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject unknown fields", func() {
+			vm.Annotations = map[string]string{utils.AnnotationHookSidecar: `{"image": "quay.io/example/hook:v1", "command": "sh"}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid JSON"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should add the sidecar to the hook annotation", func() {
+			vm.Annotations = map[string]string{utils.AnnotationHookSidecar: scriptSidecar}
+			result, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationHookSidecarApplied, "true"))
+
+			sidecars := hookAnnotation()
+			Expect(sidecars).To(HaveLen(1))
+			Expect(sidecars[0]["configMap"]).To(HaveKeyWithValue("name", "my-hook"))
+		})
+
+		It("should accept an array of sidecars", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationHookSidecar: `[{"image": "quay.io/example/a:v1"}, {"image": "quay.io/example/b:v1"}]`,
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hookAnnotation()).To(HaveLen(2))
+		})
+
+		It("should append to existing sidecars and keep their fields", func() {
+			vm.Spec.Template.ObjectMeta.Annotations = map[string]string{
+				utils.HookAnnotationKey: `[{"args": ["--version", "v1alpha2"], "pvc": {"name": "hooks", "volumePath": "/hooks"}}]`,
+			}
+			vm.Annotations = map[string]string{utils.AnnotationHookSidecar: `{"image": "quay.io/example/hook:v1"}`}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			sidecars := hookAnnotation()
+			Expect(sidecars).To(HaveLen(2))
+			Expect(sidecars[0]).To(HaveKey("pvc"))
+			Expect(sidecars[1]).To(HaveKeyWithValue("image", "quay.io/example/hook:v1"))
+		})
+
+		It("should not duplicate a sidecar that is already present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationHookSidecar: `{"image": "quay.io/example/hook:v1"}`}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(hookAnnotation()).To(HaveLen(1))
+		})
+
+		It("should fail on a malformed existing hook annotation", func() {
+			vm.Spec.Template.ObjectMeta.Annotations = map[string]string{utils.HookAnnotationKey: "not-json"}
+			vm.Annotations = map[string]string{utils.AnnotationHookSidecar: `{"image": "quay.io/example/hook:v1"}`}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
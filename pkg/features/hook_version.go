@@ -0,0 +1,71 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/version"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// hookVersionCacheTTL bounds how long a detected hook API version is reused
+// before the KubeVirt CR is read again
+const hookVersionCacheTTL = 5 * time.Minute
+
+// minKubeVirtV1Alpha3 is the first KubeVirt release serving the v1alpha3 hook API
+var minKubeVirtV1Alpha3 = version.MustParseGeneric("1.0.0")
+
+// hookVersionDetector picks the hook sidecar API version from the KubeVirt
+// version reported in the KubeVirt CR status, caching the answer for
+// hookVersionCacheTTL
+type hookVersionDetector struct {
+	mu      sync.Mutex
+	version string
+	expires time.Time
+}
+
+// detect returns the newest hook API version supported by the deployed
+// KubeVirt, falling back to utils.SidecarHookVersion when it is unknown. The
+// KubeVirt CR is read without holding the lock, so a slow API server doesn't
+// hold up requests that could be answered from the cache.
+func (d *hookVersionDetector) detect(ctx context.Context, k8sClient client.Client) (string, error) {
+	d.mu.Lock()
+	cached, fresh := d.version, time.Now().Before(d.expires)
+	d.mu.Unlock()
+	if fresh {
+		return cached, nil
+	}
+
+	kubevirts := &kubevirtv1.KubeVirtList{}
+	if err := k8sClient.List(ctx, kubevirts); err != nil && !meta.IsNoMatchError(err) {
+		return "", fmt.Errorf("failed to list KubeVirt resources: %w", err)
+	}
+
+	hookVersion := utils.SidecarHookVersion
+	if len(kubevirts.Items) > 0 {
+		hookVersion = hookVersionFor(kubevirts.Items[0].Status.ObservedKubeVirtVersion)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.version = hookVersion
+	d.expires = time.Now().Add(hookVersionCacheTTL)
+
+	return hookVersion, nil
+}
+
+// hookVersionFor maps a KubeVirt version such as "v1.4.0" to the hook API
+// version to request. Unparseable versions use the fallback.
+func hookVersionFor(kubevirtVersion string) string {
+	v, err := version.ParseGeneric(kubevirtVersion)
+	if err != nil || !v.AtLeast(minKubeVirtV1Alpha3) {
+		return utils.SidecarHookVersion
+	}
+	return utils.SidecarHookVersionV1Alpha3
+}
@@ -0,0 +1,157 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// Hotplug implements CPU and memory hotplug enablement.
+// It sets the maximum sockets and guest memory so VMs are created hotplug-ready.
+type Hotplug struct {
+	configSource utils.ConfigSource
+}
+
+// NewHotplug creates a new Hotplug feature
+func NewHotplug(configSource utils.ConfigSource) *Hotplug {
+	return &Hotplug{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *Hotplug) Name() string {
+	return utils.FeatureHotplug
+}
+
+// Dependencies validates hotplug limits against the topology set by cpu-topology
+func (f *Hotplug) Dependencies() []Dependency {
+	return []Dependency{
+		{Feature: utils.FeatureCPUTopology},
+	}
+}
+
+// IsEnabled checks if maximum sockets or guest memory are requested via annotations or labels
+func (f *Hotplug) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	maxSockets, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationMaxSockets)
+	maxGuest, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationMaxGuestMemory)
+	return maxSockets != "" || maxGuest != ""
+}
+
+// Validate ensures the maximum values parse and are not below the current values
+func (f *Hotplug) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	maxSockets, maxGuest, err := f.parse(vm)
+	if err != nil {
+		return err
+	}
+
+	if vm.Spec.Template == nil {
+		return nil
+	}
+	domain := vm.Spec.Template.Spec.Domain
+
+	if maxSockets > 0 {
+		sockets := uint32(1)
+		if domain.CPU != nil && domain.CPU.Sockets > 0 {
+			sockets = domain.CPU.Sockets
+		}
+		if maxSockets < sockets {
+			return fmt.Errorf("%s (%d) must be >= current sockets (%d)", utils.AnnotationMaxSockets, maxSockets, sockets)
+		}
+	}
+
+	if maxGuest != nil {
+		if current := currentGuestMemory(domain); current != nil && maxGuest.Cmp(*current) < 0 {
+			return fmt.Errorf("%s (%s) must be >= current guest memory (%s)", utils.AnnotationMaxGuestMemory, maxGuest.String(), current.String())
+		}
+	}
+
+	return nil
+}
+
+// Apply sets the maximum sockets and guest memory on the VM template
+func (f *Hotplug) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	maxSockets, maxGuest, _ := f.parse(vm)
+	domain := &vm.Spec.Template.Spec.Domain
+
+	if maxSockets > 0 {
+		if domain.CPU == nil {
+			domain.CPU = &kubevirtv1.CPU{}
+		}
+		domain.CPU.MaxSockets = maxSockets
+		result.AddMessage(fmt.Sprintf("Set maximum CPU sockets to %d", maxSockets))
+	}
+
+	if maxGuest != nil {
+		if domain.Memory == nil {
+			domain.Memory = &kubevirtv1.Memory{}
+		}
+		domain.Memory.MaxGuest = maxGuest
+		result.AddMessage(fmt.Sprintf("Set maximum guest memory to %s", maxGuest.String()))
+	}
+
+	logger.Info("Applied hotplug limits", "vm", vm.Name, "maxSockets", maxSockets, "maxGuest", maxGuest)
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationHotplugApplied, "true")
+
+	return result, nil
+}
+
+// parse reads the maximum sockets and guest memory from the VM configuration
+func (f *Hotplug) parse(vm *kubevirtv1.VirtualMachine) (uint32, *resource.Quantity, error) {
+	var maxSockets uint32
+	if value, ok := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationMaxSockets); ok {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || n == 0 {
+			return 0, nil, fmt.Errorf("invalid value for %s: %s (expected a positive integer)", utils.AnnotationMaxSockets, value)
+		}
+		maxSockets = uint32(n)
+	}
+
+	var maxGuest *resource.Quantity
+	if value, ok := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationMaxGuestMemory); ok {
+		q, err := resource.ParseQuantity(value)
+		if err != nil || q.Sign() <= 0 {
+			return 0, nil, fmt.Errorf("invalid value for %s: %s (expected a memory quantity such as 16Gi)", utils.AnnotationMaxGuestMemory, value)
+		}
+		maxGuest = &q
+	}
+
+	return maxSockets, maxGuest, nil
+}
+
+// currentGuestMemory returns the guest memory, falling back to the memory request
+func currentGuestMemory(domain kubevirtv1.DomainSpec) *resource.Quantity {
+	if domain.Memory != nil && domain.Memory.Guest != nil {
+		return domain.Memory.Guest
+	}
+	if request, ok := domain.Resources.Requests[corev1.ResourceMemory]; ok {
+		return &request
+	}
+	return nil
+}
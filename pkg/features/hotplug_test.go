@@ -0,0 +1,138 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Hotplug", func() {
+	var (
+		feature *features.Hotplug
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewHotplug(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		guest := resource.MustParse("4Gi")
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							CPU:    &kubevirtv1.CPU{Sockets: 2},
+							Memory: &kubevirtv1.Memory{Guest: &guest},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureHotplug))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when no annotation is present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when either annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMaxSockets: "8"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+
+			vm.Annotations = map[string]string{utils.AnnotationMaxGuestMemory: "16Gi"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept max values above current values", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationMaxSockets:     "8",
+				utils.AnnotationMaxGuestMemory: "16Gi",
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject max sockets below current sockets", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMaxSockets: "1"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("current sockets"))
+		})
+
+		It("should reject max guest memory below current guest memory", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMaxGuestMemory: "2Gi"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("current guest memory"))
+		})
+
+		It("should compare against the memory request without guest memory", func() {
+			vm.Spec.Template.Spec.Domain.Memory = nil
+			vm.Spec.Template.Spec.Domain.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+			}
+			vm.Annotations = map[string]string{utils.AnnotationMaxGuestMemory: "4Gi"}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject invalid values", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMaxSockets: "many"}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+
+			vm.Annotations = map[string]string{utils.AnnotationMaxGuestMemory: "lots"}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should set max sockets and max guest memory", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationMaxSockets:     "8",
+				utils.AnnotationMaxGuestMemory: "16Gi",
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationHotplugApplied, "true"))
+
+			domain := vm.Spec.Template.Spec.Domain
+			Expect(domain.CPU.MaxSockets).To(Equal(uint32(8)))
+			Expect(domain.Memory.MaxGuest.Equal(resource.MustParse("16Gi"))).To(BeTrue())
+		})
+
+		It("should only set the requested maximum", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMaxSockets: "4"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.Memory.MaxGuest).To(BeNil())
+		})
+
+		It("should return error when max is below current", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMaxSockets: "1"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,171 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// hypervSpinlockRetries is the spinlock retry count used when enabling spinlocks
+const hypervSpinlockRetries = uint32(8191)
+
+// hypervEnlightenments maps enlightenment names to setters on the Hyper-V features.
+// Setters leave enlightenments already configured on the VM untouched.
+var hypervEnlightenments = map[string]func(h *kubevirtv1.FeatureHyperv){
+	"relaxed": func(h *kubevirtv1.FeatureHyperv) { h.Relaxed = enableFeatureState(h.Relaxed) },
+	"vapic":   func(h *kubevirtv1.FeatureHyperv) { h.VAPIC = enableFeatureState(h.VAPIC) },
+	"spinlocks": func(h *kubevirtv1.FeatureHyperv) {
+		if h.Spinlocks == nil {
+			enabled := true
+			retries := hypervSpinlockRetries
+			h.Spinlocks = &kubevirtv1.FeatureSpinlocks{Enabled: &enabled, Retries: &retries}
+		}
+	},
+	"vpindex": func(h *kubevirtv1.FeatureHyperv) { h.VPIndex = enableFeatureState(h.VPIndex) },
+	"runtime": func(h *kubevirtv1.FeatureHyperv) { h.Runtime = enableFeatureState(h.Runtime) },
+	"synic":   func(h *kubevirtv1.FeatureHyperv) { h.SyNIC = enableFeatureState(h.SyNIC) },
+	"stimer": func(h *kubevirtv1.FeatureHyperv) {
+		if h.SyNICTimer == nil {
+			enabled := true
+			h.SyNICTimer = &kubevirtv1.SyNICTimer{Enabled: &enabled}
+		}
+	},
+	"reset":           func(h *kubevirtv1.FeatureHyperv) { h.Reset = enableFeatureState(h.Reset) },
+	"frequencies":     func(h *kubevirtv1.FeatureHyperv) { h.Frequencies = enableFeatureState(h.Frequencies) },
+	"reenlightenment": func(h *kubevirtv1.FeatureHyperv) { h.Reenlightenment = enableFeatureState(h.Reenlightenment) },
+	"ipi":             func(h *kubevirtv1.FeatureHyperv) { h.IPI = enableFeatureState(h.IPI) },
+}
+
+// HyperV implements the Hyper-V enlightenments feature for Windows guests.
+// The set of enlightenments enabled is taken from the webhook configuration.
+type HyperV struct {
+	config       *config.HyperVConfig
+	configSource utils.ConfigSource
+}
+
+// NewHyperV creates a new HyperV feature
+func NewHyperV(cfg *config.HyperVConfig, configSource utils.ConfigSource) *HyperV {
+	return &HyperV{
+		config:       cfg,
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *HyperV) Name() string {
+	return utils.FeatureHyperV
+}
+
+// Config returns the feature settings
+func (f *HyperV) Config() interface{} {
+	return f.config
+}
+
+// IsEnabled checks if Hyper-V enlightenments are requested via annotations or labels
+func (f *HyperV) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	if !f.config.Enabled {
+		return false
+	}
+
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationHyperV)
+	return exists && utils.IsTruthyValue(value)
+}
+
+// Validate ensures the annotation value and configured enlightenments are valid
+func (f *HyperV) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationHyperV)
+	if !exists {
+		return nil
+	}
+
+	if value != "enabled" {
+		return fmt.Errorf("invalid value for %s: %s (expected 'enabled')",
+			utils.AnnotationHyperV, value)
+	}
+
+	for _, name := range f.config.Enlightenments {
+		if _, ok := hypervEnlightenments[strings.TrimSpace(name)]; !ok {
+			return fmt.Errorf("unsupported Hyper-V enlightenment %q in configuration", name)
+		}
+	}
+
+	return nil
+}
+
+// Apply enables the configured Hyper-V enlightenments and the Hyper-V clock timer
+func (f *HyperV) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	logger.Info("Applying Hyper-V enlightenments", "vm", vm.Name, "enlightenments", f.config.Enlightenments)
+
+	enabled := applyHyperVEnlightenments(&vm.Spec.Template.Spec.Domain, f.config.Enlightenments)
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationHyperVApplied, "true")
+	result.AddMessage(fmt.Sprintf("Enabled Hyper-V enlightenments: %s", strings.Join(enabled, ",")))
+
+	return result, nil
+}
+
+// applyHyperVEnlightenments enables the named enlightenments and the Hyper-V
+// clock timer on the domain, returning the names that were applied.
+// Names must have been validated against hypervEnlightenments.
+func applyHyperVEnlightenments(domain *kubevirtv1.DomainSpec, names []string) []string {
+	if domain.Features == nil {
+		domain.Features = &kubevirtv1.Features{}
+	}
+	if domain.Features.Hyperv == nil {
+		domain.Features.Hyperv = &kubevirtv1.FeatureHyperv{}
+	}
+
+	enabled := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		hypervEnlightenments[name](domain.Features.Hyperv)
+		enabled = append(enabled, name)
+	}
+
+	// Add the Hyper-V reference clock
+	if domain.Clock == nil {
+		domain.Clock = &kubevirtv1.Clock{}
+	}
+	if domain.Clock.Timer == nil {
+		domain.Clock.Timer = &kubevirtv1.Timer{}
+	}
+	if domain.Clock.Timer.Hyperv == nil {
+		timerEnabled := true
+		domain.Clock.Timer.Hyperv = &kubevirtv1.HypervTimer{Enabled: &timerEnabled}
+	}
+
+	return enabled
+}
+
+// enableFeatureState returns an enabled feature state unless one is already set
+func enableFeatureState(state *kubevirtv1.FeatureState) *kubevirtv1.FeatureState {
+	if state != nil {
+		return state
+	}
+	enabled := true
+	return &kubevirtv1.FeatureState{Enabled: &enabled}
+}
@@ -0,0 +1,151 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("HyperV", func() {
+	var (
+		cfg     *config.HyperVConfig
+		feature *features.HyperV
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		cfg = &config.HyperVConfig{
+			Enabled:        true,
+			Enlightenments: []string{"relaxed", "vapic", "spinlocks", "vpindex", "synic", "stimer"},
+		}
+		feature = features.NewHyperV(cfg, utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureHyperV))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is enabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationHyperV: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return false when the feature is disabled in config", func() {
+			cfg.Enabled = false
+			vm.Annotations = map[string]string{utils.AnnotationHyperV: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+	})
+
+	Describe("Validate", func() {
+		BeforeEach(func() {
+			vm.Annotations = map[string]string{utils.AnnotationHyperV: "enabled"}
+		})
+
+		It("should succeed with the configured enlightenments", func() {
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject invalid values", func() {
+			vm.Annotations[utils.AnnotationHyperV] = "maybe"
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid value"))
+		})
+
+		It("should reject unknown enlightenments in config", func() {
+			cfg.Enlightenments = []string{"relaxed", "warp-drive"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("warp-drive"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should not modify the VM when not enabled", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.Features).To(BeNil())
+		})
+
+		It("should enable the configured enlightenments and Hyper-V clock", func() {
+			vm.Annotations = map[string]string{utils.AnnotationHyperV: "enabled"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationHyperVApplied, "true"))
+
+			hyperv := vm.Spec.Template.Spec.Domain.Features.Hyperv
+			Expect(hyperv.Relaxed).ToNot(BeNil())
+			Expect(*hyperv.Relaxed.Enabled).To(BeTrue())
+			Expect(hyperv.VAPIC).ToNot(BeNil())
+			Expect(hyperv.Spinlocks).ToNot(BeNil())
+			Expect(*hyperv.Spinlocks.Retries).To(Equal(uint32(8191)))
+			Expect(hyperv.VPIndex).ToNot(BeNil())
+			Expect(hyperv.SyNIC).ToNot(BeNil())
+			Expect(hyperv.SyNICTimer).ToNot(BeNil())
+			Expect(hyperv.Reset).To(BeNil())
+			Expect(hyperv.IPI).To(BeNil())
+
+			timer := vm.Spec.Template.Spec.Domain.Clock.Timer
+			Expect(timer.Hyperv).ToNot(BeNil())
+			Expect(*timer.Hyperv.Enabled).To(BeTrue())
+		})
+
+		It("should only enable enlightenments from the override list", func() {
+			cfg.Enlightenments = []string{"relaxed"}
+			vm.Annotations = map[string]string{utils.AnnotationHyperV: "enabled"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			hyperv := vm.Spec.Template.Spec.Domain.Features.Hyperv
+			Expect(hyperv.Relaxed).ToNot(BeNil())
+			Expect(hyperv.VAPIC).To(BeNil())
+			Expect(hyperv.SyNIC).To(BeNil())
+		})
+
+		It("should keep enlightenments already configured on the VM", func() {
+			disabled := false
+			vm.Spec.Template.Spec.Domain.Features = &kubevirtv1.Features{
+				Hyperv: &kubevirtv1.FeatureHyperv{
+					Relaxed: &kubevirtv1.FeatureState{Enabled: &disabled},
+				},
+			}
+			vm.Annotations = map[string]string{utils.AnnotationHyperV: "enabled"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*vm.Spec.Template.Spec.Domain.Features.Hyperv.Relaxed.Enabled).To(BeFalse())
+		})
+	})
+})
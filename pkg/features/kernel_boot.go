@@ -0,0 +1,138 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// KernelBootSpec defines the structure of the kernel boot annotation
+type KernelBootSpec struct {
+	Image           string            `json:"image"`
+	ImagePullSecret string            `json:"imagePullSecret,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	KernelPath      string            `json:"kernelPath,omitempty"`
+	InitrdPath      string            `json:"initrdPath,omitempty"`
+	KernelArgs      string            `json:"kernelArgs,omitempty"`
+}
+
+// KernelBoot implements booting a kernel and initrd from a container image
+type KernelBoot struct {
+	configSource utils.ConfigSource
+}
+
+// NewKernelBoot creates a new KernelBoot feature
+func NewKernelBoot(configSource utils.ConfigSource) *KernelBoot {
+	return &KernelBoot{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *KernelBoot) Name() string {
+	return utils.FeatureKernelBoot
+}
+
+// IsEnabled checks if kernel boot is requested via annotations or labels
+func (f *KernelBoot) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationKernelBoot)
+	return exists && value != ""
+}
+
+// Validate performs validation of the kernel boot configuration
+func (f *KernelBoot) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationKernelBoot)
+	if !exists {
+		return nil
+	}
+
+	_, err := parseKernelBoot(value)
+	return err
+}
+
+// Apply sets the kernel boot firmware configuration on the VM template
+func (f *KernelBoot) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationKernelBoot)
+	spec, _ := parseKernelBoot(value)
+
+	logger.Info("Applying kernel boot", "vm", vm.Name, "image", spec.Image)
+
+	domain := &vm.Spec.Template.Spec.Domain
+	if domain.Firmware == nil {
+		domain.Firmware = &kubevirtv1.Firmware{}
+	}
+	domain.Firmware.KernelBoot = &kubevirtv1.KernelBoot{
+		KernelArgs: spec.KernelArgs,
+		Container: &kubevirtv1.KernelBootContainer{
+			Image:           spec.Image,
+			ImagePullSecret: spec.ImagePullSecret,
+			ImagePullPolicy: spec.ImagePullPolicy,
+			KernelPath:      spec.KernelPath,
+			InitrdPath:      spec.InitrdPath,
+		},
+	}
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationKernelBootApplied, spec.Image)
+	result.AddMessage(fmt.Sprintf("Configured kernel boot from %s", spec.Image))
+
+	return result, nil
+}
+
+// parseKernelBoot parses and validates the kernel boot annotation
+func parseKernelBoot(value string) (*KernelBootSpec, error) {
+	var spec KernelBootSpec
+	if err := json.Unmarshal([]byte(value), &spec); err != nil {
+		return nil, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationKernelBoot, err)
+	}
+
+	if spec.Image == "" {
+		return nil, fmt.Errorf("kernel boot image is required in %s", utils.AnnotationKernelBoot)
+	}
+
+	if spec.KernelPath == "" && spec.InitrdPath == "" {
+		return nil, fmt.Errorf("kernel boot requires kernelPath and/or initrdPath")
+	}
+
+	for name, path := range map[string]string{"kernelPath": spec.KernelPath, "initrdPath": spec.InitrdPath} {
+		if path != "" && !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("kernel boot %s must be an absolute path, got %q", name, path)
+		}
+	}
+
+	if spec.KernelArgs != "" && spec.KernelPath == "" {
+		return nil, fmt.Errorf("kernel boot kernelArgs require a kernelPath")
+	}
+
+	switch spec.ImagePullPolicy {
+	case "", corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+	default:
+		return nil, fmt.Errorf("invalid kernel boot imagePullPolicy %q", spec.ImagePullPolicy)
+	}
+
+	return &spec, nil
+}
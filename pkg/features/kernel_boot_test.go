@@ -0,0 +1,141 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("KernelBoot", func() {
+	var (
+		feature *features.KernelBoot
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewKernelBoot(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureKernelBoot))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationKernelBoot: `{"image": "quay.io/example/kernel:latest", "kernelPath": "/boot/vmlinuz"}`,
+			}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept a valid configuration", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationKernelBoot: `{"image": "quay.io/example/kernel:latest", "kernelPath": "/boot/vmlinuz",
+					"initrdPath": "/boot/initrd", "kernelArgs": "console=ttyS0"}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject malformed JSON", func() {
+			vm.Annotations = map[string]string{utils.AnnotationKernelBoot: `{"image": `}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid JSON"))
+		})
+
+		It("should require an image", func() {
+			vm.Annotations = map[string]string{utils.AnnotationKernelBoot: `{"kernelPath": "/boot/vmlinuz"}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("image is required"))
+		})
+
+		It("should require a kernel or initrd path", func() {
+			vm.Annotations = map[string]string{utils.AnnotationKernelBoot: `{"image": "quay.io/example/kernel"}`}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should require absolute paths", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationKernelBoot: `{"image": "quay.io/example/kernel", "kernelPath": "boot/vmlinuz"}`,
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("absolute path"))
+		})
+
+		It("should reject kernel args without a kernel path", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationKernelBoot: `{"image": "quay.io/example/kernel", "initrdPath": "/boot/initrd", "kernelArgs": "quiet"}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject invalid pull policies", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationKernelBoot: `{"image": "quay.io/example/kernel", "kernelPath": "/boot/vmlinuz", "imagePullPolicy": "Sometimes"}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should set the kernel boot configuration", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationKernelBoot: `{"image": "quay.io/example/kernel:latest", "kernelPath": "/boot/vmlinuz",
+					"initrdPath": "/boot/initrd", "kernelArgs": "console=ttyS0"}`,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationKernelBootApplied, "quay.io/example/kernel:latest"))
+
+			kernelBoot := vm.Spec.Template.Spec.Domain.Firmware.KernelBoot
+			Expect(kernelBoot).ToNot(BeNil())
+			Expect(kernelBoot.KernelArgs).To(Equal("console=ttyS0"))
+			Expect(kernelBoot.Container.Image).To(Equal("quay.io/example/kernel:latest"))
+			Expect(kernelBoot.Container.KernelPath).To(Equal("/boot/vmlinuz"))
+			Expect(kernelBoot.Container.InitrdPath).To(Equal("/boot/initrd"))
+		})
+
+		It("should preserve other firmware settings", func() {
+			vm.Spec.Template.Spec.Domain.Firmware = &kubevirtv1.Firmware{Serial: "ABC123"}
+			vm.Annotations = map[string]string{
+				utils.AnnotationKernelBoot: `{"image": "quay.io/example/kernel", "kernelPath": "/boot/vmlinuz"}`,
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.Firmware.Serial).To(Equal("ABC123"))
+		})
+	})
+})
@@ -0,0 +1,163 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// MacAddresses implements static MAC address assignment.
+// The annotation maps interface names to MAC addresses,
+// e.g. {"default": "02:00:00:00:00:01"}.
+type MacAddresses struct {
+	configSource utils.ConfigSource
+}
+
+// NewMacAddresses creates a new MacAddresses feature
+func NewMacAddresses(configSource utils.ConfigSource) *MacAddresses {
+	return &MacAddresses{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *MacAddresses) Name() string {
+	return utils.FeatureMacAddresses
+}
+
+// IsEnabled checks if static MAC addresses are requested via annotations or labels
+func (f *MacAddresses) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationMacAddresses)
+	return exists && value != ""
+}
+
+// Validate ensures the MAC addresses are valid, unique and refer to existing interfaces
+func (f *MacAddresses) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationMacAddresses)
+	if !exists {
+		return nil
+	}
+
+	macs, err := parseMacAddresses(value)
+	if err != nil {
+		return err
+	}
+
+	if vm.Spec.Template == nil {
+		return fmt.Errorf("VM template is nil")
+	}
+
+	// Resulting MAC per interface, including addresses already set on other interfaces
+	owners := make(map[string]string)
+	known := make(map[string]bool)
+	for _, iface := range vm.Spec.Template.Spec.Domain.Devices.Interfaces {
+		known[iface.Name] = true
+		mac, requested := macs[iface.Name]
+		if !requested {
+			if iface.MacAddress == "" {
+				continue
+			}
+			hw, err := net.ParseMAC(iface.MacAddress)
+			if err != nil {
+				continue
+			}
+			mac = hw.String()
+		}
+		if other, ok := owners[mac]; ok {
+			return fmt.Errorf("duplicate MAC address %s on interfaces %q and %q", mac, other, iface.Name)
+		}
+		owners[mac] = iface.Name
+	}
+
+	for _, name := range sortedMacAddressNames(macs) {
+		if !known[name] {
+			return fmt.Errorf("unknown interface %q in %s", name, utils.AnnotationMacAddresses)
+		}
+	}
+
+	return nil
+}
+
+// Apply sets the MAC address on the matching interfaces
+func (f *MacAddresses) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationMacAddresses)
+	macs, _ := parseMacAddresses(value)
+
+	logger.Info("Applying static MAC addresses", "vm", vm.Name, "macAddresses", macs)
+
+	interfaces := vm.Spec.Template.Spec.Domain.Devices.Interfaces
+	for i := range interfaces {
+		if mac, ok := macs[interfaces[i].Name]; ok {
+			interfaces[i].MacAddress = mac
+		}
+	}
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationMacAddressesApplied, fmt.Sprintf("%d", len(macs)))
+	result.AddMessage(fmt.Sprintf("Set MAC addresses for %d interfaces", len(macs)))
+
+	return result, nil
+}
+
+// parseMacAddresses parses the annotation into normalized unicast MAC addresses
+func parseMacAddresses(value string) (map[string]string, error) {
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationMacAddresses, err)
+	}
+
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no interfaces specified in %s", utils.AnnotationMacAddresses)
+	}
+
+	macs := make(map[string]string, len(raw))
+	seen := make(map[string]string, len(raw))
+	for _, name := range sortedMacAddressNames(raw) {
+		hw, err := net.ParseMAC(raw[name])
+		if err != nil || len(hw) != 6 {
+			return nil, fmt.Errorf("invalid MAC address for %q: %s", name, raw[name])
+		}
+		if hw[0]&0x01 != 0 {
+			return nil, fmt.Errorf("invalid MAC address for %q: %s is a multicast address", name, raw[name])
+		}
+
+		mac := hw.String()
+		if other, ok := seen[mac]; ok {
+			return nil, fmt.Errorf("duplicate MAC address %s for %q and %q", mac, other, name)
+		}
+		seen[mac] = name
+		macs[name] = mac
+	}
+
+	return macs, nil
+}
+
+// sortedMacAddressNames returns the interface names in a stable order for deterministic errors
+func sortedMacAddressNames(macs map[string]string) []string {
+	names := make([]string, 0, len(macs))
+	for name := range macs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,138 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("MacAddresses", func() {
+	var (
+		feature *features.MacAddresses
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewMacAddresses(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							Devices: kubevirtv1.Devices{
+								Interfaces: []kubevirtv1.Interface{
+									{Name: "default"},
+									{Name: "secondary"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureMacAddresses))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMacAddresses: `{"default": "02:00:00:00:00:01"}`}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept valid MAC addresses", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationMacAddresses: `{"default": "02:00:00:00:00:01", "secondary": "02-00-00-00-00-02"}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject malformed JSON", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMacAddresses: `{"default":`}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject an invalid MAC address", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMacAddresses: `{"default": "02:00:00:00:00"}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid MAC address"))
+		})
+
+		It("should reject a multicast MAC address", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMacAddresses: `{"default": "01:00:5e:00:00:01"}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("multicast"))
+		})
+
+		It("should reject duplicate MAC addresses in the annotation", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationMacAddresses: `{"default": "02:00:00:00:00:01", "secondary": "02:00:00:00:00:01"}`,
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("duplicate MAC address"))
+		})
+
+		It("should reject a MAC address already used by another interface", func() {
+			vm.Spec.Template.Spec.Domain.Devices.Interfaces[1].MacAddress = "02:00:00:00:00:01"
+			vm.Annotations = map[string]string{utils.AnnotationMacAddresses: `{"default": "02:00:00:00:00:01"}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("duplicate MAC address"))
+		})
+
+		It("should reject unknown interfaces", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMacAddresses: `{"missing": "02:00:00:00:00:01"}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unknown interface"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should set normalized MAC addresses on matching interfaces", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMacAddresses: `{"secondary": "02-AB-CD-00-00-02"}`}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationMacAddressesApplied, "1"))
+
+			interfaces := vm.Spec.Template.Spec.Domain.Devices.Interfaces
+			Expect(interfaces[0].MacAddress).To(BeEmpty())
+			Expect(interfaces[1].MacAddress).To(Equal("02:ab:cd:00:00:02"))
+		})
+
+		It("should override an existing MAC address on the same interface", func() {
+			vm.Spec.Template.Spec.Domain.Devices.Interfaces[0].MacAddress = "02:00:00:00:00:09"
+			vm.Annotations = map[string]string{utils.AnnotationMacAddresses: `{"default": "02:00:00:00:00:01"}`}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.Interfaces[0].MacAddress).To(Equal("02:00:00:00:00:01"))
+		})
+	})
+})
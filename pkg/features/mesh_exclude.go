@@ -0,0 +1,133 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// meshExclusions maps service meshes to the pod annotation disabling sidecar injection
+var meshExclusions = map[string]struct{ key, value string }{
+	"istio":   {key: "sidecar.istio.io/inject", value: "false"},
+	"linkerd": {key: "linkerd.io/inject", value: "disabled"},
+	"kuma":    {key: "kuma.io/sidecar-injection", value: "disabled"},
+}
+
+// MeshExclude implements service mesh sidecar exclusion.
+// Mesh sidecars intercept pod traffic and break KubeVirt networking, so the
+// opt-out annotations are added to the template metadata of the virt-launcher pod.
+type MeshExclude struct {
+	configSource utils.ConfigSource
+}
+
+// NewMeshExclude creates a new MeshExclude feature
+func NewMeshExclude(configSource utils.ConfigSource) *MeshExclude {
+	return &MeshExclude{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *MeshExclude) Name() string {
+	return utils.FeatureMeshExclude
+}
+
+// IsEnabled checks if mesh exclusion is requested via annotations or labels
+func (f *MeshExclude) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationMeshExclude)
+	return exists && value != ""
+}
+
+// Validate ensures the requested meshes are supported
+func (f *MeshExclude) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationMeshExclude)
+	if !exists {
+		return nil
+	}
+
+	_, err := parseMeshExclude(value)
+	return err
+}
+
+// Apply adds the sidecar opt-out annotations to the template metadata
+func (f *MeshExclude) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationMeshExclude)
+	meshes, _ := parseMeshExclude(value)
+
+	logger.Info("Applying service mesh exclusion", "vm", vm.Name, "meshes", meshes)
+
+	if vm.Spec.Template.ObjectMeta.Annotations == nil {
+		vm.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+	}
+
+	annotations := vm.Spec.Template.ObjectMeta.Annotations
+	for _, mesh := range meshes {
+		exclusion := meshExclusions[mesh]
+		// Respect an injection setting chosen explicitly on the template
+		if _, exists := annotations[exclusion.key]; !exists {
+			annotations[exclusion.key] = exclusion.value
+		}
+	}
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationMeshExcludeApplied, strings.Join(meshes, ","))
+	result.AddMessage(fmt.Sprintf("Excluded VM from service meshes: %s", strings.Join(meshes, ",")))
+
+	return result, nil
+}
+
+// parseMeshExclude returns the sorted meshes to exclude; a truthy value selects all meshes
+func parseMeshExclude(value string) ([]string, error) {
+	var meshes []string
+	if utils.IsTruthyValue(value) {
+		for mesh := range meshExclusions {
+			meshes = append(meshes, mesh)
+		}
+		sort.Strings(meshes)
+		return meshes, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, mesh := range strings.Split(value, ",") {
+		mesh = strings.ToLower(strings.TrimSpace(mesh))
+		if mesh == "" || seen[mesh] {
+			continue
+		}
+		if _, ok := meshExclusions[mesh]; !ok {
+			return nil, fmt.Errorf("unsupported service mesh %q in %s (supported: istio, kuma, linkerd)",
+				mesh, utils.AnnotationMeshExclude)
+		}
+		seen[mesh] = true
+		meshes = append(meshes, mesh)
+	}
+
+	if len(meshes) == 0 {
+		return nil, fmt.Errorf("no service meshes specified in %s", utils.AnnotationMeshExclude)
+	}
+
+	sort.Strings(meshes)
+	return meshes, nil
+}
@@ -0,0 +1,104 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("MeshExclude", func() {
+	var (
+		feature *features.MeshExclude
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewMeshExclude(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureMeshExclude))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMeshExclude: "istio"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept supported meshes", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMeshExclude: "istio, Linkerd"}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject unsupported meshes", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMeshExclude: "istio,consul"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported service mesh"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should exclude all meshes when enabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMeshExclude: "enabled"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationMeshExcludeApplied, "istio,kuma,linkerd"))
+
+			annotations := vm.Spec.Template.ObjectMeta.Annotations
+			Expect(annotations).To(HaveKeyWithValue("sidecar.istio.io/inject", "false"))
+			Expect(annotations).To(HaveKeyWithValue("linkerd.io/inject", "disabled"))
+			Expect(annotations).To(HaveKeyWithValue("kuma.io/sidecar-injection", "disabled"))
+		})
+
+		It("should only exclude the listed meshes", func() {
+			vm.Annotations = map[string]string{utils.AnnotationMeshExclude: "istio"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			annotations := vm.Spec.Template.ObjectMeta.Annotations
+			Expect(annotations).To(HaveKeyWithValue("sidecar.istio.io/inject", "false"))
+			Expect(annotations).ToNot(HaveKey("linkerd.io/inject"))
+		})
+
+		It("should not override an explicit template setting", func() {
+			vm.Spec.Template.ObjectMeta.Annotations = map[string]string{"sidecar.istio.io/inject": "true"}
+			vm.Annotations = map[string]string{utils.AnnotationMeshExclude: "istio"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.ObjectMeta.Annotations).To(HaveKeyWithValue("sidecar.istio.io/inject", "true"))
+		})
+	})
+})
@@ -3,7 +3,7 @@ package features
 import (
 	"context"
 	"fmt"
-	"runtime"
+	"strings"
 
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -17,6 +17,7 @@ import (
 type NestedVirtualization struct {
 	config       *config.NestedVirtConfig
 	configSource utils.ConfigSource
+	detector     *cpuFeatureDetector
 }
 
 // NewNestedVirtualization creates a new NestedVirtualization feature
@@ -24,6 +25,7 @@ func NewNestedVirtualization(cfg *config.NestedVirtConfig, configSource utils.Co
 	return &NestedVirtualization{
 		config:       cfg,
 		configSource: configSource,
+		detector:     &cpuFeatureDetector{},
 	}
 }
 
@@ -32,6 +34,11 @@ func (f *NestedVirtualization) Name() string {
 	return utils.FeatureNestedVirt
 }
 
+// Config returns the feature settings
+func (f *NestedVirtualization) Config() interface{} {
+	return f.config
+}
+
 // IsEnabled checks if nested virtualization is requested via annotations or labels
 func (f *NestedVirtualization) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
 	if !f.config.Enabled {
@@ -39,11 +46,11 @@ func (f *NestedVirtualization) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
 	}
 
 	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationNestedVirt)
-	return exists && utils.IsTruthyValue(value)
+	return exists && (utils.IsTruthyValue(value) || isNestedVirtModeValue(value))
 }
 
 // Apply enables nested virtualization by adding CPU features
-func (f *NestedVirtualization) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) (*MutationResult, error) {
+func (f *NestedVirtualization) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
 	logger := log.FromContext(ctx)
 	result := NewMutationResult()
 
@@ -53,8 +60,21 @@ func (f *NestedVirtualization) Apply(ctx context.Context, vm *kubevirtv1.Virtual
 
 	logger.Info("Applying nested virtualization feature", "vm", vm.Name)
 
-	// Determine CPU feature to add (AMD SVM or Intel VMX)
-	cpuFeature := f.detectCPUFeature()
+	// Determine CPU feature to add (AMD SVM or Intel VMX), unless pinned by the VM.
+	// In optional mode both are added so the VM schedules on either vendor.
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationNestedVirt)
+	cpuFeature := pinnedCPUFeature(value)
+	optional := strings.EqualFold(value, utils.NestedVirtOptional) || (f.config.OptionalCPUFeatures && cpuFeature == "")
+
+	cpuFeatures := []string{cpuFeature}
+	policy := "require"
+	switch {
+	case optional:
+		cpuFeatures = []string{utils.CPUFeatureVMX, utils.CPUFeatureSVM}
+		policy = "optional"
+	case cpuFeature == "":
+		cpuFeatures = []string{f.detectCPUFeature(ctx, k8sClient)}
+	}
 
 	// Initialize domain if needed
 	if vm.Spec.Template == nil {
@@ -64,40 +84,73 @@ func (f *NestedVirtualization) Apply(ctx context.Context, vm *kubevirtv1.Virtual
 		vm.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{}
 	}
 
-	// Add CPU feature
-	feature := kubevirtv1.CPUFeature{
-		Name:   cpuFeature,
-		Policy: "require",
-	}
+	for _, name := range cpuFeatures {
+		// Check if feature already exists
+		featureExists := false
+		for _, existing := range vm.Spec.Template.Spec.Domain.CPU.Features {
+			if existing.Name == name {
+				featureExists = true
+				break
+			}
+		}
 
-	// Check if feature already exists
-	featureExists := false
-	for _, existing := range vm.Spec.Template.Spec.Domain.CPU.Features {
-		if existing.Name == cpuFeature {
-			featureExists = true
-			break
+		if !featureExists {
+			vm.Spec.Template.Spec.Domain.CPU.Features = append(
+				vm.Spec.Template.Spec.Domain.CPU.Features,
+				kubevirtv1.CPUFeature{Name: name, Policy: policy},
+			)
 		}
 	}
 
-	if !featureExists {
-		vm.Spec.Template.Spec.Domain.CPU.Features = append(
-			vm.Spec.Template.Spec.Domain.CPU.Features,
-			feature,
-		)
+	// Optional features don't constrain the vendor, so there is no label to require
+	if f.config.NodeAffinity && !optional {
+		labels := map[string]string{utils.NodeLabelCPUFeaturePrefix + cpuFeatures[0]: "true"}
+		if added := requireNodeLabels(vm, f.Name(), labels, result); len(added) > 0 {
+			result.AddMessage(fmt.Sprintf("Required node labels: %s", strings.Join(added, ", ")))
+		}
 	}
 
 	// Mark as applied
 	result.Applied = true
 	result.AddAnnotation(utils.AnnotationNestedVirtApplied, "true")
-	result.AddMessage(fmt.Sprintf("Enabled nested virtualization with %s CPU feature", cpuFeature))
+	result.AddMessage(fmt.Sprintf("Enabled nested virtualization with %s CPU feature (policy %s)",
+		strings.Join(cpuFeatures, " and "), policy))
 
 	logger.Info("Nested virtualization applied successfully",
 		"vm", vm.Name,
-		"cpuFeature", cpuFeature)
+		"cpuFeatures", cpuFeatures,
+		"policy", policy)
 
 	return result, nil
 }
 
+// Revert removes the nested virtualization CPU features and node labels
+func (f *NestedVirtualization) Revert(_ context.Context, vm, previous *kubevirtv1.VirtualMachine) error {
+	if vm.Spec.Template == nil {
+		return nil
+	}
+	spec := &vm.Spec.Template.Spec
+
+	if spec.Domain.CPU != nil {
+		kept := spec.Domain.CPU.Features[:0]
+		for _, feature := range spec.Domain.CPU.Features {
+			if feature.Name != utils.CPUFeatureVMX && feature.Name != utils.CPUFeatureSVM {
+				kept = append(kept, feature)
+			}
+		}
+		spec.Domain.CPU.Features = kept
+		if len(kept) == 0 {
+			spec.Domain.CPU.Features = nil
+		}
+	}
+
+	releaseNodeLabels(vm, previous, f.Name(), map[string]string{
+		utils.NodeLabelCPUFeaturePrefix + utils.CPUFeatureVMX: "true",
+		utils.NodeLabelCPUFeaturePrefix + utils.CPUFeatureSVM: "true",
+	})
+	return nil
+}
+
 // Validate performs basic validation
 func (f *NestedVirtualization) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
 	// Check if config value is present
@@ -107,32 +160,57 @@ func (f *NestedVirtualization) Validate(_ context.Context, vm *kubevirtv1.Virtua
 	}
 
 	// If config value exists, validate it
-	if value != "enabled" {
-		return fmt.Errorf("invalid value for %s: %s (expected 'enabled')",
+	if value != "enabled" && !isNestedVirtModeValue(value) {
+		return fmt.Errorf("invalid value for %s: %s (expected 'enabled', 'auto', 'optional', 'vmx' or 'svm')",
 			utils.AnnotationNestedVirt, value)
 	}
 
 	return nil
 }
 
-// detectCPUFeature determines which CPU feature to use based on platform
-func (f *NestedVirtualization) detectCPUFeature() string {
-	if !f.config.AutoDetectCPU {
-		// Default to AMD if auto-detect is disabled
+// isNestedVirtModeValue reports whether the value selects how the CPU feature is chosen
+func isNestedVirtModeValue(value string) bool {
+	return pinnedCPUFeature(value) != "" ||
+		strings.EqualFold(value, utils.NestedVirtAuto) ||
+		strings.EqualFold(value, utils.NestedVirtOptional)
+}
+
+// pinnedCPUFeature returns the CPU feature explicitly requested by the value, if any
+func pinnedCPUFeature(value string) string {
+	switch strings.ToLower(value) {
+	case utils.CPUFeatureVMX:
+		return utils.CPUFeatureVMX
+	case utils.CPUFeatureSVM:
 		return utils.CPUFeatureSVM
+	default:
+		return ""
 	}
+}
 
-	// In a real implementation, you might read /proc/cpuinfo or query the node
-	// For now, we'll use a simple heuristic based on GOARCH
-	// This is a placeholder - actual detection would need to query the cluster nodes
-	arch := runtime.GOARCH
+// detectCPUFeature determines whether nodes use Intel VMX or AMD SVM from their
+// labels, falling back to the configured default when detection is disabled
+// or inconclusive
+func (f *NestedVirtualization) detectCPUFeature(ctx context.Context, k8sClient client.Client) string {
+	if !f.config.AutoDetectCPU || k8sClient == nil {
+		return f.defaultCPUFeature()
+	}
 
-	if arch == "amd64" || arch == "x86_64" {
-		// Default to AMD SVM for x86_64
-		// TODO: In production, this should query actual node CPU capabilities
-		return utils.CPUFeatureSVM
+	feature, err := f.detector.detect(ctx, k8sClient)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "CPU feature detection failed, using default", "default", f.defaultCPUFeature())
+		return f.defaultCPUFeature()
+	}
+	if feature == "" {
+		return f.defaultCPUFeature()
 	}
 
-	// Fallback to AMD
+	return feature
+}
+
+// defaultCPUFeature returns the configured fallback CPU feature, defaulting to AMD SVM
+func (f *NestedVirtualization) defaultCPUFeature() string {
+	if f.config.DefaultCPUFeature == utils.CPUFeatureVMX {
+		return utils.CPUFeatureVMX
+	}
 	return utils.CPUFeatureSVM
 }
@@ -5,8 +5,12 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
@@ -139,6 +143,16 @@ var _ = Describe("NestedVirtualization", func() {
 			})
 		})
 
+		Context("when annotation pins or auto-detects the CPU feature", func() {
+			It("should accept vmx, svm and auto", func() {
+				for _, value := range []string{"vmx", "svm", "auto", "optional", "VMX"} {
+					vm.Annotations = map[string]string{utils.AnnotationNestedVirt: value}
+					Expect(feature.Validate(ctx, vm, nil)).To(Succeed(), value)
+					Expect(feature.IsEnabled(vm)).To(BeTrue(), value)
+				}
+			})
+		})
+
 		Context("when annotation value is invalid", func() {
 			BeforeEach(func() {
 				vm.Annotations = map[string]string{
@@ -311,5 +325,208 @@ var _ = Describe("NestedVirtualization", func() {
 				Expect(vm.Spec.Template.Spec.Domain.CPU.Features[0].Name).To(Equal(utils.CPUFeatureSVM))
 			})
 		})
+
+		Context("with node affinity enabled", func() {
+			BeforeEach(func() {
+				cfg := &config.NestedVirtConfig{
+					Enabled:      true,
+					NodeAffinity: true,
+				}
+				feature = features.NewNestedVirtualization(cfg, utils.ConfigSourceAnnotations)
+				vm.Annotations = map[string]string{
+					utils.AnnotationNestedVirt: "enabled",
+				}
+			})
+
+			It("should require the CPU feature node label", func() {
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue("cpu-feature.node.kubevirt.io/svm", "true"))
+			})
+
+			It("should not override an existing selector entry", func() {
+				vm.Spec.Template.Spec.NodeSelector = map[string]string{"cpu-feature.node.kubevirt.io/svm": "false"}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue("cpu-feature.node.kubevirt.io/svm", "false"))
+			})
+		})
+
+		Context("with an explicit CPU feature", func() {
+			It("should use vmx when pinned", func() {
+				vm.Annotations = map[string]string{utils.AnnotationNestedVirt: "vmx"}
+				result, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Applied).To(BeTrue())
+				Expect(vm.Spec.Template.Spec.Domain.CPU.Features).To(HaveLen(1))
+				Expect(vm.Spec.Template.Spec.Domain.CPU.Features[0].Name).To(Equal(utils.CPUFeatureVMX))
+			})
+
+			It("should detect the feature for auto", func() {
+				vm.Annotations = map[string]string{utils.AnnotationNestedVirt: "auto"}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.Spec.Template.Spec.Domain.CPU.Features[0].Name).To(Equal(utils.CPUFeatureSVM))
+			})
+		})
+
+		Context("in optional mode", func() {
+			expectOptionalFeatures := func() {
+				Expect(vm.Spec.Template.Spec.Domain.CPU.Features).To(ConsistOf(
+					kubevirtv1.CPUFeature{Name: utils.CPUFeatureVMX, Policy: "optional"},
+					kubevirtv1.CPUFeature{Name: utils.CPUFeatureSVM, Policy: "optional"},
+				))
+			}
+
+			It("should add vmx and svm as optional when requested by annotation", func() {
+				vm.Annotations = map[string]string{utils.AnnotationNestedVirt: "optional"}
+				result, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Applied).To(BeTrue())
+				expectOptionalFeatures()
+			})
+
+			It("should add both features for enabled VMs when configured", func() {
+				cfg := &config.NestedVirtConfig{
+					Enabled:             true,
+					OptionalCPUFeatures: true,
+					NodeAffinity:        true,
+				}
+				feature = features.NewNestedVirtualization(cfg, utils.ConfigSourceAnnotations)
+				vm.Annotations = map[string]string{utils.AnnotationNestedVirt: "enabled"}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				expectOptionalFeatures()
+				Expect(vm.Spec.Template.Spec.NodeSelector).To(BeEmpty())
+			})
+
+			It("should still honour a pinned feature when configured", func() {
+				cfg := &config.NestedVirtConfig{
+					Enabled:             true,
+					OptionalCPUFeatures: true,
+				}
+				feature = features.NewNestedVirtualization(cfg, utils.ConfigSourceAnnotations)
+				vm.Annotations = map[string]string{utils.AnnotationNestedVirt: "vmx"}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.Spec.Template.Spec.Domain.CPU.Features).To(ConsistOf(
+					kubevirtv1.CPUFeature{Name: utils.CPUFeatureVMX, Policy: "require"},
+				))
+			})
+		})
+
+		Context("with CPU feature detection from node labels", func() {
+			node := func(name string, labels map[string]string) *corev1.Node {
+				return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+			}
+
+			nodeClient := func(nodes ...client.Object) client.Client {
+				scheme := runtime.NewScheme()
+				_ = corev1.AddToScheme(scheme)
+				return fake.NewClientBuilder().WithScheme(scheme).WithObjects(nodes...).Build()
+			}
+
+			appliedFeature := func(k8sClient client.Client) string {
+				_, err := feature.Apply(ctx, vm, k8sClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.Spec.Template.Spec.Domain.CPU.Features).To(HaveLen(1))
+				return vm.Spec.Template.Spec.Domain.CPU.Features[0].Name
+			}
+
+			BeforeEach(func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationNestedVirt: "enabled",
+				}
+			})
+
+			It("should choose VMX on Intel nodes labelled by KubeVirt", func() {
+				k8sClient := nodeClient(
+					node("intel-1", map[string]string{"cpu-feature.node.kubevirt.io/vmx": "true"}),
+					node("intel-2", map[string]string{"cpu-feature.node.kubevirt.io/vmx": "true"}),
+					node("amd-1", map[string]string{"cpu-feature.node.kubevirt.io/svm": "true"}),
+				)
+				Expect(appliedFeature(k8sClient)).To(Equal(utils.CPUFeatureVMX))
+			})
+
+			It("should recognise Node Feature Discovery labels", func() {
+				k8sClient := nodeClient(
+					node("amd-1", map[string]string{"feature.node.kubernetes.io/cpu-cpuid.SVM": "true"}),
+				)
+				Expect(appliedFeature(k8sClient)).To(Equal(utils.CPUFeatureSVM))
+			})
+
+			It("should fall back to the configured default without labelled nodes", func() {
+				cfg := &config.NestedVirtConfig{
+					Enabled:           true,
+					AutoDetectCPU:     true,
+					DefaultCPUFeature: utils.CPUFeatureVMX,
+				}
+				feature = features.NewNestedVirtualization(cfg, utils.ConfigSourceAnnotations)
+				Expect(appliedFeature(nodeClient(node("plain", nil)))).To(Equal(utils.CPUFeatureVMX))
+			})
+
+			It("should reuse the detected feature for subsequent VMs", func() {
+				k8sClient := nodeClient(
+					node("intel-1", map[string]string{"cpu-feature.node.kubevirt.io/vmx": "true"}),
+				)
+				Expect(appliedFeature(k8sClient)).To(Equal(utils.CPUFeatureVMX))
+
+				for _, name := range []string{"amd-1", "amd-2"} {
+					Expect(k8sClient.Create(ctx, node(name, map[string]string{"cpu-feature.node.kubevirt.io/svm": "true"}))).To(Succeed())
+				}
+				vm.Spec.Template.Spec.Domain.CPU = nil
+				Expect(appliedFeature(k8sClient)).To(Equal(utils.CPUFeatureVMX))
+			})
+		})
+	})
+
+	Describe("Revert", func() {
+		It("should remove the nested virtualization CPU feature and node label", func() {
+			feature = features.NewNestedVirtualization(&config.NestedVirtConfig{
+				Enabled:      true,
+				NodeAffinity: true,
+			}, utils.ConfigSourceAnnotations)
+			vm.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{
+				Features: []kubevirtv1.CPUFeature{{Name: "pdpe1gb", Policy: "require"}},
+			}
+			vm.Annotations = map[string]string{
+				utils.AnnotationNestedVirt: utils.CPUFeatureVMX,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+
+			previous := vm.DeepCopy()
+			for k, v := range result.Annotations {
+				previous.Annotations[k] = v
+			}
+			delete(vm.Annotations, utils.AnnotationNestedVirt)
+
+			Expect(feature.Revert(ctx, vm, previous)).To(Succeed())
+			Expect(vm.Spec.Template.Spec.Domain.CPU.Features).To(ConsistOf(kubevirtv1.CPUFeature{Name: "pdpe1gb", Policy: "require"}))
+			Expect(vm.Spec.Template.Spec.NodeSelector).To(BeEmpty())
+		})
+
+		It("should keep node selector entries the user set", func() {
+			feature = features.NewNestedVirtualization(&config.NestedVirtConfig{
+				Enabled:      true,
+				NodeAffinity: true,
+			}, utils.ConfigSourceAnnotations)
+			vm.Spec.Template.Spec.NodeSelector = map[string]string{"cpu-feature.node.kubevirt.io/vmx": "true"}
+			vm.Annotations = map[string]string{
+				utils.AnnotationNestedVirt: utils.CPUFeatureVMX,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			previous := vm.DeepCopy()
+			for k, v := range result.Annotations {
+				previous.Annotations[k] = v
+			}
+			delete(vm.Annotations, utils.AnnotationNestedVirt)
+
+			Expect(feature.Revert(ctx, vm, previous)).To(Succeed())
+			Expect(vm.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue("cpu-feature.node.kubevirt.io/vmx", "true"))
+		})
 	})
 })
@@ -0,0 +1,91 @@
+package features
+
+import (
+	"context"
+	"fmt"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// virtioModel is the network interface model supporting multi-queue
+const virtioModel = "virtio"
+
+// NetMultiQueue implements network interface multi-queue.
+// Multi-queue is only effective for virtio interfaces.
+type NetMultiQueue struct {
+	configSource utils.ConfigSource
+}
+
+// NewNetMultiQueue creates a new NetMultiQueue feature
+func NewNetMultiQueue(configSource utils.ConfigSource) *NetMultiQueue {
+	return &NetMultiQueue{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *NetMultiQueue) Name() string {
+	return utils.FeatureNetMultiQueue
+}
+
+// IsEnabled checks if network multi-queue is requested via annotations or labels
+func (f *NetMultiQueue) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationNetMultiQueue)
+	return exists && utils.IsTruthyValue(value)
+}
+
+// Validate ensures the value is supported and the VM has a virtio interface
+func (f *NetMultiQueue) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationNetMultiQueue)
+	if !exists {
+		return nil
+	}
+
+	if value != "enabled" {
+		return fmt.Errorf("invalid value for %s: %s (expected 'enabled')",
+			utils.AnnotationNetMultiQueue, value)
+	}
+
+	if vm.Spec.Template == nil {
+		return fmt.Errorf("VM template is nil")
+	}
+
+	// Interfaces without a model default to virtio
+	for _, iface := range vm.Spec.Template.Spec.Domain.Devices.Interfaces {
+		if iface.Model == "" || iface.Model == virtioModel {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("network multi-queue requires at least one virtio network interface")
+}
+
+// Apply enables network interface multi-queue on the VM template
+func (f *NetMultiQueue) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	logger.Info("Applying network multi-queue", "vm", vm.Name)
+
+	enabled := true
+	vm.Spec.Template.Spec.Domain.Devices.NetworkInterfaceMultiQueue = &enabled
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationNetMultiQueueApplied, "true")
+	result.AddMessage("Enabled network interface multi-queue")
+
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("NetMultiQueue", func() {
+	var (
+		feature *features.NetMultiQueue
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewNetMultiQueue(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							Devices: kubevirtv1.Devices{
+								Interfaces: []kubevirtv1.Interface{
+									{Name: "default", Model: "virtio"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureNetMultiQueue))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is enabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationNetMultiQueue: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		BeforeEach(func() {
+			vm.Annotations = map[string]string{utils.AnnotationNetMultiQueue: "enabled"}
+		})
+
+		It("should succeed with a virtio interface", func() {
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should treat interfaces without a model as virtio", func() {
+			vm.Spec.Template.Spec.Domain.Devices.Interfaces[0].Model = ""
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject VMs without virtio interfaces", func() {
+			vm.Spec.Template.Spec.Domain.Devices.Interfaces[0].Model = "e1000"
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("virtio"))
+		})
+
+		It("should reject VMs without interfaces", func() {
+			vm.Spec.Template.Spec.Domain.Devices.Interfaces = nil
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject invalid values", func() {
+			vm.Annotations[utils.AnnotationNetMultiQueue] = "on"
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid value"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should enable network interface multi-queue", func() {
+			vm.Annotations = map[string]string{utils.AnnotationNetMultiQueue: "enabled"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationNetMultiQueueApplied, "true"))
+
+			multiQueue := vm.Spec.Template.Spec.Domain.Devices.NetworkInterfaceMultiQueue
+			Expect(multiQueue).ToNot(BeNil())
+			Expect(*multiQueue).To(BeTrue())
+		})
+
+		It("should return error without a virtio interface", func() {
+			vm.Spec.Template.Spec.Domain.Devices.Interfaces = nil
+			vm.Annotations = map[string]string{utils.AnnotationNetMultiQueue: "enabled"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.NetworkInterfaceMultiQueue).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,90 @@
+package features
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// nodeLabelsAnnotationFormat names the tracking annotation listing the node
+// selector keys a feature added, so reverting it leaves the user's own alone
+const nodeLabelsAnnotationFormat = utils.DefaultKeyPrefix + "%s-node-labels"
+
+// requireNodeLabels adds labels to the VM's node selector so it is only scheduled
+// on nodes advertising the hardware a feature needs. Selector entries already set
+// by the user are left untouched. The keys the feature owns, those added now and
+// those recorded by an earlier admission that are still set, are recorded in
+// result for releaseNodeLabels. It returns the keys that were added, sorted.
+func requireNodeLabels(vm *kubevirtv1.VirtualMachine, feature string, labels map[string]string, result *MutationResult) []string {
+	if len(labels) == 0 || vm.Spec.Template == nil {
+		return nil
+	}
+
+	spec := &vm.Spec.Template.Spec
+	if spec.NodeSelector == nil {
+		spec.NodeSelector = make(map[string]string, len(labels))
+	}
+
+	owned := make(map[string]bool, len(labels))
+	for _, key := range recordedNodeLabels(vm, feature) {
+		if value, ok := labels[key]; ok && spec.NodeSelector[key] == value {
+			owned[key] = true
+		}
+	}
+
+	var added []string
+	for key, value := range labels {
+		if _, exists := spec.NodeSelector[key]; exists {
+			continue
+		}
+		spec.NodeSelector[key] = value
+		added = append(added, key)
+		owned[key] = true
+	}
+
+	if len(owned) > 0 {
+		keys := make([]string, 0, len(owned))
+		for key := range owned {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		encoded, _ := json.Marshal(keys)
+		result.AddAnnotation(fmt.Sprintf(nodeLabelsAnnotationFormat, feature), string(encoded))
+	}
+
+	sort.Strings(added)
+	return added
+}
+
+// releaseNodeLabels removes the node selector entries requireNodeLabels
+// recorded for the feature on the previous VM, along with the record.
+// Entries whose value no longer matches labels are kept.
+func releaseNodeLabels(vm, previous *kubevirtv1.VirtualMachine, feature string, labels map[string]string) {
+	delete(vm.Annotations, fmt.Sprintf(nodeLabelsAnnotationFormat, feature))
+	if vm.Spec.Template == nil {
+		return
+	}
+
+	spec := &vm.Spec.Template.Spec
+	for _, key := range recordedNodeLabels(previous, feature) {
+		if value, ok := labels[key]; ok && spec.NodeSelector[key] == value {
+			delete(spec.NodeSelector, key)
+		}
+	}
+	if len(spec.NodeSelector) == 0 {
+		spec.NodeSelector = nil
+	}
+}
+
+// recordedNodeLabels returns the node selector keys recorded for the feature on the VM
+func recordedNodeLabels(vm *kubevirtv1.VirtualMachine, feature string) []string {
+	var keys []string
+	if recorded := vm.GetAnnotations()[fmt.Sprintf(nodeLabelsAnnotationFormat, feature)]; recorded != "" {
+		_ = json.Unmarshal([]byte(recorded), &keys)
+	}
+	return keys
+}
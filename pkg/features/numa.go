@@ -0,0 +1,109 @@
+package features
+
+import (
+	"context"
+	"fmt"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// Numa implements the guest NUMA topology feature.
+// It maps the host NUMA topology of the VM's dedicated CPUs into the guest,
+// which KubeVirt only supports for VMs backed by hugepages.
+type Numa struct {
+	configSource utils.ConfigSource
+}
+
+// NewNuma creates a new Numa feature
+func NewNuma(configSource utils.ConfigSource) *Numa {
+	return &Numa{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *Numa) Name() string {
+	return utils.FeatureNuma
+}
+
+// Dependencies runs guest NUMA mapping after the features that configure
+// hugepages and dedicated CPUs
+func (f *Numa) Dependencies() []Dependency {
+	return []Dependency{
+		{Feature: utils.FeatureGuaranteedQoS},
+		{Feature: utils.FeatureDedicatedCPUs},
+	}
+}
+
+// IsEnabled checks if guest NUMA mapping is requested via annotations or labels
+func (f *Numa) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationNuma)
+	return exists && value == utils.NumaGuestMapping
+}
+
+// Validate ensures the configured value is supported and hugepages are configured
+func (f *Numa) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationNuma)
+	if !exists {
+		return nil
+	}
+
+	if value != utils.NumaGuestMapping {
+		return fmt.Errorf("invalid value for %s: %s (expected '%s')",
+			utils.AnnotationNuma, value, utils.NumaGuestMapping)
+	}
+
+	if vm.Spec.Template == nil {
+		return fmt.Errorf("VM template is nil")
+	}
+
+	memory := vm.Spec.Template.Spec.Domain.Memory
+	if memory == nil || memory.Hugepages == nil || memory.Hugepages.PageSize == "" {
+		return fmt.Errorf("guest NUMA mapping requires hugepages (spec.template.spec.domain.memory.hugepages.pageSize)")
+	}
+
+	return nil
+}
+
+// Apply enables guest NUMA mapping passthrough on the VM template.
+// Dedicated CPU placement is enabled as well since KubeVirt requires it.
+func (f *Numa) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	logger.Info("Applying guest NUMA mapping", "vm", vm.Name)
+
+	domain := &vm.Spec.Template.Spec.Domain
+	if domain.CPU == nil {
+		domain.CPU = &kubevirtv1.CPU{}
+	}
+	if domain.CPU.NUMA == nil {
+		domain.CPU.NUMA = &kubevirtv1.NUMA{}
+	}
+	if domain.CPU.NUMA.GuestMappingPassthrough == nil {
+		domain.CPU.NUMA.GuestMappingPassthrough = &kubevirtv1.NUMAGuestMappingPassthrough{}
+	}
+	if !domain.CPU.DedicatedCPUPlacement {
+		domain.CPU.DedicatedCPUPlacement = true
+		result.AddMessage("Enabled dedicated CPU placement required by guest NUMA mapping")
+	}
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationNumaApplied, utils.NumaGuestMapping)
+	result.AddMessage(fmt.Sprintf("Enabled guest NUMA mapping with %s hugepages", domain.Memory.Hugepages.PageSize))
+
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Numa", func() {
+	var (
+		feature *features.Numa
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewNuma(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							Memory: &kubevirtv1.Memory{
+								Hugepages: &kubevirtv1.Hugepages{PageSize: "1Gi"},
+							},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureNuma))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true for guest-mapping", func() {
+			vm.Annotations = map[string]string{utils.AnnotationNuma: "guest-mapping"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return false for other values", func() {
+			vm.Annotations = map[string]string{utils.AnnotationNuma: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+	})
+
+	Describe("Validate", func() {
+		BeforeEach(func() {
+			vm.Annotations = map[string]string{utils.AnnotationNuma: "guest-mapping"}
+		})
+
+		It("should succeed when hugepages are configured", func() {
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject invalid values", func() {
+			vm.Annotations[utils.AnnotationNuma] = "enabled"
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid value"))
+		})
+
+		It("should reject VMs without hugepages", func() {
+			vm.Spec.Template.Spec.Domain.Memory = nil
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("hugepages"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should not modify the VM when not enabled", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.CPU).To(BeNil())
+		})
+
+		It("should enable guest NUMA mapping and dedicated CPUs", func() {
+			vm.Annotations = map[string]string{utils.AnnotationNuma: "guest-mapping"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationNumaApplied, utils.NumaGuestMapping))
+
+			cpu := vm.Spec.Template.Spec.Domain.CPU
+			Expect(cpu.NUMA).ToNot(BeNil())
+			Expect(cpu.NUMA.GuestMappingPassthrough).ToNot(BeNil())
+			Expect(cpu.DedicatedCPUPlacement).To(BeTrue())
+		})
+
+		It("should return error without hugepages", func() {
+			vm.Annotations = map[string]string{utils.AnnotationNuma: "guest-mapping"}
+			vm.Spec.Template.Spec.Domain.Memory = &kubevirtv1.Memory{}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,198 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// windowsNetworkModels are the NIC models the Windows preset may apply.
+// e1000e works with in-box drivers; virtio requires the virtio-win drivers.
+var windowsNetworkModels = map[string]bool{
+	"e1000e": true,
+	"virtio": true,
+}
+
+// OSPreset implements composite OS presets. The "windows" preset applies
+// Hyper-V enlightenments, Windows-friendly clock timers, a TPM, EFI secure boot
+// and a NIC model, each of which can be turned off in the webhook configuration.
+// Settings already present on the VM are left untouched.
+type OSPreset struct {
+	config       *config.WindowsPresetConfig
+	hypervConfig *config.HyperVConfig
+	configSource utils.ConfigSource
+}
+
+// NewOSPreset creates a new OSPreset feature
+func NewOSPreset(cfg *config.WindowsPresetConfig, hypervCfg *config.HyperVConfig, configSource utils.ConfigSource) *OSPreset {
+	return &OSPreset{
+		config:       cfg,
+		hypervConfig: hypervCfg,
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *OSPreset) Name() string {
+	return utils.FeatureOSPreset
+}
+
+// Config returns the feature settings
+func (f *OSPreset) Config() interface{} {
+	return f.config
+}
+
+// IsEnabled checks if an OS preset is requested via annotations or labels
+func (f *OSPreset) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	if !f.config.Enabled {
+		return false
+	}
+
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationOSPreset)
+	return exists && value != ""
+}
+
+// Validate ensures the preset is known and its configuration is valid
+func (f *OSPreset) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationOSPreset)
+	if !exists {
+		return nil
+	}
+
+	if value != utils.OSPresetWindows {
+		return fmt.Errorf("invalid value for %s: %s (expected '%s')",
+			utils.AnnotationOSPreset, value, utils.OSPresetWindows)
+	}
+
+	if f.config.HyperV {
+		for _, name := range f.hypervConfig.Enlightenments {
+			if _, ok := hypervEnlightenments[strings.TrimSpace(name)]; !ok {
+				return fmt.Errorf("unsupported Hyper-V enlightenment %q in configuration", name)
+			}
+		}
+	}
+
+	if f.config.NetworkModel != "" && !windowsNetworkModels[f.config.NetworkModel] {
+		return fmt.Errorf("unsupported Windows preset network model %q in configuration", f.config.NetworkModel)
+	}
+
+	return nil
+}
+
+// Apply applies the enabled items of the Windows preset
+func (f *OSPreset) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	logger.Info("Applying Windows OS preset", "vm", vm.Name)
+
+	domain := &vm.Spec.Template.Spec.Domain
+	var applied []string
+
+	if f.config.HyperV {
+		applyHyperVEnlightenments(domain, f.hypervConfig.Enlightenments)
+		applied = append(applied, "hyperv")
+	}
+
+	if f.config.ClockTimers {
+		applyWindowsClockTimers(domain)
+		applied = append(applied, "clock-timers")
+	}
+
+	if f.config.TPM {
+		if domain.Devices.TPM == nil {
+			domain.Devices.TPM = &kubevirtv1.TPMDevice{}
+		}
+		applied = append(applied, "tpm")
+	}
+
+	if f.config.SecureBoot && applySecureBoot(domain) {
+		applied = append(applied, "secure-boot")
+	}
+
+	if f.config.NetworkModel != "" {
+		for i := range domain.Devices.Interfaces {
+			if domain.Devices.Interfaces[i].Model == "" {
+				domain.Devices.Interfaces[i].Model = f.config.NetworkModel
+			}
+		}
+		applied = append(applied, "network-model="+f.config.NetworkModel)
+	}
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationOSPresetApplied, utils.OSPresetWindows)
+	result.AddMessage(fmt.Sprintf("Applied Windows preset: %s", strings.Join(applied, ",")))
+
+	return result, nil
+}
+
+// applyWindowsClockTimers configures the timers recommended for Windows guests:
+// HPET disabled, PIT delay, RTC catchup and the Hyper-V reference clock
+func applyWindowsClockTimers(domain *kubevirtv1.DomainSpec) {
+	if domain.Clock == nil {
+		domain.Clock = &kubevirtv1.Clock{}
+	}
+	if domain.Clock.Timer == nil {
+		domain.Clock.Timer = &kubevirtv1.Timer{}
+	}
+
+	timer := domain.Clock.Timer
+	if timer.HPET == nil {
+		disabled := false
+		timer.HPET = &kubevirtv1.HPETTimer{Enabled: &disabled}
+	}
+	if timer.PIT == nil {
+		timer.PIT = &kubevirtv1.PITTimer{TickPolicy: kubevirtv1.PITTickPolicyDelay}
+	}
+	if timer.RTC == nil {
+		timer.RTC = &kubevirtv1.RTCTimer{TickPolicy: kubevirtv1.RTCTickPolicyCatchup}
+	}
+	if timer.Hyperv == nil {
+		enabled := true
+		timer.Hyperv = &kubevirtv1.HypervTimer{Enabled: &enabled}
+	}
+}
+
+// applySecureBoot enables EFI secure boot and SMM unless a bootloader is
+// already configured. Returns false when an existing bootloader was kept.
+func applySecureBoot(domain *kubevirtv1.DomainSpec) bool {
+	if domain.Firmware == nil {
+		domain.Firmware = &kubevirtv1.Firmware{}
+	}
+	if domain.Firmware.Bootloader != nil {
+		return false
+	}
+
+	secureBoot := true
+	domain.Firmware.Bootloader = &kubevirtv1.Bootloader{
+		EFI: &kubevirtv1.EFI{SecureBoot: &secureBoot},
+	}
+
+	// Secure boot requires SMM
+	if domain.Features == nil {
+		domain.Features = &kubevirtv1.Features{}
+	}
+	domain.Features.SMM = enableFeatureState(domain.Features.SMM)
+
+	return true
+}
@@ -0,0 +1,171 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("OSPreset", func() {
+	var (
+		feature   *features.OSPreset
+		presetCfg *config.WindowsPresetConfig
+		hypervCfg *config.HyperVConfig
+		vm        *kubevirtv1.VirtualMachine
+		ctx       context.Context
+	)
+
+	BeforeEach(func() {
+		presetCfg = &config.WindowsPresetConfig{
+			Enabled:      true,
+			HyperV:       true,
+			ClockTimers:  true,
+			TPM:          true,
+			SecureBoot:   true,
+			NetworkModel: "e1000e",
+		}
+		hypervCfg = &config.HyperVConfig{
+			Enabled:        true,
+			Enlightenments: []string{"relaxed", "vapic", "spinlocks"},
+		}
+		feature = features.NewOSPreset(presetCfg, hypervCfg, utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							Devices: kubevirtv1.Devices{
+								Interfaces: []kubevirtv1.Interface{
+									{Name: "default"},
+									{Name: "secondary", Model: "virtio"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureOSPreset))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationOSPreset: "windows"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return false when the feature is disabled in config", func() {
+			presetCfg.Enabled = false
+			vm.Annotations = map[string]string{utils.AnnotationOSPreset: "windows"}
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should reject unknown presets", func() {
+			vm.Annotations = map[string]string{utils.AnnotationOSPreset: "linux"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid value"))
+		})
+
+		It("should reject unsupported network models in config", func() {
+			presetCfg.NetworkModel = "rtl8139"
+			vm.Annotations = map[string]string{utils.AnnotationOSPreset: "windows"}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject unsupported enlightenments in config", func() {
+			hypervCfg.Enlightenments = []string{"bogus"}
+			vm.Annotations = map[string]string{utils.AnnotationOSPreset: "windows"}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Apply", func() {
+		BeforeEach(func() {
+			vm.Annotations = map[string]string{utils.AnnotationOSPreset: "windows"}
+		})
+
+		It("should apply the full Windows preset", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationOSPresetApplied, "windows"))
+
+			domain := vm.Spec.Template.Spec.Domain
+			Expect(domain.Features.Hyperv.Relaxed).ToNot(BeNil())
+			Expect(domain.Features.Hyperv.Spinlocks).ToNot(BeNil())
+			Expect(*domain.Clock.Timer.HPET.Enabled).To(BeFalse())
+			Expect(domain.Clock.Timer.PIT.TickPolicy).To(Equal(kubevirtv1.PITTickPolicyDelay))
+			Expect(domain.Clock.Timer.RTC.TickPolicy).To(Equal(kubevirtv1.RTCTickPolicyCatchup))
+			Expect(domain.Clock.Timer.Hyperv).ToNot(BeNil())
+			Expect(domain.Devices.TPM).ToNot(BeNil())
+			Expect(*domain.Firmware.Bootloader.EFI.SecureBoot).To(BeTrue())
+			Expect(*domain.Features.SMM.Enabled).To(BeTrue())
+			Expect(domain.Devices.Interfaces[0].Model).To(Equal("e1000e"))
+			Expect(domain.Devices.Interfaces[1].Model).To(Equal("virtio"))
+		})
+
+		It("should skip items disabled in config", func() {
+			presetCfg.TPM = false
+			presetCfg.SecureBoot = false
+			presetCfg.NetworkModel = ""
+
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			domain := vm.Spec.Template.Spec.Domain
+			Expect(domain.Devices.TPM).To(BeNil())
+			Expect(domain.Firmware).To(BeNil())
+			Expect(domain.Devices.Interfaces[0].Model).To(BeEmpty())
+			Expect(domain.Features.Hyperv).ToNot(BeNil())
+		})
+
+		It("should keep an existing bootloader", func() {
+			vm.Spec.Template.Spec.Domain.Firmware = &kubevirtv1.Firmware{
+				Bootloader: &kubevirtv1.Bootloader{BIOS: &kubevirtv1.BIOS{}},
+			}
+
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			domain := vm.Spec.Template.Spec.Domain
+			Expect(domain.Firmware.Bootloader.BIOS).ToNot(BeNil())
+			Expect(domain.Firmware.Bootloader.EFI).To(BeNil())
+		})
+
+		It("should keep existing clock timers", func() {
+			enabled := true
+			vm.Spec.Template.Spec.Domain.Clock = &kubevirtv1.Clock{
+				Timer: &kubevirtv1.Timer{HPET: &kubevirtv1.HPETTimer{Enabled: &enabled}},
+			}
+
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*vm.Spec.Template.Spec.Domain.Clock.Timer.HPET.Enabled).To(BeTrue())
+		})
+	})
+})
@@ -7,10 +7,12 @@ import (
 	"regexp"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
 
@@ -24,12 +26,14 @@ type PCIPassthroughSpec struct {
 
 // PciPassthrough implements PCI device passthrough feature
 type PciPassthrough struct {
+	config       *config.PCIPassthroughConfig
 	configSource utils.ConfigSource
 }
 
 // NewPciPassthrough creates a new PciPassthrough feature
-func NewPciPassthrough(configSource utils.ConfigSource) *PciPassthrough {
+func NewPciPassthrough(cfg *config.PCIPassthroughConfig, configSource utils.ConfigSource) *PciPassthrough {
 	return &PciPassthrough{
+		config:       cfg,
 		configSource: configSource,
 	}
 }
@@ -39,14 +43,23 @@ func (f *PciPassthrough) Name() string {
 	return utils.FeaturePciPassthrough
 }
 
+// Config returns the feature settings
+func (f *PciPassthrough) Config() interface{} {
+	return f.config
+}
+
 // IsEnabled checks if PCI passthrough is requested via annotations or labels
 func (f *PciPassthrough) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	if !f.config.Enabled {
+		return false
+	}
+
 	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationPciPassthrough)
 	return exists && value != ""
 }
 
 // Validate performs validation of PCI passthrough configuration
-func (f *PciPassthrough) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+func (f *PciPassthrough) Validate(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) error {
 	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationPciPassthrough)
 	if !exists {
 		return nil
@@ -71,9 +84,37 @@ func (f *PciPassthrough) Validate(_ context.Context, vm *kubevirtv1.VirtualMachi
 		}
 		seen[device] = true
 
-		// Validate PCI address format
-		if !pciAddressRegex.MatchString(device) {
-			return fmt.Errorf("invalid PCI address format: %s (expected DDDD:BB:DD.F)", device)
+		// Validate PCI address format or a known alias
+		deviceName, err := f.resolveDeviceName(device)
+		if err != nil {
+			return err
+		}
+
+		// Keep tenants to the devices allowed in their namespace
+		if allowed := f.allowedDevices(vm.Namespace); allowed != nil && !allowed[strings.ToLower(device)] && !allowed[strings.ToLower(deviceName)] {
+			return fmt.Errorf("PCI device %s is not allowed in namespace %s", device, vm.Namespace)
+		}
+	}
+
+	// Enforce the per-VM device limit, counting host devices already on the VM
+	if f.config.MaxDevices > 0 && vm.Spec.Template != nil {
+		hostDevices := vm.Spec.Template.Spec.Domain.Devices.HostDevices
+		total := len(hostDevices) + len(f.pendingDevices(hostDevices, spec.Devices))
+
+		if total > f.config.MaxDevices {
+			return fmt.Errorf("too many host devices: %d requested (including %d already present) exceeds the maximum of %d",
+				total, len(hostDevices), f.config.MaxDevices)
+		}
+	}
+
+	// Catch devices KubeVirt will refuse at scheduling time; only rejected
+	// when the PCI error handling mode is reject, otherwise logged
+	if k8sClient != nil {
+		if err := f.checkPermitted(ctx, k8sClient, spec.Devices); err != nil {
+			if f.config.ErrorHandling == utils.ErrorHandlingReject {
+				return err
+			}
+			log.FromContext(ctx).Info("PCI devices may not be permitted by KubeVirt", "vm", vm.Name, "reason", err.Error())
 		}
 	}
 
@@ -85,10 +126,10 @@ func (f *PciPassthrough) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachin
 	logger := log.FromContext(ctx)
 	result := NewMutationResult()
 
-	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationPciPassthrough)
-	if !exists || value == "" {
+	if !f.IsEnabled(vm) {
 		return result, nil
 	}
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationPciPassthrough)
 
 	logger.Info("Applying PCI passthrough feature", "vm", vm.Name)
 
@@ -103,30 +144,34 @@ func (f *PciPassthrough) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachin
 		return result, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationPciPassthrough, err)
 	}
 
-	// Get existing host devices to check for duplicates
-	existingDevices := make(map[string]bool)
-	if vm.Spec.Template.Spec.Domain.Devices.HostDevices != nil {
-		for _, hd := range vm.Spec.Template.Spec.Domain.Devices.HostDevices {
-			existingDevices[hd.DeviceName] = true
-		}
+	// Add each PCI device not already present on the VM
+	hostDevices := vm.Spec.Template.Spec.Domain.Devices.HostDevices
+	pending := f.pendingDevices(hostDevices, spec.Devices)
+	owned := f.ownedDevices(vm)
+	usedNames := make(map[string]bool, len(hostDevices))
+	for _, hd := range hostDevices {
+		usedNames[hd.Name] = true
 	}
-
-	// Add each PCI device
 	var addedDevices []string
-	for i, pciAddr := range spec.Devices {
-		// Convert PCI address to KubeVirt device name format
-		// 0000:00:02.0 -> pci_0000_00_02_0
-		deviceName := "pci_" + strings.ReplaceAll(strings.ReplaceAll(pciAddr, ":", "_"), ".", "_")
-
-		// Skip if already exists
-		if existingDevices[deviceName] {
-			logger.Info("PCI device already exists, skipping", "device", pciAddr)
+	for i, device := range spec.Devices {
+		deviceName, ok := pending[i]
+		if !ok {
+			logger.Info("PCI device already exists, skipping", "device", device)
+			if !previouslyApplied(vm, utils.AnnotationPciPassthroughApplied, device) {
+				result.AddWarning(fmt.Sprintf("PCI device %s is already attached, skipping", device))
+			}
 			continue
 		}
 
-		// Add the host device
+		// Add the host device, named by its list index unless another
+		// device already has that name
+		name := fmt.Sprintf("pci-device-%d", i)
+		for n := 0; usedNames[name]; n++ {
+			name = fmt.Sprintf("pci-device-%d", n)
+		}
+		usedNames[name] = true
 		hostDevice := kubevirtv1.HostDevice{
-			Name:       fmt.Sprintf("pci-device-%d", i),
+			Name:       name,
 			DeviceName: deviceName,
 		}
 
@@ -135,16 +180,225 @@ func (f *PciPassthrough) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachin
 			hostDevice,
 		)
 
-		addedDevices = append(addedDevices, pciAddr)
+		addedDevices = append(addedDevices, device)
 		result.Applied = true
 	}
 
 	if result.Applied {
-		// Add tracking annotation with the list of devices
-		devicesJSON, _ := json.Marshal(addedDevices)
+		if added := requireNodeLabels(vm, f.Name(), f.config.NodeSelector, result); len(added) > 0 {
+			result.AddMessage(fmt.Sprintf("Required node labels: %s", strings.Join(added, ", ")))
+		}
+
+		// Track every device this feature attached, not only those added now,
+		// so Revert removes them all
+		devicesJSON, _ := json.Marshal(append(owned, addedDevices...))
 		result.AddAnnotation(utils.AnnotationPciPassthroughApplied, string(devicesJSON))
 		logger.Info("Successfully applied PCI passthrough", "devices", addedDevices)
 	}
 
 	return result, nil
 }
+
+// Revert removes the host devices recorded in the previous VM's tracking annotation
+func (f *PciPassthrough) Revert(_ context.Context, vm, previous *kubevirtv1.VirtualMachine) error {
+	if vm.Spec.Template == nil {
+		return nil
+	}
+
+	var devices []string
+	if applied := previous.GetAnnotations()[utils.AnnotationPciPassthroughApplied]; applied != "" {
+		if err := json.Unmarshal([]byte(applied), &devices); err != nil {
+			return fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationPciPassthroughApplied, err)
+		}
+	}
+
+	deviceNames := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		deviceName, err := f.resolveDeviceName(device)
+		if err != nil {
+			return err
+		}
+		deviceNames[deviceName] = true
+	}
+
+	spec := &vm.Spec.Template.Spec
+	kept := spec.Domain.Devices.HostDevices[:0]
+	for _, hd := range spec.Domain.Devices.HostDevices {
+		if strings.HasPrefix(hd.Name, "pci-device-") && deviceNames[hd.DeviceName] {
+			continue
+		}
+		kept = append(kept, hd)
+	}
+	spec.Domain.Devices.HostDevices = kept
+	if len(kept) == 0 {
+		spec.Domain.Devices.HostDevices = nil
+	}
+
+	releaseNodeLabels(vm, previous, f.Name(), f.config.NodeSelector)
+	return nil
+}
+
+// ownedDevices returns the devices recorded in the VM's tracking annotation
+// that are still attached
+func (f *PciPassthrough) ownedDevices(vm *kubevirtv1.VirtualMachine) []string {
+	var recorded []string
+	if applied := vm.GetAnnotations()[utils.AnnotationPciPassthroughApplied]; applied != "" {
+		_ = json.Unmarshal([]byte(applied), &recorded)
+	}
+
+	attached := make(map[string]int)
+	for _, hd := range vm.Spec.Template.Spec.Domain.Devices.HostDevices {
+		if strings.HasPrefix(hd.Name, "pci-device-") {
+			attached[hd.DeviceName]++
+		}
+	}
+
+	var owned []string
+	for _, device := range recorded {
+		deviceName, err := f.resolveDeviceName(device)
+		if err != nil || attached[deviceName] == 0 {
+			continue
+		}
+		attached[deviceName]--
+		owned = append(owned, device)
+	}
+	return owned
+}
+
+// ResourceNames returns the device names requested by the VM, resolved through
+// the resource map. It returns nil when PCI passthrough is not requested.
+func (f *PciPassthrough) ResourceNames(vm *kubevirtv1.VirtualMachine) ([]string, error) {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationPciPassthrough)
+	if !exists || value == "" {
+		return nil, nil
+	}
+
+	var spec PCIPassthroughSpec
+	if err := json.Unmarshal([]byte(value), &spec); err != nil {
+		return nil, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationPciPassthrough, err)
+	}
+
+	names := make([]string, 0, len(spec.Devices))
+	for _, device := range spec.Devices {
+		deviceName, err := f.resolveDeviceName(device)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, deviceName)
+	}
+
+	return names, nil
+}
+
+// allowedDevices returns the lowercased devices VMs in the namespace may
+// request, or nil when the namespace is unrestricted
+func (f *PciPassthrough) allowedDevices(namespace string) map[string]bool {
+	devices, ok := f.config.NamespaceDevices[namespace]
+	if !ok {
+		if devices, ok = f.config.NamespaceDevices["*"]; !ok {
+			return nil
+		}
+	}
+
+	allowed := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		allowed[strings.ToLower(device)] = true
+	}
+	return allowed
+}
+
+// resolveDeviceName returns the KubeVirt device name for a PCI address or alias.
+// Entries in the resource map take precedence so the name matches the
+// cluster's permittedHostDevices; unmapped addresses fall back to the
+// synthetic pci_DDDD_BB_DD_F form.
+func (f *PciPassthrough) resolveDeviceName(device string) (string, error) {
+	if resourceName, ok := f.config.ResourceMap[strings.ToLower(device)]; ok {
+		return resourceName, nil
+	}
+
+	if !pciAddressRegex.MatchString(device) {
+		if len(f.config.ResourceMap) > 0 {
+			return "", fmt.Errorf("unknown PCI device %s (expected DDDD:BB:DD.F or an alias from the resource map)", device)
+		}
+		return "", fmt.Errorf("invalid PCI address format: %s (expected DDDD:BB:DD.F)", device)
+	}
+
+	return pciDeviceName(device), nil
+}
+
+// checkPermitted verifies that every requested device resolves to a resource
+// name listed in the KubeVirt CR's permittedHostDevices. The HyperConverged
+// operator propagates its configuration to the KubeVirt CR, so reading the
+// latter covers both installation methods. Clusters without a KubeVirt CR are
+// not checked.
+func (f *PciPassthrough) checkPermitted(ctx context.Context, k8sClient client.Client, devices []string) error {
+	kubevirts := &kubevirtv1.KubeVirtList{}
+	if err := k8sClient.List(ctx, kubevirts); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list KubeVirt resources: %w", err)
+	}
+	if len(kubevirts.Items) == 0 {
+		return nil
+	}
+
+	permitted := make(map[string]bool)
+	if phd := kubevirts.Items[0].Spec.Configuration.PermittedHostDevices; phd != nil {
+		for _, dev := range phd.PciHostDevices {
+			permitted[dev.ResourceName] = true
+		}
+	}
+
+	var missing []string
+	for _, device := range devices {
+		deviceName, err := f.resolveDeviceName(device)
+		if err != nil {
+			return err
+		}
+		// Allowlisted devices are registered by the controller once admitted
+		if f.config.AutoRegister && f.config.AutoRegisterAllowlist[deviceName] != "" {
+			continue
+		}
+		if !permitted[deviceName] {
+			missing = append(missing, fmt.Sprintf("%s (%s)", device, deviceName))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("PCI devices not listed in KubeVirt permittedHostDevices: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// pendingDevices returns the device names, keyed by request index, that still
+// need to be added. Mapped resource names may be requested more than once, so
+// each existing host device with the same name satisfies one request.
+func (f *PciPassthrough) pendingDevices(hostDevices []kubevirtv1.HostDevice, devices []string) map[int]string {
+	existing := make(map[string]int, len(hostDevices))
+	for _, hd := range hostDevices {
+		existing[hd.DeviceName]++
+	}
+
+	pending := make(map[int]string, len(devices))
+	for i, device := range devices {
+		deviceName, err := f.resolveDeviceName(device)
+		if err != nil {
+			continue
+		}
+		if existing[deviceName] > 0 {
+			existing[deviceName]--
+			continue
+		}
+		pending[i] = deviceName
+	}
+
+	return pending
+}
+
+// pciDeviceName converts a PCI address to the KubeVirt device name format
+// (0000:00:02.0 -> pci_0000_00_02_0)
+func pciDeviceName(pciAddr string) string {
+	return "pci_" + strings.ReplaceAll(strings.ReplaceAll(pciAddr, ":", "_"), ".", "_")
+}
@@ -6,8 +6,12 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
@@ -15,12 +19,17 @@ import (
 var _ = Describe("PciPassthrough", func() {
 	var (
 		feature *features.PciPassthrough
+		pciCfg  *config.PCIPassthroughConfig
 		vm      *kubevirtv1.VirtualMachine
 		ctx     context.Context
 	)
 
 	BeforeEach(func() {
-		feature = features.NewPciPassthrough(utils.ConfigSourceAnnotations)
+		pciCfg = &config.PCIPassthroughConfig{
+			Enabled:    true,
+			MaxDevices: 4,
+		}
+		feature = features.NewPciPassthrough(pciCfg, utils.ConfigSourceAnnotations)
 		ctx = context.Background()
 
 		vm = &kubevirtv1.VirtualMachine{
@@ -60,6 +69,16 @@ var _ = Describe("PciPassthrough", func() {
 			})
 		})
 
+		Context("when the feature is disabled in config", func() {
+			It("should return false", func() {
+				pciCfg.Enabled = false
+				vm.Annotations = map[string]string{
+					utils.AnnotationPciPassthrough: `{"devices": ["0000:00:02.0"]}`,
+				}
+				Expect(feature.IsEnabled(vm)).To(BeFalse())
+			})
+		})
+
 		Context("when annotation is present with empty value", func() {
 			It("should return false", func() {
 				vm.Annotations = map[string]string{
@@ -71,7 +90,7 @@ var _ = Describe("PciPassthrough", func() {
 
 		Context("when using labels as config source", func() {
 			BeforeEach(func() {
-				feature = features.NewPciPassthrough(utils.ConfigSourceLabels)
+				feature = features.NewPciPassthrough(pciCfg, utils.ConfigSourceLabels)
 			})
 
 			It("should return true when label is set", func() {
@@ -177,7 +196,7 @@ var _ = Describe("PciPassthrough", func() {
 
 		Context("when using labels as config source", func() {
 			BeforeEach(func() {
-				feature = features.NewPciPassthrough(utils.ConfigSourceLabels)
+				feature = features.NewPciPassthrough(pciCfg, utils.ConfigSourceLabels)
 			})
 
 			It("should accept valid PCI address from label", func() {
@@ -198,6 +217,90 @@ var _ = Describe("PciPassthrough", func() {
 		})
 	})
 
+	Describe("MaxDevices", func() {
+		It("should accept requests up to the limit", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices":["0000:01:00.0","0000:01:00.1","0000:02:00.0","0000:02:00.1"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject requests above the limit", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices":["0000:01:00.0","0000:01:00.1","0000:02:00.0","0000:02:00.1","0000:03:00.0"]}`,
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("exceeds the maximum of 4"))
+		})
+
+		It("should count host devices already on the VM", func() {
+			vm.Spec.Template.Spec.Domain.Devices.HostDevices = []kubevirtv1.HostDevice{
+				{Name: "existing-0", DeviceName: "nvidia.com/GA102"},
+				{Name: "existing-1", DeviceName: "nvidia.com/GA102"},
+				{Name: "existing-2", DeviceName: "pci_0000_01_00_0"},
+			}
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices":["0000:01:00.0","0000:01:00.1","0000:02:00.0"]}`,
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("5 requested"))
+		})
+
+		It("should not enforce a limit of zero", func() {
+			pciCfg.MaxDevices = 0
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices":["0000:01:00.0","0000:01:00.1","0000:02:00.0","0000:02:00.1","0000:03:00.0"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+	})
+
+	Describe("NamespaceDevices", func() {
+		BeforeEach(func() {
+			pciCfg.ResourceMap = map[string]string{"0000:02:00.0": "nvidia.com/GA102"}
+			pciCfg.NamespaceDevices = map[string][]string{
+				"default": {"0000:01:00.0", "nvidia.com/GA102"},
+				"*":       {"0000:03:00.0"},
+			}
+		})
+
+		It("should accept devices allowed in the namespace by address or resource name", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices":["0000:01:00.0","0000:02:00.0"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject devices outside the namespace's allowlist", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices":["0000:01:00.0","0000:03:00.0"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(MatchError("PCI device 0000:03:00.0 is not allowed in namespace default"))
+		})
+
+		It("should apply the wildcard entry to namespaces not listed", func() {
+			vm.Namespace = "tenant-b"
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices":["0000:03:00.0"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+
+			vm.Annotations[utils.AnnotationPciPassthrough] = `{"devices":["0000:01:00.0"]}`
+			Expect(feature.Validate(ctx, vm, nil)).To(MatchError(ContainSubstring("not allowed in namespace tenant-b")))
+		})
+
+		It("should not restrict namespaces without an entry", func() {
+			delete(pciCfg.NamespaceDevices, "*")
+			vm.Namespace = "tenant-b"
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices":["0000:01:00.0","0000:04:00.0"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+	})
+
 	Describe("Apply", func() {
 		Context("when VM template is nil", func() {
 			It("should return error", func() {
@@ -227,6 +330,22 @@ var _ = Describe("PciPassthrough", func() {
 				Expect(devices[0].DeviceName).To(Equal("pci_0000_00_02_0"))
 			})
 
+			It("should not reuse the name of an attached device", func() {
+				vm.Spec.Template.Spec.Domain.Devices.HostDevices = []kubevirtv1.HostDevice{
+					{Name: "pci-device-0", DeviceName: "pci_0000_00_02_0"},
+				}
+				vm.Annotations = map[string]string{
+					utils.AnnotationPciPassthrough: `{"devices": ["0000:00:03.0", "0000:00:02.0"]}`,
+				}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				devices := vm.Spec.Template.Spec.Domain.Devices.HostDevices
+				Expect(devices).To(HaveLen(2))
+				Expect(devices[1].Name).To(Equal("pci-device-1"))
+				Expect(devices[1].DeviceName).To(Equal("pci_0000_00_03_0"))
+			})
+
 			It("should add tracking annotation", func() {
 				vm.Annotations = map[string]string{
 					utils.AnnotationPciPassthrough: `{"devices": ["0000:00:02.0"]}`,
@@ -273,6 +392,7 @@ var _ = Describe("PciPassthrough", func() {
 				devices := vm.Spec.Template.Spec.Domain.Devices.HostDevices
 				Expect(devices).To(HaveLen(2)) // existing + new one
 				Expect(devices[1].DeviceName).To(Equal("pci_0000_01_00_0"))
+				Expect(result.Warnings).To(ConsistOf(ContainSubstring("0000:00:02.0")))
 			})
 		})
 
@@ -290,7 +410,7 @@ var _ = Describe("PciPassthrough", func() {
 
 		Context("when using labels as config source", func() {
 			BeforeEach(func() {
-				feature = features.NewPciPassthrough(utils.ConfigSourceLabels)
+				feature = features.NewPciPassthrough(pciCfg, utils.ConfigSourceLabels)
 			})
 
 			It("should add hostDevice from label", func() {
@@ -325,4 +445,234 @@ var _ = Describe("PciPassthrough", func() {
 			})
 		})
 	})
+
+	Describe("ResourceMap", func() {
+		BeforeEach(func() {
+			pciCfg.ResourceMap = map[string]string{
+				"0000:01:00.0": "nvidia.com/GA102",
+				"0000:02:00.0": "nvidia.com/GA102",
+				"fast-nic":     "mellanox.com/ConnectX6",
+			}
+		})
+
+		It("should use the mapped resource name for a PCI address", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:01:00.0"]}`,
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			devices := vm.Spec.Template.Spec.Domain.Devices.HostDevices
+			Expect(devices).To(HaveLen(1))
+			Expect(devices[0].DeviceName).To(Equal("nvidia.com/GA102"))
+		})
+
+		It("should match PCI addresses regardless of hex case", func() {
+			pciCfg.ResourceMap["0000:0a:00.0"] = "intel.com/QAT"
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:0A:00.0"]}`,
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.HostDevices[0].DeviceName).To(Equal("intel.com/QAT"))
+		})
+
+		It("should accept aliases from the map", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["fast-nic"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.HostDevices[0].DeviceName).To(Equal("mellanox.com/ConnectX6"))
+		})
+
+		It("should reject unknown aliases", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["slow-nic"]}`,
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unknown PCI device"))
+		})
+
+		It("should fall back to the synthetic name for unmapped addresses", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:03:00.0"]}`,
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.HostDevices[0].DeviceName).To(Equal("pci_0000_03_00_0"))
+		})
+
+		It("should add one host device per address sharing a resource name", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:01:00.0", "0000:02:00.0"]}`,
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.HostDevices).To(HaveLen(2))
+
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.HostDevices).To(HaveLen(2))
+		})
+	})
+
+	Describe("PermittedHostDevices", func() {
+		var fakeClient client.Client
+
+		BeforeEach(func() {
+			pciCfg.ErrorHandling = utils.ErrorHandlingReject
+			pciCfg.ResourceMap = map[string]string{
+				"0000:01:00.0": "nvidia.com/GA102",
+				"0000:02:00.0": "intel.com/QAT",
+			}
+
+			scheme := runtime.NewScheme()
+			_ = kubevirtv1.AddToScheme(scheme)
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&kubevirtv1.KubeVirt{
+					ObjectMeta: metav1.ObjectMeta{Name: "kubevirt", Namespace: "kubevirt"},
+					Spec: kubevirtv1.KubeVirtSpec{
+						Configuration: kubevirtv1.KubeVirtConfiguration{
+							PermittedHostDevices: &kubevirtv1.PermittedHostDevices{
+								PciHostDevices: []kubevirtv1.PciHostDevice{
+									{PCIVendorSelector: "10DE:2204", ResourceName: "nvidia.com/GA102"},
+								},
+							},
+						},
+					},
+				},
+			).Build()
+		})
+
+		It("should accept permitted devices", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:01:00.0"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, fakeClient)).To(Succeed())
+		})
+
+		It("should reject devices missing from permittedHostDevices", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:01:00.0", "0000:02:00.0"]}`,
+			}
+			err := feature.Validate(ctx, vm, fakeClient)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("0000:02:00.0 (intel.com/QAT)"))
+			Expect(err.Error()).ToNot(ContainSubstring("nvidia.com/GA102"))
+		})
+
+		It("should only log when not in reject mode", func() {
+			pciCfg.ErrorHandling = utils.ErrorHandlingAllowAndLog
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:02:00.0"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, fakeClient)).To(Succeed())
+		})
+
+		It("should allow devices the controller will auto-register", func() {
+			pciCfg.AutoRegister = true
+			pciCfg.AutoRegisterAllowlist = map[string]string{"intel.com/QAT": "8086:4940"}
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:02:00.0"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, fakeClient)).To(Succeed())
+		})
+
+		It("should skip the check when no KubeVirt CR exists", func() {
+			scheme := runtime.NewScheme()
+			_ = kubevirtv1.AddToScheme(scheme)
+			emptyClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:02:00.0"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, emptyClient)).To(Succeed())
+		})
+	})
+
+	Describe("ResourceNames", func() {
+		It("should return nil when not requested", func() {
+			names, err := feature.ResourceNames(vm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(BeNil())
+		})
+
+		It("should resolve mapped and unmapped devices", func() {
+			pciCfg.ResourceMap = map[string]string{"fast-nic": "mellanox.com/ConnectX6"}
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["fast-nic", "0000:03:00.0"]}`,
+			}
+			names, err := feature.ResourceNames(vm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal([]string{"mellanox.com/ConnectX6", "pci_0000_03_00_0"}))
+		})
+	})
+
+	Describe("NodeSelector", func() {
+		It("should require the configured node labels when devices are added", func() {
+			pciCfg.NodeSelector = map[string]string{"example.com/passthrough": "true"}
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:01:00.0"]}`,
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue("example.com/passthrough", "true"))
+		})
+	})
+
+	Describe("Revert", func() {
+		It("should remove the applied host devices and keep others", func() {
+			pciCfg.NodeSelector = map[string]string{"example.com/passthrough": "true"}
+			vm.Spec.Template.Spec.Domain.Devices.HostDevices = []kubevirtv1.HostDevice{
+				{Name: "user-device", DeviceName: "example.com/nic"},
+			}
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:01:00.0"]}`,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			previous := vm.DeepCopy()
+			for k, v := range result.Annotations {
+				previous.Annotations[k] = v
+			}
+			delete(vm.Annotations, utils.AnnotationPciPassthrough)
+
+			Expect(feature.Revert(ctx, vm, previous)).To(Succeed())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.HostDevices).To(ConsistOf(
+				kubevirtv1.HostDevice{Name: "user-device", DeviceName: "example.com/nic"},
+			))
+			Expect(vm.Spec.Template.Spec.NodeSelector).To(BeEmpty())
+		})
+
+		It("should remove devices added by earlier updates", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationPciPassthrough: `{"devices": ["0000:01:00.0"]}`,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			for k, v := range result.Annotations {
+				vm.Annotations[k] = v
+			}
+
+			vm.Annotations[utils.AnnotationPciPassthrough] = `{"devices": ["0000:01:00.0", "0000:02:00.0"]}`
+			result, err = feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Annotations[utils.AnnotationPciPassthroughApplied]).To(Equal(`["0000:01:00.0","0000:02:00.0"]`))
+
+			previous := vm.DeepCopy()
+			for k, v := range result.Annotations {
+				previous.Annotations[k] = v
+			}
+			delete(vm.Annotations, utils.AnnotationPciPassthrough)
+
+			Expect(feature.Revert(ctx, vm, previous)).To(Succeed())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.HostDevices).To(BeEmpty())
+		})
+	})
 })
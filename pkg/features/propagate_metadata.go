@@ -0,0 +1,182 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// featureManagerKeyPrefix prefixes the webhook's own keys, which are never propagated
+const featureManagerKeyPrefix = utils.DefaultKeyPrefix
+
+// propagatedKeys lists the template keys PropagateMetadata copied, recorded
+// as JSON in its tracking annotation so later admissions can update or remove
+// them without touching keys the user set on the template
+type propagatedKeys struct {
+	Labels      []string `json:"labels,omitempty"`
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// PropagateMetadata implements propagation of VM labels and annotations onto the
+// VMI template, so the virt-launcher pod inherits e.g. cost-allocation and
+// monitoring labels. Only keys matching the configured prefixes are copied.
+type PropagateMetadata struct {
+	config       *config.MetadataPropagationConfig
+	configSource utils.ConfigSource
+}
+
+// NewPropagateMetadata creates a new PropagateMetadata feature
+func NewPropagateMetadata(cfg *config.MetadataPropagationConfig, configSource utils.ConfigSource) *PropagateMetadata {
+	return &PropagateMetadata{
+		config:       cfg,
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *PropagateMetadata) Name() string {
+	return utils.FeaturePropagateMetadata
+}
+
+// Config returns the feature settings
+func (f *PropagateMetadata) Config() interface{} {
+	return f.config
+}
+
+// IsEnabled checks if metadata propagation is requested via annotations or labels
+func (f *PropagateMetadata) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	if !f.config.Enabled {
+		return false
+	}
+
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationPropagateMetadata)
+	return exists && utils.IsTruthyValue(value)
+}
+
+// Validate performs validation of the metadata propagation configuration
+func (f *PropagateMetadata) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationPropagateMetadata)
+	if !exists {
+		return nil
+	}
+
+	if !utils.IsTruthyValue(value) && strings.ToLower(value) != "false" && strings.ToLower(value) != "disabled" {
+		return fmt.Errorf("invalid value for %s: %s (expected 'enabled', 'true', 'yes', '1', 'disabled' or 'false')",
+			utils.AnnotationPropagateMetadata, value)
+	}
+
+	return nil
+}
+
+// Apply copies matching VM labels and annotations onto the template metadata
+func (f *PropagateMetadata) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	recorded := recordedPropagation(vm)
+	template := &vm.Spec.Template.ObjectMeta
+	var propagated propagatedKeys
+	template.Labels, propagated.Labels = propagateMatching(template.Labels, vm.GetLabels(), f.config.LabelPrefixes, recorded.Labels)
+	template.Annotations, propagated.Annotations = propagateMatching(template.Annotations, vm.GetAnnotations(), f.config.AnnotationPrefixes, recorded.Annotations)
+	labels, annotations := len(propagated.Labels), len(propagated.Annotations)
+
+	logger.Info("Applying metadata propagation", "vm", vm.Name, "labels", labels, "annotations", annotations)
+
+	encoded, err := json.Marshal(propagated)
+	if err != nil {
+		return result, err
+	}
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationPropagateMetadataApplied, string(encoded))
+	result.AddMessage(fmt.Sprintf("Propagated %d labels and %d annotations to the VMI template", labels, annotations))
+
+	return result, nil
+}
+
+// Revert removes the template labels and annotations recorded in the
+// previous VM's tracking annotation
+func (f *PropagateMetadata) Revert(_ context.Context, vm, previous *kubevirtv1.VirtualMachine) error {
+	if vm.Spec.Template == nil {
+		return nil
+	}
+
+	recorded := recordedPropagation(previous)
+	template := &vm.Spec.Template.ObjectMeta
+	template.Labels, _ = propagateMatching(template.Labels, nil, nil, recorded.Labels)
+	template.Annotations, _ = propagateMatching(template.Annotations, nil, nil, recorded.Annotations)
+	return nil
+}
+
+// recordedPropagation returns the keys recorded as propagated on the VM. A
+// missing or unreadable record, e.g. the count older versions wrote, owns no keys.
+func recordedPropagation(vm *kubevirtv1.VirtualMachine) propagatedKeys {
+	var keys propagatedKeys
+	if recorded := vm.GetAnnotations()[utils.AnnotationPropagateMetadataApplied]; recorded != "" {
+		_ = json.Unmarshal([]byte(recorded), &keys)
+	}
+	return keys
+}
+
+// propagateMatching copies entries of src whose keys match a prefix into dst.
+// Values the user set in dst are kept, while owned keys, propagated by an
+// earlier admission, are updated, or removed once src no longer provides
+// them. Returns the updated map and the propagated keys, sorted.
+func propagateMatching(dst, src map[string]string, prefixes, owned []string) (map[string]string, []string) {
+	var propagated []string
+	for key, value := range src {
+		if strings.HasPrefix(key, featureManagerKeyPrefix) || !hasAnyPrefix(key, prefixes) {
+			continue
+		}
+		if _, exists := dst[key]; exists && !slices.Contains(owned, key) {
+			continue
+		}
+		if dst == nil {
+			dst = make(map[string]string)
+		}
+		dst[key] = value
+		propagated = append(propagated, key)
+	}
+
+	for _, key := range owned {
+		if !slices.Contains(propagated, key) {
+			delete(dst, key)
+		}
+	}
+
+	sort.Strings(propagated)
+	return dst, propagated
+}
+
+// hasAnyPrefix reports whether key starts with one of the non-empty prefixes
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSpace(prefix)
+		if prefix != "" && strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("PropagateMetadata", func() {
+	var (
+		feature *features.PropagateMetadata
+		cfg     *config.MetadataPropagationConfig
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		cfg = &config.MetadataPropagationConfig{
+			Enabled:            true,
+			LabelPrefixes:      []string{"cost-center", "app.kubernetes.io/"},
+			AnnotationPrefixes: []string{"prometheus.io/"},
+		}
+		feature = features.NewPropagateMetadata(cfg, utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+				Labels: map[string]string{
+					"cost-center":               "1234",
+					"app.kubernetes.io/name":    "db",
+					"unrelated":                 "value",
+					"vm-feature-manager.io/tpm": "enabled",
+				},
+				Annotations: map[string]string{
+					utils.AnnotationPropagateMetadata: "enabled",
+					"prometheus.io/scrape":            "true",
+					"description":                     "database VM",
+				},
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeaturePropagateMetadata))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return true when annotation is enabled", func() {
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return false when annotation is not present", func() {
+			delete(vm.Annotations, utils.AnnotationPropagateMetadata)
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return false when the feature is disabled in config", func() {
+			cfg.Enabled = false
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should reject invalid values", func() {
+			vm.Annotations[utils.AnnotationPropagateMetadata] = "sometimes"
+			Expect(feature.Validate(ctx, vm, nil)).To(MatchError(ContainSubstring("'disabled' or 'false'")))
+		})
+
+		It("should accept false", func() {
+			vm.Annotations[utils.AnnotationPropagateMetadata] = "false"
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should copy matching labels and annotations to the template", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationPropagateMetadataApplied,
+				`{"labels":["app.kubernetes.io/name","cost-center"],"annotations":["prometheus.io/scrape"]}`))
+
+			template := vm.Spec.Template.ObjectMeta
+			Expect(template.Labels).To(HaveKeyWithValue("cost-center", "1234"))
+			Expect(template.Labels).To(HaveKeyWithValue("app.kubernetes.io/name", "db"))
+			Expect(template.Labels).ToNot(HaveKey("unrelated"))
+			Expect(template.Annotations).To(HaveKeyWithValue("prometheus.io/scrape", "true"))
+			Expect(template.Annotations).ToNot(HaveKey("description"))
+		})
+
+		It("should never propagate the webhook's own keys", func() {
+			cfg.LabelPrefixes = []string{"vm-feature-manager.io/"}
+			cfg.AnnotationPrefixes = []string{"vm-feature-manager.io/"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.ObjectMeta.Labels).To(BeEmpty())
+			Expect(vm.Spec.Template.ObjectMeta.Annotations).To(BeEmpty())
+		})
+
+		It("should keep existing template values", func() {
+			vm.Spec.Template.ObjectMeta.Labels = map[string]string{"cost-center": "9999"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.ObjectMeta.Labels).To(HaveKeyWithValue("cost-center", "9999"))
+			Expect(result.Annotations[utils.AnnotationPropagateMetadataApplied]).ToNot(ContainSubstring("cost-center"))
+		})
+
+		It("should update and remove the keys it propagated before", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			vm.Annotations[utils.AnnotationPropagateMetadataApplied] = result.Annotations[utils.AnnotationPropagateMetadataApplied]
+
+			vm.Labels["cost-center"] = "5678"
+			delete(vm.Labels, "app.kubernetes.io/name")
+			delete(vm.Annotations, "prometheus.io/scrape")
+			vm.Spec.Template.ObjectMeta.Labels["team"] = "storage"
+			_, err = feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			template := vm.Spec.Template.ObjectMeta
+			Expect(template.Labels).To(Equal(map[string]string{"cost-center": "5678", "team": "storage"}))
+			Expect(template.Annotations).ToNot(HaveKey("prometheus.io/scrape"))
+		})
+	})
+
+	Describe("Revert", func() {
+		It("should remove only the keys it propagated", func() {
+			vm.Spec.Template.ObjectMeta.Labels = map[string]string{"team": "storage"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			vm.Annotations[utils.AnnotationPropagateMetadataApplied] = result.Annotations[utils.AnnotationPropagateMetadataApplied]
+
+			previous := vm.DeepCopy()
+			delete(vm.Annotations, utils.AnnotationPropagateMetadata)
+			Expect(feature.Revert(ctx, vm, previous)).To(Succeed())
+
+			template := vm.Spec.Template.ObjectMeta
+			Expect(template.Labels).To(Equal(map[string]string{"team": "storage"}))
+			Expect(template.Annotations).To(BeEmpty())
+		})
+	})
+})
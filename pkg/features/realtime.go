@@ -0,0 +1,116 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// vcpuMaskRegex validates realtime vCPU masks.
+// Format: comma-separated vCPU ids or ranges, optionally excluded with ^ (e.g., 0-3,^1)
+var vcpuMaskRegex = regexp.MustCompile(`^\^?\d+(-\d+)?(,\^?\d+(-\d+)?)*$`)
+
+// Realtime implements the real-time workload feature.
+// It enables the realtime CPU tuning of the VM together with the
+// dedicated CPU placement KubeVirt requires for it.
+type Realtime struct {
+	configSource utils.ConfigSource
+}
+
+// NewRealtime creates a new Realtime feature
+func NewRealtime(configSource utils.ConfigSource) *Realtime {
+	return &Realtime{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *Realtime) Name() string {
+	return utils.FeatureRealtime
+}
+
+// Dependencies runs realtime tuning after the features that place CPUs
+func (f *Realtime) Dependencies() []Dependency {
+	return []Dependency{
+		{Feature: utils.FeatureDedicatedCPUs},
+		{Feature: utils.FeatureGuaranteedQoS},
+	}
+}
+
+// IsEnabled checks if realtime is requested via annotations or labels.
+// The value is either a truthy value or a vCPU mask.
+func (f *Realtime) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationRealtime)
+	return exists && (utils.IsTruthyValue(value) || vcpuMaskRegex.MatchString(value))
+}
+
+// Validate ensures the value is either enabled or a valid vCPU mask
+func (f *Realtime) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationRealtime)
+	if !exists {
+		return nil
+	}
+
+	if !utils.IsTruthyValue(value) && !vcpuMaskRegex.MatchString(value) {
+		return fmt.Errorf("invalid value for %s: %s (expected 'enabled' or a vCPU mask such as '0-3,^1')",
+			utils.AnnotationRealtime, value)
+	}
+
+	return nil
+}
+
+// Apply enables realtime and dedicated CPU placement on the VM template
+func (f *Realtime) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationRealtime)
+	mask := ""
+	if !utils.IsTruthyValue(value) {
+		mask = value
+	}
+
+	logger.Info("Applying realtime feature", "vm", vm.Name, "mask", mask)
+
+	domain := &vm.Spec.Template.Spec.Domain
+	if domain.CPU == nil {
+		domain.CPU = &kubevirtv1.CPU{}
+	}
+	if domain.CPU.Realtime == nil {
+		domain.CPU.Realtime = &kubevirtv1.Realtime{}
+	}
+	if mask != "" {
+		domain.CPU.Realtime.Mask = mask
+	}
+	domain.CPU.DedicatedCPUPlacement = true
+
+	result.Applied = true
+	if mask != "" {
+		result.AddAnnotation(utils.AnnotationRealtimeApplied, mask)
+		result.AddMessage(fmt.Sprintf("Enabled realtime for vCPUs %s", mask))
+	} else {
+		result.AddAnnotation(utils.AnnotationRealtimeApplied, "true")
+		result.AddMessage("Enabled realtime for all vCPUs")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Realtime", func() {
+	var (
+		feature *features.Realtime
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewRealtime(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureRealtime))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true for enabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationRealtime: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return true for a vCPU mask", func() {
+			vm.Annotations = map[string]string{utils.AnnotationRealtime: "0-3,^1"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept valid masks", func() {
+			for _, mask := range []string{"0", "0-3", "0-3,^1", "1,2,5-7"} {
+				vm.Annotations = map[string]string{utils.AnnotationRealtime: mask}
+				Expect(feature.Validate(ctx, vm, nil)).To(Succeed(), mask)
+			}
+		})
+
+		It("should reject invalid masks", func() {
+			for _, mask := range []string{"a-b", "0-", ",1", "0;1"} {
+				vm.Annotations = map[string]string{utils.AnnotationRealtime: mask}
+				Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed(), mask)
+			}
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should not modify the VM when not enabled", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.CPU).To(BeNil())
+		})
+
+		It("should enable realtime for all vCPUs", func() {
+			vm.Annotations = map[string]string{utils.AnnotationRealtime: "enabled"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationRealtimeApplied, "true"))
+
+			cpu := vm.Spec.Template.Spec.Domain.CPU
+			Expect(cpu.Realtime).ToNot(BeNil())
+			Expect(cpu.Realtime.Mask).To(BeEmpty())
+			Expect(cpu.DedicatedCPUPlacement).To(BeTrue())
+		})
+
+		It("should set the vCPU mask", func() {
+			vm.Annotations = map[string]string{utils.AnnotationRealtime: "0-3,^1"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationRealtimeApplied, "0-3,^1"))
+			Expect(vm.Spec.Template.Spec.Domain.CPU.Realtime.Mask).To(Equal("0-3,^1"))
+		})
+
+		It("should return error when VM template is nil", func() {
+			vm.Spec.Template = nil
+			vm.Annotations = map[string]string{utils.AnnotationRealtime: "enabled"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("template is nil"))
+		})
+	})
+})
@@ -0,0 +1,197 @@
+package features
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// FeatureState reports whether a registered feature is enabled, with its
+// settings for features that have them
+type FeatureState struct {
+	Name    string      `json:"name"`
+	Enabled bool        `json:"enabled"`
+	Config  interface{} `json:"config,omitempty"`
+}
+
+// Registry holds the features in dependency order and lets them be disabled
+// at runtime. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	features []Feature
+	disabled map[string]bool
+}
+
+// NewRegistry creates a registry of the given features, all enabled
+func NewRegistry(featureList []Feature) (*Registry, error) {
+	r := &Registry{disabled: make(map[string]bool)}
+	if err := r.Register(featureList...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register adds features, keeping prerequisites ahead of the features that
+// build on them. Feature names must be unique.
+func (r *Registry) Register(featureList ...Feature) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	combined := append(append([]Feature{}, r.features...), featureList...)
+	seen := make(map[string]bool, len(combined))
+	for _, feature := range combined {
+		if seen[feature.Name()] {
+			return fmt.Errorf("feature %s is registered twice", feature.Name())
+		}
+		seen[feature.Name()] = true
+	}
+
+	sorted, err := SortByDependencies(combined)
+	if err != nil {
+		return err
+	}
+	r.features = sorted
+	return nil
+}
+
+// Replace swaps registered features for the given ones of the same name,
+// e.g. built-in features recreated from a reloaded configuration. Runtime
+// states are kept.
+func (r *Registry) Replace(featureList ...Feature) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	registered := make(map[string]bool, len(r.features))
+	for _, feature := range r.features {
+		registered[feature.Name()] = true
+	}
+	replacements := make(map[string]Feature, len(featureList))
+	for _, feature := range featureList {
+		if !registered[feature.Name()] {
+			return fmt.Errorf("feature %s is not registered", feature.Name())
+		}
+		replacements[feature.Name()] = feature
+	}
+
+	replaced := make([]Feature, 0, len(r.features))
+	for _, feature := range r.features {
+		if replacement, ok := replacements[feature.Name()]; ok {
+			feature = replacement
+		}
+		replaced = append(replaced, feature)
+	}
+
+	sorted, err := SortByDependencies(replaced)
+	if err != nil {
+		return err
+	}
+	r.features = sorted
+	return nil
+}
+
+// Enabled returns the enabled features in application order
+func (r *Registry) Enabled() []Feature {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	enabled := make([]Feature, 0, len(r.features))
+	for _, feature := range r.features {
+		if !r.disabled[feature.Name()] {
+			enabled = append(enabled, feature)
+		}
+	}
+	return enabled
+}
+
+// States returns every registered feature with its runtime state
+func (r *Registry) States() []FeatureState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	states := make([]FeatureState, 0, len(r.features))
+	for _, feature := range r.features {
+		state := FeatureState{Name: feature.Name(), Enabled: !r.disabled[feature.Name()]}
+		if configured, ok := feature.(Configured); ok {
+			state.Config = configured.Config()
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// SetStates replaces the runtime overrides: features set to false are
+// disabled and all others enabled. Names that aren't registered are returned.
+func (r *Registry) SetStates(states map[string]bool) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	registered := make(map[string]bool, len(r.features))
+	for _, feature := range r.features {
+		registered[feature.Name()] = true
+	}
+
+	disabled := make(map[string]bool)
+	var unknown []string
+	for name, enabled := range states {
+		if !registered[name] {
+			unknown = append(unknown, name)
+			continue
+		}
+		if !enabled {
+			disabled[name] = true
+		}
+	}
+	r.disabled = disabled
+	sort.Strings(unknown)
+	return unknown
+}
+
+// WatchStateFile applies the feature states in the YAML file at path
+// (feature name to enabled), re-reading it every interval until ctx is done.
+// A missing file enables every feature; an invalid one keeps the current states.
+func (r *Registry) WatchStateFile(ctx context.Context, path string, interval time.Duration) {
+	logger := log.FromContext(ctx).WithValues("path", path)
+
+	var last []byte
+	loaded := false
+	load := func() {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Error(err, "Failed to read feature state file")
+			return
+		}
+		if loaded && bytes.Equal(data, last) {
+			return
+		}
+
+		states := map[string]bool{}
+		if err := yaml.UnmarshalStrict(data, &states); err != nil {
+			logger.Error(err, "Invalid feature state file, keeping current feature states")
+			return
+		}
+		last, loaded = data, true
+		if unknown := r.SetStates(states); len(unknown) > 0 {
+			logger.Info("Feature state file names unknown features", "features", unknown)
+		}
+		logger.Info("Feature states loaded", "states", states)
+	}
+
+	load()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			load()
+		}
+	}
+}
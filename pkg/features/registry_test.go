@@ -0,0 +1,163 @@
+package features_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Registry", func() {
+	var registry *features.Registry
+
+	BeforeEach(func() {
+		var err error
+		registry, err = features.NewRegistry([]features.Feature{
+			&dependentFeature{name: "a", deps: []features.Dependency{{Feature: "c"}}},
+			&dependentFeature{name: "b"},
+			&dependentFeature{name: "c"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should enable every feature in dependency order", func() {
+		Expect(featureNames(registry.Enabled())).To(Equal([]string{"b", "c", "a"}))
+		Expect(registry.States()).To(Equal([]features.FeatureState{
+			{Name: "b", Enabled: true},
+			{Name: "c", Enabled: true},
+			{Name: "a", Enabled: true},
+		}))
+	})
+
+	It("should register all built-in features", func() {
+		builtin, err := features.NewRegistry(features.Builtin(&config.Config{ConfigSource: utils.ConfigSourceAnnotations}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(builtin.Enabled()).ToNot(BeEmpty())
+	})
+
+	It("should report the settings of configured features", func() {
+		cfg := &config.Config{ConfigSource: utils.ConfigSourceAnnotations}
+		builtin, err := features.NewRegistry(features.Builtin(cfg))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(builtin.States()).To(ContainElements(
+			features.FeatureState{Name: utils.FeatureNestedVirt, Enabled: true, Config: &cfg.Features.NestedVirtualization},
+			features.FeatureState{Name: utils.FeatureTpm, Enabled: true},
+		))
+	})
+
+	Describe("Register", func() {
+		It("should keep dependency order across registrations", func() {
+			Expect(registry.Register(&dependentFeature{name: "d", deps: []features.Dependency{{Feature: "e"}}}, &dependentFeature{name: "e"})).To(Succeed())
+			Expect(featureNames(registry.Enabled())).To(Equal([]string{"b", "c", "a", "e", "d"}))
+		})
+
+		It("should reject duplicate names", func() {
+			Expect(registry.Register(&dependentFeature{name: "b"})).To(MatchError("feature b is registered twice"))
+			Expect(registry.Enabled()).To(HaveLen(3))
+		})
+
+		It("should reject dependency cycles", func() {
+			err := registry.Register(
+				&dependentFeature{name: "d", deps: []features.Dependency{{Feature: "e"}}},
+				&dependentFeature{name: "e", deps: []features.Dependency{{Feature: "d"}}},
+			)
+			Expect(err).To(HaveOccurred())
+			Expect(registry.Enabled()).To(HaveLen(3))
+		})
+	})
+
+	Describe("Replace", func() {
+		It("should swap features of the same name and keep their states", func() {
+			registry.SetStates(map[string]bool{"b": false})
+			replacement := &dependentFeature{name: "b"}
+
+			Expect(registry.Replace(replacement)).To(Succeed())
+			Expect(registry.States()).To(ContainElement(features.FeatureState{Name: "b", Enabled: false}))
+			registry.SetStates(map[string]bool{})
+			Expect(registry.Enabled()).To(ContainElement(BeIdenticalTo(replacement)))
+		})
+
+		It("should re-sort on changed dependencies", func() {
+			Expect(registry.Replace(&dependentFeature{name: "b", deps: []features.Dependency{{Feature: "a"}}})).To(Succeed())
+			Expect(featureNames(registry.Enabled())).To(Equal([]string{"c", "a", "b"}))
+		})
+
+		It("should reject features that aren't registered", func() {
+			Expect(registry.Replace(&dependentFeature{name: "z"})).To(MatchError("feature z is not registered"))
+		})
+	})
+
+	Describe("SetStates", func() {
+		It("should disable features set to false", func() {
+			Expect(registry.SetStates(map[string]bool{"c": false, "b": true})).To(BeEmpty())
+			Expect(featureNames(registry.Enabled())).To(Equal([]string{"b", "a"}))
+			Expect(registry.States()).To(ContainElement(features.FeatureState{Name: "c", Enabled: false}))
+		})
+
+		It("should re-enable features left out of later states", func() {
+			registry.SetStates(map[string]bool{"c": false})
+			registry.SetStates(map[string]bool{})
+			Expect(registry.Enabled()).To(HaveLen(3))
+		})
+
+		It("should return unknown feature names", func() {
+			Expect(registry.SetStates(map[string]bool{"z": false, "y": true, "a": false})).To(Equal([]string{"y", "z"}))
+			Expect(featureNames(registry.Enabled())).To(Equal([]string{"b", "c"}))
+		})
+	})
+
+	Describe("WatchStateFile", func() {
+		var (
+			path   string
+			cancel context.CancelFunc
+		)
+
+		BeforeEach(func() {
+			path = filepath.Join(GinkgoT().TempDir(), "feature-states.yaml")
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		watch := func() {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go registry.WatchStateFile(ctx, path, 10*time.Millisecond)
+		}
+
+		It("should apply the file and follow its changes", func() {
+			Expect(os.WriteFile(path, []byte("c: false\n"), 0o644)).To(Succeed())
+			watch()
+			Eventually(func() []string { return featureNames(registry.Enabled()) }).Should(Equal([]string{"b", "a"}))
+
+			Expect(os.WriteFile(path, []byte("c: true\nb: false\n"), 0o644)).To(Succeed())
+			Eventually(func() []string { return featureNames(registry.Enabled()) }).Should(Equal([]string{"c", "a"}))
+		})
+
+		It("should enable every feature when the file is removed", func() {
+			Expect(os.WriteFile(path, []byte("c: false\n"), 0o644)).To(Succeed())
+			watch()
+			Eventually(func() []features.Feature { return registry.Enabled() }).Should(HaveLen(2))
+
+			Expect(os.Remove(path)).To(Succeed())
+			Eventually(func() []features.Feature { return registry.Enabled() }).Should(HaveLen(3))
+		})
+
+		It("should keep the current states when the file is invalid", func() {
+			Expect(os.WriteFile(path, []byte("c: false\n"), 0o644)).To(Succeed())
+			watch()
+			Eventually(func() []features.Feature { return registry.Enabled() }).Should(HaveLen(2))
+
+			Expect(os.WriteFile(path, []byte("c: maybe\n"), 0o644)).To(Succeed())
+			Consistently(func() []string { return featureNames(registry.Enabled()) }, 100*time.Millisecond).Should(Equal([]string{"b", "a"}))
+		})
+	})
+})
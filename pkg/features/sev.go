@@ -0,0 +1,124 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// Sev implements the AMD SEV confidential computing feature.
+// It sets the SEV launch security of the VM, optionally with encrypted
+// state (SEV-ES), after checking the VM meets the SEV prerequisites.
+type Sev struct {
+	configSource utils.ConfigSource
+}
+
+// NewSev creates a new Sev feature
+func NewSev(configSource utils.ConfigSource) *Sev {
+	return &Sev{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *Sev) Name() string {
+	return utils.FeatureSev
+}
+
+// IsEnabled checks if SEV is requested via annotations or labels
+func (f *Sev) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSev)
+	return exists && (utils.IsTruthyValue(value) || isSevES(value))
+}
+
+// Validate checks the configured value and the SEV prerequisites:
+// an EFI bootloader without secure boot, and no host or GPU devices
+func (f *Sev) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSev)
+	if !exists {
+		return nil
+	}
+
+	if !utils.IsTruthyValue(value) && !isSevES(value) {
+		return fmt.Errorf("invalid value for %s: %s (expected 'enabled' or '%s')",
+			utils.AnnotationSev, value, utils.SevES)
+	}
+
+	if vm.Spec.Template == nil {
+		return fmt.Errorf("VM template is nil")
+	}
+
+	domain := vm.Spec.Template.Spec.Domain
+	if domain.Firmware == nil || domain.Firmware.Bootloader == nil || domain.Firmware.Bootloader.EFI == nil {
+		return fmt.Errorf("SEV requires an EFI bootloader")
+	}
+	if efi := domain.Firmware.Bootloader.EFI; efi.SecureBoot == nil || *efi.SecureBoot {
+		return fmt.Errorf("SEV requires EFI secure boot to be disabled")
+	}
+
+	if len(domain.Devices.HostDevices) > 0 {
+		return fmt.Errorf("SEV is not supported with host devices (%d configured)", len(domain.Devices.HostDevices))
+	}
+	if len(domain.Devices.GPUs) > 0 {
+		return fmt.Errorf("SEV is not supported with GPU devices (%d configured)", len(domain.Devices.GPUs))
+	}
+
+	return nil
+}
+
+// Apply sets the SEV launch security on the VM template
+func (f *Sev) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSev)
+	encryptedState := isSevES(value)
+
+	logger.Info("Applying SEV feature", "vm", vm.Name, "encryptedState", encryptedState)
+
+	domain := &vm.Spec.Template.Spec.Domain
+	if domain.LaunchSecurity == nil {
+		domain.LaunchSecurity = &kubevirtv1.LaunchSecurity{}
+	}
+	if domain.LaunchSecurity.SEV == nil {
+		domain.LaunchSecurity.SEV = &kubevirtv1.SEV{}
+	}
+	if encryptedState {
+		if domain.LaunchSecurity.SEV.Policy == nil {
+			domain.LaunchSecurity.SEV.Policy = &kubevirtv1.SEVPolicy{}
+		}
+		enabled := true
+		domain.LaunchSecurity.SEV.Policy.EncryptedState = &enabled
+	}
+
+	result.Applied = true
+	if encryptedState {
+		result.AddAnnotation(utils.AnnotationSevApplied, utils.SevES)
+		result.AddMessage("Enabled AMD SEV-ES launch security")
+	} else {
+		result.AddAnnotation(utils.AnnotationSevApplied, "sev")
+		result.AddMessage("Enabled AMD SEV launch security")
+	}
+
+	return result, nil
+}
+
+// isSevES reports whether the value requests SEV with encrypted state
+func isSevES(value string) bool {
+	return strings.EqualFold(value, utils.SevES)
+}
@@ -0,0 +1,169 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Sev", func() {
+	var (
+		feature *features.Sev
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewSev(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		secureBoot := false
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							Firmware: &kubevirtv1.Firmware{
+								Bootloader: &kubevirtv1.Bootloader{
+									EFI: &kubevirtv1.EFI{SecureBoot: &secureBoot},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureSev))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true for enabled and sev-es", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSev: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+
+			vm.Annotations[utils.AnnotationSev] = "sev-es"
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		Context("when using labels as config source", func() {
+			BeforeEach(func() {
+				feature = features.NewSev(utils.ConfigSourceLabels)
+			})
+
+			It("should return true when label is set", func() {
+				vm.Labels = map[string]string{utils.AnnotationSev: "enabled"}
+				Expect(feature.IsEnabled(vm)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		BeforeEach(func() {
+			vm.Annotations = map[string]string{utils.AnnotationSev: "enabled"}
+		})
+
+		It("should succeed for an EFI VM without secure boot", func() {
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject unknown values", func() {
+			vm.Annotations[utils.AnnotationSev] = "snp"
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid value"))
+		})
+
+		It("should reject VMs without an EFI bootloader", func() {
+			vm.Spec.Template.Spec.Domain.Firmware = nil
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("EFI bootloader"))
+		})
+
+		It("should reject VMs with secure boot enabled", func() {
+			vm.Spec.Template.Spec.Domain.Firmware.Bootloader.EFI.SecureBoot = nil
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("secure boot"))
+		})
+
+		It("should reject VMs with host devices", func() {
+			vm.Spec.Template.Spec.Domain.Devices.HostDevices = []kubevirtv1.HostDevice{
+				{Name: "hostdev0", DeviceName: "pci_0000_00_02_0"},
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("host devices"))
+		})
+
+		It("should reject VMs with GPUs", func() {
+			vm.Spec.Template.Spec.Domain.Devices.GPUs = []kubevirtv1.GPU{
+				{Name: "gpu0", DeviceName: "nvidia.com/gpu"},
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("GPU devices"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should not modify the VM when not enabled", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.LaunchSecurity).To(BeNil())
+		})
+
+		It("should set SEV launch security", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSev: "enabled"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationSevApplied, "sev"))
+
+			launchSecurity := vm.Spec.Template.Spec.Domain.LaunchSecurity
+			Expect(launchSecurity).ToNot(BeNil())
+			Expect(launchSecurity.SEV).ToNot(BeNil())
+			Expect(launchSecurity.SEV.Policy).To(BeNil())
+		})
+
+		It("should set encrypted state for sev-es", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSev: "sev-es"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationSevApplied, utils.SevES))
+
+			policy := vm.Spec.Template.Spec.Domain.LaunchSecurity.SEV.Policy
+			Expect(policy).ToNot(BeNil())
+			Expect(policy.EncryptedState).ToNot(BeNil())
+			Expect(*policy.EncryptedState).To(BeTrue())
+		})
+
+		It("should return error when prerequisites are not met", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSev: "enabled"}
+			vm.Spec.Template.Spec.Domain.Firmware = nil
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,158 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// smbiosMaxFieldLength bounds SMBIOS string values
+const smbiosMaxFieldLength = 64
+
+// SmbiosSpec defines the structure of the SMBIOS annotation.
+// Product and Family are only configurable cluster-wide in KubeVirt
+// (spec.configuration.smbios) and are rejected per VM.
+type SmbiosSpec struct {
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Product      string `json:"product,omitempty"`
+	Family       string `json:"family,omitempty"`
+	Version      string `json:"version,omitempty"`
+	Serial       string `json:"serial,omitempty"`
+	Sku          string `json:"sku,omitempty"`
+	Asset        string `json:"asset,omitempty"`
+}
+
+// Smbios implements SMBIOS / asset-tag injection.
+// The serial is set as the firmware serial, the remaining values on the chassis.
+type Smbios struct {
+	configSource utils.ConfigSource
+}
+
+// NewSmbios creates a new Smbios feature
+func NewSmbios(configSource utils.ConfigSource) *Smbios {
+	return &Smbios{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *Smbios) Name() string {
+	return utils.FeatureSmbios
+}
+
+// IsEnabled checks if SMBIOS values are requested via annotations or labels
+func (f *Smbios) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSmbios)
+	return exists && value != ""
+}
+
+// Validate performs validation of the SMBIOS configuration
+func (f *Smbios) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSmbios)
+	if !exists {
+		return nil
+	}
+
+	_, err := parseSmbios(value)
+	return err
+}
+
+// Apply sets the firmware serial and chassis fields on the VM template
+func (f *Smbios) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSmbios)
+	spec, _ := parseSmbios(value)
+
+	logger.Info("Applying SMBIOS values", "vm", vm.Name)
+
+	domain := &vm.Spec.Template.Spec.Domain
+	if spec.Serial != "" {
+		if domain.Firmware == nil {
+			domain.Firmware = &kubevirtv1.Firmware{}
+		}
+		domain.Firmware.Serial = spec.Serial
+	}
+
+	if spec.Manufacturer != "" || spec.Version != "" || spec.Sku != "" || spec.Asset != "" {
+		if domain.Chassis == nil {
+			domain.Chassis = &kubevirtv1.Chassis{}
+		}
+		if spec.Manufacturer != "" {
+			domain.Chassis.Manufacturer = spec.Manufacturer
+		}
+		if spec.Version != "" {
+			domain.Chassis.Version = spec.Version
+		}
+		if spec.Sku != "" {
+			domain.Chassis.Sku = spec.Sku
+		}
+		if spec.Asset != "" {
+			domain.Chassis.Asset = spec.Asset
+		}
+	}
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationSmbiosApplied, "true")
+	result.AddMessage("Injected SMBIOS values")
+
+	return result, nil
+}
+
+// parseSmbios parses and validates the SMBIOS annotation
+func parseSmbios(value string) (*SmbiosSpec, error) {
+	var spec SmbiosSpec
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationSmbios, err)
+	}
+
+	if spec.Product != "" || spec.Family != "" {
+		return nil, fmt.Errorf("SMBIOS product and family cannot be set per VM; configure them cluster-wide in the KubeVirt CR (spec.configuration.smbios)")
+	}
+
+	fields := []struct{ name, value string }{
+		{"manufacturer", spec.Manufacturer},
+		{"version", spec.Version},
+		{"serial", spec.Serial},
+		{"sku", spec.Sku},
+		{"asset", spec.Asset},
+	}
+	empty := true
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		empty = false
+		if len(field.value) > smbiosMaxFieldLength {
+			return nil, fmt.Errorf("SMBIOS %s exceeds %d characters", field.name, smbiosMaxFieldLength)
+		}
+	}
+	if empty {
+		return nil, fmt.Errorf("no SMBIOS values specified in %s", utils.AnnotationSmbios)
+	}
+
+	return &spec, nil
+}
@@ -0,0 +1,141 @@
+package features_test
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Smbios", func() {
+	var (
+		feature *features.Smbios
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewSmbios(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureSmbios))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSmbios: `{"serial": "ABC123"}`}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept supported fields", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationSmbios: `{"manufacturer": "Acme", "serial": "ABC123", "asset": "IT-0042"}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject malformed JSON", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSmbios: `{"serial": }`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid JSON"))
+		})
+
+		It("should reject unknown fields", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSmbios: `{"bios": "x"}`}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject product and family", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSmbios: `{"product": "Widget"}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cluster-wide"))
+		})
+
+		It("should reject empty values", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSmbios: `{}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no SMBIOS values"))
+		})
+
+		It("should reject values that are too long", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationSmbios: `{"serial": "` + strings.Repeat("x", 65) + `"}`,
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("serial exceeds"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should set the firmware serial", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSmbios: `{"serial": "ABC123"}`}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationSmbiosApplied, "true"))
+			Expect(vm.Spec.Template.Spec.Domain.Firmware.Serial).To(Equal("ABC123"))
+			Expect(vm.Spec.Template.Spec.Domain.Chassis).To(BeNil())
+		})
+
+		It("should set chassis fields", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationSmbios: `{"manufacturer": "Acme", "version": "2", "sku": "S1", "asset": "IT-0042"}`,
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			chassis := vm.Spec.Template.Spec.Domain.Chassis
+			Expect(chassis).ToNot(BeNil())
+			Expect(chassis.Manufacturer).To(Equal("Acme"))
+			Expect(chassis.Version).To(Equal("2"))
+			Expect(chassis.Sku).To(Equal("S1"))
+			Expect(chassis.Asset).To(Equal("IT-0042"))
+			Expect(vm.Spec.Template.Spec.Domain.Firmware).To(BeNil())
+		})
+
+		It("should preserve existing firmware settings", func() {
+			vm.Spec.Template.Spec.Domain.Firmware = &kubevirtv1.Firmware{
+				Bootloader: &kubevirtv1.Bootloader{EFI: &kubevirtv1.EFI{}},
+			}
+			vm.Annotations = map[string]string{utils.AnnotationSmbios: `{"serial": "ABC123"}`}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Domain.Firmware.Bootloader.EFI).ToNot(BeNil())
+			Expect(vm.Spec.Template.Spec.Domain.Firmware.Serial).To(Equal("ABC123"))
+		})
+	})
+})
@@ -0,0 +1,191 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// Guest user name validation (POSIX-style login names)
+var guestUserRegex = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+
+// sshPublicKeyPrefixes are the key type prefixes accepted in authorized_keys entries
+var sshPublicKeyPrefixes = []string{
+	"ssh-rsa ",
+	"ssh-dss ",
+	"ssh-ed25519 ",
+	"ecdsa-sha2-",
+	"sk-ssh-ed25519@openssh.com ",
+	"sk-ecdsa-sha2-",
+}
+
+// SSHKeys implements SSH public key injection through KubeVirt access credentials.
+// Keys are read from the named Secret and propagated by the QEMU guest agent.
+type SSHKeys struct {
+	configSource utils.ConfigSource
+}
+
+// NewSSHKeys creates a new SSHKeys feature
+func NewSSHKeys(configSource utils.ConfigSource) *SSHKeys {
+	return &SSHKeys{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *SSHKeys) Name() string {
+	return utils.FeatureSSHKeys
+}
+
+// IsEnabled checks if SSH key injection is requested via annotations or labels
+func (f *SSHKeys) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSSHKeys)
+	return exists && value != ""
+}
+
+// Validate ensures the Secret exists and contains SSH public keys
+func (f *SSHKeys) Validate(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) error {
+	secretName, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSSHKeys)
+	if !exists {
+		return nil
+	}
+
+	if secretName == "" {
+		return fmt.Errorf("empty Secret name in %s configuration key", utils.AnnotationSSHKeys)
+	}
+
+	if len(secretName) > 253 || !configMapNameRegex.MatchString(secretName) {
+		return fmt.Errorf("invalid Secret name format: %s (must be a valid DNS subdomain)", secretName)
+	}
+
+	if _, err := f.users(vm); err != nil {
+		return err
+	}
+
+	// Secret contents can only be checked when a client is available
+	if k8sClient == nil {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{
+		Namespace: vm.Namespace,
+		Name:      secretName,
+	}
+	if err := k8sClient.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("secret %s/%s not found", vm.Namespace, secretName)
+		}
+		return fmt.Errorf("failed to fetch secret %s/%s: %w", vm.Namespace, secretName, err)
+	}
+
+	for _, data := range secret.Data {
+		if containsSSHPublicKey(string(data)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("secret %s/%s does not contain any SSH public keys", vm.Namespace, secretName)
+}
+
+// Apply adds an access credential propagating the Secret's keys via the guest agent
+func (f *SSHKeys) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	secretName, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSSHKeys)
+	users, _ := f.users(vm)
+
+	spec := &vm.Spec.Template.Spec
+	for _, cred := range spec.AccessCredentials {
+		if cred.SSHPublicKey != nil && cred.SSHPublicKey.Source.Secret != nil &&
+			cred.SSHPublicKey.Source.Secret.SecretName == secretName {
+			logger.Info("Access credential for Secret already present, skipping", "vm", vm.Name, "secret", secretName)
+			return result, nil
+		}
+	}
+
+	logger.Info("Applying SSH key injection", "vm", vm.Name, "secret", secretName, "users", users)
+
+	spec.AccessCredentials = append(spec.AccessCredentials, kubevirtv1.AccessCredential{
+		SSHPublicKey: &kubevirtv1.SSHPublicKeyAccessCredential{
+			Source: kubevirtv1.SSHPublicKeyAccessCredentialSource{
+				Secret: &kubevirtv1.AccessCredentialSecretSource{
+					SecretName: secretName,
+				},
+			},
+			PropagationMethod: kubevirtv1.SSHPublicKeyAccessCredentialPropagationMethod{
+				QemuGuestAgent: &kubevirtv1.QemuGuestAgentSSHPublicKeyAccessCredentialPropagation{
+					Users: users,
+				},
+			},
+		},
+	})
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationSSHKeysApplied, secretName)
+	result.AddMessage(fmt.Sprintf("Configured SSH keys from Secret %s for users %s", secretName, strings.Join(users, ",")))
+
+	return result, nil
+}
+
+// users returns the guest users that receive the keys, defaulting to root
+func (f *SSHKeys) users(vm *kubevirtv1.VirtualMachine) ([]string, error) {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSSHKeysUsers)
+	if !exists || strings.TrimSpace(value) == "" {
+		return []string{utils.DefaultSSHKeysUser}, nil
+	}
+
+	var users []string
+	for _, user := range strings.Split(value, ",") {
+		user = strings.TrimSpace(user)
+		if user == "" {
+			continue
+		}
+		if !guestUserRegex.MatchString(user) {
+			return nil, fmt.Errorf("invalid guest user %q in %s", user, utils.AnnotationSSHKeysUsers)
+		}
+		users = append(users, user)
+	}
+
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no guest users in %s", utils.AnnotationSSHKeysUsers)
+	}
+
+	return users, nil
+}
+
+// containsSSHPublicKey reports whether the data has at least one authorized_keys entry
+func containsSSHPublicKey(data string) bool {
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		for _, prefix := range sshPublicKeyPrefixes {
+			if strings.HasPrefix(line, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
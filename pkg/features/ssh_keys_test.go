@@ -0,0 +1,160 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("SSHKeys", func() {
+	var (
+		feature    *features.SSHKeys
+		vm         *kubevirtv1.VirtualMachine
+		ctx        context.Context
+		fakeClient client.Client
+	)
+
+	BeforeEach(func() {
+		feature = features.NewSSHKeys(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		_ = corev1.AddToScheme(scheme)
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-keys", Namespace: "default"},
+				Data: map[string][]byte{
+					"key1": []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExample user@host\n"),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "not-keys", Namespace: "default"},
+				Data: map[string][]byte{
+					"password": []byte("hunter2"),
+				},
+			},
+		).Build()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureSSHKeys))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSSHKeys: "my-keys"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept a Secret with public keys", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSSHKeys: "my-keys"}
+			Expect(feature.Validate(ctx, vm, fakeClient)).To(Succeed())
+		})
+
+		It("should reject a missing Secret", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSSHKeys: "missing"}
+			err := feature.Validate(ctx, vm, fakeClient)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not found"))
+		})
+
+		It("should reject a Secret without public keys", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSSHKeys: "not-keys"}
+			err := feature.Validate(ctx, vm, fakeClient)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("does not contain any SSH public keys"))
+		})
+
+		It("should reject an invalid Secret name", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSSHKeys: "My_Keys"}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject invalid user names", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationSSHKeys:      "my-keys",
+				utils.AnnotationSSHKeysUsers: "fedora,Bad User",
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid guest user"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should add a guest agent access credential for root by default", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSSHKeys: "my-keys"}
+			result, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationSSHKeysApplied, "my-keys"))
+
+			creds := vm.Spec.Template.Spec.AccessCredentials
+			Expect(creds).To(HaveLen(1))
+			Expect(creds[0].SSHPublicKey.Source.Secret.SecretName).To(Equal("my-keys"))
+			Expect(creds[0].SSHPublicKey.PropagationMethod.QemuGuestAgent.Users).To(Equal([]string{"root"}))
+		})
+
+		It("should use the configured users", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationSSHKeys:      "my-keys",
+				utils.AnnotationSSHKeysUsers: "fedora, admin",
+			}
+			_, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+
+			creds := vm.Spec.Template.Spec.AccessCredentials
+			Expect(creds[0].SSHPublicKey.PropagationMethod.QemuGuestAgent.Users).To(Equal([]string{"fedora", "admin"}))
+		})
+
+		It("should not duplicate an existing credential for the same Secret", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSSHKeys: "my-keys"}
+			_, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+
+			result, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.AccessCredentials).To(HaveLen(1))
+		})
+
+		It("should fail when the Secret has no keys", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSSHKeys: "not-keys"}
+			_, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).To(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.AccessCredentials).To(BeEmpty())
+		})
+	})
+})
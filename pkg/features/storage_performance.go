@@ -0,0 +1,103 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// StoragePerformance implements storage performance tuning.
+// It sets the IO threads policy and block multi-queue of the VM,
+// each controlled by its own annotation.
+type StoragePerformance struct {
+	configSource utils.ConfigSource
+}
+
+// NewStoragePerformance creates a new StoragePerformance feature
+func NewStoragePerformance(configSource utils.ConfigSource) *StoragePerformance {
+	return &StoragePerformance{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *StoragePerformance) Name() string {
+	return utils.FeatureStoragePerformance
+}
+
+// IsEnabled checks if IO threads or block multi-queue are requested via annotations or labels
+func (f *StoragePerformance) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	ioThreads, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationIOThreads)
+	multiQueue, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationBlockMultiQueue)
+	return ioThreads != "" || utils.IsTruthyValue(multiQueue)
+}
+
+// Validate ensures the IO threads policy and block multi-queue values are supported
+func (f *StoragePerformance) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	if ioThreads, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationIOThreads); exists {
+		switch kubevirtv1.IOThreadsPolicy(ioThreads) {
+		case kubevirtv1.IOThreadsPolicyAuto, kubevirtv1.IOThreadsPolicyShared:
+		default:
+			return fmt.Errorf("invalid value for %s: %s (expected '%s' or '%s')",
+				utils.AnnotationIOThreads, ioThreads, kubevirtv1.IOThreadsPolicyAuto, kubevirtv1.IOThreadsPolicyShared)
+		}
+	}
+
+	if multiQueue, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationBlockMultiQueue); exists {
+		if multiQueue != "enabled" {
+			return fmt.Errorf("invalid value for %s: %s (expected 'enabled')",
+				utils.AnnotationBlockMultiQueue, multiQueue)
+		}
+	}
+
+	return nil
+}
+
+// Apply sets the IO threads policy and block multi-queue on the VM template
+func (f *StoragePerformance) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	domain := &vm.Spec.Template.Spec.Domain
+	var applied []string
+
+	if ioThreads, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationIOThreads); ioThreads != "" {
+		policy := kubevirtv1.IOThreadsPolicy(ioThreads)
+		domain.IOThreadsPolicy = &policy
+		applied = append(applied, "io-threads="+ioThreads)
+		result.AddMessage(fmt.Sprintf("Set IO threads policy to %s", ioThreads))
+	}
+
+	if multiQueue, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationBlockMultiQueue); utils.IsTruthyValue(multiQueue) {
+		enabled := true
+		domain.Devices.BlockMultiQueue = &enabled
+		applied = append(applied, "block-multiqueue")
+		result.AddMessage("Enabled block multi-queue")
+	}
+
+	logger.Info("Applied storage performance tuning", "vm", vm.Name, "settings", applied)
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationStoragePerformanceApplied, strings.Join(applied, ","))
+
+	return result, nil
+}
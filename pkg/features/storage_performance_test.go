@@ -0,0 +1,122 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("StoragePerformance", func() {
+	var (
+		feature *features.StoragePerformance
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewStoragePerformance(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureStoragePerformance))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when no annotation is present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when io-threads is set", func() {
+			vm.Annotations = map[string]string{utils.AnnotationIOThreads: "auto"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return true when block-multiqueue is enabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBlockMultiQueue: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept auto and shared", func() {
+			for _, policy := range []string{"auto", "shared"} {
+				vm.Annotations = map[string]string{utils.AnnotationIOThreads: policy}
+				Expect(feature.Validate(ctx, vm, nil)).To(Succeed(), policy)
+			}
+		})
+
+		It("should reject unknown IO threads policies", func() {
+			vm.Annotations = map[string]string{utils.AnnotationIOThreads: "dedicated"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(utils.AnnotationIOThreads))
+		})
+
+		It("should reject invalid block-multiqueue values", func() {
+			vm.Annotations = map[string]string{utils.AnnotationBlockMultiQueue: "on"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(utils.AnnotationBlockMultiQueue))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should not modify the VM when not enabled", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.IOThreadsPolicy).To(BeNil())
+		})
+
+		It("should set the IO threads policy", func() {
+			vm.Annotations = map[string]string{utils.AnnotationIOThreads: "shared"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(*vm.Spec.Template.Spec.Domain.IOThreadsPolicy).To(Equal(kubevirtv1.IOThreadsPolicyShared))
+			Expect(vm.Spec.Template.Spec.Domain.Devices.BlockMultiQueue).To(BeNil())
+		})
+
+		It("should set both settings", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationIOThreads:       "auto",
+				utils.AnnotationBlockMultiQueue: "enabled",
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationStoragePerformanceApplied, "io-threads=auto,block-multiqueue"))
+			Expect(*vm.Spec.Template.Spec.Domain.IOThreadsPolicy).To(Equal(kubevirtv1.IOThreadsPolicyAuto))
+			Expect(*vm.Spec.Template.Spec.Domain.Devices.BlockMultiQueue).To(BeTrue())
+		})
+
+		It("should return error for invalid values", func() {
+			vm.Annotations = map[string]string{utils.AnnotationIOThreads: "dedicated"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+		})
+	})
+})
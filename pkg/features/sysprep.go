@@ -0,0 +1,146 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// sysprepDeviceName is the name of the injected sysprep disk and volume
+const sysprepDeviceName = "sysprep"
+
+// sysprepKeys are the answer file names Windows setup looks for
+var sysprepKeys = []string{"autounattend.xml", "unattend.xml"}
+
+// Sysprep implements Windows sysprep injection from a ConfigMap.
+// The ConfigMap is attached as a sysprep volume exposed to the guest as a CD-ROM.
+type Sysprep struct {
+	configSource utils.ConfigSource
+}
+
+// NewSysprep creates a new Sysprep feature
+func NewSysprep(configSource utils.ConfigSource) *Sysprep {
+	return &Sysprep{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *Sysprep) Name() string {
+	return utils.FeatureSysprep
+}
+
+// IsEnabled checks if sysprep injection is requested via annotations or labels
+func (f *Sysprep) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSysprep)
+	return exists && value != ""
+}
+
+// Validate ensures the ConfigMap exists and contains an answer file
+func (f *Sysprep) Validate(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) error {
+	configMapName, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSysprep)
+	if !exists {
+		return nil
+	}
+
+	if configMapName == "" {
+		return fmt.Errorf("empty ConfigMap name in %s configuration key", utils.AnnotationSysprep)
+	}
+
+	if len(configMapName) > 253 || !configMapNameRegex.MatchString(configMapName) {
+		return fmt.Errorf("invalid ConfigMap name format: %s (must be a valid DNS subdomain)", configMapName)
+	}
+
+	// ConfigMap contents can only be checked when a client is available
+	if k8sClient == nil {
+		return nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	key := client.ObjectKey{
+		Namespace: vm.Namespace,
+		Name:      configMapName,
+	}
+	if err := k8sClient.Get(ctx, key, configMap); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("ConfigMap %s/%s not found", vm.Namespace, configMapName)
+		}
+		return fmt.Errorf("failed to fetch ConfigMap %s/%s: %w", vm.Namespace, configMapName, err)
+	}
+
+	for key := range configMap.Data {
+		for _, name := range sysprepKeys {
+			if strings.EqualFold(key, name) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("ConfigMap %s/%s has no answer file (expected key: %s)",
+		vm.Namespace, configMapName, strings.Join(sysprepKeys, " or "))
+}
+
+// Apply adds the sysprep volume and its CD-ROM disk to the VM
+func (f *Sysprep) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	configMapName, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationSysprep)
+
+	spec := &vm.Spec.Template.Spec
+	for _, vol := range spec.Volumes {
+		if vol.Sysprep != nil || vol.Name == sysprepDeviceName {
+			logger.Info("Sysprep volume already present, skipping", "vm", vm.Name)
+			return result, nil
+		}
+	}
+
+	logger.Info("Applying sysprep injection", "vm", vm.Name, "configMap", configMapName)
+
+	spec.Domain.Devices.Disks = append(spec.Domain.Devices.Disks, kubevirtv1.Disk{
+		Name: sysprepDeviceName,
+		DiskDevice: kubevirtv1.DiskDevice{
+			CDRom: &kubevirtv1.CDRomTarget{
+				Bus: kubevirtv1.DiskBusSATA,
+			},
+		},
+	})
+
+	spec.Volumes = append(spec.Volumes, kubevirtv1.Volume{
+		Name: sysprepDeviceName,
+		VolumeSource: kubevirtv1.VolumeSource{
+			Sysprep: &kubevirtv1.SysprepSource{
+				ConfigMap: &corev1.LocalObjectReference{
+					Name: configMapName,
+				},
+			},
+		},
+	})
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationSysprepApplied, configMapName)
+	result.AddMessage(fmt.Sprintf("Configured sysprep with ConfigMap %s", configMapName))
+
+	return result, nil
+}
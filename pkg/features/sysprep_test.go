@@ -0,0 +1,139 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Sysprep", func() {
+	var (
+		feature    *features.Sysprep
+		vm         *kubevirtv1.VirtualMachine
+		ctx        context.Context
+		fakeClient client.Client
+	)
+
+	BeforeEach(func() {
+		feature = features.NewSysprep(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		_ = corev1.AddToScheme(scheme)
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "win-unattend", Namespace: "default"},
+				Data:       map[string]string{"Autounattend.xml": "<unattend/>"},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+				Data:       map[string]string{"config.yaml": "{}"},
+			},
+		).Build()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureSysprep))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSysprep: "win-unattend"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept a ConfigMap with an answer file", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSysprep: "win-unattend"}
+			Expect(feature.Validate(ctx, vm, fakeClient)).To(Succeed())
+		})
+
+		It("should reject a missing ConfigMap", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSysprep: "missing"}
+			err := feature.Validate(ctx, vm, fakeClient)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not found"))
+		})
+
+		It("should reject a ConfigMap without an answer file", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSysprep: "other"}
+			err := feature.Validate(ctx, vm, fakeClient)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no answer file"))
+		})
+
+		It("should reject an invalid ConfigMap name", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSysprep: "Win_Unattend"}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should add the sysprep volume and CD-ROM disk", func() {
+			vm.Annotations = map[string]string{utils.AnnotationSysprep: "win-unattend"}
+			result, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationSysprepApplied, "win-unattend"))
+
+			volumes := vm.Spec.Template.Spec.Volumes
+			Expect(volumes).To(HaveLen(1))
+			Expect(volumes[0].Sysprep).ToNot(BeNil())
+			Expect(volumes[0].Sysprep.ConfigMap.Name).To(Equal("win-unattend"))
+
+			disks := vm.Spec.Template.Spec.Domain.Devices.Disks
+			Expect(disks).To(HaveLen(1))
+			Expect(disks[0].Name).To(Equal(volumes[0].Name))
+			Expect(disks[0].CDRom).ToNot(BeNil())
+			Expect(disks[0].CDRom.Bus).To(Equal(kubevirtv1.DiskBusSATA))
+		})
+
+		It("should not add a second sysprep volume", func() {
+			vm.Spec.Template.Spec.Volumes = []kubevirtv1.Volume{{
+				Name: "existing",
+				VolumeSource: kubevirtv1.VolumeSource{
+					Sysprep: &kubevirtv1.SysprepSource{
+						Secret: &corev1.LocalObjectReference{Name: "existing"},
+					},
+				},
+			}}
+			vm.Annotations = map[string]string{utils.AnnotationSysprep: "win-unattend"}
+
+			result, err := feature.Apply(ctx, vm, fakeClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Volumes).To(HaveLen(1))
+		})
+	})
+})
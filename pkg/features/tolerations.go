@@ -0,0 +1,144 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// Tolerations implements tolerations injection.
+// Tolerations from the annotation are merged into the VMI template,
+// skipping tolerations the VM already has.
+type Tolerations struct {
+	configSource utils.ConfigSource
+}
+
+// NewTolerations creates a new Tolerations feature
+func NewTolerations(configSource utils.ConfigSource) *Tolerations {
+	return &Tolerations{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *Tolerations) Name() string {
+	return utils.FeatureTolerations
+}
+
+// IsEnabled checks if tolerations are requested via annotations or labels
+func (f *Tolerations) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationTolerations)
+	return exists && value != ""
+}
+
+// Validate performs validation of the tolerations
+func (f *Tolerations) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationTolerations)
+	if !exists {
+		return nil
+	}
+
+	_, err := parseTolerations(value)
+	return err
+}
+
+// Apply merges the tolerations into the VM template
+func (f *Tolerations) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationTolerations)
+	tolerations, _ := parseTolerations(value)
+
+	added := 0
+	for _, toleration := range tolerations {
+		if hasToleration(vm.Spec.Template.Spec.Tolerations, toleration) {
+			continue
+		}
+		vm.Spec.Template.Spec.Tolerations = append(vm.Spec.Template.Spec.Tolerations, toleration)
+		added++
+	}
+
+	if added == 0 {
+		logger.Info("All tolerations already present, skipping", "vm", vm.Name)
+		return result, nil
+	}
+
+	logger.Info("Applied tolerations", "vm", vm.Name, "added", added)
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationTolerationsApplied, fmt.Sprintf("%d", added))
+	result.AddMessage(fmt.Sprintf("Added %d tolerations", added))
+
+	return result, nil
+}
+
+// parseTolerations parses and validates the tolerations annotation
+func parseTolerations(value string) ([]corev1.Toleration, error) {
+	var tolerations []corev1.Toleration
+	if err := json.Unmarshal([]byte(value), &tolerations); err != nil {
+		return nil, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationTolerations, err)
+	}
+
+	if len(tolerations) == 0 {
+		return nil, fmt.Errorf("no tolerations specified in %s", utils.AnnotationTolerations)
+	}
+
+	for i, t := range tolerations {
+		switch t.Operator {
+		case "", corev1.TolerationOpEqual:
+		case corev1.TolerationOpExists:
+			if t.Value != "" {
+				return nil, fmt.Errorf("toleration %d: value must be empty when operator is Exists", i)
+			}
+		default:
+			return nil, fmt.Errorf("toleration %d: invalid operator %q", i, t.Operator)
+		}
+
+		switch t.Effect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return nil, fmt.Errorf("toleration %d: invalid effect %q", i, t.Effect)
+		}
+
+		if t.Key == "" && t.Operator != corev1.TolerationOpExists {
+			return nil, fmt.Errorf("toleration %d: operator must be Exists when key is empty", i)
+		}
+
+		if t.TolerationSeconds != nil && t.Effect != corev1.TaintEffectNoExecute {
+			return nil, fmt.Errorf("toleration %d: tolerationSeconds requires effect NoExecute", i)
+		}
+	}
+
+	return tolerations, nil
+}
+
+// hasToleration checks whether an equivalent toleration is already present
+func hasToleration(existing []corev1.Toleration, toleration corev1.Toleration) bool {
+	for i := range existing {
+		if existing[i].MatchToleration(&toleration) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Tolerations", func() {
+	var (
+		feature *features.Tolerations
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewTolerations(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureTolerations))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationTolerations: `[{"key": "nvidia.com/gpu", "operator": "Exists", "effect": "NoSchedule"}]`,
+			}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept valid tolerations", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationTolerations: `[{"key": "nvidia.com/gpu", "operator": "Exists", "effect": "NoSchedule"},
+					{"key": "dedicated", "operator": "Equal", "value": "gpu"}]`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject malformed JSON", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTolerations: `{"key": "a"}`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid JSON"))
+		})
+
+		It("should reject an empty list", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTolerations: `[]`}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject invalid operators", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTolerations: `[{"key": "a", "operator": "In"}]`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid operator"))
+		})
+
+		It("should reject invalid effects", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTolerations: `[{"key": "a", "effect": "Never"}]`}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid effect"))
+		})
+
+		It("should reject a value with the Exists operator", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTolerations: `[{"key": "a", "operator": "Exists", "value": "b"}]`}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject tolerationSeconds without NoExecute", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationTolerations: `[{"key": "a", "operator": "Exists", "effect": "NoSchedule", "tolerationSeconds": 30}]`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should add tolerations to the VM template", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationTolerations: `[{"key": "nvidia.com/gpu", "operator": "Exists", "effect": "NoSchedule"}]`,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationTolerationsApplied, "1"))
+
+			tolerations := vm.Spec.Template.Spec.Tolerations
+			Expect(tolerations).To(HaveLen(1))
+			Expect(tolerations[0].Key).To(Equal("nvidia.com/gpu"))
+			Expect(tolerations[0].Operator).To(Equal(corev1.TolerationOpExists))
+			Expect(tolerations[0].Effect).To(Equal(corev1.TaintEffectNoSchedule))
+		})
+
+		It("should merge with existing tolerations", func() {
+			vm.Spec.Template.Spec.Tolerations = []corev1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+			}
+			vm.Annotations = map[string]string{
+				utils.AnnotationTolerations: `[{"key": "nvidia.com/gpu", "operator": "Exists", "effect": "NoSchedule"},
+					{"key": "dedicated", "operator": "Equal", "value": "gpu", "effect": "NoSchedule"}]`,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationTolerationsApplied, "1"))
+			Expect(vm.Spec.Template.Spec.Tolerations).To(HaveLen(2))
+		})
+
+		It("should not apply when all tolerations already exist", func() {
+			vm.Spec.Template.Spec.Tolerations = []corev1.Toleration{
+				{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "gpu"},
+			}
+			vm.Annotations = map[string]string{
+				utils.AnnotationTolerations: `[{"key": "dedicated", "operator": "Equal", "value": "gpu"}]`,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Tolerations).To(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,99 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// Tpm implements the virtual TPM feature.
+// It adds an emulated TPM device to the VM, optionally with persistent state,
+// as required by guests such as Windows 11.
+type Tpm struct {
+	configSource utils.ConfigSource
+}
+
+// NewTpm creates a new Tpm feature
+func NewTpm(configSource utils.ConfigSource) *Tpm {
+	return &Tpm{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *Tpm) Name() string {
+	return utils.FeatureTpm
+}
+
+// IsEnabled checks if a vTPM is requested via annotations or labels
+func (f *Tpm) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationTpm)
+	return exists && (utils.IsTruthyValue(value) || isPersistentTpm(value))
+}
+
+// Validate ensures the configured value is supported
+func (f *Tpm) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationTpm)
+	if !exists {
+		return nil
+	}
+
+	if !utils.IsTruthyValue(value) && !isPersistentTpm(value) {
+		return fmt.Errorf("invalid value for %s: %s (expected 'enabled' or '%s')",
+			utils.AnnotationTpm, value, utils.TpmPersistent)
+	}
+
+	return nil
+}
+
+// Apply adds a TPM device to the VM template
+func (f *Tpm) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationTpm)
+	persistent := isPersistentTpm(value)
+
+	logger.Info("Applying vTPM feature", "vm", vm.Name, "persistent", persistent)
+
+	// Respect an explicitly configured TPM device, only filling in persistence
+	tpm := vm.Spec.Template.Spec.Domain.Devices.TPM
+	if tpm == nil {
+		tpm = &kubevirtv1.TPMDevice{}
+		vm.Spec.Template.Spec.Domain.Devices.TPM = tpm
+	}
+	if persistent && tpm.Persistent == nil {
+		enabled := true
+		tpm.Persistent = &enabled
+	}
+
+	result.Applied = true
+	if persistent {
+		result.AddAnnotation(utils.AnnotationTpmApplied, utils.TpmPersistent)
+		result.AddMessage("Added persistent vTPM device")
+	} else {
+		result.AddAnnotation(utils.AnnotationTpmApplied, "true")
+		result.AddMessage("Added vTPM device")
+	}
+
+	return result, nil
+}
+
+// isPersistentTpm reports whether the value requests a persistent TPM
+func isPersistentTpm(value string) bool {
+	return strings.EqualFold(value, utils.TpmPersistent)
+}
@@ -0,0 +1,156 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Tpm", func() {
+	var (
+		feature *features.Tpm
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewTpm(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureTpm))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true for enabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTpm: "enabled"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return true for persistent", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTpm: "persistent"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return false for disabled", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTpm: "disabled"}
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		Context("when using labels as config source", func() {
+			BeforeEach(func() {
+				feature = features.NewTpm(utils.ConfigSourceLabels)
+			})
+
+			It("should return true when label is set", func() {
+				vm.Labels = map[string]string{utils.AnnotationTpm: "enabled"}
+				Expect(feature.IsEnabled(vm)).To(BeTrue())
+			})
+
+			It("should return false when only annotation is set", func() {
+				vm.Annotations = map[string]string{utils.AnnotationTpm: "enabled"}
+				Expect(feature.IsEnabled(vm)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should succeed when annotation is not present", func() {
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should accept enabled and persistent", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTpm: "enabled"}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+
+			vm.Annotations[utils.AnnotationTpm] = "persistent"
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should reject unknown values", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTpm: "sometimes"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid value"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should not modify the VM when not enabled", func() {
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.TPM).To(BeNil())
+		})
+
+		It("should return error when VM template is nil", func() {
+			vm.Spec.Template = nil
+			vm.Annotations = map[string]string{utils.AnnotationTpm: "enabled"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("template is nil"))
+			Expect(result.Applied).To(BeFalse())
+		})
+
+		It("should add a non-persistent TPM device", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTpm: "enabled"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationTpmApplied, "true"))
+
+			tpm := vm.Spec.Template.Spec.Domain.Devices.TPM
+			Expect(tpm).ToNot(BeNil())
+			Expect(tpm.Persistent).To(BeNil())
+		})
+
+		It("should add a persistent TPM device", func() {
+			vm.Annotations = map[string]string{utils.AnnotationTpm: "persistent"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationTpmApplied, utils.TpmPersistent))
+
+			tpm := vm.Spec.Template.Spec.Domain.Devices.TPM
+			Expect(tpm).ToNot(BeNil())
+			Expect(tpm.Persistent).ToNot(BeNil())
+			Expect(*tpm.Persistent).To(BeTrue())
+		})
+
+		It("should not override an existing persistence setting", func() {
+			persistent := false
+			vm.Spec.Template.Spec.Domain.Devices.TPM = &kubevirtv1.TPMDevice{Persistent: &persistent}
+			vm.Annotations = map[string]string{utils.AnnotationTpm: "persistent"}
+
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*vm.Spec.Template.Spec.Domain.Devices.TPM.Persistent).To(BeFalse())
+		})
+	})
+})
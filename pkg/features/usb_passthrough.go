@@ -0,0 +1,162 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// USB vendor:product ID format: VVVV:PPPP (hexadecimal)
+var usbIDRegex = regexp.MustCompile(`^[0-9a-fA-F]{4}:[0-9a-fA-F]{4}$`)
+
+// usbResourceNameRegex validates USB resource names permitted in KubeVirt (e.g., kubevirt.io/storage)
+var usbResourceNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)+/[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// USBPassthroughSpec defines the structure of the USB passthrough annotation
+type USBPassthroughSpec struct {
+	Devices []string `json:"devices"`
+}
+
+// UsbPassthrough implements USB host device passthrough feature
+type UsbPassthrough struct {
+	configSource utils.ConfigSource
+}
+
+// NewUsbPassthrough creates a new UsbPassthrough feature
+func NewUsbPassthrough(configSource utils.ConfigSource) *UsbPassthrough {
+	return &UsbPassthrough{
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *UsbPassthrough) Name() string {
+	return utils.FeatureUsbPassthrough
+}
+
+// IsEnabled checks if USB passthrough is requested via annotations or labels
+func (f *UsbPassthrough) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationUsbPassthrough)
+	return exists && value != ""
+}
+
+// Validate performs validation of USB passthrough configuration
+func (f *UsbPassthrough) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationUsbPassthrough)
+	if !exists {
+		return nil
+	}
+
+	// Parse the JSON spec
+	var spec USBPassthroughSpec
+	if err := json.Unmarshal([]byte(value), &spec); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationUsbPassthrough, err)
+	}
+
+	// Validate devices array is not empty
+	if len(spec.Devices) == 0 {
+		return fmt.Errorf("no devices specified in %s", utils.AnnotationUsbPassthrough)
+	}
+
+	// Check for duplicates
+	seen := make(map[string]bool)
+	for _, device := range spec.Devices {
+		if seen[device] {
+			return fmt.Errorf("duplicate USB device: %s", device)
+		}
+		seen[device] = true
+
+		// Validate resource name or vendor:product format
+		if !usbIDRegex.MatchString(device) && !usbResourceNameRegex.MatchString(device) {
+			return fmt.Errorf("invalid USB device: %s (expected resource name such as kubevirt.io/storage or VVVV:PPPP)", device)
+		}
+	}
+
+	return nil
+}
+
+// Apply adds USB devices to the VM spec
+func (f *UsbPassthrough) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, cl client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationUsbPassthrough)
+	if !exists || value == "" {
+		return result, nil
+	}
+
+	logger.Info("Applying USB passthrough feature", "vm", vm.Name)
+
+	// Validate template exists
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	// Parse the JSON spec
+	var spec USBPassthroughSpec
+	if err := json.Unmarshal([]byte(value), &spec); err != nil {
+		return result, fmt.Errorf("invalid JSON in %s: %w", utils.AnnotationUsbPassthrough, err)
+	}
+
+	// Get existing host devices to check for duplicates
+	existingDevices := make(map[string]bool)
+	for _, hd := range vm.Spec.Template.Spec.Domain.Devices.HostDevices {
+		existingDevices[hd.DeviceName] = true
+	}
+
+	// Add each USB device
+	var addedDevices []string
+	for i, device := range spec.Devices {
+		deviceName := usbDeviceName(device)
+
+		// Skip if already exists
+		if existingDevices[deviceName] {
+			logger.Info("USB device already exists, skipping", "device", device)
+			if !previouslyApplied(vm, utils.AnnotationUsbPassthroughApplied, device) {
+				result.AddWarning(fmt.Sprintf("USB device %s is already attached, skipping", device))
+			}
+			continue
+		}
+
+		// Add the host device
+		hostDevice := kubevirtv1.HostDevice{
+			Name:       fmt.Sprintf("usb-device-%d", i),
+			DeviceName: deviceName,
+		}
+
+		vm.Spec.Template.Spec.Domain.Devices.HostDevices = append(
+			vm.Spec.Template.Spec.Domain.Devices.HostDevices,
+			hostDevice,
+		)
+
+		addedDevices = append(addedDevices, device)
+		result.Applied = true
+	}
+
+	if result.Applied {
+		// Add tracking annotation with the list of devices
+		devicesJSON, _ := json.Marshal(addedDevices)
+		result.AddAnnotation(utils.AnnotationUsbPassthroughApplied, string(devicesJSON))
+		logger.Info("Successfully applied USB passthrough", "devices", addedDevices)
+	}
+
+	return result, nil
+}
+
+// usbDeviceName converts a USB device to the KubeVirt device name.
+// Resource names are used as-is; vendor:product IDs are converted
+// to the usb_VVVV_PPPP naming used for permitted USB host devices.
+func usbDeviceName(device string) string {
+	if usbIDRegex.MatchString(device) {
+		return "usb_" + strings.ToLower(strings.ReplaceAll(device, ":", "_"))
+	}
+	return device
+}
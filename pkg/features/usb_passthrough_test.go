@@ -0,0 +1,166 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("UsbPassthrough", func() {
+	var (
+		feature *features.UsbPassthrough
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		feature = features.NewUsbPassthrough(utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureUsbPassthrough))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when annotation is present", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationUsbPassthrough: `{"devices": ["kubevirt.io/storage"]}`,
+			}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		Context("when using labels as config source", func() {
+			BeforeEach(func() {
+				feature = features.NewUsbPassthrough(utils.ConfigSourceLabels)
+			})
+
+			It("should return false when annotation is set but labels are used", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationUsbPassthrough: `{"devices": ["kubevirt.io/storage"]}`,
+				}
+				Expect(feature.IsEnabled(vm)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept resource names and vendor:product IDs", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationUsbPassthrough: `{"devices": ["kubevirt.io/storage", "046d:c52b"]}`,
+			}
+			Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		})
+
+		It("should return error for malformed JSON", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationUsbPassthrough: `{"devices": [`,
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid JSON"))
+		})
+
+		It("should return error for empty devices", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationUsbPassthrough: `{"devices": []}`,
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no devices"))
+		})
+
+		It("should reject invalid devices", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationUsbPassthrough: `{"devices": ["046d-c52b"]}`,
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid USB device"))
+		})
+
+		It("should reject duplicate devices", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationUsbPassthrough: `{"devices": ["046d:c52b", "046d:c52b"]}`,
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("duplicate"))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should add hostDevices for each USB device", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationUsbPassthrough: `{"devices": ["kubevirt.io/storage", "046D:C52B"]}`,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+
+			hostDevices := vm.Spec.Template.Spec.Domain.Devices.HostDevices
+			Expect(hostDevices).To(HaveLen(2))
+			Expect(hostDevices[0].Name).To(Equal("usb-device-0"))
+			Expect(hostDevices[0].DeviceName).To(Equal("kubevirt.io/storage"))
+			Expect(hostDevices[1].Name).To(Equal("usb-device-1"))
+			Expect(hostDevices[1].DeviceName).To(Equal("usb_046d_c52b"))
+		})
+
+		It("should add tracking annotation", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationUsbPassthrough: `{"devices": ["kubevirt.io/storage"]}`,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationUsbPassthroughApplied, `["kubevirt.io/storage"]`))
+		})
+
+		It("should not add duplicates", func() {
+			vm.Spec.Template.Spec.Domain.Devices.HostDevices = []kubevirtv1.HostDevice{
+				{Name: "existing", DeviceName: "kubevirt.io/storage"},
+			}
+			vm.Annotations = map[string]string{
+				utils.AnnotationUsbPassthrough: `{"devices": ["kubevirt.io/storage"]}`,
+			}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.HostDevices).To(HaveLen(1))
+		})
+
+		It("should return error when VM template is nil", func() {
+			vm.Spec.Template = nil
+			vm.Annotations = map[string]string{
+				utils.AnnotationUsbPassthrough: `{"devices": ["kubevirt.io/storage"]}`,
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
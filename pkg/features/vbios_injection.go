@@ -2,15 +2,22 @@ package features
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"regexp"
+	"strings"
+	"text/template"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
 
@@ -18,25 +25,43 @@ import (
 // lowercase alphanumeric characters, '-' or '.', start and end with alphanumeric
 var configMapNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
 
+// Namespace name validation: DNS label (RFC 1123)
+var namespaceRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// SHA256 hex digest validation
+var sha256Regex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
 // Container image reference validation (simplified)
 var imageRefRegex = regexp.MustCompile(`^[a-zA-Z0-9._/-]+:[a-zA-Z0-9._-]+$`)
 
-// HookSidecar represents a KubeVirt hook sidecar configuration
-type HookSidecar struct {
-	Image           string   `json:"image"`
-	ImagePullPolicy string   `json:"imagePullPolicy,omitempty"`
-	Args            []string `json:"args,omitempty"`
-}
+// vbiosHookScript attaches the ROM to every PCI host device in the domain XML.
+// sidecar-shim invokes it as: onDefineDomain --vmi <json> --domain <xml>
+const vbiosHookScript = `#!/bin/sh
+set -e
+echo "$4" | xmlstarlet ed \
+  -s '//devices/hostdev[@type="pci"][not(rom)]' -t elem -n rom \
+  -i '//devices/hostdev[@type="pci"]/rom[not(@file)]' -t attr -n file -v '%s'
+`
+
+// vBIOS source kinds, also used in validation messages
+const (
+	vbiosKindConfigMap = "ConfigMap"
+	vbiosKindSecret    = "Secret"
+)
 
 // VBiosInjection implements vBIOS injection via KubeVirt hook sidecar
 type VBiosInjection struct {
+	config       *config.VBiosConfig
 	configSource utils.ConfigSource
+	hookVersions *hookVersionDetector
 }
 
 // NewVBiosInjection creates a new VBiosInjection feature
-func NewVBiosInjection(configSource utils.ConfigSource) *VBiosInjection {
+func NewVBiosInjection(cfg *config.VBiosConfig, configSource utils.ConfigSource) *VBiosInjection {
 	return &VBiosInjection{
+		config:       cfg,
 		configSource: configSource,
+		hookVersions: &hookVersionDetector{},
 	}
 }
 
@@ -45,32 +70,37 @@ func (f *VBiosInjection) Name() string {
 	return utils.FeatureVBiosInjection
 }
 
+// Config returns the feature settings
+func (f *VBiosInjection) Config() interface{} {
+	return f.config
+}
+
 // IsEnabled checks if vBIOS injection is requested via annotations or labels
 func (f *VBiosInjection) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	if !f.config.Enabled {
+		return false
+	}
+
 	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationVBiosInjection)
 	return exists && value != ""
 }
 
 // Validate performs validation of vBIOS injection configuration
-func (f *VBiosInjection) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
-	configMapName, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationVBiosInjection)
+func (f *VBiosInjection) Validate(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) error {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationVBiosInjection)
 	if !exists {
 		return nil
 	}
 
-	// Validate ConfigMap name is not empty
-	if configMapName == "" {
-		return fmt.Errorf("empty ConfigMap name in %s configuration key", utils.AnnotationVBiosInjection)
-	}
-
-	// Validate ConfigMap name length (max 253 characters per DNS subdomain spec)
-	if len(configMapName) > 253 {
-		return fmt.Errorf("ConfigMap name too long (max 253 characters): %s", configMapName)
+	src, err := parseVBiosSource(value, vm.Namespace)
+	if err != nil {
+		return err
 	}
 
-	// Validate ConfigMap name format (DNS subdomain)
-	if !configMapNameRegex.MatchString(configMapName) {
-		return fmt.Errorf("invalid ConfigMap name format: %s (must be a valid DNS subdomain)", configMapName)
+	// Sources outside the VM's namespace must come from an allowlisted library namespace
+	if src.namespace != vm.Namespace && !f.namespaceAllowed(src.namespace) {
+		return fmt.Errorf("vBIOS source namespace %s is not allowed (allowed: %s)",
+			src.namespace, strings.Join(f.config.AllowedSourceNamespaces, ","))
 	}
 
 	// Validate sidecar image if provided (always read from annotations since it's a secondary config)
@@ -83,30 +113,220 @@ func (f *VBiosInjection) Validate(_ context.Context, vm *kubevirtv1.VirtualMachi
 		}
 	}
 
+	// The checksum is always read from annotations since a SHA256 digest exceeds the label value limit
+	checksum := strings.ToLower(annotations[utils.AnnotationVBiosSHA256])
+	if checksum != "" && !sha256Regex.MatchString(checksum) {
+		return fmt.Errorf("invalid %s value: %s (must be a 64 character hex digest)", utils.AnnotationVBiosSHA256, checksum)
+	}
+
+	// The ROM contents can only be checked when a client is available
+	if k8sClient == nil {
+		return nil
+	}
+
+	rom, err := f.fetchROM(ctx, k8sClient, src)
+	if err != nil {
+		return err
+	}
+
+	if checksum != "" {
+		sum := sha256.Sum256(rom)
+		if actual := hex.EncodeToString(sum[:]); actual != checksum {
+			return fmt.Errorf("vBIOS %s checksum mismatch: expected sha256 %s, got %s", src, checksum, actual)
+		}
+	}
+
+	return nil
+}
+
+// vbiosSource identifies the ConfigMap or Secret holding the ROM
+type vbiosSource struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// String returns the source as "<kind> <namespace>/<name>"
+func (s vbiosSource) String() string {
+	return fmt.Sprintf("%s %s/%s", s.kind, s.namespace, s.name)
+}
+
+// parseVBiosSource parses "[secret:][<namespace>/]<name>", defaulting to a
+// ConfigMap in the VM's namespace
+func parseVBiosSource(value, vmNamespace string) (vbiosSource, error) {
+	src := vbiosSource{kind: vbiosKindConfigMap, namespace: vmNamespace, name: value}
+
+	if rest, ok := strings.CutPrefix(value, utils.VBiosSourceSecretPrefix); ok {
+		src.kind = vbiosKindSecret
+		src.name = rest
+	}
+
+	if namespace, name, ok := strings.Cut(src.name, "/"); ok {
+		if len(namespace) > 63 || !namespaceRegex.MatchString(namespace) {
+			return src, fmt.Errorf("invalid vBIOS source namespace: %q (must be a valid DNS label)", namespace)
+		}
+		src.namespace = namespace
+		src.name = name
+	}
+
+	// Validate name is not empty
+	if src.name == "" {
+		return src, fmt.Errorf("empty %s name in %s configuration key", src.kind, utils.AnnotationVBiosInjection)
+	}
+
+	// Validate name length (max 253 characters per DNS subdomain spec)
+	if len(src.name) > 253 {
+		return src, fmt.Errorf("%s name too long (max 253 characters): %s", src.kind, src.name)
+	}
+
+	// Validate name format (DNS subdomain)
+	if !configMapNameRegex.MatchString(src.name) {
+		return src, fmt.Errorf("invalid %s name format: %s (must be a valid DNS subdomain)", src.kind, src.name)
+	}
+
+	return src, nil
+}
+
+// namespaceAllowed reports whether ROMs may be read from the given namespace
+func (f *VBiosInjection) namespaceAllowed(namespace string) bool {
+	for _, allowed := range f.config.AllowedSourceNamespaces {
+		if allowed == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchROM returns the non-empty ROM stored under the configured key of the source
+func (f *VBiosInjection) fetchROM(ctx context.Context, k8sClient client.Client, src vbiosSource) ([]byte, error) {
+	romKey := f.romKey()
+	key := client.ObjectKey{Namespace: src.namespace, Name: src.name}
+
+	var (
+		data  []byte
+		found bool
+		err   error
+	)
+	if src.kind == vbiosKindSecret {
+		secret := &corev1.Secret{}
+		if err = k8sClient.Get(ctx, key, secret); err == nil {
+			data, found = secret.Data[romKey]
+		}
+	} else {
+		configMap := &corev1.ConfigMap{}
+		if err = k8sClient.Get(ctx, key, configMap); err == nil {
+			data, found = configMap.BinaryData[romKey]
+			if !found {
+				var text string
+				text, found = configMap.Data[romKey]
+				data = []byte(text)
+			}
+		}
+	}
+
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("vBIOS %s not found", src)
+		}
+		return nil, fmt.Errorf("failed to get vBIOS %s: %w", src, err)
+	}
+	if !found {
+		return nil, fmt.Errorf("vBIOS %s has no %q key", src, romKey)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("vBIOS %s has an empty %q key", src, romKey)
+	}
+
+	return data, nil
+}
+
+// mirrorROM copies a ROM from a library namespace into an object of the same
+// kind in the VM's namespace, since volumes cannot reference other namespaces
+func (f *VBiosInjection) mirrorROM(ctx context.Context, k8sClient client.Client, src vbiosSource, target client.ObjectKey) error {
+	rom, err := f.fetchROM(ctx, k8sClient, src)
+	if err != nil {
+		return err
+	}
+
+	var obj client.Object
+	var mutate controllerutil.MutateFn
+	if src.kind == vbiosKindSecret {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: target.Name, Namespace: target.Namespace}}
+		obj = secret
+		mutate = func() error {
+			secret.Data = map[string][]byte{f.romKey(): rom}
+			return nil
+		}
+	} else {
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: target.Name, Namespace: target.Namespace}}
+		obj = configMap
+		mutate = func() error {
+			configMap.Data = nil
+			configMap.BinaryData = map[string][]byte{f.romKey(): rom}
+			return nil
+		}
+	}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, k8sClient, obj, func() error {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[utils.AnnotationVBiosSource] = src.namespace + "/" + src.name
+		obj.SetAnnotations(annotations)
+		return mutate()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy vBIOS %s to %s/%s: %w", src, target.Namespace, target.Name, err)
+	}
 	return nil
 }
 
+// romKey returns the ConfigMap or Secret key holding the ROM, defaulting to "rom"
+func (f *VBiosInjection) romKey() string {
+	if f.config.SourceConfigMapKey != "" {
+		return f.config.SourceConfigMapKey
+	}
+	return utils.VBiosConfigMapKey
+}
+
 // Apply adds vBIOS injection hook sidecar to the VM
-func (f *VBiosInjection) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) (*MutationResult, error) {
+func (f *VBiosInjection) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
 	logger := log.FromContext(ctx)
 	result := NewMutationResult()
 
-	configMapName, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationVBiosInjection)
-	if !exists || configMapName == "" {
+	if !f.IsEnabled(vm) {
 		return result, nil
 	}
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationVBiosInjection)
 
-	logger.Info("Applying vBIOS injection feature", "vm", vm.Name, "configMap", configMapName)
+	logger.Info("Applying vBIOS injection feature", "vm", vm.Name, "source", value)
 
 	// Validate template exists
 	if vm.Spec.Template == nil {
 		return result, fmt.Errorf("VM template is nil")
 	}
 
-	// Validate ConfigMap name
-	if err := f.Validate(ctx, vm, nil); err != nil {
+	// Validate source name and contents
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
 		return result, err
 	}
+	src, _ := parseVBiosSource(value, vm.Namespace)
+
+	// Volumes can only reference the VM's namespace, so library ROMs are copied next to the VM
+	volumeSource := src
+	if src.namespace != vm.Namespace {
+		if k8sClient == nil {
+			return result, fmt.Errorf("cannot copy vBIOS %s without a Kubernetes client", src)
+		}
+		volumeSource.namespace = vm.Namespace
+		volumeSource.name = vm.Name + utils.VBiosMirrorSuffix
+		if IsDryRun(ctx) {
+			logger.Info("Dry run, not copying vBIOS ROM", "vm", vm.Name, "source", src.String())
+		} else if err := f.mirrorROM(ctx, k8sClient, src, client.ObjectKey{Namespace: volumeSource.namespace, Name: volumeSource.name}); err != nil {
+			return result, err
+		}
+	}
 
 	// Determine sidecar image to use (always read from annotations since it's a secondary config)
 	sidecarImage := utils.DefaultSidecarImage
@@ -119,30 +339,89 @@ func (f *VBiosInjection) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachin
 	}
 
 	// Add vBIOS volume if not already present
-	if err := f.addVBiosVolume(vm, configMapName); err != nil {
+	if err := f.addVBiosVolume(vm, volumeSource); err != nil {
 		return result, err
 	}
 
+	hookVersion := f.hookVersion(ctx, k8sClient)
+	hookSidecar := HookSidecar{
+		Image:           sidecarImage,
+		ImagePullPolicy: "IfNotPresent",
+		Args: []string{
+			"--version", hookVersion,
+			"--hook-type", utils.SidecarHookType,
+		},
+	}
+
+	switch f.config.HookMode {
+	case "", utils.VBiosHookModeImage:
+	case utils.VBiosHookModeConfigMap:
+		hookConfigMap, err := f.ensureHookConfigMap(ctx, vm, k8sClient)
+		if err != nil {
+			return result, err
+		}
+		hookSidecar.Args = []string{"--version", hookVersion}
+		hookSidecar.ConfigMap = hookConfigMap
+	default:
+		return result, fmt.Errorf("unknown vBIOS hook mode %q (must be %s or %s)",
+			f.config.HookMode, utils.VBiosHookModeImage, utils.VBiosHookModeConfigMap)
+	}
+
 	// Add hook sidecar annotation
-	if err := f.addHookSidecar(vm, sidecarImage); err != nil {
+	if err := f.addHookSidecar(vm, hookSidecar); err != nil {
 		return result, err
 	}
 
 	// Mark as applied
 	result.Applied = true
-	result.AddAnnotation(utils.AnnotationVBiosInjectionApplied, configMapName)
-	result.AddMessage(fmt.Sprintf("Configured vBIOS injection with ConfigMap %s", configMapName))
+	result.AddAnnotation(utils.AnnotationVBiosInjectionApplied, value)
+	result.AddMessage(fmt.Sprintf("Configured vBIOS injection with %s", src))
 
 	logger.Info("vBIOS injection applied successfully",
 		"vm", vm.Name,
-		"configMap", configMapName,
+		"source", src.String(),
 		"sidecarImage", sidecarImage)
 
 	return result, nil
 }
 
-// addVBiosVolume adds the vBIOS ConfigMap volume to the VM spec
-func (f *VBiosInjection) addVBiosVolume(vm *kubevirtv1.VirtualMachine, configMapName string) error {
+// Revert removes the vBIOS volume and hook sidecar. A ROM copied next to the
+// VM is left in place.
+func (f *VBiosInjection) Revert(_ context.Context, vm, _ *kubevirtv1.VirtualMachine) error {
+	if vm.Spec.Template == nil {
+		return nil
+	}
+
+	kept := vm.Spec.Template.Spec.Volumes[:0]
+	for _, vol := range vm.Spec.Template.Spec.Volumes {
+		if vol.Name != "vbios-rom" {
+			kept = append(kept, vol)
+		}
+	}
+	vm.Spec.Template.Spec.Volumes = kept
+	if len(kept) == 0 {
+		vm.Spec.Template.Spec.Volumes = nil
+	}
+
+	_, err := removeHookSidecars(vm, isVBiosHookSidecar)
+	return err
+}
+
+// isVBiosHookSidecar reports whether the sidecar is one added by vBIOS injection
+func isVBiosHookSidecar(sidecar HookSidecar) bool {
+	if sidecar.ConfigMap != nil {
+		return sidecar.ConfigMap.Key == utils.VBiosHookScriptKey
+	}
+	for i := 0; i+1 < len(sidecar.Args); i++ {
+		if sidecar.Args[i] == "--hook-type" && sidecar.Args[i+1] == utils.SidecarHookType {
+			return true
+		}
+	}
+	return false
+}
+
+// addVBiosVolume adds the vBIOS ConfigMap or Secret volume to the VM spec
+func (f *VBiosInjection) addVBiosVolume(vm *kubevirtv1.VirtualMachine, src vbiosSource) error {
 	// Check if volume already exists
 	for _, vol := range vm.Spec.Template.Spec.Volumes {
 		if vol.Name == "vbios-rom" {
@@ -152,50 +431,103 @@ func (f *VBiosInjection) addVBiosVolume(vm *kubevirtv1.VirtualMachine, configMap
 	}
 
 	// Add the volume
-	vbiosVolume := kubevirtv1.Volume{
-		Name: "vbios-rom",
-		VolumeSource: kubevirtv1.VolumeSource{
-			ConfigMap: &kubevirtv1.ConfigMapVolumeSource{
-				LocalObjectReference: corev1.LocalObjectReference{
-					Name: configMapName,
-				},
+	vbiosVolume := kubevirtv1.Volume{Name: "vbios-rom"}
+	if src.kind == vbiosKindSecret {
+		vbiosVolume.VolumeSource.Secret = &kubevirtv1.SecretVolumeSource{
+			SecretName: src.name,
+		}
+	} else {
+		vbiosVolume.VolumeSource.ConfigMap = &kubevirtv1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{
+				Name: src.name,
 			},
-		},
+		}
 	}
 
 	vm.Spec.Template.Spec.Volumes = append(vm.Spec.Template.Spec.Volumes, vbiosVolume)
 	return nil
 }
 
-// addHookSidecar adds the KubeVirt hook sidecar annotation
-func (f *VBiosInjection) addHookSidecar(vm *kubevirtv1.VirtualMachine, sidecarImage string) error {
-	// Initialize template annotations if needed
-	if vm.Spec.Template.ObjectMeta.Annotations == nil {
-		vm.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+// hookVersion returns the configured hook API version, or detects it from the
+// KubeVirt CR when set to "auto"
+func (f *VBiosInjection) hookVersion(ctx context.Context, k8sClient client.Client) string {
+	if f.config.SidecarVersion != "" && f.config.SidecarVersion != utils.SidecarHookVersionAuto {
+		return f.config.SidecarVersion
+	}
+	if k8sClient == nil {
+		return utils.SidecarHookVersion
+	}
+
+	hookVersion, err := f.hookVersions.detect(ctx, k8sClient)
+	if err != nil {
+		log.FromContext(ctx).Info("Could not detect KubeVirt version, using fallback hook version",
+			"version", utils.SidecarHookVersion, "reason", err.Error())
+		return utils.SidecarHookVersion
+	}
+	return hookVersion
+}
+
+// ensureHookConfigMap creates or updates the per-VM ConfigMap holding the vBIOS
+// hook script and returns the reference for the hookSidecars annotation
+func (f *VBiosInjection) ensureHookConfigMap(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*HookSidecarConfigMap, error) {
+	name, err := f.hookConfigMapName(vm)
+	if err != nil {
+		return nil, err
+	}
+	ref := &HookSidecarConfigMap{
+		Name:     name,
+		Key:      utils.VBiosHookScriptKey,
+		HookPath: utils.SidecarHookPathOnDefineDomain,
+	}
+
+	if IsDryRun(ctx) {
+		log.FromContext(ctx).Info("Dry run, not writing vBIOS hook ConfigMap", "vm", vm.Name, "configMap", name)
+		return ref, nil
+	}
+	if k8sClient == nil {
+		return nil, fmt.Errorf("cannot write vBIOS hook ConfigMap %s/%s without a Kubernetes client", vm.Namespace, name)
 	}
 
-	// Check if hook sidecar already exists
-	if existingHook, exists := vm.Spec.Template.ObjectMeta.Annotations[utils.HookAnnotationKey]; exists && existingHook != "" {
-		// Hook already configured, don't override
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: vm.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, k8sClient, configMap, func() error {
+		configMap.Data = map[string]string{
+			utils.VBiosHookScriptKey: fmt.Sprintf(vbiosHookScript, f.config.VBiosPath),
+		}
 		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write vBIOS hook ConfigMap %s/%s: %w", vm.Namespace, name, err)
 	}
 
-	// Create hook sidecar configuration
-	hookSidecar := HookSidecar{
-		Image:           sidecarImage,
-		ImagePullPolicy: "IfNotPresent",
-		Args: []string{
-			"--version", utils.SidecarHookVersion,
-			"--hook-type", utils.SidecarHookType,
-		},
+	return ref, nil
+}
+
+// hookConfigMapName renders the configured hook ConfigMap name template for the VM
+func (f *VBiosInjection) hookConfigMapName(vm *kubevirtv1.VirtualMachine) (string, error) {
+	text := f.config.HookConfigMapNameTemplate
+	if text == "" {
+		text = utils.DefaultVBiosHookConfigMapTemplate
 	}
 
-	// Marshal to JSON array (KubeVirt expects an array of sidecars)
-	hookJSON, err := json.Marshal([]HookSidecar{hookSidecar})
+	tmpl, err := template.New("hook-configmap").Parse(text)
 	if err != nil {
-		return fmt.Errorf("failed to marshal hook sidecar configuration: %w", err)
+		return "", fmt.Errorf("invalid vBIOS hook ConfigMap name template: %w", err)
 	}
 
-	vm.Spec.Template.ObjectMeta.Annotations[utils.HookAnnotationKey] = string(hookJSON)
-	return nil
+	var name strings.Builder
+	if err := tmpl.Execute(&name, struct{ VMName string }{VMName: vm.Name}); err != nil {
+		return "", fmt.Errorf("failed to render vBIOS hook ConfigMap name: %w", err)
+	}
+
+	if len(name.String()) > 253 || !configMapNameRegex.MatchString(name.String()) {
+		return "", fmt.Errorf("invalid vBIOS hook ConfigMap name: %s (must be a valid DNS subdomain)", name.String())
+	}
+	return name.String(), nil
+}
+
+// addHookSidecar adds the vBIOS hook sidecar to the KubeVirt hook annotation,
+// keeping any sidecars other tools have already configured
+func (f *VBiosInjection) addHookSidecar(vm *kubevirtv1.VirtualMachine, hookSidecar HookSidecar) error {
+	_, err := mergeHookSidecars(vm, hookSidecar)
+	return err
 }
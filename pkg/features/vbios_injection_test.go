@@ -2,26 +2,34 @@ package features_test
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
 
 var _ = Describe("VBiosInjection", func() {
 	var (
-		feature *features.VBiosInjection
-		vm      *kubevirtv1.VirtualMachine
-		ctx     context.Context
+		feature  *features.VBiosInjection
+		vbiosCfg *config.VBiosConfig
+		vm       *kubevirtv1.VirtualMachine
+		ctx      context.Context
 	)
 
 	BeforeEach(func() {
-		feature = features.NewVBiosInjection(utils.ConfigSourceAnnotations)
+		vbiosCfg = &config.VBiosConfig{Enabled: true}
+		feature = features.NewVBiosInjection(vbiosCfg, utils.ConfigSourceAnnotations)
 		ctx = context.Background()
 
 		vm = &kubevirtv1.VirtualMachine{
@@ -72,7 +80,7 @@ var _ = Describe("VBiosInjection", func() {
 
 		Context("when using labels as config source", func() {
 			BeforeEach(func() {
-				feature = features.NewVBiosInjection(utils.ConfigSourceLabels)
+				feature = features.NewVBiosInjection(vbiosCfg, utils.ConfigSourceLabels)
 			})
 
 			It("should return true when label is set", func() {
@@ -162,7 +170,7 @@ var _ = Describe("VBiosInjection", func() {
 
 		Context("when using labels as config source", func() {
 			BeforeEach(func() {
-				feature = features.NewVBiosInjection(utils.ConfigSourceLabels)
+				feature = features.NewVBiosInjection(vbiosCfg, utils.ConfigSourceLabels)
 			})
 
 			It("should accept valid ConfigMap name from label", func() {
@@ -181,6 +189,99 @@ var _ = Describe("VBiosInjection", func() {
 				Expect(err.Error()).To(ContainSubstring("invalid ConfigMap name"))
 			})
 		})
+		Context("with a client", func() {
+			romConfigMap := func(name string, binaryData map[string][]byte, data map[string]string) *corev1.ConfigMap {
+				return &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+					BinaryData: binaryData,
+					Data:       data,
+				}
+			}
+
+			validate := func(name string, objs ...*corev1.ConfigMap) error {
+				scheme := runtime.NewScheme()
+				_ = corev1.AddToScheme(scheme)
+				builder := fake.NewClientBuilder().WithScheme(scheme)
+				for _, obj := range objs {
+					builder = builder.WithObjects(obj)
+				}
+				if vm.Annotations == nil {
+					vm.Annotations = map[string]string{}
+				}
+				vm.Annotations[utils.AnnotationVBiosInjection] = name
+				return feature.Validate(ctx, vm, builder.Build())
+			}
+
+			It("should accept a ConfigMap with a binary ROM", func() {
+				cm := romConfigMap("my-vbios", map[string][]byte{utils.VBiosConfigMapKey: []byte("rom-data")}, nil)
+				Expect(validate("my-vbios", cm)).To(Succeed())
+			})
+
+			It("should accept a ConfigMap with a string ROM", func() {
+				cm := romConfigMap("my-vbios", nil, map[string]string{utils.VBiosConfigMapKey: "rom-data"})
+				Expect(validate("my-vbios", cm)).To(Succeed())
+			})
+
+			It("should reject a missing ConfigMap", func() {
+				err := validate("my-vbios")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("not found"))
+			})
+
+			It("should reject a ConfigMap without the ROM key", func() {
+				cm := romConfigMap("my-vbios", map[string][]byte{"other": []byte("data")}, nil)
+				err := validate("my-vbios", cm)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(`has no "rom" key`))
+			})
+
+			It("should reject an empty ROM", func() {
+				cm := romConfigMap("my-vbios", map[string][]byte{utils.VBiosConfigMapKey: {}}, nil)
+				err := validate("my-vbios", cm)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("empty"))
+			})
+
+			It("should use the configured ROM key", func() {
+				vbiosCfg.SourceConfigMapKey = "vbios.bin"
+				cm := romConfigMap("my-vbios", map[string][]byte{"vbios.bin": []byte("rom-data")}, nil)
+				Expect(validate("my-vbios", cm)).To(Succeed())
+			})
+
+			Context("with a SHA256 checksum", func() {
+				const romSHA256 = "0f583703247ac5ba7226e6864f921aff4bae11be0d4c10aa5bcd5072ab3c3a1a"
+
+				var cm *corev1.ConfigMap
+
+				BeforeEach(func() {
+					cm = romConfigMap("my-vbios", map[string][]byte{utils.VBiosConfigMapKey: []byte("rom-data")}, nil)
+				})
+
+				It("should accept a matching checksum", func() {
+					vm.Annotations = map[string]string{utils.AnnotationVBiosSHA256: romSHA256}
+					Expect(validate("my-vbios", cm)).To(Succeed())
+				})
+
+				It("should accept an uppercase checksum", func() {
+					vm.Annotations = map[string]string{utils.AnnotationVBiosSHA256: strings.ToUpper(romSHA256)}
+					Expect(validate("my-vbios", cm)).To(Succeed())
+				})
+
+				It("should reject a mismatching checksum", func() {
+					vm.Annotations = map[string]string{utils.AnnotationVBiosSHA256: strings.Repeat("0", 64)}
+					err := validate("my-vbios", cm)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("checksum mismatch"))
+				})
+
+				It("should reject a malformed checksum", func() {
+					vm.Annotations = map[string]string{utils.AnnotationVBiosSHA256: "not-a-digest"}
+					err := validate("my-vbios", cm)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("invalid"))
+				})
+			})
+		})
 	})
 
 	Describe("Apply", func() {
@@ -299,22 +400,45 @@ var _ = Describe("VBiosInjection", func() {
 		})
 
 		Context("when hook sidecar already exists", func() {
-			It("should not add duplicate hook sidecar", func() {
+			BeforeEach(func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationVBiosInjection: "my-vbios-configmap",
+				}
+			})
+
+			It("should append the vBIOS sidecar to existing sidecars", func() {
 				existingHook := `[{"image":"registry.k8s.io/kubevirt/sidecar-shim:v1.3.0"}]`
 				if vm.Spec.Template.ObjectMeta.Annotations == nil {
 					vm.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
 				}
 				vm.Spec.Template.ObjectMeta.Annotations[utils.HookAnnotationKey] = existingHook
 
-				vm.Annotations = map[string]string{
-					utils.AnnotationVBiosInjection: "my-vbios-configmap",
-				}
 				result, err := feature.Apply(ctx, vm, nil)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(result.Applied).To(BeTrue())
 
-				// Hook should still be present (not removed)
-				Expect(vm.Spec.Template.ObjectMeta.Annotations).To(HaveKey(utils.HookAnnotationKey))
+				var sidecars []features.HookSidecar
+				Expect(json.Unmarshal([]byte(vm.Spec.Template.ObjectMeta.Annotations[utils.HookAnnotationKey]), &sidecars)).To(Succeed())
+				Expect(sidecars).To(HaveLen(2))
+				Expect(sidecars[0].Image).To(Equal("registry.k8s.io/kubevirt/sidecar-shim:v1.3.0"))
+				Expect(sidecars[1].Image).To(Equal(utils.DefaultSidecarImage))
+			})
+
+			It("should not add duplicate hook sidecar", func() {
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				var sidecars []features.HookSidecar
+				Expect(json.Unmarshal([]byte(vm.Spec.Template.ObjectMeta.Annotations[utils.HookAnnotationKey]), &sidecars)).To(Succeed())
+				Expect(sidecars).To(HaveLen(1))
+			})
+
+			It("should fail on a malformed hook annotation", func() {
+				vm.Spec.Template.ObjectMeta.Annotations = map[string]string{utils.HookAnnotationKey: "not-json"}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).To(HaveOccurred())
 			})
 		})
 
@@ -332,7 +456,7 @@ var _ = Describe("VBiosInjection", func() {
 
 		Context("when using labels as config source", func() {
 			BeforeEach(func() {
-				feature = features.NewVBiosInjection(utils.ConfigSourceLabels)
+				feature = features.NewVBiosInjection(vbiosCfg, utils.ConfigSourceLabels)
 			})
 
 			It("should add hook sidecar from label", func() {
@@ -379,5 +503,266 @@ var _ = Describe("VBiosInjection", func() {
 				Expect(volumes[0].ConfigMap.Name).To(Equal("my-vbios-configmap"))
 			})
 		})
+		Context("with Secret and cross-namespace sources", func() {
+			var libraryClient client.Client
+
+			BeforeEach(func() {
+				scheme := runtime.NewScheme()
+				_ = corev1.AddToScheme(scheme)
+				libraryClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+					&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{Name: "rtx-a4000", Namespace: "vbios-library"},
+						BinaryData: map[string][]byte{utils.VBiosConfigMapKey: []byte("library-rom")},
+					},
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: "rtx-a4000", Namespace: "vbios-library"},
+						Data:       map[string][]byte{utils.VBiosConfigMapKey: []byte("secret-rom")},
+					},
+				).Build()
+				vbiosCfg.AllowedSourceNamespaces = []string{"vbios-library"}
+			})
+
+			It("should add a Secret volume for secret: sources", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationVBiosInjection: "secret:my-vbios",
+				}
+				_, err := feature.Apply(ctx, vm, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				volumes := vm.Spec.Template.Spec.Volumes
+				Expect(volumes).To(HaveLen(1))
+				Expect(volumes[0].ConfigMap).To(BeNil())
+				Expect(volumes[0].Secret).ToNot(BeNil())
+				Expect(volumes[0].Secret.SecretName).To(Equal("my-vbios"))
+			})
+
+			It("should reject namespaces outside the allowlist", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationVBiosInjection: "other-team/rtx-a4000",
+				}
+				err := feature.Validate(ctx, vm, libraryClient)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("not allowed"))
+			})
+
+			It("should accept an explicit reference to the VM's namespace", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationVBiosInjection: "default/my-vbios",
+				}
+				Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+			})
+
+			It("should reject an invalid namespace", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationVBiosInjection: "Bad_Namespace/my-vbios",
+				}
+				err := feature.Validate(ctx, vm, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid vBIOS source namespace"))
+			})
+
+			It("should copy a library ConfigMap next to the VM", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationVBiosInjection: "vbios-library/rtx-a4000",
+				}
+				result, err := feature.Apply(ctx, vm, libraryClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Applied).To(BeTrue())
+
+				volumes := vm.Spec.Template.Spec.Volumes
+				Expect(volumes).To(HaveLen(1))
+				Expect(volumes[0].ConfigMap.Name).To(Equal("test-vm-vbios"))
+
+				mirror := &corev1.ConfigMap{}
+				Expect(libraryClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-vm-vbios"}, mirror)).To(Succeed())
+				Expect(mirror.BinaryData).To(HaveKeyWithValue(utils.VBiosConfigMapKey, []byte("library-rom")))
+				Expect(mirror.Annotations).To(HaveKeyWithValue(utils.AnnotationVBiosSource, "vbios-library/rtx-a4000"))
+			})
+
+			It("should copy a library Secret next to the VM", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationVBiosInjection: "secret:vbios-library/rtx-a4000",
+				}
+				_, err := feature.Apply(ctx, vm, libraryClient)
+				Expect(err).ToNot(HaveOccurred())
+
+				volumes := vm.Spec.Template.Spec.Volumes
+				Expect(volumes[0].Secret.SecretName).To(Equal("test-vm-vbios"))
+
+				mirror := &corev1.Secret{}
+				Expect(libraryClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-vm-vbios"}, mirror)).To(Succeed())
+				Expect(mirror.Data).To(HaveKeyWithValue(utils.VBiosConfigMapKey, []byte("secret-rom")))
+			})
+
+			It("should not copy the ROM on dry run", func() {
+				vm.Annotations = map[string]string{
+					utils.AnnotationVBiosInjection: "vbios-library/rtx-a4000",
+				}
+				_, err := feature.Apply(features.WithDryRun(ctx, true), vm, libraryClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.Spec.Template.Spec.Volumes[0].ConfigMap.Name).To(Equal("test-vm-vbios"))
+
+				mirror := &corev1.ConfigMap{}
+				err = libraryClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-vm-vbios"}, mirror)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("with configmap hook mode", func() {
+			var hookClient client.Client
+
+			BeforeEach(func() {
+				scheme := runtime.NewScheme()
+				_ = corev1.AddToScheme(scheme)
+				hookClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+					&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{Name: "my-vbios", Namespace: "default"},
+						BinaryData: map[string][]byte{utils.VBiosConfigMapKey: []byte("rom-data")},
+					},
+				).Build()
+				vbiosCfg.HookMode = utils.VBiosHookModeConfigMap
+				vbiosCfg.VBiosPath = "/tmp/vbios.rom"
+				vm.Annotations = map[string]string{
+					utils.AnnotationVBiosInjection: "my-vbios",
+				}
+			})
+
+			hookSidecars := func() []features.HookSidecar {
+				var sidecars []features.HookSidecar
+				Expect(json.Unmarshal([]byte(vm.Spec.Template.ObjectMeta.Annotations[utils.HookAnnotationKey]), &sidecars)).To(Succeed())
+				return sidecars
+			}
+
+			It("should reference the hook script ConfigMap", func() {
+				_, err := feature.Apply(ctx, vm, hookClient)
+				Expect(err).ToNot(HaveOccurred())
+
+				sidecars := hookSidecars()
+				Expect(sidecars).To(HaveLen(1))
+				Expect(sidecars[0].Args).To(Equal([]string{"--version", utils.SidecarHookVersion}))
+				Expect(sidecars[0].ConfigMap).To(Equal(&features.HookSidecarConfigMap{
+					Name:     "test-vm-vbios-hook",
+					Key:      utils.VBiosHookScriptKey,
+					HookPath: utils.SidecarHookPathOnDefineDomain,
+				}))
+			})
+
+			It("should write the hook script ConfigMap", func() {
+				_, err := feature.Apply(ctx, vm, hookClient)
+				Expect(err).ToNot(HaveOccurred())
+
+				hook := &corev1.ConfigMap{}
+				Expect(hookClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-vm-vbios-hook"}, hook)).To(Succeed())
+				Expect(hook.Data[utils.VBiosHookScriptKey]).To(ContainSubstring("xmlstarlet"))
+				Expect(hook.Data[utils.VBiosHookScriptKey]).To(ContainSubstring("/tmp/vbios.rom"))
+			})
+
+			It("should use the configured name template", func() {
+				vbiosCfg.HookConfigMapNameTemplate = "hook-{{ .VMName }}"
+				_, err := feature.Apply(ctx, vm, hookClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hookSidecars()[0].ConfigMap.Name).To(Equal("hook-test-vm"))
+			})
+
+			It("should not write the ConfigMap on dry run", func() {
+				_, err := feature.Apply(features.WithDryRun(ctx, true), vm, hookClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hookSidecars()[0].ConfigMap.Name).To(Equal("test-vm-vbios-hook"))
+
+				hook := &corev1.ConfigMap{}
+				err = hookClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-vm-vbios-hook"}, hook)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("should reject an unknown hook mode", func() {
+				vbiosCfg.HookMode = "script"
+				_, err := feature.Apply(ctx, vm, hookClient)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("unknown vBIOS hook mode"))
+			})
+		})
+		Context("with hook version detection", func() {
+			hookArgs := func(kubevirtVersion string) []string {
+				scheme := runtime.NewScheme()
+				_ = corev1.AddToScheme(scheme)
+				_ = kubevirtv1.AddToScheme(scheme)
+				builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+					&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{Name: "my-vbios", Namespace: "default"},
+						BinaryData: map[string][]byte{utils.VBiosConfigMapKey: []byte("rom-data")},
+					},
+				)
+				if kubevirtVersion != "" {
+					builder = builder.WithObjects(&kubevirtv1.KubeVirt{
+						ObjectMeta: metav1.ObjectMeta{Name: "kubevirt", Namespace: "kubevirt"},
+						Status:     kubevirtv1.KubeVirtStatus{ObservedKubeVirtVersion: kubevirtVersion},
+					})
+				}
+
+				vm.Annotations = map[string]string{utils.AnnotationVBiosInjection: "my-vbios"}
+				_, err := feature.Apply(ctx, vm, builder.Build())
+				Expect(err).ToNot(HaveOccurred())
+
+				var sidecars []features.HookSidecar
+				Expect(json.Unmarshal([]byte(vm.Spec.Template.ObjectMeta.Annotations[utils.HookAnnotationKey]), &sidecars)).To(Succeed())
+				Expect(sidecars).To(HaveLen(1))
+				return sidecars[0].Args
+			}
+
+			BeforeEach(func() {
+				vbiosCfg.SidecarVersion = utils.SidecarHookVersionAuto
+			})
+
+			It("should use v1alpha3 on KubeVirt v1.0 and later", func() {
+				Expect(hookArgs("v1.4.0")).To(ContainElement(utils.SidecarHookVersionV1Alpha3))
+			})
+
+			It("should fall back to v1alpha2 on older KubeVirt", func() {
+				Expect(hookArgs("v0.59.2")).To(ContainElement(utils.SidecarHookVersion))
+			})
+
+			It("should fall back to v1alpha2 without a KubeVirt CR", func() {
+				Expect(hookArgs("")).To(ContainElement(utils.SidecarHookVersion))
+			})
+
+			It("should use an explicitly configured version", func() {
+				vbiosCfg.SidecarVersion = utils.SidecarHookVersion
+				Expect(hookArgs("v1.4.0")).To(ContainElement(utils.SidecarHookVersion))
+			})
+		})
+	})
+
+	Describe("Revert", func() {
+		It("should remove the vBIOS volume and hook sidecar only", func() {
+			vm.Spec.Template.ObjectMeta.Annotations = map[string]string{
+				utils.HookAnnotationKey: `[{"image":"example.com/other-hook:v1"}]`,
+			}
+			vm.Annotations = map[string]string{
+				utils.AnnotationVBiosInjection: "test-vbios",
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.Volumes).To(HaveLen(1))
+
+			previous := vm.DeepCopy()
+			delete(vm.Annotations, utils.AnnotationVBiosInjection)
+
+			Expect(feature.Revert(ctx, vm, previous)).To(Succeed())
+			Expect(vm.Spec.Template.Spec.Volumes).To(BeEmpty())
+			Expect(vm.Spec.Template.ObjectMeta.Annotations[utils.HookAnnotationKey]).To(MatchJSON(`[{"image":"example.com/other-hook:v1"}]`))
+		})
+
+		It("should drop the hook annotation when no sidecars remain", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationVBiosInjection: "test-vbios",
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			previous := vm.DeepCopy()
+			delete(vm.Annotations, utils.AnnotationVBiosInjection)
+
+			Expect(feature.Revert(ctx, vm, previous)).To(Succeed())
+			Expect(vm.Spec.Template.ObjectMeta.Annotations).ToNot(HaveKey(utils.HookAnnotationKey))
+		})
 	})
 })
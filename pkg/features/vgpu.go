@@ -0,0 +1,173 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// mdevResourceNameRegex validates mediated device resource names.
+// Format: domain/resource-name, where the resource may contain upper case
+// letters and underscores (e.g., nvidia.com/GRID_T4-2Q)
+var mdevResourceNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)+/[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// VGpu implements mediated device (vGPU) passthrough.
+// It adds the requested mdev resource to the VM's GPUs, optionally
+// configuring the vGPU display.
+type VGpu struct {
+	config       *config.VGpuConfig
+	configSource utils.ConfigSource
+}
+
+// NewVGpu creates a new VGpu feature
+func NewVGpu(cfg *config.VGpuConfig, configSource utils.ConfigSource) *VGpu {
+	return &VGpu{
+		config:       cfg,
+		configSource: configSource,
+	}
+}
+
+// Name returns the feature name
+func (f *VGpu) Name() string {
+	return utils.FeatureVGpu
+}
+
+// Config returns the feature settings
+func (f *VGpu) Config() interface{} {
+	return f.config
+}
+
+// Dependencies attaches the vGPU after an optional vBIOS has been injected
+func (f *VGpu) Dependencies() []Dependency {
+	return []Dependency{
+		{Feature: utils.FeatureVBiosInjection},
+	}
+}
+
+// IsEnabled checks if a vGPU is requested via annotations or labels
+func (f *VGpu) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	if !f.config.Enabled {
+		return false
+	}
+
+	resourceName, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationVGpu)
+	return exists && resourceName != ""
+}
+
+// Validate ensures the mdev resource name and display option are valid
+func (f *VGpu) Validate(_ context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	resourceName, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationVGpu)
+	if !exists {
+		return nil
+	}
+
+	if resourceName == "" {
+		return fmt.Errorf("vGPU resource name cannot be empty")
+	}
+
+	if !mdevResourceNameRegex.MatchString(resourceName) {
+		return fmt.Errorf("invalid vGPU resource name %q: must be in format 'domain/resource' (e.g., nvidia.com/GRID_T4-2Q)", resourceName)
+	}
+
+	if display, ok := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationVGpuDisplay); ok {
+		if _, err := parseVGpuDisplay(utils.AnnotationVGpuDisplay, display); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Apply adds the vGPU to the VM's GPU devices
+func (f *VGpu) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, k8sClient client.Client) (*MutationResult, error) {
+	logger := log.FromContext(ctx)
+	result := NewMutationResult()
+
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+
+	// Validate before applying
+	if err := f.Validate(ctx, vm, k8sClient); err != nil {
+		return result, err
+	}
+
+	if vm.Spec.Template == nil {
+		return result, fmt.Errorf("VM template is nil")
+	}
+
+	resourceName, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationVGpu)
+
+	gpus := vm.Spec.Template.Spec.Domain.Devices.GPUs
+	usedNames := make(map[string]bool, len(gpus))
+	for _, gpu := range gpus {
+		if gpu.DeviceName == resourceName {
+			logger.Info("vGPU already attached, skipping", "vm", vm.Name, "deviceName", resourceName)
+			if !previouslyApplied(vm, utils.AnnotationVGpuApplied, resourceName) {
+				result.AddWarning(fmt.Sprintf("vGPU %s is already attached, skipping", resourceName))
+			}
+			return result, nil
+		}
+		usedNames[gpu.Name] = true
+	}
+
+	// Generate a unique device name
+	name := ""
+	for i := 0; ; i++ {
+		name = fmt.Sprintf("vgpu-device-%d", i)
+		if !usedNames[name] {
+			break
+		}
+	}
+
+	gpu := kubevirtv1.GPU{
+		Name:       name,
+		DeviceName: resourceName,
+	}
+	if display, ok := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationVGpuDisplay); ok {
+		displayOptions, _ := parseVGpuDisplay(utils.AnnotationVGpuDisplay, display)
+		gpu.VirtualGPUOptions = &kubevirtv1.VGPUOptions{Display: displayOptions}
+	}
+
+	logger.Info("Applying vGPU feature", "vm", vm.Name, "deviceName", resourceName, "name", name)
+
+	vm.Spec.Template.Spec.Domain.Devices.GPUs = append(gpus, gpu)
+
+	if added := requireNodeLabels(vm, f.Name(), f.config.NodeSelector, result); len(added) > 0 {
+		result.AddMessage(fmt.Sprintf("Required node labels: %s", strings.Join(added, ", ")))
+	}
+
+	result.Applied = true
+	result.AddAnnotation(utils.AnnotationVGpuApplied, resourceName)
+	result.AddMessage(fmt.Sprintf("Attached vGPU %s as %s", resourceName, name))
+
+	return result, nil
+}
+
+// parseVGpuDisplay converts a GPU display value read from key into display options
+func parseVGpuDisplay(key, value string) (*kubevirtv1.VGPUDisplayOptions, error) {
+	enabled := true
+	switch {
+	case strings.EqualFold(value, utils.VGpuDisplayRamFB):
+		return &kubevirtv1.VGPUDisplayOptions{
+			Enabled: &enabled,
+			RamFB:   &kubevirtv1.FeatureState{Enabled: &enabled},
+		}, nil
+	case utils.IsTruthyValue(value):
+		return &kubevirtv1.VGPUDisplayOptions{Enabled: &enabled}, nil
+	case strings.EqualFold(value, "disabled") || strings.EqualFold(value, "false"):
+		disabled := false
+		return &kubevirtv1.VGPUDisplayOptions{Enabled: &disabled}, nil
+	default:
+		return nil, fmt.Errorf("invalid value for %s: %s (expected 'enabled', '%s' or 'disabled')",
+			key, value, utils.VGpuDisplayRamFB)
+	}
+}
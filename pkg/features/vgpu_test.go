@@ -0,0 +1,185 @@
+package features_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("VGpu", func() {
+	var (
+		feature *features.VGpu
+		vgpuCfg *config.VGpuConfig
+		vm      *kubevirtv1.VirtualMachine
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		vgpuCfg = &config.VGpuConfig{Enabled: true}
+		feature = features.NewVGpu(vgpuCfg, utils.ConfigSourceAnnotations)
+		ctx = context.Background()
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("Name", func() {
+		It("should return the correct feature name", func() {
+			Expect(feature.Name()).To(Equal(utils.FeatureVGpu))
+		})
+	})
+
+	Describe("IsEnabled", func() {
+		It("should return false when annotation is not present", func() {
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return true when a resource name is set", func() {
+			vm.Annotations = map[string]string{utils.AnnotationVGpu: "nvidia.com/GRID_T4-2Q"}
+			Expect(feature.IsEnabled(vm)).To(BeTrue())
+		})
+
+		It("should return false for an empty value", func() {
+			vm.Annotations = map[string]string{utils.AnnotationVGpu: ""}
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+
+		It("should return false when disabled in config", func() {
+			vgpuCfg.Enabled = false
+			vm.Annotations = map[string]string{utils.AnnotationVGpu: "nvidia.com/GRID_T4-2Q"}
+			Expect(feature.IsEnabled(vm)).To(BeFalse())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept mdev resource names", func() {
+			for _, name := range []string{"nvidia.com/GRID_T4-2Q", "nvidia.com/NVIDIA_A10-4Q", "intel.com/i915-GVTg_V5_4"} {
+				vm.Annotations = map[string]string{utils.AnnotationVGpu: name}
+				Expect(feature.Validate(ctx, vm, nil)).To(Succeed(), name)
+			}
+		})
+
+		It("should reject names without a domain", func() {
+			vm.Annotations = map[string]string{utils.AnnotationVGpu: "GRID_T4-2Q"}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid vGPU resource name"))
+		})
+
+		It("should reject an empty name", func() {
+			vm.Annotations = map[string]string{utils.AnnotationVGpu: ""}
+			Expect(feature.Validate(ctx, vm, nil)).ToNot(Succeed())
+		})
+
+		It("should reject invalid display values", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationVGpu:        "nvidia.com/GRID_T4-2Q",
+				utils.AnnotationVGpuDisplay: "vnc",
+			}
+			err := feature.Validate(ctx, vm, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(utils.AnnotationVGpuDisplay))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should add the vGPU with a generated name", func() {
+			vm.Annotations = map[string]string{utils.AnnotationVGpu: "nvidia.com/GRID_T4-2Q"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue(utils.AnnotationVGpuApplied, "nvidia.com/GRID_T4-2Q"))
+
+			gpus := vm.Spec.Template.Spec.Domain.Devices.GPUs
+			Expect(gpus).To(HaveLen(1))
+			Expect(gpus[0].Name).To(Equal("vgpu-device-0"))
+			Expect(gpus[0].DeviceName).To(Equal("nvidia.com/GRID_T4-2Q"))
+			Expect(gpus[0].VirtualGPUOptions).To(BeNil())
+		})
+
+		It("should avoid existing GPU names", func() {
+			vm.Spec.Template.Spec.Domain.Devices.GPUs = []kubevirtv1.GPU{
+				{Name: "vgpu-device-0", DeviceName: "nvidia.com/GRID_T4-1Q"},
+			}
+			vm.Annotations = map[string]string{utils.AnnotationVGpu: "nvidia.com/GRID_T4-2Q"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			gpus := vm.Spec.Template.Spec.Domain.Devices.GPUs
+			Expect(gpus).To(HaveLen(2))
+			Expect(gpus[1].Name).To(Equal("vgpu-device-1"))
+		})
+
+		It("should not add the same device twice", func() {
+			vm.Spec.Template.Spec.Domain.Devices.GPUs = []kubevirtv1.GPU{
+				{Name: "gpu1", DeviceName: "nvidia.com/GRID_T4-2Q"},
+			}
+			vm.Annotations = map[string]string{utils.AnnotationVGpu: "nvidia.com/GRID_T4-2Q"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Applied).To(BeFalse())
+			Expect(vm.Spec.Template.Spec.Domain.Devices.GPUs).To(HaveLen(1))
+		})
+
+		It("should configure the display with ramfb", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationVGpu:        "nvidia.com/GRID_T4-2Q",
+				utils.AnnotationVGpuDisplay: "ramfb",
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			options := vm.Spec.Template.Spec.Domain.Devices.GPUs[0].VirtualGPUOptions
+			Expect(options).ToNot(BeNil())
+			Expect(*options.Display.Enabled).To(BeTrue())
+			Expect(*options.Display.RamFB.Enabled).To(BeTrue())
+		})
+
+		It("should disable the display", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationVGpu:        "nvidia.com/GRID_T4-2Q",
+				utils.AnnotationVGpuDisplay: "disabled",
+			}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			options := vm.Spec.Template.Spec.Domain.Devices.GPUs[0].VirtualGPUOptions
+			Expect(*options.Display.Enabled).To(BeFalse())
+			Expect(options.Display.RamFB).To(BeNil())
+		})
+
+		It("should require the configured node labels", func() {
+			vgpuCfg.NodeSelector = map[string]string{"nvidia.com/vgpu.present": "true"}
+			vm.Annotations = map[string]string{utils.AnnotationVGpu: "nvidia.com/GRID_T4-2Q"}
+			result, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue("nvidia.com/vgpu.present", "true"))
+			Expect(result.Messages).To(ContainElement(ContainSubstring("nvidia.com/vgpu.present")))
+		})
+
+		It("should not add node labels when none are configured", func() {
+			vm.Annotations = map[string]string{utils.AnnotationVGpu: "nvidia.com/GRID_T4-2Q"}
+			_, err := feature.Apply(ctx, vm, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Spec.Template.Spec.NodeSelector).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,181 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// maxExecStderr caps the stderr kept for error messages
+const maxExecStderr = 512
+
+// errOutputLimit reports an executable writing more than the output limit
+var errOutputLimit = errors.New("output limit exceeded")
+
+// ExecFeature runs an executable for VMs that set vm-feature-manager.io/<name>,
+// where name is the executable's file name without extension. The VM JSON is
+// written to stdin and an RFC 6902 JSON patch against it is read from stdout.
+type ExecFeature struct {
+	name         string
+	path         string
+	configSource utils.ConfigSource
+	timeout      time.Duration
+	maxOutput    int
+}
+
+// LoadExecDir creates a feature for every executable regular file in dir, in
+// name order. Each run is bounded by timeout and maxOutput bytes of stdout.
+func LoadExecDir(dir string, configSource utils.ConfigSource, timeout time.Duration, maxOutput int) ([]*ExecFeature, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exec hooks in %s: %w", dir, err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var loaded []*ExecFeature
+	for _, entry := range entries {
+		// Follow symlinks, which ConfigMap volumes use for their keys
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		loaded = append(loaded, NewExecFeature(path, configSource, timeout, maxOutput))
+	}
+	return loaded, nil
+}
+
+// NewExecFeature creates a feature running the executable at path
+func NewExecFeature(path string, configSource utils.ConfigSource, timeout time.Duration, maxOutput int) *ExecFeature {
+	base := filepath.Base(path)
+	return &ExecFeature{
+		name:         strings.TrimSuffix(base, filepath.Ext(base)),
+		path:         path,
+		configSource: configSource,
+		timeout:      timeout,
+		maxOutput:    maxOutput,
+	}
+}
+
+// Name returns the feature name
+func (f *ExecFeature) Name() string {
+	return f.name
+}
+
+// requestKey is the key VMs set to request the feature. The mutator hands
+// plugins the VM with a custom key prefix swapped to the default one.
+func (f *ExecFeature) requestKey() string {
+	return utils.DefaultKeyPrefix + f.name
+}
+
+// IsEnabled checks if the feature is requested with a value other than a disabling one
+func (f *ExecFeature) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	value, exists := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), f.requestKey())
+	return exists && value != "" && !utils.IsFalsyValue(value)
+}
+
+// Validate is a no-op; the executable reports problems when it runs
+func (f *ExecFeature) Validate(_ context.Context, _ *kubevirtv1.VirtualMachine, _ client.Client) error {
+	return nil
+}
+
+// Apply runs the executable and applies the patch it prints to the VM. The
+// requested value and dry-run state are passed in VM_FEATURE_VALUE and
+// VM_FEATURE_DRY_RUN.
+func (f *ExecFeature) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) (*features.MutationResult, error) {
+	result := features.NewMutationResult()
+	if !f.IsEnabled(vm) {
+		return result, nil
+	}
+	value, _ := utils.GetConfigValue(f.configSource, vm.GetAnnotations(), vm.GetLabels(), f.requestKey())
+
+	vmBytes, err := json.Marshal(vm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode VM: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: f.maxOutput}
+	stderr := &limitedBuffer{limit: maxExecStderr, truncate: true}
+	cmd := exec.CommandContext(ctx, f.path)
+	cmd.Stdin = bytes.NewReader(vmBytes)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(),
+		"VM_FEATURE_VALUE="+value,
+		fmt.Sprintf("VM_FEATURE_DRY_RUN=%t", features.IsDryRun(ctx)),
+	)
+	// Don't wait on pipes held open by children the hook left behind
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		switch {
+		case ctx.Err() != nil:
+			return nil, fmt.Errorf("exec hook %s timed out after %s", f.name, f.timeout)
+		case errors.Is(err, errOutputLimit):
+			return nil, fmt.Errorf("exec hook %s wrote more than %d bytes", f.name, f.maxOutput)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("exec hook %s failed: %s", f.name, msg)
+		}
+		return nil, fmt.Errorf("exec hook %s failed: %w", f.name, err)
+	}
+
+	patch := bytes.TrimSpace(stdout.Bytes())
+	if len(patch) == 0 || string(patch) == "[]" {
+		return result, nil
+	}
+	result, err = applyResponse(f.name, vm, vmBytes, &ApplyResponse{Applied: true, Patch: patch})
+	if err != nil {
+		return nil, err
+	}
+	result.AddAnnotation(f.requestKey()+"-applied", value)
+	result.AddMessage(fmt.Sprintf("Applied exec hook %s", f.name))
+	return result, nil
+}
+
+// limitedBuffer collects up to limit bytes. Beyond that it fails the write,
+// or silently drops the rest when truncate is set.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	truncate bool
+}
+
+// Write implements io.Writer
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); len(p) > remaining {
+		if !b.truncate {
+			return 0, errOutputLimit
+		}
+		b.buf.Write(p[:max(remaining, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// Bytes returns the collected output
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// String returns the collected output as a string
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,129 @@
+package plugins_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/plugins"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("ExecFeature", func() {
+	var (
+		dir string
+		vm  *kubevirtv1.VirtualMachine
+		ctx context.Context
+	)
+
+	writeHook := func(name, script string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755)).To(Succeed())
+		return path
+	}
+
+	newHook := func(path string) *plugins.ExecFeature {
+		return plugins.NewExecFeature(path, utils.ConfigSourceAnnotations, 2*time.Second, 1024)
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		ctx = context.Background()
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-vm",
+				Namespace:   "default",
+				Annotations: map[string]string{utils.DefaultKeyPrefix + "team-label": "ml"},
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+			},
+		}
+	})
+
+	It("should load executables in name order", func() {
+		writeHook("b-hook.sh", "exit 0\n")
+		writeHook("a-hook", "exit 0\n")
+		Expect(os.WriteFile(filepath.Join(dir, "README"), []byte("not a hook"), 0o644)).To(Succeed())
+
+		hooks, err := plugins.LoadExecDir(dir, utils.ConfigSourceAnnotations, time.Second, 1024)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hooks).To(HaveLen(2))
+		Expect(hooks[0].Name()).To(Equal("a-hook"))
+		Expect(hooks[1].Name()).To(Equal("b-hook"))
+	})
+
+	It("should be enabled by its key", func() {
+		hook := newHook(writeHook("team-label.sh", "exit 0\n"))
+		Expect(hook.IsEnabled(vm)).To(BeTrue())
+
+		vm.Annotations[utils.DefaultKeyPrefix+"team-label"] = "disabled"
+		Expect(hook.IsEnabled(vm)).To(BeFalse())
+	})
+
+	It("should apply the patch printed by the hook", func() {
+		hook := newHook(writeHook("team-label.sh", `cat > /dev/null
+printf '[{"op": "add", "path": "/spec/template/metadata/labels", "value": {"team": "%s"}}]' "$VM_FEATURE_VALUE"
+`))
+
+		result, err := hook.Apply(ctx, vm, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Applied).To(BeTrue())
+		Expect(result.Annotations).To(HaveKeyWithValue(utils.DefaultKeyPrefix+"team-label-applied", "ml"))
+		Expect(vm.Spec.Template.ObjectMeta.Labels).To(HaveKeyWithValue("team", "ml"))
+	})
+
+	It("should pass the VM on stdin", func() {
+		hook := newHook(writeHook("team-label.sh", `grep -q '"name":"test-vm"' || exit 1
+echo '[]'
+`))
+
+		result, err := hook.Apply(ctx, vm, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Applied).To(BeFalse())
+	})
+
+	It("should pass the dry-run state", func() {
+		hook := newHook(writeHook("team-label.sh", `cat > /dev/null
+[ "$VM_FEATURE_DRY_RUN" = true ] || exit 1
+`))
+
+		_, err := hook.Apply(features.WithDryRun(ctx, true), vm, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should report stderr when the hook fails", func() {
+		hook := newHook(writeHook("team-label.sh", "echo 'unknown team' >&2\nexit 3\n"))
+
+		_, err := hook.Apply(ctx, vm, nil)
+		Expect(err).To(MatchError("exec hook team-label failed: unknown team"))
+	})
+
+	It("should enforce the timeout", func() {
+		hook := plugins.NewExecFeature(writeHook("team-label.sh", "sleep 10\n"), utils.ConfigSourceAnnotations, 100*time.Millisecond, 1024)
+
+		_, err := hook.Apply(ctx, vm, nil)
+		Expect(err).To(MatchError(ContainSubstring("timed out")))
+	})
+
+	It("should enforce the output limit", func() {
+		hook := newHook(writeHook("team-label.sh", "cat > /dev/null\nhead -c 4096 /dev/zero\n"))
+
+		_, err := hook.Apply(ctx, vm, nil)
+		Expect(err).To(MatchError(ContainSubstring("wrote more than 1024 bytes")))
+	})
+
+	It("should reject output that isn't a JSON patch", func() {
+		hook := newHook(writeHook("team-label.sh", "cat > /dev/null\necho 'not json'\n"))
+
+		_, err := hook.Apply(ctx, vm, nil)
+		Expect(err).To(MatchError(ContainSubstring("invalid patch from plugin team-label")))
+	})
+})
@@ -0,0 +1,162 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+)
+
+// GRPCFeature is a feature served by an external gRPC plugin
+type GRPCFeature struct {
+	name    string
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+// NewGRPCFeature creates a feature backed by the plugin at endpoint. The
+// connection is established lazily and uses plaintext unless opts override
+// the transport credentials.
+func NewGRPCFeature(name, endpoint string, timeout time.Duration, opts ...grpc.DialOption) (*GRPCFeature, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for plugin %s at %s: %w", name, endpoint, err)
+	}
+	return &GRPCFeature{name: name, conn: conn, timeout: timeout}, nil
+}
+
+// Name returns the feature name
+func (f *GRPCFeature) Name() string {
+	return f.name
+}
+
+// CheckName asks the plugin for the feature name it serves and reports a
+// mismatch with the configured name
+func (f *GRPCFeature) CheckName(ctx context.Context) error {
+	out := &wrapperspb.StringValue{}
+	if err := f.invoke(ctx, "Name", &emptypb.Empty{}, out); err != nil {
+		return err
+	}
+	if out.GetValue() != f.name {
+		return fmt.Errorf("plugin configured as %s serves feature %s", f.name, out.GetValue())
+	}
+	return nil
+}
+
+// IsEnabled asks the plugin whether the VM requests the feature. An
+// unreachable plugin is logged and treated as not requested.
+func (f *GRPCFeature) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	in, err := vmValue(vm)
+	if err != nil {
+		log.Log.Error(err, "Failed to encode VM for plugin", "plugin", f.name)
+		return false
+	}
+
+	out := &wrapperspb.BoolValue{}
+	if err := f.invoke(context.Background(), "IsEnabled", in, out); err != nil {
+		log.Log.Error(err, "Failed to query plugin", "plugin", f.name)
+		return false
+	}
+	return out.GetValue()
+}
+
+// Validate has the plugin check the VM's request
+func (f *GRPCFeature) Validate(ctx context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	in, err := vmValue(vm)
+	if err != nil {
+		return err
+	}
+	return f.invoke(ctx, "Validate", in, &emptypb.Empty{})
+}
+
+// Apply has the plugin compute its mutation and applies the returned patch to the VM
+func (f *GRPCFeature) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) (*features.MutationResult, error) {
+	in, err := vmValue(vm)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &wrapperspb.BytesValue{}
+	if err := f.invoke(ctx, "Apply", in, out); err != nil {
+		return nil, err
+	}
+
+	var response ApplyResponse
+	if err := json.Unmarshal(out.GetValue(), &response); err != nil {
+		return nil, fmt.Errorf("invalid response from plugin %s: %w", f.name, err)
+	}
+	return applyResponse(f.name, vm, in.GetValue(), &response)
+}
+
+// Close releases the plugin connection
+func (f *GRPCFeature) Close() error {
+	return f.conn.Close()
+}
+
+// invoke calls a plugin method within the feature's timeout, using the gRPC
+// status message as the error
+func (f *GRPCFeature) invoke(ctx context.Context, method string, in, out proto.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	if err := f.conn.Invoke(ctx, "/"+featurePluginService+"/"+method, in, out); err != nil {
+		return fmt.Errorf("plugin %s: %s", f.name, status.Convert(err).Message())
+	}
+	return nil
+}
+
+// vmValue encodes the VM as the JSON payload of a plugin request
+func vmValue(vm *kubevirtv1.VirtualMachine) (*wrapperspb.BytesValue, error) {
+	vmBytes, err := json.Marshal(vm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode VM: %w", err)
+	}
+	return wrapperspb.Bytes(vmBytes), nil
+}
+
+// applyResponse applies a plugin's patch to the VM, whose JSON form the patch
+// was computed against, and converts the response to a MutationResult
+func applyResponse(name string, vm *kubevirtv1.VirtualMachine, vmBytes []byte, response *ApplyResponse) (*features.MutationResult, error) {
+	if len(response.Patch) > 0 && string(response.Patch) != "null" {
+		patch, err := jsonpatch.DecodePatch(response.Patch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid patch from plugin %s: %w", name, err)
+		}
+		patchedBytes, err := patch.Apply(vmBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply patch from plugin %s: %w", name, err)
+		}
+		patched := &kubevirtv1.VirtualMachine{}
+		if err := json.Unmarshal(patchedBytes, patched); err != nil {
+			return nil, fmt.Errorf("patch from plugin %s produced an invalid VM: %w", name, err)
+		}
+		*vm = *patched
+	}
+
+	result := features.NewMutationResult()
+	result.Applied = response.Applied
+	for key, value := range response.Annotations {
+		result.AddAnnotation(key, value)
+	}
+	for _, msg := range response.Messages {
+		result.AddMessage(msg)
+	}
+	for _, msg := range response.Warnings {
+		result.AddWarning(msg)
+	}
+	return result, nil
+}
@@ -0,0 +1,153 @@
+package plugins_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/plugins"
+)
+
+const teamLabelKey = "example.com/team"
+
+// teamLabelPlugin copies the VM's example.com/team annotation to its template labels
+type teamLabelPlugin struct{}
+
+func (teamLabelPlugin) Name(context.Context) (string, error) {
+	return "team-label", nil
+}
+
+func (teamLabelPlugin) IsEnabled(_ context.Context, vmBytes []byte) (bool, error) {
+	team, err := requestedTeam(vmBytes)
+	return team != "", err
+}
+
+func (teamLabelPlugin) Validate(_ context.Context, vmBytes []byte) error {
+	team, err := requestedTeam(vmBytes)
+	if err != nil {
+		return err
+	}
+	if team == "invalid" {
+		return status.Error(codes.InvalidArgument, "unknown team invalid")
+	}
+	return nil
+}
+
+func (teamLabelPlugin) Apply(_ context.Context, vmBytes []byte) (*plugins.ApplyResponse, error) {
+	team, err := requestedTeam(vmBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &plugins.ApplyResponse{
+		Applied:     true,
+		Patch:       json.RawMessage(`[{"op": "add", "path": "/spec/template/metadata/labels", "value": {"team": "` + team + `"}}]`),
+		Annotations: map[string]string{teamLabelKey + "-applied": team},
+		Warnings:    []string{"team labels are informational"},
+	}, nil
+}
+
+func requestedTeam(vmBytes []byte) (string, error) {
+	vm := &kubevirtv1.VirtualMachine{}
+	if err := json.Unmarshal(vmBytes, vm); err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	return vm.Annotations[teamLabelKey], nil
+}
+
+var _ = Describe("GRPCFeature", func() {
+	var (
+		server   *grpc.Server
+		listener *bufconn.Listener
+		feature  *plugins.GRPCFeature
+		vm       *kubevirtv1.VirtualMachine
+		ctx      context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		listener = bufconn.Listen(1024 * 1024)
+		server = grpc.NewServer()
+		plugins.RegisterFeaturePluginServer(server, teamLabelPlugin{})
+		go func() {
+			_ = server.Serve(listener)
+		}()
+
+		var err error
+		feature, err = plugins.NewGRPCFeature("team-label", "passthrough:///bufnet", time.Second,
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}))
+		Expect(err).ToNot(HaveOccurred())
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-vm",
+				Namespace:   "default",
+				Annotations: map[string]string{teamLabelKey: "ml"},
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(feature.Close()).To(Succeed())
+		server.Stop()
+	})
+
+	It("should use the configured name", func() {
+		Expect(feature.Name()).To(Equal("team-label"))
+		Expect(feature.CheckName(ctx)).To(Succeed())
+	})
+
+	It("should report a plugin serving another feature", func() {
+		other, err := plugins.NewGRPCFeature("other", "passthrough:///bufnet", time.Second,
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}))
+		Expect(err).ToNot(HaveOccurred())
+		defer other.Close()
+
+		Expect(other.CheckName(ctx)).To(MatchError(ContainSubstring("serves feature team-label")))
+	})
+
+	It("should ask the plugin whether the feature is enabled", func() {
+		Expect(feature.IsEnabled(vm)).To(BeTrue())
+
+		vm.Annotations = nil
+		Expect(feature.IsEnabled(vm)).To(BeFalse())
+	})
+
+	It("should surface validation errors from the plugin", func() {
+		Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+
+		vm.Annotations[teamLabelKey] = "invalid"
+		Expect(feature.Validate(ctx, vm, nil)).To(MatchError("plugin team-label: unknown team invalid"))
+	})
+
+	It("should apply the patch returned by the plugin", func() {
+		result, err := feature.Apply(ctx, vm, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Applied).To(BeTrue())
+		Expect(result.Annotations).To(HaveKeyWithValue(teamLabelKey+"-applied", "ml"))
+		Expect(result.Warnings).To(ConsistOf("team labels are informational"))
+		Expect(vm.Spec.Template.ObjectMeta.Labels).To(HaveKeyWithValue("team", "ml"))
+		Expect(vm.Name).To(Equal("test-vm"))
+	})
+
+	It("should treat an unreachable plugin as not requested", func() {
+		server.Stop()
+		Expect(feature.IsEnabled(vm)).To(BeFalse())
+	})
+})
@@ -0,0 +1,121 @@
+// Package plugins provides features served outside the webhook process.
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// featurePluginService is the fully qualified service name from plugin.proto
+const featurePluginService = "vmfeaturemanager.plugin.v1.FeaturePlugin"
+
+// ApplyResponse is the JSON document a plugin returns from Apply
+type ApplyResponse struct {
+	// Applied indicates the feature was applied
+	Applied bool `json:"applied"`
+
+	// Patch is an RFC 6902 JSON patch against the VM sent to the plugin
+	Patch json.RawMessage `json:"patch,omitempty"`
+
+	// Annotations are tracking annotations to add to the VM
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Messages are informational messages about the mutation
+	Messages []string `json:"messages,omitempty"`
+
+	// Warnings are surfaced to the client in the admission response
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// FeaturePluginServer is implemented by plugins written in Go. The VM is
+// passed as JSON; returned errors should be gRPC status errors.
+type FeaturePluginServer interface {
+	Name(ctx context.Context) (string, error)
+	IsEnabled(ctx context.Context, vm []byte) (bool, error)
+	Validate(ctx context.Context, vm []byte) error
+	Apply(ctx context.Context, vm []byte) (*ApplyResponse, error)
+}
+
+// RegisterFeaturePluginServer registers a plugin implementation with a gRPC server
+func RegisterFeaturePluginServer(s grpc.ServiceRegistrar, srv FeaturePluginServer) {
+	s.RegisterService(&featurePluginServiceDesc, srv)
+}
+
+// featurePluginServiceDesc describes the FeaturePlugin service; its messages
+// are protobuf well-known types, so no generated code is needed
+var featurePluginServiceDesc = grpc.ServiceDesc{
+	ServiceName: featurePluginService,
+	HandlerType: (*FeaturePluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Name",
+			Handler: unaryHandler("Name", func(ctx context.Context, srv FeaturePluginServer, _ *emptypb.Empty) (proto.Message, error) {
+				name, err := srv.Name(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return wrapperspb.String(name), nil
+			}),
+		},
+		{
+			MethodName: "IsEnabled",
+			Handler: unaryHandler("IsEnabled", func(ctx context.Context, srv FeaturePluginServer, in *wrapperspb.BytesValue) (proto.Message, error) {
+				enabled, err := srv.IsEnabled(ctx, in.GetValue())
+				if err != nil {
+					return nil, err
+				}
+				return wrapperspb.Bool(enabled), nil
+			}),
+		},
+		{
+			MethodName: "Validate",
+			Handler: unaryHandler("Validate", func(ctx context.Context, srv FeaturePluginServer, in *wrapperspb.BytesValue) (proto.Message, error) {
+				if err := srv.Validate(ctx, in.GetValue()); err != nil {
+					return nil, err
+				}
+				return &emptypb.Empty{}, nil
+			}),
+		},
+		{
+			MethodName: "Apply",
+			Handler: unaryHandler("Apply", func(ctx context.Context, srv FeaturePluginServer, in *wrapperspb.BytesValue) (proto.Message, error) {
+				response, err := srv.Apply(ctx, in.GetValue())
+				if err != nil {
+					return nil, err
+				}
+				out, err := json.Marshal(response)
+				if err != nil {
+					return nil, err
+				}
+				return wrapperspb.Bytes(out), nil
+			}),
+		},
+	},
+	Metadata: "plugin.proto",
+}
+
+// unaryHandler adapts a typed method to a grpc.MethodHandler, running any
+// server interceptor like generated code does
+func unaryHandler[T any, PT interface {
+	*T
+	proto.Message
+}](method string, call func(context.Context, FeaturePluginServer, PT) (proto.Message, error)) grpc.MethodHandler {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		in := PT(new(T))
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(ctx, srv.(FeaturePluginServer), in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + featurePluginService + "/" + method}
+		return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+			return call(ctx, srv.(FeaturePluginServer), req.(PT))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Command teamlabel is a WASM feature module used by the plugin tests. It
+// copies the VM's example.com/team annotation to its template labels.
+//
+// Build with: GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o teamlabel.wasm
+package main
+
+import (
+	"encoding/json"
+	"unsafe"
+)
+
+const teamLabelKey = "example.com/team"
+
+// buffers keeps memory handed to the host alive until the next call
+var buffers [][]byte
+
+func main() {}
+
+//go:wasmexport alloc
+func alloc(size uint32) uint32 {
+	buf := make([]byte, size)
+	buffers = append(buffers, buf)
+	return uint32(uintptr(unsafe.Pointer(unsafe.SliceData(buf))))
+}
+
+//go:wasmexport feature_name
+func featureName() uint64 {
+	return result([]byte("team-label"))
+}
+
+//go:wasmexport feature_is_enabled
+func featureIsEnabled(ptr, size uint32) uint32 {
+	if requestedTeam(ptr, size) != "" {
+		return 1
+	}
+	return 0
+}
+
+//go:wasmexport feature_validate
+func featureValidate(ptr, size uint32) uint64 {
+	switch requestedTeam(ptr, size) {
+	case "invalid":
+		return result([]byte("unknown team invalid"))
+	case "slow":
+		// Never returns, for the timeout tests
+		for {
+		}
+	}
+	return 0
+}
+
+//go:wasmexport feature_apply
+func featureApply(ptr, size uint32) uint64 {
+	team := requestedTeam(ptr, size)
+	response, _ := json.Marshal(map[string]any{
+		"applied":     true,
+		"patch":       []map[string]any{{"op": "add", "path": "/spec/template/metadata/labels", "value": map[string]string{"team": team}}},
+		"annotations": map[string]string{teamLabelKey + "-applied": team},
+	})
+	return result(response)
+}
+
+// requestedTeam reads the team annotation from the VM JSON the host wrote
+func requestedTeam(ptr, size uint32) string {
+	input := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(ptr))), size)
+	var vm struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(input, &vm); err != nil {
+		return ""
+	}
+	buffers = nil
+	return vm.Metadata.Annotations[teamLabelKey]
+}
+
+// result packs a buffer's address and length for the host
+func result(data []byte) uint64 {
+	buffers = append(buffers, data)
+	return uint64(uintptr(unsafe.Pointer(unsafe.SliceData(data))))<<32 | uint64(len(data))
+}
@@ -0,0 +1,323 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+)
+
+// wasmPageSize is the size of a WebAssembly memory page
+const wasmPageSize = 64 * 1024
+
+// Exports a WASM feature module must provide. Strings and JSON documents are
+// returned as a uint64 packing the address (high 32 bits) and length (low 32
+// bits) of a buffer in the module's memory, which must stay valid until the
+// next call; inputs are written to memory obtained from alloc.
+const (
+	wasmExportAlloc     = "alloc"              // alloc(size u32) -> ptr u32
+	wasmExportName      = "feature_name"       // feature_name() -> packed name
+	wasmExportIsEnabled = "feature_is_enabled" // feature_is_enabled(ptr, len) -> u32 (non-zero when requested)
+	wasmExportValidate  = "feature_validate"   // feature_validate(ptr, len) -> packed error message, 0 when valid
+	wasmExportApply     = "feature_apply"      // feature_apply(ptr, len) -> packed ApplyResponse JSON
+)
+
+// wasmApplyResponse is the JSON document returned by feature_apply; Error
+// reports a failure
+type wasmApplyResponse struct {
+	ApplyResponse
+	Error string `json:"error,omitempty"`
+}
+
+// WASMRuntime hosts sandboxed WASM feature modules. Modules get no
+// filesystem, network, environment or clock beyond WASI's defaults, and their
+// memory is capped.
+type WASMRuntime struct {
+	runtime wazero.Runtime
+	timeout time.Duration
+}
+
+// NewWASMRuntime creates a runtime limiting each module to memoryLimitMB of
+// memory and each call to timeout
+func NewWASMRuntime(ctx context.Context, memoryLimitMB int, timeout time.Duration) (*WASMRuntime, error) {
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(memoryLimitMB * 1024 * 1024 / wasmPageSize)).
+		WithCloseOnContextDone(true)
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		_ = runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+	return &WASMRuntime{runtime: runtime, timeout: timeout}, nil
+}
+
+// LoadDir loads every *.wasm file in dir, in name order
+func (r *WASMRuntime) LoadDir(ctx context.Context, dir string) ([]*WASMFeature, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.wasm"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list WASM modules in %s: %w", dir, err)
+	}
+	sort.Strings(paths)
+
+	var loaded []*WASMFeature
+	for _, path := range paths {
+		wasm, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WASM module %s: %w", path, err)
+		}
+		feature, err := r.Load(ctx, filepath.Base(path), wasm)
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, feature)
+	}
+	return loaded, nil
+}
+
+// Load compiles and instantiates a WASM feature module. source names the
+// module in errors.
+func (r *WASMRuntime) Load(ctx context.Context, source string, wasm []byte) (*WASMFeature, error) {
+	compiled, err := r.runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile WASM module %s: %w", source, err)
+	}
+	for _, export := range []string{wasmExportAlloc, wasmExportName, wasmExportIsEnabled, wasmExportValidate, wasmExportApply} {
+		if _, ok := compiled.ExportedFunctions()[export]; !ok {
+			return nil, fmt.Errorf("WASM module %s does not export %s", source, export)
+		}
+	}
+
+	// Reactor modules (e.g. Go's c-shared build) initialize through _initialize
+	moduleConfig := wazero.NewModuleConfig().WithName("").WithStartFunctions()
+	if _, ok := compiled.ExportedFunctions()["_initialize"]; ok {
+		moduleConfig = moduleConfig.WithStartFunctions("_initialize")
+	} else if _, ok := compiled.ExportedFunctions()["_start"]; ok {
+		moduleConfig = moduleConfig.WithStartFunctions("_start")
+	}
+
+	feature := &WASMFeature{
+		source:       source,
+		timeout:      r.timeout,
+		runtime:      r.runtime,
+		compiled:     compiled,
+		moduleConfig: moduleConfig,
+	}
+	if err := feature.instantiate(ctx); err != nil {
+		return nil, err
+	}
+	name, err := feature.callString(ctx, wasmExportName)
+	if err != nil {
+		_ = feature.module.Close(ctx)
+		return nil, err
+	}
+	if name == "" {
+		_ = feature.module.Close(ctx)
+		return nil, fmt.Errorf("WASM module %s returned an empty feature name", source)
+	}
+	feature.name = name
+	return feature, nil
+}
+
+// Close releases all modules loaded by the runtime
+func (r *WASMRuntime) Close(ctx context.Context) error {
+	return r.runtime.Close(ctx)
+}
+
+// WASMFeature is a feature implemented by a WASM module. Module instances are
+// single threaded, so calls are serialized. A call that times out terminates
+// the instance, and the next call runs on a fresh one.
+type WASMFeature struct {
+	name         string
+	source       string
+	timeout      time.Duration
+	runtime      wazero.Runtime
+	compiled     wazero.CompiledModule
+	moduleConfig wazero.ModuleConfig
+
+	mu     sync.Mutex
+	module api.Module
+}
+
+// instantiate replaces the module instance with a fresh one; f.mu must be
+// held once the feature is in use
+func (f *WASMFeature) instantiate(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	module, err := f.runtime.InstantiateModule(ctx, f.compiled, f.moduleConfig)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate WASM module %s: %w", f.source, err)
+	}
+	f.module = module
+	return nil
+}
+
+// Name returns the feature name reported by the module
+func (f *WASMFeature) Name() string {
+	return f.name
+}
+
+// IsEnabled asks the module whether the VM requests the feature. A failing
+// module is logged and treated as not requested.
+func (f *WASMFeature) IsEnabled(vm *kubevirtv1.VirtualMachine) bool {
+	vmBytes, err := json.Marshal(vm)
+	if err != nil {
+		log.Log.Error(err, "Failed to encode VM for WASM module", "plugin", f.name)
+		return false
+	}
+
+	results, err := f.call(context.Background(), wasmExportIsEnabled, vmBytes)
+	if err != nil {
+		log.Log.Error(err, "Failed to query WASM module", "plugin", f.name)
+		return false
+	}
+	return uint32(results[0]) != 0
+}
+
+// Validate has the module check the VM's request
+func (f *WASMFeature) Validate(ctx context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) error {
+	vmBytes, err := json.Marshal(vm)
+	if err != nil {
+		return fmt.Errorf("failed to encode VM: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	results, err := f.callLocked(ctx, wasmExportValidate, vmBytes)
+	if err != nil {
+		return err
+	}
+	if results[0] == 0 {
+		return nil
+	}
+	msg, err := f.read(results[0])
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("plugin %s: %s", f.name, msg)
+}
+
+// Apply has the module compute its mutation and applies the returned patch to the VM
+func (f *WASMFeature) Apply(ctx context.Context, vm *kubevirtv1.VirtualMachine, _ client.Client) (*features.MutationResult, error) {
+	vmBytes, err := json.Marshal(vm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode VM: %w", err)
+	}
+
+	f.mu.Lock()
+	results, err := f.callLocked(ctx, wasmExportApply, vmBytes)
+	var out []byte
+	if err == nil {
+		out, err = f.read(results[0])
+	}
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var response wasmApplyResponse
+	if err := json.Unmarshal(out, &response); err != nil {
+		return nil, fmt.Errorf("invalid response from plugin %s: %w", f.name, err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", f.name, response.Error)
+	}
+	return applyResponse(f.name, vm, vmBytes, &response.ApplyResponse)
+}
+
+// call invokes an export with input written to module memory
+func (f *WASMFeature) call(ctx context.Context, export string, input []byte) ([]uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.callLocked(ctx, export, input)
+}
+
+// callLocked invokes an export within the feature's timeout; f.mu must be held
+func (f *WASMFeature) callLocked(ctx context.Context, export string, input []byte) ([]uint64, error) {
+	if f.module.IsClosed() {
+		if err := f.instantiate(ctx); err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", f.pluginName(), err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	var params []uint64
+	if input != nil {
+		allocated, err := f.module.ExportedFunction(wasmExportAlloc).Call(ctx, uint64(len(input)))
+		if err != nil {
+			return nil, wasmCallError(f.pluginName(), export, err)
+		}
+		ptr := uint32(allocated[0])
+		if !f.module.Memory().Write(ptr, input) {
+			return nil, fmt.Errorf("plugin %s: alloc returned memory out of range", f.pluginName())
+		}
+		params = []uint64{uint64(ptr), uint64(len(input))}
+	}
+
+	results, err := f.module.ExportedFunction(export).Call(ctx, params...)
+	if err != nil {
+		return nil, wasmCallError(f.pluginName(), export, err)
+	}
+	if len(results) != 1 {
+		return nil, fmt.Errorf("plugin %s: %s must return one value", f.pluginName(), export)
+	}
+	return results, nil
+}
+
+// callString invokes an export without input that returns a packed string
+func (f *WASMFeature) callString(ctx context.Context, export string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	results, err := f.callLocked(ctx, export, nil)
+	if err != nil {
+		return "", err
+	}
+	out, err := f.read(results[0])
+	return string(out), err
+}
+
+// read copies a packed address and length out of module memory; f.mu must be held
+func (f *WASMFeature) read(packed uint64) ([]byte, error) {
+	ptr, size := uint32(packed>>32), uint32(packed)
+	data, ok := f.module.Memory().Read(ptr, size)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: result out of memory range", f.pluginName())
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// pluginName names the feature in errors, falling back to the module file
+// before the name is known
+func (f *WASMFeature) pluginName() string {
+	if f.name != "" {
+		return f.name
+	}
+	return f.source
+}
+
+// wasmCallError describes a failed call; a timeout terminates the module instance
+func wasmCallError(name, export string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "deadline exceeded") {
+		return fmt.Errorf("plugin %s: %s timed out", name, export)
+	}
+	return fmt.Errorf("plugin %s: %s failed: %w", name, export, err)
+}
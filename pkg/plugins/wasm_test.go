@@ -0,0 +1,121 @@
+package plugins_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/plugins"
+)
+
+var _ = Describe("WASMFeature", Ordered, func() {
+	var (
+		moduleDir string
+		runtime   *plugins.WASMRuntime
+		feature   *plugins.WASMFeature
+		vm        *kubevirtv1.VirtualMachine
+		ctx       context.Context
+	)
+
+	BeforeAll(func() {
+		moduleDir = GinkgoT().TempDir()
+		build := exec.Command("go", "build", "-buildmode=c-shared", "-o", filepath.Join(moduleDir, "team-label.wasm"), ".")
+		build.Dir = filepath.Join("testdata", "teamlabel")
+		build.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+		output, err := build.CombinedOutput()
+		Expect(err).ToNot(HaveOccurred(), string(output))
+	})
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		var err error
+		runtime, err = plugins.NewWASMRuntime(ctx, 128, 5*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		loaded, err := runtime.LoadDir(ctx, moduleDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loaded).To(HaveLen(1))
+		feature = loaded[0]
+
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-vm",
+				Namespace:   "default",
+				Annotations: map[string]string{teamLabelKey: "ml"},
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(runtime.Close(ctx)).To(Succeed())
+	})
+
+	It("should use the name reported by the module", func() {
+		Expect(feature.Name()).To(Equal("team-label"))
+	})
+
+	It("should ask the module whether the feature is enabled", func() {
+		Expect(feature.IsEnabled(vm)).To(BeTrue())
+
+		vm.Annotations = nil
+		Expect(feature.IsEnabled(vm)).To(BeFalse())
+	})
+
+	It("should surface validation errors from the module", func() {
+		Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+
+		vm.Annotations[teamLabelKey] = "invalid"
+		Expect(feature.Validate(ctx, vm, nil)).To(MatchError("plugin team-label: unknown team invalid"))
+	})
+
+	It("should apply the patch returned by the module", func() {
+		result, err := feature.Apply(ctx, vm, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Applied).To(BeTrue())
+		Expect(result.Annotations).To(HaveKeyWithValue(teamLabelKey+"-applied", "ml"))
+		Expect(vm.Spec.Template.ObjectMeta.Labels).To(HaveKeyWithValue("team", "ml"))
+	})
+
+	It("should keep working across calls", func() {
+		for range 20 {
+			_, err := feature.Apply(ctx, vm.DeepCopy(), nil)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("should run later calls on a fresh instance after a timeout", func() {
+		vm.Annotations[teamLabelKey] = "slow"
+		timeoutCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		Expect(feature.Validate(timeoutCtx, vm, nil)).To(MatchError(ContainSubstring("timed out")))
+
+		vm.Annotations[teamLabelKey] = "ml"
+		Expect(feature.Validate(ctx, vm, nil)).To(Succeed())
+		Expect(feature.IsEnabled(vm)).To(BeTrue())
+	})
+
+	It("should reject modules missing the feature exports", func() {
+		// An empty module: magic number and version only
+		_, err := runtime.Load(ctx, "empty.wasm", []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00})
+		Expect(err).To(MatchError(ContainSubstring("does not export alloc")))
+	})
+
+	It("should reject modules that exceed the memory limit", func() {
+		small, err := plugins.NewWASMRuntime(ctx, 1, 5*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		defer small.Close(ctx)
+
+		_, err = small.LoadDir(ctx, moduleDir)
+		Expect(err).To(HaveOccurred())
+	})
+})
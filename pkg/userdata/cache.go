@@ -0,0 +1,90 @@
+package userdata
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/lru"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// secretCacheLookups counts userdata Secret cache lookups by result, so the
+// hit rate is hits / (hits + misses)
+var secretCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "vm_feature_manager_userdata_secret_cache_lookups_total",
+	Help: "Userdata Secret cache lookups by result (hit or miss)",
+}, []string{"result"})
+
+func init() {
+	metrics.Registry.MustRegister(secretCacheLookups)
+}
+
+// secretEntry is what is kept of a Secret userdata is read from
+type secretEntry struct {
+	resourceVersion string
+	// labeled is whether the Secret is labeled LabelUserdataSecret=allowed
+	labeled bool
+	// data holds only the keys userdata and networkData are read from
+	data    map[string][]byte
+	expires time.Time
+}
+
+// secretCache remembers recently read userdata Secrets, so admissions of VMs
+// sharing a bootstrap Secret, e.g. during a MachineDeployment scale-up, don't
+// each fetch it. Entries are served for up to ttl, so Secret changes may take
+// that long to be seen.
+type secretCache struct {
+	entries *lru.Cache
+	ttl     time.Duration
+}
+
+// newSecretCache returns a cache of up to size Secrets, or nil when size or
+// ttl disable caching
+func newSecretCache(size int, ttl time.Duration) *secretCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &secretCache{entries: lru.New(size), ttl: ttl}
+}
+
+// secret returns the Secret namespace/name, from the cache while it is fresh
+func (p *Parser) secret(ctx context.Context, namespace, name string) (*secretEntry, error) {
+	cacheKey := namespace + "/" + name
+	if p.cache != nil {
+		if cached, ok := p.cache.entries.Get(cacheKey); ok && time.Now().Before(cached.(*secretEntry).expires) {
+			secretCacheLookups.WithLabelValues("hit").Inc()
+			return cached.(*secretEntry), nil
+		}
+		secretCacheLookups.WithLabelValues("miss").Inc()
+	}
+
+	secret := &corev1.Secret{}
+	if err := p.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to fetch secret %s/%s: %w", namespace, name, err)
+	}
+
+	entry := &secretEntry{
+		resourceVersion: secret.ResourceVersion,
+		labeled:         secret.Labels[utils.LabelUserdataSecret] == "allowed",
+		data:            map[string][]byte{},
+	}
+	for _, kind := range []dataKind{p.userDataKind(), networkDataKind} {
+		for _, key := range kind.keys {
+			if data, ok := secret.Data[key]; ok {
+				entry.data[key] = data
+			}
+		}
+	}
+
+	if p.cache != nil {
+		entry.expires = time.Now().Add(p.cache.ttl)
+		p.cache.entries.Add(cacheKey, entry)
+	}
+	return entry, nil
+}
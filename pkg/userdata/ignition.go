@@ -0,0 +1,116 @@
+package userdata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// IgnitionFeaturesPath is the file in an Ignition config's storage.files whose
+// contents hold feature directives, e.g. "nested_virt: enabled"
+const IgnitionFeaturesPath = "/etc/vm-feature-manager/features.yaml"
+
+// ignitionConfig is the part of an Ignition config feature directives are read from
+type ignitionConfig struct {
+	Storage struct {
+		Files []ignitionFile `json:"files"`
+	} `json:"storage"`
+}
+
+// ignitionFile is an entry of an Ignition config's storage.files
+type ignitionFile struct {
+	Path     string `json:"path"`
+	Contents struct {
+		Source      string `json:"source"`
+		Compression string `json:"compression"`
+	} `json:"contents"`
+}
+
+// isIgnition reports whether the parsed userdata document is an Ignition config
+func isIgnition(doc map[string]interface{}) bool {
+	ignition, ok := doc["ignition"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = ignition["version"]
+	return ok
+}
+
+// ignitionDirectives returns the feature directives in the IgnitionFeaturesPath
+// file of an Ignition config, or nil when it has none
+func ignitionDirectives(userData string, maxSize int) (map[string]interface{}, error) {
+	var config ignitionConfig
+	if err := yaml.Unmarshal([]byte(userData), &config); err != nil {
+		return nil, fmt.Errorf("invalid Ignition config: %w", err)
+	}
+
+	for _, file := range config.Storage.Files {
+		if file.Path != IgnitionFeaturesPath {
+			continue
+		}
+		contents, err := decodeDataURL(file.Contents.Source)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", IgnitionFeaturesPath, err)
+		}
+		if file.Contents.Compression == "gzip" {
+			if contents, err = gunzip(contents, maxSize); err != nil {
+				return nil, fmt.Errorf("%s: %w", IgnitionFeaturesPath, err)
+			}
+		} else if file.Contents.Compression != "" {
+			return nil, fmt.Errorf("%s: unsupported compression %q", IgnitionFeaturesPath, file.Contents.Compression)
+		}
+
+		directives := map[string]interface{}{}
+		if err := yaml.Unmarshal(contents, &directives); err != nil {
+			return nil, fmt.Errorf("%s: %w", IgnitionFeaturesPath, err)
+		}
+		return directives, nil
+	}
+	return nil, nil
+}
+
+// decodeDataURL returns the data of an RFC 2397 data URL. Other sources, such
+// as remote URLs, aren't fetched.
+func decodeDataURL(source string) ([]byte, error) {
+	rest, ok := strings.CutPrefix(source, "data:")
+	if !ok {
+		return nil, fmt.Errorf("only data URLs are supported")
+	}
+	header, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid data URL")
+	}
+
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data URL: %w", err)
+	}
+	return []byte(decoded), nil
+}
+
+// gunzip decompresses data, up to maxSize bytes
+func gunzip(data []byte, maxSize int) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > maxSize {
+		return nil, fmt.Errorf("decompressed contents exceed %d bytes", maxSize)
+	}
+	return decompressed, nil
+}
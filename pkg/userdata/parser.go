@@ -1,88 +1,162 @@
 // Package userdata provides parsing of feature directives from VM userdata.
 // It supports extracting x_kubevirt_features dictionary entries from cloud-init userdata
-// in various formats: plain text, base64-encoded, or Secret references.
+// in various formats: plain text, base64-encoded, or Secret references. Ignition
+// configs may carry the directives in a designated storage file instead.
 package userdata
 
 import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/yaml"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
 
+// defaultMaxUserdataSize is the largest userdata feature directives are read
+// from when no limit is configured
+const defaultMaxUserdataSize = 65536
+
 // Parser extracts feature directives from VM userdata
 type Parser struct {
 	client client.Client
+	cfg    *config.UserdataSecretsConfig
+	cache  *secretCache
 }
 
-// NewParser creates a new userdata parser
-func NewParser(client client.Client) *Parser {
-	return &Parser{
+// NewParser creates a new userdata parser reading Secrets as allowed by cfg
+func NewParser(client client.Client, cfg *config.UserdataSecretsConfig) *Parser {
+	p := &Parser{
 		client: client,
+		cfg:    cfg,
+	}
+	if cfg != nil {
+		p.cache = newSecretCache(cfg.CacheSize, time.Duration(cfg.CacheTTLSeconds)*time.Second)
+	}
+	return p
+}
+
+// maxSize returns the largest userdata feature directives are read from
+func (p *Parser) maxSize() int {
+	if p.cfg != nil && p.cfg.MaxSize > 0 {
+		return p.cfg.MaxSize
 	}
+	return defaultMaxUserdataSize
+}
+
+// userDataKind returns the userdata kind with the configured Secret keys
+func (p *Parser) userDataKind() dataKind {
+	if p.cfg != nil && len(p.cfg.Keys) > 0 {
+		return dataKind{name: defaultUserDataKind.name, keys: p.cfg.Keys}
+	}
+	return defaultUserDataKind
 }
 
 // ParseFeatures extracts feature directives from VM userdata volumes
-// and returns them as a map of annotation key -> value
-func (p *Parser) ParseFeatures(ctx context.Context, vm *kubevirtv1.VirtualMachine) (map[string]string, error) {
+// and returns them as a map of annotation key -> value.
+// Volumes whose userdata cannot be read are skipped and reported in the
+// returned error alongside the features found in the other volumes.
+// Malformed x_kubevirt_features entries are dropped and, like entries naming
+// neither a built-in feature nor one of extraFeatures, reported as warnings.
+func (p *Parser) ParseFeatures(ctx context.Context, vm *kubevirtv1.VirtualMachine, extraFeatures ...string) (map[string]string, []string, error) {
 	logger := log.FromContext(ctx)
 	features := make(map[string]string)
+	var warnings []string
+	var errs []error
 
 	if vm.Spec.Template == nil {
-		return features, nil
+		return features, nil, nil
 	}
 
-	// Iterate through volumes looking for cloud-init userdata
+	// Iterate through volumes looking for cloud-init userdata and networkData
 	for _, volume := range vm.Spec.Template.Spec.Volumes {
-		var userData string
+		var userData, networkData string
 		var err error
 
 		// Handle CloudInitNoCloud
-		if volume.CloudInitNoCloud != nil {
-			userData, err = p.extractUserData(ctx, vm, volume.CloudInitNoCloud.UserData, volume.CloudInitNoCloud.UserDataBase64, volume.CloudInitNoCloud.UserDataSecretRef)
+		if source := volume.CloudInitNoCloud; source != nil {
+			userData, err = p.extractData(ctx, vm, p.userDataKind(), source.UserData, source.UserDataBase64, source.UserDataSecretRef)
 			if err != nil {
 				logger.Error(err, "Failed to extract userdata from CloudInitNoCloud", "volume", volume.Name)
+				errs = append(errs, fmt.Errorf("volume %s: %w", volume.Name, err))
 				continue
 			}
+			networkData, err = p.extractData(ctx, vm, networkDataKind, source.NetworkData, source.NetworkDataBase64, source.NetworkDataSecretRef)
+			if err != nil {
+				logger.Error(err, "Failed to extract networkData from CloudInitNoCloud", "volume", volume.Name)
+				errs = append(errs, fmt.Errorf("volume %s: %w", volume.Name, err))
+			}
 		}
 
 		// Handle CloudInitConfigDrive
-		if volume.CloudInitConfigDrive != nil {
-			userData, err = p.extractUserData(ctx, vm, volume.CloudInitConfigDrive.UserData, volume.CloudInitConfigDrive.UserDataBase64, volume.CloudInitConfigDrive.UserDataSecretRef)
+		if source := volume.CloudInitConfigDrive; source != nil {
+			userData, err = p.extractData(ctx, vm, p.userDataKind(), source.UserData, source.UserDataBase64, source.UserDataSecretRef)
 			if err != nil {
 				logger.Error(err, "Failed to extract userdata from CloudInitConfigDrive", "volume", volume.Name)
+				errs = append(errs, fmt.Errorf("volume %s: %w", volume.Name, err))
 				continue
 			}
+			networkData, err = p.extractData(ctx, vm, networkDataKind, source.NetworkData, source.NetworkDataBase64, source.NetworkDataSecretRef)
+			if err != nil {
+				logger.Error(err, "Failed to extract networkData from CloudInitConfigDrive", "volume", volume.Name)
+				errs = append(errs, fmt.Errorf("volume %s: %w", volume.Name, err))
+			}
 		}
 
-		// Parse feature directives from userdata
+		// Parse feature directives from networkData comments, then from
+		// userdata comments and x_kubevirt_features, which win within the volume
+		volumeFeatures := map[string]string{}
+		if networkData != "" {
+			maps.Copy(volumeFeatures, parseCommentDirectives(networkData, p.maxSize()))
+		}
 		if userData != "" {
-			volumeFeatures := p.parseDirectives(userData)
-			for k, v := range volumeFeatures {
-				if prev, exists := features[k]; exists {
-					logger.Info("Overwriting feature key from previous volume", "key", k, "previousValue", prev, "newValue", v, "volume", volume.Name)
-				}
-				features[k] = v
+			maps.Copy(volumeFeatures, parseCommentDirectives(userData, p.maxSize()))
+			directives, directiveWarnings := p.parseDirectives(userData, extraFeatures)
+			maps.Copy(volumeFeatures, directives)
+			for _, warning := range directiveWarnings {
+				warnings = append(warnings, fmt.Sprintf("volume %s: %s", volume.Name, warning))
 			}
 		}
+		for k, v := range volumeFeatures {
+			if prev, exists := features[k]; exists {
+				logger.Info("Overwriting feature key from previous volume", "key", k, "previousValue", prev, "newValue", v, "volume", volume.Name)
+			}
+			features[k] = v
+		}
 	}
 
 	if len(features) > 0 {
 		logger.Info("Extracted feature directives from userdata", "features", features)
 	}
 
-	return features, nil
+	return features, warnings, errors.Join(errs...)
+}
+
+// dataKind describes a kind of cloud-init data and the Secret keys it is read from
+type dataKind struct {
+	name string
+	keys []string
 }
 
-// extractUserData extracts userdata from plain text, base64, or secret reference
-func (p *Parser) extractUserData(ctx context.Context, vm *kubevirtv1.VirtualMachine, plainText, base64Text string, secretRef *corev1.LocalObjectReference) (string, error) {
+var (
+	defaultUserDataKind = dataKind{name: "userdata", keys: []string{"userdata", "userData", "user-data"}}
+	networkDataKind     = dataKind{name: "networkdata", keys: []string{"networkdata", "networkData", "network-data"}}
+)
+
+// extractData extracts cloud-init data from plain text, base64, or secret reference
+func (p *Parser) extractData(ctx context.Context, vm *kubevirtv1.VirtualMachine, kind dataKind, plainText, base64Text string, secretRef *corev1.LocalObjectReference) (string, error) {
 	// Priority: plain text -> base64 -> secret
 	if plainText != "" {
 		return plainText, nil
@@ -91,56 +165,117 @@ func (p *Parser) extractUserData(ctx context.Context, vm *kubevirtv1.VirtualMach
 	if base64Text != "" {
 		decoded, err := base64.StdEncoding.DecodeString(base64Text)
 		if err != nil {
-			return "", fmt.Errorf("failed to decode base64 userdata: %w", err)
+			return "", fmt.Errorf("failed to decode base64 %s: %w", kind.name, err)
 		}
 		return string(decoded), nil
 	}
 
 	if secretRef != nil {
-		return p.fetchSecretUserData(ctx, vm.Namespace, secretRef.Name)
+		return p.fetchSecretData(ctx, vm.Namespace, secretRef.Name, kind)
 	}
 
 	return "", nil
 }
 
-// fetchSecretUserData fetches userdata from a Kubernetes Secret.
-// Security: The webhook can read any Secret in the same namespace as the VM.
-// This assumes that if the webhook can mutate a VM in a namespace,
-// it is permitted to read the referenced Secret in that namespace.
-func (p *Parser) fetchSecretUserData(ctx context.Context, namespace, secretName string) (string, error) {
+// fetchSecretData fetches cloud-init data from a Kubernetes Secret.
+// Security: Without a guard the webhook reads any Secret in the same
+// namespace as the VM, assuming that if it can mutate a VM in a namespace it
+// is permitted to read the referenced Secret there. The guard narrows this to
+// labeled Secrets or allowlisted namespaces, so VMs can't be used to probe
+// arbitrary Secrets.
+func (p *Parser) fetchSecretData(ctx context.Context, namespace, secretName string, kind dataKind) (string, error) {
 	logger := log.FromContext(ctx)
 
-	secret := &corev1.Secret{}
-	key := client.ObjectKey{
-		Namespace: namespace,
-		Name:      secretName,
+	guard := utils.UserdataSecretGuardNone
+	if p.cfg != nil && p.cfg.Guard != "" {
+		guard = p.cfg.Guard
+	}
+	switch guard {
+	case utils.UserdataSecretGuardNone, utils.UserdataSecretGuardLabel:
+	case utils.UserdataSecretGuardNamespaceAllowlist:
+		if !slices.Contains(p.cfg.Namespaces, namespace) {
+			return "", fmt.Errorf("reading %s secrets is not allowed in namespace %s", kind.name, namespace)
+		}
+	default:
+		return "", fmt.Errorf("unknown userdata secret guard %q", guard)
 	}
 
-	if err := p.client.Get(ctx, key, secret); err != nil {
-		return "", fmt.Errorf("failed to fetch secret %s/%s: %w", namespace, secretName, err)
+	secret, err := p.secret(ctx, namespace, secretName)
+	if err != nil {
+		return "", err
 	}
 
-	// No guard: Assume if the webhook can mutate the VM in a namespace,
-	// it is permitted to read the referenced Secret in that namespace.
+	if guard == utils.UserdataSecretGuardLabel && !secret.labeled {
+		return "", fmt.Errorf("secret %s/%s is not labeled %s=allowed", namespace, secretName, utils.LabelUserdataSecret)
+	}
 
-	// Try common userdata keys
-	for _, key := range []string{"userdata", "userData", "user-data"} {
-		if data, ok := secret.Data[key]; ok {
-			logger.Info("Found userdata in secret", "secret", secretName, "key", key)
+	// Try common keys
+	for _, key := range kind.keys {
+		if data, ok := secret.data[key]; ok {
+			logger.Info("Found "+kind.name+" in secret", "secret", secretName, "key", key, "resourceVersion", secret.resourceVersion)
 			return string(data), nil
 		}
 	}
 
-	return "", fmt.Errorf("no userdata found in secret %s/%s (tried keys: userdata, userData, user-data)", namespace, secretName)
+	return "", fmt.Errorf("no %s found in secret %s/%s (tried keys: %s)", kind.name, namespace, secretName, strings.Join(kind.keys, ", "))
+}
+
+// commentDirectivePrefix starts a feature directive comment, for data such as
+// networkData that can't carry an x_kubevirt_features key
+const commentDirectivePrefix = "@kubevirt-feature"
+
+// commentDirective returns the directive of a "# @kubevirt-feature ..." line
+func commentDirective(line string) (string, bool) {
+	comment, ok := strings.CutPrefix(strings.TrimSpace(line), "#")
+	if !ok {
+		return "", false
+	}
+	directive, ok := strings.CutPrefix(strings.TrimSpace(comment), commentDirectivePrefix)
+	if !ok || directive == "" || (directive[0] != ' ' && directive[0] != '\t') {
+		return "", false
+	}
+	return strings.TrimSpace(directive), true
 }
 
-// parseDirectives extracts x_kubevirt_features dictionary from userdata text
-func (p *Parser) parseDirectives(userData string) map[string]string {
+// parseCommentDirectives extracts "# @kubevirt-feature name=value" comments
+// from data of up to maxSize bytes. A name without a value enables the feature.
+func parseCommentDirectives(data string, maxSize int) map[string]string {
 	features := make(map[string]string)
+	if len(data) > maxSize {
+		return features
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		directive, ok := commentDirective(line)
+		if !ok {
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(directive, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !hasValue {
+			value = "enabled"
+		}
+		if name == "" || value == "" || len(value) > 1024 {
+			continue
+		}
+		features[utils.DefaultKeyPrefix+strings.ReplaceAll(name, "_", "-")] = value
+	}
+	return features
+}
+
+// parseDirectives extracts x_kubevirt_features dictionary from userdata text,
+// with warnings for the entries that are dropped or unknown
+func (p *Parser) parseDirectives(userData string, extraFeatures []string) (map[string]string, []string) {
+	features := make(map[string]string)
+	var warnings []string
 
 	// Reject overly large userdata to prevent resource exhaustion
-	if len(userData) > 65536 { // 64KB limit
-		return features
+	if len(userData) > p.maxSize() {
+		if strings.Contains(userData, "x_kubevirt_features") {
+			warnings = append(warnings, fmt.Sprintf("userdata exceeds %d bytes, x_kubevirt_features ignored", p.maxSize()))
+		}
+		return features, warnings
 	}
 
 	// Parse userdata as YAML to extract x_kubevirt_features
@@ -149,19 +284,29 @@ func (p *Parser) parseDirectives(userData string) map[string]string {
 		// Not valid YAML or not a map, return empty features
 		// Log at debug level to help troubleshoot why features aren't being applied
 		log.Log.V(1).Info("Failed to parse userdata as YAML, skipping feature extraction", "error", err)
-		return features
+		return features, nil
 	}
 
 	// Look for x_kubevirt_features key
-	xKubevirtFeatures, exists := cloudConfig["x_kubevirt_features"]
-	if !exists {
-		return features
-	}
+	featuresMap, _ := cloudConfig["x_kubevirt_features"].(map[string]interface{})
 
-	// Convert x_kubevirt_features to map
-	featuresMap, ok := xKubevirtFeatures.(map[string]interface{})
-	if !ok {
-		return features
+	// Ignition configs may instead carry directives in a designated file,
+	// which takes precedence over the key
+	if isIgnition(cloudConfig) {
+		fileFeatures, err := ignitionDirectives(userData, p.maxSize())
+		if err != nil {
+			log.Log.Info("Failed to read feature directives from Ignition config", "error", err.Error())
+			warnings = append(warnings, err.Error())
+		}
+		if len(fileFeatures) > 0 && featuresMap == nil {
+			featuresMap = make(map[string]interface{}, len(fileFeatures))
+		}
+		for k, v := range fileFeatures {
+			featuresMap[k] = v
+		}
+	}
+	if featuresMap == nil {
+		return features, warnings
 	}
 
 	// Process each feature
@@ -169,6 +314,15 @@ func (p *Parser) parseDirectives(userData string) map[string]string {
 		// Convert feature name to kebab-case (underscores to hyphens)
 		featureNameKebab := strings.ReplaceAll(featureName, "_", "-")
 
+		// Type-check the entry against the schema of the built-in features
+		if err := checkDirective(featureNameKebab, featureValue); err != nil {
+			warnings = append(warnings, fmt.Sprintf("x_kubevirt_features.%s ignored: %v", featureName, err))
+			continue
+		}
+		if _, known := directiveSchema[featureNameKebab]; !known && !slices.Contains(extraFeatures, featureNameKebab) {
+			warnings = append(warnings, fmt.Sprintf("x_kubevirt_features.%s is not a known feature", featureName))
+		}
+
 		// Convert feature value to string
 		var valueStr string
 		switch v := featureValue.(type) {
@@ -184,7 +338,7 @@ func (p *Parser) parseDirectives(userData string) map[string]string {
 			// Marshal back to JSON for complex values (e.g., pci-passthrough)
 			jsonBytes, err := json.Marshal(v)
 			if err != nil {
-				log.Log.V(1).Info("Failed to marshal complex feature value to JSON, skipping", "feature", featureName, "error", err)
+				warnings = append(warnings, fmt.Sprintf("x_kubevirt_features.%s ignored: %v", featureName, err))
 				continue
 			}
 			valueStr = string(jsonBytes)
@@ -192,7 +346,7 @@ func (p *Parser) parseDirectives(userData string) map[string]string {
 			// Try to convert to string via JSON
 			jsonBytes, err := json.Marshal(v)
 			if err != nil {
-				log.Log.V(1).Info("Failed to marshal feature value to JSON, skipping", "feature", featureName, "type", fmt.Sprintf("%T", v), "error", err)
+				warnings = append(warnings, fmt.Sprintf("x_kubevirt_features.%s ignored: %v", featureName, err))
 				continue
 			}
 			valueStr = string(jsonBytes)
@@ -202,13 +356,14 @@ func (p *Parser) parseDirectives(userData string) map[string]string {
 		// Limit is 1024 bytes per value, which is sufficient for all expected feature directives.
 		// If a larger value is needed, review and document the security implications before increasing.
 		if len(valueStr) > 1024 {
-			continue // Skip overly long values
+			warnings = append(warnings, fmt.Sprintf("x_kubevirt_features.%s ignored: value exceeds 1024 bytes", featureName))
+			continue
 		}
 
 		// Map feature names to annotation keys
-		annotationKey := fmt.Sprintf("vm-feature-manager.io/%s", featureNameKebab)
+		annotationKey := utils.DefaultKeyPrefix + featureNameKebab
 		features[annotationKey] = valueStr
 	}
 
-	return features
+	return features, warnings
 }
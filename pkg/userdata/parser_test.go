@@ -1,7 +1,10 @@
 package userdata_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -11,8 +14,12 @@ import (
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/userdata"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
 
 var _ = Describe("Userdata Parser", func() {
@@ -26,7 +33,7 @@ var _ = Describe("Userdata Parser", func() {
 		ctx = context.Background()
 		scheme := setupScheme()
 		fakeClient = fake.NewClientBuilder().WithScheme(scheme).Build()
-		parser = userdata.NewParser(fakeClient)
+		parser = userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{Guard: utils.UserdataSecretGuardNone})
 	})
 
 	Describe("ParseFeatures", func() {
@@ -60,7 +67,7 @@ users:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
 			})
@@ -98,7 +105,7 @@ users:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(HaveLen(3))
 				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
@@ -134,7 +141,7 @@ x_kubevirt_features:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(HaveLen(2))
 				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
@@ -173,13 +180,88 @@ packages:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(HaveLen(1))
 				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
 			})
 		})
 
+		Context("with x_kubevirt_features schema validation", func() {
+			userdataVM := func(userData string) *kubevirtv1.VirtualMachine {
+				return &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: "default"},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{{
+									Name: "cloudinit",
+									VolumeSource: kubevirtv1.VolumeSource{
+										CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{UserData: userData},
+									},
+								}},
+							},
+						},
+					},
+				}
+			}
+
+			It("should drop malformed nested entries with a warning", func() {
+				features, warnings, err := parser.ParseFeatures(ctx, userdataVM(`#cloud-config
+x_kubevirt_features:
+  nested_virt: enabled
+  pci_passthrough:
+    devices: "0000:01:00.0"
+  usb_passthrough:
+    device: ["046d:c52b"]
+  tpm:
+    enabled: true
+  tolerations: true
+`))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(Equal(map[string]string{"vm-feature-manager.io/nested-virt": "enabled"}))
+				Expect(warnings).To(ConsistOf(
+					And(ContainSubstring("volume cloudinit"), ContainSubstring("x_kubevirt_features.pci_passthrough ignored"), ContainSubstring("[]string")),
+					And(ContainSubstring("x_kubevirt_features.usb_passthrough ignored"), ContainSubstring(`unknown field "device"`)),
+					ContainSubstring("x_kubevirt_features.tpm ignored: expected a string or boolean"),
+					ContainSubstring("x_kubevirt_features.tolerations ignored: expected a nested value"),
+				))
+			})
+
+			It("should accept nested entries given as JSON strings", func() {
+				features, warnings, err := parser.ParseFeatures(ctx, userdataVM(`#cloud-config
+x_kubevirt_features:
+  pci_passthrough: '{"devices": ["0000:01:00.0"]}'
+  boot_order: '{"rootdisk": "first"}'
+  cpu_topology: sockets=2,cores=4
+`))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/pci-passthrough", `{"devices": ["0000:01:00.0"]}`))
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/cpu-topology", "sockets=2,cores=4"))
+				Expect(features).NotTo(HaveKey("vm-feature-manager.io/boot-order"))
+				Expect(warnings).To(ConsistOf(ContainSubstring("x_kubevirt_features.boot_order ignored")))
+			})
+
+			It("should warn about unknown features but keep them", func() {
+				userData := `#cloud-config
+x_kubevirt_features:
+  nested_virtualization: enabled
+  my_plugin: enabled
+`
+				features, warnings, err := parser.ParseFeatures(ctx, userdataVM(userData))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virtualization", "enabled"))
+				Expect(warnings).To(ConsistOf(
+					ContainSubstring("x_kubevirt_features.nested_virtualization is not a known feature"),
+					ContainSubstring("x_kubevirt_features.my_plugin is not a known feature"),
+				))
+
+				_, warnings, err = parser.ParseFeatures(ctx, userdataVM(userData), "my-plugin")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(warnings).To(ConsistOf(ContainSubstring("nested_virtualization")))
+			})
+		})
+
 		Context("with base64-encoded userdata", func() {
 			It("should decode and extract features", func() {
 				vm := &kubevirtv1.VirtualMachine{
@@ -205,7 +287,7 @@ packages:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
 			})
@@ -234,8 +316,9 @@ packages:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
-				Expect(err).NotTo(HaveOccurred())
+				features, _, err := parser.ParseFeatures(ctx, vm)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to decode base64 userdata"))
 				Expect(features).To(BeEmpty())
 			})
 		})
@@ -283,7 +366,7 @@ users:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
 			})
@@ -328,7 +411,7 @@ x_kubevirt_features:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
 			})
@@ -359,12 +442,410 @@ x_kubevirt_features:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("missing-secret"))
+				Expect(features).To(BeEmpty())
+			})
+		})
+
+		Context("with configured keys and size limit", func() {
+			secretVM := func() *kubevirtv1.VirtualMachine {
+				return &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: "default"},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{{
+									Name: "cloudinit",
+									VolumeSource: kubevirtv1.VolumeSource{
+										CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+											UserDataSecretRef: &corev1.LocalObjectReference{Name: "capk-userdata"},
+										},
+									},
+								}},
+							},
+						},
+					},
+				}
+			}
+
+			BeforeEach(func() {
+				Expect(fakeClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "capk-userdata", Namespace: "default"},
+					Data: map[string][]byte{
+						"value": []byte("#cloud-config\nx_kubevirt_features:\n  nested_virt: enabled\n"),
+					},
+				})).To(Succeed())
+			})
+
+			It("should read userdata from the configured secret keys", func() {
+				_, _, err := parser.ParseFeatures(ctx, secretVM())
+				Expect(err).To(MatchError(ContainSubstring("tried keys: userdata, userData, user-data")))
+
+				parser = userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{Keys: []string{"value"}})
+				features, _, err := parser.ParseFeatures(ctx, secretVM())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
+			})
+
+			It("should skip userdata larger than the configured size", func() {
+				parser = userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{Keys: []string{"value"}, MaxSize: 16})
+				features, _, err := parser.ParseFeatures(ctx, secretVM())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(BeEmpty())
+			})
+		})
+
+		Context("with a secret cache", func() {
+			var gets int
+
+			cacheVM := func() *kubevirtv1.VirtualMachine {
+				return &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: "default"},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{{
+									Name: "cloudinit",
+									VolumeSource: kubevirtv1.VolumeSource{
+										CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+											UserDataSecretRef:    &corev1.LocalObjectReference{Name: "bootstrap"},
+											NetworkDataSecretRef: &corev1.LocalObjectReference{Name: "bootstrap"},
+										},
+									},
+								}},
+							},
+						},
+					},
+				}
+			}
+
+			// cacheHits returns the userdata Secret cache hits counted so far
+			cacheHits := func() float64 {
+				families, err := metrics.Registry.Gather()
+				Expect(err).NotTo(HaveOccurred())
+				for _, family := range families {
+					if family.GetName() != "vm_feature_manager_userdata_secret_cache_lookups_total" {
+						continue
+					}
+					for _, metric := range family.GetMetric() {
+						if metric.GetLabel()[0].GetValue() == "hit" {
+							return metric.GetCounter().GetValue()
+						}
+					}
+				}
+				return 0
+			}
+
+			BeforeEach(func() {
+				gets = 0
+				fakeClient = fake.NewClientBuilder().WithScheme(setupScheme()).WithInterceptorFuncs(interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						gets++
+						return c.Get(ctx, key, obj, opts...)
+					},
+				}).Build()
+				Expect(fakeClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "bootstrap", Namespace: "default"},
+					Data: map[string][]byte{
+						"userdata":    []byte("#cloud-config\nx_kubevirt_features:\n  nested_virt: enabled\n"),
+						"networkdata": []byte("# @kubevirt-feature tpm\nversion: 2\n"),
+					},
+				})).To(Succeed())
+			})
+
+			It("should fetch a shared Secret once while cached", func() {
+				parser = userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{CacheSize: 8, CacheTTLSeconds: 60})
+				hits := cacheHits()
+
+				for range 3 {
+					features, _, err := parser.ParseFeatures(ctx, cacheVM())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(features).To(Equal(map[string]string{
+						"vm-feature-manager.io/nested-virt": "enabled",
+						"vm-feature-manager.io/tpm":         "enabled",
+					}))
+				}
+				Expect(gets).To(Equal(1))
+				Expect(cacheHits() - hits).To(Equal(float64(5)))
+			})
+
+			It("should still apply the label guard to cached Secrets", func() {
+				parser = userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{CacheSize: 8, CacheTTLSeconds: 60})
+				_, _, err := parser.ParseFeatures(ctx, cacheVM())
+				Expect(err).NotTo(HaveOccurred())
+
+				guarded := userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{Guard: utils.UserdataSecretGuardLabel, CacheSize: 8, CacheTTLSeconds: 60})
+				for range 2 {
+					_, _, err = guarded.ParseFeatures(ctx, cacheVM())
+					Expect(err).To(MatchError(ContainSubstring("is not labeled")))
+				}
+			})
+
+			It("should fetch the Secret every time with the cache disabled", func() {
+				parser = userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{CacheTTLSeconds: 60})
+				for range 2 {
+					_, _, err := parser.ParseFeatures(ctx, cacheVM())
+					Expect(err).NotTo(HaveOccurred())
+				}
+				Expect(gets).To(Equal(4))
+			})
+		})
+
+		Context("with a secret guard", func() {
+			var vm *kubevirtv1.VirtualMachine
+
+			BeforeEach(func() {
+				Expect(fakeClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "labeled-secret", Namespace: "default", Labels: map[string]string{utils.LabelUserdataSecret: "allowed"}},
+					Data:       map[string][]byte{"userdata": []byte("x_kubevirt_features:\n  nested_virt: enabled\n")},
+				})).To(Succeed())
+				Expect(fakeClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "plain-secret", Namespace: "default"},
+					Data:       map[string][]byte{"userdata": []byte("x_kubevirt_features:\n  nested_virt: enabled\n")},
+				})).To(Succeed())
+
+				vm = &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: "default"},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{{
+									Name: "cloudinit",
+									VolumeSource: kubevirtv1.VolumeSource{
+										CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+											UserDataSecretRef: &corev1.LocalObjectReference{Name: "labeled-secret"},
+										},
+									},
+								}},
+							},
+						},
+					},
+				}
+			})
+
+			useSecret := func(name string) {
+				vm.Spec.Template.Spec.Volumes[0].CloudInitNoCloud.UserDataSecretRef.Name = name
+			}
+
+			It("should only read labeled secrets in label mode", func() {
+				parser = userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{Guard: utils.UserdataSecretGuardLabel})
+
+				features, _, err := parser.ParseFeatures(ctx, vm)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
+
+				useSecret("plain-secret")
+				features, _, err = parser.ParseFeatures(ctx, vm)
+				Expect(err).To(MatchError(ContainSubstring("secret default/plain-secret is not labeled vm-feature-manager.io/userdata=allowed")))
+				Expect(features).To(BeEmpty())
+			})
+
+			It("should only read secrets in allowlisted namespaces in namespace-allowlist mode", func() {
+				parser = userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{
+					Guard:      utils.UserdataSecretGuardNamespaceAllowlist,
+					Namespaces: []string{"default"},
+				})
+				useSecret("plain-secret")
+				features, _, err := parser.ParseFeatures(ctx, vm)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(HaveKey("vm-feature-manager.io/nested-virt"))
+
+				parser = userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{
+					Guard:      utils.UserdataSecretGuardNamespaceAllowlist,
+					Namespaces: []string{"tenant-a"},
+				})
+				features, _, err = parser.ParseFeatures(ctx, vm)
+				Expect(err).To(MatchError(ContainSubstring("not allowed in namespace default")))
+				Expect(features).To(BeEmpty())
+			})
+
+			It("should refuse to read secrets with an unknown guard", func() {
+				parser = userdata.NewParser(fakeClient, &config.UserdataSecretsConfig{Guard: "everything"})
+				_, _, err := parser.ParseFeatures(ctx, vm)
+				Expect(err).To(MatchError(ContainSubstring(`unknown userdata secret guard "everything"`)))
+			})
+		})
+
+		Context("with Ignition userdata", func() {
+			ignitionVM := func(userData string) *kubevirtv1.VirtualMachine {
+				return &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: "default"},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{{
+									Name: "ignition",
+									VolumeSource: kubevirtv1.VolumeSource{
+										CloudInitConfigDrive: &kubevirtv1.CloudInitConfigDriveSource{UserData: userData},
+									},
+								}},
+							},
+						},
+					},
+				}
+			}
+
+			It("should read directives from the designated file", func() {
+				features, _, err := parser.ParseFeatures(ctx, ignitionVM(`{
+  "ignition": {"version": "3.4.0"},
+  "storage": {"files": [
+    {"path": "/etc/motd", "contents": {"source": "data:,hello"}},
+    {"path": "`+userdata.IgnitionFeaturesPath+`", "contents": {"source": "data:,nested_virt%3A%20enabled%0Agpu_device_plugin%3A%20nvidia.com%2Fgpu%0A"}}
+  ]}
+}`))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(Equal(map[string]string{
+					"vm-feature-manager.io/nested-virt":       "enabled",
+					"vm-feature-manager.io/gpu-device-plugin": "nvidia.com/gpu",
+				}))
+			})
+
+			It("should read base64 and gzip-compressed file contents", func() {
+				var compressed bytes.Buffer
+				writer := gzip.NewWriter(&compressed)
+				_, err := writer.Write([]byte("nested_virt: true\n"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(writer.Close()).To(Succeed())
+
+				features, _, err := parser.ParseFeatures(ctx, ignitionVM(`{
+  "ignition": {"version": "3.4.0"},
+  "storage": {"files": [
+    {"path": "`+userdata.IgnitionFeaturesPath+`", "contents": {"compression": "gzip", "source": "data:;base64,`+base64.StdEncoding.EncodeToString(compressed.Bytes())+`"}}
+  ]}
+}`))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
+			})
+
+			It("should let the file override x_kubevirt_features", func() {
+				features, _, err := parser.ParseFeatures(ctx, ignitionVM(`{
+  "ignition": {"version": "3.4.0"},
+  "x_kubevirt_features": {"nested_virt": "disabled", "tpm": "enabled"},
+  "storage": {"files": [
+    {"path": "`+userdata.IgnitionFeaturesPath+`", "contents": {"source": "data:,nested_virt%3A%20enabled"}}
+  ]}
+}`))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/tpm", "enabled"))
+			})
+
+			It("should not fetch remote file sources", func() {
+				features, _, err := parser.ParseFeatures(ctx, ignitionVM(`{
+  "ignition": {"version": "3.4.0"},
+  "storage": {"files": [
+    {"path": "`+userdata.IgnitionFeaturesPath+`", "contents": {"source": "https://example.com/features.yaml"}}
+  ]}
+}`))
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(BeEmpty())
 			})
 		})
 
+		Context("with networkData", func() {
+			networkDataVM := func(source *kubevirtv1.CloudInitNoCloudSource) *kubevirtv1.VirtualMachine {
+				return &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: "default"},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{{
+									Name:         "cloudinit",
+									VolumeSource: kubevirtv1.VolumeSource{CloudInitNoCloud: source},
+								}},
+							},
+						},
+					},
+				}
+			}
+
+			networkData := `version: 2
+# @kubevirt-feature nested_virt=enabled
+  # @kubevirt-feature gpu_device_plugin = nvidia.com/gpu
+# @kubevirt-feature tpm
+# @kubevirt-featureless=enabled
+ethernets:
+  eth0:
+    dhcp4: true # @kubevirt-feature pci_passthrough=0000:01:00.0
+`
+
+			It("should extract comment directives from plain networkData", func() {
+				features, _, err := parser.ParseFeatures(ctx, networkDataVM(&kubevirtv1.CloudInitNoCloudSource{NetworkData: networkData}))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(Equal(map[string]string{
+					"vm-feature-manager.io/nested-virt":       "enabled",
+					"vm-feature-manager.io/gpu-device-plugin": "nvidia.com/gpu",
+					"vm-feature-manager.io/tpm":               "enabled",
+				}))
+			})
+
+			It("should extract comment directives from base64 networkData", func() {
+				features, _, err := parser.ParseFeatures(ctx, networkDataVM(&kubevirtv1.CloudInitNoCloudSource{
+					NetworkDataBase64: base64.StdEncoding.EncodeToString([]byte(networkData)),
+				}))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
+			})
+
+			It("should extract comment directives from a networkData secret", func() {
+				secret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "network-secret", Namespace: "default"},
+					Data:       map[string][]byte{"networkData": []byte(networkData)},
+				}
+				Expect(fakeClient.Create(ctx, secret)).To(Succeed())
+
+				features, _, err := parser.ParseFeatures(ctx, networkDataVM(&kubevirtv1.CloudInitNoCloudSource{
+					NetworkDataSecretRef: &corev1.LocalObjectReference{Name: "network-secret"},
+				}))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
+			})
+
+			It("should let userdata override networkData directives", func() {
+				features, _, err := parser.ParseFeatures(ctx, networkDataVM(&kubevirtv1.CloudInitNoCloudSource{
+					NetworkData: networkData,
+					UserData: `#cloud-config
+x_kubevirt_features:
+  nested_virt: disabled
+`,
+				}))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "disabled"))
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/tpm", "enabled"))
+			})
+
+			It("should read comment directives from userdata under x_kubevirt_features", func() {
+				features, _, err := parser.ParseFeatures(ctx, networkDataVM(&kubevirtv1.CloudInitNoCloudSource{
+					UserData: `#cloud-config
+# @kubevirt-feature tpm
+# @kubevirt-feature nested_virt=enabled
+x_kubevirt_features:
+  nested_virt: disabled
+`,
+				}))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(features).To(Equal(map[string]string{
+					"vm-feature-manager.io/tpm":         "enabled",
+					"vm-feature-manager.io/nested-virt": "disabled",
+				}))
+			})
+
+			It("should keep userdata directives when networkData can't be read", func() {
+				features, _, err := parser.ParseFeatures(ctx, networkDataVM(&kubevirtv1.CloudInitNoCloudSource{
+					NetworkDataSecretRef: &corev1.LocalObjectReference{Name: "missing"},
+					UserData: `#cloud-config
+x_kubevirt_features:
+  nested_virt: enabled
+`,
+				}))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("default/missing"))
+				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
+			})
+		})
+
 		Context("with CloudInitConfigDrive", func() {
 			It("should extract features from ConfigDrive userdata", func() {
 				vm := &kubevirtv1.VirtualMachine{
@@ -393,7 +874,7 @@ x_kubevirt_features:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
 			})
@@ -438,7 +919,7 @@ x_kubevirt_features:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(HaveLen(2))
 				Expect(features).To(HaveKeyWithValue("vm-feature-manager.io/nested-virt", "enabled"))
@@ -455,7 +936,7 @@ x_kubevirt_features:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(BeEmpty())
 			})
@@ -475,12 +956,59 @@ x_kubevirt_features:
 					},
 				}
 
-				features, err := parser.ParseFeatures(ctx, vm)
+				features, _, err := parser.ParseFeatures(ctx, vm)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(features).To(BeEmpty())
 			})
 		})
 	})
+
+	Describe("StripDirectives", func() {
+		It("should remove directive comments from inline userdata and networkData", func() {
+			vm := &kubevirtv1.VirtualMachine{
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+						Spec: kubevirtv1.VirtualMachineInstanceSpec{
+							Volumes: []kubevirtv1.Volume{
+								{
+									Name: "cloudinit",
+									VolumeSource: kubevirtv1.VolumeSource{
+										CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+											UserData:    "#cloud-config\n# @kubevirt-feature tpm\n# keep me\nusers: []\n",
+											NetworkData: "  # @kubevirt-feature nested_virt=enabled\nversion: 2\n",
+										},
+									},
+								},
+								{
+									Name: "configdrive",
+									VolumeSource: kubevirtv1.VolumeSource{
+										CloudInitConfigDrive: &kubevirtv1.CloudInitConfigDriveSource{
+											UserData:       "#cloud-config\n# @kubevirt-featureless\n",
+											UserDataBase64: base64.StdEncoding.EncodeToString([]byte("# @kubevirt-feature tpm\n")),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			base64Data := vm.Spec.Template.Spec.Volumes[1].CloudInitConfigDrive.UserDataBase64
+
+			Expect(userdata.StripDirectives(vm)).To(BeTrue())
+			volumes := vm.Spec.Template.Spec.Volumes
+			Expect(volumes[0].CloudInitNoCloud.UserData).To(Equal("#cloud-config\n# keep me\nusers: []\n"))
+			Expect(volumes[0].CloudInitNoCloud.NetworkData).To(Equal("version: 2\n"))
+			Expect(volumes[1].CloudInitConfigDrive.UserData).To(Equal("#cloud-config\n# @kubevirt-featureless\n"))
+			Expect(volumes[1].CloudInitConfigDrive.UserDataBase64).To(Equal(base64Data))
+
+			Expect(userdata.StripDirectives(vm)).To(BeFalse())
+		})
+
+		It("should handle a VM without template", func() {
+			Expect(userdata.StripDirectives(&kubevirtv1.VirtualMachine{})).To(BeFalse())
+		})
+	})
 })
 
 // setupScheme creates a scheme with required types for testing
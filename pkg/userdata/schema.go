@@ -0,0 +1,128 @@
+package userdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// directiveType describes the values an x_kubevirt_features entry takes
+type directiveType struct {
+	// scalar accepts strings, booleans and numbers
+	scalar bool
+	// target returns what a nested value must decode into, or nil when the
+	// entry takes no nested values
+	target func(value interface{}) interface{}
+}
+
+// scalarDirective takes strings, booleans and numbers only
+var scalarDirective = directiveType{scalar: true}
+
+// nested returns a directiveType whose nested values decode into what
+// newTarget returns
+func nested(newTarget func() interface{}) directiveType {
+	return directiveType{target: func(interface{}) interface{} { return newTarget() }}
+}
+
+// hookSidecars decodes a single hook sidecar or a list of them
+func hookSidecars(value interface{}) interface{} {
+	if _, ok := value.([]interface{}); ok {
+		return &[]features.HookSidecar{}
+	}
+	return &features.HookSidecar{}
+}
+
+// directiveSchema lists the x_kubevirt_features entries, by kebab-case name,
+// and the values they take
+var directiveSchema = map[string]directiveType{
+	"profile":                       scalarDirective,
+	utils.FeatureNestedVirt:         scalarDirective,
+	utils.FeatureVBiosInjection:     scalarDirective,
+	"vbios-sha256":                  scalarDirective,
+	"sidecar-image":                 scalarDirective,
+	utils.FeaturePciPassthrough:     nested(func() interface{} { return &features.PCIPassthroughSpec{} }),
+	utils.FeatureGpuDevicePlugin:    scalarDirective,
+	"gpu-mode":                      scalarDirective,
+	"gpu-display":                   scalarDirective,
+	utils.FeatureTpm:                scalarDirective,
+	utils.FeatureSev:                scalarDirective,
+	utils.FeatureDedicatedCPUs:      scalarDirective,
+	"isolate-emulator-thread":       scalarDirective,
+	utils.FeatureNuma:               scalarDirective,
+	utils.FeatureRealtime:           scalarDirective,
+	utils.FeatureHyperV:             scalarDirective,
+	utils.FeatureCPUModel:           scalarDirective,
+	utils.FeatureVGpu:               scalarDirective,
+	"vgpu-display":                  scalarDirective,
+	utils.FeatureUsbPassthrough:     nested(func() interface{} { return &features.USBPassthroughSpec{} }),
+	utils.FeatureStoragePerformance: scalarDirective,
+	"io-threads":                    scalarDirective,
+	"block-multiqueue":              scalarDirective,
+	utils.FeatureNetMultiQueue:      scalarDirective,
+	utils.FeatureBootOrder:          nested(func() interface{} { return &map[string]uint{} }),
+	utils.FeatureSmbios:             nested(func() interface{} { return &features.SmbiosSpec{} }),
+	utils.FeatureTolerations:        nested(func() interface{} { return &[]corev1.Toleration{} }),
+	utils.FeatureCPUTopology:        {scalar: true, target: func(interface{}) interface{} { return &features.CPUTopologySpec{} }},
+	utils.FeatureGuaranteedQoS:      scalarDirective,
+	"hugepages-size":                scalarDirective,
+	utils.FeatureHotplug:            scalarDirective,
+	"max-sockets":                   scalarDirective,
+	"max-guest-memory":              scalarDirective,
+	utils.FeatureKernelBoot:         nested(func() interface{} { return &features.KernelBootSpec{} }),
+	utils.FeatureCdromIso:           scalarDirective,
+	utils.FeatureSSHKeys:            scalarDirective,
+	"ssh-keys-users":                scalarDirective,
+	utils.FeatureSysprep:            scalarDirective,
+	utils.FeatureGuestAgent:         scalarDirective,
+	utils.FeatureMacAddresses:       nested(func() interface{} { return &map[string]string{} }),
+	utils.FeatureOSPreset:           scalarDirective,
+	utils.FeatureMeshExclude:        scalarDirective,
+	utils.FeaturePropagateMetadata:  scalarDirective,
+	utils.FeatureHookSidecar:        {target: hookSidecars},
+}
+
+// checkDirective checks an x_kubevirt_features value against the schema entry
+// for name. Names missing from the schema aren't checked.
+func checkDirective(name string, value interface{}) error {
+	schema, ok := directiveSchema[name]
+	if !ok {
+		return nil
+	}
+
+	var encoded []byte
+	switch v := value.(type) {
+	case map[string]interface{}, []interface{}:
+		if schema.target == nil {
+			return fmt.Errorf("expected a string or boolean, got a nested value")
+		}
+		var err error
+		if encoded, err = json.Marshal(v); err != nil {
+			return err
+		}
+	case nil:
+		return fmt.Errorf("missing value")
+	case string:
+		if schema.scalar {
+			return nil
+		}
+		// Nested values may also be given as the JSON the annotation takes
+		encoded = []byte(v)
+		if err := json.Unmarshal(encoded, &value); err != nil {
+			return fmt.Errorf("expected a nested value or JSON: %w", err)
+		}
+	default:
+		if !schema.scalar {
+			return fmt.Errorf("expected a nested value, got %T", v)
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(schema.target(value))
+}
@@ -0,0 +1,51 @@
+package userdata
+
+import (
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+// StripDirectives removes "# @kubevirt-feature" comment lines from the inline
+// userdata and networkData of the VM's cloud-init volumes, so the guest never
+// sees them. Base64 and Secret sources are left as they are. It reports
+// whether anything was removed.
+func StripDirectives(vm *kubevirtv1.VirtualMachine) bool {
+	if vm.Spec.Template == nil {
+		return false
+	}
+
+	stripped := false
+	for i := range vm.Spec.Template.Spec.Volumes {
+		volume := &vm.Spec.Template.Spec.Volumes[i]
+		if source := volume.CloudInitNoCloud; source != nil {
+			stripped = stripLines(&source.UserData) || stripped
+			stripped = stripLines(&source.NetworkData) || stripped
+		}
+		if source := volume.CloudInitConfigDrive; source != nil {
+			stripped = stripLines(&source.UserData) || stripped
+			stripped = stripLines(&source.NetworkData) || stripped
+		}
+	}
+	return stripped
+}
+
+// stripLines removes directive comment lines from data in place
+func stripLines(data *string) bool {
+	if *data == "" {
+		return false
+	}
+
+	lines := strings.Split(*data, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if _, ok := commentDirective(line); !ok {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == len(lines) {
+		return false
+	}
+	*data = strings.Join(kept, "\n")
+	return true
+}
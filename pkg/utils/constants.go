@@ -3,35 +3,242 @@ package utils
 
 import "strings"
 
+// DefaultKeyPrefix prefixes all feature request, tracking and error keys.
+// Deployments can use another prefix (see SwapKeyPrefix); the constants below
+// always carry the default one. Rather than building every key from the
+// configured prefix, which would have to reach each feature, plugin and
+// userdata directive, the prefix is swapped where objects are read: the
+// admitted and stored objects in the webhook, Namespaces for namespace
+// defaults and exclusion, and VMs read by the PCI registration controller.
+// Code past those points only ever sees this prefix.
+const DefaultKeyPrefix = "vm-feature-manager.io/"
+
 const (
+	// AnnotationFeatures requests several features through one key, either as
+	// "name=value,..." pairs or as a JSON/YAML document mapping names to values
+	AnnotationFeatures = DefaultKeyPrefix + "features"
+	// AnnotationProfile names a FeatureProfile whose features apply under the VM's own requests
+	AnnotationProfile = DefaultKeyPrefix + "profile"
+	// AnnotationExclude opts a VM (or, on a Namespace, all its VMs) out of feature management
+	AnnotationExclude = DefaultKeyPrefix + "exclude"
+	// LabelUserdataSecret marks a Secret whose userdata may be read when the
+	// userdata Secret guard is "label"
+	LabelUserdataSecret = DefaultKeyPrefix + "userdata"
 	// AnnotationNestedVirt enables nested virtualization for a VM
-	AnnotationNestedVirt = "vm-feature-manager.io/nested-virt"
+	AnnotationNestedVirt = DefaultKeyPrefix + "nested-virt"
 	// AnnotationVBiosInjection specifies the ConfigMap containing the vBIOS blob
-	AnnotationVBiosInjection = "vm-feature-manager.io/vbios-injection"
+	AnnotationVBiosInjection = DefaultKeyPrefix + "vbios-injection"
 	// AnnotationPciPassthrough specifies PCI devices for passthrough (JSON array)
-	AnnotationPciPassthrough = "vm-feature-manager.io/pci-passthrough"
+	AnnotationPciPassthrough = DefaultKeyPrefix + "pci-passthrough"
 	// AnnotationGpuDevicePlugin specifies the GPU device plugin to use
-	AnnotationGpuDevicePlugin = "vm-feature-manager.io/gpu-device-plugin"
+	AnnotationGpuDevicePlugin = DefaultKeyPrefix + "gpu-device-plugin"
+	// AnnotationGpuMode selects how the GPU is assigned ("resource" or "device")
+	AnnotationGpuMode = DefaultKeyPrefix + "gpu-mode"
+	// AnnotationGpuDisplay configures the GPU display in device mode ("enabled", "ramfb", or "disabled")
+	AnnotationGpuDisplay = DefaultKeyPrefix + "gpu-display"
+	// AnnotationTpm enables a virtual TPM device ("enabled" or "persistent")
+	AnnotationTpm = DefaultKeyPrefix + "tpm"
+	// AnnotationSev enables AMD SEV confidential computing ("enabled" or "sev-es")
+	AnnotationSev = DefaultKeyPrefix + "sev"
+	// AnnotationDedicatedCPUs enables dedicated CPU placement (CPU pinning)
+	AnnotationDedicatedCPUs = DefaultKeyPrefix + "dedicated-cpus"
+	// AnnotationIsolateEmulatorThread isolates the emulator thread when dedicated CPUs are enabled
+	AnnotationIsolateEmulatorThread = DefaultKeyPrefix + "isolate-emulator-thread"
+	// AnnotationNuma enables guest NUMA topology ("guest-mapping")
+	AnnotationNuma = DefaultKeyPrefix + "numa"
+	// AnnotationRealtime enables realtime vCPUs ("enabled" or a vCPU mask such as "0-3,^1")
+	AnnotationRealtime = DefaultKeyPrefix + "realtime"
+	// AnnotationHyperV enables Hyper-V enlightenments for Windows guests
+	AnnotationHyperV = DefaultKeyPrefix + "hyperv"
+	// AnnotationCPUModel selects the guest CPU model (host-passthrough, host-model, or a named model)
+	AnnotationCPUModel = DefaultKeyPrefix + "cpu-model"
+	// AnnotationVGpu specifies the mediated device (vGPU) resource name to attach
+	AnnotationVGpu = DefaultKeyPrefix + "vgpu"
+	// AnnotationVGpuDisplay configures the vGPU display ("enabled", "ramfb", or "disabled")
+	AnnotationVGpuDisplay = DefaultKeyPrefix + "vgpu-display"
+	// AnnotationUsbPassthrough specifies USB devices for passthrough (JSON array)
+	AnnotationUsbPassthrough = DefaultKeyPrefix + "usb-passthrough"
+	// AnnotationIOThreads sets the IO threads policy ("auto" or "shared")
+	AnnotationIOThreads = DefaultKeyPrefix + "io-threads"
+	// AnnotationBlockMultiQueue enables block multi-queue for disks
+	AnnotationBlockMultiQueue = DefaultKeyPrefix + "block-multiqueue"
+	// AnnotationNetMultiQueue enables network interface multi-queue
+	AnnotationNetMultiQueue = DefaultKeyPrefix + "net-multiqueue"
+	// AnnotationBootOrder specifies boot order per disk or interface name (JSON object)
+	AnnotationBootOrder = DefaultKeyPrefix + "boot-order"
+	// AnnotationSmbios specifies SMBIOS/DMI values for the guest (JSON object)
+	AnnotationSmbios = DefaultKeyPrefix + "smbios"
+	// AnnotationTolerations specifies tolerations to add to the VM (JSON array)
+	AnnotationTolerations = DefaultKeyPrefix + "tolerations"
+	// AnnotationCPUTopology sets the vCPU topology ("sockets=2,cores=4,threads=2" or JSON)
+	AnnotationCPUTopology = DefaultKeyPrefix + "cpu-topology"
+	// AnnotationGuaranteedQoS turns the VM into a guaranteed-QoS VM
+	AnnotationGuaranteedQoS = DefaultKeyPrefix + "guaranteed-qos"
+	// AnnotationHugepagesSize overrides the hugepage size used by the guaranteed-QoS preset
+	AnnotationHugepagesSize = DefaultKeyPrefix + "hugepages-size"
+	// AnnotationMaxSockets sets the maximum CPU sockets for CPU hotplug
+	AnnotationMaxSockets = DefaultKeyPrefix + "max-sockets"
+	// AnnotationMaxGuestMemory sets the maximum guest memory for memory hotplug
+	AnnotationMaxGuestMemory = DefaultKeyPrefix + "max-guest-memory"
+	// AnnotationKernelBoot specifies a kernel boot container image and kernel args (JSON object)
+	AnnotationKernelBoot = DefaultKeyPrefix + "kernel-boot"
+	// AnnotationCdromIso specifies the DataVolume or PVC holding an ISO to attach as a CD-ROM
+	AnnotationCdromIso = DefaultKeyPrefix + "cdrom-iso"
+	// AnnotationSSHKeys specifies a Secret holding SSH public keys to propagate via the guest agent
+	AnnotationSSHKeys = DefaultKeyPrefix + "ssh-keys"
+	// AnnotationSSHKeysUsers specifies the comma-separated guest users that receive the SSH keys
+	AnnotationSSHKeysUsers = DefaultKeyPrefix + "ssh-keys-users"
+	// AnnotationSysprep specifies the ConfigMap holding a Windows unattend.xml or autounattend.xml
+	AnnotationSysprep = DefaultKeyPrefix + "sysprep"
+	// AnnotationGuestAgent requires the QEMU guest agent channel for the VM
+	AnnotationGuestAgent = DefaultKeyPrefix + "guest-agent"
+	// AnnotationMacAddresses maps interface names to static MAC addresses (JSON object)
+	AnnotationMacAddresses = DefaultKeyPrefix + "mac-addresses"
+	// AnnotationOSPreset applies a composite OS preset ("windows")
+	AnnotationOSPreset = DefaultKeyPrefix + "os-preset"
+	// AnnotationMeshExclude excludes the VM from service mesh injection ("enabled" or a list such as "istio,linkerd")
+	AnnotationMeshExclude = DefaultKeyPrefix + "mesh-exclude"
+	// AnnotationPropagateMetadata copies configured VM labels/annotations onto the VMI template
+	AnnotationPropagateMetadata = DefaultKeyPrefix + "propagate-metadata"
+	// AnnotationHookSidecar adds a KubeVirt hook sidecar (JSON object or array with image, args, configMap)
+	AnnotationHookSidecar = DefaultKeyPrefix + "hook-sidecar"
 	// AnnotationSidecarImage overrides the default sidecar image for vBIOS injection
-	AnnotationSidecarImage = "vm-feature-manager.io/sidecar-image"
+	AnnotationSidecarImage = DefaultKeyPrefix + "sidecar-image"
+	// AnnotationVBiosSHA256 specifies the expected SHA256 hex digest of the vBIOS ROM
+	AnnotationVBiosSHA256 = DefaultKeyPrefix + "vbios-sha256"
+	// AnnotationVBiosSource records the library ConfigMap or Secret a copied vBIOS ROM came from
+	AnnotationVBiosSource = DefaultKeyPrefix + "vbios-source"
 
 	// AnnotationNestedVirtApplied tracks successful nested virt application
-	AnnotationNestedVirtApplied = "vm-feature-manager.io/nested-virt-applied"
+	AnnotationNestedVirtApplied = DefaultKeyPrefix + "nested-virt-applied"
 	// AnnotationVBiosInjectionApplied tracks successful vBIOS injection
-	AnnotationVBiosInjectionApplied = "vm-feature-manager.io/vbios-injection-applied"
+	AnnotationVBiosInjectionApplied = DefaultKeyPrefix + "vbios-injection-applied"
 	// AnnotationPciPassthroughApplied tracks successful PCI passthrough
-	AnnotationPciPassthroughApplied = "vm-feature-manager.io/pci-passthrough-applied"
+	AnnotationPciPassthroughApplied = DefaultKeyPrefix + "pci-passthrough-applied"
 	// AnnotationGpuDevicePluginApplied tracks successful GPU device plugin
-	AnnotationGpuDevicePluginApplied = "vm-feature-manager.io/gpu-device-plugin-applied"
+	AnnotationGpuDevicePluginApplied = DefaultKeyPrefix + "gpu-device-plugin-applied"
+	// AnnotationTpmApplied tracks successful vTPM application
+	AnnotationTpmApplied = DefaultKeyPrefix + "tpm-applied"
+	// AnnotationSevApplied tracks successful SEV application
+	AnnotationSevApplied = DefaultKeyPrefix + "sev-applied"
+	// AnnotationDedicatedCPUsApplied tracks successful dedicated CPU placement
+	AnnotationDedicatedCPUsApplied = DefaultKeyPrefix + "dedicated-cpus-applied"
+	// AnnotationNumaApplied tracks successful guest NUMA mapping
+	AnnotationNumaApplied = DefaultKeyPrefix + "numa-applied"
+	// AnnotationRealtimeApplied tracks successful realtime application
+	AnnotationRealtimeApplied = DefaultKeyPrefix + "realtime-applied"
+	// AnnotationHyperVApplied tracks successful Hyper-V enlightenments application
+	AnnotationHyperVApplied = DefaultKeyPrefix + "hyperv-applied"
+	// AnnotationCPUModelApplied tracks successful CPU model selection
+	AnnotationCPUModelApplied = DefaultKeyPrefix + "cpu-model-applied"
+	// AnnotationVGpuApplied tracks successful vGPU attachment
+	AnnotationVGpuApplied = DefaultKeyPrefix + "vgpu-applied"
+	// AnnotationUsbPassthroughApplied tracks successful USB passthrough
+	AnnotationUsbPassthroughApplied = DefaultKeyPrefix + "usb-passthrough-applied"
+	// AnnotationStoragePerformanceApplied tracks successful storage performance tuning
+	AnnotationStoragePerformanceApplied = DefaultKeyPrefix + "storage-performance-applied"
+	// AnnotationNetMultiQueueApplied tracks successful network multi-queue application
+	AnnotationNetMultiQueueApplied = DefaultKeyPrefix + "net-multiqueue-applied"
+	// AnnotationBootOrderApplied tracks successful boot order application
+	AnnotationBootOrderApplied = DefaultKeyPrefix + "boot-order-applied"
+	// AnnotationSmbiosApplied tracks successful SMBIOS injection
+	AnnotationSmbiosApplied = DefaultKeyPrefix + "smbios-applied"
+	// AnnotationTolerationsApplied tracks successful tolerations injection
+	AnnotationTolerationsApplied = DefaultKeyPrefix + "tolerations-applied"
+	// AnnotationCPUTopologyApplied tracks successful vCPU topology application
+	AnnotationCPUTopologyApplied = DefaultKeyPrefix + "cpu-topology-applied"
+	// AnnotationGuaranteedQoSApplied tracks successful guaranteed-QoS preset application
+	AnnotationGuaranteedQoSApplied = DefaultKeyPrefix + "guaranteed-qos-applied"
+	// AnnotationHotplugApplied tracks successful hotplug enablement
+	AnnotationHotplugApplied = DefaultKeyPrefix + "hotplug-applied"
+	// AnnotationKernelBootApplied tracks successful kernel boot configuration
+	AnnotationKernelBootApplied = DefaultKeyPrefix + "kernel-boot-applied"
+	// AnnotationCdromIsoApplied tracks successful CD-ROM ISO attachment
+	AnnotationCdromIsoApplied = DefaultKeyPrefix + "cdrom-iso-applied"
+	// AnnotationSSHKeysApplied tracks successful SSH key injection
+	AnnotationSSHKeysApplied = DefaultKeyPrefix + "ssh-keys-applied"
+	// AnnotationSysprepApplied tracks successful sysprep injection
+	AnnotationSysprepApplied = DefaultKeyPrefix + "sysprep-applied"
+	// AnnotationGuestAgentApplied tracks successful guest agent enforcement
+	AnnotationGuestAgentApplied = DefaultKeyPrefix + "guest-agent-applied"
+	// AnnotationGuestAgentWarning records why the guest image may lack the agent
+	AnnotationGuestAgentWarning = DefaultKeyPrefix + "guest-agent-warning"
+	// AnnotationMacAddressesApplied tracks successful MAC address assignment
+	AnnotationMacAddressesApplied = DefaultKeyPrefix + "mac-addresses-applied"
+	// AnnotationOSPresetApplied tracks successful OS preset application
+	AnnotationOSPresetApplied = DefaultKeyPrefix + "os-preset-applied"
+	// AnnotationMeshExcludeApplied tracks successful service mesh exclusion
+	AnnotationMeshExcludeApplied = DefaultKeyPrefix + "mesh-exclude-applied"
+	// AnnotationPropagateMetadataApplied tracks successful metadata propagation
+	AnnotationPropagateMetadataApplied = DefaultKeyPrefix + "propagate-metadata-applied"
+	// AnnotationHookSidecarApplied tracks successful hook sidecar injection
+	AnnotationHookSidecarApplied = DefaultKeyPrefix + "hook-sidecar-applied"
+	// AnnotationLastMutation records the last mutation as JSON: webhook
+	// version, timestamp, applied and reverted features and config source
+	AnnotationLastMutation = DefaultKeyPrefix + "last-mutation"
 
 	// AnnotationNestedVirtError tracks nested virt errors
-	AnnotationNestedVirtError = "vm-feature-manager.io/nested-virt-error"
+	AnnotationNestedVirtError = DefaultKeyPrefix + "nested-virt-error"
 	// AnnotationVBiosInjectionError tracks vBIOS injection errors
-	AnnotationVBiosInjectionError = "vm-feature-manager.io/vbios-injection-error"
+	AnnotationVBiosInjectionError = DefaultKeyPrefix + "vbios-injection-error"
 	// AnnotationPciPassthroughError tracks PCI passthrough errors
-	AnnotationPciPassthroughError = "vm-feature-manager.io/pci-passthrough-error"
+	AnnotationPciPassthroughError = DefaultKeyPrefix + "pci-passthrough-error"
 	// AnnotationGpuDevicePluginError tracks GPU device plugin errors
-	AnnotationGpuDevicePluginError = "vm-feature-manager.io/gpu-device-plugin-error"
+	AnnotationGpuDevicePluginError = DefaultKeyPrefix + "gpu-device-plugin-error"
+	// AnnotationTpmError tracks vTPM errors
+	AnnotationTpmError = DefaultKeyPrefix + "tpm-error"
+	// AnnotationSevError tracks SEV errors
+	AnnotationSevError = DefaultKeyPrefix + "sev-error"
+	// AnnotationDedicatedCPUsError tracks dedicated CPU placement errors
+	AnnotationDedicatedCPUsError = DefaultKeyPrefix + "dedicated-cpus-error"
+	// AnnotationNumaError tracks guest NUMA mapping errors
+	AnnotationNumaError = DefaultKeyPrefix + "numa-error"
+	// AnnotationRealtimeError tracks realtime errors
+	AnnotationRealtimeError = DefaultKeyPrefix + "realtime-error"
+	// AnnotationHyperVError tracks Hyper-V enlightenments errors
+	AnnotationHyperVError = DefaultKeyPrefix + "hyperv-error"
+	// AnnotationCPUModelError tracks CPU model selection errors
+	AnnotationCPUModelError = DefaultKeyPrefix + "cpu-model-error"
+	// AnnotationVGpuError tracks vGPU errors
+	AnnotationVGpuError = DefaultKeyPrefix + "vgpu-error"
+	// AnnotationUsbPassthroughError tracks USB passthrough errors
+	AnnotationUsbPassthroughError = DefaultKeyPrefix + "usb-passthrough-error"
+	// AnnotationStoragePerformanceError tracks storage performance tuning errors
+	AnnotationStoragePerformanceError = DefaultKeyPrefix + "storage-performance-error"
+	// AnnotationNetMultiQueueError tracks network multi-queue errors
+	AnnotationNetMultiQueueError = DefaultKeyPrefix + "net-multiqueue-error"
+	// AnnotationBootOrderError tracks boot order errors
+	AnnotationBootOrderError = DefaultKeyPrefix + "boot-order-error"
+	// AnnotationSmbiosError tracks SMBIOS injection errors
+	AnnotationSmbiosError = DefaultKeyPrefix + "smbios-error"
+	// AnnotationTolerationsError tracks tolerations injection errors
+	AnnotationTolerationsError = DefaultKeyPrefix + "tolerations-error"
+	// AnnotationCPUTopologyError tracks vCPU topology errors
+	AnnotationCPUTopologyError = DefaultKeyPrefix + "cpu-topology-error"
+	// AnnotationGuaranteedQoSError tracks guaranteed-QoS preset errors
+	AnnotationGuaranteedQoSError = DefaultKeyPrefix + "guaranteed-qos-error"
+	// AnnotationHotplugError tracks hotplug enablement errors
+	AnnotationHotplugError = DefaultKeyPrefix + "hotplug-error"
+	// AnnotationKernelBootError tracks kernel boot errors
+	AnnotationKernelBootError = DefaultKeyPrefix + "kernel-boot-error"
+	// AnnotationCdromIsoError tracks CD-ROM ISO errors
+	AnnotationCdromIsoError = DefaultKeyPrefix + "cdrom-iso-error"
+	// AnnotationSSHKeysError tracks SSH key injection errors
+	AnnotationSSHKeysError = DefaultKeyPrefix + "ssh-keys-error"
+	// AnnotationSysprepError tracks sysprep injection errors
+	AnnotationSysprepError = DefaultKeyPrefix + "sysprep-error"
+	// AnnotationGuestAgentError tracks guest agent errors
+	AnnotationGuestAgentError = DefaultKeyPrefix + "guest-agent-error"
+	// AnnotationMacAddressesError tracks MAC address assignment errors
+	AnnotationMacAddressesError = DefaultKeyPrefix + "mac-addresses-error"
+	// AnnotationOSPresetError tracks OS preset errors
+	AnnotationOSPresetError = DefaultKeyPrefix + "os-preset-error"
+	// AnnotationMeshExcludeError tracks service mesh exclusion errors
+	AnnotationMeshExcludeError = DefaultKeyPrefix + "mesh-exclude-error"
+	// AnnotationPropagateMetadataError tracks metadata propagation errors
+	AnnotationPropagateMetadataError = DefaultKeyPrefix + "propagate-metadata-error"
+	// AnnotationHookSidecarError tracks hook sidecar injection errors
+	AnnotationHookSidecarError = DefaultKeyPrefix + "hook-sidecar-error"
 
 	// FeatureNestedVirt is the name for the nested virtualization feature
 	FeatureNestedVirt = "nested-virt"
@@ -41,29 +248,164 @@ const (
 	FeaturePciPassthrough = "pci-passthrough"
 	// FeatureGpuDevicePlugin is the name for the GPU device plugin feature
 	FeatureGpuDevicePlugin = "gpu-device-plugin"
+	// FeatureTpm is the name for the vTPM feature
+	FeatureTpm = "tpm"
+	// FeatureSev is the name for the AMD SEV feature
+	FeatureSev = "sev"
+	// FeatureDedicatedCPUs is the name for the dedicated CPU placement feature
+	FeatureDedicatedCPUs = "dedicated-cpus"
+	// FeatureNuma is the name for the guest NUMA topology feature
+	FeatureNuma = "numa"
+	// FeatureRealtime is the name for the realtime workload feature
+	FeatureRealtime = "realtime"
+	// FeatureHyperV is the name for the Hyper-V enlightenments feature
+	FeatureHyperV = "hyperv"
+	// FeatureCPUModel is the name for the CPU model selection feature
+	FeatureCPUModel = "cpu-model"
+	// FeatureVGpu is the name for the mediated device (vGPU) feature
+	FeatureVGpu = "vgpu"
+	// FeatureUsbPassthrough is the name for the USB passthrough feature
+	FeatureUsbPassthrough = "usb-passthrough"
+	// FeatureStoragePerformance is the name for the IO threads and block multi-queue feature
+	FeatureStoragePerformance = "storage-performance"
+	// FeatureNetMultiQueue is the name for the network multi-queue feature
+	FeatureNetMultiQueue = "net-multiqueue"
+	// FeatureBootOrder is the name for the boot order feature
+	FeatureBootOrder = "boot-order"
+	// FeatureSmbios is the name for the SMBIOS injection feature
+	FeatureSmbios = "smbios"
+	// FeatureTolerations is the name for the tolerations injection feature
+	FeatureTolerations = "tolerations"
+	// FeatureCPUTopology is the name for the vCPU topology feature
+	FeatureCPUTopology = "cpu-topology"
+	// FeatureGuaranteedQoS is the name for the guaranteed-QoS preset feature
+	FeatureGuaranteedQoS = "guaranteed-qos"
+	// FeatureHotplug is the name for the CPU and memory hotplug feature
+	FeatureHotplug = "hotplug"
+	// FeatureKernelBoot is the name for the kernel boot feature
+	FeatureKernelBoot = "kernel-boot"
+	// FeatureCdromIso is the name for the CD-ROM ISO feature
+	FeatureCdromIso = "cdrom-iso"
+	// FeatureSSHKeys is the name for the SSH public key injection feature
+	FeatureSSHKeys = "ssh-keys"
+	// FeatureSysprep is the name for the sysprep injection feature
+	FeatureSysprep = "sysprep"
+	// FeatureGuestAgent is the name for the guest agent enforcement feature
+	FeatureGuestAgent = "guest-agent"
+	// FeatureMacAddresses is the name for the static MAC address feature
+	FeatureMacAddresses = "mac-addresses"
+	// FeatureOSPreset is the name for the OS preset feature
+	FeatureOSPreset = "os-preset"
+	// FeatureMeshExclude is the name for the service mesh exclusion feature
+	FeatureMeshExclude = "mesh-exclude"
+	// FeaturePropagateMetadata is the name for the metadata propagation feature
+	FeaturePropagateMetadata = "propagate-metadata"
+	// FeatureHookSidecar is the name for the generic hook sidecar feature
+	FeatureHookSidecar = "hook-sidecar"
 
 	// CPUFeatureSVM is the AMD SVM CPU feature name for nested virtualization
 	CPUFeatureSVM = "svm"
 	// CPUFeatureVMX is the Intel VMX CPU feature name for nested virtualization
 	CPUFeatureVMX = "vmx"
+	// NodeLabelCPUFeaturePrefix prefixes the CPU feature labels set by KubeVirt's node labeller
+	NodeLabelCPUFeaturePrefix = "cpu-feature.node.kubevirt.io/"
+
+	// TpmPersistent is the vTPM value requesting persistent TPM state
+	TpmPersistent = "persistent"
+	// SevES is the SEV value requesting encrypted state (SEV-ES)
+	SevES = "sev-es"
+	// NumaGuestMapping is the NUMA value requesting guest mapping passthrough
+	NumaGuestMapping = "guest-mapping"
+	// VGpuDisplayRamFB is the vGPU display value enabling the display with ramfb
+	VGpuDisplayRamFB = "ramfb"
+	// GpuModeResource assigns GPUs by adding the device plugin resource to the limits
+	GpuModeResource = "resource"
+	// GpuModeDevice assigns GPUs through spec.domain.devices.gpus
+	GpuModeDevice = "device"
+	// DefaultHugepagesSize is the hugepage size used by the guaranteed-QoS preset
+	DefaultHugepagesSize = "2Mi"
+	// CdromIsoSourceDataVolume is the CD-ROM ISO source prefix for DataVolumes
+	CdromIsoSourceDataVolume = "datavolume"
+	// CdromIsoSourcePVC is the CD-ROM ISO source prefix for PersistentVolumeClaims
+	CdromIsoSourcePVC = "pvc"
+	// DefaultSSHKeysUser is the guest user that receives SSH keys when none are specified
+	DefaultSSHKeysUser = "root"
+	// OSPresetWindows is the OS preset value for Windows guests
+	OSPresetWindows = "windows"
+	// NestedVirtAuto is the nested-virt value requesting CPU feature detection
+	NestedVirtAuto = "auto"
+	// NestedVirtOptional is the nested-virt value adding both vmx and svm with the optional policy
+	NestedVirtOptional = "optional"
 
 	// DefaultSidecarImage is the default KubeVirt sidecar-shim image for vBIOS injection
 	DefaultSidecarImage = "registry.k8s.io/kubevirt/sidecar-shim:v1.4.0"
-	// SidecarHookVersion is the hook sidecar API version
+	// SidecarHookVersion is the hook sidecar API version used when KubeVirt's version is unknown
 	SidecarHookVersion = "v1alpha2"
+	// SidecarHookVersionV1Alpha3 is the hook sidecar API version served by KubeVirt v1.0 and later
+	SidecarHookVersionV1Alpha3 = "v1alpha3"
+	// SidecarHookVersionAuto selects the hook sidecar API version from the KubeVirt CR status
+	SidecarHookVersionAuto = "auto"
 	// SidecarHookType is the type of hook to use
 	SidecarHookType = "onDefineDomain"
 	// VBiosConfigMapKey is the key name for vBIOS data in ConfigMaps
 	VBiosConfigMapKey = "rom"
+	// VBiosSourceSecretPrefix marks a vbios-injection value referring to a Secret
+	VBiosSourceSecretPrefix = "secret:"
+	// VBiosMirrorSuffix is appended to the VM name for ROMs copied from another namespace
+	VBiosMirrorSuffix = "-vbios"
+	// SidecarHookPathOnDefineDomain is where sidecar-shim expects an onDefineDomain hook script
+	SidecarHookPathOnDefineDomain = "/usr/bin/onDefineDomain"
+	// VBiosHookModeImage runs the vBIOS hook from the sidecar image
+	VBiosHookModeImage = "image"
+	// VBiosHookModeConfigMap runs the vBIOS hook from a script ConfigMap mounted into sidecar-shim
+	VBiosHookModeConfigMap = "configmap"
+	// DefaultVBiosHookConfigMapTemplate names the per-VM vBIOS hook script ConfigMap
+	DefaultVBiosHookConfigMapTemplate = "{{ .VMName }}-vbios-hook"
+	// VBiosHookScriptKey is the key holding the vBIOS hook script in the hook ConfigMap
+	VBiosHookScriptKey = "vbios-hook.sh"
 	// HookAnnotationKey is the KubeVirt annotation for hook sidecars
 	HookAnnotationKey = "hooks.kubevirt.io/hookSidecars"
 
+	// UserdataSecretGuardNone reads any userdata Secret in the VM's namespace
+	UserdataSecretGuardNone = "none"
+	// UserdataSecretGuardLabel only reads userdata Secrets labeled LabelUserdataSecret=allowed
+	UserdataSecretGuardLabel = "label"
+	// UserdataSecretGuardNamespaceAllowlist only reads userdata Secrets in allowlisted namespaces
+	UserdataSecretGuardNamespaceAllowlist = "namespace-allowlist"
+
 	// ErrorHandlingReject causes the webhook to reject VMs when feature application fails
 	ErrorHandlingReject = "reject"
 	// ErrorHandlingAllowAndLog allows VMs through but logs feature application failures
 	ErrorHandlingAllowAndLog = "allow-and-log"
 	// ErrorHandlingStripLabel removes the failing feature annotation and allows the VM through
 	ErrorHandlingStripLabel = "strip-label"
+	// ErrorHandlingContinue skips failing features, records their errors and applies the rest
+	ErrorHandlingContinue = "continue"
+
+	// TimeoutPolicyAllow admits a VM unmutated, with a warning, when admission runs out of time
+	TimeoutPolicyAllow = "allow"
+	// TimeoutPolicyReject rejects a VM when admission runs out of time
+	TimeoutPolicyReject = "reject"
+
+	// WebhookModeEnforce returns the computed patches and rejections
+	WebhookModeEnforce = "enforce"
+	// WebhookModeShadow computes, logs and counts patches and rejections but
+	// admits every object unmutated
+	WebhookModeShadow = "shadow"
+
+	// SaturationPolicyAllow admits a VM unmutated, with a warning, when too many requests are in flight
+	SaturationPolicyAllow = "allow"
+	// SaturationPolicyReject rejects a VM with 429 when too many requests are in flight
+	SaturationPolicyReject = "reject"
+
+	// EventReasonFeaturesApplied is recorded when features were applied to an object
+	EventReasonFeaturesApplied = "FeaturesApplied"
+	// EventReasonFeaturesReverted is recorded when removed features were reverted
+	EventReasonFeaturesReverted = "FeaturesReverted"
+	// EventReasonFeatureFailed is recorded when a feature failed to apply
+	EventReasonFeatureFailed = "FeatureFailed"
+	// EventReasonFeatureStripped is recorded when a failing feature's request was stripped
+	EventReasonFeatureStripped = "FeatureStripped"
 )
 
 // ConfigSource represents where to read feature configuration from
@@ -74,6 +416,10 @@ const (
 	ConfigSourceAnnotations ConfigSource = "annotations"
 	// ConfigSourceLabels reads feature configuration from VM labels
 	ConfigSourceLabels ConfigSource = "labels"
+	// ConfigSourceBoth reads labels and annotations, annotations taking precedence
+	ConfigSourceBoth ConfigSource = "both"
+	// ConfigSourceBothLabelsFirst reads labels and annotations, labels taking precedence
+	ConfigSourceBothLabelsFirst ConfigSource = "both-labels-first"
 )
 
 // IsTruthyValue checks if a string value represents a boolean "true"
@@ -87,10 +433,21 @@ func IsTruthyValue(value string) bool {
 	}
 }
 
+// IsFalsyValue checks if a string value represents a boolean "false"
+// Accepts: "false", "disabled", "no", "0" (case-insensitive)
+func IsFalsyValue(value string) bool {
+	switch strings.ToLower(value) {
+	case "false", "disabled", "no", "0":
+		return true
+	default:
+		return false
+	}
+}
+
 // IsValidConfigSource checks if the provided config source is valid
 func IsValidConfigSource(source string) bool {
 	switch ConfigSource(strings.ToLower(source)) {
-	case ConfigSourceAnnotations, ConfigSourceLabels:
+	case ConfigSourceAnnotations, ConfigSourceLabels, ConfigSourceBoth, ConfigSourceBothLabelsFirst:
 		return true
 	default:
 		return false
@@ -104,31 +461,105 @@ func ParseConfigSource(source string) ConfigSource {
 		return ConfigSourceAnnotations
 	case ConfigSourceLabels:
 		return ConfigSourceLabels
+	case ConfigSourceBoth:
+		return ConfigSourceBoth
+	case ConfigSourceBothLabelsFirst:
+		return ConfigSourceBothLabelsFirst
 	default:
 		return ConfigSourceAnnotations
 	}
 }
 
-// GetConfigValue retrieves a configuration value from either annotations or labels
+// WithPrecedence applies a precedence ("annotations" or "labels") to the
+// "both" config source; other sources are returned unchanged
+func WithPrecedence(source ConfigSource, precedence string) ConfigSource {
+	if source == ConfigSourceBoth && ConfigSource(strings.ToLower(precedence)) == ConfigSourceLabels {
+		return ConfigSourceBothLabelsFirst
+	}
+	return source
+}
+
+// ConfigSourceOrder returns the metadata maps to read, highest precedence first
+func ConfigSourceOrder(configSource ConfigSource, annotations, labels map[string]string) []map[string]string {
+	switch configSource {
+	case ConfigSourceLabels:
+		return []map[string]string{labels}
+	case ConfigSourceBoth:
+		return []map[string]string{annotations, labels}
+	case ConfigSourceBothLabelsFirst:
+		return []map[string]string{labels, annotations}
+	default:
+		return []map[string]string{annotations}
+	}
+}
+
+// ReadsLabels reports whether the config source includes labels
+func ReadsLabels(configSource ConfigSource) bool {
+	return configSource != ConfigSourceAnnotations && IsValidConfigSource(string(configSource))
+}
+
+// ReadsAnnotations reports whether the config source includes annotations
+func ReadsAnnotations(configSource ConfigSource) bool {
+	return configSource != ConfigSourceLabels
+}
+
+// GetConfigValue retrieves a configuration value from annotations and/or labels
 // based on the configSource setting. Returns the value and whether it was found.
 func GetConfigValue(configSource ConfigSource, annotations, labels map[string]string, key string) (string, bool) {
-	var source map[string]string
-	if configSource == ConfigSourceLabels {
-		source = labels
-	} else {
-		source = annotations
-	}
-	if source == nil {
-		return "", false
+	for _, source := range ConfigSourceOrder(configSource, annotations, labels) {
+		if value, exists := source[key]; exists {
+			return value, true
+		}
 	}
-	value, exists := source[key]
-	return value, exists
+	return "", false
 }
 
-// GetConfigMap returns either annotations or labels based on the configSource setting.
+// GetConfigMap returns annotations or labels based on the configSource setting.
+// For the combined sources it returns a merged copy honoring their precedence.
 func GetConfigMap(configSource ConfigSource, annotations, labels map[string]string) map[string]string {
-	if configSource == ConfigSourceLabels {
-		return labels
+	sources := ConfigSourceOrder(configSource, annotations, labels)
+	if len(sources) == 1 {
+		return sources[0]
+	}
+	if annotations == nil && labels == nil {
+		return nil
+	}
+
+	merged := make(map[string]string, len(annotations)+len(labels))
+	for i := len(sources) - 1; i >= 0; i-- {
+		for key, value := range sources[i] {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// SwapKeyPrefix exchanges DefaultKeyPrefix and prefix on the keys of m, in
+// place. Swapping twice restores the original keys, which lets a deployment
+// with a custom prefix process its keys as the default ones while keys of the
+// default prefix are kept out of the way. A new reader of keys on objects
+// other than those listed at DefaultKeyPrefix must swap them as well.
+func SwapKeyPrefix(m map[string]string, prefix string) {
+	if prefix == "" || prefix == DefaultKeyPrefix || len(m) == 0 {
+		return
+	}
+
+	swapped := make(map[string]string, len(m))
+	for key, value := range m {
+		switch {
+		case strings.HasPrefix(key, prefix):
+			swapped[DefaultKeyPrefix+strings.TrimPrefix(key, prefix)] = value
+		case strings.HasPrefix(key, DefaultKeyPrefix):
+			swapped[prefix+strings.TrimPrefix(key, DefaultKeyPrefix)] = value
+		default:
+			swapped[key] = value
+		}
+	}
+
+	for key := range m {
+		delete(m, key)
+	}
+	for key, value := range swapped {
+		m[key] = value
 	}
-	return annotations
 }
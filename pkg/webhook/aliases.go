@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// resolveKeyAliases honors the deprecated keys of the configured alias table.
+// An alias applies to its canonical key unless that key is set too; with
+// RewriteKeyAliases the alias is renamed in the patch, otherwise it applies
+// for this request only. A deprecation warning is returned per alias found.
+func (m *Mutator) resolveKeyAliases(vm *kubevirtv1.VirtualMachine, overlay *requestOverlay) []string {
+	aliases := make([]string, 0, len(m.config.KeyAliases))
+	for alias := range m.config.KeyAliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	var warnings []string
+	for _, alias := range aliases {
+		value, exists := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), alias)
+		if !exists {
+			continue
+		}
+		canonical := featureKey(m.config.KeyAliases[alias])
+		warnings = append(warnings, fmt.Sprintf("%s is deprecated, use %s", alias, m.externalKey(canonical)))
+
+		if _, set := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), canonical); set {
+			continue
+		}
+		if !m.config.RewriteKeyAliases {
+			overlay.set(vm, canonical, value)
+			continue
+		}
+		if utils.ReadsLabels(m.config.ConfigSource) {
+			renameKey(vm.Labels, alias, canonical)
+		}
+		if utils.ReadsAnnotations(m.config.ConfigSource) {
+			renameKey(vm.Annotations, alias, canonical)
+		}
+	}
+	return warnings
+}
+
+// renameKey moves the value of from to to, unless to is already set
+func renameKey(metadata map[string]string, from, to string) {
+	value, exists := metadata[from]
+	if !exists {
+		return
+	}
+	delete(metadata, from)
+	if _, set := metadata[to]; !set {
+		metadata[to] = value
+	}
+}
+
+// externalKey returns a key as users write it, with the configured prefix
+func (m *Mutator) externalKey(key string) string {
+	if !m.customKeyPrefix() || !strings.HasPrefix(key, utils.DefaultKeyPrefix) {
+		return key
+	}
+	return m.config.KeyPrefix + strings.TrimPrefix(key, utils.DefaultKeyPrefix)
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Key aliases", func() {
+	const legacyKey = "gpu.legacy-webhook.example.com/plugin"
+
+	var (
+		cfg *config.Config
+		vm  *kubevirtv1.VirtualMachine
+	)
+
+	BeforeEach(func() {
+		cfg = &config.Config{
+			ErrorHandlingMode:      utils.ErrorHandlingReject,
+			AddTrackingAnnotations: true,
+			ConfigSource:           utils.ConfigSourceAnnotations,
+			KeyAliases:             map[string]string{legacyKey: "gpu-device-plugin"},
+		}
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-vm",
+				Namespace:   "default",
+				Annotations: map[string]string{legacyKey: "nvidia.com/gpu"},
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+			},
+		}
+	})
+
+	handle := func() (*admissionv1.AdmissionResponse, *kubevirtv1.VirtualMachine) {
+		vmBytes, err := json.Marshal(vm)
+		Expect(err).ToNot(HaveOccurred())
+
+		mutator := mustNewMutator(nil, cfg, []features.Feature{
+			features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, cfg.ConfigSource),
+		})
+		response, err := mutator.Handle(context.Background(), &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: vmBytes},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Allowed).To(BeTrue())
+		return response, applyPatch(vmBytes, response.Patch)
+	}
+
+	It("should honor an aliased key with a deprecation warning", func() {
+		response, patched := handle()
+
+		Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(BeEquivalentTo("nvidia.com/gpu")))
+		Expect(patched.Annotations).To(HaveKeyWithValue(legacyKey, "nvidia.com/gpu"))
+		Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationGpuDevicePlugin))
+		Expect(response.Warnings).To(ContainElement(And(
+			ContainSubstring(legacyKey),
+			ContainSubstring("deprecated"),
+			ContainSubstring(utils.AnnotationGpuDevicePlugin),
+		)))
+	})
+
+	It("should rewrite aliased keys to the canonical key when configured", func() {
+		cfg.RewriteKeyAliases = true
+
+		_, patched := handle()
+
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePlugin, "nvidia.com/gpu"))
+		Expect(patched.Annotations).ToNot(HaveKey(legacyKey))
+	})
+
+	It("should let the canonical key take precedence", func() {
+		vm.Annotations[utils.AnnotationGpuDevicePlugin] = "amd.com/gpu"
+
+		_, patched := handle()
+
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "amd.com/gpu"))
+	})
+})
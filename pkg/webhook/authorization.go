@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// Users need the use verb on the virtual resource
+// features.vm-feature-manager.io/<feature> to request an RBAC-gated feature
+const (
+	featureAuthorizationGroup    = "vm-feature-manager.io"
+	featureAuthorizationResource = "features"
+	featureAuthorizationVerb     = "use"
+)
+
+// unauthorizedFeatures removes from the VM the RBAC-gated features the
+// requesting user may not use, and returns their keys as users write them.
+// Only requests that are new or changed by the request are checked, so updates
+// by other users leave existing requests alone. Features whose check fails are
+// removed as well and reported in the error.
+func (m *Mutator) unauthorizedFeatures(ctx context.Context, req *admissionv1.AdmissionRequest, vm *kubevirtv1.VirtualMachine, namespace string, overlay *requestOverlay) ([]string, error) {
+	if len(m.config.RBACFeatures) == 0 {
+		return nil, nil
+	}
+
+	var previous *kubevirtv1.VirtualMachine
+	oldObj, err := decodeOldObject(req)
+	if err != nil {
+		return nil, err
+	}
+	if oldObj != nil {
+		previous = m.previousRequests(ctx, oldObj.VirtualMachine())
+	}
+
+	var denied []string
+	var errs []error
+	for _, name := range m.config.RBACFeatures {
+		key := featureKey(name)
+		value, requested := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), key)
+		if !requested || utils.IsFalsyValue(value) {
+			continue
+		}
+		if previous != nil {
+			if old, ok := utils.GetConfigValue(m.config.ConfigSource, previous.GetAnnotations(), previous.GetLabels(), key); ok && old == value {
+				continue
+			}
+		}
+
+		allowed, err := m.mayUseFeature(ctx, req.UserInfo, namespace, strings.TrimPrefix(key, featureKeyPrefix))
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("failed to authorize %s: %w", m.externalKey(key), err))
+		case !allowed:
+			denied = append(denied, m.externalKey(key))
+		default:
+			continue
+		}
+		overlay.remove(vm, key)
+	}
+	return denied, errors.Join(errs...)
+}
+
+// previousRequests expands the requests of the VM stored before an UPDATE
+// like those of the admitted VM: key aliases, the combined features key and
+// userdata directives, so a request the update leaves alone reads the same on
+// both. Problems are ignored; they were reported when the stored VM was
+// admitted. It returns previous.
+func (m *Mutator) previousRequests(ctx context.Context, previous *kubevirtv1.VirtualMachine) *kubevirtv1.VirtualMachine {
+	m.swapKeyPrefix(previous)
+
+	var directives map[string]string
+	if m.config.UserdataDirectives {
+		directives, _, _ = m.userdataParser.ParseFeatures(ctx, previous, m.registeredFeatures()...)
+	}
+
+	overlay := newRequestOverlay(m.config.ConfigSource)
+	m.resolveKeyAliases(previous, overlay)
+	_ = m.expandCombinedFeatures(previous, overlay)
+	if len(directives) > 0 && previous.Annotations == nil {
+		previous.Annotations = make(map[string]string, len(directives))
+	}
+	for key, value := range directives {
+		if _, exists := previous.Annotations[key]; !exists {
+			previous.Annotations[key] = value
+		}
+	}
+	return previous
+}
+
+// mayUseFeature asks the API server whether the user may use the feature in the namespace
+func (m *Mutator) mayUseFeature(ctx context.Context, user authenticationv1.UserInfo, namespace, feature string) (bool, error) {
+	if m.client == nil {
+		return false, errors.New("no Kubernetes client")
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      featureAuthorizationVerb,
+				Group:     featureAuthorizationGroup,
+				Resource:  featureAuthorizationResource,
+				Name:      feature,
+			},
+		},
+	}
+	if err := m.client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,243 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Feature authorization", func() {
+	var (
+		cfg      *config.Config
+		vm       *kubevirtv1.VirtualMachine
+		allowed  map[string]bool
+		reviews  []authorizationv1.SubjectAccessReviewSpec
+		reviewFn func(spec authorizationv1.SubjectAccessReviewSpec) (bool, error)
+	)
+
+	BeforeEach(func() {
+		cfg = &config.Config{
+			ErrorHandlingMode:      utils.ErrorHandlingReject,
+			AddTrackingAnnotations: true,
+			ConfigSource:           utils.ConfigSourceAnnotations,
+			RBACFeatures:           []string{"gpu-device-plugin"},
+		}
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-vm",
+				Namespace:   "default",
+				Annotations: map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"},
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+			},
+		}
+		allowed = map[string]bool{}
+		reviews = nil
+		reviewFn = func(spec authorizationv1.SubjectAccessReviewSpec) (bool, error) {
+			return allowed[spec.User], nil
+		}
+	})
+
+	handle := func(operation admissionv1.Operation, oldVM *kubevirtv1.VirtualMachine) (*admissionv1.AdmissionResponse, []byte) {
+		vmBytes, err := json.Marshal(vm)
+		Expect(err).ToNot(HaveOccurred())
+		req := &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Operation: operation,
+			Namespace: "default",
+			UserInfo:  authenticationv1.UserInfo{Username: "alice", Groups: []string{"tenants"}},
+			Object:    runtime.RawExtension{Raw: vmBytes},
+		}
+		if oldVM != nil {
+			oldBytes, err := json.Marshal(oldVM)
+			Expect(err).ToNot(HaveOccurred())
+			req.OldObject = runtime.RawExtension{Raw: oldBytes}
+		}
+
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				reviews = append(reviews, review.Spec)
+				var err error
+				review.Status.Allowed, err = reviewFn(review.Spec)
+				return err
+			},
+		}).Build()
+		mutator := mustNewMutator(fakeClient, cfg, []features.Feature{
+			features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, cfg.ConfigSource),
+		})
+		response, err := mutator.Handle(context.Background(), req)
+		Expect(err).ToNot(HaveOccurred())
+		return response, vmBytes
+	}
+
+	It("should allow gated features for authorized users", func() {
+		allowed["alice"] = true
+
+		response, vmBytes := handle(admissionv1.Create, nil)
+		Expect(response.Allowed).To(BeTrue())
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "nvidia.com/gpu"))
+
+		Expect(reviews).To(HaveLen(1))
+		Expect(reviews[0].User).To(Equal("alice"))
+		Expect(reviews[0].Groups).To(Equal([]string{"tenants"}))
+		Expect(*reviews[0].ResourceAttributes).To(Equal(authorizationv1.ResourceAttributes{
+			Namespace: "default",
+			Verb:      "use",
+			Group:     "vm-feature-manager.io",
+			Resource:  "features",
+			Name:      "gpu-device-plugin",
+		}))
+	})
+
+	It("should reject gated features for other users in reject mode", func() {
+		response, _ := handle(admissionv1.Create, nil)
+		Expect(response.Allowed).To(BeFalse())
+		Expect(response.Result.Message).To(ContainSubstring("user alice is not authorized to request features: " + utils.AnnotationGpuDevicePlugin))
+	})
+
+	It("should skip gated features with a warning otherwise", func() {
+		cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
+
+		response, _ := handle(admissionv1.Create, nil)
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Patch).To(BeNil())
+		Expect(response.Warnings).To(ContainElement(ContainSubstring("not authorized")))
+	})
+
+	It("should not check features that aren't gated or are disabled", func() {
+		cfg.RBACFeatures = []string{"pci-passthrough"}
+		response, _ := handle(admissionv1.Create, nil)
+		Expect(response.Allowed).To(BeTrue())
+
+		cfg.RBACFeatures = []string{"gpu-device-plugin"}
+		vm.Annotations[utils.AnnotationGpuDevicePlugin] = "disabled"
+		handle(admissionv1.Create, nil)
+		Expect(reviews).To(BeEmpty())
+	})
+
+	It("should not recheck requests an update leaves unchanged", func() {
+		response, _ := handle(admissionv1.Update, vm.DeepCopy())
+		Expect(response.Allowed).To(BeTrue())
+		Expect(reviews).To(BeEmpty())
+	})
+
+	Context("when an update only touches a label", func() {
+		update := func() *admissionv1.AdmissionResponse {
+			vm.Annotations[utils.AnnotationGpuDevicePluginApplied] = "nvidia.com/gpu"
+			oldVM := vm.DeepCopy()
+			vm.Labels = map[string]string{"team": "ml"}
+
+			response, _ := handle(admissionv1.Update, oldVM)
+			Expect(response.Allowed).To(BeTrue())
+			Expect(reviews).To(BeEmpty())
+			return response
+		}
+
+		It("should not recheck a request of the combined key", func() {
+			vm.Annotations = map[string]string{utils.AnnotationFeatures: "gpu-device-plugin=nvidia.com/gpu"}
+			update()
+		})
+
+		It("should not recheck a request of a deprecated key", func() {
+			cfg.KeyAliases = map[string]string{"legacy.example.com/gpu": "gpu-device-plugin"}
+			vm.Annotations = map[string]string{"legacy.example.com/gpu": "nvidia.com/gpu"}
+			update()
+		})
+
+		It("should not recheck a userdata directive", func() {
+			cfg.UserdataDirectives = true
+			vm.Annotations = map[string]string{}
+			vm.Spec.Template.Spec.Volumes = []kubevirtv1.Volume{{
+				Name: "cloudinit",
+				VolumeSource: kubevirtv1.VolumeSource{
+					CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+						UserData: "#cloud-config\n# @kubevirt-feature gpu_device_plugin=nvidia.com/gpu\nusers: []\n",
+					},
+				},
+			}}
+			update()
+		})
+
+		It("should keep the feature applied in other modes", func() {
+			cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
+			vm.Annotations = map[string]string{utils.AnnotationFeatures: "gpu-device-plugin=nvidia.com/gpu"}
+
+			response := update()
+			Expect(response.Warnings).ToNot(ContainElement(ContainSubstring("not authorized")))
+			Expect(string(response.Patch)).ToNot(ContainSubstring("gpu-device-plugin-applied"))
+		})
+	})
+
+	It("should check requests an update changes", func() {
+		oldVM := vm.DeepCopy()
+		oldVM.Annotations[utils.AnnotationGpuDevicePlugin] = "amd.com/gpu"
+
+		response, _ := handle(admissionv1.Update, oldVM)
+		Expect(response.Allowed).To(BeFalse())
+		Expect(reviews).To(HaveLen(1))
+	})
+
+	Context("with a custom key prefix", func() {
+		const key = "ourcompany.io/vm-gpu-device-plugin"
+
+		BeforeEach(func() {
+			cfg.KeyPrefix = "ourcompany.io/vm-"
+			vm.Annotations = map[string]string{key: "nvidia.com/gpu"}
+		})
+
+		It("should name the key as the user wrote it", func() {
+			response, _ := handle(admissionv1.Create, nil)
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Message).To(HaveSuffix("not authorized to request features: " + key))
+			Expect(reviews).To(HaveLen(1))
+		})
+
+		It("should read the stored object's requests under the configured prefix", func() {
+			response, _ := handle(admissionv1.Update, vm.DeepCopy())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(reviews).To(BeEmpty())
+		})
+
+		It("should not check requests of the default prefix", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+
+			response, _ := handle(admissionv1.Create, nil)
+			Expect(response.Allowed).To(BeTrue())
+			Expect(reviews).To(BeEmpty())
+		})
+	})
+
+	It("should fail closed when the review fails", func() {
+		cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
+		reviewFn = func(authorizationv1.SubjectAccessReviewSpec) (bool, error) {
+			return false, errors.New("apiserver unavailable")
+		}
+
+		response, _ := handle(admissionv1.Create, nil)
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Patch).To(BeNil())
+		Expect(response.Warnings).To(ContainElement(ContainSubstring("apiserver unavailable")))
+	})
+})
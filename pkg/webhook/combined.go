@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// featureKeyPrefix qualifies the feature names used in the combined spec
+const featureKeyPrefix = utils.DefaultKeyPrefix
+
+// parseCombinedFeatures expands the value of the combined features key into
+// individual feature keys. The value is either a JSON/YAML document mapping
+// feature names to values, or comma-separated name=value pairs. Names may be
+// fully qualified or short (nested-virt, nested_virt).
+func parseCombinedFeatures(value string) (map[string]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(value, "{") || strings.Contains(value, "\n") {
+		return parseCombinedDocument(value)
+	}
+
+	requests := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, featureValue, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid entry %q in %s: expected name=value", pair, utils.AnnotationFeatures)
+		}
+		requests[featureKey(name)] = strings.TrimSpace(featureValue)
+	}
+	return requests, nil
+}
+
+// parseCombinedDocument expands a JSON or YAML combined features document.
+// Booleans become enabled/disabled and structured values are encoded as JSON.
+func parseCombinedDocument(value string) (map[string]string, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return nil, fmt.Errorf("invalid document in %s: %w", utils.AnnotationFeatures, err)
+	}
+
+	requests := make(map[string]string, len(doc))
+	for name, raw := range doc {
+		var featureValue string
+		switch v := raw.(type) {
+		case string:
+			featureValue = v
+		case bool:
+			featureValue = "disabled"
+			if v {
+				featureValue = "enabled"
+			}
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s in %s: %w", name, utils.AnnotationFeatures, err)
+			}
+			featureValue = string(encoded)
+		}
+		requests[featureKey(name)] = featureValue
+	}
+	return requests, nil
+}
+
+// featureKey returns the fully qualified key for a feature name
+func featureKey(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, featureKeyPrefix) {
+		return name
+	}
+	return featureKeyPrefix + strings.ReplaceAll(name, "_", "-")
+}
+
+// expandCombinedFeatures overlays the requests of the combined features key
+// on the VM's config source, without overriding keys that are set individually
+func (m *Mutator) expandCombinedFeatures(vm *kubevirtv1.VirtualMachine, overlay *requestOverlay) error {
+	value, exists := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationFeatures)
+	if !exists {
+		return nil
+	}
+
+	requests, err := parseCombinedFeatures(value)
+	if err != nil {
+		return err
+	}
+
+	for key, featureValue := range requests {
+		if _, set := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), key); set {
+			continue
+		}
+		overlay.set(vm, key, featureValue)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Combined feature spec", func() {
+	Describe("parseCombinedFeatures", func() {
+		It("should expand name=value pairs", func() {
+			requests, err := parseCombinedFeatures("nested-virt=enabled, gpu_device_plugin=nvidia.com/gpu")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal(map[string]string{
+				utils.AnnotationNestedVirt:      "enabled",
+				utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+			}))
+		})
+
+		It("should accept fully qualified names", func() {
+			requests, err := parseCombinedFeatures(utils.AnnotationTpm + "=persistent")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(HaveKeyWithValue(utils.AnnotationTpm, "persistent"))
+		})
+
+		It("should expand a JSON document", func() {
+			requests, err := parseCombinedFeatures(`{"nested-virt": true, "pci-passthrough": {"devices": ["0000:01:00.0"]}}`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(HaveKeyWithValue(utils.AnnotationNestedVirt, "enabled"))
+			Expect(requests[utils.AnnotationPciPassthrough]).To(MatchJSON(`{"devices": ["0000:01:00.0"]}`))
+		})
+
+		It("should expand a YAML document", func() {
+			requests, err := parseCombinedFeatures("nested-virt: enabled\ntpm: persistent\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(HaveKeyWithValue(utils.AnnotationNestedVirt, "enabled"))
+			Expect(requests).To(HaveKeyWithValue(utils.AnnotationTpm, "persistent"))
+		})
+
+		It("should reject entries without a value", func() {
+			_, err := parseCombinedFeatures("nested-virt")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("expected name=value"))
+		})
+	})
+
+	Describe("Mutator expansion", func() {
+		var (
+			cfg *config.Config
+			vm  *kubevirtv1.VirtualMachine
+		)
+
+		BeforeEach(func() {
+			cfg = &config.Config{
+				ErrorHandlingMode:      utils.ErrorHandlingReject,
+				AddTrackingAnnotations: true,
+				ConfigSource:           utils.ConfigSourceAnnotations,
+			}
+			vm = &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-vm",
+					Namespace: "default",
+				},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+				},
+			}
+		})
+
+		handle := func() (*admissionv1.AdmissionResponse, []byte) {
+			vmBytes, err := json.Marshal(vm)
+			Expect(err).ToNot(HaveOccurred())
+
+			mutator := mustNewMutator(nil, cfg, []features.Feature{
+				features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, cfg.ConfigSource),
+			})
+			response, err := mutator.Handle(context.Background(), &admissionv1.AdmissionRequest{
+				UID:       "test-uid",
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: vmBytes},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			return response, vmBytes
+		}
+
+		It("should apply features requested through the combined key without persisting them", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationFeatures: "gpu-device-plugin=nvidia.com/gpu",
+			}
+
+			response, vmBytes := handle()
+			Expect(response.Allowed).To(BeTrue())
+
+			patched := applyPatch(vmBytes, response.Patch)
+			Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(BeEquivalentTo("nvidia.com/gpu")))
+			Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "nvidia.com/gpu"))
+			Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationGpuDevicePlugin))
+		})
+
+		It("should let individually set keys take precedence", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationFeatures:        "gpu-device-plugin=nvidia.com/gpu",
+				utils.AnnotationGpuDevicePlugin: "amd.com/gpu",
+			}
+
+			response, vmBytes := handle()
+			Expect(response.Allowed).To(BeTrue())
+
+			patched := applyPatch(vmBytes, response.Patch)
+			Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "amd.com/gpu"))
+		})
+
+		It("should read the combined key from labels when labels are the config source", func() {
+			cfg.ConfigSource = utils.ConfigSourceLabels
+			vm.Labels = map[string]string{
+				utils.AnnotationFeatures: "gpu-device-plugin=nvidia.com/gpu",
+			}
+
+			response, vmBytes := handle()
+			Expect(response.Allowed).To(BeTrue())
+
+			patched := applyPatch(vmBytes, response.Patch)
+			Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(BeEquivalentTo("nvidia.com/gpu")))
+			Expect(patched.Labels).ToNot(HaveKey(utils.AnnotationGpuDevicePlugin))
+		})
+
+		It("should reject an invalid combined spec in reject mode", func() {
+			vm.Annotations = map[string]string{
+				utils.AnnotationFeatures: "gpu-device-plugin",
+			}
+
+			response, _ := handle()
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Message).To(ContainSubstring(utils.AnnotationFeatures))
+		})
+	})
+})
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/record"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// eventLog collects the events of one request. They are recorded only once
+// the request's outcome is known, so a timed-out or dry-run request leaves
+// no events behind.
+type eventLog struct {
+	// ref is the admitted object, unset until it has been decoded
+	ref    *corev1.ObjectReference
+	events []event
+}
+
+// event is a single Kubernetes Event on the admitted object
+type event struct {
+	eventType string
+	reason    string
+	message   string
+}
+
+// setObject makes the events refer to the admitted object. Objects being
+// created have no UID yet and are referenced by name.
+func (l *eventLog) setObject(req *admissionv1.AdmissionRequest, obj admissionObject) {
+	accessor, err := meta.Accessor(obj.Original())
+	if err != nil {
+		return
+	}
+
+	kind := req.Kind
+	if kind.Kind == "" {
+		kind.Kind = KindVirtualMachine
+		kind.Group = kubevirtv1.SchemeGroupVersion.Group
+		kind.Version = kubevirtv1.SchemeGroupVersion.Version
+	}
+
+	name := accessor.GetName()
+	if name == "" {
+		name = req.Name
+	}
+	namespace := accessor.GetNamespace()
+	if namespace == "" {
+		namespace = req.Namespace
+	}
+
+	l.ref = &corev1.ObjectReference{
+		Kind:            kind.Kind,
+		APIVersion:      kindAPIVersion(kind.Group, kind.Version),
+		Name:            name,
+		Namespace:       namespace,
+		UID:             accessor.GetUID(),
+		ResourceVersion: accessor.GetResourceVersion(),
+	}
+}
+
+// normal adds an informational event
+func (l *eventLog) normal(reason, format string, args ...interface{}) {
+	l.events = append(l.events, event{eventType: corev1.EventTypeNormal, reason: reason, message: fmt.Sprintf(format, args...)})
+}
+
+// warning adds a warning event
+func (l *eventLog) warning(reason, format string, args ...interface{}) {
+	l.events = append(l.events, event{eventType: corev1.EventTypeWarning, reason: reason, message: fmt.Sprintf(format, args...)})
+}
+
+// featureFailed adds the event for a feature that failed under the given
+// error handling mode
+func (l *eventLog) featureFailed(featureName, mode string, err error) {
+	switch mode {
+	case utils.ErrorHandlingReject:
+		l.warning(utils.EventReasonFeatureFailed, "Feature %s failed, admission rejected: %v", featureName, err)
+	case utils.ErrorHandlingStripLabel, utils.ErrorHandlingContinue:
+		l.warning(utils.EventReasonFeatureStripped, "Feature %s failed and its request was stripped: %v", featureName, err)
+	default:
+		l.warning(utils.EventReasonFeatureFailed, "Feature %s failed and was skipped: %v", featureName, err)
+	}
+}
+
+// record emits the collected events through recorder
+func (l *eventLog) record(recorder record.EventRecorder) {
+	if l.ref == nil || l.ref.Name == "" {
+		return
+	}
+	for _, e := range l.events {
+		recorder.Event(l.ref, e.eventType, e.reason, e.message)
+	}
+}
+
+// kindAPIVersion joins a group and version into an apiVersion
+func kindAPIVersion(group, version string) string {
+	if group == "" {
+		return version
+	}
+	return group + "/" + version
+}
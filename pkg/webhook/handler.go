@@ -1,15 +1,20 @@
 // Package webhook implements the HTTP server and admission webhook handlers
 // for the KubeVirt VM Feature Manager. It processes admission requests,
-// applies feature mutations to VirtualMachine objects, and returns JSON patches.
+// applies feature mutations to VirtualMachines, VirtualMachineInstances, pools
+// and replica sets, and returns JSON patches.
 package webhook
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -31,8 +36,20 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	logger := log.FromContext(ctx)
 
+	if err := checkContentType(r.Header.Get("Content-Type")); err != nil {
+		logger.Info("Rejecting request", "reason", err.Error())
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
 	// Read request body
 	body, err := io.ReadAll(r.Body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		logger.Info("Request body too large", "limit", tooLarge.Limit)
+		http.Error(w, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
 	if err != nil {
 		logger.Error(err, "Failed to read request body")
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
@@ -44,7 +61,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// Decode admission review
+	// Decode admission review; v1beta1 shares v1's wire format
 	admissionReview := &admissionv1.AdmissionReview{}
 	if err := json.Unmarshal(body, admissionReview); err != nil {
 		logger.Error(err, "Failed to unmarshal admission review")
@@ -77,10 +94,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		admissionResponse.UID = admissionReview.Request.UID
 	}
 
-	// Construct response
+	// Construct response in the version the apiserver sent
 	responseReview := &admissionv1.AdmissionReview{
 		TypeMeta: metav1.TypeMeta{
-			APIVersion: "admission.k8s.io/v1",
+			APIVersion: responseAPIVersion(admissionReview.APIVersion),
 			Kind:       "AdmissionReview",
 		},
 		Response: admissionResponse,
@@ -101,3 +118,31 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		logger.Error(err, "Failed to write response")
 	}
 }
+
+// checkContentType accepts JSON bodies, optionally declared as UTF-8, which
+// is all the apiserver sends to webhooks
+func checkContentType(contentType string) error {
+	if contentType == "" {
+		return errors.New("missing Content-Type, expected application/json")
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid Content-Type %q: %v", contentType, err)
+	}
+	if mediaType != "application/json" {
+		return fmt.Errorf("unsupported Content-Type %q, expected application/json", mediaType)
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return fmt.Errorf("unsupported charset %q, expected utf-8", charset)
+	}
+	return nil
+}
+
+// responseAPIVersion returns the AdmissionReview version to answer a request
+// of apiVersion with: v1beta1 for legacy apiservers, v1 otherwise
+func responseAPIVersion(apiVersion string) string {
+	if apiVersion == admissionv1beta1.SchemeGroupVersion.String() {
+		return apiVersion
+	}
+	return admissionv1.SchemeGroupVersion.String()
+}
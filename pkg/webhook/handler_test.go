@@ -39,7 +39,7 @@ var _ = Describe("Handler", func() {
 			AutoDetectCPU: true,
 		}, utils.ConfigSourceAnnotations)
 
-		mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+		mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
 		handler = NewHandler(mutator)
 		recorder = httptest.NewRecorder()
 	})
@@ -108,12 +108,50 @@ var _ = Describe("Handler", func() {
 				err = json.Unmarshal(recorder.Body.Bytes(), &responseReview)
 				Expect(err).ToNot(HaveOccurred())
 
+				Expect(responseReview.APIVersion).To(Equal("admission.k8s.io/v1"))
 				Expect(responseReview.Response).ToNot(BeNil())
 				Expect(string(responseReview.Response.UID)).To(Equal("test-uid"))
 				Expect(responseReview.Response.Allowed).To(BeTrue())
 			})
 		})
 
+		Context("with a v1beta1 admission review", func() {
+			It("should respond with a v1beta1 admission review", func() {
+				vmBytes, err := json.Marshal(&kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: "default"},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				body, err := json.Marshal(&admissionv1.AdmissionReview{
+					TypeMeta: metav1.TypeMeta{
+						APIVersion: "admission.k8s.io/v1beta1",
+						Kind:       "AdmissionReview",
+					},
+					Request: &admissionv1.AdmissionRequest{
+						UID:       "legacy-uid",
+						Kind:      metav1.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: "VirtualMachine"},
+						Name:      "test-vm",
+						Namespace: "default",
+						Operation: admissionv1.Create,
+						Object:    runtime.RawExtension{Raw: vmBytes},
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				req := httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+
+				handler.ServeHTTP(recorder, req)
+
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				var responseReview admissionv1.AdmissionReview
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &responseReview)).To(Succeed())
+				Expect(responseReview.APIVersion).To(Equal("admission.k8s.io/v1beta1"))
+				Expect(responseReview.Kind).To(Equal("AdmissionReview"))
+				Expect(string(responseReview.Response.UID)).To(Equal("legacy-uid"))
+			})
+		})
+
 		Context("with VM without feature annotations", func() {
 			It("should return allowed response with correct UID", func() {
 				// VM without any feature annotations - tests the allowResponse path
@@ -219,6 +257,46 @@ var _ = Describe("Handler", func() {
 			})
 		})
 
+		Context("with a body over the size limit", func() {
+			It("should return request entity too large", func() {
+				req := httptest.NewRequest(http.MethodPost, "/mutate", nil)
+				req.Body = http.MaxBytesReader(recorder, io.NopCloser(bytes.NewReader([]byte("{}{}{}"))), 4)
+				req.Header.Set("Content-Type", "application/json")
+
+				handler.ServeHTTP(recorder, req)
+
+				Expect(recorder.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			})
+		})
+
+		Context("with an unsupported Content-Type", func() {
+			It("should return unsupported media type", func() {
+				for contentType, message := range map[string]string{
+					"":                                    "missing Content-Type",
+					"application/vnd.kubernetes.protobuf": `unsupported Content-Type "application/vnd.kubernetes.protobuf"`,
+					"application/json; charset=latin1":    `unsupported charset "latin1"`,
+					"application/json; charset":           "invalid Content-Type",
+				} {
+					recorder := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader([]byte("{}")))
+					if contentType != "" {
+						req.Header.Set("Content-Type", contentType)
+					}
+
+					handler.ServeHTTP(recorder, req)
+
+					Expect(recorder.Code).To(Equal(http.StatusUnsupportedMediaType), contentType)
+					Expect(recorder.Body.String()).To(ContainSubstring(message), contentType)
+				}
+			})
+
+			It("should accept JSON declared as UTF-8", func() {
+				for _, contentType := range []string{"application/json;charset=utf-8", "Application/JSON; charset=UTF-8"} {
+					Expect(checkContentType(contentType)).To(Succeed(), contentType)
+				}
+			})
+		})
+
 		Context("with unreadable body", func() {
 			It("should return bad request", func() {
 				req := httptest.NewRequest(http.MethodPost, "/mutate", &errorReader{})
@@ -276,8 +354,8 @@ var _ = Describe("Handler", func() {
 				}
 
 				// Add vBIOS feature to trigger the error path
-				vbiosFeature := features.NewVBiosInjection(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{vbiosFeature})
+				vbiosFeature := features.NewVBiosInjection(&config.VBiosConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{vbiosFeature})
 				handler = NewHandler(mutator)
 
 				body, err := json.Marshal(admissionReview)
@@ -429,7 +507,7 @@ var _ = Describe("Handler Error Paths", func() {
 			ErrorHandlingMode:      utils.ErrorHandlingReject,
 		}
 
-		mutator = NewMutator(nil, cfg, []features.Feature{})
+		mutator = mustNewMutator(nil, cfg, []features.Feature{})
 		handler = NewHandler(mutator)
 	})
 
@@ -466,6 +544,7 @@ var _ = Describe("Handler Error Paths", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			req := httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
 
 			// Use errorWriter to simulate write failure
 			recorder := &errorWriter{ResponseRecorder: httptest.NewRecorder()}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// admissionsShed counts requests answered without being handled because the
+// concurrency limit was reached
+var admissionsShed = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "vm_feature_manager_admissions_shed_total",
+	Help: "Admission requests not handled because too many were in flight, by policy (allow or reject)",
+}, []string{"policy"})
+
+func init() {
+	metrics.Registry.MustRegister(admissionsShed)
+}
+
+// limiter bounds how many admission requests are handled at once
+type limiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// newLimiter returns a limiter for cfg, or nil when concurrency is unlimited
+func newLimiter(cfg config.ConcurrencyConfig) *limiter {
+	if cfg.MaxInFlight <= 0 {
+		return nil
+	}
+	return &limiter{
+		slots: make(chan struct{}, cfg.MaxInFlight),
+		wait:  time.Duration(cfg.WaitMilliseconds) * time.Millisecond,
+	}
+}
+
+// acquire takes a slot, waiting up to the configured wait for one to free
+// up. It returns the function releasing the slot, or false when none was
+// free. A nil limiter always succeeds.
+func (l *limiter) acquire(ctx context.Context) (func(), bool) {
+	if l == nil {
+		return func() {}, true
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, true
+	default:
+	}
+	if l.wait <= 0 {
+		return nil, false
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return nil, false
+}
+
+// release frees a slot taken by acquire
+func (l *limiter) release() {
+	<-l.slots
+}
+
+// saturatedResponse answers a request shed by the limiter according to
+// policy, admitting it unmutated with a warning unless policy is reject
+func (m *Mutator) saturatedResponse(policy string) *admissionv1.AdmissionResponse {
+	admissionsShed.WithLabelValues(policy).Inc()
+	err := fmt.Errorf("too many admission requests in flight (limit %d)", cap(m.limiter.slots))
+	if policy == utils.SaturationPolicyReject {
+		response := m.errorResponse(err)
+		response.Result.Code = http.StatusTooManyRequests
+		return response
+	}
+	response := m.allowResponse(fmt.Sprintf("%v, not mutated", err))
+	response.Warnings = []string{fmt.Sprintf("%v, features not applied", err)}
+	return response
+}
@@ -3,16 +3,27 @@ package webhook
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	gomodulesjsonpatch "gomodules.xyz/jsonpatch/v2"
 	admissionv1 "k8s.io/api/admission/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/apis/v1alpha1"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/userdata"
@@ -23,58 +34,350 @@ var (
 	scheme = runtime.NewScheme()
 )
 
+// featureErrorAnnotationFormat builds the per-feature error annotation key
+// (see the utils.Annotation*Error constants)
+const featureErrorAnnotationFormat = utils.DefaultKeyPrefix + "%s-error"
+
+// featureOnErrorAnnotationFormat builds the per-feature error handling
+// override key; values are reject, allow or strip
+const featureOnErrorAnnotationFormat = utils.DefaultKeyPrefix + "%s-on-error"
+
+// featureAppliedAnnotationFormat builds the per-feature tracking annotation key
+// (see the utils.Annotation*Applied constants)
+const featureAppliedAnnotationFormat = utils.DefaultKeyPrefix + "%s-applied"
+
+// maxErrorAnnotationLength caps the error message recorded on the VM
+const maxErrorAnnotationLength = 256
+
+// lastMutation is the JSON recorded in the last-mutation annotation
+type lastMutation struct {
+	WebhookVersion string   `json:"webhookVersion"`
+	Timestamp      string   `json:"timestamp"`
+	Features       []string `json:"features,omitempty"`
+	Reverted       []string `json:"reverted,omitempty"`
+	ConfigSource   string   `json:"configSource"`
+}
+
 func init() {
+	_ = corev1.AddToScheme(scheme)
 	_ = kubevirtv1.AddToScheme(scheme)
 	_ = admissionv1.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = authorizationv1.AddToScheme(scheme)
 }
 
 // Mutator handles VM mutation based on feature annotations
 type Mutator struct {
-	client        client.Client
-	config        *config.Config
-	features      []features.Feature
+	// reloadMu keeps a reload from changing the config or features mid-request
+	reloadMu sync.RWMutex
+
+	client         client.Client
+	config         *config.Config
+	registry       *features.Registry
 	userdataParser *userdata.Parser
+	namespaces     *namespaceCache
+	rules          *ruleCache
+
+	// vmLister, when set, serves VM lists to features instead of client
+	vmLister client.Reader
+
+	// recorder, when set, records what was done to each object as Events on it
+	recorder record.EventRecorder
+
+	// limiter, when set, sheds requests beyond the concurrency limit. It is
+	// created from the initial config and kept across reloads.
+	limiter *limiter
+}
+
+// NewMutator creates a new Mutator applying featureList. It fails if the
+// features can't form a registry, i.e. on duplicate names or a dependency cycle.
+func NewMutator(client client.Client, cfg *config.Config, featureList []features.Feature) (*Mutator, error) {
+	registry, err := features.NewRegistry(featureList)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feature list: %w", err)
+	}
+	return NewMutatorWithRegistry(client, cfg, registry), nil
 }
 
-// NewMutator creates a new Mutator
-func NewMutator(client client.Client, cfg *config.Config, featureList []features.Feature) *Mutator {
+// NewMutatorWithRegistry creates a new Mutator applying the features enabled
+// in registry at the time of each request
+func NewMutatorWithRegistry(client client.Client, cfg *config.Config, registry *features.Registry) *Mutator {
 	return &Mutator{
-		client:        client,
-		config:        cfg,
-		features:      featureList,
-		userdataParser: userdata.NewParser(client),
+		client:         client,
+		config:         cfg,
+		registry:       registry,
+		userdataParser: userdata.NewParser(client, &cfg.UserdataSecrets),
+		namespaces:     &namespaceCache{},
+		rules:          &ruleCache{},
+		limiter:        newLimiter(cfg.Concurrency),
+	}
+}
+
+// Reload switches to cfg and replaces the registered features of the same
+// name with builtin, which should be created from cfg. It waits for in-flight
+// requests, so every request sees either the old or the new configuration.
+func (m *Mutator) Reload(cfg *config.Config, builtin []features.Feature) error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	if err := m.registry.Replace(builtin...); err != nil {
+		return err
 	}
+	m.config = cfg
+	m.userdataParser = userdata.NewParser(m.client, &cfg.UserdataSecrets)
+	return nil
+}
+
+// SetVMLister makes features list VMs from reader, typically an informer
+// cache, instead of querying the API server on every request
+func (m *Mutator) SetVMLister(reader client.Reader) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	m.vmLister = reader
+}
+
+// SetEventRecorder records Events on the admitted objects when features are
+// applied, reverted, stripped or fail
+func (m *Mutator) SetEventRecorder(recorder record.EventRecorder) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	m.recorder = recorder
 }
 
-// Handle processes admission requests
+// Handle processes admission requests within the configured request deadline,
+// so a slow feature can't run into the apiserver's webhook timeout. Beyond
+// the concurrency limit, requests are answered right away without mutation.
 func (m *Mutator) Handle(ctx context.Context, req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	m.reloadMu.RLock()
+	timeouts := m.config.Timeouts
+	onSaturated := m.config.Concurrency.OnSaturated
+	m.reloadMu.RUnlock()
+
+	// The slot is held until handling finishes, even past the deadline, so
+	// abandoned requests still count against the limit
+	release, ok := m.limiter.acquire(ctx)
+	if !ok {
+		log.FromContext(ctx).Info("Admission shed, too many requests in flight", "uid", req.UID, "onSaturated", onSaturated)
+		return m.saturatedResponse(onSaturated), nil
+	}
+
+	if timeouts.RequestSeconds <= 0 {
+		defer release()
+		return m.handle(ctx, req)
+	}
+
+	timeout := time.Duration(timeouts.RequestSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type handled struct {
+		response *admissionv1.AdmissionResponse
+		err      error
+	}
+	done := make(chan handled, 1)
+	go func() {
+		defer release()
+		response, err := m.handle(ctx, req)
+		done <- handled{response, err}
+	}()
+
+	select {
+	case h := <-done:
+		// A failure caused by the deadline is answered like the deadline itself
+		if ctx.Err() == nil || (h.err == nil && h.response.Allowed) {
+			return h.response, h.err
+		}
+	case <-ctx.Done():
+	}
+
+	log.FromContext(ctx).Info("Admission timed out", "uid", req.UID, "timeout", timeout, "onTimeout", timeouts.OnTimeout)
+	return m.timeoutResponse(timeout, timeouts.OnTimeout), nil
+}
+
+// handle mutates the request under the reload lock
+func (m *Mutator) handle(ctx context.Context, req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
 	logger := log.FromContext(ctx)
 
-	// Decode the VM object
-	vm := &kubevirtv1.VirtualMachine{}
-	if err := json.Unmarshal(req.Object.Raw, vm); err != nil {
-		logger.Error(err, "Failed to unmarshal VM")
+	m.reloadMu.RLock()
+	defer m.reloadMu.RUnlock()
+
+	// Propagate dry-run so features can skip side effects; shadow mode
+	// requests have none either
+	dryRun := req.DryRun != nil && *req.DryRun
+	shadow := m.config.Mode == utils.WebhookModeShadow
+	ctx = m.featureContext(ctx, dryRun || shadow)
+
+	events := &eventLog{}
+	response, err := m.mutate(ctx, req, events)
+	if err != nil {
+		if !shadow {
+			return nil, err
+		}
+		response = m.errorResponse(err)
+	}
+
+	if shadow {
+		m.discardShadowResult(ctx, req, response)
+		return response, nil
+	}
+
+	// A request that ran out of time is answered without its mutation
+	if m.recorder != nil && !dryRun && ctx.Err() == nil {
+		events.record(m.recorder)
+	}
+
+	// In strict dry-run mode the intended mutation is computed but never returned
+	if dryRun && m.config.DryRunStrict && response.Patch != nil {
+		logger.Info("Dry-run request in strict mode, discarding patch", "uid", req.UID)
+		response.Patch = nil
+		response.PatchType = nil
+	}
+
+	return response, nil
+}
+
+// Mutate computes the mutation admission would apply to the object of req,
+// for objects that exist already rather than ones being admitted. It is not
+// subject to the concurrency limit or the request deadline, records no Events
+// and isn't counted in the admission metrics. In shadow mode nothing is
+// enforced, so the object is allowed without a patch.
+func (m *Mutator) Mutate(ctx context.Context, req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	m.reloadMu.RLock()
+	defer m.reloadMu.RUnlock()
+
+	if m.config.Mode == utils.WebhookModeShadow {
+		log.FromContext(ctx).Info("Shadow mode, not mutating", "kind", req.Kind.Kind, "namespace", req.Namespace, "name", req.Name)
+		return m.allowResponse("shadow mode, not enforced"), nil
+	}
+	return m.mutate(m.featureContext(ctx, false), req, &eventLog{})
+}
+
+// featureContext returns ctx carrying what features read from it
+func (m *Mutator) featureContext(ctx context.Context, dryRun bool) context.Context {
+	ctx = features.WithDryRun(ctx, dryRun)
+	if m.vmLister != nil {
+		ctx = features.WithVMLister(ctx, m.vmLister)
+	}
+	return ctx
+}
+
+// mutate decodes the admitted object and applies all enabled features
+func (m *Mutator) mutate(ctx context.Context, req *admissionv1.AdmissionRequest, events *eventLog) (*admissionv1.AdmissionResponse, error) {
+	logger := log.FromContext(ctx)
+
+	// Only creates and updates carry an object to mutate
+	switch req.Operation {
+	case admissionv1.Delete, admissionv1.Connect:
+		logger.Info("Skipping operation", "operation", req.Operation, "kind", req.Kind.Kind)
+		return m.allowResponse(fmt.Sprintf("operation %s is not mutated", req.Operation)), nil
+	}
+	if req.SubResource != "" {
+		logger.Info("Skipping subresource", "subresource", req.SubResource, "kind", req.Kind.Kind)
+		return m.allowResponse(fmt.Sprintf("subresource %s is not mutated", req.SubResource)), nil
+	}
+	// The spec of a VMI can't change after creation
+	if req.Kind.Kind == KindVirtualMachineInstance && req.Operation == admissionv1.Update {
+		logger.Info("Skipping VirtualMachineInstance update", "name", req.Name)
+		return m.allowResponse("VirtualMachineInstance updates are not mutated"), nil
+	}
+
+	// Admit kinds the webhook doesn't handle untouched rather than misreading them
+	if err := checkKind(req); err != nil {
+		logger.Info("Skipping unsupported request", "reason", err.Error())
+		response := m.allowResponse(fmt.Sprintf("%v, not mutated", err))
+		response.Warnings = []string{fmt.Sprintf("%v, features not applied", err)}
+		return response, nil
+	}
+
+	// Decode the admitted object and get the VM view features operate on
+	obj, err := decodeObject(req)
+	if err != nil {
+		logger.Error(err, "Failed to unmarshal object", "kind", req.Kind.Kind)
 		return m.errorResponse(err), nil
 	}
+	vm := obj.VirtualMachine()
+	events.setObject(req, obj)
+
+	// VMIs created from a VM or replica set were mutated through its template
+	if owner := templateOwner(obj); owner != "" {
+		logger.Info("Skipping VirtualMachineInstance created from a template", "vmi", vm.Name, "owner", owner)
+		return m.allowResponse(fmt.Sprintf("VirtualMachineInstance created by %s is not mutated again", owner)), nil
+	}
+
+	// Keys of a custom prefix are processed under the default one
+	if m.customKeyPrefix() {
+		vm = vm.DeepCopy()
+		m.swapKeyPrefix(vm)
+	}
 
 	logger.Info("Processing VM mutation",
+		"kind", obj.Kind(),
 		"vm", vm.Name,
 		"namespace", vm.Namespace,
-		"operation", req.Operation)
+		"operation", req.Operation,
+		"dryRun", features.IsDryRun(ctx))
 
-	// Parse userdata for feature directives (non-fatal if fails)
-	userdataFeatures, err := m.userdataParser.ParseFeatures(ctx, vm)
+	namespace := vm.Namespace
+	if namespace == "" {
+		namespace = req.Namespace
+	}
+
+	// Non-fatal issues returned to the client as admission warnings
+	warnings := []string{}
+
+	// Excluded VMs are admitted untouched, without reading their userdata
+	excludedBy, err := m.excludedBy(ctx, vm, namespace)
 	if err != nil {
-		logger.Error(err, "Failed to parse userdata features")
-		// Non-fatal: continue with annotation-based features only
-		userdataFeatures = nil
-	} else if len(userdataFeatures) > 0 {
-		logger.Info("Found feature directives in userdata", "features", userdataFeatures)
+		logger.Error(err, "Failed to check whether the namespace is excluded")
+		if m.config.ErrorHandlingMode == utils.ErrorHandlingReject {
+			return m.errorResponse(err), nil
+		}
+		warnings = append(warnings, fmt.Sprintf("namespace exclusion could not be checked: %v", err))
+	}
+	if excludedBy != "" {
+		logger.Info("VM excluded from feature management", "vm", vm.Name, "excludedBy", excludedBy)
+		return m.allowResponse(fmt.Sprintf("%s opted out of feature management via %s", excludedBy, utils.AnnotationExclude)), nil
+	}
+
+	// Parse userdata for feature directives (non-fatal if fails)
+	var userdataFeatures map[string]string
+	if m.config.UserdataDirectives {
+		var directiveWarnings []string
+		var err error
+		userdataFeatures, directiveWarnings, err = m.userdataParser.ParseFeatures(ctx, vm, m.registeredFeatures()...)
+		if err != nil {
+			// Non-fatal: unreadable volumes are skipped, directives from the rest still apply
+			logger.Error(err, "Failed to parse userdata features")
+			warnings = append(warnings, fmt.Sprintf("some userdata could not be read for feature directives: %v", err))
+		}
+		for _, warning := range directiveWarnings {
+			warnings = append(warnings, "userdata "+warning)
+		}
+		if len(userdataFeatures) > 0 {
+			logger.Info("Found feature directives in userdata", "features", userdataFeatures)
+		}
 	}
 
 	// Create a copy to mutate
 	mutatedVM := vm.DeepCopy()
 
+	// Keys overlaid for this request only, restored before patching
+	overlay := newRequestOverlay(m.config.ConfigSource)
+
+	// Honor deprecated keys from the alias table
+	warnings = append(warnings, m.resolveKeyAliases(mutatedVM, overlay)...)
+
+	// Expand the combined features key (individually set keys take precedence)
+	if err := m.expandCombinedFeatures(mutatedVM, overlay); err != nil {
+		logger.Error(err, "Failed to parse combined feature spec")
+		if m.config.ErrorHandlingMode == utils.ErrorHandlingReject {
+			return m.errorResponse(err), nil
+		}
+		warnings = append(warnings, fmt.Sprintf("ignoring %s: %v", utils.AnnotationFeatures, err))
+	}
+
 	// Merge userdata features into mutated VM's annotations (annotations take precedence)
 	if len(userdataFeatures) > 0 {
 		if mutatedVM.Annotations == nil {
@@ -90,52 +393,173 @@ func (m *Mutator) Handle(ctx context.Context, req *admissionv1.AdmissionRequest)
 		}
 	}
 
+	// Keep directive comments from reaching the guest once they have been read
+	stripped := m.config.UserdataDirectives && m.config.StripDirectiveComments && userdata.StripDirectives(mutatedVM)
+
+	// Drop RBAC-gated features the requesting user may not use
+	denied, err := m.unauthorizedFeatures(ctx, req, mutatedVM, namespace, overlay)
+	if err != nil {
+		logger.Error(err, "Failed to authorize feature requests")
+		if m.config.ErrorHandlingMode == utils.ErrorHandlingReject {
+			return m.errorResponse(err), nil
+		}
+		warnings = append(warnings, fmt.Sprintf("%v (feature skipped)", err))
+	}
+	if len(denied) > 0 {
+		err := fmt.Errorf("user %s is not authorized to request features: %s", req.UserInfo.Username, strings.Join(denied, ", "))
+		logger.Info("VM requests unauthorized features", "vm", vm.Name, "user", req.UserInfo.Username, "features", denied)
+		if m.config.ErrorHandlingMode == utils.ErrorHandlingReject {
+			return m.errorResponse(err), nil
+		}
+		warnings = append(warnings, fmt.Sprintf("%v (ignored)", err))
+	}
+
+	// Layer the selected profile under the VM's own requests
+	if err := m.applyProfile(ctx, mutatedVM, overlay); err != nil {
+		logger.Error(err, "Failed to apply feature profile")
+		if m.config.ErrorHandlingMode == utils.ErrorHandlingReject {
+			return m.errorResponse(err), nil
+		}
+		warnings = append(warnings, fmt.Sprintf("feature profile was not applied: %v", err))
+	}
+
+	// Layer the namespace's feature keys under the VM's own requests and profile
+	if err := m.applyNamespaceDefaults(ctx, mutatedVM, namespace, overlay); err != nil {
+		logger.Error(err, "Failed to apply namespace feature defaults")
+		if m.config.ErrorHandlingMode == utils.ErrorHandlingReject {
+			return m.errorResponse(err), nil
+		}
+		warnings = append(warnings, fmt.Sprintf("namespace feature defaults were not applied: %v", err))
+	}
+
+	// Layer matching feature policies over the VM's own requests
+	policy, err := m.resolvePolicies(ctx, mutatedVM, namespace)
+	if err != nil {
+		logger.Error(err, "Failed to resolve feature policies")
+		if m.config.ErrorHandlingMode == utils.ErrorHandlingReject {
+			return m.errorResponse(err), nil
+		}
+		warnings = append(warnings, fmt.Sprintf("feature policies were not applied: %v", err))
+	}
+	if violations := m.applyPolicy(mutatedVM, policy, overlay); len(violations) > 0 {
+		err := fmt.Errorf("features forbidden by policy: %s", strings.Join(violations, ", "))
+		logger.Info("VM requests forbidden features", "vm", vm.Name, "features", violations)
+		if m.config.ErrorHandlingMode == utils.ErrorHandlingReject {
+			return m.errorResponse(err), nil
+		}
+		warnings = append(warnings, fmt.Sprintf("%v (ignored)", err))
+	}
+
+	// Use one set of features for the whole request even if the registry changes
+	featureList := m.registry.Enabled()
+
 	// Log detailed feature detection information for debugging
-	m.logFeatureDetection(ctx, mutatedVM)
+	m.logFeatureDetection(ctx, featureList, mutatedVM)
+
+	// Undo features whose request was removed by this update
+	reverted, err := m.revertRemovedFeatures(ctx, featureList, req, mutatedVM)
+	if err != nil {
+		logger.Error(err, "Failed to revert removed features")
+		warnings = append(warnings, fmt.Sprintf("failed to revert removed features: %v", err))
+	}
 
 	// Check if any features are enabled (check mutatedVM with merged userdata)
-	if !m.hasEnabledFeatures(mutatedVM) {
+	if !m.hasEnabledFeatures(featureList, mutatedVM) && len(reverted) == 0 && !stripped {
 		logger.Info("No features enabled for VM", "vm", vm.Name)
-		return m.allowResponse("No features requested"), nil
+		response := m.allowResponse("No features requested")
+		if len(warnings) > 0 {
+			response.Warnings = warnings
+		}
+		return response, nil
 	}
 
 	// Apply features
 	appliedFeatures := []string{}
 	allAnnotations := make(map[string]string)
 
-	for _, feature := range m.features {
+	// In continue mode a failing feature is skipped and the rest still run
+	continueOnError := m.config.ErrorHandlingMode == utils.ErrorHandlingContinue
+	outcomes := []string{}
+	failures := 0
+	rejections := []string{}
+
+	for _, feature := range featureList {
 		if !feature.IsEnabled(mutatedVM) {
 			continue
 		}
 
 		logger.Info("Feature enabled", "feature", feature.Name(), "vm", vm.Name)
 
-		// Validate
-		if err := feature.Validate(ctx, mutatedVM, m.client); err != nil {
-			logger.Error(err, "Feature validation failed", "feature", feature.Name())
-			return m.handleError(feature.Name(), err, vm, mutatedVM), nil
-		}
+		// Snapshot so a failing feature's partial changes can be discarded
+		snapshot := mutatedVM.DeepCopy()
 
-		// Apply
-		result, err := feature.Apply(ctx, mutatedVM, m.client)
+		result, err := m.runFeature(ctx, featureList, feature, mutatedVM)
 		if err != nil {
-			logger.Error(err, "Feature application failed", "feature", feature.Name())
-			return m.handleError(feature.Name(), err, vm, mutatedVM), nil
+			mode, overridden := m.featureErrorHandlingMode(ctx, mutatedVM, feature.Name())
+			if mode == utils.ErrorHandlingReject {
+				// Keep checking the remaining features so the rejection lists every problem
+				rejections = append(rejections, fmt.Sprintf("feature %s failed: %v", feature.Name(), err))
+				events.featureFailed(feature.Name(), mode, err)
+				mutatedVM = snapshot
+				continue
+			}
+			if !overridden && !continueOnError {
+				if len(rejections) > 0 {
+					// The request is rejected anyway, only collect further rejections
+					mutatedVM = snapshot
+					continue
+				}
+				overlay.restore(snapshot)
+				events.featureFailed(feature.Name(), m.config.ErrorHandlingMode, err)
+				response := m.handleError(feature.Name(), err, req.Object.Raw, obj, snapshot)
+				if response.Allowed {
+					response.Warnings = append(warnings, fmt.Sprintf("feature %s failed: %v", feature.Name(), err))
+				}
+				return response, nil
+			}
+
+			// Per-feature overrides and continue mode skip the failing feature only
+			mutatedVM = snapshot
+			m.markFeatureFailed(mutatedVM, feature.Name(), err, mode != utils.ErrorHandlingAllowAndLog)
+			events.featureFailed(feature.Name(), mode, err)
+			outcomes = append(outcomes, fmt.Sprintf("%s=failed (%v)", feature.Name(), err))
+			failures++
+			warnings = append(warnings, fmt.Sprintf("feature %s failed and was skipped: %v", feature.Name(), err))
+			continue
 		}
 
-		if result.Applied {
-			appliedFeatures = append(appliedFeatures, feature.Name())
+		for _, warning := range result.Warnings {
+			warnings = append(warnings, fmt.Sprintf("feature %s: %s", feature.Name(), warning))
+		}
 
-			// Collect tracking annotations
-			for k, v := range result.Annotations {
-				allAnnotations[k] = v
-			}
+		if !result.Applied {
+			outcomes = append(outcomes, feature.Name()+"=unchanged")
+			continue
+		}
 
-			logger.Info("Feature applied successfully",
-				"feature", feature.Name(),
-				"vm", vm.Name,
-				"messages", result.Messages)
+		// On reinvocation an already-applied feature leaves the VM as it was
+		if m.alreadyApplied(snapshot, mutatedVM, result) {
+			logger.Info("Feature already applied, skipping", "feature", feature.Name(), "vm", vm.Name)
+			outcomes = append(outcomes, feature.Name()+"=unchanged")
+			continue
 		}
+
+		appliedFeatures = append(appliedFeatures, feature.Name())
+		outcomes = append(outcomes, feature.Name()+"=applied")
+
+		// Collect tracking annotations
+		for k, v := range result.Annotations {
+			allAnnotations[k] = v
+		}
+
+		logger.Info("Feature applied successfully",
+			"feature", feature.Name(),
+			"vm", vm.Name,
+			"messages", result.Messages)
+	}
+
+	if len(rejections) > 0 {
+		return m.errorResponse(rejectionError(rejections)), nil
 	}
 
 	// Add tracking annotations if enabled
@@ -150,8 +574,23 @@ func (m *Mutator) Handle(ctx context.Context, req *admissionv1.AdmissionRequest)
 		}
 	}
 
+	// Summarize the mutation in one machine-readable annotation
+	if m.config.AddTrackingAnnotations && (len(appliedFeatures) > 0 || len(reverted) > 0) {
+		record, err := m.lastMutation(appliedFeatures, reverted)
+		if err != nil {
+			logger.Error(err, "Failed to encode last mutation")
+		} else {
+			if mutatedVM.Annotations == nil {
+				mutatedVM.Annotations = make(map[string]string)
+			}
+			mutatedVM.Annotations[utils.AnnotationLastMutation] = record
+		}
+	}
+
 	// Create JSON patch
-	patch, err := m.createPatch(vm, mutatedVM)
+	overlay.restore(mutatedVM)
+	m.swapKeyPrefix(mutatedVM)
+	patch, err := m.createPatch(req.Object.Raw, obj.Original(), obj.Mutated(mutatedVM))
 	if err != nil {
 		logger.Error(err, "Failed to create patch")
 		return m.errorResponse(err), nil
@@ -159,22 +598,249 @@ func (m *Mutator) Handle(ctx context.Context, req *admissionv1.AdmissionRequest)
 
 	logger.Info("VM mutation successful",
 		"vm", vm.Name,
-		"appliedFeatures", appliedFeatures)
+		"appliedFeatures", appliedFeatures,
+		"revertedFeatures", reverted)
+	if len(appliedFeatures) > 0 {
+		events.normal(utils.EventReasonFeaturesApplied, "Applied features: %s", strings.Join(appliedFeatures, ", "))
+	}
+	if len(reverted) > 0 {
+		events.normal(utils.EventReasonFeaturesReverted, "Reverted removed features: %s", strings.Join(reverted, ", "))
+	}
 
-	return &admissionv1.AdmissionResponse{
+	response := &admissionv1.AdmissionResponse{
 		UID:     req.UID,
 		Allowed: true,
-		Patch:   patch,
-		PatchType: func() *admissionv1.PatchType {
+	}
+	if patch != nil {
+		response.Patch = patch
+		response.PatchType = func() *admissionv1.PatchType {
 			pt := admissionv1.PatchTypeJSONPatch
 			return &pt
-		}(),
-	}, nil
+		}()
+	}
+
+	// Report per-feature outcomes so partial failures are visible to the client
+	if continueOnError || failures > 0 {
+		response.Result = &metav1.Status{
+			Message: "Feature outcomes: " + strings.Join(outcomes, ", "),
+		}
+	}
+	if len(warnings) > 0 {
+		response.Warnings = warnings
+	}
+
+	return response, nil
+}
+
+// revertRemovedFeatures reverts, on UPDATE, the features whose request was
+// removed while the stored object shows they had been applied. It returns the
+// names of the reverted features.
+func (m *Mutator) revertRemovedFeatures(ctx context.Context, featureList []features.Feature, req *admissionv1.AdmissionRequest, vm *kubevirtv1.VirtualMachine) ([]string, error) {
+	logger := log.FromContext(ctx)
+
+	oldObj, err := decodeOldObject(req)
+	if err != nil || oldObj == nil {
+		return nil, err
+	}
+	previous := oldObj.VirtualMachine()
+	m.swapKeyPrefix(previous)
+
+	var reverted []string
+	for _, feature := range featureList {
+		reverter, ok := feature.(features.Reverter)
+		if !ok || feature.IsEnabled(vm) {
+			continue
+		}
+		appliedKey := fmt.Sprintf(featureAppliedAnnotationFormat, feature.Name())
+		if _, applied := previous.GetAnnotations()[appliedKey]; !applied {
+			continue
+		}
+
+		// Revert on a copy so a failure leaves the VM untouched
+		candidate := vm.DeepCopy()
+		if err := reverter.Revert(ctx, candidate, previous); err != nil {
+			return reverted, fmt.Errorf("feature %s: %w", feature.Name(), err)
+		}
+		*vm = *candidate
+		delete(vm.Annotations, appliedKey)
+		delete(vm.Annotations, fmt.Sprintf(featureErrorAnnotationFormat, feature.Name()))
+		reverted = append(reverted, feature.Name())
+		logger.Info("Reverted removed feature", "feature", feature.Name(), "vm", vm.Name)
+	}
+
+	return reverted, nil
+}
+
+// alreadyApplied reports whether applying a feature left the VM unchanged and
+// its tracking annotations were already recorded, as happens when the webhook
+// is reinvoked on an object it has mutated before
+func (m *Mutator) alreadyApplied(before, after *kubevirtv1.VirtualMachine, result *features.MutationResult) bool {
+	if !equality.Semantic.DeepEqual(before.Spec, after.Spec) ||
+		!equality.Semantic.DeepEqual(before.Labels, after.Labels) ||
+		!equality.Semantic.DeepEqual(before.Annotations, after.Annotations) {
+		return false
+	}
+	if !m.config.AddTrackingAnnotations {
+		return true
+	}
+	for k, v := range result.Annotations {
+		if current, ok := before.Annotations[k]; !ok || current != v {
+			return false
+		}
+	}
+	return true
+}
+
+// runFeature validates and applies a single feature to the VM
+func (m *Mutator) runFeature(ctx context.Context, featureList []features.Feature, feature features.Feature, vm *kubevirtv1.VirtualMachine) (*features.MutationResult, error) {
+	logger := log.FromContext(ctx)
+
+	if err := features.CheckPrerequisites(feature, featureList, vm); err != nil {
+		logger.Error(err, "Feature prerequisites missing", "feature", feature.Name())
+		return nil, err
+	}
+
+	if m.config.Timeouts.FeatureSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(m.config.Timeouts.FeatureSeconds)*time.Second)
+		defer cancel()
+	}
+
+	if err := feature.Validate(ctx, vm, m.client); err != nil {
+		err = deadlineError(ctx, err)
+		logger.Error(err, "Feature validation failed", "feature", feature.Name())
+		return nil, err
+	}
+
+	result, err := feature.Apply(ctx, vm, m.client)
+	if err != nil {
+		err = deadlineError(ctx, err)
+		logger.Error(err, "Feature application failed", "feature", feature.Name())
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// deadlineError marks err as caused by the feature running out of time, so
+// the failure isn't mistaken for a problem with the VM
+func deadlineError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out: %w", err)
+	}
+	return err
+}
+
+// featureErrorHandlingMode returns the error handling mode for a feature,
+// honouring a vm-feature-manager.io/<feature>-on-error override on the VM.
+// The second result reports whether a valid override was found.
+func (m *Mutator) featureErrorHandlingMode(ctx context.Context, vm *kubevirtv1.VirtualMachine, featureName string) (string, bool) {
+	key := fmt.Sprintf(featureOnErrorAnnotationFormat, featureName)
+	value, exists := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), key)
+	if !exists {
+		return m.config.ErrorHandlingMode, false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case utils.ErrorHandlingReject:
+		return utils.ErrorHandlingReject, true
+	case "allow", utils.ErrorHandlingAllowAndLog:
+		return utils.ErrorHandlingAllowAndLog, true
+	case "strip", utils.ErrorHandlingStripLabel:
+		return utils.ErrorHandlingStripLabel, true
+	default:
+		log.FromContext(ctx).Info("Ignoring invalid error handling override, using global mode",
+			"key", key, "value", value, "mode", m.config.ErrorHandlingMode)
+		return m.config.ErrorHandlingMode, false
+	}
+}
+
+// markFeatureFailed records the error as vm-feature-manager.io/<feature>-error,
+// stripping the failing feature's request key when strip is set
+func (m *Mutator) markFeatureFailed(vm *kubevirtv1.VirtualMachine, featureName string, err error, strip bool) {
+	if strip {
+		m.stripFeatureKey(vm, featureName)
+	}
+	if vm.Annotations == nil {
+		vm.Annotations = make(map[string]string)
+	}
+	vm.Annotations[fmt.Sprintf(featureErrorAnnotationFormat, featureName)] = truncateErrorMessage(err.Error())
+}
+
+// stripFeatureKey removes the feature's request key from the configured
+// config source (annotations, labels or both)
+func (m *Mutator) stripFeatureKey(vm *kubevirtv1.VirtualMachine, featureName string) {
+	for _, key := range m.getFeatureAnnotationKeys(featureName) {
+		if utils.ReadsLabels(m.config.ConfigSource) {
+			delete(vm.Labels, key)
+		}
+		if utils.ReadsAnnotations(m.config.ConfigSource) {
+			delete(vm.Annotations, key)
+		}
+	}
+}
+
+// registeredFeatures returns the names of all registered features, so
+// directives can name features beyond the built-in ones, e.g. plugins
+func (m *Mutator) registeredFeatures() []string {
+	registered := []string{}
+	for _, state := range m.registry.States() {
+		registered = append(registered, state.Name)
+	}
+	return registered
+}
+
+// customKeyPrefix reports whether keys use a prefix other than the default one
+func (m *Mutator) customKeyPrefix() bool {
+	return m.config.KeyPrefix != "" && m.config.KeyPrefix != utils.DefaultKeyPrefix
+}
+
+// swapKeyPrefix exchanges the configured key prefix and the default one on
+// the VM's labels and annotations; swapping again restores them
+func (m *Mutator) swapKeyPrefix(vm *kubevirtv1.VirtualMachine) {
+	if !m.customKeyPrefix() {
+		return
+	}
+	utils.SwapKeyPrefix(vm.Labels, m.config.KeyPrefix)
+	utils.SwapKeyPrefix(vm.Annotations, m.config.KeyPrefix)
+}
+
+// lastMutation encodes the last-mutation annotation for a mutation applying
+// and reverting the given features
+func (m *Mutator) lastMutation(applied, reverted []string) (string, error) {
+	record, err := json.Marshal(lastMutation{
+		WebhookVersion: m.config.WebhookVersion,
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+		Features:       applied,
+		Reverted:       reverted,
+		ConfigSource:   string(m.config.ConfigSource),
+	})
+	return string(record), err
+}
+
+// configSourceKind names the metadata kind holding feature requests
+func (m *Mutator) configSourceKind() string {
+	switch {
+	case !utils.ReadsLabels(m.config.ConfigSource):
+		return "annotation"
+	case !utils.ReadsAnnotations(m.config.ConfigSource):
+		return "label"
+	default:
+		return "annotation/label"
+	}
+}
+
+// truncateErrorMessage shortens an error message to maxErrorAnnotationLength
+func truncateErrorMessage(msg string) string {
+	if len(msg) <= maxErrorAnnotationLength {
+		return msg
+	}
+	return msg[:maxErrorAnnotationLength-3] + "..."
 }
 
 // hasEnabledFeatures checks if any feature is requested via annotations
-func (m *Mutator) hasEnabledFeatures(vm *kubevirtv1.VirtualMachine) bool {
-	for _, feature := range m.features {
+func (m *Mutator) hasEnabledFeatures(featureList []features.Feature, vm *kubevirtv1.VirtualMachine) bool {
+	for _, feature := range featureList {
 		if feature.IsEnabled(vm) {
 			return true
 		}
@@ -183,7 +849,7 @@ func (m *Mutator) hasEnabledFeatures(vm *kubevirtv1.VirtualMachine) bool {
 }
 
 // logFeatureDetection logs detailed information about feature detection for debugging
-func (m *Mutator) logFeatureDetection(ctx context.Context, vm *kubevirtv1.VirtualMachine) {
+func (m *Mutator) logFeatureDetection(ctx context.Context, featureList []features.Feature, vm *kubevirtv1.VirtualMachine) {
 	logger := log.FromContext(ctx).V(1) // V(1) = debug level
 
 	configMap := utils.GetConfigMap(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels())
@@ -198,7 +864,7 @@ func (m *Mutator) logFeatureDetection(ctx context.Context, vm *kubevirtv1.Virtua
 		"configCount", len(configMap),
 		"config", configMap)
 
-	for _, feature := range m.features {
+	for _, feature := range featureList {
 		enabled := feature.IsEnabled(vm)
 		logger.Info("Feature detection result",
 			"feature", feature.Name(),
@@ -207,100 +873,171 @@ func (m *Mutator) logFeatureDetection(ctx context.Context, vm *kubevirtv1.Virtua
 	}
 }
 
-// createPatch creates a JSON patch between original and mutated VM
-func (m *Mutator) createPatch(original, mutated *kubevirtv1.VirtualMachine) ([]byte, error) {
+// createPatch creates a minimal RFC 6902 JSON patch that transforms the
+// admitted object into the mutated one. The difference between the decoded
+// and mutated objects is first expressed as a merge patch and applied to the
+// raw request object, so fields unknown to the KubeVirt API types are
+// preserved and missing parent paths are created by the generated operations.
+// It returns a nil patch when the objects don't differ.
+func (m *Mutator) createPatch(raw []byte, original, mutated interface{}) ([]byte, error) {
 	originalBytes, err := json.Marshal(original)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal original VM: %w", err)
+		return nil, fmt.Errorf("failed to marshal original object: %w", err)
 	}
 
 	mutatedBytes, err := json.Marshal(mutated)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal mutated VM: %w", err)
+		return nil, fmt.Errorf("failed to marshal mutated object: %w", err)
 	}
 
-	// For now, we'll use a simple approach - in production you might want to use
-	// a proper JSON patch library like github.com/evanphx/json-patch
-	// This is a simplified version that replaces the entire object
-	patch := []map[string]interface{}{
-		{
-			"op":    "replace",
-			"path":  "/spec",
-			"value": mutated.Spec,
-		},
-		{
-			"op":    "replace",
-			"path":  "/metadata/annotations",
-			"value": mutated.Annotations,
-		},
+	mergePatch, err := jsonpatch.CreateMergePatch(originalBytes, mutatedBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create merge patch: %w", err)
+	}
+
+	if len(raw) == 0 {
+		raw = originalBytes
 	}
 
-	patchBytes, err := json.Marshal(patch)
+	patchedBytes, err := jsonpatch.MergePatch(raw, mergePatch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal patch: %w", err)
+		return nil, fmt.Errorf("failed to apply merge patch: %w", err)
+	}
+
+	operations, err := gomodulesjsonpatch.CreatePatch(raw, patchedBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JSON patch: %w", err)
+	}
+	if len(operations) == 0 {
+		return nil, nil
 	}
 
-	// Debug: log the patch size
-	_ = originalBytes
-	_ = mutatedBytes
+	patchBytes, err := json.Marshal(operations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal patch: %w", err)
+	}
 
 	return patchBytes, nil
 }
 
 // handleError handles feature errors based on error handling mode
-func (m *Mutator) handleError(featureName string, err error, originalVM, mutatedVM *kubevirtv1.VirtualMachine) *admissionv1.AdmissionResponse {
+func (m *Mutator) handleError(featureName string, err error, raw []byte, obj admissionObject, mutatedVM *kubevirtv1.VirtualMachine) *admissionv1.AdmissionResponse {
 	switch m.config.ErrorHandlingMode {
 	case utils.ErrorHandlingReject:
 		return m.errorResponse(fmt.Errorf("feature %s failed: %w", featureName, err))
 	case utils.ErrorHandlingAllowAndLog:
-		// Log error but allow admission
-		return m.allowResponse(fmt.Sprintf("Feature %s failed but admission allowed: %v", featureName, err))
+		// Allow admission without feature mutations, recording only the error
+		failedVM := obj.VirtualMachine().DeepCopy()
+		m.swapKeyPrefix(failedVM)
+		m.markFeatureFailed(failedVM, featureName, err, false)
+		return m.failureResponse(raw, obj, failedVM,
+			fmt.Sprintf("Feature %s failed but admission allowed: %v", featureName, err))
 	case utils.ErrorHandlingStripLabel:
-		// Strip the feature annotation and allow admission with patch
-		if mutatedVM.Annotations != nil {
-			// Remove the feature-specific annotation based on feature name
-			annotationKey := m.getFeatureAnnotationKey(featureName)
-			if annotationKey != "" {
-				delete(mutatedVM.Annotations, annotationKey)
-			}
-		}
-
-		// Create patch with the stripped annotation
-		patch, patchErr := m.createPatch(originalVM, mutatedVM)
-		if patchErr != nil {
-			// If we can't create a patch, fall back to allowing without mutation
-			return m.allowResponse(fmt.Sprintf("Feature %s failed, annotation strip failed: %v", featureName, patchErr))
-		}
-
-		return &admissionv1.AdmissionResponse{
-			Allowed: true,
-			Patch:   patch,
-			PatchType: func() *admissionv1.PatchType {
-				pt := admissionv1.PatchTypeJSONPatch
-				return &pt
-			}(),
-			Result: &metav1.Status{
-				Message: fmt.Sprintf("Feature %s failed, annotation %s stripped and admission allowed", featureName, m.getFeatureAnnotationKey(featureName)),
-			},
-		}
+		// Strip the feature annotation, record the error and allow admission with patch
+		m.markFeatureFailed(mutatedVM, featureName, err, true)
+		return m.failureResponse(raw, obj, mutatedVM,
+			fmt.Sprintf("Feature %s failed, %s %s stripped and admission allowed", featureName, m.configSourceKind(), strings.Join(m.getFeatureAnnotationKeys(featureName), ", ")))
 	default:
 		return m.errorResponse(err)
 	}
 }
 
-// getFeatureAnnotationKey returns the annotation key for a given feature name
-func (m *Mutator) getFeatureAnnotationKey(featureName string) string {
+// failureResponse allows admission with a patch carrying the failure annotations
+func (m *Mutator) failureResponse(raw []byte, obj admissionObject, vm *kubevirtv1.VirtualMachine, message string) *admissionv1.AdmissionResponse {
+	m.swapKeyPrefix(vm)
+	patch, patchErr := m.createPatch(raw, obj.Original(), obj.Mutated(vm))
+	if patchErr != nil {
+		// If we can't create a patch, fall back to allowing without mutation
+		return m.allowResponse(fmt.Sprintf("%s (patch failed: %v)", message, patchErr))
+	}
+
+	response := m.allowResponse(message)
+	if patch != nil {
+		response.Patch = patch
+		response.PatchType = func() *admissionv1.PatchType {
+			pt := admissionv1.PatchTypeJSONPatch
+			return &pt
+		}()
+	}
+	return response
+}
+
+// rejectionError combines the failures of all rejecting features into one error
+func rejectionError(rejections []string) error {
+	if len(rejections) == 1 {
+		return errors.New(rejections[0])
+	}
+	return fmt.Errorf("%d features failed: %s", len(rejections), strings.Join(rejections, "; "))
+}
+
+// getFeatureAnnotationKeys returns the annotation keys a given feature reads
+// its request from
+func (m *Mutator) getFeatureAnnotationKeys(featureName string) []string {
 	switch featureName {
 	case utils.FeatureNestedVirt:
-		return utils.AnnotationNestedVirt
+		return []string{utils.AnnotationNestedVirt}
 	case utils.FeatureGpuDevicePlugin:
-		return utils.AnnotationGpuDevicePlugin
+		return []string{utils.AnnotationGpuDevicePlugin}
 	case utils.FeaturePciPassthrough:
-		return utils.AnnotationPciPassthrough
+		return []string{utils.AnnotationPciPassthrough}
 	case utils.FeatureVBiosInjection:
-		return utils.AnnotationVBiosInjection
+		return []string{utils.AnnotationVBiosInjection}
+	case utils.FeatureTpm:
+		return []string{utils.AnnotationTpm}
+	case utils.FeatureSev:
+		return []string{utils.AnnotationSev}
+	case utils.FeatureDedicatedCPUs:
+		return []string{utils.AnnotationDedicatedCPUs}
+	case utils.FeatureNuma:
+		return []string{utils.AnnotationNuma}
+	case utils.FeatureRealtime:
+		return []string{utils.AnnotationRealtime}
+	case utils.FeatureHyperV:
+		return []string{utils.AnnotationHyperV}
+	case utils.FeatureCPUModel:
+		return []string{utils.AnnotationCPUModel}
+	case utils.FeatureVGpu:
+		return []string{utils.AnnotationVGpu}
+	case utils.FeatureUsbPassthrough:
+		return []string{utils.AnnotationUsbPassthrough}
+	case utils.FeatureStoragePerformance:
+		return []string{utils.AnnotationIOThreads, utils.AnnotationBlockMultiQueue}
+	case utils.FeatureNetMultiQueue:
+		return []string{utils.AnnotationNetMultiQueue}
+	case utils.FeatureBootOrder:
+		return []string{utils.AnnotationBootOrder}
+	case utils.FeatureSmbios:
+		return []string{utils.AnnotationSmbios}
+	case utils.FeatureTolerations:
+		return []string{utils.AnnotationTolerations}
+	case utils.FeatureCPUTopology:
+		return []string{utils.AnnotationCPUTopology}
+	case utils.FeatureGuaranteedQoS:
+		return []string{utils.AnnotationGuaranteedQoS}
+	case utils.FeatureHotplug:
+		return []string{utils.AnnotationMaxSockets, utils.AnnotationMaxGuestMemory}
+	case utils.FeatureKernelBoot:
+		return []string{utils.AnnotationKernelBoot}
+	case utils.FeatureCdromIso:
+		return []string{utils.AnnotationCdromIso}
+	case utils.FeatureSSHKeys:
+		return []string{utils.AnnotationSSHKeys}
+	case utils.FeatureSysprep:
+		return []string{utils.AnnotationSysprep}
+	case utils.FeatureGuestAgent:
+		return []string{utils.AnnotationGuestAgent}
+	case utils.FeatureMacAddresses:
+		return []string{utils.AnnotationMacAddresses}
+	case utils.FeatureOSPreset:
+		return []string{utils.AnnotationOSPreset}
+	case utils.FeatureMeshExclude:
+		return []string{utils.AnnotationMeshExclude}
+	case utils.FeaturePropagateMetadata:
+		return []string{utils.AnnotationPropagateMetadata}
+	case utils.FeatureHookSidecar:
+		return []string{utils.AnnotationHookSidecar}
 	default:
-		return ""
+		return nil
 	}
 }
 
@@ -314,6 +1051,18 @@ func (m *Mutator) allowResponse(message string) *admissionv1.AdmissionResponse {
 	}
 }
 
+// timeoutResponse answers a request that ran out of time according to policy,
+// admitting it unmutated with a warning unless policy is reject
+func (m *Mutator) timeoutResponse(timeout time.Duration, policy string) *admissionv1.AdmissionResponse {
+	err := fmt.Errorf("admission timed out after %s", timeout)
+	if policy == utils.TimeoutPolicyReject {
+		return m.errorResponse(err)
+	}
+	response := m.allowResponse(fmt.Sprintf("%v, not mutated", err))
+	response.Warnings = []string{fmt.Sprintf("%v, features not applied", err)}
+	return response
+}
+
 // errorResponse creates a denied admission response
 func (m *Mutator) errorResponse(err error) *admissionv1.AdmissionResponse {
 	return &admissionv1.AdmissionResponse{
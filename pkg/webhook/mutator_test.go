@@ -3,18 +3,29 @@ package webhook
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	kubevirtv1 "kubevirt.io/api/core/v1"
+	poolv1alpha1 "kubevirt.io/api/pool/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/plugins"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
 
@@ -29,6 +40,7 @@ var _ = Describe("Mutator", func() {
 		ctx = context.Background()
 		cfg = &config.Config{
 			AddTrackingAnnotations: true,
+			UserdataDirectives:     true,
 			ErrorHandlingMode:      utils.ErrorHandlingReject,
 			ConfigSource:           utils.ConfigSourceAnnotations,
 		}
@@ -66,7 +78,7 @@ var _ = Describe("Mutator", func() {
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -110,7 +122,7 @@ var _ = Describe("Mutator", func() {
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -126,54 +138,24 @@ var _ = Describe("Mutator", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(patchOps).ToNot(BeEmpty())
 
-				// Verify spec patch contains CPU features
-				foundSpecPatch := false
+				// Verify the patch only touches the mutated fields
 				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "spec patch value should be a map")
-
-						// Navigate to CPU features
-						template, ok := spec["template"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "template should exist in spec")
-						specMap, ok := template["spec"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "spec should exist in template")
-						domain, ok := specMap["domain"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "domain should exist in spec")
-						cpu, ok := domain["cpu"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "CPU should be present")
-						features, ok := cpu["features"].([]interface{})
-						Expect(ok).To(BeTrue(), "CPU features should be present")
-						Expect(features).ToNot(BeEmpty(), "CPU features should not be empty")
-
-						// Verify CPU feature is svm or vmx
-						cpuFeature, ok := features[0].(map[string]interface{})
-						Expect(ok).To(BeTrue())
-						name, ok := cpuFeature["name"].(string)
-						Expect(ok).To(BeTrue())
-						Expect(name).To(Or(Equal("svm"), Equal("vmx")))
-						policy, ok := cpuFeature["policy"].(string)
-						Expect(ok).To(BeTrue())
-						Expect(policy).To(Equal("require"))
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue(), "should have a spec patch operation")
-
-				// Verify annotations patch contains tracking annotation
-				foundAnnotationsPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/metadata/annotations" {
-						foundAnnotationsPatch = true
-						annotations, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "annotations patch value should be a map")
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
-						Expect(annotations[utils.AnnotationNestedVirtApplied]).To(Equal("true"))
-						break
-					}
+					Expect(op["path"]).ToNot(Equal("/spec"), "patch should not replace the whole spec")
+					Expect(op["path"]).ToNot(Equal("/metadata/annotations"), "patch should not replace all annotations")
 				}
-				Expect(foundAnnotationsPatch).To(BeTrue(), "should have an annotations patch operation")
+
+				patched := applyPatch(vmBytes, response.Patch)
+
+				// Verify patched spec contains CPU features
+				cpu := patched.Spec.Template.Spec.Domain.CPU
+				Expect(cpu).ToNot(BeNil(), "CPU should be present")
+				Expect(cpu.Features).ToNot(BeEmpty(), "CPU features should not be empty")
+				Expect(cpu.Features[0].Name).To(Or(Equal("svm"), Equal("vmx")))
+				Expect(cpu.Features[0].Policy).To(Equal("require"))
+
+				// Verify patched annotations contain tracking annotation
+				Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationNestedVirtApplied, "true"))
+				Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationNestedVirt, "enabled"))
 			})
 
 			It("should not add tracking annotations when disabled in config", func() {
@@ -211,7 +193,7 @@ var _ = Describe("Mutator", func() {
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -219,20 +201,50 @@ var _ = Describe("Mutator", func() {
 				Expect(response.Allowed).To(BeTrue())
 
 				// Verify patch does NOT contain tracking annotations
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
+				patched := applyPatch(vmBytes, response.Patch)
+				// Should only have the original nested-virt annotation, not the "applied" tracking one
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationNestedVirt))
+				Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationNestedVirtApplied))
+				Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationLastMutation))
+			})
+
+			It("should record the mutation in a machine-readable annotation", func() {
+				cfg.WebhookVersion = "v1.2.3"
+				vmBytes, err := json.Marshal(&kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "test-vm",
+						Namespace:   "default",
+						Annotations: map[string]string{utils.AnnotationNestedVirt: "enabled"},
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+					},
+				})
 				Expect(err).ToNot(HaveOccurred())
 
-				// Check that annotations patch either doesn't exist or doesn't contain tracking
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/metadata/annotations" {
-						annotations, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue())
-						// Should only have the original nested-virt annotation, not the "applied" tracking one
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirt))
-						Expect(annotations).ToNot(HaveKey(utils.AnnotationNestedVirtApplied))
-					}
-				}
+				mutator = mustNewMutator(nil, cfg, []features.Feature{features.NewNestedVirtualization(&config.NestedVirtConfig{
+					Enabled:       true,
+					AutoDetectCPU: true,
+				}, utils.ConfigSourceAnnotations)})
+
+				response, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object:    runtime.RawExtension{Raw: vmBytes},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationLastMutation))
+				var record lastMutation
+				Expect(json.Unmarshal([]byte(patched.Annotations[utils.AnnotationLastMutation]), &record)).To(Succeed())
+				Expect(record.WebhookVersion).To(Equal("v1.2.3"))
+				Expect(record.Features).To(ConsistOf(utils.FeatureNestedVirt))
+				Expect(record.Reverted).To(BeEmpty())
+				Expect(record.ConfigSource).To(Equal("annotations"))
+				timestamp, err := time.Parse(time.RFC3339, record.Timestamp)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(timestamp).To(BeTemporally("~", time.Now(), time.Minute))
 			})
 		})
 
@@ -271,8 +283,8 @@ var _ = Describe("Mutator", func() {
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				gpuFeature := features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature, gpuFeature})
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature, gpuFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -281,48 +293,19 @@ var _ = Describe("Mutator", func() {
 				Expect(response.Patch).ToNot(BeNil())
 
 				// Verify both features are applied in the patch
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
-				Expect(err).ToNot(HaveOccurred())
+				patched := applyPatch(vmBytes, response.Patch)
+				domain := patched.Spec.Template.Spec.Domain
 
-				// Verify spec patch contains both CPU features and GPU resource limits
-				foundSpecPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue())
-
-						// Check CPU features (nested virt)
-						template := spec["template"].(map[string]interface{})
-						specMap := template["spec"].(map[string]interface{})
-						domain := specMap["domain"].(map[string]interface{})
-						cpu := domain["cpu"].(map[string]interface{})
-						cpuFeatures := cpu["features"].([]interface{})
-						Expect(cpuFeatures).ToNot(BeEmpty(), "CPU features should be present")
-
-						// Check GPU resource limits
-						resources := domain["resources"].(map[string]interface{})
-						limits := resources["limits"].(map[string]interface{})
-						Expect(limits).To(HaveKey("nvidia.com/gpu"), "GPU resource limit should be present")
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue())
+				// Check CPU features (nested virt)
+				Expect(domain.CPU).ToNot(BeNil())
+				Expect(domain.CPU.Features).ToNot(BeEmpty(), "CPU features should be present")
+
+				// Check GPU resource limits
+				Expect(domain.Resources.Limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")), "GPU resource limit should be present")
 
 				// Verify tracking annotations for both features
-				foundAnnotationsPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/metadata/annotations" {
-						foundAnnotationsPatch = true
-						annotations, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue())
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
-						Expect(annotations).To(HaveKey(utils.AnnotationGpuDevicePluginApplied))
-						break
-					}
-				}
-				Expect(foundAnnotationsPatch).To(BeTrue())
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationGpuDevicePluginApplied))
 			})
 		})
 
@@ -356,8 +339,8 @@ var _ = Describe("Mutator", func() {
 					},
 				}
 
-				gpuFeature := features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{gpuFeature})
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{gpuFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -392,8 +375,8 @@ var _ = Describe("Mutator", func() {
 					},
 				}
 
-				vbiosFeature := features.NewVBiosInjection(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{vbiosFeature})
+				vbiosFeature := features.NewVBiosInjection(&config.VBiosConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{vbiosFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -417,7 +400,7 @@ var _ = Describe("Mutator", func() {
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -456,25 +439,24 @@ var _ = Describe("Mutator", func() {
 					},
 				}
 
-				vbiosFeature := features.NewVBiosInjection(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{vbiosFeature})
+				vbiosFeature := features.NewVBiosInjection(&config.VBiosConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{vbiosFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(response.Allowed).To(BeFalse())
 			})
-		})
 
-		Context("with ErrorHandlingAllowAndLog mode", func() {
-			It("should allow VM on feature error", func() {
-				cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
+			It("should list every failing feature in the rejection", func() {
+				cfg.ErrorHandlingMode = utils.ErrorHandlingReject
 
 				vm := &kubevirtv1.VirtualMachine{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "test-vm",
 						Namespace: "default",
 						Annotations: map[string]string{
-							utils.AnnotationVBiosInjection: "test-vbios",
+							utils.AnnotationGpuDevicePlugin: "invalid plugin",
+							utils.AnnotationVBiosInjection:  "test-vbios",
 						},
 					},
 					Spec: kubevirtv1.VirtualMachineSpec{
@@ -493,19 +475,23 @@ var _ = Describe("Mutator", func() {
 					},
 				}
 
-				vbiosFeature := features.NewVBiosInjection(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{vbiosFeature})
+				mutator = mustNewMutator(nil, cfg, []features.Feature{
+					features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations),
+					features.NewVBiosInjection(&config.VBiosConfig{Enabled: true}, utils.ConfigSourceAnnotations),
+				})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(response.Allowed).To(BeTrue())
-				Expect(response.Result.Message).To(ContainSubstring("allowed"))
+				Expect(response.Allowed).To(BeFalse())
+				Expect(response.Result.Message).To(ContainSubstring("2 features failed"))
+				Expect(response.Result.Message).To(ContainSubstring("invalid device plugin name"))
+				Expect(response.Result.Message).To(ContainSubstring("template is nil"))
 			})
 		})
 
-		Context("with ErrorHandlingStripLabel mode", func() {
-			It("should allow VM on feature error and strip the annotation", func() {
-				cfg.ErrorHandlingMode = utils.ErrorHandlingStripLabel
+		Context("with ErrorHandlingAllowAndLog mode", func() {
+			It("should allow VM on feature error", func() {
+				cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
 
 				vm := &kubevirtv1.VirtualMachine{
 					ObjectMeta: metav1.ObjectMeta{
@@ -513,7 +499,6 @@ var _ = Describe("Mutator", func() {
 						Namespace: "default",
 						Annotations: map[string]string{
 							utils.AnnotationVBiosInjection: "test-vbios",
-							"other-annotation":             "should-remain",
 						},
 					},
 					Spec: kubevirtv1.VirtualMachineSpec{
@@ -532,129 +517,33 @@ var _ = Describe("Mutator", func() {
 					},
 				}
 
-				vbiosFeature := features.NewVBiosInjection(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{vbiosFeature})
+				vbiosFeature := features.NewVBiosInjection(&config.VBiosConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{vbiosFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(response.Allowed).To(BeTrue())
-				Expect(response.Result.Message).To(ContainSubstring("annotation"))
-				Expect(response.Result.Message).To(ContainSubstring("stripped"))
-
-				// Verify the patch actually strips the annotation
-				Expect(response.Patch).ToNot(BeNil())
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
-				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Result.Message).To(ContainSubstring("allowed"))
 
-				// Find the annotations patch operation
-				foundAnnotationPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/metadata/annotations" {
-						foundAnnotationPatch = true
-						annotations, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "annotations patch value should be a map")
-						// The failing annotation should be stripped
-						Expect(annotations).ToNot(HaveKey(utils.AnnotationVBiosInjection))
-						// Other annotations should remain
-						Expect(annotations).To(HaveKey("other-annotation"))
-						break
-					}
-				}
-				Expect(foundAnnotationPatch).To(BeTrue(), "should have an annotations patch operation")
+				// The failure reason is recorded while the request annotation is kept
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationVBiosInjection, "test-vbios"))
+				Expect(patched.Annotations[utils.AnnotationVBiosInjectionError]).To(ContainSubstring("template is nil"))
 			})
-		})
-	})
-
-	Describe("createPatch", func() {
-		It("should create valid JSON patch", func() {
-			original := &kubevirtv1.VirtualMachine{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "test-vm",
-					Namespace: "default",
-				},
-				Spec: kubevirtv1.VirtualMachineSpec{
-					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
-						Spec: kubevirtv1.VirtualMachineInstanceSpec{
-							Domain: kubevirtv1.DomainSpec{},
-						},
-					},
-				},
-			}
-
-			mutated := original.DeepCopy()
-			mutated.Annotations = map[string]string{
-				"test-key": "test-value",
-			}
-			mutated.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{
-				Features: []kubevirtv1.CPUFeature{
-					{Name: "svm", Policy: "require"},
-				},
-			}
-
-			mutator = NewMutator(nil, cfg, []features.Feature{})
-			patch, err := mutator.createPatch(original, mutated)
-
-			Expect(err).ToNot(HaveOccurred())
-			Expect(patch).ToNot(BeNil())
-
-			// Verify it's valid JSON
-			var patchOps []map[string]interface{}
-			err = json.Unmarshal(patch, &patchOps)
-			Expect(err).ToNot(HaveOccurred())
-			Expect(patchOps).ToNot(BeEmpty())
-		})
-	})
 
-	Describe("hasEnabledFeatures", func() {
-		It("should return true when features are enabled", func() {
-			vm := &kubevirtv1.VirtualMachine{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: map[string]string{
-						utils.AnnotationNestedVirt: "enabled",
-					},
-				},
-			}
-
-			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
-				Enabled:       true,
-				AutoDetectCPU: true,
-			}, utils.ConfigSourceAnnotations)
-			mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
-
-			Expect(mutator.hasEnabledFeatures(vm)).To(BeTrue())
-		})
-
-		It("should return false when no features are enabled", func() {
-			vm := &kubevirtv1.VirtualMachine{
-				ObjectMeta: metav1.ObjectMeta{},
-			}
-
-			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
-				Enabled:       true,
-				AutoDetectCPU: true,
-			}, utils.ConfigSourceAnnotations)
-			mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
-
-			Expect(mutator.hasEnabledFeatures(vm)).To(BeFalse())
-		})
-	})
-
-	Describe("Edge Cases and Additional Coverage", func() {
-		Context("with unknown error handling mode", func() {
-			It("should use default error handling", func() {
-				cfg.ErrorHandlingMode = "unknown-mode"
+			It("should truncate long error messages", func() {
+				cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
 
 				vm := &kubevirtv1.VirtualMachine{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "test-vm",
 						Namespace: "default",
 						Annotations: map[string]string{
-							utils.AnnotationVBiosInjection: "test-vbios",
+							utils.AnnotationGpuDevicePlugin: strings.Repeat("x", 400) + " invalid",
 						},
 					},
 					Spec: kubevirtv1.VirtualMachineSpec{
-						Template: nil,
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
 					},
 				}
 
@@ -669,31 +558,35 @@ var _ = Describe("Mutator", func() {
 					},
 				}
 
-				vbiosFeature := features.NewVBiosInjection(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{vbiosFeature})
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{gpuFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(response.Allowed).To(BeFalse())
+				Expect(response.Allowed).To(BeTrue())
+
+				patched := applyPatch(vmBytes, response.Patch)
+				errMsg := patched.Annotations[utils.AnnotationGpuDevicePluginError]
+				Expect(errMsg).To(HaveLen(256))
+				Expect(errMsg).To(HaveSuffix("..."))
 			})
 		})
 
-		Context("with UPDATE operation", func() {
-			It("should process update requests same as create", func() {
+		Context("with ErrorHandlingStripLabel mode", func() {
+			It("should allow VM on feature error and strip the annotation", func() {
+				cfg.ErrorHandlingMode = utils.ErrorHandlingStripLabel
+
 				vm := &kubevirtv1.VirtualMachine{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "test-vm",
 						Namespace: "default",
 						Annotations: map[string]string{
-							utils.AnnotationNestedVirt: "enabled",
+							utils.AnnotationVBiosInjection: "test-vbios",
+							"other-annotation":             "should-remain",
 						},
 					},
 					Spec: kubevirtv1.VirtualMachineSpec{
-						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
-							Spec: kubevirtv1.VirtualMachineInstanceSpec{
-								Domain: kubevirtv1.DomainSpec{},
-							},
-						},
+						Template: nil,
 					},
 				}
 
@@ -701,110 +594,133 @@ var _ = Describe("Mutator", func() {
 				Expect(err).ToNot(HaveOccurred())
 
 				req := &admissionv1.AdmissionRequest{
-					UID:       "test-uid-update",
-					Operation: admissionv1.Update,
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
 					Object: runtime.RawExtension{
 						Raw: vmBytes,
 					},
 				}
 
-				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
-					Enabled:       true,
-					AutoDetectCPU: true,
-				}, utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+				vbiosFeature := features.NewVBiosInjection(&config.VBiosConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{vbiosFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(response).ToNot(BeNil())
 				Expect(response.Allowed).To(BeTrue())
-				Expect(response.Patch).ToNot(BeNil())
+				Expect(response.Result.Message).To(ContainSubstring("annotation"))
+				Expect(response.Result.Message).To(ContainSubstring("stripped"))
 
-				// Verify the patch is applied correctly for updates
+				// Verify the patch actually strips the annotation
+				Expect(response.Patch).ToNot(BeNil())
+				patched := applyPatch(vmBytes, response.Patch)
+				// The failing annotation should be stripped
+				Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationVBiosInjection))
+				// Other annotations should remain
+				Expect(patched.Annotations).To(HaveKey("other-annotation"))
+				// The failure reason should be recorded
+				Expect(patched.Annotations[utils.AnnotationVBiosInjectionError]).To(ContainSubstring("template is nil"))
+
+				// Stripping should be a targeted remove operation
 				var patchOps []map[string]interface{}
 				err = json.Unmarshal(response.Patch, &patchOps)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(patchOps).ToNot(BeEmpty())
+				Expect(patchOps).To(ContainElement(And(
+					HaveKeyWithValue("op", "remove"),
+					HaveKeyWithValue("path", "/metadata/annotations/vm-feature-manager.io~1vbios-injection"),
+				)))
 			})
 
-			It("should handle adding feature annotation during update", func() {
-				// Original VM without features
-				oldVM := &kubevirtv1.VirtualMachine{
+			It("should strip the label when labels are the config source", func() {
+				cfg.ErrorHandlingMode = utils.ErrorHandlingStripLabel
+				cfg.ConfigSource = utils.ConfigSourceLabels
+
+				vm := &kubevirtv1.VirtualMachine{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "test-vm",
 						Namespace: "default",
+						Labels: map[string]string{
+							utils.AnnotationVBiosInjection: "test-vbios",
+							"other-label":                  "should-remain",
+						},
 					},
 					Spec: kubevirtv1.VirtualMachineSpec{
-						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
-							Spec: kubevirtv1.VirtualMachineInstanceSpec{
-								Domain: kubevirtv1.DomainSpec{},
-							},
-						},
+						Template: nil,
 					},
 				}
 
-				// Updated VM with new feature annotation
-				newVM := oldVM.DeepCopy()
-				newVM.Annotations = map[string]string{
-					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
-				}
-
-				vmBytes, err := json.Marshal(newVM)
+				vmBytes, err := json.Marshal(vm)
 				Expect(err).ToNot(HaveOccurred())
 
 				req := &admissionv1.AdmissionRequest{
-					UID:       "test-uid-update-add",
-					Operation: admissionv1.Update,
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
 					Object: runtime.RawExtension{
 						Raw: vmBytes,
 					},
 				}
 
-				gpuFeature := features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{gpuFeature})
+				vbiosFeature := features.NewVBiosInjection(&config.VBiosConfig{Enabled: true}, utils.ConfigSourceLabels)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{vbiosFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(response).ToNot(BeNil())
 				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Result.Message).To(ContainSubstring("label"))
+
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Labels).ToNot(HaveKey(utils.AnnotationVBiosInjection))
+				Expect(patched.Labels).To(HaveKey("other-label"))
 
-				// Verify GPU resource was added
 				var patchOps []map[string]interface{}
 				err = json.Unmarshal(response.Patch, &patchOps)
 				Expect(err).ToNot(HaveOccurred())
+				Expect(patchOps).To(ContainElement(And(
+					HaveKeyWithValue("op", "remove"),
+					HaveKeyWithValue("path", "/metadata/labels/vm-feature-manager.io~1vbios-injection"),
+				)))
+			})
 
-				foundSpecPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec := op["value"].(map[string]interface{})
-						template := spec["template"].(map[string]interface{})
-						specMap := template["spec"].(map[string]interface{})
-						domain := specMap["domain"].(map[string]interface{})
-						resources := domain["resources"].(map[string]interface{})
-						limits := resources["limits"].(map[string]interface{})
-						Expect(limits).To(HaveKey("nvidia.com/gpu"))
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue())
+			It("should strip every key of a feature read from several keys", func() {
+				mutator = mustNewMutator(nil, cfg, nil)
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							utils.AnnotationMaxSockets:     "4",
+							utils.AnnotationMaxGuestMemory: "16Gi",
+							"other-annotation":             "should-remain",
+						},
+					},
+				}
+
+				mutator.stripFeatureKey(vm, utils.FeatureHotplug)
+				Expect(vm.Annotations).To(Equal(map[string]string{"other-annotation": "should-remain"}))
 			})
-		})
-	})
 
-	Describe("Label-based Configuration", func() {
-		Context("with labels as config source", func() {
-			BeforeEach(func() {
-				cfg.ConfigSource = utils.ConfigSourceLabels
+			It("should strip both storage performance keys", func() {
+				mutator = mustNewMutator(nil, cfg, nil)
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							utils.AnnotationIOThreads:       "auto",
+							utils.AnnotationBlockMultiQueue: "enabled",
+						},
+					},
+				}
+
+				mutator.stripFeatureKey(vm, utils.FeatureStoragePerformance)
+				Expect(vm.Annotations).To(BeEmpty())
 			})
+		})
 
-			It("should apply nested virt feature from label", func() {
+		Context("when reinvoked on an already mutated object", func() {
+			It("should return no patch", func() {
 				vm := &kubevirtv1.VirtualMachine{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "test-vm",
 						Namespace: "default",
-						Labels: map[string]string{
-							utils.AnnotationNestedVirt: "enabled",
+						Annotations: map[string]string{
+							utils.AnnotationNestedVirt:     "enabled",
+							utils.AnnotationPciPassthrough: `{"devices": ["0000:01:00.0"]}`,
 						},
 					},
 					Spec: kubevirtv1.VirtualMachineSpec{
@@ -815,54 +731,1578 @@ var _ = Describe("Mutator", func() {
 						},
 					},
 				}
-
 				vmBytes, err := json.Marshal(vm)
 				Expect(err).ToNot(HaveOccurred())
 
-				req := &admissionv1.AdmissionRequest{
+				mutator = mustNewMutator(nil, cfg, []features.Feature{
+					features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true, AutoDetectCPU: true}, utils.ConfigSourceAnnotations),
+					features.NewPciPassthrough(&config.PCIPassthroughConfig{Enabled: true, MaxDevices: 4}, utils.ConfigSourceAnnotations),
+				})
+
+				first, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
 					UID:       "test-uid",
 					Operation: admissionv1.Create,
-					Object: runtime.RawExtension{
-						Raw: vmBytes,
-					},
-				}
-
-				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
-					Enabled:       true,
-					AutoDetectCPU: true,
-				}, utils.ConfigSourceLabels)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
-
-				response, err := mutator.Handle(ctx, req)
+					Object:    runtime.RawExtension{Raw: vmBytes},
+				})
 				Expect(err).ToNot(HaveOccurred())
-				Expect(response).ToNot(BeNil())
-				Expect(response.Allowed).To(BeTrue())
-				Expect(response.Patch).ToNot(BeNil())
+				Expect(first.Patch).ToNot(BeNil())
 
-				// Verify the patch contains CPU features
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
+				mutatedBytes := patchRaw(vmBytes, first.Patch)
+				second, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object:    runtime.RawExtension{Raw: mutatedBytes},
+				})
 				Expect(err).ToNot(HaveOccurred())
-				Expect(patchOps).ToNot(BeEmpty())
+				Expect(second.Allowed).To(BeTrue())
+				Expect(second.Patch).To(BeNil())
+				Expect(second.PatchType).To(BeNil())
+				Expect(second.Warnings).To(BeEmpty())
+			})
+		})
 
-				foundSpecPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec := op["value"].(map[string]interface{})
-						template := spec["template"].(map[string]interface{})
-						specMap := template["spec"].(map[string]interface{})
-						domain := specMap["domain"].(map[string]interface{})
-						cpu := domain["cpu"].(map[string]interface{})
-						cpuFeatures := cpu["features"].([]interface{})
-						Expect(cpuFeatures).ToNot(BeEmpty())
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue())
+		Context("when a feature request is removed on UPDATE", func() {
+			var nestedVirtFeature features.Feature
+
+			BeforeEach(func() {
+				nestedVirtFeature = features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
 			})
 
-			It("should not apply feature when annotation is set but labels are used", func() {
+			buildVM := func(annotations map[string]string) *kubevirtv1.VirtualMachine {
+				return &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "test-vm",
+						Namespace:   "default",
+						Annotations: annotations,
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{
+									CPU: &kubevirtv1.CPU{
+										Features: []kubevirtv1.CPUFeature{{Name: utils.CPUFeatureVMX, Policy: "require"}},
+									},
+								},
+							},
+						},
+					},
+				}
+			}
+
+			update := func(oldVM, newVM *kubevirtv1.VirtualMachine) (*admissionv1.AdmissionResponse, []byte) {
+				oldBytes, err := json.Marshal(oldVM)
+				Expect(err).ToNot(HaveOccurred())
+				newBytes, err := json.Marshal(newVM)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Update,
+					Object:    runtime.RawExtension{Raw: newBytes},
+					OldObject: runtime.RawExtension{Raw: oldBytes},
+				}
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				return response, newBytes
+			}
+
+			It("should revert the previously applied mutation", func() {
+				oldVM := buildVM(map[string]string{
+					utils.AnnotationNestedVirt:        "enabled",
+					utils.AnnotationNestedVirtApplied: "true",
+				})
+				newVM := buildVM(map[string]string{
+					utils.AnnotationNestedVirtApplied: "true",
+				})
+
+				response, newBytes := update(oldVM, newVM)
+				Expect(response.Allowed).To(BeTrue())
+
+				patched := applyPatch(newBytes, response.Patch)
+				Expect(patched.Spec.Template.Spec.Domain.CPU.Features).To(BeEmpty())
+				Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationNestedVirtApplied))
+				Expect(patched.Annotations[utils.AnnotationLastMutation]).To(ContainSubstring(`"reverted":["nested-virt"]`))
+			})
+
+			It("should leave the spec alone when the feature was never applied", func() {
+				oldVM := buildVM(map[string]string{
+					utils.AnnotationNestedVirt: "enabled",
+				})
+				newVM := buildVM(nil)
+
+				response, _ := update(oldVM, newVM)
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Patch).To(BeNil())
+			})
+
+			It("should not revert on CREATE", func() {
+				vm := buildVM(map[string]string{
+					utils.AnnotationNestedVirtApplied: "true",
+				})
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				response, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object:    runtime.RawExtension{Raw: vmBytes},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Patch).To(BeNil())
+			})
+		})
+
+		Context("with non-fatal issues", func() {
+			It("should warn when a requested device is already present", func() {
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+						Annotations: map[string]string{
+							utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+						},
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{
+									Resources: kubevirtv1.ResourceRequirements{
+										Limits: corev1.ResourceList{
+											"nvidia.com/gpu": resource.MustParse("1"),
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{gpuFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Warnings).To(ConsistOf(And(
+					ContainSubstring(utils.FeatureGpuDevicePlugin),
+					ContainSubstring("already set"),
+				)))
+			})
+
+			It("should warn when userdata cannot be read", func() {
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{
+									{
+										Name: "cloudinit",
+										VolumeSource: kubevirtv1.VolumeSource{
+											CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+												UserDataSecretRef: &corev1.LocalObjectReference{
+													Name: "missing-secret",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				scheme := runtime.NewScheme()
+				Expect(corev1.AddToScheme(scheme)).To(Succeed())
+				fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(fakeClient, cfg, []features.Feature{nestedVirtFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Warnings).To(ConsistOf(ContainSubstring("missing-secret")))
+			})
+
+			It("should not read userdata when userdata directives are disabled", func() {
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{
+									{
+										Name: "cloudinit",
+										VolumeSource: kubevirtv1.VolumeSource{
+											CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+												UserData: "#cloud-config\nx_kubevirt_features:\n  nested_virt: enabled\n",
+											},
+										},
+									},
+									{
+										Name: "cloudinit-secret",
+										VolumeSource: kubevirtv1.VolumeSource{
+											CloudInitConfigDrive: &kubevirtv1.CloudInitConfigDriveSource{
+												UserDataSecretRef: &corev1.LocalObjectReference{
+													Name: "missing-secret",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				scheme := runtime.NewScheme()
+				Expect(corev1.AddToScheme(scheme)).To(Succeed())
+				fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+				cfg.UserdataDirectives = false
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(fakeClient, cfg, []features.Feature{nestedVirtFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Warnings).To(BeEmpty())
+				Expect(response.Patch).To(BeNil())
+			})
+
+			It("should strip directive comments from inline userdata when configured", func() {
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{
+									{
+										Name: "cloudinit",
+										VolumeSource: kubevirtv1.VolumeSource{
+											CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+												UserData: "#cloud-config\n# @kubevirt-feature nested_virt=enabled\nusers: []\n",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				cfg.StripDirectiveComments = true
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Allowed).To(BeTrue())
+
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationNestedVirt, "enabled"))
+				Expect(patched.Spec.Template.Spec.Volumes[0].CloudInitNoCloud.UserData).To(Equal("#cloud-config\nusers: []\n"))
+			})
+
+			It("should warn about malformed and unknown userdata directives", func() {
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{
+									{
+										Name: "cloudinit",
+										VolumeSource: kubevirtv1.VolumeSource{
+											CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+												UserData: "#cloud-config\nx_kubevirt_features:\n  nested_virt: enabled\n  pci_passthrough: [\"0000:01:00.0\"]\n  tmp: enabled\n",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Warnings).To(ConsistOf(
+					ContainSubstring("userdata volume cloudinit: x_kubevirt_features.pci_passthrough ignored"),
+					ContainSubstring("userdata volume cloudinit: x_kubevirt_features.tmp is not a known feature"),
+				))
+
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationNestedVirt, "enabled"))
+				Expect(patched.Annotations).NotTo(HaveKey(utils.AnnotationPciPassthrough))
+			})
+
+			It("should skip excluded VMs without reading their userdata", func() {
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+						Annotations: map[string]string{
+							utils.AnnotationExclude:    "true",
+							utils.AnnotationNestedVirt: "enabled",
+						},
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Volumes: []kubevirtv1.Volume{
+									{
+										Name: "cloudinit",
+										VolumeSource: kubevirtv1.VolumeSource{
+											CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+												UserDataSecretRef: &corev1.LocalObjectReference{
+													Name: "missing-secret",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				scheme := runtime.NewScheme()
+				Expect(corev1.AddToScheme(scheme)).To(Succeed())
+				fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(fakeClient, cfg, []features.Feature{nestedVirtFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Patch).To(BeNil())
+				Expect(response.Warnings).To(BeEmpty())
+				Expect(response.Result.Message).To(ContainSubstring(utils.AnnotationExclude))
+			})
+		})
+
+		Context("with ErrorHandlingContinue mode", func() {
+			var (
+				vmBytes []byte
+				req     *admissionv1.AdmissionRequest
+			)
+
+			BeforeEach(func() {
+				cfg.ErrorHandlingMode = utils.ErrorHandlingContinue
+
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+						Annotations: map[string]string{
+							utils.AnnotationGpuDevicePlugin: "invalid plugin",
+							utils.AnnotationNestedVirt:      "enabled",
+						},
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{},
+							},
+						},
+					},
+				}
+
+				var err error
+				vmBytes, err = json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req = &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+					Enabled:       true,
+					AutoDetectCPU: true,
+				}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{gpuFeature, nestedVirtFeature})
+			})
+
+			It("should apply the remaining features after a failure", func() {
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Patch).ToNot(BeNil())
+
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Spec.Template.Spec.Domain.CPU).ToNot(BeNil())
+				Expect(patched.Spec.Template.Spec.Domain.CPU.Features).ToNot(BeEmpty())
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
+			})
+
+			It("should strip the failing feature and record its error", func() {
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationGpuDevicePlugin))
+				Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginError, ContainSubstring("invalid device plugin name")))
+				Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(BeEmpty())
+			})
+
+			It("should report per-feature outcomes", func() {
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Result.Message).To(ContainSubstring(utils.FeatureGpuDevicePlugin + "=failed"))
+				Expect(response.Result.Message).To(ContainSubstring(utils.FeatureNestedVirt + "=applied"))
+				Expect(response.Warnings).To(HaveLen(1))
+				Expect(response.Warnings[0]).To(ContainSubstring(utils.FeatureGpuDevicePlugin))
+			})
+
+			It("should record the outcomes as events on the VM", func() {
+				recorder := record.NewFakeRecorder(10)
+				mutator.SetEventRecorder(recorder)
+
+				_, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).To(HaveLen(2))
+				Expect(<-recorder.Events).To(And(HavePrefix("Warning "+utils.EventReasonFeatureStripped), ContainSubstring(utils.FeatureGpuDevicePlugin)))
+				Expect(<-recorder.Events).To(Equal("Normal " + utils.EventReasonFeaturesApplied + " Applied features: " + utils.FeatureNestedVirt))
+			})
+
+			It("should not record events for dry-run requests", func() {
+				recorder := record.NewFakeRecorder(10)
+				mutator.SetEventRecorder(recorder)
+				dryRun := true
+				req.DryRun = &dryRun
+
+				_, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).To(BeEmpty())
+			})
+		})
+
+		Context("with per-feature error handling overrides", func() {
+			handle := func(annotations map[string]string) (*admissionv1.AdmissionResponse, []byte) {
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "test-vm",
+						Namespace:   "default",
+						Annotations: annotations,
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{},
+							},
+						},
+					},
+				}
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+					Enabled:       true,
+					AutoDetectCPU: true,
+				}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{gpuFeature, nestedVirtFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				return response, vmBytes
+			}
+
+			It("should skip a best-effort feature with allow and keep its annotation", func() {
+				response, vmBytes := handle(map[string]string{
+					utils.AnnotationGpuDevicePlugin:                    "invalid plugin",
+					"vm-feature-manager.io/gpu-device-plugin-on-error": "allow",
+					utils.AnnotationNestedVirt:                         "enabled",
+				})
+				Expect(response.Allowed).To(BeTrue())
+
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationGpuDevicePlugin))
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationGpuDevicePluginError))
+				Expect(response.Warnings).To(HaveLen(1))
+			})
+
+			It("should strip a best-effort feature with strip", func() {
+				response, vmBytes := handle(map[string]string{
+					utils.AnnotationGpuDevicePlugin:                    "invalid plugin",
+					"vm-feature-manager.io/gpu-device-plugin-on-error": "strip",
+					utils.AnnotationNestedVirt:                         "enabled",
+				})
+				Expect(response.Allowed).To(BeTrue())
+
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
+				Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationGpuDevicePlugin))
+			})
+
+			It("should reject a must-have feature even in continue mode", func() {
+				cfg.ErrorHandlingMode = utils.ErrorHandlingContinue
+				response, _ := handle(map[string]string{
+					utils.AnnotationGpuDevicePlugin:                    "invalid plugin",
+					"vm-feature-manager.io/gpu-device-plugin-on-error": "reject",
+				})
+				Expect(response.Allowed).To(BeFalse())
+				Expect(response.Result.Message).To(ContainSubstring("invalid device plugin name"))
+			})
+
+			It("should fall back to the global mode for invalid overrides", func() {
+				response, _ := handle(map[string]string{
+					utils.AnnotationGpuDevicePlugin:                    "invalid plugin",
+					"vm-feature-manager.io/gpu-device-plugin-on-error": "sometimes",
+				})
+				Expect(response.Allowed).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("createPatch", func() {
+		It("should create valid JSON patch", func() {
+			original := &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-vm",
+					Namespace: "default",
+				},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+						Spec: kubevirtv1.VirtualMachineInstanceSpec{
+							Domain: kubevirtv1.DomainSpec{},
+						},
+					},
+				},
+			}
+
+			mutated := original.DeepCopy()
+			mutated.Annotations = map[string]string{
+				"test-key": "test-value",
+			}
+			mutated.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{
+				Features: []kubevirtv1.CPUFeature{
+					{Name: "svm", Policy: "require"},
+				},
+			}
+
+			mutator = mustNewMutator(nil, cfg, []features.Feature{})
+			patch, err := mutator.createPatch(nil, original, mutated)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patch).ToNot(BeNil())
+
+			// Verify it's valid JSON
+			var patchOps []map[string]interface{}
+			err = json.Unmarshal(patch, &patchOps)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patchOps).ToNot(BeEmpty())
+		})
+
+		It("should only emit operations for changed fields", func() {
+			original := &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-vm",
+					Namespace: "default",
+					Annotations: map[string]string{
+						"existing-key": "existing-value",
+					},
+				},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+						Spec: kubevirtv1.VirtualMachineInstanceSpec{
+							Domain: kubevirtv1.DomainSpec{},
+						},
+					},
+				},
+			}
+
+			mutated := original.DeepCopy()
+			mutated.Annotations["test-key"] = "test-value"
+
+			mutator = mustNewMutator(nil, cfg, []features.Feature{})
+			patch, err := mutator.createPatch(nil, original, mutated)
+			Expect(err).ToNot(HaveOccurred())
+
+			var patchOps []map[string]interface{}
+			err = json.Unmarshal(patch, &patchOps)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patchOps).To(HaveLen(1))
+			Expect(patchOps[0]).To(HaveKeyWithValue("op", "add"))
+			Expect(patchOps[0]).To(HaveKeyWithValue("path", "/metadata/annotations/test-key"))
+			Expect(patchOps[0]).To(HaveKeyWithValue("value", "test-value"))
+		})
+
+		It("should produce a patch that applies to the raw object and preserves unknown fields", func() {
+			raw := []byte(`{
+				"apiVersion": "kubevirt.io/v1",
+				"kind": "VirtualMachine",
+				"metadata": {"name": "test-vm", "namespace": "default"},
+				"spec": {
+					"futureField": "keep-me",
+					"template": {"spec": {"domain": {"devices": {}}}}
+				}
+			}`)
+
+			original := &kubevirtv1.VirtualMachine{}
+			Expect(json.Unmarshal(raw, original)).To(Succeed())
+
+			mutated := original.DeepCopy()
+			mutated.Spec.Template.ObjectMeta.Annotations = map[string]string{
+				utils.HookAnnotationKey: "[]",
+			}
+
+			mutator = mustNewMutator(nil, cfg, []features.Feature{})
+			patch, err := mutator.createPatch(raw, original, mutated)
+			Expect(err).ToNot(HaveOccurred())
+
+			decoded, err := jsonpatch.DecodePatch(patch)
+			Expect(err).ToNot(HaveOccurred())
+			patchedBytes, err := decoded.Apply(raw)
+			Expect(err).ToNot(HaveOccurred())
+
+			var patchedMap map[string]interface{}
+			Expect(json.Unmarshal(patchedBytes, &patchedMap)).To(Succeed())
+			Expect(patchedMap["spec"]).To(HaveKeyWithValue("futureField", "keep-me"))
+
+			patched := &kubevirtv1.VirtualMachine{}
+			Expect(json.Unmarshal(patchedBytes, patched)).To(Succeed())
+			Expect(patched.Spec.Template.ObjectMeta.Annotations).To(HaveKeyWithValue(utils.HookAnnotationKey, "[]"))
+		})
+	})
+
+	Describe("hasEnabledFeatures", func() {
+		It("should return true when features are enabled", func() {
+			vm := &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						utils.AnnotationNestedVirt: "enabled",
+					},
+				},
+			}
+
+			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+				Enabled:       true,
+				AutoDetectCPU: true,
+			}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+
+			Expect(mutator.hasEnabledFeatures([]features.Feature{nestedVirtFeature}, vm)).To(BeTrue())
+		})
+
+		It("should return false when no features are enabled", func() {
+			vm := &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{},
+			}
+
+			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+				Enabled:       true,
+				AutoDetectCPU: true,
+			}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+
+			Expect(mutator.hasEnabledFeatures([]features.Feature{nestedVirtFeature}, vm)).To(BeFalse())
+		})
+	})
+
+	Describe("Reload", func() {
+		BeforeEach(func() {
+			nestedVirtFeature := features.NewNestedVirtualization(&cfg.Features.NestedVirtualization, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+		})
+
+		It("should switch to the new config and features", func() {
+			reloaded := &config.Config{ErrorHandlingMode: utils.ErrorHandlingContinue, ConfigSource: utils.ConfigSourceAnnotations}
+			nestedVirtFeature := features.NewNestedVirtualization(&reloaded.Features.NestedVirtualization, utils.ConfigSourceAnnotations)
+
+			Expect(mutator.Reload(reloaded, []features.Feature{nestedVirtFeature})).To(Succeed())
+			Expect(mutator.config).To(BeIdenticalTo(reloaded))
+			Expect(mutator.registry.Enabled()).To(HaveExactElements(BeIdenticalTo(nestedVirtFeature)))
+		})
+
+		It("should keep the current config when features can't be replaced", func() {
+			reloaded := &config.Config{ErrorHandlingMode: utils.ErrorHandlingContinue}
+
+			Expect(mutator.Reload(reloaded, []features.Feature{features.NewTpm(utils.ConfigSourceAnnotations)})).ToNot(Succeed())
+			Expect(mutator.config).To(BeIdenticalTo(cfg))
+		})
+	})
+
+	Describe("Edge Cases and Additional Coverage", func() {
+		Context("with unknown error handling mode", func() {
+			It("should use default error handling", func() {
+				cfg.ErrorHandlingMode = "unknown-mode"
+
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+						Annotations: map[string]string{
+							utils.AnnotationVBiosInjection: "test-vbios",
+						},
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: nil,
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				vbiosFeature := features.NewVBiosInjection(&config.VBiosConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{vbiosFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Allowed).To(BeFalse())
+			})
+		})
+
+		Context("with UPDATE operation", func() {
+			It("should process update requests same as create", func() {
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+						Annotations: map[string]string{
+							utils.AnnotationNestedVirt: "enabled",
+						},
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{},
+							},
+						},
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid-update",
+					Operation: admissionv1.Update,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+					Enabled:       true,
+					AutoDetectCPU: true,
+				}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).ToNot(BeNil())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Patch).ToNot(BeNil())
+
+				// Verify the patch is applied correctly for updates
+				var patchOps []map[string]interface{}
+				err = json.Unmarshal(response.Patch, &patchOps)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(patchOps).ToNot(BeEmpty())
+			})
+
+			It("should handle adding feature annotation during update", func() {
+				// Original VM without features
+				oldVM := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{},
+							},
+						},
+					},
+				}
+
+				// Updated VM with new feature annotation
+				newVM := oldVM.DeepCopy()
+				newVM.Annotations = map[string]string{
+					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+				}
+
+				vmBytes, err := json.Marshal(newVM)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid-update-add",
+					Operation: admissionv1.Update,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{gpuFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).ToNot(BeNil())
+				Expect(response.Allowed).To(BeTrue())
+
+				// Verify GPU resource was added
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+			})
+		})
+	})
+
+	Describe("Dry Run", func() {
+		var req *admissionv1.AdmissionRequest
+
+		BeforeEach(func() {
+			vm := &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-vm",
+					Namespace: "default",
+					Annotations: map[string]string{
+						utils.AnnotationNestedVirt: "enabled",
+					},
+				},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+						Spec: kubevirtv1.VirtualMachineInstanceSpec{
+							Domain: kubevirtv1.DomainSpec{},
+						},
+					},
+				},
+			}
+
+			vmBytes, err := json.Marshal(vm)
+			Expect(err).ToNot(HaveOccurred())
+
+			dryRun := true
+			req = &admissionv1.AdmissionRequest{
+				UID:       "test-uid-dry-run",
+				Operation: admissionv1.Create,
+				DryRun:    &dryRun,
+				Object: runtime.RawExtension{
+					Raw: vmBytes,
+				},
+			}
+
+			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+				Enabled:       true,
+				AutoDetectCPU: true,
+			}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+		})
+
+		It("should still return the patch for dry-run requests", func() {
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).ToNot(BeNil())
+			Expect(response.PatchType).ToNot(BeNil())
+
+			patched := applyPatch(req.Object.Raw, response.Patch)
+			Expect(patched.Spec.Template.Spec.Domain.CPU).ToNot(BeNil())
+			Expect(patched.Spec.Template.Spec.Domain.CPU.Features).ToNot(BeEmpty())
+		})
+
+		It("should return no patch for dry-run requests in strict mode", func() {
+			cfg.DryRunStrict = true
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+			Expect(response.PatchType).To(BeNil())
+		})
+
+		It("should return the patch for regular requests in strict mode", func() {
+			cfg.DryRunStrict = true
+			dryRun := false
+			req.DryRun = &dryRun
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).ToNot(BeNil())
+		})
+	})
+
+	Describe("Timeouts", func() {
+		var req *admissionv1.AdmissionRequest
+
+		BeforeEach(func() {
+			vm := &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: "default"},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+				},
+			}
+			vmBytes, err := json.Marshal(vm)
+			Expect(err).ToNot(HaveOccurred())
+
+			req = &admissionv1.AdmissionRequest{
+				UID:       "test-uid-timeout",
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: vmBytes},
+			}
+		})
+
+		It("should allow the VM unmutated with a warning when the request deadline passes", func() {
+			release := make(chan struct{})
+			DeferCleanup(func() { close(release) })
+			cfg.Timeouts = config.TimeoutsConfig{RequestSeconds: 1, OnTimeout: utils.TimeoutPolicyAllow}
+			mutator = mustNewMutator(nil, cfg, []features.Feature{&slowFeature{release: release}})
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+			Expect(response.Warnings).To(ConsistOf("admission timed out after 1s, features not applied"))
+		})
+
+		It("should reject the VM when the request deadline passes in reject mode", func() {
+			release := make(chan struct{})
+			DeferCleanup(func() { close(release) })
+			cfg.Timeouts = config.TimeoutsConfig{RequestSeconds: 1, OnTimeout: utils.TimeoutPolicyReject}
+			mutator = mustNewMutator(nil, cfg, []features.Feature{&slowFeature{release: release}})
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Message).To(Equal("admission timed out after 1s"))
+		})
+
+		It("should fail a feature that exceeds its own deadline", func() {
+			cfg.Timeouts = config.TimeoutsConfig{FeatureSeconds: 1}
+			mutator = mustNewMutator(nil, cfg, []features.Feature{&slowFeature{}})
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Message).To(ContainSubstring("timed out: context deadline exceeded"))
+		})
+	})
+
+	Describe("Shadow mode", func() {
+		var req *admissionv1.AdmissionRequest
+
+		BeforeEach(func() {
+			cfg.Mode = utils.WebhookModeShadow
+			vmBytes, err := json.Marshal(&kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-vm",
+					Namespace:   "default",
+					Annotations: map[string]string{utils.AnnotationNestedVirt: "enabled"},
+				},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			req = &admissionv1.AdmissionRequest{
+				UID:       "test-uid-shadow",
+				Kind:      metav1.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: "VirtualMachine"},
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: vmBytes},
+			}
+		})
+
+		It("should compute the patch but not return it", func() {
+			recorder := record.NewFakeRecorder(10)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{features.NewNestedVirtualization(&config.NestedVirtConfig{
+				Enabled:       true,
+				AutoDetectCPU: true,
+			}, utils.ConfigSourceAnnotations)})
+			mutator.SetEventRecorder(recorder)
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+			Expect(response.PatchType).To(BeNil())
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		It("should allow a VM enforcement would reject, with a warning", func() {
+			cfg.Timeouts = config.TimeoutsConfig{FeatureSeconds: 1}
+			mutator = mustNewMutator(nil, cfg, []features.Feature{&slowFeature{}})
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+			Expect(response.Warnings).To(ContainElement(HavePrefix("shadow mode: would be rejected: ")))
+		})
+
+		It("should not return a patch to reconcile", func() {
+			mutator = mustNewMutator(nil, cfg, []features.Feature{features.NewNestedVirtualization(&config.NestedVirtConfig{
+				Enabled:       true,
+				AutoDetectCPU: true,
+			}, utils.ConfigSourceAnnotations)})
+
+			response, err := mutator.Mutate(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+		})
+	})
+
+	Describe("Concurrency limit", func() {
+		var (
+			req     *admissionv1.AdmissionRequest
+			release chan struct{}
+		)
+
+		BeforeEach(func() {
+			vmBytes, err := json.Marshal(&kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: "default"},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			req = &admissionv1.AdmissionRequest{
+				UID:       "test-uid-concurrency",
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: vmBytes},
+			}
+			release = make(chan struct{})
+		})
+
+		// occupy starts a request that holds the only slot until release is closed
+		occupy := func() {
+			go func() {
+				defer GinkgoRecover()
+				_, _ = mutator.Handle(ctx, req)
+			}()
+			Eventually(func() int { return len(mutator.limiter.slots) }).Should(Equal(1))
+		}
+
+		It("should allow the VM unmutated with a warning when saturated", func() {
+			cfg.Concurrency = config.ConcurrencyConfig{MaxInFlight: 1, OnSaturated: utils.SaturationPolicyAllow}
+			mutator = mustNewMutator(nil, cfg, []features.Feature{&slowFeature{release: release}})
+			occupy()
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+			Expect(response.Warnings).To(ConsistOf("too many admission requests in flight (limit 1), features not applied"))
+
+			close(release)
+			Eventually(func() int { return len(mutator.limiter.slots) }).Should(BeZero())
+		})
+
+		It("should reject the VM with 429 when saturated in reject mode", func() {
+			DeferCleanup(func() { close(release) })
+			cfg.Concurrency = config.ConcurrencyConfig{MaxInFlight: 1, OnSaturated: utils.SaturationPolicyReject}
+			mutator = mustNewMutator(nil, cfg, []features.Feature{&slowFeature{release: release}})
+			occupy()
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Code).To(Equal(int32(http.StatusTooManyRequests)))
+		})
+
+		It("should keep the slot of a timed out request until it finishes", func() {
+			DeferCleanup(func() { close(release) })
+			cfg.Timeouts = config.TimeoutsConfig{RequestSeconds: 1, OnTimeout: utils.TimeoutPolicyAllow}
+			cfg.Concurrency = config.ConcurrencyConfig{MaxInFlight: 1, OnSaturated: utils.SaturationPolicyAllow}
+			mutator = mustNewMutator(nil, cfg, []features.Feature{&slowFeature{release: release}})
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Warnings).To(ConsistOf("admission timed out after 1s, features not applied"))
+			Expect(mutator.limiter.slots).To(HaveLen(1))
+		})
+
+		It("should wait for a slot to free up", func() {
+			cfg.Concurrency = config.ConcurrencyConfig{MaxInFlight: 1, WaitMilliseconds: 5000, OnSaturated: utils.SaturationPolicyReject}
+			mutator = mustNewMutator(nil, cfg, []features.Feature{&slowFeature{release: release}})
+			occupy()
+
+			time.AfterFunc(100*time.Millisecond, func() { close(release) })
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Warnings).To(BeEmpty())
+		})
+
+		It("should not limit reconciles", func() {
+			cfg.Concurrency = config.ConcurrencyConfig{MaxInFlight: 1, OnSaturated: utils.SaturationPolicyReject}
+			mutator = mustNewMutator(nil, cfg, []features.Feature{&slowFeature{release: release}})
+			occupy()
+
+			time.AfterFunc(100*time.Millisecond, func() { close(release) })
+			response, err := mutator.Mutate(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Warnings).To(BeEmpty())
+		})
+	})
+
+	Describe("VirtualMachineInstance Mutation", func() {
+		vmiKind := metav1.GroupVersionKind{
+			Group:   "kubevirt.io",
+			Version: "v1",
+			Kind:    "VirtualMachineInstance",
+		}
+
+		It("should apply nested virt to a standalone VMI", func() {
+			vmi := &kubevirtv1.VirtualMachineInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-vmi",
+					Namespace: "default",
+					Annotations: map[string]string{
+						utils.AnnotationNestedVirt: "enabled",
+					},
+				},
+				Spec: kubevirtv1.VirtualMachineInstanceSpec{
+					Domain: kubevirtv1.DomainSpec{},
+				},
+			}
+
+			vmiBytes, err := json.Marshal(vmi)
+			Expect(err).ToNot(HaveOccurred())
+
+			req := &admissionv1.AdmissionRequest{
+				UID:       "test-uid-vmi",
+				Kind:      vmiKind,
+				Operation: admissionv1.Create,
+				Object: runtime.RawExtension{
+					Raw: vmiBytes,
+				},
+			}
+
+			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+				Enabled:       true,
+				AutoDetectCPU: true,
+			}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).ToNot(BeNil())
+
+			patched := applyPatchVMI(vmiBytes, response.Patch)
+			Expect(patched.Spec.Domain.CPU).ToNot(BeNil())
+			Expect(patched.Spec.Domain.CPU.Features).ToNot(BeEmpty())
+			Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationNestedVirtApplied, "true"))
+
+			// Patch paths must target the VMI spec, not a VM template
+			var patchOps []map[string]interface{}
+			Expect(json.Unmarshal(response.Patch, &patchOps)).To(Succeed())
+			for _, op := range patchOps {
+				Expect(op["path"]).ToNot(HavePrefix("/spec/template"))
+			}
+		})
+
+		It("should not mutate VMIs created from a VirtualMachine", func() {
+			vmi := &kubevirtv1.VirtualMachineInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-vm",
+					Namespace: "default",
+					Annotations: map[string]string{
+						utils.AnnotationNestedVirt: "enabled",
+					},
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "kubevirt.io/v1",
+						Kind:       KindVirtualMachine,
+						Name:       "test-vm",
+					}},
+				},
+			}
+			vmiBytes, err := json.Marshal(vmi)
+			Expect(err).ToNot(HaveOccurred())
+
+			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+
+			response, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+				UID:       "test-uid-owned-vmi",
+				Kind:      vmiKind,
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: vmiBytes},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+		})
+
+		It("should not mutate VMI updates", func() {
+			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+
+			response, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+				UID:       "test-uid-vmi-update",
+				Kind:      vmiKind,
+				Operation: admissionv1.Update,
+				Object:    runtime.RawExtension{Raw: []byte(`{"metadata":{"annotations":{"vm-feature-manager.io/nested-virt":"enabled"}}}`)},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+		})
+
+		It("should place the vBIOS hook sidecar annotation on the VMI", func() {
+			vmi := &kubevirtv1.VirtualMachineInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-vmi",
+					Namespace: "default",
+					Annotations: map[string]string{
+						utils.AnnotationVBiosInjection: "test-vbios",
+					},
+				},
+				Spec: kubevirtv1.VirtualMachineInstanceSpec{
+					Domain: kubevirtv1.DomainSpec{},
+				},
+			}
+
+			vmiBytes, err := json.Marshal(vmi)
+			Expect(err).ToNot(HaveOccurred())
+
+			req := &admissionv1.AdmissionRequest{
+				UID:       "test-uid-vmi-vbios",
+				Kind:      vmiKind,
+				Operation: admissionv1.Create,
+				Object: runtime.RawExtension{
+					Raw: vmiBytes,
+				},
+			}
+
+			vbiosFeature := features.NewVBiosInjection(&config.VBiosConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{vbiosFeature})
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+
+			patched := applyPatchVMI(vmiBytes, response.Patch)
+			Expect(patched.Annotations).To(HaveKey(utils.HookAnnotationKey))
+			Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationVBiosInjection, "test-vbios"))
+			Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationVBiosInjectionApplied, "test-vbios"))
+			Expect(patched.Spec.Volumes).To(ContainElement(HaveField("Name", "vbios-rom")))
+		})
+	})
+
+	Describe("VirtualMachinePool Mutation", func() {
+		It("should apply features from pool metadata to the VM template", func() {
+			pool := &poolv1alpha1.VirtualMachinePool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pool",
+					Namespace: "default",
+					Annotations: map[string]string{
+						utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+					},
+				},
+				Spec: poolv1alpha1.VirtualMachinePoolSpec{
+					VirtualMachineTemplate: &poolv1alpha1.VirtualMachineTemplateSpec{
+						Spec: kubevirtv1.VirtualMachineSpec{
+							Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+								Spec: kubevirtv1.VirtualMachineInstanceSpec{
+									Domain: kubevirtv1.DomainSpec{},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			poolBytes, err := json.Marshal(pool)
+			Expect(err).ToNot(HaveOccurred())
+
+			req := &admissionv1.AdmissionRequest{
+				UID: "test-uid-pool",
+				Kind: metav1.GroupVersionKind{
+					Group:   "pool.kubevirt.io",
+					Version: "v1alpha1",
+					Kind:    "VirtualMachinePool",
+				},
+				Operation: admissionv1.Create,
+				Object: runtime.RawExtension{
+					Raw: poolBytes,
+				},
+			}
+
+			gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{gpuFeature})
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+
+			patched := &poolv1alpha1.VirtualMachinePool{}
+			Expect(json.Unmarshal(patchRaw(poolBytes, response.Patch), patched)).To(Succeed())
+			limits := patched.Spec.VirtualMachineTemplate.Spec.Template.Spec.Domain.Resources.Limits
+			Expect(limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+			Expect(patched.Annotations).To(HaveKey(utils.AnnotationGpuDevicePluginApplied))
+		})
+	})
+
+	Describe("VirtualMachineInstanceReplicaSet Mutation", func() {
+		It("should apply features from replica set metadata to the VMI template", func() {
+			rs := &kubevirtv1.VirtualMachineInstanceReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-rs",
+					Namespace: "default",
+					Annotations: map[string]string{
+						utils.AnnotationNestedVirt: "enabled",
+					},
+				},
+				Spec: kubevirtv1.VirtualMachineInstanceReplicaSetSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+						Spec: kubevirtv1.VirtualMachineInstanceSpec{
+							Domain: kubevirtv1.DomainSpec{},
+						},
+					},
+				},
+			}
+
+			rsBytes, err := json.Marshal(rs)
+			Expect(err).ToNot(HaveOccurred())
+
+			req := &admissionv1.AdmissionRequest{
+				UID: "test-uid-rs",
+				Kind: metav1.GroupVersionKind{
+					Group:   "kubevirt.io",
+					Version: "v1",
+					Kind:    "VirtualMachineInstanceReplicaSet",
+				},
+				Operation: admissionv1.Create,
+				Object: runtime.RawExtension{
+					Raw: rsBytes,
+				},
+			}
+
+			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+				Enabled:       true,
+				AutoDetectCPU: true,
+			}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+
+			patched := &kubevirtv1.VirtualMachineInstanceReplicaSet{}
+			Expect(json.Unmarshal(patchRaw(rsBytes, response.Patch), patched)).To(Succeed())
+			Expect(patched.Spec.Template.Spec.Domain.CPU).ToNot(BeNil())
+			Expect(patched.Spec.Template.Spec.Domain.CPU.Features).ToNot(BeEmpty())
+			Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationNestedVirtApplied, "true"))
+		})
+	})
+
+	Describe("Unsupported requests", func() {
+		BeforeEach(func() {
+			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+		})
+
+		It("should allow deletes without decoding the object", func() {
+			response, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+				UID:       "test-uid-delete",
+				Kind:      metav1.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: KindVirtualMachine},
+				Operation: admissionv1.Delete,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+			Expect(response.Warnings).To(BeEmpty())
+		})
+
+		It("should allow subresource requests untouched", func() {
+			response, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+				UID:         "test-uid-status",
+				Kind:        metav1.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: KindVirtualMachine},
+				Operation:   admissionv1.Update,
+				SubResource: "status",
+				Object:      runtime.RawExtension{Raw: []byte(`{"metadata":{"annotations":{"vm-feature-manager.io/nested-virt":"enabled"}}}`)},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+		})
+
+		It("should allow unsupported kinds with a warning", func() {
+			response, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+				UID:       "test-uid-pod",
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: []byte(`{"metadata":{"annotations":{"vm-feature-manager.io/nested-virt":"enabled"}}}`)},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+			Expect(response.Warnings).To(ConsistOf("unsupported kind Pod, features not applied"))
+		})
+	})
+
+	Describe("Label-based Configuration", func() {
+		Context("with labels as config source", func() {
+			BeforeEach(func() {
+				cfg.ConfigSource = utils.ConfigSourceLabels
+			})
+
+			It("should apply nested virt feature from label", func() {
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+						Labels: map[string]string{
+							utils.AnnotationNestedVirt: "enabled",
+						},
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{},
+							},
+						},
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+					Enabled:       true,
+					AutoDetectCPU: true,
+				}, utils.ConfigSourceLabels)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).ToNot(BeNil())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Patch).ToNot(BeNil())
+
+				// Verify the patch contains CPU features
+				patched := applyPatch(vmBytes, response.Patch)
+				cpu := patched.Spec.Template.Spec.Domain.CPU
+				Expect(cpu).ToNot(BeNil())
+				Expect(cpu.Features).ToNot(BeEmpty())
+			})
+
+			It("should not apply feature when annotation is set but labels are used", func() {
 				vm := &kubevirtv1.VirtualMachine{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "test-vm",
@@ -895,7 +2335,7 @@ var _ = Describe("Mutator", func() {
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceLabels)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -915,11 +2355,107 @@ var _ = Describe("Mutator", func() {
 						},
 					},
 					Spec: kubevirtv1.VirtualMachineSpec{
-						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
-							Spec: kubevirtv1.VirtualMachineInstanceSpec{
-								Domain: kubevirtv1.DomainSpec{},
-							},
-						},
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{},
+							},
+						},
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceLabels)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{gpuFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).ToNot(BeNil())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Patch).ToNot(BeNil())
+
+				// Verify GPU resource limit is added
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+			})
+
+			It("should apply multiple features from labels", func() {
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vm",
+						Namespace: "default",
+						Labels: map[string]string{
+							utils.AnnotationNestedVirt:      "enabled",
+							utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+						},
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{},
+							},
+						},
+					},
+				}
+
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
+
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+					Enabled:       true,
+					AutoDetectCPU: true,
+				}, utils.ConfigSourceLabels)
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceLabels)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature, gpuFeature})
+
+				response, err := mutator.Handle(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).ToNot(BeNil())
+				Expect(response.Allowed).To(BeTrue())
+				Expect(response.Patch).ToNot(BeNil())
+
+				// Verify both features are applied
+				patched := applyPatch(vmBytes, response.Patch)
+				domain := patched.Spec.Template.Spec.Domain
+
+				// Check CPU features
+				Expect(domain.CPU).ToNot(BeNil())
+				Expect(domain.CPU.Features).ToNot(BeEmpty())
+
+				// Check GPU resource limits
+				Expect(domain.Resources.Limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+			})
+		})
+
+		Context("with both labels and annotations as config source", func() {
+			handleBoth := func(source utils.ConfigSource, labels, annotations map[string]string) *kubevirtv1.VirtualMachine {
+				cfg.ConfigSource = source
+				vm := &kubevirtv1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "test-vm",
+						Namespace:   "default",
+						Labels:      labels,
+						Annotations: annotations,
+					},
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
 					},
 				}
 
@@ -934,108 +2470,246 @@ var _ = Describe("Mutator", func() {
 					},
 				}
 
-				gpuFeature := features.NewGpuDevicePlugin(utils.ConfigSourceLabels)
-				mutator = NewMutator(nil, cfg, []features.Feature{gpuFeature})
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, source)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{gpuFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(response).ToNot(BeNil())
 				Expect(response.Allowed).To(BeTrue())
 				Expect(response.Patch).ToNot(BeNil())
+				return applyPatch(vmBytes, response.Patch)
+			}
 
-				// Verify GPU resource limit is added
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
-				Expect(err).ToNot(HaveOccurred())
+			It("should apply features requested by labels", func() {
+				patched := handleBoth(utils.ConfigSourceBoth,
+					map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}, nil)
+				Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+			})
 
-				foundSpecPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec := op["value"].(map[string]interface{})
-						template := spec["template"].(map[string]interface{})
-						specMap := template["spec"].(map[string]interface{})
-						domain := specMap["domain"].(map[string]interface{})
-						resources := domain["resources"].(map[string]interface{})
-						limits := resources["limits"].(map[string]interface{})
-						Expect(limits).To(HaveKey("nvidia.com/gpu"))
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue())
+			It("should let annotations take precedence by default", func() {
+				patched := handleBoth(utils.ConfigSourceBoth,
+					map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"},
+					map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"})
+				Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "amd.com/gpu"))
 			})
 
-			It("should apply multiple features from labels", func() {
+			It("should let labels take precedence when configured", func() {
+				patched := handleBoth(utils.ConfigSourceBothLabelsFirst,
+					map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"},
+					map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"})
+				Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "nvidia.com/gpu"))
+			})
+		})
+	})
+
+	Describe("Custom key prefix", func() {
+		const prefix = "ourcompany.io/vm-"
+
+		// customKey returns key under the configured prefix
+		customKey := func(key string) string {
+			return prefix + strings.TrimPrefix(key, utils.DefaultKeyPrefix)
+		}
+
+		BeforeEach(func() {
+			cfg.KeyPrefix = prefix
+		})
+
+		It("should process keys of the configured prefix and ignore default ones", func() {
+			vm := &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-vm",
+					Namespace: "default",
+					Annotations: map[string]string{
+						"ourcompany.io/vm-gpu-device-plugin": "nvidia.com/gpu",
+						utils.AnnotationNestedVirt:           "enabled",
+					},
+				},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+				},
+			}
+
+			vmBytes, err := json.Marshal(vm)
+			Expect(err).ToNot(HaveOccurred())
+
+			req := &admissionv1.AdmissionRequest{
+				UID:       "test-uid",
+				Operation: admissionv1.Create,
+				Object: runtime.RawExtension{
+					Raw: vmBytes,
+				},
+			}
+
+			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+			gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+			mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature, gpuFeature})
+
+			response, err := mutator.Handle(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+
+			patched := applyPatch(vmBytes, response.Patch)
+			Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+			Expect(patched.Spec.Template.Spec.Domain.CPU).To(BeNil())
+			Expect(patched.Annotations).To(HaveKeyWithValue("ourcompany.io/vm-gpu-device-plugin-applied", "nvidia.com/gpu"))
+			Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationGpuDevicePluginApplied))
+			Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationNestedVirt, "enabled"))
+		})
+
+		It("should read the stored object's keys under the configured prefix", func() {
+			mutator = mustNewMutator(nil, cfg, []features.Feature{
+				features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations),
+			})
+			vm := &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-vm",
+					Namespace:   "default",
+					Annotations: map[string]string{customKey(utils.AnnotationNestedVirt): "enabled"},
+				},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+				},
+			}
+			vmBytes, err := json.Marshal(vm)
+			Expect(err).ToNot(HaveOccurred())
+
+			response, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+				UID:       "test-uid",
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: vmBytes},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			stored := applyPatch(vmBytes, response.Patch)
+			Expect(stored.Spec.Template.Spec.Domain.CPU.Features).ToNot(BeEmpty())
+			Expect(stored.Annotations).To(HaveKey(customKey(utils.AnnotationNestedVirtApplied)))
+
+			updated := stored.DeepCopy()
+			delete(updated.Annotations, customKey(utils.AnnotationNestedVirt))
+			storedBytes, err := json.Marshal(stored)
+			Expect(err).ToNot(HaveOccurred())
+			updatedBytes, err := json.Marshal(updated)
+			Expect(err).ToNot(HaveOccurred())
+
+			response, err = mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+				UID:       "test-uid",
+				Operation: admissionv1.Update,
+				Object:    runtime.RawExtension{Raw: updatedBytes},
+				OldObject: runtime.RawExtension{Raw: storedBytes},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+
+			patched := applyPatch(updatedBytes, response.Patch)
+			Expect(patched.Spec.Template.Spec.Domain.CPU.Features).To(BeEmpty())
+			Expect(patched.Annotations).ToNot(HaveKey(customKey(utils.AnnotationNestedVirtApplied)))
+			Expect(patched.Annotations[customKey(utils.AnnotationLastMutation)]).To(ContainSubstring(`"reverted":["nested-virt"]`))
+		})
+
+		Context("with namespace defaults", func() {
+			handleIn := func(namespace *corev1.Namespace, annotations map[string]string) (*admissionv1.AdmissionResponse, []byte) {
+				cfg.NamespaceDefaults = true
+				fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(namespace).Build()
+				mutator = mustNewMutator(fakeClient, cfg, []features.Feature{
+					features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations),
+					features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations),
+				})
+
 				vm := &kubevirtv1.VirtualMachine{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "test-vm",
-						Namespace: "default",
-						Labels: map[string]string{
-							utils.AnnotationNestedVirt:      "enabled",
-							utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
-						},
+						Name:        "test-vm",
+						Namespace:   namespace.Name,
+						Annotations: annotations,
 					},
 					Spec: kubevirtv1.VirtualMachineSpec{
-						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
-							Spec: kubevirtv1.VirtualMachineInstanceSpec{
-								Domain: kubevirtv1.DomainSpec{},
-							},
-						},
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
 					},
 				}
-
 				vmBytes, err := json.Marshal(vm)
 				Expect(err).ToNot(HaveOccurred())
 
-				req := &admissionv1.AdmissionRequest{
+				response, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
 					UID:       "test-uid",
 					Operation: admissionv1.Create,
-					Object: runtime.RawExtension{
-						Raw: vmBytes,
+					Namespace: namespace.Name,
+					Object:    runtime.RawExtension{Raw: vmBytes},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Allowed).To(BeTrue())
+				return response, vmBytes
+			}
+
+			It("should apply the namespace's keys of the configured prefix", func() {
+				response, vmBytes := handleIn(&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "ci-runners",
+						Annotations: map[string]string{
+							customKey(utils.AnnotationGpuDevicePlugin): "nvidia.com/gpu",
+							utils.AnnotationNestedVirt:                 "enabled",
+						},
 					},
-				}
+				}, nil)
 
-				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
-					Enabled:       true,
-					AutoDetectCPU: true,
-				}, utils.ConfigSourceLabels)
-				gpuFeature := features.NewGpuDevicePlugin(utils.ConfigSourceLabels)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature, gpuFeature})
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+				Expect(patched.Spec.Template.Spec.Domain.CPU).To(BeNil())
+			})
 
-				response, err := mutator.Handle(ctx, req)
-				Expect(err).ToNot(HaveOccurred())
-				Expect(response).ToNot(BeNil())
-				Expect(response.Allowed).To(BeTrue())
+			It("should honor the namespace's exclusion under the configured prefix only", func() {
+				request := map[string]string{customKey(utils.AnnotationGpuDevicePlugin): "nvidia.com/gpu"}
+
+				response, _ := handleIn(&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "ci-runners",
+						Annotations: map[string]string{customKey(utils.AnnotationExclude): "true"},
+					},
+				}, request)
+				Expect(response.Patch).To(BeNil())
+
+				response, _ = handleIn(&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "ci-runners",
+						Annotations: map[string]string{utils.AnnotationExclude: "true"},
+					},
+				}, request)
 				Expect(response.Patch).ToNot(BeNil())
+			})
+		})
 
-				// Verify both features are applied
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
-				Expect(err).ToNot(HaveOccurred())
+		It("should hand plugins their keys under the default prefix", func() {
+			hookPath := filepath.Join(GinkgoT().TempDir(), "team-label.sh")
+			Expect(os.WriteFile(hookPath, []byte(`#!/bin/sh
+cat > /dev/null
+printf '[{"op": "add", "path": "/spec/template/metadata/labels", "value": {"team": "%s"}}]' "$VM_FEATURE_VALUE"
+`), 0o755)).To(Succeed())
+			mutator = mustNewMutator(nil, cfg, []features.Feature{
+				plugins.NewExecFeature(hookPath, utils.ConfigSourceAnnotations, 2*time.Second, 1024),
+			})
 
-				foundSpecPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec := op["value"].(map[string]interface{})
-						template := spec["template"].(map[string]interface{})
-						specMap := template["spec"].(map[string]interface{})
-						domain := specMap["domain"].(map[string]interface{})
-
-						// Check CPU features
-						cpu := domain["cpu"].(map[string]interface{})
-						cpuFeatures := cpu["features"].([]interface{})
-						Expect(cpuFeatures).ToNot(BeEmpty())
-
-						// Check GPU resource limits
-						resources := domain["resources"].(map[string]interface{})
-						limits := resources["limits"].(map[string]interface{})
-						Expect(limits).To(HaveKey("nvidia.com/gpu"))
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue())
+			vm := &kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-vm",
+					Namespace:   "default",
+					Annotations: map[string]string{prefix + "team-label": "ml"},
+				},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+				},
+			}
+			vmBytes, err := json.Marshal(vm)
+			Expect(err).ToNot(HaveOccurred())
+
+			response, err := mutator.Handle(ctx, &admissionv1.AdmissionRequest{
+				UID:       "test-uid",
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: vmBytes},
 			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Allowed).To(BeTrue())
+
+			patched := applyPatch(vmBytes, response.Patch)
+			Expect(patched.Spec.Template.ObjectMeta.Labels).To(HaveKeyWithValue("team", "ml"))
+			Expect(patched.Annotations).To(HaveKeyWithValue(prefix+"team-label-applied", "ml"))
+			Expect(patched.Annotations).ToNot(HaveKey(utils.DefaultKeyPrefix + "team-label-applied"))
 		})
 	})
 
@@ -1086,7 +2760,7 @@ users:
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -1097,50 +2771,17 @@ users:
 				Expect(*response.PatchType).To(Equal(admissionv1.PatchTypeJSONPatch))
 
 				// Verify the patch contains CPU features from nested virt
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
-				Expect(err).ToNot(HaveOccurred())
+				patched := applyPatch(vmBytes, response.Patch)
 
-				// Verify spec patch contains CPU features
-				foundSpecPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "spec patch value should be a map")
-
-						template, ok := spec["template"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "template should exist in spec")
-						specMap, ok := template["spec"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "spec should exist in template")
-						domain, ok := specMap["domain"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "domain should exist in spec")
-						cpu, ok := domain["cpu"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "CPU should be present")
-						cpuFeatures, ok := cpu["features"].([]interface{})
-						Expect(ok).To(BeTrue(), "CPU features should be present")
-						Expect(cpuFeatures).ToNot(BeEmpty(), "CPU features should not be empty")
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue(), "should have a spec patch operation")
-
-				// Verify annotations patch contains both the merged userdata annotation and tracking annotation
-				foundAnnotationsPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/metadata/annotations" {
-						foundAnnotationsPatch = true
-						annotations, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "annotations patch value should be a map")
-						// Should have the userdata-derived annotation merged in
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirt))
-						Expect(annotations[utils.AnnotationNestedVirt]).To(Equal("enabled"))
-						// Should have tracking annotation
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
-						break
-					}
-				}
-				Expect(foundAnnotationsPatch).To(BeTrue(), "should have an annotations patch operation")
+				// Verify spec contains CPU features
+				cpu := patched.Spec.Template.Spec.Domain.CPU
+				Expect(cpu).ToNot(BeNil(), "CPU should be present")
+				Expect(cpu.Features).ToNot(BeEmpty(), "CPU features should not be empty")
+
+				// Should have the userdata-derived annotation merged in
+				Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationNestedVirt, "enabled"))
+				// Should have tracking annotation
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
 			})
 
 			It("should apply multiple features from userdata", func() {
@@ -1189,8 +2830,8 @@ users:
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				gpuFeature := features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature, gpuFeature})
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature, gpuFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -1199,22 +2840,16 @@ users:
 				Expect(response.Patch).ToNot(BeNil())
 
 				// Verify the patch contains both CPU features and GPU resource limits
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
-				Expect(err).ToNot(HaveOccurred())
-
-				// Check annotations patch has both merged annotations
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/metadata/annotations" {
-						annotations, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue())
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirt))
-						Expect(annotations).To(HaveKey(utils.AnnotationGpuDevicePlugin))
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
-						Expect(annotations).To(HaveKey(utils.AnnotationGpuDevicePluginApplied))
-						break
-					}
-				}
+				patched := applyPatch(vmBytes, response.Patch)
+				Expect(patched.Spec.Template.Spec.Domain.CPU).ToNot(BeNil())
+				Expect(patched.Spec.Template.Spec.Domain.CPU.Features).ToNot(BeEmpty())
+				Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+
+				// Check annotations have both merged annotations
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationNestedVirt))
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationGpuDevicePlugin))
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationGpuDevicePluginApplied))
 			})
 		})
 
@@ -1226,47 +2861,47 @@ users:
 						Name:      "test-vm",
 						Namespace: "default",
 						Annotations: map[string]string{
-						// Annotation specifies a different GPU than userdata
-						utils.AnnotationGpuDevicePlugin: "amd.com/gpu",
+							// Annotation specifies a different GPU than userdata
+							utils.AnnotationGpuDevicePlugin: "amd.com/gpu",
+						},
 					},
-				},
-				Spec: kubevirtv1.VirtualMachineSpec{
-					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
-						Spec: kubevirtv1.VirtualMachineInstanceSpec{
-							Domain: kubevirtv1.DomainSpec{},
-							Volumes: []kubevirtv1.Volume{
-								{
-									Name: "cloudinit",
-									VolumeSource: kubevirtv1.VolumeSource{
-										CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
-											UserData: `#cloud-config
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{},
+								Volumes: []kubevirtv1.Volume{
+									{
+										Name: "cloudinit",
+										VolumeSource: kubevirtv1.VolumeSource{
+											CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+												UserData: `#cloud-config
 x_kubevirt_features:
   gpu_device_plugin: nvidia.com/gpu
 users:
   - name: ubuntu
 `,
+											},
 										},
 									},
 								},
 							},
 						},
 					},
-				},
-			}
+				}
 
-			vmBytes, err := json.Marshal(vm)
-			Expect(err).ToNot(HaveOccurred())
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
 
-			req := &admissionv1.AdmissionRequest{
-				UID:       "test-uid",
-				Operation: admissionv1.Create,
-				Object: runtime.RawExtension{
-					Raw: vmBytes,
-				},
-			}
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
 
-			gpuFeature := features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations)
-			mutator = NewMutator(nil, cfg, []features.Feature{gpuFeature})
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{gpuFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -1275,27 +2910,11 @@ users:
 				Expect(response.Patch).ToNot(BeNil())
 
 				// Verify the patch uses the annotation value (amd.com/gpu), not userdata value (nvidia.com/gpu)
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
-				Expect(err).ToNot(HaveOccurred())
-
-				foundSpecPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec := op["value"].(map[string]interface{})
-						template := spec["template"].(map[string]interface{})
-						specMap := template["spec"].(map[string]interface{})
-						domain := specMap["domain"].(map[string]interface{})
-						resources := domain["resources"].(map[string]interface{})
-						limits := resources["limits"].(map[string]interface{})
-						// Annotation value should take precedence
-						Expect(limits).To(HaveKey("amd.com/gpu"), "annotation value should be used")
-						Expect(limits).ToNot(HaveKey("nvidia.com/gpu"), "userdata value should be overridden")
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue())
+				patched := applyPatch(vmBytes, response.Patch)
+				limits := patched.Spec.Template.Spec.Domain.Resources.Limits
+				// Annotation value should take precedence
+				Expect(limits).To(HaveKey(corev1.ResourceName("amd.com/gpu")), "annotation value should be used")
+				Expect(limits).ToNot(HaveKey(corev1.ResourceName("nvidia.com/gpu")), "userdata value should be overridden")
 			})
 
 			It("should merge non-conflicting features from both sources", func() {
@@ -1304,51 +2923,51 @@ users:
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "test-vm",
 						Namespace: "default",
-					Annotations: map[string]string{
-						utils.AnnotationNestedVirt: "enabled",
+						Annotations: map[string]string{
+							utils.AnnotationNestedVirt: "enabled",
+						},
 					},
-				},
-				Spec: kubevirtv1.VirtualMachineSpec{
-					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
-						Spec: kubevirtv1.VirtualMachineInstanceSpec{
-							Domain: kubevirtv1.DomainSpec{},
-							Volumes: []kubevirtv1.Volume{
-								{
-									Name: "cloudinit",
-									VolumeSource: kubevirtv1.VolumeSource{
-										CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
-											UserData: `#cloud-config
+					Spec: kubevirtv1.VirtualMachineSpec{
+						Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+							Spec: kubevirtv1.VirtualMachineInstanceSpec{
+								Domain: kubevirtv1.DomainSpec{},
+								Volumes: []kubevirtv1.Volume{
+									{
+										Name: "cloudinit",
+										VolumeSource: kubevirtv1.VolumeSource{
+											CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+												UserData: `#cloud-config
 x_kubevirt_features:
   gpu_device_plugin: nvidia.com/gpu
 users:
   - name: ubuntu
 `,
+											},
 										},
 									},
 								},
 							},
 						},
 					},
-				},
-			}
+				}
 
-			vmBytes, err := json.Marshal(vm)
-			Expect(err).ToNot(HaveOccurred())
+				vmBytes, err := json.Marshal(vm)
+				Expect(err).ToNot(HaveOccurred())
 
-			req := &admissionv1.AdmissionRequest{
-				UID:       "test-uid",
-				Operation: admissionv1.Create,
-				Object: runtime.RawExtension{
-					Raw: vmBytes,
-				},
-			}
+				req := &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: vmBytes,
+					},
+				}
 
-			nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
-				Enabled:       true,
+				nestedVirtFeature := features.NewNestedVirtualization(&config.NestedVirtConfig{
+					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				gpuFeature := features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations)
-				mutator = NewMutator(nil, cfg, []features.Feature{nestedVirtFeature, gpuFeature})
+				gpuFeature := features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+				mutator = mustNewMutator(nil, cfg, []features.Feature{nestedVirtFeature, gpuFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -1357,45 +2976,21 @@ users:
 				Expect(response.Patch).ToNot(BeNil())
 
 				// Verify both features were applied
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
-				Expect(err).ToNot(HaveOccurred())
+				patched := applyPatch(vmBytes, response.Patch)
+				domain := patched.Spec.Template.Spec.Domain
 
-				foundSpecPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec := op["value"].(map[string]interface{})
-						template := spec["template"].(map[string]interface{})
-						specMap := template["spec"].(map[string]interface{})
-						domain := specMap["domain"].(map[string]interface{})
-
-						// Check CPU features from nested virt (from annotation)
-						cpu := domain["cpu"].(map[string]interface{})
-						cpuFeatures := cpu["features"].([]interface{})
-						Expect(cpuFeatures).ToNot(BeEmpty())
-
-						// Check GPU resource limits (from userdata)
-						resources := domain["resources"].(map[string]interface{})
-						limits := resources["limits"].(map[string]interface{})
-						Expect(limits).To(HaveKey("nvidia.com/gpu"))
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue())
+				// Check CPU features from nested virt (from annotation)
+				Expect(domain.CPU).ToNot(BeNil())
+				Expect(domain.CPU.Features).ToNot(BeEmpty())
+
+				// Check GPU resource limits (from userdata)
+				Expect(domain.Resources.Limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
 
 				// Verify annotations contain both the original and merged annotations
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/metadata/annotations" {
-						annotations, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue())
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirt))
-						Expect(annotations).To(HaveKey(utils.AnnotationGpuDevicePlugin))
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
-						Expect(annotations).To(HaveKey(utils.AnnotationGpuDevicePluginApplied))
-						break
-					}
-				}
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationNestedVirt))
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationGpuDevicePlugin))
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
+				Expect(patched.Annotations).To(HaveKey(utils.AnnotationGpuDevicePluginApplied))
 			})
 		})
 
@@ -1453,7 +3048,7 @@ users:
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				mutator = NewMutator(fakeClient, cfg, []features.Feature{nestedVirtFeature})
+				mutator = mustNewMutator(fakeClient, cfg, []features.Feature{nestedVirtFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -1462,25 +3057,10 @@ users:
 				Expect(response.Patch).ToNot(BeNil())
 
 				// Verify the annotation-based feature was still applied
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
-				Expect(err).ToNot(HaveOccurred())
-
-				foundSpecPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec := op["value"].(map[string]interface{})
-						template := spec["template"].(map[string]interface{})
-						specMap := template["spec"].(map[string]interface{})
-						domain := specMap["domain"].(map[string]interface{})
-						cpu := domain["cpu"].(map[string]interface{})
-						cpuFeatures := cpu["features"].([]interface{})
-						Expect(cpuFeatures).ToNot(BeEmpty())
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue())
+				patched := applyPatch(vmBytes, response.Patch)
+				cpu := patched.Spec.Template.Spec.Domain.CPU
+				Expect(cpu).ToNot(BeNil())
+				Expect(cpu.Features).ToNot(BeEmpty())
 			})
 
 			It("should allow VM with no features when userdata parsing fails", func() {
@@ -1532,7 +3112,7 @@ users:
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				mutator = NewMutator(fakeClient, cfg, []features.Feature{nestedVirtFeature})
+				mutator = mustNewMutator(fakeClient, cfg, []features.Feature{nestedVirtFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -1605,7 +3185,7 @@ users:
 					Enabled:       true,
 					AutoDetectCPU: true,
 				}, utils.ConfigSourceAnnotations)
-				mutator = NewMutator(fakeClient, cfg, []features.Feature{nestedVirtFeature})
+				mutator = mustNewMutator(fakeClient, cfg, []features.Feature{nestedVirtFeature})
 
 				response, err := mutator.Handle(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
@@ -1614,26 +3194,58 @@ users:
 				Expect(response.Patch).ToNot(BeNil())
 
 				// Verify the feature from secret userdata was applied
-				var patchOps []map[string]interface{}
-				err = json.Unmarshal(response.Patch, &patchOps)
-				Expect(err).ToNot(HaveOccurred())
-
-				foundSpecPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/spec" {
-						foundSpecPatch = true
-						spec := op["value"].(map[string]interface{})
-						template := spec["template"].(map[string]interface{})
-						specMap := template["spec"].(map[string]interface{})
-						domain := specMap["domain"].(map[string]interface{})
-						cpu := domain["cpu"].(map[string]interface{})
-						cpuFeatures := cpu["features"].([]interface{})
-						Expect(cpuFeatures).ToNot(BeEmpty())
-						break
-					}
-				}
-				Expect(foundSpecPatch).To(BeTrue())
+				patched := applyPatch(vmBytes, response.Patch)
+				cpu := patched.Spec.Template.Spec.Domain.CPU
+				Expect(cpu).ToNot(BeNil())
+				Expect(cpu.Features).ToNot(BeEmpty())
 			})
 		})
 	})
 })
+
+// applyPatch applies a JSON patch to the raw VM and decodes the result
+func applyPatch(raw, patch []byte) *kubevirtv1.VirtualMachine {
+	vm := &kubevirtv1.VirtualMachine{}
+	Expect(json.Unmarshal(patchRaw(raw, patch), vm)).To(Succeed())
+	return vm
+}
+
+// applyPatchVMI applies a JSON patch to the raw VMI and decodes the result
+func applyPatchVMI(raw, patch []byte) *kubevirtv1.VirtualMachineInstance {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	Expect(json.Unmarshal(patchRaw(raw, patch), vmi)).To(Succeed())
+	return vmi
+}
+
+// patchRaw applies a JSON patch to a raw object
+func patchRaw(raw, patch []byte) []byte {
+	decoded, err := jsonpatch.DecodePatch(patch)
+	Expect(err).ToNot(HaveOccurred())
+
+	patchedBytes, err := decoded.Apply(raw)
+	Expect(err).ToNot(HaveOccurred())
+	return patchedBytes
+}
+
+// slowFeature is always enabled and, when applied, blocks until release is
+// closed or, without release, until its context is done
+type slowFeature struct {
+	release chan struct{}
+}
+
+func (f *slowFeature) Name() string { return "slow" }
+
+func (f *slowFeature) IsEnabled(*kubevirtv1.VirtualMachine) bool { return true }
+
+func (f *slowFeature) Validate(context.Context, *kubevirtv1.VirtualMachine, client.Client) error {
+	return nil
+}
+
+func (f *slowFeature) Apply(ctx context.Context, _ *kubevirtv1.VirtualMachine, _ client.Client) (*features.MutationResult, error) {
+	if f.release != nil {
+		<-f.release
+		return features.NewMutationResult(), nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
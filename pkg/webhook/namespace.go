@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// namespaceCacheTTL bounds how long a namespace's feature defaults are reused
+// before the Namespace is read again
+const namespaceCacheTTL = time.Minute
+
+// namespaceEntry holds the cached feature keys of one namespace
+type namespaceEntry struct {
+	features map[string]string
+	expires  time.Time
+}
+
+// namespaceCache caches the feature keys set on Namespace objects for
+// namespaceCacheTTL
+type namespaceCache struct {
+	mu      sync.Mutex
+	entries map[string]namespaceEntry
+}
+
+// get returns the feature keys of the namespace's config source, under the
+// default key prefix, reading the Namespace when the cached entry is missing
+// or expired. The Namespace is read without holding the lock, so a slow read
+// doesn't hold up requests for other namespaces.
+func (c *namespaceCache) get(ctx context.Context, k8sClient client.Client, configSource utils.ConfigSource, keyPrefix, name string) (map[string]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.features, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", name, err)
+	}
+
+	source := utils.GetConfigMap(configSource, namespace.Annotations, namespace.Labels)
+	utils.SwapKeyPrefix(source, keyPrefix)
+
+	featureKeys := make(map[string]string)
+	for key, value := range source {
+		if strings.HasPrefix(key, featureKeyPrefix) {
+			featureKeys[key] = value
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]namespaceEntry)
+	}
+	c.entries[name] = namespaceEntry{features: featureKeys, expires: time.Now().Add(namespaceCacheTTL)}
+	return featureKeys, nil
+}
+
+// applyNamespaceDefaults overlays the feature keys set on the VM's Namespace,
+// under keys the VM sets itself and its profile. A profile chosen by the
+// namespace applies under the namespace's other keys.
+func (m *Mutator) applyNamespaceDefaults(ctx context.Context, vm *kubevirtv1.VirtualMachine, namespace string, overlay *requestOverlay) error {
+	if !m.config.NamespaceDefaults || m.client == nil || namespace == "" {
+		return nil
+	}
+
+	defaults, err := m.namespaces.get(ctx, m.client, m.config.ConfigSource, m.config.KeyPrefix, namespace)
+	if err != nil {
+		return err
+	}
+
+	profileDefaulted := false
+	for key, value := range defaults {
+		if key == utils.AnnotationExclude {
+			continue
+		}
+		if _, set := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), key); set {
+			continue
+		}
+		overlay.set(vm, key, value)
+		profileDefaulted = profileDefaulted || key == utils.AnnotationProfile
+	}
+
+	if profileDefaulted {
+		return m.applyProfile(ctx, vm, overlay)
+	}
+	return nil
+}
+
+// excludedBy reports whether the VM or its Namespace opts out of feature
+// management with the exclude key, returning which one did. The Namespace is
+// checked whether or not namespace defaults are enabled.
+func (m *Mutator) excludedBy(ctx context.Context, vm *kubevirtv1.VirtualMachine, namespace string) (string, error) {
+	if value, exists := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationExclude); exists && utils.IsTruthyValue(value) {
+		return "VM", nil
+	}
+	if m.client == nil || namespace == "" {
+		return "", nil
+	}
+
+	keys, err := m.namespaces.get(ctx, m.client, m.config.ConfigSource, m.config.KeyPrefix, namespace)
+	if err != nil {
+		return "", err
+	}
+	if utils.IsTruthyValue(keys[utils.AnnotationExclude]) {
+		return "Namespace", nil
+	}
+	return "", nil
+}
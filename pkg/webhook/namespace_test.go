@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/apis/v1alpha1"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Namespace feature defaults", func() {
+	var (
+		cfg        *config.Config
+		vm         *kubevirtv1.VirtualMachine
+		namespace  *corev1.Namespace
+		fakeClient client.Client
+		mutator    *Mutator
+	)
+
+	BeforeEach(func() {
+		cfg = &config.Config{
+			ErrorHandlingMode:      utils.ErrorHandlingReject,
+			AddTrackingAnnotations: true,
+			ConfigSource:           utils.ConfigSourceAnnotations,
+			NamespaceDefaults:      true,
+		}
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "ci-runners",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+			},
+		}
+		namespace = &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "ci-runners",
+				Annotations: map[string]string{
+					utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
+					"unrelated.example.com/key":     "value",
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		nsScheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(nsScheme)).To(Succeed())
+		Expect(kubevirtv1.AddToScheme(nsScheme)).To(Succeed())
+		Expect(v1alpha1.AddToScheme(nsScheme)).To(Succeed())
+		fakeClient = fake.NewClientBuilder().WithScheme(nsScheme).WithObjects(namespace).Build()
+		mutator = mustNewMutator(fakeClient, cfg, []features.Feature{
+			features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, cfg.ConfigSource),
+		})
+	})
+
+	handle := func() (*admissionv1.AdmissionResponse, []byte) {
+		vmBytes, err := json.Marshal(vm)
+		Expect(err).ToNot(HaveOccurred())
+
+		response, err := mutator.Handle(context.Background(), &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: vmBytes},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return response, vmBytes
+	}
+
+	It("should apply the namespace's feature keys without persisting them", func() {
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(BeEquivalentTo("nvidia.com/gpu")))
+		Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationGpuDevicePlugin))
+		Expect(patched.Annotations).ToNot(HaveKey("unrelated.example.com/key"))
+	})
+
+	It("should let the VM's annotations take precedence", func() {
+		vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"}
+
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "amd.com/gpu"))
+	})
+
+	It("should cache the namespace's feature keys", func() {
+		response, _ := handle()
+		Expect(response.Patch).ToNot(BeNil())
+
+		Expect(fakeClient.Delete(context.Background(), namespace)).To(Succeed())
+
+		response, _ = handle()
+		Expect(response.Patch).ToNot(BeNil())
+	})
+
+	It("should not hold up other namespaces while reading one", func() {
+		unblock := make(chan struct{})
+		DeferCleanup(func() { close(unblock) })
+		blockingClient := interceptor.NewClient(fakeClient.(client.WithWatch), interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if key.Name == "slow" {
+					<-unblock
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		})
+
+		cache := &namespaceCache{}
+		go func() {
+			_, _ = cache.get(context.Background(), blockingClient, cfg.ConfigSource, "", "slow")
+		}()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = cache.get(context.Background(), blockingClient, cfg.ConfigSource, "", "ci-runners")
+		}()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("should apply a profile chosen by the namespace under its other keys", func() {
+		current := &corev1.Namespace{}
+		Expect(fakeClient.Get(context.Background(), client.ObjectKeyFromObject(namespace), current)).To(Succeed())
+		current.Annotations = map[string]string{utils.AnnotationProfile: "gpu"}
+		Expect(fakeClient.Update(context.Background(), current)).To(Succeed())
+
+		profile := &v1alpha1.FeatureProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "gpu"},
+			Spec: v1alpha1.FeatureProfileSpec{
+				Features: map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+			},
+		}
+		Expect(fakeClient.Create(context.Background(), profile)).To(Succeed())
+
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "nvidia.com/gpu"))
+		Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationProfile))
+	})
+
+	It("should skip VMs in excluded namespaces", func() {
+		current := &corev1.Namespace{}
+		Expect(fakeClient.Get(context.Background(), client.ObjectKeyFromObject(namespace), current)).To(Succeed())
+		current.Annotations[utils.AnnotationExclude] = "true"
+		Expect(fakeClient.Update(context.Background(), current)).To(Succeed())
+		vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"}
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Patch).To(BeNil())
+		Expect(response.Result.Message).To(ContainSubstring("Namespace opted out"))
+	})
+
+	It("should ignore namespaces when disabled", func() {
+		cfg.NamespaceDefaults = false
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Patch).To(BeNil())
+	})
+
+	Context("when namespace defaults are disabled", func() {
+		BeforeEach(func() {
+			cfg.NamespaceDefaults = false
+			namespace.Annotations[utils.AnnotationExclude] = "true"
+		})
+
+		It("should still skip VMs in excluded namespaces", func() {
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"}
+
+			response, _ := handle()
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+			Expect(response.Result.Message).To(ContainSubstring("Namespace opted out"))
+		})
+	})
+
+	Context("when the namespace can't be read", func() {
+		JustBeforeEach(func() {
+			failingClient := interceptor.NewClient(fakeClient.(client.WithWatch), interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					if _, ok := obj.(*corev1.Namespace); ok {
+						return errors.New("apiserver unavailable")
+					}
+					return c.Get(ctx, key, obj, opts...)
+				},
+			})
+			mutator = mustNewMutator(failingClient, cfg, []features.Feature{
+				features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, cfg.ConfigSource),
+			})
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"}
+		})
+
+		It("should reject the VM in reject mode", func() {
+			response, _ := handle()
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Message).To(ContainSubstring("apiserver unavailable"))
+		})
+
+		It("should warn and apply features in other modes", func() {
+			cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
+
+			response, vmBytes := handle()
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Warnings).To(ContainElement(ContainSubstring("namespace exclusion could not be checked")))
+			patched := applyPatch(vmBytes, response.Patch)
+			Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "amd.com/gpu"))
+		})
+	})
+})
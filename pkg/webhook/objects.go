@@ -0,0 +1,357 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	poolv1alpha1 "kubevirt.io/api/pool/v1alpha1"
+)
+
+const (
+	// KindVirtualMachine is the admission kind for VirtualMachine objects
+	KindVirtualMachine = "VirtualMachine"
+	// KindVirtualMachineInstance is the admission kind for VirtualMachineInstance objects
+	KindVirtualMachineInstance = "VirtualMachineInstance"
+	// KindVirtualMachinePool is the admission kind for VirtualMachinePool objects
+	KindVirtualMachinePool = "VirtualMachinePool"
+	// KindVirtualMachineInstanceReplicaSet is the admission kind for VirtualMachineInstanceReplicaSet objects
+	KindVirtualMachineInstanceReplicaSet = "VirtualMachineInstanceReplicaSet"
+)
+
+// supportedKinds maps each admitted kind to its API group and resource
+var supportedKinds = map[string]metav1.GroupResource{
+	KindVirtualMachine:                   {Group: kubevirtv1.SchemeGroupVersion.Group, Resource: "virtualmachines"},
+	KindVirtualMachineInstance:           {Group: kubevirtv1.SchemeGroupVersion.Group, Resource: "virtualmachineinstances"},
+	KindVirtualMachinePool:               {Group: poolv1alpha1.SchemeGroupVersion.Group, Resource: "virtualmachinepools"},
+	KindVirtualMachineInstanceReplicaSet: {Group: kubevirtv1.SchemeGroupVersion.Group, Resource: "virtualmachineinstancereplicasets"},
+}
+
+// checkKind verifies that the request's kind and resource are ones the
+// webhook mutates. Requests without a kind are treated as VirtualMachines.
+func checkKind(req *admissionv1.AdmissionRequest) error {
+	if req.Kind.Kind == "" {
+		return nil
+	}
+	expected, ok := supportedKinds[req.Kind.Kind]
+	if !ok || (req.Kind.Group != "" && req.Kind.Group != expected.Group) {
+		return fmt.Errorf("unsupported kind %s", kindString(req.Kind))
+	}
+	if req.Resource.Resource != "" && (req.Resource.Resource != expected.Resource || req.Resource.Group != expected.Group) {
+		return fmt.Errorf("unexpected resource %s.%s for kind %s", req.Resource.Resource, req.Resource.Group, kindString(req.Kind))
+	}
+	return nil
+}
+
+// kindString formats a kind as Kind.group, or just Kind without a group
+func kindString(gvk metav1.GroupVersionKind) string {
+	if gvk.Group == "" {
+		return gvk.Kind
+	}
+	return gvk.Kind + "." + gvk.Group
+}
+
+// admissionObject adapts an admitted KubeVirt object to the VirtualMachine view
+// that features operate on, and maps the mutated view back onto the object.
+type admissionObject interface {
+	// Kind returns the kind of the admitted object
+	Kind() string
+
+	// VirtualMachine returns a VirtualMachine view of the object.
+	// Feature configuration is read from its metadata and mutations are
+	// applied to its template.
+	VirtualMachine() *kubevirtv1.VirtualMachine
+
+	// Original returns the object as it was admitted
+	Original() interface{}
+
+	// Mutated returns a copy of the admitted object with the mutated view applied
+	Mutated(vm *kubevirtv1.VirtualMachine) interface{}
+}
+
+// decodeObject decodes the admission request object based on its kind.
+// Requests without a kind are treated as VirtualMachines.
+func decodeObject(req *admissionv1.AdmissionRequest) (admissionObject, error) {
+	switch req.Kind.Kind {
+	case KindVirtualMachineInstance:
+		vmi := &kubevirtv1.VirtualMachineInstance{}
+		if err := json.Unmarshal(req.Object.Raw, vmi); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal VirtualMachineInstance: %w", err)
+		}
+		return newVMIObject(vmi), nil
+	case KindVirtualMachinePool:
+		pool := &poolv1alpha1.VirtualMachinePool{}
+		if err := json.Unmarshal(req.Object.Raw, pool); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal VirtualMachinePool: %w", err)
+		}
+		return newPoolObject(pool), nil
+	case KindVirtualMachineInstanceReplicaSet:
+		rs := &kubevirtv1.VirtualMachineInstanceReplicaSet{}
+		if err := json.Unmarshal(req.Object.Raw, rs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal VirtualMachineInstanceReplicaSet: %w", err)
+		}
+		return newReplicaSetObject(rs), nil
+	case KindVirtualMachine, "":
+		vm := &kubevirtv1.VirtualMachine{}
+		if err := json.Unmarshal(req.Object.Raw, vm); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal VirtualMachine: %w", err)
+		}
+		return &vmObject{vm: vm}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kind %s", kindString(req.Kind))
+	}
+}
+
+// decodeOldObject decodes the stored object of an UPDATE request.
+// It returns nil when the request carries no old object.
+func decodeOldObject(req *admissionv1.AdmissionRequest) (admissionObject, error) {
+	if req.Operation != admissionv1.Update || len(req.OldObject.Raw) == 0 {
+		return nil, nil
+	}
+	oldReq := req.DeepCopy()
+	oldReq.Object = req.OldObject
+	return decodeObject(oldReq)
+}
+
+// templateOwner returns the kind and name of the VirtualMachine or replica
+// set a VMI was created from, whose template the webhook has already mutated.
+// It returns "" for other objects and standalone VMIs.
+func templateOwner(obj admissionObject) string {
+	vmi, ok := obj.Original().(*kubevirtv1.VirtualMachineInstance)
+	if !ok {
+		return ""
+	}
+	for _, ref := range vmi.OwnerReferences {
+		if !strings.HasPrefix(ref.APIVersion, kubevirtv1.SchemeGroupVersion.Group+"/") {
+			continue
+		}
+		if ref.Kind == KindVirtualMachine || ref.Kind == KindVirtualMachineInstanceReplicaSet {
+			return ref.Kind + "/" + ref.Name
+		}
+	}
+	return ""
+}
+
+// vmObject is a VirtualMachine, which is its own view
+type vmObject struct {
+	vm *kubevirtv1.VirtualMachine
+}
+
+// Kind returns the admitted kind
+func (o *vmObject) Kind() string {
+	return KindVirtualMachine
+}
+
+// VirtualMachine returns the view features operate on
+func (o *vmObject) VirtualMachine() *kubevirtv1.VirtualMachine {
+	return o.vm
+}
+
+// Original returns the admitted object
+func (o *vmObject) Original() interface{} {
+	return o.vm
+}
+
+// Mutated returns the admitted object with the mutated view applied
+func (o *vmObject) Mutated(vm *kubevirtv1.VirtualMachine) interface{} {
+	return vm
+}
+
+// vmiObject is a standalone VirtualMachineInstance. Its metadata serves both as
+// the VM metadata (feature configuration, tracking annotations) and as the
+// template metadata (e.g. hook sidecar annotations) of the view.
+type vmiObject struct {
+	vmi  *kubevirtv1.VirtualMachineInstance
+	view *kubevirtv1.VirtualMachine
+}
+
+// newVMIObject builds the VirtualMachine view of a VirtualMachineInstance
+func newVMIObject(vmi *kubevirtv1.VirtualMachineInstance) *vmiObject {
+	return &vmiObject{
+		vmi: vmi,
+		view: &kubevirtv1.VirtualMachine{
+			ObjectMeta: *vmi.ObjectMeta.DeepCopy(),
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels:      copyStringMap(vmi.Labels),
+						Annotations: copyStringMap(vmi.Annotations),
+					},
+					Spec: *vmi.Spec.DeepCopy(),
+				},
+			},
+		},
+	}
+}
+
+// Kind returns the admitted kind
+func (o *vmiObject) Kind() string {
+	return KindVirtualMachineInstance
+}
+
+// VirtualMachine returns the view features operate on
+func (o *vmiObject) VirtualMachine() *kubevirtv1.VirtualMachine {
+	return o.view
+}
+
+// Original returns the admitted object
+func (o *vmiObject) Original() interface{} {
+	return o.vmi
+}
+
+// Mutated returns the admitted object with the mutated view applied
+func (o *vmiObject) Mutated(vm *kubevirtv1.VirtualMachine) interface{} {
+	vmi := o.vmi.DeepCopy()
+	vmi.Annotations = copyStringMap(vm.Annotations)
+	vmi.Labels = copyStringMap(vm.Labels)
+
+	if vm.Spec.Template != nil {
+		vmi.Spec = *vm.Spec.Template.Spec.DeepCopy()
+
+		// Fold template metadata changes made by features into the VMI metadata
+		vmi.Annotations = applyMapDelta(vmi.Annotations, o.vmi.Annotations, vm.Spec.Template.ObjectMeta.Annotations)
+		vmi.Labels = applyMapDelta(vmi.Labels, o.vmi.Labels, vm.Spec.Template.ObjectMeta.Labels)
+	}
+
+	return vmi
+}
+
+// poolObject is a VirtualMachinePool. Feature configuration is read from the
+// pool metadata and applied to the VirtualMachine template of the pool.
+type poolObject struct {
+	pool *poolv1alpha1.VirtualMachinePool
+	view *kubevirtv1.VirtualMachine
+}
+
+// newPoolObject builds the VirtualMachine view of a VirtualMachinePool
+func newPoolObject(pool *poolv1alpha1.VirtualMachinePool) *poolObject {
+	view := &kubevirtv1.VirtualMachine{
+		ObjectMeta: *pool.ObjectMeta.DeepCopy(),
+	}
+	if pool.Spec.VirtualMachineTemplate != nil {
+		view.Spec = *pool.Spec.VirtualMachineTemplate.Spec.DeepCopy()
+	}
+
+	return &poolObject{
+		pool: pool,
+		view: view,
+	}
+}
+
+// Kind returns the admitted kind
+func (o *poolObject) Kind() string {
+	return KindVirtualMachinePool
+}
+
+// VirtualMachine returns the view features operate on
+func (o *poolObject) VirtualMachine() *kubevirtv1.VirtualMachine {
+	return o.view
+}
+
+// Original returns the admitted object
+func (o *poolObject) Original() interface{} {
+	return o.pool
+}
+
+// Mutated returns the admitted object with the mutated view applied
+func (o *poolObject) Mutated(vm *kubevirtv1.VirtualMachine) interface{} {
+	pool := o.pool.DeepCopy()
+	pool.Annotations = copyStringMap(vm.Annotations)
+	pool.Labels = copyStringMap(vm.Labels)
+
+	if pool.Spec.VirtualMachineTemplate == nil {
+		if vm.Spec.Template == nil {
+			return pool
+		}
+		pool.Spec.VirtualMachineTemplate = &poolv1alpha1.VirtualMachineTemplateSpec{}
+	}
+	pool.Spec.VirtualMachineTemplate.Spec = *vm.Spec.DeepCopy()
+
+	return pool
+}
+
+// replicaSetObject is a VirtualMachineInstanceReplicaSet. Feature configuration
+// is read from the replica set metadata and applied to its VMI template.
+type replicaSetObject struct {
+	rs   *kubevirtv1.VirtualMachineInstanceReplicaSet
+	view *kubevirtv1.VirtualMachine
+}
+
+// newReplicaSetObject builds the VirtualMachine view of a VirtualMachineInstanceReplicaSet
+func newReplicaSetObject(rs *kubevirtv1.VirtualMachineInstanceReplicaSet) *replicaSetObject {
+	view := &kubevirtv1.VirtualMachine{
+		ObjectMeta: *rs.ObjectMeta.DeepCopy(),
+	}
+	if rs.Spec.Template != nil {
+		view.Spec.Template = rs.Spec.Template.DeepCopy()
+	}
+
+	return &replicaSetObject{
+		rs:   rs,
+		view: view,
+	}
+}
+
+// Kind returns the admitted kind
+func (o *replicaSetObject) Kind() string {
+	return KindVirtualMachineInstanceReplicaSet
+}
+
+// VirtualMachine returns the view features operate on
+func (o *replicaSetObject) VirtualMachine() *kubevirtv1.VirtualMachine {
+	return o.view
+}
+
+// Original returns the admitted object
+func (o *replicaSetObject) Original() interface{} {
+	return o.rs
+}
+
+// Mutated returns the admitted object with the mutated view applied
+func (o *replicaSetObject) Mutated(vm *kubevirtv1.VirtualMachine) interface{} {
+	rs := o.rs.DeepCopy()
+	rs.Annotations = copyStringMap(vm.Annotations)
+	rs.Labels = copyStringMap(vm.Labels)
+
+	if vm.Spec.Template != nil {
+		rs.Spec.Template = vm.Spec.Template.DeepCopy()
+	}
+
+	return rs
+}
+
+// applyMapDelta applies the entries that differ between base and updated to dst
+func applyMapDelta(dst, base, updated map[string]string) map[string]string {
+	for k, v := range updated {
+		if original, exists := base[k]; exists && original == v {
+			continue
+		}
+		if dst == nil {
+			dst = make(map[string]string)
+		}
+		dst[k] = v
+	}
+
+	for k := range base {
+		if _, exists := updated[k]; !exists {
+			delete(dst, k)
+		}
+	}
+
+	return dst
+}
+
+// copyStringMap returns a shallow copy of a string map, preserving nil
+func copyStringMap(in map[string]string) map[string]string {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	poolv1alpha1 "kubevirt.io/api/pool/v1alpha1"
+)
+
+var _ = Describe("Admission Objects", func() {
+	Describe("decodeObject", func() {
+		It("should decode requests without a kind as VirtualMachines", func() {
+			vmBytes, err := json.Marshal(&kubevirtv1.VirtualMachine{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: "default"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			obj, err := decodeObject(&admissionv1.AdmissionRequest{
+				Object: runtime.RawExtension{Raw: vmBytes},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(obj.Kind()).To(Equal(KindVirtualMachine))
+			Expect(obj.VirtualMachine().Name).To(Equal("test-vm"))
+		})
+
+		It("should decode VirtualMachineInstances into a VM view", func() {
+			vmiBytes, err := json.Marshal(&kubevirtv1.VirtualMachineInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-vmi",
+					Namespace:   "default",
+					Annotations: map[string]string{"key": "value"},
+				},
+				Spec: kubevirtv1.VirtualMachineInstanceSpec{
+					Domain: kubevirtv1.DomainSpec{
+						CPU: &kubevirtv1.CPU{Cores: 2},
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			obj, err := decodeObject(&admissionv1.AdmissionRequest{
+				Kind:   metav1.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: KindVirtualMachineInstance},
+				Object: runtime.RawExtension{Raw: vmiBytes},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(obj.Kind()).To(Equal(KindVirtualMachineInstance))
+
+			vm := obj.VirtualMachine()
+			Expect(vm.Name).To(Equal("test-vmi"))
+			Expect(vm.Annotations).To(HaveKeyWithValue("key", "value"))
+			Expect(vm.Spec.Template).ToNot(BeNil())
+			Expect(vm.Spec.Template.ObjectMeta.Annotations).To(HaveKeyWithValue("key", "value"))
+			Expect(vm.Spec.Template.Spec.Domain.CPU.Cores).To(Equal(uint32(2)))
+		})
+
+		It("should return an error for invalid objects", func() {
+			_, err := decodeObject(&admissionv1.AdmissionRequest{
+				Kind:   metav1.GroupVersionKind{Kind: KindVirtualMachineInstance},
+				Object: runtime.RawExtension{Raw: []byte("invalid json")},
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("checkKind", func() {
+		It("should accept supported kinds and their resources", func() {
+			Expect(checkKind(&admissionv1.AdmissionRequest{
+				Kind:     metav1.GroupVersionKind{Group: "pool.kubevirt.io", Version: "v1alpha1", Kind: KindVirtualMachinePool},
+				Resource: metav1.GroupVersionResource{Group: "pool.kubevirt.io", Version: "v1alpha1", Resource: "virtualmachinepools"},
+			})).To(Succeed())
+			Expect(checkKind(&admissionv1.AdmissionRequest{})).To(Succeed())
+		})
+
+		It("should reject kinds of other groups", func() {
+			Expect(checkKind(&admissionv1.AdmissionRequest{
+				Kind: metav1.GroupVersionKind{Group: "example.com", Version: "v1", Kind: KindVirtualMachine},
+			})).To(MatchError("unsupported kind VirtualMachine.example.com"))
+		})
+
+		It("should reject mismatched resources", func() {
+			Expect(checkKind(&admissionv1.AdmissionRequest{
+				Kind:     metav1.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: KindVirtualMachine},
+				Resource: metav1.GroupVersionResource{Group: "kubevirt.io", Version: "v1", Resource: "virtualmachineinstances"},
+			})).To(MatchError(ContainSubstring("unexpected resource virtualmachineinstances.kubevirt.io")))
+		})
+	})
+
+	Describe("vmiObject", func() {
+		var obj *vmiObject
+
+		BeforeEach(func() {
+			obj = newVMIObject(&kubevirtv1.VirtualMachineInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-vmi",
+					Namespace: "default",
+					Annotations: map[string]string{
+						"feature": "enabled",
+						"keep":    "me",
+					},
+				},
+			})
+		})
+
+		It("should fold VM and template metadata changes into the VMI", func() {
+			mutated := obj.VirtualMachine().DeepCopy()
+			mutated.Annotations["tracking"] = "true"
+			mutated.Spec.Template.ObjectMeta.Annotations["hook"] = "[]"
+			mutated.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{Cores: 4}
+
+			vmi, ok := obj.Mutated(mutated).(*kubevirtv1.VirtualMachineInstance)
+			Expect(ok).To(BeTrue())
+			Expect(vmi.Annotations).To(HaveKeyWithValue("feature", "enabled"))
+			Expect(vmi.Annotations).To(HaveKeyWithValue("keep", "me"))
+			Expect(vmi.Annotations).To(HaveKeyWithValue("tracking", "true"))
+			Expect(vmi.Annotations).To(HaveKeyWithValue("hook", "[]"))
+			Expect(vmi.Spec.Domain.CPU.Cores).To(Equal(uint32(4)))
+		})
+
+		It("should not restore annotations removed from the VM metadata", func() {
+			mutated := obj.VirtualMachine().DeepCopy()
+			delete(mutated.Annotations, "feature")
+
+			vmi := obj.Mutated(mutated).(*kubevirtv1.VirtualMachineInstance)
+			Expect(vmi.Annotations).ToNot(HaveKey("feature"))
+			Expect(vmi.Annotations).To(HaveKey("keep"))
+		})
+
+		It("should leave the original VMI untouched", func() {
+			mutated := obj.VirtualMachine().DeepCopy()
+			mutated.Annotations["tracking"] = "true"
+			_ = obj.Mutated(mutated)
+
+			original := obj.Original().(*kubevirtv1.VirtualMachineInstance)
+			Expect(original.Annotations).ToNot(HaveKey("tracking"))
+		})
+	})
+
+	Describe("poolObject", func() {
+		It("should expose the pool metadata and VM template as the view", func() {
+			pool := &poolv1alpha1.VirtualMachinePool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-pool",
+					Namespace:   "default",
+					Annotations: map[string]string{"feature": "enabled"},
+				},
+				Spec: poolv1alpha1.VirtualMachinePoolSpec{
+					VirtualMachineTemplate: &poolv1alpha1.VirtualMachineTemplateSpec{
+						Spec: kubevirtv1.VirtualMachineSpec{
+							Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+						},
+					},
+				},
+			}
+			poolBytes, err := json.Marshal(pool)
+			Expect(err).ToNot(HaveOccurred())
+
+			obj, err := decodeObject(&admissionv1.AdmissionRequest{
+				Kind:   metav1.GroupVersionKind{Group: "pool.kubevirt.io", Version: "v1alpha1", Kind: KindVirtualMachinePool},
+				Object: runtime.RawExtension{Raw: poolBytes},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(obj.Kind()).To(Equal(KindVirtualMachinePool))
+
+			vm := obj.VirtualMachine()
+			Expect(vm.Annotations).To(HaveKeyWithValue("feature", "enabled"))
+			Expect(vm.Spec.Template).ToNot(BeNil())
+
+			mutated := vm.DeepCopy()
+			mutated.Spec.Template.Spec.Domain.CPU = &kubevirtv1.CPU{Cores: 2}
+			mutated.Annotations["tracking"] = "true"
+
+			result, ok := obj.Mutated(mutated).(*poolv1alpha1.VirtualMachinePool)
+			Expect(ok).To(BeTrue())
+			Expect(result.Annotations).To(HaveKeyWithValue("tracking", "true"))
+			Expect(result.Spec.VirtualMachineTemplate.Spec.Template.Spec.Domain.CPU.Cores).To(Equal(uint32(2)))
+		})
+
+		It("should not add a VM template when none was created", func() {
+			obj := newPoolObject(&poolv1alpha1.VirtualMachinePool{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-pool"},
+			})
+
+			result := obj.Mutated(obj.VirtualMachine().DeepCopy()).(*poolv1alpha1.VirtualMachinePool)
+			Expect(result.Spec.VirtualMachineTemplate).To(BeNil())
+		})
+	})
+
+	Describe("replicaSetObject", func() {
+		It("should expose the replica set metadata and VMI template as the view", func() {
+			rs := &kubevirtv1.VirtualMachineInstanceReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-rs",
+					Namespace:   "default",
+					Annotations: map[string]string{"feature": "enabled"},
+				},
+				Spec: kubevirtv1.VirtualMachineInstanceReplicaSetSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+				},
+			}
+			rsBytes, err := json.Marshal(rs)
+			Expect(err).ToNot(HaveOccurred())
+
+			obj, err := decodeObject(&admissionv1.AdmissionRequest{
+				Kind:   metav1.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: KindVirtualMachineInstanceReplicaSet},
+				Object: runtime.RawExtension{Raw: rsBytes},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(obj.Kind()).To(Equal(KindVirtualMachineInstanceReplicaSet))
+
+			vm := obj.VirtualMachine()
+			Expect(vm.Annotations).To(HaveKeyWithValue("feature", "enabled"))
+
+			mutated := vm.DeepCopy()
+			mutated.Spec.Template.ObjectMeta.Annotations = map[string]string{"hook": "[]"}
+
+			result, ok := obj.Mutated(mutated).(*kubevirtv1.VirtualMachineInstanceReplicaSet)
+			Expect(ok).To(BeTrue())
+			Expect(result.Spec.Template.ObjectMeta.Annotations).To(HaveKeyWithValue("hook", "[]"))
+			Expect(result.Annotations).To(HaveKeyWithValue("feature", "enabled"))
+		})
+	})
+})
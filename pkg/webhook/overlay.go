@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// overlayKey identifies an overlaid key in the VM's labels or annotations
+type overlayKey struct {
+	label bool
+	key   string
+}
+
+// requestOverlay tracks feature keys changed on the VM's config source for
+// the current request only. Overlaid values (combined key expansion, policy
+// defaults) aren't necessarily valid labels, so the original values are
+// restored before the patch is built.
+type requestOverlay struct {
+	source   utils.ConfigSource
+	original map[overlayKey]*string
+
+	// Maps the overlay created, dropped again if they end up empty
+	createdLabels      bool
+	createdAnnotations bool
+}
+
+// newRequestOverlay creates an empty overlay for the given config source
+func newRequestOverlay(source utils.ConfigSource) *requestOverlay {
+	return &requestOverlay{
+		source:   source,
+		original: make(map[overlayKey]*string),
+	}
+}
+
+// metadata returns the VM's labels or annotations, creating the map if needed
+func (o *requestOverlay) metadata(vm *kubevirtv1.VirtualMachine, label bool) map[string]string {
+	if label {
+		if vm.Labels == nil {
+			vm.Labels = make(map[string]string)
+			o.createdLabels = true
+		}
+		return vm.Labels
+	}
+	if vm.Annotations == nil {
+		vm.Annotations = make(map[string]string)
+		o.createdAnnotations = true
+	}
+	return vm.Annotations
+}
+
+// labelsFirst reports whether labels take precedence in the config source
+func (o *requestOverlay) labelsFirst() bool {
+	return o.source == utils.ConfigSourceLabels || o.source == utils.ConfigSourceBothLabelsFirst
+}
+
+// record remembers the value of key before its first overlay change
+func (o *requestOverlay) record(vm *kubevirtv1.VirtualMachine, label bool, key string) map[string]string {
+	target := o.metadata(vm, label)
+	id := overlayKey{label: label, key: key}
+	if _, seen := o.original[id]; seen {
+		return target
+	}
+	if value, exists := target[key]; exists {
+		o.original[id] = &value
+	} else {
+		o.original[id] = nil
+	}
+	return target
+}
+
+// set overlays key with value in the config source that takes precedence
+func (o *requestOverlay) set(vm *kubevirtv1.VirtualMachine, key, value string) {
+	o.record(vm, o.labelsFirst(), key)[key] = value
+}
+
+// remove hides key from every part of the config source for the rest of the request
+func (o *requestOverlay) remove(vm *kubevirtv1.VirtualMachine, key string) {
+	if utils.ReadsLabels(o.source) {
+		delete(o.record(vm, true, key), key)
+	}
+	if utils.ReadsAnnotations(o.source) {
+		delete(o.record(vm, false, key), key)
+	}
+}
+
+// restore puts back the values the overlaid keys had before the request
+func (o *requestOverlay) restore(vm *kubevirtv1.VirtualMachine) {
+	for id, value := range o.original {
+		target := o.metadata(vm, id.label)
+		if value == nil {
+			delete(target, id.key)
+			continue
+		}
+		target[id.key] = *value
+	}
+
+	if o.createdLabels && len(vm.Labels) == 0 {
+		vm.Labels = nil
+	}
+	if o.createdAnnotations && len(vm.Annotations) == 0 {
+		vm.Annotations = nil
+	}
+}
@@ -0,0 +1,197 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/apis/v1alpha1"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// featurePolicy is the merged effect of the policies matching a VM
+type featurePolicy struct {
+	defaults  map[string]string
+	forced    map[string]string
+	forbidden map[string]bool
+}
+
+// resolvePolicies merges the ClusterVMFeaturePolicies and the namespace's
+// VMFeaturePolicies whose selector matches the VM, along with the rules from
+// the rules file and the features restricted to other namespaces. Namespace defaults override cluster defaults, cluster
+// forced values override namespace forced values, and forbidden features are
+// the union of all matching policies. It returns nil when neither policies
+// nor rules apply or the CRDs aren't installed.
+func (m *Mutator) resolvePolicies(ctx context.Context, vm *kubevirtv1.VirtualMachine, namespace string) (*featurePolicy, error) {
+	var clusterSpecs, namespaceSpecs []v1alpha1.VMFeaturePolicySpec
+	if m.config.FeaturePolicies && m.client != nil {
+		var clusterPolicies v1alpha1.ClusterVMFeaturePolicyList
+		if err := m.client.List(ctx, &clusterPolicies); err != nil && !meta.IsNoMatchError(err) {
+			return nil, fmt.Errorf("failed to list cluster feature policies: %w", err)
+		}
+		var namespacePolicies v1alpha1.VMFeaturePolicyList
+		if err := m.client.List(ctx, &namespacePolicies, client.InNamespace(namespace)); err != nil && !meta.IsNoMatchError(err) {
+			return nil, fmt.Errorf("failed to list feature policies in namespace %s: %w", namespace, err)
+		}
+
+		var err error
+		clusterSpecs, err = matchingPolicies(vm, clusterPolicies.Items, func(p v1alpha1.ClusterVMFeaturePolicy) (string, v1alpha1.VMFeaturePolicySpec) {
+			return p.Name, p.Spec
+		})
+		if err != nil {
+			return nil, err
+		}
+		namespaceSpecs, err = matchingPolicies(vm, namespacePolicies.Items, func(p v1alpha1.VMFeaturePolicy) (string, v1alpha1.VMFeaturePolicySpec) {
+			return namespace + "/" + p.Name, p.Spec
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Rules from the rules file act as a cluster policy matching every VM
+	if len(m.config.FeatureRules) > 0 {
+		clusterSpecs = append([]v1alpha1.VMFeaturePolicySpec{{Rules: m.config.FeatureRules}}, clusterSpecs...)
+	}
+	// Features restricted to other namespaces are forbidden like a cluster policy would
+	if restricted := m.restrictedFeatures(namespace); len(restricted) > 0 {
+		clusterSpecs = append(clusterSpecs, v1alpha1.VMFeaturePolicySpec{Forbidden: restricted})
+	}
+	if len(clusterSpecs) == 0 && len(namespaceSpecs) == 0 {
+		return nil, nil
+	}
+
+	if err := m.evaluatePolicyRules(vm, namespace, clusterSpecs, namespaceSpecs); err != nil {
+		return nil, err
+	}
+
+	policy := &featurePolicy{
+		defaults:  make(map[string]string),
+		forced:    make(map[string]string),
+		forbidden: make(map[string]bool),
+	}
+	// Later merges win: cluster then namespace defaults, namespace then cluster forced
+	for _, spec := range append(clusterSpecs, namespaceSpecs...) {
+		mergeFeatureValues(policy.defaults, spec.Defaults)
+		for _, name := range spec.Forbidden {
+			policy.forbidden[featureKey(name)] = true
+		}
+	}
+	for _, spec := range append(namespaceSpecs, clusterSpecs...) {
+		mergeFeatureValues(policy.forced, spec.Forced)
+	}
+	return policy, nil
+}
+
+// restrictedFeatures returns the features of FeatureNamespaces that the
+// namespace isn't allowed to request, in name order
+func (m *Mutator) restrictedFeatures(namespace string) []string {
+	var restricted []string
+	for feature, namespaces := range m.config.FeatureNamespaces {
+		if !slices.Contains(namespaces, namespace) {
+			restricted = append(restricted, feature)
+		}
+	}
+	sort.Strings(restricted)
+	return restricted
+}
+
+// evaluatePolicyRules replaces each spec with rules by one carrying the
+// effect of its matching rules
+func (m *Mutator) evaluatePolicyRules(vm *kubevirtv1.VirtualMachine, namespace string, specLists ...[]v1alpha1.VMFeaturePolicySpec) error {
+	var input map[string]any
+	for _, specs := range specLists {
+		for i, spec := range specs {
+			if len(spec.Rules) == 0 {
+				continue
+			}
+			if input == nil {
+				var err error
+				if input, err = ruleInput(vm, namespace); err != nil {
+					return err
+				}
+			}
+			evaluated, err := m.evaluateRules(spec, input)
+			if err != nil {
+				return err
+			}
+			specs[i] = evaluated
+		}
+	}
+	return nil
+}
+
+// matchingPolicies returns the specs of the policies whose selector matches
+// the VM's labels, in name order
+func matchingPolicies[T any](vm *kubevirtv1.VirtualMachine, items []T, spec func(T) (string, v1alpha1.VMFeaturePolicySpec)) ([]v1alpha1.VMFeaturePolicySpec, error) {
+	sort.SliceStable(items, func(i, j int) bool {
+		nameI, _ := spec(items[i])
+		nameJ, _ := spec(items[j])
+		return nameI < nameJ
+	})
+
+	var matched []v1alpha1.VMFeaturePolicySpec
+	for _, item := range items {
+		name, policySpec := spec(item)
+		selector := labels.Everything()
+		if policySpec.Selector != nil {
+			var err error
+			selector, err = metav1.LabelSelectorAsSelector(policySpec.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector in feature policy %s: %w", name, err)
+			}
+		}
+		if selector.Matches(labels.Set(vm.GetLabels())) {
+			matched = append(matched, policySpec)
+		}
+	}
+	return matched, nil
+}
+
+// mergeFeatureValues copies policy feature values into dst under their fully qualified keys
+func mergeFeatureValues(dst, src map[string]string) {
+	for name, value := range src {
+		dst[featureKey(name)] = value
+	}
+}
+
+// applyPolicy overlays the policy on the VM's config source. Forbidden
+// features the VM requests are removed and returned, while disabling one is
+// allowed; defaults only fill keys the VM doesn't set and forced values
+// replace the VM's own.
+func (m *Mutator) applyPolicy(vm *kubevirtv1.VirtualMachine, policy *featurePolicy, overlay *requestOverlay) []string {
+	if policy == nil {
+		return nil
+	}
+
+	var violations []string
+	for key := range policy.forbidden {
+		if value, requested := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), key); requested && !utils.IsFalsyValue(value) {
+			violations = append(violations, key)
+			overlay.remove(vm, key)
+		}
+	}
+	sort.Strings(violations)
+
+	for key, value := range policy.defaults {
+		if policy.forbidden[key] {
+			continue
+		}
+		if _, set := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), key); !set {
+			overlay.set(vm, key, value)
+		}
+	}
+	for key, value := range policy.forced {
+		if policy.forbidden[key] {
+			continue
+		}
+		overlay.set(vm, key, value)
+	}
+	return violations
+}
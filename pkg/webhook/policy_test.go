@@ -0,0 +1,241 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/apis/v1alpha1"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Feature policies", func() {
+	var (
+		cfg      *config.Config
+		vm       *kubevirtv1.VirtualMachine
+		policies []client.Object
+	)
+
+	BeforeEach(func() {
+		cfg = &config.Config{
+			ErrorHandlingMode:      utils.ErrorHandlingReject,
+			AddTrackingAnnotations: true,
+			ConfigSource:           utils.ConfigSourceAnnotations,
+			FeaturePolicies:        true,
+		}
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "default",
+				Labels:    map[string]string{"tier": "gpu"},
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+			},
+		}
+		policies = nil
+	})
+
+	handle := func() (*admissionv1.AdmissionResponse, []byte) {
+		vmBytes, err := json.Marshal(vm)
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(policies...).Build()
+		mutator := mustNewMutator(fakeClient, cfg, []features.Feature{
+			features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, cfg.ConfigSource),
+		})
+		response, err := mutator.Handle(context.Background(), &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Operation: admissionv1.Create,
+			Namespace: "default",
+			Object:    runtime.RawExtension{Raw: vmBytes},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return response, vmBytes
+	}
+
+	namespacePolicy := func(name string, spec v1alpha1.VMFeaturePolicySpec) *v1alpha1.VMFeaturePolicy {
+		return &v1alpha1.VMFeaturePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       spec,
+		}
+	}
+
+	clusterPolicy := func(name string, spec v1alpha1.VMFeaturePolicySpec) *v1alpha1.ClusterVMFeaturePolicy {
+		return &v1alpha1.ClusterVMFeaturePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       spec,
+		}
+	}
+
+	It("should apply namespace defaults without persisting the request", func() {
+		policies = append(policies, namespacePolicy("gpus", v1alpha1.VMFeaturePolicySpec{
+			Defaults: map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+		}))
+
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(BeEquivalentTo("nvidia.com/gpu")))
+		Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationGpuDevicePlugin))
+	})
+
+	It("should let the VM's own request override defaults", func() {
+		vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"}
+		policies = append(policies, namespacePolicy("gpus", v1alpha1.VMFeaturePolicySpec{
+			Defaults: map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+		}))
+
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "amd.com/gpu"))
+	})
+
+	It("should prefer namespace defaults over cluster defaults", func() {
+		policies = append(policies,
+			clusterPolicy("gpus", v1alpha1.VMFeaturePolicySpec{
+				Defaults: map[string]string{"gpu-device-plugin": "amd.com/gpu"},
+			}),
+			namespacePolicy("gpus", v1alpha1.VMFeaturePolicySpec{
+				Defaults: map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+			}),
+		)
+
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "nvidia.com/gpu"))
+	})
+
+	It("should override the VM's request with forced values", func() {
+		vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "amd.com/gpu"}
+		policies = append(policies, clusterPolicy("gpus", v1alpha1.VMFeaturePolicySpec{
+			Forced: map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+		}))
+
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "nvidia.com/gpu"))
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePlugin, "amd.com/gpu"))
+	})
+
+	It("should skip policies whose selector doesn't match", func() {
+		policies = append(policies, namespacePolicy("gpus", v1alpha1.VMFeaturePolicySpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "cpu"}},
+			Defaults: map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+		}))
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Patch).To(BeNil())
+	})
+
+	It("should reject forbidden features in reject mode", func() {
+		vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+		policies = append(policies, clusterPolicy("no-gpus", v1alpha1.VMFeaturePolicySpec{
+			Forbidden: []string{"gpu-device-plugin"},
+		}))
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeFalse())
+		Expect(response.Result.Message).To(ContainSubstring("forbidden by policy"))
+	})
+
+	It("should allow disabling forbidden features", func() {
+		vm.Annotations = map[string]string{utils.AnnotationNestedVirt: "disabled"}
+		policies = append(policies, clusterPolicy("no-nesting", v1alpha1.VMFeaturePolicySpec{
+			Forbidden: []string{"nested-virt"},
+		}))
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Warnings).To(BeEmpty())
+	})
+
+	It("should ignore forbidden features with a warning otherwise", func() {
+		cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
+		vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+		policies = append(policies, namespacePolicy("no-gpus", v1alpha1.VMFeaturePolicySpec{
+			Forbidden: []string{utils.AnnotationGpuDevicePlugin},
+		}))
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Patch).To(BeNil())
+		Expect(response.Warnings).To(ContainElement(ContainSubstring("forbidden by policy")))
+	})
+
+	It("should not evaluate policies when disabled", func() {
+		cfg.FeaturePolicies = false
+		policies = append(policies, namespacePolicy("gpus", v1alpha1.VMFeaturePolicySpec{
+			Defaults: map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+		}))
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Patch).To(BeNil())
+	})
+
+	Context("with namespace restrictions", func() {
+		BeforeEach(func() {
+			cfg.FeaturePolicies = false
+			vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+		})
+
+		It("should reject features restricted to other namespaces", func() {
+			cfg.FeatureNamespaces = map[string][]string{"gpu-device-plugin": {"hw-lab", "ci"}}
+
+			response, _ := handle()
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Message).To(ContainSubstring(utils.AnnotationGpuDevicePlugin))
+		})
+
+		It("should allow disabling features restricted to other namespaces", func() {
+			cfg.FeatureNamespaces = map[string][]string{"nested-virt": {"hw-lab", "ci"}}
+			vm.Annotations = map[string]string{utils.AnnotationNestedVirt: "false"}
+
+			response, _ := handle()
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+		})
+
+		It("should allow features in their namespaces", func() {
+			cfg.FeatureNamespaces = map[string][]string{utils.AnnotationGpuDevicePlugin: {"hw-lab", "default"}}
+
+			response, vmBytes := handle()
+			Expect(response.Allowed).To(BeTrue())
+
+			patched := applyPatch(vmBytes, response.Patch)
+			Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "nvidia.com/gpu"))
+		})
+
+		It("should keep policy defaults from adding restricted features", func() {
+			cfg.FeaturePolicies = true
+			cfg.FeatureNamespaces = map[string][]string{"gpu-device-plugin": {"hw-lab"}}
+			vm.Annotations = nil
+			policies = append(policies, clusterPolicy("gpus", v1alpha1.VMFeaturePolicySpec{
+				Defaults: map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+			}))
+
+			response, _ := handle()
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Patch).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/apis/v1alpha1"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+// applyProfile overlays the features of the FeatureProfile named by the VM's
+// profile key. Keys the VM sets itself take precedence over the profile.
+func (m *Mutator) applyProfile(ctx context.Context, vm *kubevirtv1.VirtualMachine, overlay *requestOverlay) error {
+	name, exists := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), utils.AnnotationProfile)
+	name = strings.TrimSpace(name)
+	if !exists || name == "" {
+		return nil
+	}
+	if m.client == nil {
+		return fmt.Errorf("cannot resolve feature profile %q without a Kubernetes client", name)
+	}
+
+	var profile v1alpha1.FeatureProfile
+	if err := m.client.Get(ctx, client.ObjectKey{Name: name}, &profile); err != nil {
+		return fmt.Errorf("failed to get feature profile %q: %w", name, err)
+	}
+
+	for featureName, value := range profile.Spec.Features {
+		key := featureKey(featureName)
+		if key == utils.AnnotationProfile {
+			continue
+		}
+		if _, set := utils.GetConfigValue(m.config.ConfigSource, vm.GetAnnotations(), vm.GetLabels(), key); set {
+			continue
+		}
+		overlay.set(vm, key, value)
+	}
+	return nil
+}
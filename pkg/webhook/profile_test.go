@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/apis/v1alpha1"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Feature profiles", func() {
+	var (
+		cfg     *config.Config
+		vm      *kubevirtv1.VirtualMachine
+		profile *v1alpha1.FeatureProfile
+	)
+
+	BeforeEach(func() {
+		cfg = &config.Config{
+			ErrorHandlingMode:      utils.ErrorHandlingReject,
+			AddTrackingAnnotations: true,
+			ConfigSource:           utils.ConfigSourceAnnotations,
+		}
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-vm",
+				Namespace:   "default",
+				Annotations: map[string]string{utils.AnnotationProfile: "gaming-gpu"},
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+			},
+		}
+		profile = &v1alpha1.FeatureProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "gaming-gpu"},
+			Spec: v1alpha1.FeatureProfileSpec{
+				Features: map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+			},
+		}
+	})
+
+	handle := func() (*admissionv1.AdmissionResponse, []byte) {
+		vmBytes, err := json.Marshal(vm)
+		Expect(err).ToNot(HaveOccurred())
+
+		builder := fake.NewClientBuilder().WithScheme(scheme)
+		if profile != nil {
+			builder = builder.WithObjects(profile)
+		}
+		mutator := mustNewMutator(builder.Build(), cfg, []features.Feature{
+			features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, cfg.ConfigSource),
+		})
+		response, err := mutator.Handle(context.Background(), &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: vmBytes},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return response, vmBytes
+	}
+
+	It("should apply the profile's features without persisting them", func() {
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Spec.Template.Spec.Domain.Resources.Limits).To(HaveKey(BeEquivalentTo("nvidia.com/gpu")))
+		Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationGpuDevicePlugin))
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationProfile, "gaming-gpu"))
+	})
+
+	It("should let explicit annotations take precedence over the profile", func() {
+		vm.Annotations[utils.AnnotationGpuDevicePlugin] = "amd.com/gpu"
+
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "amd.com/gpu"))
+	})
+
+	It("should reject an unknown profile in reject mode", func() {
+		profile = nil
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeFalse())
+		Expect(response.Result.Message).To(ContainSubstring("gaming-gpu"))
+	})
+
+	It("should warn about an unknown profile otherwise", func() {
+		cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
+		profile = nil
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Warnings).To(ContainElement(ContainSubstring("feature profile was not applied")))
+	})
+})
@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// readinessTimeout bounds all readiness checks of one probe
+const readinessTimeout = 3 * time.Second
+
+// ReadinessCheck reports why the server can't serve admission requests yet
+type ReadinessCheck func(ctx context.Context) error
+
+// namedCheck is a readiness check with the name it is reported under
+type namedCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// AddReadinessCheck makes /readyz fail while check does, in addition to the
+// built-in certificate and feature registry checks. It must be called
+// before Start.
+func (s *Server) AddReadinessCheck(name string, check ReadinessCheck) {
+	s.readinessChecks = append(s.readinessChecks, namedCheck{name: name, check: check})
+}
+
+// checkReadiness runs every readiness check and returns the failures
+// reported as "name: error"
+func (s *Server) checkReadiness(ctx context.Context) []string {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	checks := append([]namedCheck{
+		{name: "certificate", check: s.checkCertificate},
+		{name: "features", check: s.checkFeatures},
+	}, s.readinessChecks...)
+
+	var failures []string
+	for _, c := range checks {
+		if err := c.check(ctx); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", c.name, err))
+		}
+	}
+	return failures
+}
+
+// checkCertificate verifies that the serving certificate loads and is
+// currently valid
+func (s *Server) checkCertificate(_ context.Context) error {
+	pair, err := tls.LoadX509KeyPair(filepath.Join(s.config.CertDir, "tls.crt"), filepath.Join(s.config.CertDir, "tls.key"))
+	if err != nil {
+		return err
+	}
+	if len(pair.Certificate) == 0 {
+		return errors.New("no certificate found")
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("certificate not valid before %s", cert.NotBefore.Format(time.RFC3339))
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("certificate expired at %s", cert.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkFeatures verifies that features have been registered
+func (s *Server) checkFeatures(_ context.Context) error {
+	if len(s.handler.mutator.registry.States()) == 0 {
+		return errors.New("no features registered")
+	}
+	return nil
+}
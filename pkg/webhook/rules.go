@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/apis/v1alpha1"
+)
+
+// ruleEnv declares the variables and functions available to rule expressions
+var ruleEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("vm", cel.DynType),
+		cel.Variable("namespaceName", cel.StringType),
+		cel.Variable("memory", cel.IntType),
+		cel.Variable("cpus", cel.IntType),
+		cel.Function("quantity",
+			cel.Overload("quantity_string", []*cel.Type{cel.StringType}, cel.IntType,
+				cel.UnaryBinding(func(value ref.Val) ref.Val {
+					quantity, err := resource.ParseQuantity(string(value.(types.String)))
+					if err != nil {
+						return types.NewErr("invalid quantity %q: %v", value, err)
+					}
+					return types.Int(quantity.Value())
+				}),
+			),
+		),
+	)
+})
+
+// ruleCache caches compiled rule programs by expression
+type ruleCache struct {
+	mu       sync.Mutex
+	programs map[string]cel.Program
+}
+
+// program returns the compiled program for the rule's expression
+func (c *ruleCache) program(rule v1alpha1.FeatureRule) (cel.Program, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if program, ok := c.programs[rule.Expression]; ok {
+		return program, nil
+	}
+
+	program, err := compileRule(rule)
+	if err != nil {
+		return nil, err
+	}
+	if c.programs == nil {
+		c.programs = make(map[string]cel.Program)
+	}
+	c.programs[rule.Expression] = program
+	return program, nil
+}
+
+// compileRule compiles the rule's expression and checks it yields a bool
+func compileRule(rule v1alpha1.FeatureRule) (cel.Program, error) {
+	env, err := ruleEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rule environment: %w", err)
+	}
+
+	ast, issues := env.Compile(rule.Expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression in rule %s: %w", rule.Name, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression in rule %s must evaluate to a bool, not %s", rule.Name, ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression in rule %s: %w", rule.Name, err)
+	}
+	return program, nil
+}
+
+// ValidateFeatureRules checks that every rule has a name and an expression
+// that compiles
+func ValidateFeatureRules(rules []v1alpha1.FeatureRule) error {
+	for i, rule := range rules {
+		if rule.Name == "" || rule.Expression == "" {
+			return fmt.Errorf("feature rule %d: name and expression are required", i)
+		}
+		if _, err := compileRule(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluateRules adds the features enabled by matching rules to the spec's
+// forced values and the features they deny to its forbidden list
+func (m *Mutator) evaluateRules(spec v1alpha1.VMFeaturePolicySpec, input map[string]any) (v1alpha1.VMFeaturePolicySpec, error) {
+	if len(spec.Rules) == 0 {
+		return spec, nil
+	}
+
+	spec = *spec.DeepCopy()
+	for _, rule := range spec.Rules {
+		program, err := m.rules.program(rule)
+		if err != nil {
+			return spec, err
+		}
+		out, _, err := program.Eval(input)
+		if err != nil {
+			return spec, fmt.Errorf("failed to evaluate rule %s: %w", rule.Name, err)
+		}
+		matched, ok := out.(types.Bool)
+		if !ok {
+			return spec, fmt.Errorf("expression in rule %s must evaluate to a bool, not %s", rule.Name, out.Type())
+		}
+		if !matched {
+			continue
+		}
+
+		if len(rule.Enable) > 0 && spec.Forced == nil {
+			spec.Forced = make(map[string]string, len(rule.Enable))
+		}
+		for name, value := range rule.Enable {
+			spec.Forced[name] = value
+		}
+		spec.Forbidden = append(spec.Forbidden, rule.Deny...)
+	}
+	return spec, nil
+}
+
+// ruleInput builds the variables rule expressions are evaluated against
+func ruleInput(vm *kubevirtv1.VirtualMachine, namespace string) (map[string]any, error) {
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(vm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert VM for rule evaluation: %w", err)
+	}
+
+	var memory, cpus int64 = 0, 1
+	if vm.Spec.Template != nil {
+		domain := vm.Spec.Template.Spec.Domain
+		if domain.Memory != nil && domain.Memory.Guest != nil {
+			memory = domain.Memory.Guest.Value()
+		} else if request, ok := domain.Resources.Requests[corev1.ResourceMemory]; ok {
+			memory = request.Value()
+		}
+
+		if domain.CPU != nil {
+			cpus = int64(max(domain.CPU.Sockets, 1) * max(domain.CPU.Cores, 1) * max(domain.CPU.Threads, 1))
+		} else if request, ok := domain.Resources.Requests[corev1.ResourceCPU]; ok {
+			cpus = request.Value()
+		}
+	}
+
+	return map[string]any{
+		"vm":            object,
+		"namespaceName": namespace,
+		"memory":        memory,
+		"cpus":          cpus,
+	}, nil
+}
@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/apis/v1alpha1"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
+)
+
+var _ = Describe("Feature rules", func() {
+	var (
+		cfg      *config.Config
+		vm       *kubevirtv1.VirtualMachine
+		policies []client.Object
+	)
+
+	const largeMLVMs = "memory > quantity('16Gi') && namespaceName.startsWith('ml-')"
+
+	BeforeEach(func() {
+		cfg = &config.Config{
+			ErrorHandlingMode:      utils.ErrorHandlingReject,
+			AddTrackingAnnotations: true,
+			ConfigSource:           utils.ConfigSourceAnnotations,
+			FeaturePolicies:        true,
+		}
+		guest := resource.MustParse("32Gi")
+		vm = &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vm",
+				Namespace: "ml-training",
+			},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							Memory: &kubevirtv1.Memory{Guest: &guest},
+						},
+					},
+				},
+			},
+		}
+		policies = nil
+	})
+
+	handle := func() (*admissionv1.AdmissionResponse, []byte) {
+		vmBytes, err := json.Marshal(vm)
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(policies...).Build()
+		mutator := mustNewMutator(fakeClient, cfg, []features.Feature{
+			features.NewGpuDevicePlugin(&config.GPUDevicePluginConfig{Enabled: true}, cfg.ConfigSource),
+		})
+		response, err := mutator.Handle(context.Background(), &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Operation: admissionv1.Create,
+			Namespace: vm.Namespace,
+			Object:    runtime.RawExtension{Raw: vmBytes},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return response, vmBytes
+	}
+
+	rulePolicy := func(rules ...v1alpha1.FeatureRule) *v1alpha1.ClusterVMFeaturePolicy {
+		return &v1alpha1.ClusterVMFeaturePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "rules"},
+			Spec:       v1alpha1.VMFeaturePolicySpec{Rules: rules},
+		}
+	}
+
+	It("should enable features for VMs matching a policy rule", func() {
+		policies = append(policies, rulePolicy(v1alpha1.FeatureRule{
+			Name:       "large-ml-vms",
+			Expression: largeMLVMs,
+			Enable:     map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+		}))
+
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "nvidia.com/gpu"))
+		Expect(patched.Annotations).ToNot(HaveKey(utils.AnnotationGpuDevicePlugin))
+	})
+
+	It("should skip rules whose expression doesn't match", func() {
+		vm.Namespace = "default"
+		policies = append(policies, rulePolicy(v1alpha1.FeatureRule{
+			Name:       "large-ml-vms",
+			Expression: largeMLVMs,
+			Enable:     map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+		}))
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Patch).To(BeNil())
+	})
+
+	It("should reject features denied by a matching rule", func() {
+		vm.Annotations = map[string]string{utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu"}
+		policies = append(policies, rulePolicy(v1alpha1.FeatureRule{
+			Name:       "labelled-gpus-only",
+			Expression: "!has(vm.metadata.labels) || !('gpu' in vm.metadata.labels)",
+			Deny:       []string{"gpu-device-plugin"},
+		}))
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeFalse())
+		Expect(response.Result.Message).To(ContainSubstring("forbidden by policy"))
+	})
+
+	It("should apply rules from the rules file without policies", func() {
+		cfg.FeaturePolicies = false
+		cfg.FeatureRules = []v1alpha1.FeatureRule{{
+			Name:       "large-ml-vms",
+			Expression: largeMLVMs,
+			Enable:     map[string]string{"gpu-device-plugin": "nvidia.com/gpu"},
+		}}
+
+		response, vmBytes := handle()
+		Expect(response.Allowed).To(BeTrue())
+
+		patched := applyPatch(vmBytes, response.Patch)
+		Expect(patched.Annotations).To(HaveKeyWithValue(utils.AnnotationGpuDevicePluginApplied, "nvidia.com/gpu"))
+	})
+
+	It("should warn about invalid rules outside reject mode", func() {
+		cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
+		policies = append(policies, rulePolicy(v1alpha1.FeatureRule{
+			Name:       "broken",
+			Expression: "memory >",
+		}))
+
+		response, _ := handle()
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Warnings).To(ContainElement(ContainSubstring("invalid expression in rule broken")))
+	})
+
+	Describe("ValidateFeatureRules", func() {
+		It("should accept valid rules", func() {
+			Expect(ValidateFeatureRules([]v1alpha1.FeatureRule{
+				{Name: "large-ml-vms", Expression: largeMLVMs},
+				{Name: "many-cpus", Expression: "cpus >= 8"},
+			})).To(Succeed())
+		})
+
+		It("should reject rules without a name", func() {
+			Expect(ValidateFeatureRules([]v1alpha1.FeatureRule{{Expression: "true"}})).ToNot(Succeed())
+		})
+
+		It("should reject expressions that don't evaluate to a bool", func() {
+			err := ValidateFeatureRules([]v1alpha1.FeatureRule{{Name: "memory", Expression: "memory"}})
+			Expect(err).To(MatchError(ContainSubstring("must evaluate to a bool")))
+		})
+	})
+})
@@ -3,11 +3,20 @@ package webhook
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 )
@@ -17,6 +26,13 @@ type Server struct {
 	config  *config.Config
 	handler *Handler
 	server  *http.Server
+
+	// healthServer serves the probe, metrics and debug endpoints over plain
+	// HTTP when a health port is configured
+	healthServer *http.Server
+
+	// readinessChecks are run by /readyz after the built-in ones
+	readinessChecks []namedCheck
 }
 
 // NewServer creates a new webhook server
@@ -31,36 +47,67 @@ func NewServer(cfg *config.Config, handler *Handler) *Server {
 func (s *Server) Start(ctx context.Context) error {
 	logger := log.FromContext(ctx)
 
-	mux := http.NewServeMux()
-	mux.Handle("/mutate", s.handler)
-	mux.HandleFunc("/healthz", s.healthzHandler)
-	mux.HandleFunc("/readyz", s.readyzHandler)
+	mux, healthMux := s.muxes()
+	if healthMux != nil {
+		s.healthServer = &http.Server{
+			Addr:              fmt.Sprintf(":%d", s.config.HealthPort),
+			Handler:           healthMux,
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       60 * time.Second,
+		}
+	}
 
-	// Configure TLS
+	// Configure TLS, picking up renewed certificates without a restart
+	keyPair, err := newKeyPairReloader(filepath.Join(s.config.CertDir, "tls.crt"), filepath.Join(s.config.CertDir, "tls.key"))
+	if err != nil {
+		return err
+	}
 	tlsConfig := &tls.Config{
-		MinVersion: tls.VersionTLS12,
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: keyPair.getCertificate,
+	}
+
+	// Verify client certificates when given; /mutate requires one
+	if s.config.ClientCAFile != "" {
+		pool, err := loadClientCAs(s.config.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
 	}
 
 	s.server = &http.Server{
-		Addr:         fmt.Sprintf(":%d", s.config.Port),
-		Handler:      mux,
-		TLSConfig:    tlsConfig,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:           fmt.Sprintf(":%d", s.config.Port),
+		Handler:        mux,
+		TLSConfig:      tlsConfig,
+		ReadTimeout:    time.Duration(s.config.Server.ReadTimeoutSeconds) * time.Second,
+		WriteTimeout:   time.Duration(s.config.Server.WriteTimeoutSeconds) * time.Second,
+		IdleTimeout:    time.Duration(s.config.Server.IdleTimeoutSeconds) * time.Second,
+		MaxHeaderBytes: s.config.Server.MaxHeaderBytes,
+	}
+
+	// A non-nil TLSNextProto keeps the server from negotiating HTTP/2
+	if !s.config.Server.HTTP2 {
+		s.server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
 	}
 
 	logger.Info("Starting webhook server",
 		"port", s.config.Port,
 		"certDir", s.config.CertDir)
 
-	// Start server in a goroutine
-	errChan := make(chan error, 1)
+	// Start servers in goroutines
+	errChan := make(chan error, 2)
+	if s.healthServer != nil {
+		logger.Info("Starting health server", "port", s.config.HealthPort)
+		go func() {
+			if err := s.healthServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errChan <- fmt.Errorf("health server: %w", err)
+			}
+		}()
+	}
 	go func() {
-		certFile := fmt.Sprintf("%s/tls.crt", s.config.CertDir)
-		keyFile := fmt.Sprintf("%s/tls.key", s.config.CertDir)
-
-		if err := s.server.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
+		if err := s.server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
@@ -71,12 +118,163 @@ func (s *Server) Start(ctx context.Context) error {
 		logger.Info("Shutting down webhook server")
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
+		if s.healthServer != nil {
+			if err := s.healthServer.Shutdown(shutdownCtx); err != nil {
+				logger.Error(err, "Failed to shut down health server")
+			}
+		}
 		return s.server.Shutdown(shutdownCtx)
 	case err := <-errChan:
+		if s.healthServer != nil {
+			_ = s.healthServer.Close()
+		}
+		_ = s.server.Close()
 		return err
 	}
 }
 
+// muxes returns the handlers of the TLS port and, when a health port is
+// configured, of the plain HTTP one. With a health port, the TLS port serves
+// admission requests only.
+func (s *Server) muxes() (*http.ServeMux, *http.ServeMux) {
+	var mutate http.Handler = s.handler
+	if s.config.Server.MaxRequestBytes > 0 {
+		mutate = limitRequestBody(mutate, int64(s.config.Server.MaxRequestBytes))
+	}
+	if s.config.ClientCAFile != "" {
+		mutate = requireClientCert(mutate)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/mutate", mutate)
+	if s.config.HealthPort == 0 {
+		s.registerObservability(mux)
+		return mux, nil
+	}
+
+	healthMux := http.NewServeMux()
+	s.registerObservability(healthMux)
+	return mux, healthMux
+}
+
+// keyPairReloader serves a key pair from disk, reloading it when either file
+// changes, e.g. when cert-manager or certs.Renew replaces it
+type keyPairReloader struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+// newKeyPairReloader loads the key pair, failing when it can't be read
+func newKeyPairReloader(certFile, keyFile string) (*keyPairReloader, error) {
+	r := &keyPairReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.getCertificate(nil); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// getCertificate returns the current key pair. A pair that fails to load,
+// e.g. while it is being replaced, keeps the last one in use and is retried
+// on the next handshake.
+func (r *keyPairReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	modified, err := latestModTime(r.certFile, r.keyFile)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil && (r.cert == nil || !modified.Equal(r.modified)) {
+		var cert tls.Certificate
+		cert, err = tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err == nil {
+			r.cert, r.modified = &cert, modified
+		}
+	}
+	if r.cert == nil {
+		return nil, fmt.Errorf("failed to load serving certificate: %w", err)
+	}
+	return r.cert, nil
+}
+
+// latestModTime returns the latest modification time of the files
+func latestModTime(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// loadClientCAs reads the CAs that client certificates must chain to
+func loadClientCAs(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", path)
+	}
+	return pool, nil
+}
+
+// requireClientCert rejects requests that didn't present a client
+// certificate verified against the client CAs. Verification itself happens
+// in the TLS handshake, which lets requests without a certificate through so
+// probes on the same port keep working.
+func requireClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			log.FromContext(r.Context()).Info("Rejecting request without a verified client certificate", "remoteAddr", r.RemoteAddr)
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitRequestBody rejects requests announcing a body over limit before
+// reading it, and stops reading bodies that turn out larger
+func limitRequestBody(next http.Handler, limit int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			log.FromContext(r.Context()).Info("Rejecting oversized request", "contentLength", r.ContentLength, "limit", limit)
+			http.Error(w, fmt.Sprintf("request body exceeds %d bytes", limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// registerObservability serves the probe, metrics and debug endpoints
+func (s *Server) registerObservability(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", s.healthzHandler)
+	mux.HandleFunc("/readyz", s.readyzHandler)
+	mux.HandleFunc("/debug/features", s.featuresHandler)
+	mux.Handle("/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+	if s.config.Pprof {
+		registerPprof(mux)
+	}
+}
+
+// registerPprof serves the runtime profiles under /debug/pprof
+func registerPprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
+
 // healthzHandler handles health check requests
 func (s *Server) healthzHandler(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
@@ -86,11 +284,31 @@ func (s *Server) healthzHandler(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
-// readyzHandler handles readiness check requests
-func (s *Server) readyzHandler(w http.ResponseWriter, _ *http.Request) {
+// readyzHandler handles readiness check requests, failing with the reasons
+// while any readiness check fails
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if failures := s.checkReadiness(r.Context()); len(failures) > 0 {
+		log.Log.Info("Not ready", "failures", failures)
+		http.Error(w, strings.Join(failures, "\n"), http.StatusServiceUnavailable)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte("ready")); err != nil {
 		// Log error but don't fail - response status already sent
 		log.Log.Error(err, "Failed to write readiness check response")
 	}
 }
+
+// featuresHandler reports the registered features and their runtime state
+func (s *Server) featuresHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.handler.mutator.registry.States()); err != nil {
+		log.Log.Error(err, "Failed to write feature states response")
+	}
+}
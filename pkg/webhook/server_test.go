@@ -2,8 +2,22 @@ package webhook
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -11,6 +25,7 @@ import (
 
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
 	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/utils"
 )
 
 var _ = Describe("Server", func() {
@@ -27,7 +42,7 @@ var _ = Describe("Server", func() {
 			CertDir: "/tmp/test-certs",
 		}
 
-		mutator = NewMutator(nil, cfg, []features.Feature{})
+		mutator = mustNewMutator(nil, cfg, []features.Feature{})
 		handler = NewHandler(mutator)
 		server = NewServer(cfg, handler)
 	})
@@ -70,6 +85,13 @@ var _ = Describe("Server", func() {
 		})
 
 		Describe("readyzHandler", func() {
+			BeforeEach(func() {
+				cfg.CertDir = GinkgoT().TempDir()
+				writeTestCertificate(cfg.CertDir, time.Now().Add(time.Hour))
+				tpm := features.NewTpm(utils.ConfigSourceAnnotations)
+				server = NewServer(cfg, NewHandler(mustNewMutator(nil, cfg, []features.Feature{tpm})))
+			})
+
 			It("should return ready status", func() {
 				req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
 				server.readyzHandler(recorder, req)
@@ -78,17 +100,233 @@ var _ = Describe("Server", func() {
 				Expect(recorder.Body.String()).To(Equal("ready"))
 			})
 
-			It("should handle write errors gracefully", func() {
-				// Test that the handler completes even if write fails
-				req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
-				recorder := httptest.NewRecorder()
-				server.readyzHandler(recorder, req)
+			It("should not be ready without a certificate", func() {
+				cfg.CertDir = GinkgoT().TempDir()
+				server.readyzHandler(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
 
-				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(recorder.Body.String()).To(HavePrefix("certificate: "))
+			})
+
+			It("should not be ready with an expired certificate", func() {
+				writeTestCertificate(cfg.CertDir, time.Now().Add(-time.Hour))
+				server.readyzHandler(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+				Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(recorder.Body.String()).To(ContainSubstring("certificate expired"))
+			})
+
+			It("should not be ready without registered features", func() {
+				server = NewServer(cfg, NewHandler(mustNewMutator(nil, cfg, []features.Feature{})))
+				server.readyzHandler(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+				Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(recorder.Body.String()).To(ContainSubstring("features: no features registered"))
+			})
+
+			It("should report failing readiness checks", func() {
+				server.AddReadinessCheck("apiserver", func(context.Context) error {
+					return errors.New("connection refused")
+				})
+				server.readyzHandler(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+				Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(recorder.Body.String()).To(ContainSubstring("apiserver: connection refused"))
 			})
 		})
 	})
 
+	Describe("featuresHandler", func() {
+		It("should report the registered features and their state", func() {
+			nestedVirt := features.NewNestedVirtualization(&config.NestedVirtConfig{Enabled: true}, utils.ConfigSourceAnnotations)
+			tpm := features.NewTpm(utils.ConfigSourceAnnotations)
+			registry, err := features.NewRegistry([]features.Feature{nestedVirt, tpm})
+			Expect(err).ToNot(HaveOccurred())
+			registry.SetStates(map[string]bool{tpm.Name(): false})
+			server = NewServer(cfg, NewHandler(NewMutatorWithRegistry(nil, cfg, registry)))
+
+			recorder := httptest.NewRecorder()
+			server.featuresHandler(recorder, httptest.NewRequest(http.MethodGet, "/debug/features", nil))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+			var states []features.FeatureState
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &states)).To(Succeed())
+			Expect(states).To(ConsistOf(
+				And(
+					HaveField("Name", nestedVirt.Name()),
+					HaveField("Enabled", true),
+					HaveField("Config", HaveKeyWithValue("enabled", true)),
+				),
+				features.FeatureState{Name: tpm.Name(), Enabled: false},
+			))
+		})
+
+		It("should only allow GET", func() {
+			recorder := httptest.NewRecorder()
+			server.featuresHandler(recorder, httptest.NewRequest(http.MethodPost, "/debug/features", nil))
+
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+
+	Describe("muxes", func() {
+		It("should serve everything on the TLS port without a health port", func() {
+			mux, healthMux := server.muxes()
+			Expect(healthMux).To(BeNil())
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should move the probes to the health port when configured", func() {
+			cfg.HealthPort = 8081
+			mux, healthMux := server.muxes()
+			Expect(healthMux).ToNot(BeNil())
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+
+			recorder = httptest.NewRecorder()
+			healthMux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			recorder = httptest.NewRecorder()
+			healthMux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mutate", nil))
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("client certificates", func() {
+		It("should reject admission requests without a verified client certificate", func() {
+			cfg.ClientCAFile = "/etc/webhook/client-ca/ca.crt"
+			mux, _ := server.muxes()
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mutate", nil))
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+
+			recorder = httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should pass requests with a verified client certificate", func() {
+			called := false
+			handler := requireClientCert(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/mutate", nil)
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			Expect(called).To(BeTrue())
+		})
+
+		It("should load client CAs from PEM", func() {
+			dir := GinkgoT().TempDir()
+			writeTestCertificate(dir, time.Now().Add(time.Hour))
+
+			pool, err := loadClientCAs(filepath.Join(dir, "tls.crt"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pool).ToNot(BeNil())
+
+			_, err = loadClientCAs(filepath.Join(dir, "tls.key"))
+			Expect(err).To(MatchError(ContainSubstring("no certificates found")))
+		})
+	})
+
+	Describe("request body limits", func() {
+		It("should reject admission requests announcing an oversized body", func() {
+			cfg.Server.MaxRequestBytes = 16
+			mux, _ := server.muxes()
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mutate", strings.NewReader(strings.Repeat("x", 17))))
+			Expect(recorder.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(recorder.Body.String()).To(ContainSubstring("exceeds 16 bytes"))
+		})
+
+		It("should stop reading bodies without a length past the limit", func() {
+			var readErr error
+			handler := limitRequestBody(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				_, readErr = io.ReadAll(r.Body)
+			}), 16)
+
+			req := httptest.NewRequest(http.MethodPost, "/mutate", strings.NewReader(strings.Repeat("x", 17)))
+			req.ContentLength = -1
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			var tooLarge *http.MaxBytesError
+			Expect(errors.As(readErr, &tooLarge)).To(BeTrue())
+		})
+	})
+
+	Describe("registerObservability", func() {
+		It("should serve the probes and only serve pprof when enabled", func() {
+			mux := http.NewServeMux()
+			server.registerObservability(mux)
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			recorder = httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("registerPprof", func() {
+		It("should serve the profile index", func() {
+			mux := http.NewServeMux()
+			registerPprof(mux)
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(ContainSubstring("goroutine"))
+		})
+	})
+
+	Describe("keyPairReloader", func() {
+		It("should reload the key pair when it changes", func() {
+			dir := GinkgoT().TempDir()
+			writeTestCertificate(dir, time.Now().Add(time.Hour))
+			reloader, err := newKeyPairReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+			Expect(err).ToNot(HaveOccurred())
+			first, err := reloader.getCertificate(nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			writeTestCertificate(dir, time.Now().Add(2*time.Hour))
+			later := time.Now().Add(time.Minute)
+			Expect(os.Chtimes(filepath.Join(dir, "tls.crt"), later, later)).To(Succeed())
+			second, err := reloader.getCertificate(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(second.Certificate[0]).ToNot(Equal(first.Certificate[0]))
+		})
+
+		It("should keep the last key pair while the files can't be read", func() {
+			dir := GinkgoT().TempDir()
+			writeTestCertificate(dir, time.Now().Add(time.Hour))
+			reloader, err := newKeyPairReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(os.Remove(filepath.Join(dir, "tls.key"))).To(Succeed())
+			cert, err := reloader.getCertificate(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cert).ToNot(BeNil())
+		})
+
+		It("should fail without a key pair", func() {
+			_, err := newKeyPairReloader("/nonexistent/tls.crt", "/nonexistent/tls.key")
+			Expect(err).To(MatchError(ContainSubstring("failed to load serving certificate")))
+		})
+	})
+
 	Describe("Start", func() {
 		Context("with context cancellation", func() {
 			It("should shutdown gracefully", func() {
@@ -138,3 +376,23 @@ var _ = Describe("Server", func() {
 		})
 	})
 })
+
+// writeTestCertificate writes a self-signed serving certificate valid until
+// notAfter to dir
+func writeTestCertificate(dir string, notAfter time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "vm-feature-manager"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	Expect(os.WriteFile(filepath.Join(dir, "tls.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(dir, "tls.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)).To(Succeed())
+}
@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Outcomes of a shadow mode request
+const (
+	shadowOutcomePatch  = "patch"
+	shadowOutcomeReject = "reject"
+	shadowOutcomeNone   = "none"
+)
+
+// shadowAdmissions counts requests handled in shadow mode by what enforcing
+// them would have done
+var shadowAdmissions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "vm_feature_manager_shadow_admissions_total",
+	Help: "Admission requests handled in shadow mode by kind and the outcome enforcement would have had (patch, reject or none)",
+}, []string{"kind", "outcome"})
+
+func init() {
+	metrics.Registry.MustRegister(shadowAdmissions)
+}
+
+// discardShadowResult logs and counts what response would have done to the
+// object of req, then turns it into an allow without a patch
+func (m *Mutator) discardShadowResult(ctx context.Context, req *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse) {
+	logger := log.FromContext(ctx).WithValues("uid", req.UID, "kind", req.Kind.Kind, "namespace", req.Namespace, "name", req.Name)
+
+	outcome := shadowOutcomeNone
+	switch {
+	case !response.Allowed:
+		outcome = shadowOutcomeReject
+		reason := ""
+		if response.Result != nil {
+			reason = response.Result.Message
+		}
+		logger.Info("Shadow mode, would reject", "reason", reason)
+		response.Allowed = true
+		response.Result = &metav1.Status{Message: "shadow mode, not enforced"}
+		response.Warnings = append(response.Warnings, fmt.Sprintf("shadow mode: would be rejected: %s", reason))
+	case response.Patch != nil:
+		outcome = shadowOutcomePatch
+		logger.Info("Shadow mode, would patch", "patch", string(response.Patch))
+	}
+
+	response.Patch = nil
+	response.PatchType = nil
+	shadowAdmissions.WithLabelValues(req.Kind.Kind, outcome).Inc()
+}
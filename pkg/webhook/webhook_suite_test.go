@@ -5,9 +5,21 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/config"
+	"github.com/jaevans/kubevirt-vm-feature-manager/pkg/features"
 )
 
 func TestWebhook(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Webhook Suite")
 }
+
+// mustNewMutator creates a Mutator, failing the spec if the features can't
+// form a registry
+func mustNewMutator(client client.Client, cfg *config.Config, featureList []features.Feature) *Mutator {
+	mutator, err := NewMutator(client, cfg, featureList)
+	ExpectWithOffset(1, err).ToNot(HaveOccurred())
+	return mutator
+}
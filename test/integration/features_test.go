@@ -94,7 +94,7 @@ var _ = Describe("Integration Tests", func() {
 			})
 
 			// Apply mutations
-			feature := features.NewPciPassthrough(utils.ConfigSourceAnnotations)
+			feature := features.NewPciPassthrough(&cfg.Features.PCIPassthrough, utils.ConfigSourceAnnotations)
 			err := feature.Validate(testCtx, vm, k8sClient)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -114,7 +114,7 @@ var _ = Describe("Integration Tests", func() {
 				utils.AnnotationPciPassthrough: `{"devices":["0000:00:14.0","0000:03:00.0"]}`,
 			})
 
-			feature := features.NewPciPassthrough(utils.ConfigSourceAnnotations)
+			feature := features.NewPciPassthrough(&cfg.Features.PCIPassthrough, utils.ConfigSourceAnnotations)
 			_, err := feature.Apply(testCtx, vm, k8sClient)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -151,7 +151,7 @@ var _ = Describe("Integration Tests", func() {
 				utils.AnnotationVBiosInjection: "test-vbios",
 			})
 
-			feature := features.NewVBiosInjection(utils.ConfigSourceAnnotations)
+			feature := features.NewVBiosInjection(&cfg.Features.VBiosInjection, utils.ConfigSourceAnnotations)
 			err := feature.Validate(testCtx, vm, k8sClient)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -179,7 +179,7 @@ var _ = Describe("Integration Tests", func() {
 				utils.AnnotationVBiosInjection: "Invalid_Name_With_Underscores!",
 			})
 
-			feature := features.NewVBiosInjection(utils.ConfigSourceAnnotations)
+			feature := features.NewVBiosInjection(&cfg.Features.VBiosInjection, utils.ConfigSourceAnnotations)
 			err := feature.Validate(testCtx, vm, k8sClient)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("invalid ConfigMap name"))
@@ -192,7 +192,7 @@ var _ = Describe("Integration Tests", func() {
 				utils.AnnotationGpuDevicePlugin: "nvidia.com/gpu",
 			})
 
-			feature := features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations)
+			feature := features.NewGpuDevicePlugin(&cfg.Features.GPUDevicePlugin, utils.ConfigSourceAnnotations)
 			err := feature.Validate(testCtx, vm, k8sClient)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -214,7 +214,7 @@ var _ = Describe("Integration Tests", func() {
 					utils.AnnotationGpuDevicePlugin: vendor,
 				})
 
-				feature := features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations)
+				feature := features.NewGpuDevicePlugin(&cfg.Features.GPUDevicePlugin, utils.ConfigSourceAnnotations)
 				_, err := feature.Apply(testCtx, vm, k8sClient)
 				Expect(err).NotTo(HaveOccurred())
 
@@ -258,9 +258,9 @@ var _ = Describe("Integration Tests", func() {
 			// Apply all features
 			allFeatures := []features.Feature{
 				features.NewNestedVirtualization(&cfg.Features.NestedVirtualization, utils.ConfigSourceAnnotations),
-				features.NewPciPassthrough(utils.ConfigSourceAnnotations),
-				features.NewVBiosInjection(utils.ConfigSourceAnnotations),
-				features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations),
+				features.NewPciPassthrough(&cfg.Features.PCIPassthrough, utils.ConfigSourceAnnotations),
+				features.NewVBiosInjection(&cfg.Features.VBiosInjection, utils.ConfigSourceAnnotations),
+				features.NewGpuDevicePlugin(&cfg.Features.GPUDevicePlugin, utils.ConfigSourceAnnotations),
 			}
 
 			for _, feature := range allFeatures {
@@ -303,7 +303,7 @@ var _ = Describe("Integration Tests", func() {
 				utils.AnnotationPciPassthrough: `{"devices":["invalid"]}`,
 			})
 
-			feature := features.NewPciPassthrough(utils.ConfigSourceAnnotations)
+			feature := features.NewPciPassthrough(&cfg.Features.PCIPassthrough, utils.ConfigSourceAnnotations)
 			err := feature.Validate(testCtx, vm, k8sClient)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("invalid PCI address"))
@@ -314,7 +314,7 @@ var _ = Describe("Integration Tests", func() {
 				utils.AnnotationPciPassthrough: `{"devices":["0000:00:14.0","0000:00:14.0"]}`,
 			})
 
-			feature := features.NewPciPassthrough(utils.ConfigSourceAnnotations)
+			feature := features.NewPciPassthrough(&cfg.Features.PCIPassthrough, utils.ConfigSourceAnnotations)
 			err := feature.Validate(testCtx, vm, k8sClient)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("duplicate"))
@@ -325,7 +325,7 @@ var _ = Describe("Integration Tests", func() {
 				utils.AnnotationGpuDevicePlugin: "invalid name with spaces",
 			})
 
-			feature := features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations)
+			feature := features.NewGpuDevicePlugin(&cfg.Features.GPUDevicePlugin, utils.ConfigSourceAnnotations)
 			err := feature.Validate(testCtx, vm, k8sClient)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("invalid device plugin name"))
@@ -32,6 +32,7 @@ var _ = Describe("Webhook Integration Tests", func() {
 		// Create test config
 		cfg = &config.Config{
 			AddTrackingAnnotations: true,
+			UserdataDirectives:     true,
 			ErrorHandlingMode:      utils.ErrorHandlingReject,
 			Features: config.FeaturesConfig{
 				NestedVirtualization: config.NestedVirtConfig{
@@ -54,13 +55,15 @@ var _ = Describe("Webhook Integration Tests", func() {
 		// Create features
 		allFeatures := []features.Feature{
 			features.NewNestedVirtualization(&cfg.Features.NestedVirtualization, utils.ConfigSourceAnnotations),
-			features.NewPciPassthrough(utils.ConfigSourceAnnotations),
-			features.NewVBiosInjection(utils.ConfigSourceAnnotations),
-			features.NewGpuDevicePlugin(utils.ConfigSourceAnnotations),
+			features.NewPciPassthrough(&cfg.Features.PCIPassthrough, utils.ConfigSourceAnnotations),
+			features.NewVBiosInjection(&cfg.Features.VBiosInjection, utils.ConfigSourceAnnotations),
+			features.NewGpuDevicePlugin(&cfg.Features.GPUDevicePlugin, utils.ConfigSourceAnnotations),
 		}
 
 		// Create mutator with real Kubernetes client
-		mutator = webhook.NewMutator(webhookK8sClient, cfg, allFeatures)
+		var err error
+		mutator, err = webhook.NewMutator(webhookK8sClient, cfg, allFeatures)
+		Expect(err).ToNot(HaveOccurred())
 	})
 
 	AfterEach(func() {
@@ -472,6 +475,28 @@ x_kubevirt_features:
 		})
 
 		Context("with nil template", func() {
+			var configMap *corev1.ConfigMap
+
+			BeforeEach(func() {
+				configMap = &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vbios",
+						Namespace: "integration-test",
+					},
+					BinaryData: map[string][]byte{
+						utils.VBiosConfigMapKey: []byte("fake-vbios-data"),
+					},
+				}
+				err := k8sClient.Create(testCtx, configMap)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				if configMap != nil {
+					_ = k8sClient.Delete(testCtx, configMap)
+				}
+			})
+
 			It("should reject VM with application error", func() {
 				vm := &kubevirtv1.VirtualMachine{
 					ObjectMeta: metav1.ObjectMeta{
@@ -511,9 +536,11 @@ x_kubevirt_features:
 			BeforeEach(func() {
 				cfg.ErrorHandlingMode = utils.ErrorHandlingAllowAndLog
 				allFeatures := []features.Feature{
-					features.NewVBiosInjection(utils.ConfigSourceAnnotations),
+					features.NewVBiosInjection(&cfg.Features.VBiosInjection, utils.ConfigSourceAnnotations),
 				}
-				mutator = webhook.NewMutator(k8sClient, cfg, allFeatures)
+				var err error
+				mutator, err = webhook.NewMutator(k8sClient, cfg, allFeatures)
+				Expect(err).ToNot(HaveOccurred())
 			})
 
 			It("should allow VM despite feature error", func() {
@@ -553,9 +580,11 @@ x_kubevirt_features:
 			BeforeEach(func() {
 				cfg.ErrorHandlingMode = utils.ErrorHandlingReject
 				allFeatures := []features.Feature{
-					features.NewVBiosInjection(utils.ConfigSourceAnnotations),
+					features.NewVBiosInjection(&cfg.Features.VBiosInjection, utils.ConfigSourceAnnotations),
 				}
-				mutator = webhook.NewMutator(k8sClient, cfg, allFeatures)
+				var err error
+				mutator, err = webhook.NewMutator(k8sClient, cfg, allFeatures)
+				Expect(err).ToNot(HaveOccurred())
 			})
 
 			It("should reject VM on feature error", func() {
@@ -619,20 +648,12 @@ x_kubevirt_features:
 				err = json.Unmarshal(response.Patch, &patchOps)
 				Expect(err).ToNot(HaveOccurred())
 
-				// Look for annotations patch operation and verify it contains tracking
-				foundAnnotationsPatch := false
-				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/metadata/annotations" {
-						foundAnnotationsPatch = true
-						annotations, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue(), "annotations patch value should be a map")
-						// Verify the tracking annotation is present
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirtApplied))
-						Expect(annotations[utils.AnnotationNestedVirtApplied]).To(Equal("true"))
-						break
-					}
-				}
-				Expect(foundAnnotationsPatch).To(BeTrue())
+				// Look for the operation adding the tracking annotation
+				Expect(patchOps).To(ContainElement(And(
+					HaveKeyWithValue("op", "add"),
+					HaveKeyWithValue("path", "/metadata/annotations/vm-feature-manager.io~1nested-virt-applied"),
+					HaveKeyWithValue("value", "true"),
+				)))
 			})
 		})
 
@@ -642,7 +663,9 @@ x_kubevirt_features:
 				allFeatures := []features.Feature{
 					features.NewNestedVirtualization(&cfg.Features.NestedVirtualization, utils.ConfigSourceAnnotations),
 				}
-				mutator = webhook.NewMutator(k8sClient, cfg, allFeatures)
+				var err error
+				mutator, err = webhook.NewMutator(k8sClient, cfg, allFeatures)
+				Expect(err).ToNot(HaveOccurred())
 			})
 
 			It("should still apply feature but not add tracking annotations", func() {
@@ -671,16 +694,9 @@ x_kubevirt_features:
 				err = json.Unmarshal(response.Patch, &patchOps)
 				Expect(err).ToNot(HaveOccurred())
 
-				// Verify that if annotations patch exists, it doesn't have tracking
+				// Verify that no operation touches the tracking annotation
 				for _, op := range patchOps {
-					if path, ok := op["path"].(string); ok && path == "/metadata/annotations" {
-						annotations, ok := op["value"].(map[string]interface{})
-						Expect(ok).To(BeTrue())
-						// Original annotation should be present
-						Expect(annotations).To(HaveKey(utils.AnnotationNestedVirt))
-						// But NOT the tracking annotation
-						Expect(annotations).ToNot(HaveKey(utils.AnnotationNestedVirtApplied))
-					}
+					Expect(op["path"]).ToNot(ContainSubstring("nested-virt-applied"))
 				}
 			})
 		})